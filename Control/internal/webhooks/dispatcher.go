@@ -0,0 +1,245 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	SignatureHeader = "X-Robots-Signature"
+	TimestampHeader = "X-Robots-Timestamp"
+	EventHeader     = "X-Robots-Event"
+	DeliveryHeader  = "X-Robots-Delivery"
+)
+
+var ErrForbiddenAddress = errors.New("webhook address is not public")
+
+// Событие из реббита, которое надо разослать подписчикам
+type Event struct {
+	RoutingKey  string
+	ContentType string
+	Body        []byte
+	// Принципал, сделавший изменение (заголовок actor), может быть пустым
+	Actor string
+}
+
+// Тело POST запроса на вебхук
+type payload struct {
+	ID        string          `json:"id"`
+	Event     string          `json:"event"`
+	CreatedAt time.Time       `json:"createdAt"`
+	Actor     string          `json:"actor,omitempty"`
+	Data      json.RawMessage `json:"data"`
+}
+
+type Dispatcher struct {
+	Repo   *Repository
+	Client *http.Client
+	// Сколько раз пытаемся доставить одно событие
+	MaxAttempts int
+	// Задержка перед первым повтором, дальше удваивается
+	BaseDelay time.Duration
+	// После скольких подряд неудачных доставок вебхук отключается
+	DisableAfter int
+}
+
+func NewDispatcher(repo *Repository) *Dispatcher {
+	return &Dispatcher{
+		Repo:         repo,
+		Client:       &http.Client{Timeout: 10 * time.Second, Transport: publicTransport()},
+		MaxAttempts:  5,
+		BaseDelay:    time.Second,
+		DisableAfter: 10,
+	}
+}
+
+// Рассылаем событие всем подходящим подпискам параллельно и ждём, пока все закончат
+func (d *Dispatcher) Dispatch(ctx context.Context, event Event) error {
+	subs, err := d.Repo.ActiveFor(ctx, event.RoutingKey)
+	if err != nil {
+		return err
+	}
+	if len(subs) == 0 {
+		return nil
+	}
+
+	// У каждого подписчика своя доставка, и её id в теле совпадает с заголовком
+	createdAt := time.Now().UTC()
+	bodies := make([][]byte, len(subs))
+	deliveryIDs := make([]string, len(subs))
+	for i := range subs {
+		deliveryIDs[i] = newDeliveryID()
+		if bodies[i], err = makePayload(event, deliveryIDs[i], createdAt); err != nil {
+			return err
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i, sub := range subs {
+		wg.Add(1)
+		go func(sub Subscription, deliveryID string, body []byte) {
+			defer wg.Done()
+			d.deliver(ctx, sub, event.RoutingKey, deliveryID, body)
+		}(sub, deliveryIDs[i], bodies[i])
+	}
+	wg.Wait()
+	return nil
+}
+
+// Доставка одному подписчику с повторами и экспоненциальной задержкой. Повторы идут с тем же id
+func (d *Dispatcher) deliver(ctx context.Context, sub Subscription, event, deliveryID string, body []byte) {
+	var lastErr string
+
+	for attempt := 1; attempt <= d.MaxAttempts; attempt++ {
+		if attempt > 1 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(d.BaseDelay << (attempt - 2)):
+			}
+		}
+
+		start := time.Now()
+		status, err := d.post(ctx, sub, event, deliveryID, body)
+		success := err == nil && status >= 200 && status < 300
+		lastErr = ""
+		if err != nil {
+			lastErr = err.Error()
+		} else if !success {
+			lastErr = "unexpected status " + strconv.Itoa(status)
+		}
+
+		logErr := d.Repo.LogAttempt(ctx, Attempt{
+			WebhookID: sub.ID, DeliveryID: deliveryID, Event: event, Number: attempt,
+			StatusCode: status, Success: success, Error: lastErr, Duration: time.Since(start),
+		})
+		if logErr != nil {
+			log.Printf("[webhooks] cannot log delivery %s: %v", deliveryID, logErr)
+		}
+
+		if success {
+			if err = d.Repo.MarkSuccess(ctx, sub.ID); err != nil {
+				log.Printf("[webhooks] cannot reset failures of webhook %d: %v", sub.ID, err)
+			}
+			return
+		}
+		// Клиентские ошибки (кроме 408 и 429) и адрес во внутренней сети повторять бесполезно
+		if errors.Is(err, ErrForbiddenAddress) {
+			break
+		}
+		if status >= 400 && status < 500 && status != http.StatusRequestTimeout && status != http.StatusTooManyRequests {
+			break
+		}
+	}
+
+	log.Printf("[webhooks] delivery %s of %s to webhook %d failed: %s", deliveryID, event, sub.ID, lastErr)
+	disabled, err := d.Repo.MarkFailure(ctx, sub.ID, d.DisableAfter, "too many failed deliveries, last error: "+lastErr)
+	if err != nil {
+		log.Printf("[webhooks] cannot update failures of webhook %d: %v", sub.ID, err)
+	}
+	if disabled {
+		log.Printf("[webhooks] webhook %d disabled after %d failed deliveries", sub.ID, d.DisableAfter)
+	}
+}
+
+func (d *Dispatcher) post(ctx context.Context, sub Subscription, event, deliveryID string, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "RobotService-Webhooks/1.0")
+	req.Header.Set(EventHeader, event)
+	req.Header.Set(DeliveryHeader, deliveryID)
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, "sha256="+Sign(sub.Secret, timestamp, body))
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	return resp.StatusCode, nil
+}
+
+// Сервис роботов проверяет адрес при регистрации, но имя могло с тех пор начать указывать внутрь сети,
+// а получатель может ответить редиректом туда же. Поэтому адрес проверяем в момент соединения:
+// Control дайлера видит уже выбранный IP, так что и каждый редирект, и каждый ответ DNS проходят проверку.
+// Прокси не используем, иначе проверялся бы адрес прокси, а не получателя
+func publicTransport() *http.Transport {
+	dialer := &net.Dialer{Timeout: 5 * time.Second, KeepAlive: 30 * time.Second, Control: checkPublic}
+	return &http.Transport{
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   5 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+}
+
+func checkPublic(_, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, address)
+	}
+	addr := addrPort.Addr().Unmap()
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() || addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, addr)
+	}
+	return nil
+}
+
+// Подпись: HMAC-SHA256 от "<timestamp>.<body>". Получатель считает так же и сравнивает
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Структуры роботов отдаём как есть, текстовые сообщения заворачиваем в {"message": ...}
+func makePayload(event Event, deliveryID string, createdAt time.Time) ([]byte, error) {
+	data := json.RawMessage(event.Body)
+	if event.ContentType != "application/json" || !json.Valid(event.Body) {
+		wrapped, err := json.Marshal(map[string]string{"message": string(event.Body)})
+		if err != nil {
+			return nil, err
+		}
+		data = wrapped
+	}
+	return json.Marshal(payload{
+		ID:        deliveryID,
+		Event:     event.RoutingKey,
+		CreatedAt: createdAt,
+		Actor:     event.Actor,
+		Data:      data,
+	})
+}
+
+func newDeliveryID() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	return fmt.Sprintf("%x-%x-%x-%x-%x", buf[0:4], buf[4:6], buf[6:8], buf[8:10], buf[10:])
+}
@@ -0,0 +1,120 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckPublic(t *testing.T) {
+	tests := []struct {
+		address string
+		public  bool
+	}{
+		{"93.184.216.34:443", true},
+		{"[2606:4700::1111]:443", true},
+		{"127.0.0.1:80", false},
+		{"127.8.8.8:80", false},
+		{"[::1]:80", false},
+		{"10.0.0.5:80", false},
+		{"172.16.0.1:80", false},
+		{"192.168.1.1:80", false},
+		{"[fd00::1]:80", false},
+		{"169.254.169.254:80", false},
+		{"[fe80::1]:80", false},
+		{"0.0.0.0:80", false},
+		{"224.0.0.1:80", false},
+		// IPv4 внутри IPv6 проверяется как IPv4
+		{"[::ffff:127.0.0.1]:80", false},
+		{"[::ffff:10.0.0.1]:80", false},
+		{"[::ffff:93.184.216.34]:80", true},
+		{"localhost:80", false},
+	}
+	for _, tt := range tests {
+		err := checkPublic("tcp", tt.address, nil)
+		if tt.public != (err == nil) || (err != nil && !errors.Is(err, ErrForbiddenAddress)) {
+			t.Errorf("checkPublic(%s) = %v, public %v", tt.address, err, tt.public)
+		}
+	}
+}
+
+// Имя, которое указывает на loopback, отсекается при соединении, до запроса
+func TestPublicTransportRejectsLocalName(t *testing.T) {
+	hit := false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { hit = true }))
+	t.Cleanup(ts.Close)
+	client := &http.Client{Timeout: 5 * time.Second, Transport: publicTransport()}
+
+	for _, url := range []string{ts.URL, strings.Replace(ts.URL, "127.0.0.1", "localhost", 1)} {
+		resp, err := client.Get(url)
+		if err == nil {
+			resp.Body.Close()
+		}
+		if !errors.Is(err, ErrForbiddenAddress) {
+			t.Errorf("GET %s: err = %v, want ErrForbiddenAddress", url, err)
+		}
+	}
+	if hit {
+		t.Fatal("request reached the local server")
+	}
+}
+
+func TestPostDeliveryID(t *testing.T) {
+	var header http.Header
+	var body []byte
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(ts.Close)
+	d := &Dispatcher{Client: ts.Client()}
+
+	createdAt := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	payloadBody, err := makePayload(Event{RoutingKey: "robots.Delete", ContentType: "text/plain", Body: []byte("deleted"), Actor: "jwt:alice"}, "delivery-1", createdAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub := Subscription{ID: 3, URL: ts.URL, Secret: "secret"}
+	status, err := d.post(context.Background(), sub, "robots.Delete", "delivery-1", payloadBody)
+	if err != nil || status != http.StatusAccepted {
+		t.Fatalf("post: %d, %v", status, err)
+	}
+
+	var got payload
+	if err = json.Unmarshal(body, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != header.Get(DeliveryHeader) || got.ID != "delivery-1" {
+		t.Fatalf("payload id %q, header %q", got.ID, header.Get(DeliveryHeader))
+	}
+	if got.Event != "robots.Delete" || !got.CreatedAt.Equal(createdAt) || got.Actor != "jwt:alice" || string(got.Data) != `{"message":"deleted"}` {
+		t.Fatalf("payload = %+v", got)
+	}
+	timestamp := header.Get(TimestampHeader)
+	if header.Get(SignatureHeader) != "sha256="+Sign("secret", timestamp, body) || header.Get(EventHeader) != "robots.Delete" {
+		t.Fatalf("headers = %v", header)
+	}
+}
+
+func TestMakePayloadKeepsJSON(t *testing.T) {
+	body, err := makePayload(Event{RoutingKey: "robots.Create", ContentType: "application/json", Body: []byte(`{"id":5}`)}, "d", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got payload
+	if err = json.Unmarshal(body, &got); err != nil || string(got.Data) != `{"id":5}` || got.Actor != "" {
+		t.Fatalf("payload = %s, err = %v", body, err)
+	}
+	// Невалидный JSON заворачивается как текст
+	body, _ = makePayload(Event{ContentType: "application/json", Body: []byte(`{"id":`)}, "d", time.Now())
+	if !strings.Contains(string(body), `"data":{"message":"{\"id\":"}`) {
+		t.Fatalf("payload = %s", body)
+	}
+}
@@ -0,0 +1,96 @@
+package webhooks
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Подписка из таблицы webhooks (таблицы создаёт RobotService)
+type Subscription struct {
+	ID     int
+	URL    string
+	Secret string
+}
+
+// Одна попытка доставки для журнала
+type Attempt struct {
+	WebhookID  int
+	DeliveryID string
+	Event      string
+	Number     int
+	StatusCode int
+	Success    bool
+	Error      string
+	Duration   time.Duration
+}
+
+// pgx.Conn нельзя использовать из нескольких горутин, поэтому все запросы под мьютексом
+type Repository struct {
+	mu sync.Mutex
+	db *pgx.Conn
+}
+
+func NewRepository(db *pgx.Conn) *Repository {
+	return &Repository{db: db}
+}
+
+// Активные подписки на событие (или на все события через "*")
+func (repo *Repository) ActiveFor(ctx context.Context, event string) ([]Subscription, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	query := "SELECT id, url, secret FROM webhooks WHERE active AND ($1 = ANY(events) OR '*' = ANY(events)) ORDER BY id"
+	rows, err := repo.db.Query(ctx, query, event)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subs []Subscription
+	for rows.Next() {
+		var sub Subscription
+		if err = rows.Scan(&sub.ID, &sub.URL, &sub.Secret); err != nil {
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	return subs, rows.Err()
+}
+
+func (repo *Repository) LogAttempt(ctx context.Context, attempt Attempt) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	query := `INSERT INTO webhook_deliveries (webhook_id, delivery_id, event, attempt, status_code, success, error, duration_ms)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8)`
+	_, err := repo.db.Exec(ctx, query, attempt.WebhookID, attempt.DeliveryID, attempt.Event, attempt.Number,
+		attempt.StatusCode, attempt.Success, attempt.Error, attempt.Duration.Milliseconds())
+	return err
+}
+
+// Успешная доставка обнуляет счётчик подряд идущих ошибок
+func (repo *Repository) MarkSuccess(ctx context.Context, webhookID int) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	_, err := repo.db.Exec(ctx, "UPDATE webhooks SET failure_count = 0 WHERE id = $1 AND failure_count <> 0", webhookID)
+	return err
+}
+
+// Доставка не удалась после всех повторов. Возвращает true, если вебхук пришлось отключить
+func (repo *Repository) MarkFailure(ctx context.Context, webhookID, disableAfter int, reason string) (bool, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	query := `UPDATE webhooks SET failure_count = failure_count + 1,
+		active = CASE WHEN failure_count + 1 >= $2 THEN FALSE ELSE active END,
+		disabled_reason = CASE WHEN failure_count + 1 >= $2 THEN $3 ELSE disabled_reason END,
+		updated_at = now()
+		WHERE id = $1 RETURNING active`
+	var active bool
+	err := repo.db.QueryRow(ctx, query, webhookID, disableAfter, reason).Scan(&active)
+	return !active, err
+}
@@ -0,0 +1,477 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: robots.proto
+
+// gRPC API RobotService. Сервер: internal/grpcserver, порт задаётся grpcAddr в config/config.yaml.
+// Go код в api/robotsv1 генерируется командой buf generate из каталога Robots
+
+package robotsv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	RobotService_CreateRobot_FullMethodName           = "/robots.v1.RobotService/CreateRobot"
+	RobotService_GetRobot_FullMethodName              = "/robots.v1.RobotService/GetRobot"
+	RobotService_ListRobots_FullMethodName            = "/robots.v1.RobotService/ListRobots"
+	RobotService_UpdateRobotCords_FullMethodName      = "/robots.v1.RobotService/UpdateRobotCords"
+	RobotService_UpdateRobotName_FullMethodName       = "/robots.v1.RobotService/UpdateRobotName"
+	RobotService_ChangeRobotType_FullMethodName       = "/robots.v1.RobotService/ChangeRobotType"
+	RobotService_DeleteRobot_FullMethodName           = "/robots.v1.RobotService/DeleteRobot"
+	RobotService_UpdateRobotAttributes_FullMethodName = "/robots.v1.RobotService/UpdateRobotAttributes"
+	RobotService_UpdateRobotLabels_FullMethodName     = "/robots.v1.RobotService/UpdateRobotLabels"
+	RobotService_WatchRobots_FullMethodName           = "/robots.v1.RobotService/WatchRobots"
+)
+
+// RobotServiceClient is the client API for RobotService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type RobotServiceClient interface {
+	CreateRobot(ctx context.Context, in *CreateRobotRequest, opts ...grpc.CallOption) (*CreateRobotResponse, error)
+	GetRobot(ctx context.Context, in *GetRobotRequest, opts ...grpc.CallOption) (*Robot, error)
+	ListRobots(ctx context.Context, in *ListRobotsRequest, opts ...grpc.CallOption) (*ListRobotsResponse, error)
+	UpdateRobotCords(ctx context.Context, in *UpdateRobotCordsRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	UpdateRobotName(ctx context.Context, in *UpdateRobotNameRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	ChangeRobotType(ctx context.Context, in *ChangeRobotTypeRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	DeleteRobot(ctx context.Context, in *DeleteRobotRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// JSON Merge Patch атрибутов, возвращает робота после изменения
+	UpdateRobotAttributes(ctx context.Context, in *UpdateRobotAttributesRequest, opts ...grpc.CallOption) (*Robot, error)
+	// Ставит и снимает метки, возвращает робота после изменения
+	UpdateRobotLabels(ctx context.Context, in *UpdateRobotLabelsRequest, opts ...grpc.CallOption) (*Robot, error)
+	// Поток изменений роботов с момента подписки
+	WatchRobots(ctx context.Context, in *WatchRobotsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[RobotEvent], error)
+}
+
+type robotServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewRobotServiceClient(cc grpc.ClientConnInterface) RobotServiceClient {
+	return &robotServiceClient{cc}
+}
+
+func (c *robotServiceClient) CreateRobot(ctx context.Context, in *CreateRobotRequest, opts ...grpc.CallOption) (*CreateRobotResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CreateRobotResponse)
+	err := c.cc.Invoke(ctx, RobotService_CreateRobot_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *robotServiceClient) GetRobot(ctx context.Context, in *GetRobotRequest, opts ...grpc.CallOption) (*Robot, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Robot)
+	err := c.cc.Invoke(ctx, RobotService_GetRobot_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *robotServiceClient) ListRobots(ctx context.Context, in *ListRobotsRequest, opts ...grpc.CallOption) (*ListRobotsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListRobotsResponse)
+	err := c.cc.Invoke(ctx, RobotService_ListRobots_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *robotServiceClient) UpdateRobotCords(ctx context.Context, in *UpdateRobotCordsRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, RobotService_UpdateRobotCords_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *robotServiceClient) UpdateRobotName(ctx context.Context, in *UpdateRobotNameRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, RobotService_UpdateRobotName_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *robotServiceClient) ChangeRobotType(ctx context.Context, in *ChangeRobotTypeRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, RobotService_ChangeRobotType_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *robotServiceClient) DeleteRobot(ctx context.Context, in *DeleteRobotRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, RobotService_DeleteRobot_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *robotServiceClient) UpdateRobotAttributes(ctx context.Context, in *UpdateRobotAttributesRequest, opts ...grpc.CallOption) (*Robot, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Robot)
+	err := c.cc.Invoke(ctx, RobotService_UpdateRobotAttributes_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *robotServiceClient) UpdateRobotLabels(ctx context.Context, in *UpdateRobotLabelsRequest, opts ...grpc.CallOption) (*Robot, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Robot)
+	err := c.cc.Invoke(ctx, RobotService_UpdateRobotLabels_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *robotServiceClient) WatchRobots(ctx context.Context, in *WatchRobotsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[RobotEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &RobotService_ServiceDesc.Streams[0], RobotService_WatchRobots_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchRobotsRequest, RobotEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RobotService_WatchRobotsClient = grpc.ServerStreamingClient[RobotEvent]
+
+// RobotServiceServer is the server API for RobotService service.
+// All implementations must embed UnimplementedRobotServiceServer
+// for forward compatibility.
+type RobotServiceServer interface {
+	CreateRobot(context.Context, *CreateRobotRequest) (*CreateRobotResponse, error)
+	GetRobot(context.Context, *GetRobotRequest) (*Robot, error)
+	ListRobots(context.Context, *ListRobotsRequest) (*ListRobotsResponse, error)
+	UpdateRobotCords(context.Context, *UpdateRobotCordsRequest) (*emptypb.Empty, error)
+	UpdateRobotName(context.Context, *UpdateRobotNameRequest) (*emptypb.Empty, error)
+	ChangeRobotType(context.Context, *ChangeRobotTypeRequest) (*emptypb.Empty, error)
+	DeleteRobot(context.Context, *DeleteRobotRequest) (*emptypb.Empty, error)
+	// JSON Merge Patch атрибутов, возвращает робота после изменения
+	UpdateRobotAttributes(context.Context, *UpdateRobotAttributesRequest) (*Robot, error)
+	// Ставит и снимает метки, возвращает робота после изменения
+	UpdateRobotLabels(context.Context, *UpdateRobotLabelsRequest) (*Robot, error)
+	// Поток изменений роботов с момента подписки
+	WatchRobots(*WatchRobotsRequest, grpc.ServerStreamingServer[RobotEvent]) error
+	mustEmbedUnimplementedRobotServiceServer()
+}
+
+// UnimplementedRobotServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedRobotServiceServer struct{}
+
+func (UnimplementedRobotServiceServer) CreateRobot(context.Context, *CreateRobotRequest) (*CreateRobotResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateRobot not implemented")
+}
+func (UnimplementedRobotServiceServer) GetRobot(context.Context, *GetRobotRequest) (*Robot, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRobot not implemented")
+}
+func (UnimplementedRobotServiceServer) ListRobots(context.Context, *ListRobotsRequest) (*ListRobotsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListRobots not implemented")
+}
+func (UnimplementedRobotServiceServer) UpdateRobotCords(context.Context, *UpdateRobotCordsRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateRobotCords not implemented")
+}
+func (UnimplementedRobotServiceServer) UpdateRobotName(context.Context, *UpdateRobotNameRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateRobotName not implemented")
+}
+func (UnimplementedRobotServiceServer) ChangeRobotType(context.Context, *ChangeRobotTypeRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ChangeRobotType not implemented")
+}
+func (UnimplementedRobotServiceServer) DeleteRobot(context.Context, *DeleteRobotRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteRobot not implemented")
+}
+func (UnimplementedRobotServiceServer) UpdateRobotAttributes(context.Context, *UpdateRobotAttributesRequest) (*Robot, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateRobotAttributes not implemented")
+}
+func (UnimplementedRobotServiceServer) UpdateRobotLabels(context.Context, *UpdateRobotLabelsRequest) (*Robot, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateRobotLabels not implemented")
+}
+func (UnimplementedRobotServiceServer) WatchRobots(*WatchRobotsRequest, grpc.ServerStreamingServer[RobotEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchRobots not implemented")
+}
+func (UnimplementedRobotServiceServer) mustEmbedUnimplementedRobotServiceServer() {}
+func (UnimplementedRobotServiceServer) testEmbeddedByValue()                      {}
+
+// UnsafeRobotServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to RobotServiceServer will
+// result in compilation errors.
+type UnsafeRobotServiceServer interface {
+	mustEmbedUnimplementedRobotServiceServer()
+}
+
+func RegisterRobotServiceServer(s grpc.ServiceRegistrar, srv RobotServiceServer) {
+	// If the following call pancis, it indicates UnimplementedRobotServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&RobotService_ServiceDesc, srv)
+}
+
+func _RobotService_CreateRobot_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateRobotRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RobotServiceServer).CreateRobot(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RobotService_CreateRobot_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RobotServiceServer).CreateRobot(ctx, req.(*CreateRobotRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RobotService_GetRobot_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRobotRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RobotServiceServer).GetRobot(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RobotService_GetRobot_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RobotServiceServer).GetRobot(ctx, req.(*GetRobotRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RobotService_ListRobots_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRobotsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RobotServiceServer).ListRobots(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RobotService_ListRobots_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RobotServiceServer).ListRobots(ctx, req.(*ListRobotsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RobotService_UpdateRobotCords_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateRobotCordsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RobotServiceServer).UpdateRobotCords(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RobotService_UpdateRobotCords_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RobotServiceServer).UpdateRobotCords(ctx, req.(*UpdateRobotCordsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RobotService_UpdateRobotName_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateRobotNameRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RobotServiceServer).UpdateRobotName(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RobotService_UpdateRobotName_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RobotServiceServer).UpdateRobotName(ctx, req.(*UpdateRobotNameRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RobotService_ChangeRobotType_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ChangeRobotTypeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RobotServiceServer).ChangeRobotType(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RobotService_ChangeRobotType_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RobotServiceServer).ChangeRobotType(ctx, req.(*ChangeRobotTypeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RobotService_DeleteRobot_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteRobotRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RobotServiceServer).DeleteRobot(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RobotService_DeleteRobot_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RobotServiceServer).DeleteRobot(ctx, req.(*DeleteRobotRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RobotService_UpdateRobotAttributes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateRobotAttributesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RobotServiceServer).UpdateRobotAttributes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RobotService_UpdateRobotAttributes_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RobotServiceServer).UpdateRobotAttributes(ctx, req.(*UpdateRobotAttributesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RobotService_UpdateRobotLabels_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateRobotLabelsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RobotServiceServer).UpdateRobotLabels(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RobotService_UpdateRobotLabels_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RobotServiceServer).UpdateRobotLabels(ctx, req.(*UpdateRobotLabelsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RobotService_WatchRobots_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRobotsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RobotServiceServer).WatchRobots(m, &grpc.GenericServerStream[WatchRobotsRequest, RobotEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RobotService_WatchRobotsServer = grpc.ServerStreamingServer[RobotEvent]
+
+// RobotService_ServiceDesc is the grpc.ServiceDesc for RobotService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var RobotService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "robots.v1.RobotService",
+	HandlerType: (*RobotServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateRobot",
+			Handler:    _RobotService_CreateRobot_Handler,
+		},
+		{
+			MethodName: "GetRobot",
+			Handler:    _RobotService_GetRobot_Handler,
+		},
+		{
+			MethodName: "ListRobots",
+			Handler:    _RobotService_ListRobots_Handler,
+		},
+		{
+			MethodName: "UpdateRobotCords",
+			Handler:    _RobotService_UpdateRobotCords_Handler,
+		},
+		{
+			MethodName: "UpdateRobotName",
+			Handler:    _RobotService_UpdateRobotName_Handler,
+		},
+		{
+			MethodName: "ChangeRobotType",
+			Handler:    _RobotService_ChangeRobotType_Handler,
+		},
+		{
+			MethodName: "DeleteRobot",
+			Handler:    _RobotService_DeleteRobot_Handler,
+		},
+		{
+			MethodName: "UpdateRobotAttributes",
+			Handler:    _RobotService_UpdateRobotAttributes_Handler,
+		},
+		{
+			MethodName: "UpdateRobotLabels",
+			Handler:    _RobotService_UpdateRobotLabels_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchRobots",
+			Handler:       _RobotService_WatchRobots_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "robots.proto",
+}
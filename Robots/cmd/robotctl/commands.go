@@ -0,0 +1,371 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"RobotService/pkg/client"
+)
+
+func runCreate(ctx context.Context, a *app, args []string) error {
+	flags := a.flags("create")
+	var robot client.CreateRobotDTO
+	flags.StringVar(&robot.Name, "name", "", "robot name")
+	flags.StringVar(&robot.Type, "type", "", "robot type")
+	flags.IntVar(&robot.XCord, "x", 0, "x coordinate")
+	flags.IntVar(&robot.YCord, "y", 0, "y coordinate")
+	flags.IntVar(&robot.ZCord, "z", 0, "z coordinate")
+	if err := a.parse(flags, args, 0); err != nil {
+		return err
+	}
+	if robot.Name == "" || robot.Type == "" {
+		return errors.New("create: -name and -type are required")
+	}
+
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	id, err := c.CreateRobot(ctx, robot)
+	if err != nil {
+		return err
+	}
+	return printRobot(os.Stdout, a.output, client.Robot{
+		ID: id, Name: robot.Name, Type: robot.Type, XCord: robot.XCord, YCord: robot.YCord, ZCord: robot.ZCord,
+	})
+}
+
+func runGet(ctx context.Context, a *app, args []string) error {
+	flags := a.flags("get")
+	if err := a.parse(flags, args, 1); err != nil {
+		return err
+	}
+	id, err := parseID(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	robot, err := c.GetRobotInfo(ctx, id)
+	if err != nil {
+		return err
+	}
+	return printRobot(os.Stdout, a.output, *robot)
+}
+
+func runList(ctx context.Context, a *app, args []string) error {
+	flags := a.flags("list")
+	var opts client.ListOptions
+	flags.StringVar(&opts.Type, "type", "", "filter by robot type")
+	flags.StringVar(&opts.LabelSelector, "l", "", "label selector, e.g. site=wh2,env in (prod,stage)")
+	flags.IntVar(&opts.Limit, "limit", 0, "page size")
+	flags.IntVar(&opts.Offset, "offset", 0, "offset")
+	if err := a.parse(flags, args, 0); err != nil {
+		return err
+	}
+
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	robots, err := c.ListRobots(ctx, opts)
+	if err != nil {
+		return err
+	}
+	return printRobots(os.Stdout, a.output, robots)
+}
+
+func runMove(ctx context.Context, a *app, args []string) error {
+	flags := a.flags("move")
+	if err := a.parse(flags, args, 4); err != nil {
+		return err
+	}
+	id, err := parseID(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	cords, err := parseInts(flags.Args()[1:]...)
+	if err != nil {
+		return err
+	}
+
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	return c.UpdateRobotCords(ctx, client.UpdateRobotCordDTO{ID: id, XCord: cords[0], YCord: cords[1], ZCord: cords[2]})
+}
+
+func runRename(ctx context.Context, a *app, args []string) error {
+	flags := a.flags("rename")
+	if err := a.parse(flags, args, 2); err != nil {
+		return err
+	}
+	id, err := parseID(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	return c.UpdateRobotName(ctx, client.UpdateRobotNameDTO{ID: id, Name: flags.Arg(1)})
+}
+
+func runRetype(ctx context.Context, a *app, args []string) error {
+	flags := a.flags("retype")
+	if err := a.parse(flags, args, 2); err != nil {
+		return err
+	}
+	id, err := parseID(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	return a.pending(c.ChangeRobotType(ctx, client.ChangeTypeDTO{ID: id, Type: flags.Arg(1)}))
+}
+
+func runDelete(ctx context.Context, a *app, args []string) error {
+	flags := a.flags("delete")
+	if err := a.parse(flags, args, 1); err != nil {
+		return err
+	}
+	id, err := parseID(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	return a.pending(c.DeleteRobot(ctx, id))
+}
+
+// Изменение, ушедшее на подтверждение, не ошибка: печатаем заявку, чтобы её можно было найти
+func (a *app) pending(err error) error {
+	var pending *client.PendingApprovalError
+	if errors.As(err, &pending) {
+		return printApproval(os.Stdout, a.output, *pending.Approval)
+	}
+	return err
+}
+
+// Метки как в kubectl label: site=wh2 ставит метку, team- снимает
+func runLabel(ctx context.Context, a *app, args []string) error {
+	flags := a.flags("label")
+	if err := a.parse(flags, args, 2); err != nil {
+		return err
+	}
+	id, err := parseID(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	patch := map[string]*string{}
+	for _, change := range strings.Split(flags.Arg(1), ",") {
+		if key, value, ok := strings.Cut(change, "="); ok {
+			patch[key] = &value
+			continue
+		}
+		if key, ok := strings.CutSuffix(change, "-"); ok && key != "" {
+			patch[key] = nil
+			continue
+		}
+		return fmt.Errorf("label: %q is neither KEY=VALUE nor KEY-", change)
+	}
+
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	robot, err := c.PatchRobotLabels(ctx, id, patch)
+	if err != nil {
+		return err
+	}
+	return printRobot(os.Stdout, a.output, *robot)
+}
+
+// Опрашиваем список роботов и печатаем, что изменилось с прошлого раза
+func runWatch(ctx context.Context, a *app, args []string) error {
+	flags := a.flags("watch")
+	robotType := flags.String("type", "", "filter by robot type")
+	selector := flags.String("l", "", "label selector, e.g. site=wh2,env in (prod,stage)")
+	interval := flags.Duration("interval", 2*time.Second, "poll interval")
+	if err := a.parse(flags, args, 0); err != nil {
+		return err
+	}
+
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+
+	var known map[int]client.Robot
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	for {
+		current, err := listAll(ctx, c, client.ListOptions{Type: *robotType, LabelSelector: *selector})
+		if err != nil {
+			return err
+		}
+		if known != nil {
+			printChanges(a.output, known, current)
+		} else if err = printRobots(os.Stdout, a.output, sortedRobots(current)); err != nil {
+			return err
+		}
+		known = current
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func runImport(ctx context.Context, a *app, args []string) error {
+	flags := a.flags("import")
+	format := flags.String("format", "jsonl", "input format: jsonl or csv")
+	dryRun := flags.Bool("dry-run", false, "only validate, do not write")
+	if err := a.parse(flags, args, 1); err != nil {
+		return err
+	}
+
+	var r io.Reader = os.Stdin
+	if path := flags.Arg(0); path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		r = file
+	}
+
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	report, err := c.ImportRobots(ctx, r, *format, *dryRun)
+	if err != nil {
+		return err
+	}
+	if err = printReport(os.Stdout, a.output, *report); err != nil {
+		return err
+	}
+	if report.Failed > 0 {
+		return fmt.Errorf("import: %d of %d records failed", report.Failed, report.Total)
+	}
+	return nil
+}
+
+func runExport(ctx context.Context, a *app, args []string) error {
+	flags := a.flags("export")
+	format := flags.String("format", "jsonl", "output format: jsonl or csv")
+	selector := flags.String("l", "", "label selector, e.g. site=wh2,env in (prod,stage)")
+	output := flags.String("f", "-", "output file, - for stdout")
+	if err := a.parse(flags, args, 0); err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "-" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	return c.ExportRobots(ctx, w, *format, *selector)
+}
+
+func runConfig(_ context.Context, a *app, args []string) error {
+	if len(args) == 0 {
+		return errors.New("config: expected get-contexts, current-context, use-context or set-context")
+	}
+
+	switch args[0] {
+	case "get-contexts":
+		flags := a.flags("get-contexts")
+		if err := a.parse(flags, args[1:], 0); err != nil {
+			return err
+		}
+		// Ключи и токены не печатаем
+		type contextView struct {
+			Name    string `json:"name"`
+			Server  string `json:"server"`
+			Current bool   `json:"current"`
+		}
+		views := []contextView{}
+		for _, name := range a.cfg.names() {
+			views = append(views, contextView{Name: name, Server: a.cfg.Contexts[name].Server, Current: name == a.cfg.CurrentContext})
+		}
+		return printValue(os.Stdout, a.output, views, func(tw *tabwriter.Writer) {
+			fmt.Fprintln(tw, "CURRENT\tNAME\tSERVER")
+			for _, view := range views {
+				current := ""
+				if view.Current {
+					current = "*"
+				}
+				fmt.Fprintf(tw, "%s\t%s\t%s\n", current, view.Name, view.Server)
+			}
+		})
+
+	case "current-context":
+		if a.cfg.CurrentContext == "" {
+			return errors.New("current context is not set")
+		}
+		fmt.Println(a.cfg.CurrentContext)
+		return nil
+
+	case "use-context":
+		if len(args) != 2 {
+			return errors.New("config use-context: expected context name")
+		}
+		if _, ok := a.cfg.Contexts[args[1]]; !ok {
+			return fmt.Errorf("context %q not found", args[1])
+		}
+		a.cfg.CurrentContext = args[1]
+		return a.cfg.save()
+
+	case "set-context":
+		if len(args) < 2 {
+			return errors.New("config set-context: expected context name")
+		}
+		name := args[1]
+		entry := a.cfg.Contexts[name]
+		flags := flag.NewFlagSet("set-context", flag.ContinueOnError)
+		flags.StringVar(&entry.Server, "server", entry.Server, "RobotService base url")
+		flags.StringVar(&entry.APIKey, "api-key", entry.APIKey, "API key")
+		flags.StringVar(&entry.Token, "token", entry.Token, "JWT bearer token")
+		if err := flags.Parse(args[2:]); err != nil {
+			return err
+		}
+		a.cfg.Contexts[name] = entry
+		if a.cfg.CurrentContext == "" {
+			a.cfg.CurrentContext = name
+		}
+		return a.cfg.save()
+	}
+	return fmt.Errorf("config: unknown subcommand %q", args[0])
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+const defaultServer = "http://localhost:8083"
+
+// Именованный контекст: куда ходить и чем авторизоваться
+type Context struct {
+	Server string `yaml:"server"`
+	APIKey string `yaml:"api-key,omitempty"`
+	Token  string `yaml:"token,omitempty"`
+}
+
+// Файл ~/.robotctl.yaml (или путь из ROBOTCTL_CONFIG)
+type Config struct {
+	CurrentContext string             `yaml:"current-context"`
+	Contexts       map[string]Context `yaml:"contexts"`
+
+	path string
+}
+
+func configPath() string {
+	if path := os.Getenv("ROBOTCTL_CONFIG"); path != "" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".robotctl.yaml"
+	}
+	return filepath.Join(home, ".robotctl.yaml")
+}
+
+// Если файла нет, работаем с локальным сервером по умолчанию
+func loadConfig() (*Config, error) {
+	cfg := &Config{Contexts: map[string]Context{}, path: configPath()}
+	data, err := os.ReadFile(cfg.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", cfg.path, err)
+	}
+	if cfg.Contexts == nil {
+		cfg.Contexts = map[string]Context{}
+	}
+	return cfg, nil
+}
+
+func (cfg *Config) save() error {
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(cfg.path, data, 0o600)
+}
+
+// Контекст по имени из флага, иначе текущий, иначе localhost
+func (cfg *Config) resolve(name string) (Context, error) {
+	if name == "" {
+		name = cfg.CurrentContext
+	}
+	if name == "" {
+		return Context{Server: defaultServer}, nil
+	}
+	ctx, ok := cfg.Contexts[name]
+	if !ok {
+		return Context{}, fmt.Errorf("context %q not found in %s", name, cfg.path)
+	}
+	if ctx.Server == "" {
+		ctx.Server = defaultServer
+	}
+	return ctx, nil
+}
+
+func (cfg *Config) names() []string {
+	names := make([]string, 0, len(cfg.Contexts))
+	for name := range cfg.Contexts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "robotctl.yaml")
+	t.Setenv("ROBOTCTL_CONFIG", path)
+
+	// Без файла - пустой конфиг и локальный сервер
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ctx, err := cfg.resolve(""); err != nil || ctx.Server != defaultServer {
+		t.Fatalf("no config: %+v, err = %v", ctx, err)
+	}
+
+	cfg.Contexts["prod"] = Context{Server: "https://robots.example.com", Token: "jwt"}
+	cfg.Contexts["local"] = Context{APIKey: "key"}
+	cfg.CurrentContext = "prod"
+	if err = cfg.save(); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Fatalf("config file %v, err = %v", info, err)
+	}
+
+	loaded, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded.Contexts, cfg.Contexts) || !reflect.DeepEqual(loaded.names(), []string{"local", "prod"}) {
+		t.Fatalf("loaded = %+v", loaded)
+	}
+	tests := []struct {
+		name string
+		want Context
+	}{
+		{"", Context{Server: "https://robots.example.com", Token: "jwt"}},
+		{"local", Context{Server: defaultServer, APIKey: "key"}},
+	}
+	for _, tt := range tests {
+		if ctx, err := loaded.resolve(tt.name); err != nil || ctx != tt.want {
+			t.Errorf("resolve(%q) = %+v, err = %v", tt.name, ctx, err)
+		}
+	}
+	if _, err = loaded.resolve("staging"); err == nil {
+		t.Fatal("unknown context resolved")
+	}
+
+	if err = os.WriteFile(path, []byte("contexts: [broken"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = loadConfig(); err == nil {
+		t.Fatal("broken config loaded")
+	}
+}
@@ -0,0 +1,187 @@
+// robotctl - консольная утилита для операторов поверх HTTP API RobotService
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+
+	"RobotService/pkg/client"
+)
+
+const usage = `usage: robotctl <command> [flags] [args]
+
+commands:
+  create   -name NAME -type TYPE [-x X -y Y -z Z]
+  get      ID
+  list     [-type TYPE] [-l SELECTOR] [-limit N] [-offset N]
+  move     ID X Y Z
+  rename   ID NAME
+  retype   ID TYPE
+  delete   ID
+  label    ID KEY=VALUE,KEY-,...
+  watch    [-type TYPE] [-l SELECTOR] [-interval 2s]
+  import   [-format jsonl|csv] [-dry-run] FILE
+  export   [-format jsonl|csv] [-l SELECTOR] [-f FILE]
+  config   get-contexts | current-context | use-context NAME | set-context NAME -server URL [-api-key KEY] [-token JWT]
+
+common flags:
+  -o table|json|yaml   output format (default table)
+  -context NAME        context from ~/.robotctl.yaml (or $ROBOTCTL_CONFIG)
+`
+
+// Общие для всех команд флаги
+type globals struct {
+	output  string
+	context string
+}
+
+type command func(ctx context.Context, app *app, args []string) error
+
+var commands = map[string]command{
+	"create": runCreate,
+	"get":    runGet,
+	"list":   runList,
+	"move":   runMove,
+	"rename": runRename,
+	"retype": runRetype,
+	"delete": runDelete,
+	"label":  runLabel,
+	"watch":  runWatch,
+	"import": runImport,
+	"export": runExport,
+	"config": runConfig,
+}
+
+// Всё, что нужно командам: общие флаги и конфиг с контекстами
+type app struct {
+	globals
+	cfg *Config
+}
+
+// Заводим FlagSet команды сразу с общими флагами
+func (a *app) flags(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.StringVar(&a.output, "o", outputTable, "output format: table, json or yaml")
+	flags.StringVar(&a.context, "context", "", "context name")
+	return flags
+}
+
+// Флаги можно писать и после аргументов: robotctl get 5 -o json
+func (a *app) parse(flags *flag.FlagSet, args []string, positional int) error {
+	flagArgs, rest := splitArgs(flags, args)
+	if err := flags.Parse(flagArgs); err != nil {
+		return err
+	}
+	if err := checkOutput(a.output); err != nil {
+		return err
+	}
+	if len(rest) != positional {
+		return fmt.Errorf("%s: expected %d argument(s), got %d", flags.Name(), positional, len(rest))
+	}
+	// Возвращаем аргументы во FlagSet, чтобы команды читали их через flags.Arg
+	return flags.Parse(append(flagArgs, append([]string{"--"}, rest...)...))
+}
+
+// Делим аргументы на флаги и позиционные. Отрицательные числа - это координаты, а не флаги
+func splitArgs(flags *flag.FlagSet, args []string) ([]string, []string) {
+	var flagArgs, rest []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			rest = append(rest, args[i+1:]...)
+			break
+		}
+		if _, err := strconv.Atoi(arg); err == nil || !strings.HasPrefix(arg, "-") || arg == "-" {
+			rest = append(rest, arg)
+			continue
+		}
+		flagArgs = append(flagArgs, arg)
+		name := strings.TrimLeft(arg, "-")
+		if strings.Contains(name, "=") {
+			continue
+		}
+		// У небулевых флагов значение идёт следующим аргументом
+		if f := flags.Lookup(name); f != nil && !isBoolFlag(f) && i+1 < len(args) {
+			i++
+			flagArgs = append(flagArgs, args[i])
+		}
+	}
+	return flagArgs, rest
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+func (a *app) client() (*client.Client, error) {
+	ctx, err := a.cfg.resolve(a.context)
+	if err != nil {
+		return nil, err
+	}
+	var opts []client.Option
+	switch {
+	case ctx.Token != "":
+		opts = append(opts, client.WithAuth(client.BearerToken(ctx.Token)))
+	case ctx.APIKey != "":
+		opts = append(opts, client.WithAuth(client.APIKey(ctx.APIKey)))
+	}
+	return client.New(ctx.Server, opts...), nil
+}
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] == "-h" || os.Args[1] == "help" {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	run, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "robotctl:", err)
+		os.Exit(1)
+	}
+
+	// Ctrl+C отменяет запрос (и останавливает watch)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	err = run(ctx, &app{cfg: cfg}, os.Args[2:])
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(2)
+	}
+	if err != nil && !errors.Is(err, context.Canceled) {
+		fmt.Fprintln(os.Stderr, "robotctl:", err)
+		os.Exit(1)
+	}
+}
+
+func parseID(value string) (int, error) {
+	id, err := strconv.Atoi(value)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("invalid robot id %q", value)
+	}
+	return id, nil
+}
+
+func parseInts(values ...string) ([]int, error) {
+	result := make([]int, len(values))
+	for i, value := range values {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", value)
+		}
+		result[i] = n
+	}
+	return result, nil
+}
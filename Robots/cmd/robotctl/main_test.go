@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"reflect"
+	"testing"
+)
+
+func TestSplitArgs(t *testing.T) {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.String("o", "", "")
+	flags.Int("x", 0, "")
+	flags.Bool("dry-run", false, "")
+
+	tests := []struct {
+		name        string
+		args        []string
+		flags, rest []string
+	}{
+		{"negative coordinates", []string{"5", "-5", "0", "-12"}, nil, []string{"5", "-5", "0", "-12"}},
+		{"flags after positional", []string{"5", "-o", "json"}, []string{"-o", "json"}, []string{"5"}},
+		{"flag value is negative", []string{"-x", "-5", "robot"}, []string{"-x", "-5"}, []string{"robot"}},
+		{"bool flag takes no value", []string{"-dry-run", "robots.csv"}, []string{"-dry-run"}, []string{"robots.csv"}},
+		{"value after equals", []string{"--o=yaml", "7"}, []string{"--o=yaml"}, []string{"7"}},
+		{"stdin dash", []string{"-", "-o", "json"}, []string{"-o", "json"}, []string{"-"}},
+		{"after double dash", []string{"-o", "json", "--", "-name", "x"}, []string{"-o", "json"}, []string{"-name", "x"}},
+	}
+	for _, tt := range tests {
+		gotFlags, gotRest := splitArgs(flags, tt.args)
+		if !reflect.DeepEqual(gotFlags, tt.flags) || !reflect.DeepEqual(gotRest, tt.rest) {
+			t.Errorf("%s: flags %q, rest %q, want %q, %q", tt.name, gotFlags, gotRest, tt.flags, tt.rest)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	a := &app{cfg: &Config{}}
+	flags := a.flags("move")
+	if err := a.parse(flags, []string{"3", "-10", "-o", "yaml", "-20", "0"}, 4); err != nil {
+		t.Fatal(err)
+	}
+	if a.output != outputYAML || !reflect.DeepEqual(flags.Args(), []string{"3", "-10", "-20", "0"}) {
+		t.Fatalf("output %q, args %q", a.output, flags.Args())
+	}
+	cords, err := parseInts(flags.Args()[1:]...)
+	if err != nil || !reflect.DeepEqual(cords, []int{-10, -20, 0}) {
+		t.Fatalf("cords = %v, err = %v", cords, err)
+	}
+
+	for _, args := range [][]string{{"3", "1", "2"}, {"3", "1", "2", "3", "4"}, {"3", "1", "2", "3", "-o", "xml"}} {
+		a = &app{cfg: &Config{}}
+		if err = a.parse(a.flags("move"), args, 4); err == nil {
+			t.Errorf("parse(%q): no error", args)
+		}
+	}
+}
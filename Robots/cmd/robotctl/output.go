@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"RobotService/pkg/client"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+func checkOutput(format string) error {
+	switch format {
+	case outputTable, outputJSON, outputYAML:
+		return nil
+	}
+	return fmt.Errorf("unknown output %q, expected table, json or yaml", format)
+}
+
+// Печатаем значение в нужном формате. Для таблицы нужен отдельный принтер
+func printValue(w io.Writer, format string, value any, table func(tw *tabwriter.Writer)) error {
+	switch format {
+	case outputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(value)
+	case outputYAML:
+		return printYAML(w, value)
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	table(tw)
+	return tw.Flush()
+}
+
+// Гоняем через JSON, чтобы в YAML были те же имена полей, что и в API
+func printYAML(w io.Writer, value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	var generic any
+	if err = yaml.Unmarshal(data, &generic); err != nil {
+		return err
+	}
+	out, err := yaml.Marshal(generic)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+func printRobots(w io.Writer, format string, robots []client.Robot) error {
+	return printValue(w, format, robots, func(tw *tabwriter.Writer) {
+		fmt.Fprintln(tw, "ID\tNAME\tTYPE\tX\tY\tZ\tLABELS")
+		for _, robot := range robots {
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%d\t%d\t%d\t%s\n", robot.ID, robot.Name, robot.Type, robot.XCord, robot.YCord, robot.ZCord, formatLabels(robot.Labels))
+		}
+	})
+}
+
+// site=wh2,team=picking по порядку ключей, без меток - прочерк
+func formatLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return "-"
+	}
+	pairs := make([]string, 0, len(labels))
+	for key, value := range labels {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func printRobot(w io.Writer, format string, robot client.Robot) error {
+	if format == outputTable {
+		return printRobots(w, format, []client.Robot{robot})
+	}
+	return printValue(w, format, robot, nil)
+}
+
+func printApproval(w io.Writer, format string, approval client.Approval) error {
+	return printValue(w, format, approval, func(tw *tabwriter.Writer) {
+		fmt.Fprintf(tw, "approval %d %s, expires %s\n", approval.ID, approval.Status, approval.ExpiresAt.Local().Format(time.RFC3339))
+	})
+}
+
+func printReport(w io.Writer, format string, report client.ImportReport) error {
+	return printValue(w, format, report, func(tw *tabwriter.Writer) {
+		fmt.Fprintf(tw, "DRY RUN\tTOTAL\tIMPORTED\tFAILED\n%t\t%d\t%d\t%d\n", report.DryRun, report.Total, report.Imported, report.Failed)
+		if len(report.Errors) == 0 {
+			return
+		}
+		fmt.Fprintln(tw, "\nLINE\tID\tERROR")
+		for _, e := range report.Errors {
+			fmt.Fprintf(tw, "%d\t%s\t%s\n", e.Line, optionalID(e.ID), e.Error)
+		}
+	})
+}
+
+func optionalID(id int) string {
+	if id == 0 {
+		return "-"
+	}
+	return strconv.Itoa(id)
+}
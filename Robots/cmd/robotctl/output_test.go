@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"RobotService/internal/entities"
+	"RobotService/pkg/client"
+)
+
+func TestPrintApproval(t *testing.T) {
+	expires := time.Date(2026, 10, 20, 12, 0, 0, 0, time.Local)
+	approval := client.Approval{ID: 12, Action: "robots:delete", RobotID: 3, Status: "pending", ExpiresAt: expires}
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{outputTable, "approval 12 pending, expires " + expires.Format(time.RFC3339) + "\n"},
+		{outputJSON, `"status": "pending"`},
+		{outputYAML, "status: pending\n"},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		if err := printApproval(&out, tt.format, approval); err != nil {
+			t.Fatalf("%s: %v", tt.format, err)
+		}
+		if !strings.Contains(out.String(), tt.want) {
+			t.Fatalf("%s: output %q, want %q", tt.format, out.String(), tt.want)
+		}
+	}
+}
+
+func TestPrintRobots(t *testing.T) {
+	robots := []client.Robot{
+		{ID: 1, Name: "r1", Type: "rover", XCord: -5, Labels: map[string]string{"team": "b", "site": "a"}},
+		{ID: 22, Name: "drone-2", Type: "drone", ZCord: 40},
+	}
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{outputTable, "ID  NAME     TYPE   X   Y  Z   LABELS\n" +
+			"1   r1       rover  -5  0  0   site=a,team=b\n" +
+			"22  drone-2  drone  0   0  40  -\n"},
+		{outputJSON, "[\n  {\n    \"id\": 1,\n    \"name\": \"r1\","},
+		{outputYAML, "- id: 1\n  labels:\n    site: a\n    team: b\n  name: r1\n"},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		if err := printRobots(&out, tt.format, robots); err != nil {
+			t.Fatalf("%s: %v", tt.format, err)
+		}
+		if !strings.HasPrefix(out.String(), tt.want) {
+			t.Fatalf("%s: output %q, want prefix %q", tt.format, out.String(), tt.want)
+		}
+	}
+
+	// Один робот в JSON и YAML - объект, а не список
+	var out bytes.Buffer
+	if err := printRobot(&out, outputYAML, robots[1]); err != nil || !strings.HasPrefix(out.String(), "id: 22\n") {
+		t.Fatalf("printRobot: %q, err = %v", out.String(), err)
+	}
+}
+
+func TestPrintReport(t *testing.T) {
+	report := client.ImportReport{Total: 3, Imported: 1, Failed: 2, Errors: []entities.ImportError{
+		{Line: 2, ID: 7, Error: "name is required"},
+		{Line: 3, Error: "type is required"},
+	}}
+	var out bytes.Buffer
+	if err := printReport(&out, outputTable, report); err != nil {
+		t.Fatal(err)
+	}
+	// Пустая строка начинает новый блок колонок
+	want := "DRY RUN  TOTAL  IMPORTED  FAILED\n" +
+		"false    3      1         2\n" +
+		"\n" +
+		"LINE  ID  ERROR\n" +
+		"2     7   name is required\n" +
+		"3     -   type is required\n"
+	if out.String() != want {
+		t.Fatalf("output %q, want %q", out.String(), want)
+	}
+	if err := checkOutput("xml"); err == nil {
+		t.Fatal("xml output accepted")
+	}
+}
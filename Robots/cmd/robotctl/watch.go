@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"time"
+
+	"RobotService/pkg/client"
+)
+
+const watchPageSize = 1000
+
+// Забираем все страницы списка под фильтр opts, страницы листаем сами
+func listAll(ctx context.Context, c *client.Client, opts client.ListOptions) (map[int]client.Robot, error) {
+	robots := map[int]client.Robot{}
+	opts.Limit = watchPageSize
+	for opts.Offset = 0; ; opts.Offset += watchPageSize {
+		page, err := c.ListRobots(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, robot := range page {
+			robots[robot.ID] = robot
+		}
+		if len(page) < watchPageSize {
+			return robots, nil
+		}
+	}
+}
+
+func sortedRobots(robots map[int]client.Robot) []client.Robot {
+	list := make([]client.Robot, 0, len(robots))
+	for _, robot := range robots {
+		list = append(list, robot)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+// Одно изменение для вывода в json/yaml
+type watchEvent struct {
+	Time   time.Time     `json:"time"`
+	Event  string        `json:"event"`
+	Robot  client.Robot  `json:"robot"`
+	Before *client.Robot `json:"before,omitempty"`
+}
+
+func printChanges(format string, before, after map[int]client.Robot) {
+	var events []watchEvent
+	now := time.Now()
+	for _, robot := range sortedRobots(after) {
+		old, ok := before[robot.ID]
+		switch {
+		case !ok:
+			events = append(events, watchEvent{Time: now, Event: "created", Robot: robot})
+		case !reflect.DeepEqual(old, robot):
+			events = append(events, watchEvent{Time: now, Event: "updated", Robot: robot, Before: &old})
+		}
+	}
+	for _, robot := range sortedRobots(before) {
+		if _, ok := after[robot.ID]; !ok {
+			events = append(events, watchEvent{Time: now, Event: "deleted", Robot: robot})
+		}
+	}
+
+	for _, event := range events {
+		if format != outputTable {
+			_ = printValue(os.Stdout, format, event, nil)
+			continue
+		}
+		robot := event.Robot
+		fmt.Printf("%s  %-8s id=%d name=%s type=%s x=%d y=%d z=%d\n", event.Time.Format(time.TimeOnly), event.Event,
+			robot.ID, robot.Name, robot.Type, robot.XCord, robot.YCord, robot.ZCord)
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+
+	"RobotService/internal/auth"
+	"RobotService/internal/entities"
+	"RobotService/internal/repositories"
+)
+
+// robotsrv apikey create|list|revoke
+func runAPIKey(log *slog.Logger, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: robotsrv apikey [create|list|revoke] [flags]")
+		return 2
+	}
+	switch args[0] {
+	case "create":
+		return runAPIKeyCreate(log, args[1:])
+	case "list":
+		return runAPIKeyList(log, args[1:])
+	case "revoke":
+		return runAPIKeyRevoke(log, args[1:])
+	}
+	fmt.Fprintf(os.Stderr, "unknown apikey command %q\n", args[0])
+	return 2
+}
+
+// robotsrv apikey create -name ci [-roles admin,operator] [-db url]
+// Ключ печатается один раз, в базе остаётся только его хэш
+func runAPIKeyCreate(log *slog.Logger, args []string) int {
+	flags := flag.NewFlagSet("apikey create", flag.ExitOnError)
+	name := flags.String("name", "", "key owner, shows up as principal in logs and events")
+	roles := flags.String("roles", "", "comma separated roles")
+	dbURL := flags.String("db", defaultDatabaseURL(), "postgres connection url")
+	_ = flags.Parse(args)
+
+	if *name == "" {
+		fmt.Fprintln(os.Stderr, "-name is required")
+		return 2
+	}
+
+	key, prefix, hash, err := auth.GenerateAPIKey()
+	if err != nil {
+		log.Error("Unable to generate key", "error", err.Error())
+		return 1
+	}
+
+	db := connectDatabase(log, *dbURL)
+	defer db.Close()
+	repo := repositories.APIKeyRepository{DataBase: db}
+
+	created, err := repo.CreateAPIKey(entities.APIKey{Name: *name, Prefix: prefix, Roles: splitRoles(*roles)}, hash)
+	if err != nil {
+		log.Error("Unable to save key", "error", err.Error())
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "created key %d for %s, store it now - it cannot be shown again\n", created.ID, created.Name)
+	fmt.Println(key)
+	return 0
+}
+
+// robotsrv apikey list [-db url]
+func runAPIKeyList(log *slog.Logger, args []string) int {
+	flags := flag.NewFlagSet("apikey list", flag.ExitOnError)
+	dbURL := flags.String("db", defaultDatabaseURL(), "postgres connection url")
+	_ = flags.Parse(args)
+
+	db := connectDatabase(log, *dbURL)
+	defer db.Close()
+	repo := repositories.APIKeyRepository{DataBase: db}
+
+	keys, err := repo.ListAPIKeys()
+	if err != nil {
+		log.Error("Unable to list keys", "error", err.Error())
+		return 1
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(keys)
+	return 0
+}
+
+// robotsrv apikey revoke [-db url] ID
+func runAPIKeyRevoke(log *slog.Logger, args []string) int {
+	flags := flag.NewFlagSet("apikey revoke", flag.ExitOnError)
+	dbURL := flags.String("db", defaultDatabaseURL(), "postgres connection url")
+	_ = flags.Parse(args)
+
+	id, err := strconv.Atoi(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "usage: robotsrv apikey revoke [-db url] ID")
+		return 2
+	}
+
+	db := connectDatabase(log, *dbURL)
+	defer db.Close()
+	repo := repositories.APIKeyRepository{DataBase: db}
+
+	if err = repo.RevokeAPIKey(id); err != nil {
+		log.Error("Unable to revoke key", "id", id, "error", err.Error())
+		return 1
+	}
+	return 0
+}
+
+func splitRoles(value string) []string {
+	roles := []string{}
+	for _, role := range strings.Split(value, ",") {
+		if role = strings.TrimSpace(role); role != "" {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"RobotService/internal/auth"
+	"RobotService/internal/rabbit"
+	"RobotService/internal/repositories"
+	"RobotService/internal/robotio"
+	"RobotService/internal/services"
+	"RobotService/internal/sorrage"
+)
+
+// Разбираем подкоманду и возвращаем код выхода
+func runCommand(log *slog.Logger, name string, args []string) int {
+	switch name {
+	case "export":
+		return runExport(log, args)
+	case "import":
+		return runImport(log, args)
+	case "apikey":
+		return runAPIKey(log, args)
+	}
+	fmt.Fprintf(os.Stderr, "unknown command %q\nusage: robotsrv [export|import|apikey] [flags]\n", name)
+	return 2
+}
+
+// robotsrv export [-format jsonl|csv] [-l selector] [-o file] [-db url]
+func runExport(log *slog.Logger, args []string) int {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	format := flags.String("format", robotio.FormatJSONL, "output format: jsonl or csv")
+	output := flags.String("o", "-", "output file, - for stdout")
+	selector := flags.String("l", "", "label selector, e.g. site=wh2,env in (prod,stage)")
+	dbURL := flags.String("db", defaultDatabaseURL(), "postgres connection url")
+	_ = flags.Parse(args)
+
+	parsedFormat, err := robotio.ParseFormat(*format)
+	if err != nil {
+		log.Error("Bad format", "error", err.Error())
+		return 2
+	}
+	filter, err := services.ParseRobotFilter("", *selector, nil)
+	if err != nil {
+		log.Error("Bad label selector", "error", err.Error())
+		return 2
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "-" {
+		file, err := os.Create(*output)
+		if err != nil {
+			log.Error("Unable to create output file", "error", err.Error())
+			return 1
+		}
+		defer file.Close()
+		w = file
+	}
+
+	db := connectDatabase(log, *dbURL)
+	defer db.Close()
+	repo := repositories.RobotRepositories{DataBase: db}
+
+	if err = robotio.Export(&repo, w, parsedFormat, filter); err != nil {
+		log.Error("Export failed", "error", err.Error())
+		return 1
+	}
+	return 0
+}
+
+// robotsrv import [-format jsonl|csv] [-dry-run] [-db url] [-redis addr] [-amqp url] [file]
+// Импорт идёт через тот же сервис, что и POST /robots/import: проверки типов и карты, сброс кэша,
+// аудит и событие robots.Import. Индекс позиций у запущенного сервера свой и строится при старте,
+// поэтому после импорта сервер нужно перезапустить (или импортировать через HTTP)
+func runImport(log *slog.Logger, args []string) int {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	format := flags.String("format", robotio.FormatJSONL, "input format: jsonl or csv")
+	dryRun := flags.Bool("dry-run", false, "only validate, do not write")
+	dbURL := flags.String("db", defaultDatabaseURL(), "postgres connection url")
+	redisAddr := flags.String("redis", defaultRedisAddr, "redis address, cached robots are invalidated there")
+	amqpURL := flags.String("amqp", defaultRabbitURL, "rabbitmq url for the robots.Import event, empty to skip")
+	_ = flags.Parse(args)
+
+	parsedFormat, err := robotio.ParseFormat(*format)
+	if err != nil {
+		log.Error("Bad format", "error", err.Error())
+		return 2
+	}
+
+	var r io.Reader = os.Stdin
+	if path := flags.Arg(0); path != "" && path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			log.Error("Unable to open input file", "error", err.Error())
+			return 1
+		}
+		defer file.Close()
+		r = file
+	}
+
+	db := connectDatabase(log, *dbURL)
+	defer db.Close()
+	auditSrvc := &services.AuditService{Repository: repositories.AuditRepository{DataBase: db}}
+	service := services.RbtSrvic{
+		RobotRepository: repositories.RobotRepositories{DataBase: db},
+		Audit:           auditSrvc,
+		Types:           &services.RobotTypeService{Repository: repositories.RobotTypeRepository{DataBase: db}, Audit: auditSrvc},
+		World:           &services.WorldService{Repository: repositories.WorldRepository{DataBase: db}, Audit: auditSrvc},
+	}
+	if !*dryRun {
+		// Без редиски перезаписанные роботы ещё пять минут отдавались бы из кэша старыми
+		service.Redis = sorrage.NewClient(*redisAddr)
+		if err = service.Redis.Ping(); err != nil {
+			log.Error("Unable to connect to Redis", "error", err.Error())
+			return 1
+		}
+		if *amqpURL != "" {
+			if service.Rabbit, err = rabbit.NewPublisher(*amqpURL); err != nil {
+				log.Warn("Unable to connect to RabbitMQ, robots.Import will not be published", "error", err.Error())
+			}
+			defer service.Rabbit.Close()
+		}
+	}
+
+	ctx := auth.WithPrincipal(context.Background(), cliPrincipal())
+	report, err := service.ImportRobots(ctx, r, parsedFormat, *dryRun)
+
+	// Отчёт печатаем в stdout, чтобы его можно было сохранить
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(report)
+
+	if err != nil {
+		log.Error("Import aborted", "error", err.Error())
+		return 1
+	}
+	if !*dryRun && report.Imported > 0 {
+		log.Warn("Restart robotsrv to rebuild its spatial index, /robots/near and collision checks do not see imported positions until then")
+	}
+	if report.Failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// В аудите импорт из командной строки записывается на пользователя ОС
+func cliPrincipal() *auth.Principal {
+	user := os.Getenv("USER")
+	if user == "" {
+		user = "robotsrv"
+	}
+	return &auth.Principal{Subject: user, Method: "cli"}
+}
@@ -0,0 +1,145 @@
+package allocation
+
+import (
+	"cmp"
+	"math"
+	"slices"
+)
+
+// Нет назначения для строки
+const Unassigned = -1
+
+// Венгерский алгоритм (потенциалы, O(n^2 m)): строки - задачи, столбцы - исполнители, cost[i][j] - цена пары,
+// +Inf - пара запрещена. Возвращает для каждой строки столбец или Unassigned. Минимизирует суммарную цену
+// при наибольшем числе назначений: запрещённые пары считаются очень дорогими и потом выбрасываются
+func Hungarian(cost [][]float64) []int {
+	rows := len(cost)
+	result := make([]int, rows)
+	for i := range result {
+		result[i] = Unassigned
+	}
+	if rows == 0 || len(cost[0]) == 0 {
+		return result
+	}
+	cols := len(cost[0])
+
+	// Запрет дороже любого полного назначения из разрешённых пар
+	forbidden := 1.0
+	for _, row := range cost {
+		for _, c := range row {
+			if !math.IsInf(c, 1) {
+				forbidden += math.Abs(c)
+			}
+		}
+	}
+	forbidden *= float64(max(rows, cols)) + 1
+
+	// Алгоритм ниже хочет строк не больше, чем столбцов
+	transposed := rows > cols
+	n, m := rows, cols
+	if transposed {
+		n, m = cols, rows
+	}
+	at := func(i, j int) float64 {
+		if transposed {
+			i, j = j, i
+		}
+		c := cost[i][j]
+		if math.IsInf(c, 1) {
+			return forbidden
+		}
+		return c
+	}
+
+	// Индексы с 1, p[j] - строка на столбце j, u и v - потенциалы
+	u := make([]float64, n+1)
+	v := make([]float64, m+1)
+	p := make([]int, m+1)
+	way := make([]int, m+1)
+	for i := 1; i <= n; i++ {
+		p[0] = i
+		j0 := 0
+		minv := make([]float64, m+1)
+		used := make([]bool, m+1)
+		for j := range minv {
+			minv[j] = math.Inf(1)
+		}
+		for {
+			used[j0] = true
+			i0, delta, j1 := p[j0], math.Inf(1), 0
+			for j := 1; j <= m; j++ {
+				if used[j] {
+					continue
+				}
+				if cur := at(i0-1, j-1) - u[i0] - v[j]; cur < minv[j] {
+					minv[j], way[j] = cur, j0
+				}
+				if minv[j] < delta {
+					delta, j1 = minv[j], j
+				}
+			}
+			for j := 0; j <= m; j++ {
+				if used[j] {
+					u[p[j]] += delta
+					v[j] -= delta
+				} else {
+					minv[j] -= delta
+				}
+			}
+			j0 = j1
+			if p[j0] == 0 {
+				break
+			}
+		}
+		for j0 != 0 {
+			j1 := way[j0]
+			p[j0] = p[j1]
+			j0 = j1
+		}
+	}
+
+	for j := 1; j <= m; j++ {
+		if p[j] == 0 {
+			continue
+		}
+		row, col := p[j]-1, j-1
+		if transposed {
+			row, col = col, row
+		}
+		if !math.IsInf(cost[row][col], 1) {
+			result[row] = col
+		}
+	}
+	return result
+}
+
+// Жадное назначение: берём самые дешёвые разрешённые пары, пока есть свободные строки и столбцы.
+// Для потока задач, где ждать пачки для венгерского алгоритма не хочется
+func Greedy(cost [][]float64) []int {
+	type pair struct {
+		row, col int
+		cost     float64
+	}
+	pairs := []pair{}
+	for i, row := range cost {
+		for j, c := range row {
+			if !math.IsInf(c, 1) {
+				pairs = append(pairs, pair{i, j, c})
+			}
+		}
+	}
+	slices.SortStableFunc(pairs, func(a, b pair) int { return cmp.Compare(a.cost, b.cost) })
+
+	result := make([]int, len(cost))
+	for i := range result {
+		result[i] = Unassigned
+	}
+	taken := map[int]bool{}
+	for _, p := range pairs {
+		if result[p.row] == Unassigned && !taken[p.col] {
+			result[p.row] = p.col
+			taken[p.col] = true
+		}
+	}
+	return result
+}
@@ -0,0 +1,184 @@
+package allocation
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+var inf = math.Inf(1)
+
+// Число назначений и их суммарная цена. Заодно проверяем, что столбцы не повторяются и пары разрешены
+func score(t *testing.T, cost [][]float64, result []int) (int, float64) {
+	t.Helper()
+	if len(result) != len(cost) {
+		t.Fatalf("result has %d rows, want %d", len(result), len(cost))
+	}
+	taken := map[int]bool{}
+	count, total := 0, 0.0
+	for row, col := range result {
+		if col == Unassigned {
+			continue
+		}
+		if col < 0 || col >= len(cost[row]) || taken[col] {
+			t.Fatalf("row %d: bad or repeated column %d in %v", row, col, result)
+		}
+		if math.IsInf(cost[row][col], 1) {
+			t.Fatalf("row %d: forbidden pair with column %d", row, col)
+		}
+		taken[col] = true
+		count++
+		total += cost[row][col]
+	}
+	return count, total
+}
+
+// Перебором: сначала больше назначений, потом меньше цена
+func bruteForce(cost [][]float64) (int, float64) {
+	cols := 0
+	if len(cost) > 0 {
+		cols = len(cost[0])
+	}
+	bestCount, bestTotal := 0, 0.0
+	taken := make([]bool, cols)
+	var walk func(row, count int, total float64)
+	walk = func(row, count int, total float64) {
+		if row == len(cost) {
+			if count > bestCount || (count == bestCount && total < bestTotal) {
+				bestCount, bestTotal = count, total
+			}
+			return
+		}
+		walk(row+1, count, total)
+		for col := 0; col < cols; col++ {
+			if !taken[col] && !math.IsInf(cost[row][col], 1) {
+				taken[col] = true
+				walk(row+1, count+1, total+cost[row][col])
+				taken[col] = false
+			}
+		}
+	}
+	walk(0, 0, 0)
+	return bestCount, bestTotal
+}
+
+func TestHungarian(t *testing.T) {
+	tests := []struct {
+		name string
+		cost [][]float64
+		want []int
+	}{
+		{name: "empty", cost: nil, want: []int{}},
+		{name: "no columns", cost: [][]float64{{}, {}}, want: []int{Unassigned, Unassigned}},
+		{name: "square", cost: [][]float64{{4, 1, 3}, {2, 0, 5}, {3, 2, 2}}, want: []int{1, 0, 2}},
+		{name: "more columns", cost: [][]float64{{5, 1, 9, 2}, {1, 3, 9, 9}}, want: []int{1, 0}},
+		{name: "more rows", cost: [][]float64{{5, 4}, {1, 3}, {0, 0}}, want: []int{Unassigned, 0, 1}},
+		{name: "single column", cost: [][]float64{{3}, {1}, {2}}, want: []int{Unassigned, 0, Unassigned}},
+		{name: "forbidden pair", cost: [][]float64{{1, inf}, {inf, 1}}, want: []int{0, 1}},
+		{name: "all forbidden", cost: [][]float64{{inf, inf}, {inf, inf}}, want: []int{Unassigned, Unassigned}},
+		{name: "row without pairs", cost: [][]float64{{inf, inf}, {2, 1}}, want: []int{Unassigned, 1}},
+		// Дешёвая пара 0-0 лишила бы строку 1 единственного робота: больше назначений важнее цены
+		{name: "more assignments beat cheaper", cost: [][]float64{{1, 100}, {5, inf}}, want: []int{1, 0}},
+		{name: "forbidden in tall matrix", cost: [][]float64{{inf}, {inf}, {7}}, want: []int{Unassigned, Unassigned, 0}},
+		{name: "negative costs", cost: [][]float64{{-5, 0}, {0, -1}}, want: []int{0, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Hungarian(tt.cost)
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("Hungarian = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHungarianMatchesBruteForce(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	for iter := 0; iter < 2000; iter++ {
+		rows, cols := 1+random.Intn(5), 1+random.Intn(5)
+		cost := make([][]float64, rows)
+		for i := range cost {
+			cost[i] = make([]float64, cols)
+			for j := range cost[i] {
+				switch {
+				case random.Intn(4) == 0:
+					cost[i][j] = inf
+				case iter%2 == 0:
+					// Целые цены дают много равных вариантов
+					cost[i][j] = float64(random.Intn(5))
+				default:
+					cost[i][j] = random.Float64() * 1000
+				}
+			}
+		}
+		wantCount, wantTotal := bruteForce(cost)
+		count, total := score(t, cost, Hungarian(cost))
+		if count != wantCount || math.Abs(total-wantTotal) > 1e-6 {
+			t.Fatalf("cost %v: %d pairs for %v, brute force %d pairs for %v", cost, count, total, wantCount, wantTotal)
+		}
+	}
+}
+
+func TestGreedy(t *testing.T) {
+	tests := []struct {
+		name string
+		cost [][]float64
+		want []int
+	}{
+		{name: "empty", cost: nil, want: []int{}},
+		{name: "cheapest first", cost: [][]float64{{4, 1, 3}, {2, 0, 5}, {3, 2, 2}}, want: []int{0, 1, 2}},
+		{name: "more columns", cost: [][]float64{{5, 1, 9, 2}, {1, 3, 9, 9}}, want: []int{1, 0}},
+		{name: "more rows", cost: [][]float64{{5, 1}, {1, 3}, {0, 4}}, want: []int{1, Unassigned, 0}},
+		{name: "forbidden", cost: [][]float64{{inf, 1}, {inf, 2}}, want: []int{1, Unassigned}},
+		{name: "all forbidden", cost: [][]float64{{inf}, {inf}}, want: []int{Unassigned, Unassigned}},
+		// Ничьи решаются по порядку строк и столбцов
+		{name: "ties", cost: [][]float64{{1, 1}, {1, 1}}, want: []int{0, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Greedy(tt.cost)
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("Greedy = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGreedyIsValidButNotOptimal(t *testing.T) {
+	random := rand.New(rand.NewSource(2))
+	for iter := 0; iter < 500; iter++ {
+		rows, cols := 1+random.Intn(5), 1+random.Intn(5)
+		cost := make([][]float64, rows)
+		for i := range cost {
+			cost[i] = make([]float64, cols)
+			for j := range cost[i] {
+				cost[i][j] = float64(random.Intn(10))
+				if random.Intn(4) == 0 {
+					cost[i][j] = inf
+				}
+			}
+		}
+		result := Greedy(cost)
+		score(t, cost, result)
+		// Назначение нельзя дополнить: у свободной строки нет разрешённой пары со свободным столбцом
+		for row, col := range result {
+			if col != Unassigned {
+				continue
+			}
+			for j, c := range cost[row] {
+				if !math.IsInf(c, 1) && !slices.Contains(result, j) {
+					t.Fatalf("cost %v: row %d left free with column %d available", cost, row, j)
+				}
+			}
+		}
+	}
+
+	// Жадный берёт 0-0 за 1 и платит 100 за вторую пару, оптимум - 2+2
+	cost := [][]float64{{1, 2}, {2, 100}}
+	_, greedy := score(t, cost, Greedy(cost))
+	_, optimal := score(t, cost, Hungarian(cost))
+	if greedy != 101 || optimal != 4 {
+		t.Fatalf("greedy %v, hungarian %v", greedy, optimal)
+	}
+}
@@ -0,0 +1,35 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+const (
+	APIKeyPrefix = "rbt_"
+	// Сколько символов ключа после rbt_ показываем в списке, чтобы ключи можно было отличить
+	visiblePrefix = 8
+)
+
+// Новый ключ: сам ключ отдаём пользователю один раз, в базу идут префикс и хэш
+func GenerateAPIKey() (key, prefix, hash string, err error) {
+	buf := make([]byte, 32)
+	if _, err = rand.Read(buf); err != nil {
+		return "", "", "", err
+	}
+	key = APIKeyPrefix + base64.RawURLEncoding.EncodeToString(buf)
+	return key, key[:len(APIKeyPrefix)+visiblePrefix], HashAPIKey(key), nil
+}
+
+// Ключи длинные и случайные, так что хватает sha256 без соли
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func looksLikeAPIKey(token string) bool {
+	return strings.HasPrefix(token, APIKeyPrefix)
+}
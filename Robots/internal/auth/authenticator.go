@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"RobotService/internal/entities"
+	"RobotService/internal/repositories"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	APIKeyHeader = "X-API-Key"
+
+	// Сколько помним проверенный ключ. Столько же отзыв ключа может доходить до сервера
+	apiKeyCacheTTL = 30 * time.Second
+)
+
+var (
+	ErrNoCredentials      = errors.New("no credentials")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Хранилище ключей: по хэшу возвращает действующий (не отозванный) ключ
+type KeyStore interface {
+	AuthenticateAPIKey(hash string) (*entities.APIKey, error)
+}
+
+type cachedKey struct {
+	principal *Principal
+	expires   time.Time
+}
+
+// Проверяет X-API-Key или Authorization: Bearer. В Bearer можно передать и API ключ (rbt_...),
+// это удобно для gRPC клиентов, которые умеют только authorization
+type Authenticator struct {
+	Keys KeyStore
+	// nil, если JWKS не настроен и JWT не принимаются
+	JWT *Verifier
+	// Токены для стримов, см. AuthenticateStream. nil - стримы только по заголовкам
+	Streams *StreamTokens
+
+	mu    sync.Mutex
+	cache map[string]cachedKey
+}
+
+func NewAuthenticator(keys KeyStore, jwt *Verifier) *Authenticator {
+	return &Authenticator{Keys: keys, JWT: jwt, cache: map[string]cachedKey{}}
+}
+
+func (a *Authenticator) Authenticate(header http.Header) (*Principal, error) {
+	if key := header.Get(APIKeyHeader); key != "" {
+		return a.authenticateKey(key)
+	}
+
+	authorization := header.Get("Authorization")
+	if authorization == "" {
+		return nil, ErrNoCredentials
+	}
+	scheme, token, ok := strings.Cut(authorization, " ")
+	token = strings.TrimSpace(token)
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return nil, fmt.Errorf("%w: unsupported authorization scheme", ErrInvalidCredentials)
+	}
+	if looksLikeAPIKey(token) {
+		return a.authenticateKey(token)
+	}
+	if a.JWT == nil {
+		return nil, fmt.Errorf("%w: jwt is not configured", ErrInvalidCredentials)
+	}
+	principal, err := a.JWT.Verify(token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+	return principal, nil
+}
+
+func (a *Authenticator) authenticateKey(key string) (*Principal, error) {
+	hash := HashAPIKey(key)
+
+	a.mu.Lock()
+	cached, ok := a.cache[hash]
+	a.mu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.principal, nil
+	}
+
+	apiKey, err := a.Keys.AuthenticateAPIKey(hash)
+	if errors.Is(err, repositories.ErrAPIKeyNotFound) {
+		return nil, fmt.Errorf("%w: unknown or revoked api key", ErrInvalidCredentials)
+	}
+	if err != nil {
+		return nil, err
+	}
+	principal := &Principal{Subject: apiKey.Name, Method: MethodAPIKey, Roles: apiKey.Roles}
+
+	a.mu.Lock()
+	// Чистим протухшие записи, чтобы кэш не рос от перебора ключей
+	for h, c := range a.cache {
+		if time.Now().After(c.expires) {
+			delete(a.cache, h)
+		}
+	}
+	a.cache[hash] = cachedKey{principal: principal, expires: time.Now().Add(apiKeyCacheTTL)}
+	a.mu.Unlock()
+	return principal, nil
+}
@@ -0,0 +1,213 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+)
+
+// Как часто проверяем, не поменялся ли файл JWKS
+const jwksCheckInterval = 10 * time.Second
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type publicKey struct {
+	alg string
+	key crypto.PublicKey
+}
+
+// Набор ключей из локального JWKS файла. Файл перечитывается при изменении,
+// так что ротация ключей не требует рестарта
+type KeySet struct {
+	path string
+
+	mu      sync.RWMutex
+	keys    map[string]publicKey
+	modTime time.Time
+	checked time.Time
+}
+
+func LoadKeySet(path string) (*KeySet, error) {
+	set := &KeySet{path: path}
+	if err := set.reload(); err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+// Ключ по kid. Если kid в токене нет, а ключ в наборе один - берём его
+func (set *KeySet) lookup(kid string) (publicKey, bool) {
+	set.refresh()
+
+	set.mu.RLock()
+	defer set.mu.RUnlock()
+	if kid == "" && len(set.keys) == 1 {
+		for _, key := range set.keys {
+			return key, true
+		}
+	}
+	key, ok := set.keys[kid]
+	return key, ok
+}
+
+func (set *KeySet) refresh() {
+	set.mu.RLock()
+	fresh := time.Since(set.checked) < jwksCheckInterval
+	set.mu.RUnlock()
+	if fresh {
+		return
+	}
+	// Битый файл не ломает уже загруженные ключи
+	_ = set.reload()
+}
+
+func (set *KeySet) reload() error {
+	info, err := os.Stat(set.path)
+	if err != nil {
+		set.markChecked()
+		return err
+	}
+
+	set.mu.RLock()
+	unchanged := set.keys != nil && info.ModTime().Equal(set.modTime)
+	set.mu.RUnlock()
+	if unchanged {
+		set.markChecked()
+		return nil
+	}
+
+	data, err := os.ReadFile(set.path)
+	if err != nil {
+		set.markChecked()
+		return err
+	}
+	keys, err := parseJWKS(data)
+	if err != nil {
+		set.markChecked()
+		return fmt.Errorf("jwks %s: %w", set.path, err)
+	}
+
+	set.mu.Lock()
+	set.keys = keys
+	set.modTime = info.ModTime()
+	set.checked = time.Now()
+	set.mu.Unlock()
+	return nil
+}
+
+func (set *KeySet) markChecked() {
+	set.mu.Lock()
+	set.checked = time.Now()
+	set.mu.Unlock()
+}
+
+func parseJWKS(data []byte) (map[string]publicKey, error) {
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]publicKey, len(doc.Keys))
+	for i, k := range doc.Keys {
+		// Ключи для шифрования нам не нужны
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("key %d (%s): %w", i, k.Kid, err)
+		}
+		keys[k.Kid] = publicKey{alg: k.Alg, key: key}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no signing keys")
+	}
+	return keys, nil
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() < 3 {
+			return nil, errors.New("bad RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		curve, err := curveByName(k.Crv)
+		if err != nil {
+			return nil, err
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("EC point is not on curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %s", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("bad Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+func curveByName(name string) (elliptic.Curve, error) {
+	switch name {
+	case "P-256":
+		return elliptic.P256(), nil
+	case "P-384":
+		return elliptic.P384(), nil
+	case "P-521":
+		return elliptic.P521(), nil
+	}
+	return nil, fmt.Errorf("unsupported curve %s", name)
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(data) == 0 {
+		return nil, errors.New("bad base64url number")
+	}
+	return new(big.Int).SetBytes(data), nil
+}
@@ -0,0 +1,189 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Какая кривая положена каждому ES* алгоритму
+var curveBits = map[string]int{"ES256": 256, "ES384": 384, "ES512": 521}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// aud может быть и строкой, и массивом
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+type jwtClaims struct {
+	Subject   string   `json:"sub"`
+	Issuer    string   `json:"iss"`
+	Audience  audience `json:"aud"`
+	ExpiresAt *float64 `json:"exp"`
+	NotBefore *float64 `json:"nbf"`
+	Roles     []string `json:"roles"`
+}
+
+// Проверка JWT по ключам из JWKS. Issuer и Audience проверяются, только если заданы
+type Verifier struct {
+	Keys     *KeySet
+	Issuer   string
+	Audience string
+	// Допустимое расхождение часов
+	Leeway time.Duration
+}
+
+func (v *Verifier) Verify(token string) (*Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("header: %w", err)
+	}
+	key, ok := v.Keys.lookup(header.Kid)
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", header.Kid)
+	}
+	// alg из токена не должен подменять алгоритм ключа
+	if key.alg != "" && key.alg != header.Alg {
+		return nil, fmt.Errorf("alg %s does not match key", header.Alg)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("malformed signature")
+	}
+	if err = verifySignature(header.Alg, key.key, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	var claims jwtClaims
+	if err = decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("claims: %w", err)
+	}
+	if err = v.validate(claims, time.Now()); err != nil {
+		return nil, err
+	}
+	return &Principal{Subject: claims.Subject, Method: MethodJWT, Roles: claims.Roles}, nil
+}
+
+func (v *Verifier) validate(claims jwtClaims, now time.Time) error {
+	if claims.Subject == "" {
+		return errors.New("sub is missing")
+	}
+	// Бессрочные токены не принимаем
+	if claims.ExpiresAt == nil {
+		return errors.New("exp is missing")
+	}
+	if now.After(unixTime(*claims.ExpiresAt).Add(v.Leeway)) {
+		return errors.New("token expired")
+	}
+	if claims.NotBefore != nil && now.Add(v.Leeway).Before(unixTime(*claims.NotBefore)) {
+		return errors.New("token not valid yet")
+	}
+	if v.Issuer != "" && claims.Issuer != v.Issuer {
+		return fmt.Errorf("unexpected issuer %q", claims.Issuer)
+	}
+	if v.Audience != "" && !slices.Contains(claims.Audience, v.Audience) {
+		return errors.New("token is not for this audience")
+	}
+	return nil
+}
+
+// Поддерживаем только асимметричные алгоритмы: HS* с публичным JWKS не имеет смысла, none - тем более
+func verifySignature(alg string, key crypto.PublicKey, signed string, signature []byte) error {
+	var hash crypto.Hash
+	switch alg {
+	case "RS256", "ES256":
+		hash = crypto.SHA256
+	case "RS384", "ES384":
+		hash = crypto.SHA384
+	case "RS512", "ES512":
+		hash = crypto.SHA512
+	case "EdDSA":
+		pub, ok := key.(ed25519.PublicKey)
+		if !ok || !ed25519.Verify(pub, []byte(signed), signature) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported alg %q", alg)
+	}
+	digest := digestOf(hash, signed)
+
+	switch pub := key.(type) {
+	case *rsa.PublicKey:
+		if alg[0] != 'R' || rsa.VerifyPKCS1v15(pub, hash, digest, signature) != nil {
+			return errors.New("invalid signature")
+		}
+		return nil
+	case *ecdsa.PublicKey:
+		// Подпись ES* - это r и s подряд, каждое длиной в размер кривой
+		bits := pub.Curve.Params().BitSize
+		size := (bits + 7) / 8
+		if alg[0] != 'E' || bits != curveBits[alg] || len(signature) != 2*size {
+			return errors.New("invalid signature")
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(pub, digest, r, s) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	}
+	return errors.New("key does not match alg")
+}
+
+func digestOf(hash crypto.Hash, data string) []byte {
+	switch hash {
+	case crypto.SHA384:
+		sum := sha512.Sum384([]byte(data))
+		return sum[:]
+	case crypto.SHA512:
+		sum := sha512.Sum512([]byte(data))
+		return sum[:]
+	}
+	sum := sha256.Sum256([]byte(data))
+	return sum[:]
+}
+
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func unixTime(seconds float64) time.Time {
+	return time.Unix(0, int64(seconds*float64(time.Second)))
+}
@@ -0,0 +1,375 @@
+package auth
+
+import (
+	"RobotService/internal/entities"
+	"RobotService/internal/repositories"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Тестовые ключи и JWKS с ними
+type testKeys struct {
+	rsa   *rsa.PrivateKey
+	ec256 *ecdsa.PrivateKey
+	ec384 *ecdsa.PrivateKey
+	ed    ed25519.PrivateKey
+}
+
+func newTestKeys(t *testing.T) testKeys {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ec256, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	ec384, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	_, ed, _ := ed25519.GenerateKey(rand.Reader)
+	return testKeys{rsa: rsaKey, ec256: ec256, ec384: ec384, ed: ed}
+}
+
+func b64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func ecJWK(kid string, key *ecdsa.PrivateKey) jwk {
+	size := (key.Curve.Params().BitSize + 7) / 8
+	return jwk{Kty: "EC", Kid: kid, Crv: key.Curve.Params().Name,
+		X: b64(key.X.FillBytes(make([]byte, size))), Y: b64(key.Y.FillBytes(make([]byte, size)))}
+}
+
+func (k testKeys) jwks() []jwk {
+	rsaJWK := jwk{Kty: "RSA", N: b64(k.rsa.N.Bytes()), E: b64(big.NewInt(int64(k.rsa.E)).Bytes())}
+	pinned, open := rsaJWK, rsaJWK
+	pinned.Kid, pinned.Alg = "rsa", "RS256"
+	open.Kid = "rsa-any"
+	return []jwk{pinned, open, ecJWK("ec256", k.ec256), ecJWK("ec384", k.ec384),
+		{Kty: "OKP", Kid: "ed", Crv: "Ed25519", X: b64(k.ed.Public().(ed25519.PublicKey))}}
+}
+
+func writeJWKS(t *testing.T, path string, keys []jwk) {
+	t.Helper()
+	data, err := json.Marshal(map[string][]jwk{"keys": keys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Подписывает токен алгоритмом alg ключом key. Для none подпись пустая, для HS* key - это секрет
+func signJWT(t *testing.T, header map[string]any, claims map[string]any, key any) string {
+	t.Helper()
+	h, _ := json.Marshal(header)
+	c, _ := json.Marshal(claims)
+	signed := b64(h) + "." + b64(c)
+
+	var signature []byte
+	var err error
+	alg, _ := header["alg"].(string)
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		hash := map[string]crypto.Hash{"RS256": crypto.SHA256, "RS384": crypto.SHA384, "RS512": crypto.SHA512}[alg]
+		if hash == 0 {
+			hash = crypto.SHA256
+		}
+		signature, err = rsa.SignPKCS1v15(rand.Reader, k, hash, digestOf(hash, signed))
+	case *ecdsa.PrivateKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		hash := map[int]crypto.Hash{256: crypto.SHA256, 384: crypto.SHA384, 521: crypto.SHA512}[k.Curve.Params().BitSize]
+		r, s, signErr := ecdsa.Sign(rand.Reader, k, digestOf(hash, signed))
+		err = signErr
+		signature = append(r.FillBytes(make([]byte, size)), s.FillBytes(make([]byte, size))...)
+	case ed25519.PrivateKey:
+		signature = ed25519.Sign(k, []byte(signed))
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(signed))
+		signature = mac.Sum(nil)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + b64(signature)
+}
+
+func TestVerifier(t *testing.T) {
+	keys := newTestKeys(t)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	writeJWKS(t, path, keys.jwks())
+	set, err := LoadKeySet(path)
+	if err != nil {
+		t.Fatalf("LoadKeySet: %v", err)
+	}
+	verifier := &Verifier{Keys: set, Issuer: "https://idp.example", Audience: "robots", Leeway: 30 * time.Second}
+
+	now := time.Now().Unix()
+	claims := func(change func(map[string]any)) map[string]any {
+		c := map[string]any{"sub": "alice", "iss": "https://idp.example", "aud": "robots", "exp": now + 60, "roles": []string{"operator"}}
+		if change != nil {
+			change(c)
+		}
+		return c
+	}
+	hdr := func(alg, kid string) map[string]any {
+		return map[string]any{"alg": alg, "kid": kid, "typ": "JWT"}
+	}
+	rsaDER, _ := x509.MarshalPKIXPublicKey(&keys.rsa.PublicKey)
+	valid := signJWT(t, hdr("RS256", "rsa"), claims(nil), keys.rsa)
+	parts := strings.Split(valid, ".")
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr string
+	}{
+		{name: "RS256", token: valid},
+		{name: "RS512 on key without alg", token: signJWT(t, hdr("RS512", "rsa-any"), claims(nil), keys.rsa)},
+		{name: "ES256", token: signJWT(t, hdr("ES256", "ec256"), claims(nil), keys.ec256)},
+		{name: "ES384", token: signJWT(t, hdr("ES384", "ec384"), claims(nil), keys.ec384)},
+		{name: "EdDSA", token: signJWT(t, hdr("EdDSA", "ed"), claims(nil), keys.ed)},
+		{name: "audience in array", token: signJWT(t, hdr("RS256", "rsa"), claims(func(c map[string]any) { c["aud"] = []string{"other", "robots"} }), keys.rsa)},
+
+		// Алгоритм и тип ключа
+		{name: "alg differs from key alg", token: signJWT(t, hdr("RS512", "rsa"), claims(nil), keys.rsa), wantErr: "does not match key"},
+		{name: "RS256 on EC key", token: signJWT(t, hdr("RS256", "ec256"), claims(nil), keys.rsa), wantErr: "invalid signature"},
+		{name: "ES256 on RSA key", token: signJWT(t, hdr("ES256", "rsa-any"), claims(nil), keys.ec256), wantErr: "invalid signature"},
+		{name: "ES384 on P-256 key", token: signJWT(t, hdr("ES384", "ec256"), claims(nil), keys.ec256), wantErr: "invalid signature"},
+		{name: "EdDSA on RSA key", token: signJWT(t, hdr("EdDSA", "rsa-any"), claims(nil), keys.ed), wantErr: "invalid signature"},
+		{name: "none", token: signJWT(t, hdr("none", "rsa-any"), claims(nil), nil), wantErr: "unsupported alg"},
+		{name: "HS256 with public key as secret", token: signJWT(t, hdr("HS256", "rsa-any"), claims(nil), rsaDER), wantErr: "unsupported alg"},
+
+		// Подпись и ключ
+		{name: "tampered claims", token: parts[0] + "." + b64([]byte(`{"sub":"mallory","exp":9999999999}`)) + "." + parts[2], wantErr: "invalid signature"},
+		{name: "signed by other key", token: signJWT(t, hdr("ES256", "ec256"), claims(nil), mustECKey(t)), wantErr: "invalid signature"},
+		{name: "unknown kid", token: signJWT(t, hdr("RS256", "gone"), claims(nil), keys.rsa), wantErr: "unknown key"},
+		{name: "no kid with several keys", token: signJWT(t, hdr("RS256", ""), claims(nil), keys.rsa), wantErr: "unknown key"},
+		{name: "malformed", token: "a.b", wantErr: "malformed token"},
+		{name: "bad signature encoding", token: parts[0] + "." + parts[1] + ".!!", wantErr: "malformed signature"},
+
+		// Время
+		{name: "expired", token: signJWT(t, hdr("RS256", "rsa"), claims(func(c map[string]any) { c["exp"] = now - 60 }), keys.rsa), wantErr: "expired"},
+		{name: "expired within leeway", token: signJWT(t, hdr("RS256", "rsa"), claims(func(c map[string]any) { c["exp"] = now - 10 }), keys.rsa)},
+		{name: "no exp", token: signJWT(t, hdr("RS256", "rsa"), claims(func(c map[string]any) { delete(c, "exp") }), keys.rsa), wantErr: "exp is missing"},
+		{name: "nbf in future", token: signJWT(t, hdr("RS256", "rsa"), claims(func(c map[string]any) { c["nbf"] = now + 60 }), keys.rsa), wantErr: "not valid yet"},
+		{name: "nbf within leeway", token: signJWT(t, hdr("RS256", "rsa"), claims(func(c map[string]any) { c["nbf"] = now + 10 }), keys.rsa)},
+
+		// Кому и от кого
+		{name: "issuer mismatch", token: signJWT(t, hdr("RS256", "rsa"), claims(func(c map[string]any) { c["iss"] = "https://evil.example" }), keys.rsa), wantErr: "unexpected issuer"},
+		{name: "audience mismatch", token: signJWT(t, hdr("RS256", "rsa"), claims(func(c map[string]any) { c["aud"] = []string{"billing"} }), keys.rsa), wantErr: "audience"},
+		{name: "no audience", token: signJWT(t, hdr("RS256", "rsa"), claims(func(c map[string]any) { delete(c, "aud") }), keys.rsa), wantErr: "audience"},
+		{name: "no sub", token: signJWT(t, hdr("RS256", "rsa"), claims(func(c map[string]any) { delete(c, "sub") }), keys.rsa), wantErr: "sub is missing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal, err := verifier.Verify(tt.token)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+			if principal.String() != "jwt:alice" || len(principal.Roles) != 1 || principal.Roles[0] != "operator" {
+				t.Fatalf("principal = %+v", principal)
+			}
+		})
+	}
+}
+
+func mustECKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestVerifierWithoutIssuerAndAudience(t *testing.T) {
+	keys := newTestKeys(t)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	writeJWKS(t, path, []jwk{ecJWK("", keys.ec256)})
+	set, err := LoadKeySet(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Единственный ключ подходит и без kid
+	token := signJWT(t, map[string]any{"alg": "ES256"}, map[string]any{"sub": "bob", "iss": "any", "exp": time.Now().Unix() + 60}, keys.ec256)
+	if _, err = (&Verifier{Keys: set}).Verify(token); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+}
+
+func TestParseJWKS(t *testing.T) {
+	keys := newTestKeys(t)
+	offCurve := ecJWK("bad", keys.ec256)
+	offCurve.Y = offCurve.X
+
+	tests := []struct {
+		name    string
+		keys    []jwk
+		want    []string
+		wantErr string
+	}{
+		{name: "all types", keys: keys.jwks(), want: []string{"rsa", "rsa-any", "ec256", "ec384", "ed"}},
+		{name: "encryption keys skipped", keys: []jwk{{Kty: "RSA", Kid: "enc", Use: "enc"}, ecJWK("sig", keys.ec256)}, want: []string{"sig"}},
+		{name: "only encryption keys", keys: []jwk{{Kty: "RSA", Kid: "enc", Use: "enc"}}, wantErr: "no signing keys"},
+		{name: "point not on curve", keys: []jwk{offCurve}, wantErr: "not on curve"},
+		{name: "symmetric key", keys: []jwk{{Kty: "oct", Kid: "hs"}}, wantErr: "unsupported key type"},
+		{name: "unknown curve", keys: []jwk{{Kty: "EC", Kid: "k", Crv: "secp256k1"}}, wantErr: "unsupported curve"},
+		{name: "X25519", keys: []jwk{{Kty: "OKP", Kid: "x", Crv: "X25519"}}, wantErr: "unsupported curve"},
+		{name: "small exponent", keys: []jwk{{Kty: "RSA", Kid: "r", N: b64(keys.rsa.N.Bytes()), E: b64([]byte{1})}}, wantErr: "exponent"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, _ := json.Marshal(map[string][]jwk{"keys": tt.keys})
+			parsed, err := parseJWKS(data)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseJWKS: %v", err)
+			}
+			if len(parsed) != len(tt.want) {
+				t.Fatalf("got %d keys, want %v", len(parsed), tt.want)
+			}
+			for _, kid := range tt.want {
+				if _, ok := parsed[kid]; !ok {
+					t.Fatalf("key %q is missing", kid)
+				}
+			}
+		})
+	}
+}
+
+func TestKeySetReload(t *testing.T) {
+	keys := newTestKeys(t)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	writeJWKS(t, path, []jwk{ecJWK("old", keys.ec256)})
+	set, err := LoadKeySet(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Ротация: новый ключ появляется после проверки файла, старый пропадает
+	writeJWKS(t, path, []jwk{ecJWK("new", keys.ec384)})
+	future := time.Now().Add(time.Minute)
+	if err = os.Chtimes(path, future, future); err != nil {
+		t.Fatal(err)
+	}
+	set.checked = time.Time{}
+	if _, ok := set.lookup("new"); !ok {
+		t.Fatal("rotated key not loaded")
+	}
+	if _, ok := set.lookup("old"); ok {
+		t.Fatal("old key still present")
+	}
+
+	// Битый файл не ломает загруженные ключи
+	if err = os.WriteFile(path, []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	later := future.Add(time.Minute)
+	_ = os.Chtimes(path, later, later)
+	set.checked = time.Time{}
+	if _, ok := set.lookup("new"); !ok {
+		t.Fatal("keys lost after a broken reload")
+	}
+}
+
+type fakeKeyStore struct {
+	keys  map[string]*entities.APIKey
+	calls int
+}
+
+func (s *fakeKeyStore) AuthenticateAPIKey(hash string) (*entities.APIKey, error) {
+	s.calls++
+	if key, ok := s.keys[hash]; ok {
+		return key, nil
+	}
+	return nil, repositories.ErrAPIKeyNotFound
+}
+
+func TestAuthenticate(t *testing.T) {
+	keys := newTestKeys(t)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	writeJWKS(t, path, keys.jwks())
+	set, err := LoadKeySet(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	apiKey, _, hash, _ := GenerateAPIKey()
+	store := &fakeKeyStore{keys: map[string]*entities.APIKey{hash: {Name: "ci", Roles: []string{"admin"}}}}
+	authn := NewAuthenticator(store, &Verifier{Keys: set})
+	token := signJWT(t, map[string]any{"alg": "EdDSA", "kid": "ed"}, map[string]any{"sub": "alice", "exp": time.Now().Unix() + 60}, keys.ed)
+
+	header := func(name, value string) http.Header {
+		h := http.Header{}
+		h.Set(name, value)
+		return h
+	}
+
+	tests := []struct {
+		name    string
+		header  http.Header
+		want    string
+		wantErr error
+	}{
+		{name: "api key header", header: header(APIKeyHeader, apiKey), want: "api-key:ci"},
+		{name: "api key as bearer", header: http.Header{"Authorization": {"Bearer " + apiKey}}, want: "api-key:ci"},
+		{name: "jwt", header: http.Header{"Authorization": {"bearer " + token}}, want: "jwt:alice"},
+		{name: "nothing", header: http.Header{}, wantErr: ErrNoCredentials},
+		{name: "unknown api key", header: header(APIKeyHeader, APIKeyPrefix+"nope"), wantErr: ErrInvalidCredentials},
+		{name: "basic auth", header: http.Header{"Authorization": {"Basic YTpi"}}, wantErr: ErrInvalidCredentials},
+		{name: "empty bearer", header: http.Header{"Authorization": {"Bearer "}}, wantErr: ErrInvalidCredentials},
+		{name: "bad jwt", header: http.Header{"Authorization": {"Bearer " + token + "x"}}, wantErr: ErrInvalidCredentials},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal, err := authn.Authenticate(tt.header)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Authenticate: %v", err)
+			}
+			if principal.String() != tt.want {
+				t.Fatalf("principal = %s, want %s", principal, tt.want)
+			}
+		})
+	}
+	// Проверенный ключ берётся из кэша
+	if store.calls != 2 {
+		t.Fatalf("store called %d times, want 2", store.calls)
+	}
+
+	if _, err = NewAuthenticator(store, nil).Authenticate(http.Header{"Authorization": {"Bearer " + token}}); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("jwt without verifier: err = %v", err)
+	}
+}
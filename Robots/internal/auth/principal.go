@@ -0,0 +1,39 @@
+package auth
+
+import "context"
+
+const (
+	MethodAPIKey = "api-key"
+	MethodJWT    = "jwt"
+)
+
+// Тот, от чьего имени пришёл запрос
+type Principal struct {
+	Subject string   `json:"subject"`
+	Method  string   `json:"method"`
+	Roles   []string `json:"roles,omitempty"`
+}
+
+// В логах и событиях принципал выглядит как api-key:ci-deployer или jwt:alice
+func (p *Principal) String() string {
+	if p == nil {
+		return ""
+	}
+	return p.Method + ":" + p.Subject
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+func FromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
+
+// Кто сделал изменение. Для анонимных запросов пустая строка
+func Actor(ctx context.Context) string {
+	return FromContext(ctx).String()
+}
@@ -0,0 +1,114 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// Браузерные EventSource и WebSocket не умеют ставить заголовки, токен стрима приходит
+	// в query ?access_token= или в этой куке
+	StreamTokenParam  = "access_token"
+	StreamTokenCookie = "robots_stream_token"
+
+	DefaultStreamTokenTTL = time.Minute
+)
+
+var errBadStreamToken = errors.New("malformed stream token")
+
+// Короткоживущие токены для стримов, выдаются по обычной аутентификации и несут того же принципала.
+// Подписаны случайным ключом процесса: после перезапуска токен недействителен, клиент просто берёт новый
+type StreamTokens struct {
+	key []byte
+	ttl time.Duration
+}
+
+type streamClaims struct {
+	Principal *Principal `json:"p"`
+	Expires   int64      `json:"exp"`
+}
+
+func NewStreamTokens(ttl time.Duration) (*StreamTokens, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	if ttl <= 0 {
+		ttl = DefaultStreamTokenTTL
+	}
+	return &StreamTokens{key: key, ttl: ttl}, nil
+}
+
+func (t *StreamTokens) TTL() time.Duration {
+	return t.ttl
+}
+
+// Токен: данные в base64url и HMAC-SHA256 от них через точку
+func (t *StreamTokens) Issue(principal *Principal) (string, time.Time, error) {
+	expires := time.Now().Add(t.ttl).Truncate(time.Second)
+	payload, err := json.Marshal(streamClaims{Principal: principal, Expires: expires.Unix()})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	data := base64.RawURLEncoding.EncodeToString(payload)
+	return data + "." + base64.RawURLEncoding.EncodeToString(t.sign(data)), expires, nil
+}
+
+func (t *StreamTokens) Verify(token string) (*Principal, error) {
+	data, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, errBadStreamToken
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(sig, t.sign(data)) {
+		return nil, errors.New("invalid stream token signature")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(data)
+	if err != nil {
+		return nil, errBadStreamToken
+	}
+	var claims streamClaims
+	if err = json.Unmarshal(payload, &claims); err != nil || claims.Principal == nil {
+		return nil, errBadStreamToken
+	}
+	if time.Now().Unix() >= claims.Expires {
+		return nil, errors.New("stream token expired")
+	}
+	return claims.Principal, nil
+}
+
+func (t *StreamTokens) sign(data string) []byte {
+	mac := hmac.New(sha256.New, t.key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// Для стримов: сначала заголовки, без них - токен стрима из query или куки
+func (a *Authenticator) AuthenticateStream(r *http.Request) (*Principal, error) {
+	principal, err := a.Authenticate(r.Header)
+	if !errors.Is(err, ErrNoCredentials) || a == nil || a.Streams == nil {
+		return principal, err
+	}
+	token := r.URL.Query().Get(StreamTokenParam)
+	if token == "" {
+		if cookie, cookieErr := r.Cookie(StreamTokenCookie); cookieErr == nil {
+			token = cookie.Value
+		}
+	}
+	if token == "" {
+		return nil, ErrNoCredentials
+	}
+	principal, err = a.Streams.Verify(token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+	return principal, nil
+}
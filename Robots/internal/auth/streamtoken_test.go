@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuthenticateStream(t *testing.T) {
+	tokens, err := NewStreamTokens(time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authn := &Authenticator{Streams: tokens}
+	alice := &Principal{Subject: "alice", Method: MethodJWT, Roles: []string{"viewer"}}
+	token, expires, err := tokens.Issue(alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if time.Until(expires) > time.Minute || time.Until(expires) < 58*time.Second {
+		t.Fatalf("expires = %v", expires)
+	}
+
+	expired := &StreamTokens{key: tokens.key, ttl: -time.Second}
+	old, _, _ := expired.Issue(alice)
+	other, _ := NewStreamTokens(time.Minute)
+	foreign, _, _ := other.Issue(alice)
+	data, _, _ := strings.Cut(token, ".")
+
+	tests := []struct {
+		name    string
+		query   string
+		cookie  string
+		wantErr error
+	}{
+		{name: "query", query: token},
+		{name: "cookie", cookie: token},
+		{name: "none", wantErr: ErrNoCredentials},
+		{name: "expired", query: old, wantErr: ErrInvalidCredentials},
+		{name: "other process", query: foreign, wantErr: ErrInvalidCredentials},
+		{name: "tampered", query: data + ".AAAA", wantErr: ErrInvalidCredentials},
+		{name: "garbage", cookie: "garbage", wantErr: ErrInvalidCredentials},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/robots/stream", nil)
+			if tt.query != "" {
+				r.URL.RawQuery = StreamTokenParam + "=" + tt.query
+			}
+			if tt.cookie != "" {
+				r.AddCookie(&http.Cookie{Name: StreamTokenCookie, Value: tt.cookie})
+			}
+			principal, err := authn.AuthenticateStream(r)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("AuthenticateStream: %v", err)
+			}
+			if principal.String() != alice.String() || len(principal.Roles) != 1 || principal.Roles[0] != "viewer" {
+				t.Fatalf("principal = %+v", principal)
+			}
+		})
+	}
+}
+
+func TestAuthenticateStreamWithoutTokens(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/robots/stream?"+StreamTokenParam+"=x", nil)
+	if _, err := (&Authenticator{}).AuthenticateStream(r); !errors.Is(err, ErrNoCredentials) {
+		t.Fatalf("err = %v, want ErrNoCredentials", err)
+	}
+}
@@ -0,0 +1,215 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+const defaultPath = "config/config.yaml"
+
+type Config struct {
+	HTTPAddr  string    `yaml:"httpAddr"`
+	GRPCAddr  string    `yaml:"grpcAddr"`
+	Auth      Auth      `yaml:"auth"`
+	RBAC      RBAC      `yaml:"rbac"`
+	RateLimit RateLimit `yaml:"rateLimit"`
+	Approvals Approvals `yaml:"approvals"`
+	Lockdown  Lockdown  `yaml:"lockdown"`
+	Metrics   Metrics   `yaml:"metrics"`
+	Streams   Streams   `yaml:"streams"`
+	Motion    Motion    `yaml:"motion"`
+	Missions  Missions  `yaml:"missions"`
+	Allocator Allocator `yaml:"allocator"`
+}
+
+// Движок команд перемещения: раз в Tick роботы сдвигаются на шаг маршрута.
+// Маршруты резервируют ячейки (воксель со стороной VoxelSize, тик), на занятой ячейке робот
+// ждёт не больше MaxWait тиков за маршрут, дальше маршрут строится заново в обход
+type Motion struct {
+	Tick      time.Duration `yaml:"tick"`
+	VoxelSize int           `yaml:"voxelSize"`
+	MaxWait   int           `yaml:"maxWait"`
+}
+
+// Исполнитель миссий: раз в Tick ведёт назначенные миссии по шагам
+type Missions struct {
+	Tick time.Duration `yaml:"tick"`
+}
+
+// Распределитель миссий по свободным роботам: раз в Interval и по событиям robots.StatusChanged.
+// Роботы с battery.level ниже MinBattery работу не берут, BatteryWeight - цена процента недозаряда
+// в единицах расстояния
+type Allocator struct {
+	Enabled       bool          `yaml:"enabled"`
+	Interval      time.Duration `yaml:"interval"`
+	MinBattery    float64       `yaml:"minBattery"`
+	BatteryWeight float64       `yaml:"batteryWeight"`
+}
+
+// LabelKeys - метки роботов, которые становятся измерениями метрик (label_<ключ>).
+// Каждый ключ умножает число рядов на число его значений, поэтому список закрытый
+type Metrics struct {
+	LabelKeys []string `yaml:"labelKeys"`
+}
+
+// Стримы событий. AllowedOrigins - страницы, которым можно открыть /robots/ws из браузера
+// (https://dash.example.com, "*" - любым). Свой домен и клиенты без Origin пускаются всегда
+type Streams struct {
+	AllowedOrigins []string `yaml:"allowedOrigins"`
+}
+
+// Защитный режим при аномальной частоте изменений. Режим общий для всех инстансов
+// и снимается только вручную через /lockdown
+type Lockdown struct {
+	Enabled bool           `yaml:"enabled"`
+	Rules   []LockdownRule `yaml:"rules"`
+}
+
+// Больше Threshold изменений за Window включают режим Mode (deletes_frozen или read_only).
+// Actions - права rbac изменений, пустой список - все изменения роботов.
+// PerActor - считаем отдельно для каждого принципала, иначе общий счётчик
+type LockdownRule struct {
+	Name      string        `yaml:"name"`
+	Actions   []string      `yaml:"actions"`
+	PerActor  bool          `yaml:"perActor"`
+	Threshold int           `yaml:"threshold"`
+	Window    time.Duration `yaml:"window"`
+	Mode      string        `yaml:"mode"`
+}
+
+// Действия (права rbac), которые применяются только после подтверждения вторым человеком.
+// Пустой список - подтверждение не нужно
+type Approvals struct {
+	Actions []string      `yaml:"actions"`
+	TTL     time.Duration `yaml:"ttl"`
+}
+
+// Лимиты запросов на клиента (принципал или IP). Ключ в Routes - "МЕТОД шаблон" маршрута chi
+// ("POST /robots/create") или полное имя метода gRPC. Остальные запросы делят ведро Default.
+// PerIP - ведро адреса до аутентификации: его тратит каждый запрос, в том числе с неверным ключом,
+// так что подбор учётных данных тоже упирается в лимит. requests: 0 - без него
+type RateLimit struct {
+	Enabled bool             `yaml:"enabled"`
+	PerIP   Limit            `yaml:"perIP"`
+	Default Limit            `yaml:"default"`
+	Routes  map[string]Limit `yaml:"routes"`
+}
+
+// Requests запросов за Per в среднем, Burst подряд
+type Limit struct {
+	Requests int           `yaml:"requests"`
+	Per      time.Duration `yaml:"per"`
+	Burst    int           `yaml:"burst"`
+}
+
+type Auth struct {
+	// false - запросы без учётных данных пропускаются анонимно (на время перехода клиентов)
+	Required bool `yaml:"required"`
+	// Локальный JWKS с публичными ключами. Пустой путь - JWT не принимаем, только API ключи
+	JWKSFile string        `yaml:"jwksFile"`
+	Issuer   string        `yaml:"issuer"`
+	Audience string        `yaml:"audience"`
+	Leeway   time.Duration `yaml:"leeway"`
+	// Сколько живёт токен из POST /auth/stream-token для браузерных стримов
+	StreamTokenTTL time.Duration `yaml:"streamTokenTTL"`
+}
+
+// Политики доступа. Роли из конфига дополняют встроенные viewer/operator/admin или заменяют их целиком
+type RBAC struct {
+	// Роли для запросов без учётных данных (когда auth.required=false)
+	Anonymous []string `yaml:"anonymous"`
+	// Именованные группы роботов по ID
+	Groups map[string][]int `yaml:"groups"`
+	Roles  map[string]Role  `yaml:"roles"`
+}
+
+// Роль получает права родителей из Inherits. Types и Groups ограничивают все права роли
+// роботами этих типов и групп
+type Role struct {
+	Inherits    []string `yaml:"inherits"`
+	Permissions []string `yaml:"permissions"`
+	Types       []string `yaml:"types"`
+	Groups      []string `yaml:"groups"`
+}
+
+func defaults() *Config {
+	return &Config{
+		HTTPAddr: ":8083",
+		GRPCAddr: ":9083",
+		Auth: Auth{
+			Required:       true,
+			Leeway:         30 * time.Second,
+			StreamTokenTTL: time.Minute,
+		},
+		RateLimit: RateLimit{
+			Enabled: true,
+			PerIP:   Limit{Requests: 1200, Per: time.Minute, Burst: 200},
+			Default: Limit{Requests: 600, Per: time.Minute, Burst: 100},
+			Routes: map[string]Limit{
+				"POST /robots/create":        {Requests: 5, Per: time.Minute, Burst: 5},
+				"PUT /robots/updatecord":     {Requests: 5, Per: time.Minute, Burst: 5},
+				"POST /robots/{id}/move":     {Requests: 5, Per: time.Minute, Burst: 5},
+				"DELETE /robots/delete/{id}": {Requests: 5, Per: time.Minute, Burst: 5},
+			},
+		},
+		Approvals: Approvals{TTL: time.Hour},
+		Motion:    Motion{Tick: 500 * time.Millisecond, VoxelSize: 1, MaxWait: 20},
+		Missions:  Missions{Tick: time.Second},
+		Allocator: Allocator{Enabled: true, Interval: 10 * time.Second, MinBattery: 20, BatteryWeight: 1},
+		Lockdown: Lockdown{
+			Enabled: true,
+			Rules: []LockdownRule{
+				{Name: "mass-delete", Actions: []string{"robots:delete"}, Threshold: 20, Window: time.Minute, Mode: "deletes_frozen"},
+				{Name: "actor-delete", Actions: []string{"robots:delete"}, PerActor: true, Threshold: 10, Window: time.Minute, Mode: "deletes_frozen"},
+				{Name: "actor-mutations", PerActor: true, Threshold: 1000, Window: time.Minute, Mode: "read_only"},
+			},
+		},
+		RBAC: RBAC{
+			Roles: map[string]Role{
+				"viewer": {Permissions: []string{"robots:read", "webhooks:read"}},
+				"operator": {
+					Inherits:    []string{"viewer"},
+					Permissions: []string{"robots:move", "robots:rename", "robots:attributes", "robots:labels", "fleets:write", "missions:write", "approvals:read", "lockdown:read"},
+				},
+				"admin": {
+					Inherits:    []string{"operator"},
+					Permissions: []string{"robots:create", "robots:retype", "robots:delete", "robots:import", "webhooks:write", "types:write", "world:write", "audit:read", "approvals:decide", "lockdown:manage"},
+				},
+			},
+		},
+	}
+}
+
+// Читаем конфиг из ROBOTSRV_CONFIG или config/config.yaml.
+// Если файла нет - работаем на значениях по умолчанию
+func Load() (*Config, error) {
+	path := os.Getenv("ROBOTSRV_CONFIG")
+	if path == "" {
+		path = defaultPath
+	}
+
+	cfg := defaults()
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = yaml.Unmarshal(data, cfg); err != nil {
+		return nil, err
+	}
+	// Пустой roles: в файле обнуляет карту, встроенные роли возвращаем на место
+	if cfg.RBAC.Roles == nil {
+		cfg.RBAC.Roles = map[string]Role{}
+	}
+	for name, role := range defaults().RBAC.Roles {
+		if _, ok := cfg.RBAC.Roles[name]; !ok {
+			cfg.RBAC.Roles[name] = role
+		}
+	}
+	return cfg, nil
+}
@@ -0,0 +1,7 @@
+package dto
+
+// decision: approve или reject
+type ApprovalDecisionDTO struct {
+	Decision string `json:"decision"`
+	Comment  string `json:"comment,omitempty"`
+}
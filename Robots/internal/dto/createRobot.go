@@ -6,4 +6,7 @@ type CreateRobotDTO struct {
 	XCord int    `json:"xCord"`
 	YCord int    `json:"yCord"`
 	ZCord int    `json:"zCord"`
+	// Проверяются схемой attributeSchema типа
+	Attributes map[string]any    `json:"attributes,omitempty" swaggertype:"object"`
+	Labels     map[string]string `json:"labels,omitempty"`
 }
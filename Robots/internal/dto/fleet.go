@@ -0,0 +1,23 @@
+package dto
+
+// Флот заменяется целиком: имя, описание и родитель. parentId: null выносит флот на верхний уровень
+type FleetDTO struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	ParentID    *int   `json:"parentId,omitempty"`
+}
+
+type FleetMembersDTO struct {
+	RobotIDs []int `json:"robotIds"`
+}
+
+// Сдвиг всех роботов флота
+type FleetMoveDTO struct {
+	DX int `json:"dx"`
+	DY int `json:"dy"`
+	DZ int `json:"dz"`
+}
+
+type FleetRetypeDTO struct {
+	Type string `json:"type"`
+}
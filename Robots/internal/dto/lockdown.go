@@ -0,0 +1,7 @@
+package dto
+
+// Ручное включение защитного режима: deletes_frozen или read_only
+type LockdownDTO struct {
+	Mode   string `json:"mode"`
+	Reason string `json:"reason"`
+}
@@ -0,0 +1,22 @@
+package dto
+
+// Миссия создаётся в очереди, робота можно назначить сразу или позже
+type CreateMissionDTO struct {
+	Name    string           `json:"name"`
+	RobotID int              `json:"robotId,omitempty"`
+	Steps   []MissionStepDTO `json:"steps"`
+}
+
+// kind: move (xCord, yCord, zCord), wait (seconds), pickup (payload), dropoff (payload необязателен)
+type MissionStepDTO struct {
+	Kind    string  `json:"kind" enums:"move,wait,pickup,dropoff"`
+	XCord   int     `json:"xCord,omitempty"`
+	YCord   int     `json:"yCord,omitempty"`
+	ZCord   int     `json:"zCord,omitempty"`
+	Seconds float64 `json:"seconds,omitempty"`
+	Payload string  `json:"payload,omitempty"`
+}
+
+type AssignMissionDTO struct {
+	RobotID int `json:"robotId"`
+}
@@ -0,0 +1,9 @@
+package dto
+
+// Цель команды перемещения. UseActivePath - ехать по сохранённому активному маршруту робота, цель тогда не нужна
+type MoveDTO struct {
+	XCord         int  `json:"xCord"`
+	YCord         int  `json:"yCord"`
+	ZCord         int  `json:"zCord"`
+	UseActivePath bool `json:"useActivePath"`
+}
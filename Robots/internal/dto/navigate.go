@@ -0,0 +1,9 @@
+package dto
+
+// Цель маршрута. Store - сохранить маршрут как активный маршрут робота
+type NavigateDTO struct {
+	XCord int  `json:"xCord"`
+	YCord int  `json:"yCord"`
+	ZCord int  `json:"zCord"`
+	Store bool `json:"store"`
+}
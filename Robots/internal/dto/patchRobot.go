@@ -0,0 +1,6 @@
+package dto
+
+// JSON Merge Patch атрибутов: null удаляет ключ, вложенные объекты сливаются
+type PatchRobotDTO struct {
+	Attributes map[string]any `json:"attributes" swaggertype:"object"`
+}
@@ -0,0 +1,11 @@
+package dto
+
+// Полная замена меток, пустой объект снимает все
+type SetRobotLabelsDTO struct {
+	Labels map[string]string `json:"labels"`
+}
+
+// Точечное изменение меток: null снимает метку
+type PatchRobotLabelsDTO struct {
+	Labels map[string]*string `json:"labels"`
+}
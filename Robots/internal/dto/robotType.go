@@ -0,0 +1,25 @@
+package dto
+
+import "encoding/json"
+
+type CreateRobotTypeDTO struct {
+	Name            string          `json:"name"`
+	Description     string          `json:"description"`
+	MaxSpeed        float64         `json:"maxSpeed"`
+	MinZ            *int            `json:"minZ,omitempty"`
+	MaxZ            *int            `json:"maxZ,omitempty"`
+	PayloadCapacity float64         `json:"payloadCapacity"`
+	SafetyRadius    float64         `json:"safetyRadius"`
+	AttributeSchema json.RawMessage `json:"attributeSchema,omitempty" swaggertype:"object"`
+}
+
+// Тип заменяется целиком, имя менять нельзя: на него ссылаются роботы
+type UpdateRobotTypeDTO struct {
+	Description     string          `json:"description"`
+	MaxSpeed        float64         `json:"maxSpeed"`
+	MinZ            *int            `json:"minZ,omitempty"`
+	MaxZ            *int            `json:"maxZ,omitempty"`
+	PayloadCapacity float64         `json:"payloadCapacity"`
+	SafetyRadius    float64         `json:"safetyRadius"`
+	AttributeSchema json.RawMessage `json:"attributeSchema,omitempty" swaggertype:"object"`
+}
@@ -0,0 +1,15 @@
+package dto
+
+type CreateWebhookDTO struct {
+	URL    string   `json:"url"`
+	Secret string   `json:"secret"`
+	Events []string `json:"events"`
+}
+
+// Пустые поля не меняются. active=true включает отключённый вебхук обратно и сбрасывает счётчик ошибок
+type UpdateWebhookDTO struct {
+	URL    string   `json:"url,omitempty"`
+	Secret string   `json:"secret,omitempty"`
+	Events []string `json:"events,omitempty"`
+	Active *bool    `json:"active,omitempty"`
+}
@@ -0,0 +1,20 @@
+package dto
+
+import "RobotService/internal/entities"
+
+type ZoneBoxDTO struct {
+	MinX int `json:"minX"`
+	MinY int `json:"minY"`
+	MaxX int `json:"maxX"`
+	MaxY int `json:"maxY"`
+}
+
+// Форма зоны - ровно одно из box и polygon. Зона заменяется целиком
+type ZoneDTO struct {
+	Name    string           `json:"name"`
+	Kind    string           `json:"kind" enums:"zone,obstacle,geofence"`
+	Box     *ZoneBoxDTO      `json:"box,omitempty"`
+	Polygon []entities.Point `json:"polygon,omitempty"`
+	MinZ    *int             `json:"minZ,omitempty"`
+	MaxZ    *int             `json:"maxZ,omitempty"`
+}
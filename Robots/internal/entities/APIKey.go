@@ -0,0 +1,14 @@
+package entities
+
+import "time"
+
+// Статический ключ доступа к API. Сам ключ не храним, только его хэш
+type APIKey struct {
+	ID         int        `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Roles      []string   `json:"roles"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty"`
+}
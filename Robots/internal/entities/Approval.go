@@ -0,0 +1,28 @@
+package entities
+
+import "time"
+
+const (
+	ApprovalPending  = "pending"
+	ApprovalApproved = "approved"
+	ApprovalRejected = "rejected"
+	ApprovalExpired  = "expired"
+	// Одобрено, но изменение применить не получилось (например, робота уже удалили)
+	ApprovalFailed = "failed"
+)
+
+// Заявка на изменение, которое должен подтвердить второй человек
+type Approval struct {
+	ID          int        `json:"id"`
+	Action      string     `json:"action"`
+	RobotID     int        `json:"robotId"`
+	NewType     string     `json:"newType,omitempty"`
+	Status      string     `json:"status"`
+	RequestedBy string     `json:"requestedBy"`
+	DecidedBy   string     `json:"decidedBy,omitempty"`
+	Comment     string     `json:"comment,omitempty"`
+	Error       string     `json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	ExpiresAt   time.Time  `json:"expiresAt"`
+	DecidedAt   *time.Time `json:"decidedAt,omitempty"`
+}
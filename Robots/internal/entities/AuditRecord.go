@@ -0,0 +1,28 @@
+package entities
+
+import "time"
+
+// Запись аудита об изменении робота. Before/After - состояние робота до и после,
+// Result - success, error или denied
+type AuditRecord struct {
+	ID        int64     `json:"id"`
+	Time      time.Time `json:"time"`
+	Actor     string    `json:"actor"`
+	SourceIP  string    `json:"sourceIp"`
+	RequestID string    `json:"requestId"`
+	Action    string    `json:"action"`
+	RobotID   *int      `json:"robotId,omitempty"`
+	Before    *Robot    `json:"before,omitempty"`
+	After     *Robot    `json:"after,omitempty"`
+	Result    string    `json:"result"`
+	Error     string    `json:"error,omitempty"`
+	Details   string    `json:"details,omitempty"`
+}
+
+// Фильтр для выборки аудита, пустые поля не фильтруют
+type AuditFilter struct {
+	RobotID *int
+	Actor   string
+	From    *time.Time
+	To      *time.Time
+}
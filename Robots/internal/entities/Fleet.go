@@ -0,0 +1,24 @@
+package entities
+
+import "time"
+
+// Флот - именованная группа роботов. Флоты вкладываются друг в друга через ParentID,
+// групповые команды действуют на роботов флота и всех вложенных флотов.
+// RobotIDs - только прямые участники, без вложенных флотов
+type Fleet struct {
+	ID          int       `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	ParentID    *int      `json:"parentId,omitempty"`
+	RobotIDs    []int     `json:"robotIds"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// Итог групповой команды
+type FleetOperation struct {
+	FleetID  int    `json:"fleetId"`
+	Action   string `json:"action"`
+	Affected int    `json:"affected"`
+	RobotIDs []int  `json:"robotIds"`
+}
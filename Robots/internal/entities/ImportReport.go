@@ -0,0 +1,15 @@
+package entities
+
+type ImportError struct {
+	Line  int    `json:"line"`
+	ID    int    `json:"id,omitempty"`
+	Error string `json:"error"`
+}
+
+type ImportReport struct {
+	DryRun   bool          `json:"dryRun"`
+	Total    int           `json:"total"`
+	Imported int           `json:"imported"`
+	Failed   int           `json:"failed"`
+	Errors   []ImportError `json:"errors"`
+}
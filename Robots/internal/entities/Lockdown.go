@@ -0,0 +1,21 @@
+package entities
+
+import "time"
+
+const (
+	LockdownNormal = "normal"
+	// Запрещены удаления роботов
+	LockdownDeletesFrozen = "deletes_frozen"
+	// Запрещены все изменения роботов
+	LockdownReadOnly = "read_only"
+)
+
+// Защитный режим сервиса. Level растёт со строгостью режима, Actor - чья активность
+// включила режим или кто включил его вручную
+type LockdownState struct {
+	Mode   string     `json:"mode"`
+	Level  int        `json:"level"`
+	Reason string     `json:"reason,omitempty"`
+	Actor  string     `json:"actor,omitempty"`
+	Since  *time.Time `json:"since,omitempty"`
+}
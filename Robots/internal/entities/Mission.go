@@ -0,0 +1,55 @@
+package entities
+
+import "time"
+
+const (
+	MissionQueued    = "queued"
+	MissionActive    = "active"
+	MissionPaused    = "paused"
+	MissionCompleted = "completed"
+	MissionFailed    = "failed"
+	MissionCancelled = "cancelled"
+)
+
+const (
+	StepMove    = "move"
+	StepWait    = "wait"
+	StepPickup  = "pickup"
+	StepDropoff = "dropoff"
+)
+
+// Шаг миссии. Target - куда ехать (move), Seconds - сколько ждать (wait),
+// Payload - что взять или отдать (pickup, dropoff; для dropoff пусто - то, что везём).
+// CommandID - команда перемещения, которой выполняется move
+type MissionStep struct {
+	Kind       string     `json:"kind" enums:"move,wait,pickup,dropoff"`
+	Target     *RobotCord `json:"target,omitempty"`
+	Seconds    float64    `json:"seconds,omitempty"`
+	Payload    string     `json:"payload,omitempty"`
+	CommandID  int        `json:"commandId,omitempty"`
+	StartedAt  *time.Time `json:"startedAt,omitempty"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+}
+
+// Миссия - шаги, которые назначенный робот выполняет по порядку. CurrentStep - номер выполняемого шага,
+// Payload - груз, который робот везёт сейчас
+type Mission struct {
+	ID          int           `json:"id"`
+	Name        string        `json:"name"`
+	RobotID     *int          `json:"robotId,omitempty"`
+	Status      string        `json:"status" enums:"queued,active,paused,completed,failed,cancelled"`
+	Steps       []MissionStep `json:"steps"`
+	CurrentStep int           `json:"currentStep"`
+	Progress    float64       `json:"progress"`
+	Payload     string        `json:"payload,omitempty"`
+	Error       string        `json:"error,omitempty"`
+	CreatedBy   string        `json:"createdBy"`
+	CreatedAt   time.Time     `json:"createdAt"`
+	UpdatedAt   time.Time     `json:"updatedAt"`
+	StartedAt   *time.Time    `json:"startedAt,omitempty"`
+	FinishedAt  *time.Time    `json:"finishedAt,omitempty"`
+}
+
+func (m *Mission) Finished() bool {
+	return m.Status == MissionCompleted || m.Status == MissionFailed || m.Status == MissionCancelled
+}
@@ -0,0 +1,35 @@
+package entities
+
+import "time"
+
+const (
+	CommandRunning   = "running"
+	CommandCompleted = "completed"
+	CommandCancelled = "cancelled"
+	CommandFailed    = "failed"
+)
+
+// Команда на перемещение по маршруту. Движок двигает робота по Waypoints с максимальной скоростью типа,
+// Traveled - сколько пути уже пройдено, Position - где робот после последнего шага.
+// Blocked - почему робот сейчас стоит (другой робот на пути, защитный режим), команда при этом не отменяется
+type MoveCommand struct {
+	ID          int         `json:"id"`
+	RobotID     int         `json:"robotId"`
+	Status      string      `json:"status" enums:"running,completed,cancelled,failed"`
+	Target      RobotCord   `json:"target"`
+	Waypoints   []RobotCord `json:"waypoints"`
+	Length      float64     `json:"length"`
+	Traveled    float64     `json:"traveled"`
+	Progress    float64     `json:"progress"`
+	Position    RobotCord   `json:"position"`
+	Blocked     string      `json:"blocked,omitempty"`
+	Error       string      `json:"error,omitempty"`
+	RequestedBy string      `json:"requestedBy"`
+	CreatedAt   time.Time   `json:"createdAt"`
+	UpdatedAt   time.Time   `json:"updatedAt"`
+	FinishedAt  *time.Time  `json:"finishedAt,omitempty"`
+}
+
+func (c *MoveCommand) Finished() bool {
+	return c.Status != CommandRunning
+}
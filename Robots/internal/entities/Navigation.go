@@ -0,0 +1,16 @@
+package entities
+
+import "time"
+
+// Маршрут робота до цели в обход препятствий и геозон. Waypoints начинаются в From и кончаются в To,
+// между соседними точками робот едет по прямой.
+// EstimatedSeconds - время в пути при максимальной скорости типа, нет - скорость не ограничена
+type NavigationPlan struct {
+	RobotID          int         `json:"robotId"`
+	From             RobotCord   `json:"from"`
+	To               RobotCord   `json:"to"`
+	Waypoints        []RobotCord `json:"waypoints"`
+	Length           float64     `json:"length"`
+	EstimatedSeconds *float64    `json:"estimatedSeconds,omitempty"`
+	CreatedAt        time.Time   `json:"createdAt"`
+}
@@ -0,0 +1,26 @@
+package entities
+
+import "time"
+
+// Резервирование команды перемещения в таблице пространство-время. Слот - тик движка перемещений,
+// ячейка - воксель со стороной VoxelSize в этом слоте. Cells есть только в ответе по одной команде
+type Reservation struct {
+	CommandID int            `json:"commandId"`
+	RobotID   int            `json:"robotId"`
+	VoxelSize int            `json:"voxelSize"`
+	FromSlot  int64          `json:"fromSlot"`
+	ToSlot    int64          `json:"toSlot"`
+	StartsAt  time.Time      `json:"startsAt"`
+	EndsAt    time.Time      `json:"endsAt"`
+	CellCount int            `json:"cellCount"`
+	Cells     []ReservedCell `json:"cells,omitempty"`
+}
+
+// X, Y, Z - угол вокселя с наименьшими координатами
+type ReservedCell struct {
+	X    int       `json:"x"`
+	Y    int       `json:"y"`
+	Z    int       `json:"z"`
+	Slot int64     `json:"slot"`
+	At   time.Time `json:"at"`
+}
@@ -7,4 +7,18 @@ type Robot struct {
 	XCord int    `json:"xCord"`
 	YCord int    `json:"yCord"`
 	ZCord int    `json:"zCord"`
+	// Пользовательские поля, проверяются схемой attributeSchema типа робота
+	Attributes map[string]any `json:"attributes,omitempty" swaggertype:"object"`
+	// Метки в духе Kubernetes (site=warehouse-2), по ним работают селекторы списков и стримов
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+func (r *Robot) Cord() RobotCord {
+	return RobotCord{XCord: r.XCord, YCord: r.YCord, ZCord: r.ZCord}
+}
+
+// Робот и расстояние от него до точки запроса
+type NearbyRobot struct {
+	Robot
+	Distance float64 `json:"distance"`
 }
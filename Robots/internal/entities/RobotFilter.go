@@ -0,0 +1,71 @@
+package entities
+
+import "slices"
+
+// Операции селекторов атрибутов
+const (
+	SelectorExists   = "exists"
+	SelectorEqual    = "="
+	SelectorNotEqual = "!="
+	SelectorGreater  = ">"
+	SelectorGreaterE = ">="
+	SelectorLess     = "<"
+	SelectorLessE    = "<="
+)
+
+// Операции селекторов меток, как у Kubernetes
+const (
+	LabelEquals    = "="
+	LabelNotEquals = "!="
+	LabelIn        = "in"
+	LabelNotIn     = "notin"
+	LabelExists    = "exists"
+	LabelNotExists = "!exists"
+)
+
+// Фильтр списка роботов, пустые поля ничего не ограничивают
+type RobotFilter struct {
+	Type       string
+	Attributes []AttributeSelector
+	Labels     []LabelSelector
+}
+
+// Условие на атрибут: Path - путь во вложенных объектах (battery.level -> [battery level]).
+// Для сравнений больше/меньше Value - число
+type AttributeSelector struct {
+	Path  []string
+	Op    string
+	Value any
+}
+
+// Условие на метку. != и notin, как и в Kubernetes, пропускают роботов вовсе без этой метки
+type LabelSelector struct {
+	Key    string
+	Op     string
+	Values []string
+}
+
+func (s LabelSelector) Matches(labels map[string]string) bool {
+	value, ok := labels[s.Key]
+	switch s.Op {
+	case LabelExists:
+		return ok
+	case LabelNotExists:
+		return !ok
+	case LabelEquals, LabelIn:
+		return ok && slices.Contains(s.Values, value)
+	case LabelNotEquals, LabelNotIn:
+		return !ok || !slices.Contains(s.Values, value)
+	}
+	return false
+}
+
+// Все условия через И, пустой список пропускает всех
+func MatchLabels(selectors []LabelSelector, labels map[string]string) bool {
+	for _, selector := range selectors {
+		if !selector.Matches(labels) {
+			return false
+		}
+	}
+	return true
+}
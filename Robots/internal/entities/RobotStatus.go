@@ -0,0 +1,13 @@
+package entities
+
+const (
+	RobotIdle      = "idle"
+	RobotOnMission = "on_mission"
+)
+
+// Робот взял миссию или освободился, уходит в robots.StatusChanged
+type RobotStatus struct {
+	RobotID   int    `json:"robotId"`
+	Status    string `json:"status" enums:"idle,on_mission"`
+	MissionID int    `json:"missionId"`
+}
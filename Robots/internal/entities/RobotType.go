@@ -0,0 +1,30 @@
+package entities
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Тип робота из реестра. Роботы ссылаются на тип по имени (внешний ключ).
+// MaxSpeed - единиц координат в секунду, 0 - без ограничения.
+// MinZ/MaxZ - допустимая высота, отсутствующая граница не ограничивает.
+// PayloadCapacity - грузоподъёмность в кг.
+// SafetyRadius - радиус безопасности: роботы не сближаются больше, чем на сумму своих радиусов.
+// AttributeSchema - JSON Schema пользовательских атрибутов роботов этого типа
+type RobotType struct {
+	Name            string          `json:"name"`
+	Description     string          `json:"description"`
+	MaxSpeed        float64         `json:"maxSpeed"`
+	MinZ            *int            `json:"minZ,omitempty"`
+	MaxZ            *int            `json:"maxZ,omitempty"`
+	PayloadCapacity float64         `json:"payloadCapacity"`
+	SafetyRadius    float64         `json:"safetyRadius"`
+	AttributeSchema json.RawMessage `json:"attributeSchema" swaggertype:"object"`
+	CreatedAt       time.Time       `json:"createdAt"`
+	UpdatedAt       time.Time       `json:"updatedAt"`
+}
+
+// Можно ли роботу этого типа находиться на высоте z
+func (t *RobotType) AllowsAltitude(z int) bool {
+	return (t.MinZ == nil || z >= *t.MinZ) && (t.MaxZ == nil || z <= *t.MaxZ)
+}
@@ -0,0 +1,9 @@
+package entities
+
+import "time"
+
+// Короткоживущий токен для /robots/stream и /robots/ws: ?access_token=<token> или кука
+type StreamToken struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
@@ -0,0 +1,30 @@
+package entities
+
+import "time"
+
+// Подписка на события роботов. Секрет наружу не отдаём, он нужен только для подписи
+type Webhook struct {
+	ID             int       `json:"id"`
+	URL            string    `json:"url"`
+	Secret         string    `json:"-"`
+	Events         []string  `json:"events"`
+	Active         bool      `json:"active"`
+	FailureCount   int       `json:"failureCount"`
+	DisabledReason string    `json:"disabledReason,omitempty"`
+	CreatedAt      time.Time `json:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+// Одна попытка доставки события на вебхук
+type WebhookDelivery struct {
+	ID         int64     `json:"id"`
+	WebhookID  int       `json:"webhookId"`
+	DeliveryID string    `json:"deliveryId"`
+	Event      string    `json:"event"`
+	Attempt    int       `json:"attempt"`
+	StatusCode int       `json:"statusCode"`
+	Success    bool      `json:"success"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int       `json:"durationMs"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
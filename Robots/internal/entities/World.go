@@ -0,0 +1,100 @@
+package entities
+
+import "time"
+
+const (
+	// Обычная зона: роботы в ней могут быть, при пересечении границы шлём события
+	ZoneArea = "zone"
+	// Препятствие: занять эту точку физически нельзя
+	ZoneObstacle = "obstacle"
+	// Геозона, куда роботам запрещено заходить
+	ZoneGeofence = "geofence"
+)
+
+// Границы мира, включительно
+type WorldBounds struct {
+	MinX int `json:"minX"`
+	MinY int `json:"minY"`
+	MinZ int `json:"minZ"`
+	MaxX int `json:"maxX"`
+	MaxY int `json:"maxY"`
+	MaxZ int `json:"maxZ"`
+}
+
+func (b WorldBounds) Contains(cord RobotCord) bool {
+	return cord.XCord >= b.MinX && cord.XCord <= b.MaxX &&
+		cord.YCord >= b.MinY && cord.YCord <= b.MaxY &&
+		cord.ZCord >= b.MinZ && cord.ZCord <= b.MaxZ
+}
+
+type Point struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+// Зона - прямоугольник или многоугольник на плоскости XY, вытянутый по Z от MinZ до MaxZ
+// (отсутствующая граница не ограничивает). Для многоугольника MinX..MaxY - его описанный прямоугольник,
+// их считает сервис. Границы включительно
+type Zone struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	Kind      string    `json:"kind"`
+	MinX      int       `json:"minX"`
+	MinY      int       `json:"minY"`
+	MaxX      int       `json:"maxX"`
+	MaxY      int       `json:"maxY"`
+	Polygon   []Point   `json:"polygon,omitempty"`
+	MinZ      *int      `json:"minZ,omitempty"`
+	MaxZ      *int      `json:"maxZ,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Запрещено ли роботу находиться внутри зоны
+func (z *Zone) Blocks() bool {
+	return z.Kind == ZoneObstacle || z.Kind == ZoneGeofence
+}
+
+func (z *Zone) Contains(cord RobotCord) bool {
+	if (z.MinZ != nil && cord.ZCord < *z.MinZ) || (z.MaxZ != nil && cord.ZCord > *z.MaxZ) {
+		return false
+	}
+	if cord.XCord < z.MinX || cord.XCord > z.MaxX || cord.YCord < z.MinY || cord.YCord > z.MaxY {
+		return false
+	}
+	if len(z.Polygon) == 0 {
+		return true
+	}
+	return polygonContains(z.Polygon, cord.XCord, cord.YCord)
+}
+
+// Чётность пересечений луча вправо от точки. Точки на рёбрах считаем внутри.
+// Считаем в int64: сервис ограничивает координаты зон, и точка уже внутри описанного прямоугольника,
+// так что произведения разностей не переполняются
+func polygonContains(polygon []Point, x, y int) bool {
+	px, py := int64(x), int64(y)
+	inside := false
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		ax, ay := int64(polygon[i].X), int64(polygon[i].Y)
+		bx, by := int64(polygon[j].X), int64(polygon[j].Y)
+		cross := (bx-ax)*(py-ay) - (by-ay)*(px-ax)
+		if cross == 0 && min(ax, bx) <= px && px <= max(ax, bx) && min(ay, by) <= py && py <= max(ay, by) {
+			return true
+		}
+		if (ay > py) != (by > py) {
+			// Сравниваем px с точкой пересечения без деления: знак зависит от направления ребра
+			lhs := (px - ax) * (by - ay)
+			rhs := (bx - ax) * (py - ay)
+			if (by > ay && lhs < rhs) || (by < ay && lhs > rhs) {
+				inside = !inside
+			}
+		}
+	}
+	return inside
+}
+
+// Карта целиком: границы (nil - мир не ограничен) и зоны
+type World struct {
+	Bounds *WorldBounds `json:"bounds,omitempty"`
+	Zones  []Zone       `json:"zones"`
+}
@@ -0,0 +1,91 @@
+package entities
+
+import "testing"
+
+func cord(x, y, z int) RobotCord {
+	return RobotCord{XCord: x, YCord: y, ZCord: z}
+}
+
+func height(v int) *int {
+	return &v
+}
+
+func TestWorldBoundsContains(t *testing.T) {
+	bounds := WorldBounds{MinX: -10, MinY: -5, MinZ: 0, MaxX: 10, MaxY: 5, MaxZ: 3}
+	tests := []struct {
+		cord RobotCord
+		want bool
+	}{
+		{cord(0, 0, 0), true},
+		// Границы включительно
+		{cord(-10, -5, 0), true},
+		{cord(10, 5, 3), true},
+		{cord(11, 0, 0), false},
+		{cord(0, -6, 0), false},
+		{cord(0, 0, 4), false},
+		{cord(0, 0, -1), false},
+	}
+	for _, tt := range tests {
+		if got := bounds.Contains(tt.cord); got != tt.want {
+			t.Errorf("Contains(%v) = %v, want %v", tt.cord, got, tt.want)
+		}
+	}
+}
+
+func TestZoneContains(t *testing.T) {
+	box := Zone{MinX: 0, MinY: 0, MaxX: 10, MaxY: 5}
+	layer := Zone{MinX: 0, MinY: 0, MaxX: 10, MaxY: 5, MinZ: height(2), MaxZ: height(4)}
+	above := Zone{MinX: 0, MinY: 0, MaxX: 10, MaxY: 5, MinZ: height(2)}
+	// Невыпуклая буква L: вырез в правом верхнем углу (5..10, 5..10) снаружи
+	polygon := []Point{{0, 0}, {10, 0}, {10, 5}, {5, 5}, {5, 10}, {0, 10}}
+	lshape := Zone{MinX: 0, MinY: 0, MaxX: 10, MaxY: 10, Polygon: polygon}
+	// Треугольник с наклонной гранью: точки на ней внутри, рядом с ней снаружи
+	triangle := Zone{MinX: 0, MinY: 0, MaxX: 10, MaxY: 10, Polygon: []Point{{0, 0}, {10, 0}, {0, 10}}}
+	// Зона на пределе координат: произведения не должны переполняться
+	const limit = 1 << 30
+	huge := Zone{MinX: -limit, MinY: -limit, MaxX: limit, MaxY: limit, Polygon: []Point{{-limit, -limit}, {limit, -limit}, {limit, limit}, {-limit, limit}}}
+
+	tests := []struct {
+		name string
+		zone Zone
+		cord RobotCord
+		want bool
+	}{
+		{"box inside", box, cord(5, 2, 100), true},
+		{"box corner", box, cord(10, 5, 0), true},
+		{"box outside", box, cord(11, 2, 0), false},
+		{"layer inside", layer, cord(5, 2, 3), true},
+		{"layer floor", layer, cord(5, 2, 2), true},
+		{"layer below", layer, cord(5, 2, 1), false},
+		{"layer above", layer, cord(5, 2, 5), false},
+		{"open top", above, cord(5, 2, 1000), true},
+		{"open top below", above, cord(5, 2, 1), false},
+		{"L inside lower arm", lshape, cord(8, 2, 0), true},
+		{"L inside upper arm", lshape, cord(2, 8, 0), true},
+		{"L notch", lshape, cord(8, 8, 0), false},
+		{"L inner corner", lshape, cord(5, 5, 0), true},
+		{"L notch edge", lshape, cord(7, 5, 0), true},
+		{"L vertex", lshape, cord(0, 10, 0), true},
+		{"L outside the box", lshape, cord(11, 2, 0), false},
+		{"triangle hypotenuse", triangle, cord(5, 5, 0), true},
+		{"triangle inside", triangle, cord(3, 3, 0), true},
+		{"triangle beyond hypotenuse", triangle, cord(6, 5, 0), false},
+		{"huge inside", huge, cord(limit-1, -limit+1, 0), true},
+		{"huge edge", huge, cord(limit, 0, 0), true},
+		{"huge outside", huge, cord(limit+1, 0, 0), false},
+	}
+	for _, tt := range tests {
+		if got := tt.zone.Contains(tt.cord); got != tt.want {
+			t.Errorf("%s: Contains(%v) = %v, want %v", tt.name, tt.cord, got, tt.want)
+		}
+	}
+}
+
+func TestZoneBlocks(t *testing.T) {
+	for kind, want := range map[string]bool{ZoneArea: false, ZoneObstacle: true, ZoneGeofence: true} {
+		zone := Zone{Kind: kind}
+		if zone.Blocks() != want {
+			t.Errorf("%s blocks = %v, want %v", kind, zone.Blocks(), want)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package events
+
+import "RobotService/internal/entities"
+
+// Фильтр подписки: пустые поля ничего не ограничивают
+type Filter struct {
+	IDs    []int
+	Type   string
+	Region *Region
+	Labels []entities.LabelSelector
+}
+
+// Прямоугольная область, границы включительно
+type Region struct {
+	MinX, MinY, MinZ int
+	MaxX, MaxY, MaxZ int
+}
+
+func (r Region) Contains(x, y, z int) bool {
+	return x >= r.MinX && x <= r.MaxX &&
+		y >= r.MinY && y <= r.MaxY &&
+		z >= r.MinZ && z <= r.MaxZ
+}
+
+// Метки gap и reset касаются всего потока и проходят любой фильтр
+func (f Filter) Match(event RobotEvent) bool {
+	if event.Marker() {
+		return true
+	}
+	if len(f.IDs) > 0 {
+		found := false
+		for _, id := range f.IDs {
+			if id == event.RobotID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.Type != "" && (event.Robot == nil || event.Robot.Type != f.Type) {
+		return false
+	}
+	if f.Region != nil && (event.Robot == nil || !f.Region.Contains(event.Robot.XCord, event.Robot.YCord, event.Robot.ZCord)) {
+		return false
+	}
+	if len(f.Labels) > 0 && (event.Robot == nil || !entities.MatchLabels(f.Labels, event.Robot.Labels)) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,204 @@
+package events
+
+import (
+	"RobotService/internal/entities"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	KindCreated = "created"
+	KindUpdated = "updated"
+	KindDeleted = "deleted"
+	// Робот пересёк границу зоны карты, в Zone - её имя
+	KindZoneEntered = "zone_entered"
+	KindZoneLeft    = "zone_left"
+	// Перемещение или создание робота отклонено из-за столкновения, Robot - его состояние до попытки
+	KindCollisionPrevented = "collision_prevented"
+	// Движок перемещений сдвинул робота на очередной шаг команды
+	KindPositionChanged = "position_changed"
+	// Миссия робота сменила состояние или шаг, завершилась или провалилась. Описание в Message
+	KindMissionProgress  = "mission_progress"
+	KindMissionCompleted = "mission_completed"
+	KindMissionFailed    = "mission_failed"
+	// Робот взял миссию или освободился: миссия закончилась или отдельная команда перемещения завершена, отменена, провалилась
+	KindStatusChanged = "status_changed"
+
+	// Служебные метки потока, не изменения роботов: у них нет номера и робота, фильтры их пропускают.
+	// gap - подписчик не успевал читать и часть событий для него потеряна, в Message сколько.
+	// reset - продолжить с Last-Event-ID нельзя (сервер перезапущен, другой экземпляр
+	// или событие уже выпало из истории). В обоих случаях клиенту нужно перечитать состояние роботов
+	KindGap   = "gap"
+	KindReset = "reset"
+)
+
+// Изменение робота. Те же события уходят в реббит, а хаб раздаёт их подписчикам внутри процесса.
+// Robot - состояние после изменения, для удаления - последнее состояние перед ним.
+// Actor - принципал, который сделал изменение, MissionID - миссия для событий миссий
+type RobotEvent struct {
+	Sequence   uint64          `json:"sequence"`
+	Kind       string          `json:"kind"`
+	RoutingKey string          `json:"routingKey"`
+	RobotID    int             `json:"robotId"`
+	Robot      *entities.Robot `json:"robot,omitempty"`
+	Message    string          `json:"message,omitempty"`
+	Actor      string          `json:"actor,omitempty"`
+	Zone       string          `json:"zone,omitempty"`
+	MissionID  int             `json:"missionId,omitempty"`
+	Time       time.Time       `json:"time"`
+}
+
+// Служебная метка потока (gap или reset)
+func (e RobotEvent) Marker() bool {
+	return e.Kind == KindGap || e.Kind == KindReset
+}
+
+// Сколько последних событий держим в памяти для переподключений (Last-Event-ID)
+const historySize = 1024
+
+// Рассылка событий подписчикам (gRPC, SSE и WebSocket стримы).
+// Медленный подписчик не тормозит остальных: если его буфер полон, событие для него теряется,
+// а как только место появится, он получит метку gap.
+//
+// Хаб живёт в памяти процесса: подписчик видит изменения, сделанные только через этот экземпляр сервиса,
+// а номера событий после перезапуска начинаются заново. Поэтому ID события для Last-Event-ID
+// содержит эпоху хаба, и переподключение к другому экземпляру или после рестарта даёт метку reset.
+// Несколько экземпляров за балансировщиком стримы не поддерживают, для этого есть события в реббите
+type Hub struct {
+	mu       sync.Mutex
+	epoch    string
+	sequence uint64
+	nextID   int
+	subs     map[int]*subscriber
+	history  []RobotEvent
+}
+
+type subscriber struct {
+	ch chan RobotEvent
+	// Сколько событий потеряно с последней доставки
+	dropped int
+}
+
+func NewHub() *Hub {
+	buf := make([]byte, 4)
+	_, _ = rand.Read(buf)
+	return &Hub{epoch: hex.EncodeToString(buf), subs: map[int]*subscriber{}}
+}
+
+// ID события для SSE: эпоха-номер. У меток ID нет
+func (h *Hub) EventID(event RobotEvent) string {
+	if event.Sequence == 0 {
+		return ""
+	}
+	return h.epoch + "-" + strconv.FormatUint(event.Sequence, 10)
+}
+
+// Проставляем номер и время и рассылаем. На nil хабе ничего не делает
+func (h *Hub) Publish(event RobotEvent) RobotEvent {
+	if h == nil {
+		return event
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.sequence++
+	event.Sequence = h.sequence
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+	h.history = append(h.history, event)
+	if len(h.history) > historySize {
+		h.history = h.history[len(h.history)-historySize:]
+	}
+	for _, sub := range h.subs {
+		sub.send(event)
+	}
+	return event
+}
+
+// Перед первым событием после потерь ставим метку gap. Если и для неё нет места, копим дальше
+func (sub *subscriber) send(event RobotEvent) {
+	if sub.dropped > 0 {
+		gap := RobotEvent{Kind: KindGap, Message: fmt.Sprintf("%d events dropped", sub.dropped), Time: event.Time}
+		select {
+		case sub.ch <- gap:
+			sub.dropped = 0
+		default:
+			sub.dropped++
+			return
+		}
+	}
+	select {
+	case sub.ch <- event:
+	default:
+		sub.dropped++
+	}
+}
+
+// Подписка на новые события. Возвращённую функцию нужно вызвать, когда подписка больше не нужна
+func (h *Hub) Subscribe(buffer int) (<-chan RobotEvent, func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.subscribe(buffer)
+}
+
+// Подписка с догонялкой по Last-Event-ID: сначала события после него из истории, потом новые.
+// Между историей и подпиской ничего не теряется, всё делается под одним локом.
+// Если продолжить нельзя, вместо истории приходит метка reset
+func (h *Hub) SubscribeSince(lastEventID string, buffer int) ([]RobotEvent, <-chan RobotEvent, func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var backlog []RobotEvent
+	sequence, ok := h.resumePoint(lastEventID)
+	if !ok {
+		backlog = append(backlog, RobotEvent{Kind: KindReset, Message: "cannot resume after event " + lastEventID, Time: time.Now().UTC()})
+		sequence = h.sequence
+	}
+	for _, event := range h.history {
+		if event.Sequence > sequence {
+			backlog = append(backlog, event)
+		}
+	}
+	ch, cancel := h.subscribe(buffer)
+	return backlog, ch, cancel
+}
+
+// Номер, после которого продолжать. Новая подписка без ID получает всю историю
+func (h *Hub) resumePoint(lastEventID string) (uint64, bool) {
+	if lastEventID == "" {
+		return 0, true
+	}
+	epoch, number, found := strings.Cut(lastEventID, "-")
+	sequence, err := strconv.ParseUint(number, 10, 64)
+	if !found || err != nil || epoch != h.epoch || sequence > h.sequence {
+		return 0, false
+	}
+	// Следующее за ним событие уже вытеснено из истории
+	if len(h.history) > 0 && h.history[0].Sequence > sequence+1 {
+		return 0, false
+	}
+	return sequence, true
+}
+
+func (h *Hub) subscribe(buffer int) (<-chan RobotEvent, func()) {
+	id := h.nextID
+	h.nextID++
+	ch := make(chan RobotEvent, buffer)
+	h.subs[id] = &subscriber{ch: ch}
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			delete(h.subs, id)
+			close(ch)
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package events
+
+import (
+	"strconv"
+	"testing"
+)
+
+func receive(t *testing.T, ch <-chan RobotEvent) RobotEvent {
+	t.Helper()
+	select {
+	case event := <-ch:
+		return event
+	default:
+		t.Fatal("no event in the channel")
+		return RobotEvent{}
+	}
+}
+
+func TestGapAfterDroppedEvents(t *testing.T) {
+	hub := NewHub()
+	ch, cancel := hub.Subscribe(2)
+	defer cancel()
+
+	for id := 1; id <= 5; id++ {
+		hub.Publish(RobotEvent{Kind: KindUpdated, RobotID: id})
+	}
+	if first, second := receive(t, ch), receive(t, ch); first.RobotID != 1 || second.RobotID != 2 {
+		t.Fatalf("got robots %d and %d, want 1 and 2", first.RobotID, second.RobotID)
+	}
+
+	hub.Publish(RobotEvent{Kind: KindUpdated, RobotID: 6})
+	gap := receive(t, ch)
+	if gap.Kind != KindGap || gap.Sequence != 0 || gap.Message != "3 events dropped" {
+		t.Fatalf("gap = %+v", gap)
+	}
+	if next := receive(t, ch); next.RobotID != 6 {
+		t.Fatalf("after gap got robot %d, want 6", next.RobotID)
+	}
+	if hub.EventID(gap) != "" {
+		t.Fatalf("marker has id %q", hub.EventID(gap))
+	}
+}
+
+func TestGapWaitsForRoom(t *testing.T) {
+	hub := NewHub()
+	ch, cancel := hub.Subscribe(1)
+	defer cancel()
+
+	hub.Publish(RobotEvent{RobotID: 1})
+	hub.Publish(RobotEvent{RobotID: 2})
+	// Для метки тоже нет места, счёт потерь растёт
+	hub.Publish(RobotEvent{RobotID: 3})
+	receive(t, ch)
+	hub.Publish(RobotEvent{RobotID: 4})
+	if gap := receive(t, ch); gap.Kind != KindGap || gap.Message != "2 events dropped" {
+		t.Fatalf("gap = %+v", gap)
+	}
+}
+
+func TestSubscribeSince(t *testing.T) {
+	hub := NewHub()
+	var ids []string
+	for id := 1; id <= 3; id++ {
+		ids = append(ids, hub.EventID(hub.Publish(RobotEvent{RobotID: id})))
+	}
+
+	tests := []struct {
+		name        string
+		lastEventID string
+		reset       bool
+		robots      []int
+	}{
+		{name: "new subscription", robots: []int{1, 2, 3}},
+		{name: "resume", lastEventID: ids[0], robots: []int{2, 3}},
+		{name: "up to date", lastEventID: ids[2]},
+		{name: "other instance", lastEventID: "deadbeef-2", reset: true},
+		{name: "restart", lastEventID: ids[0][:len(ids[0])-1] + "9", reset: true},
+		{name: "legacy numeric id", lastEventID: "2", reset: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backlog, _, cancel := hub.SubscribeSince(tt.lastEventID, 1)
+			defer cancel()
+			if tt.reset {
+				if len(backlog) != 1 || backlog[0].Kind != KindReset {
+					t.Fatalf("backlog = %+v, want a single reset", backlog)
+				}
+				return
+			}
+			var robots []int
+			for _, event := range backlog {
+				robots = append(robots, event.RobotID)
+			}
+			if len(robots) != len(tt.robots) {
+				t.Fatalf("robots = %v, want %v", robots, tt.robots)
+			}
+			for i := range robots {
+				if robots[i] != tt.robots[i] {
+					t.Fatalf("robots = %v, want %v", robots, tt.robots)
+				}
+			}
+		})
+	}
+}
+
+func TestSubscribeSinceEvictedHistory(t *testing.T) {
+	hub := NewHub()
+	first := hub.EventID(hub.Publish(RobotEvent{RobotID: 1}))
+	for id := 2; id <= historySize+1; id++ {
+		hub.Publish(RobotEvent{RobotID: id})
+	}
+	// Событие 2 ещё в истории, продолжить после первого можно
+	if backlog, _, cancel := hub.SubscribeSince(first, 1); len(backlog) != historySize || backlog[0].RobotID != 2 {
+		t.Fatalf("backlog: %d events from robot %d", len(backlog), backlog[0].RobotID)
+	} else {
+		cancel()
+	}
+
+	hub.Publish(RobotEvent{RobotID: historySize + 2})
+	backlog, _, cancel := hub.SubscribeSince(first, 1)
+	defer cancel()
+	if len(backlog) != 1 || backlog[0].Kind != KindReset {
+		t.Fatalf("backlog = %d events, first %+v, want a single reset", len(backlog), backlog[0])
+	}
+}
+
+func TestFilterPassesMarkers(t *testing.T) {
+	filter := Filter{IDs: []int{7}, Type: "drone", Region: &Region{MaxX: 1, MaxY: 1, MaxZ: 1}}
+	for _, kind := range []string{KindGap, KindReset} {
+		if !filter.Match(RobotEvent{Kind: kind}) {
+			t.Fatalf("%s marker filtered out", kind)
+		}
+	}
+	if filter.Match(RobotEvent{Kind: KindUpdated, RobotID: 8}) {
+		t.Fatal("foreign robot passed the filter")
+	}
+}
+
+func TestEventID(t *testing.T) {
+	hub := NewHub()
+	event := hub.Publish(RobotEvent{RobotID: 1})
+	if want := hub.epoch + "-" + strconv.FormatUint(event.Sequence, 10); hub.EventID(event) != want {
+		t.Fatalf("EventID = %q, want %q", hub.EventID(event), want)
+	}
+	if NewHub().epoch == hub.epoch {
+		t.Fatal("two hubs share an epoch")
+	}
+}
@@ -0,0 +1,173 @@
+package grpcserver
+
+import (
+	"RobotService/internal/auth"
+	"RobotService/internal/prometheusinfo"
+	"RobotService/internal/ratelimit"
+	"RobotService/internal/requestinfo"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Сведения о вызове для интерсепторов
+type CallInfo struct {
+	// Полное имя метода: /robots.v1.RobotService/GetRobot
+	Method string
+	Stream bool
+	Peer   string
+	// Метаданные вызова в виде заголовков, чтобы аутентификация была общей с HTTP
+	Header http.Header
+}
+
+// Интерсептор оборачивает весь вызов, и унарный, и стрим.
+// Unary и Stream превращают его в стандартные интерсепторы grpc-go
+type Interceptor func(ctx context.Context, info CallInfo, call func(ctx context.Context) error) error
+
+func (i Interceptor) Unary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		var resp any
+		err := i(ctx, newCallInfo(ctx, info.FullMethod, false), func(ctx context.Context) error {
+			var err error
+			resp, err = handler(ctx, req)
+			return err
+		})
+		return resp, err
+	}
+}
+
+func (i Interceptor) Stream() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return i(ss.Context(), newCallInfo(ss.Context(), info.FullMethod, true), func(ctx context.Context) error {
+			return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+		})
+	}
+}
+
+// Стрим с контекстом, который дополнили интерсепторы (принципал, request id)
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+func newCallInfo(ctx context.Context, method string, stream bool) CallInfo {
+	info := CallInfo{Method: method, Stream: stream, Header: http.Header{}}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		info.Peer = p.Addr.String()
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	for key, values := range md {
+		for _, value := range values {
+			info.Header.Add(key, value)
+		}
+	}
+	return info
+}
+
+// Код ответа для метрик и логов. Ошибки не из статусов считаем так же, как их отдаст сервер
+func callCode(err error) codes.Code {
+	return status.Code(toStatus(err))
+}
+
+// Считаем вызовы и их длительность по методу и коду ответа
+func MetricsInterceptor() Interceptor {
+	return func(ctx context.Context, info CallInfo, call func(ctx context.Context) error) error {
+		start := time.Now()
+		err := call(ctx)
+		code := callCode(err).String()
+
+		prometheusinfo.GRPCRequests.WithLabelValues(info.Method, code).Inc()
+		// Длительность стрима - это время подписки, в гистограмму её не мешаем
+		if !info.Stream {
+			prometheusinfo.GRPCRequestDuration.WithLabelValues(info.Method, code).Observe(time.Since(start).Seconds())
+		}
+		return err
+	}
+}
+
+func LoggingInterceptor(log *slog.Logger) Interceptor {
+	return func(ctx context.Context, info CallInfo, call func(ctx context.Context) error) error {
+		start := time.Now()
+		err := call(ctx)
+		code := callCode(err)
+
+		attrs := []any{"method", info.Method, "peer", info.Peer, "principal", auth.Actor(ctx),
+			"request_id", requestinfo.From(ctx).ID, "code", code.String(), "duration", time.Since(start)}
+		switch code {
+		case codes.OK, codes.Canceled, codes.NotFound, codes.InvalidArgument, codes.Unauthenticated,
+			codes.PermissionDenied, codes.ResourceExhausted, codes.FailedPrecondition:
+			log.Info("gRPC call", attrs...)
+		default:
+			log.Error("gRPC call failed", append(attrs, "error", err)...)
+		}
+		return err
+	}
+}
+
+// Те же правила, что и в HTTP мидлвари: x-api-key или authorization: Bearer.
+// Ставим перед LoggingInterceptor, чтобы в логе вызова был принципал
+func AuthInterceptor(authn *auth.Authenticator, required bool, log *slog.Logger) Interceptor {
+	return func(ctx context.Context, info CallInfo, call func(ctx context.Context) error) error {
+		principal, err := authn.Authenticate(info.Header)
+		switch {
+		case err == nil:
+			ctx = auth.WithPrincipal(ctx, principal)
+		case errors.Is(err, auth.ErrNoCredentials) && !required:
+		case errors.Is(err, auth.ErrNoCredentials), errors.Is(err, auth.ErrInvalidCredentials):
+			log.Warn("gRPC authentication failed", "method", info.Method, "peer", info.Peer, "error", err.Error())
+			return status.Error(codes.Unauthenticated, "authentication required")
+		default:
+			log.Error("gRPC authentication error", "method", info.Method, "error", err.Error())
+			return status.Error(codes.Internal, "internal error")
+		}
+		return call(ctx)
+	}
+}
+
+// Request id из метаданных x-request-id и адрес клиента, для аудита
+func RequestInfoInterceptor() Interceptor {
+	return func(ctx context.Context, info CallInfo, call func(ctx context.Context) error) error {
+		return call(requestinfo.With(ctx, requestinfo.New(info.Header.Get(requestinfo.Header), info.Peer)))
+	}
+}
+
+// Тот же лимит, что и в HTTP. Правило ищется по полному имени метода, стрим считается одним запросом
+func RateLimitInterceptor(limiter *ratelimit.Limiter, log *slog.Logger) Interceptor {
+	return func(ctx context.Context, info CallInfo, call func(ctx context.Context) error) error {
+		decision, err := limiter.Allow(ratelimit.Client(ctx), info.Method)
+		return limitCall(ctx, decision, err, info.Method, call, log)
+	}
+}
+
+// Ведро адреса до аутентификации, как RateLimitIP в HTTP. Ставится перед AuthInterceptor,
+// после RequestInfoInterceptor, от которого берём адрес
+func IPRateLimitInterceptor(limiter *ratelimit.Limiter, log *slog.Logger) Interceptor {
+	return func(ctx context.Context, info CallInfo, call func(ctx context.Context) error) error {
+		decision, err := limiter.AllowIP(requestinfo.From(ctx).SourceIP)
+		return limitCall(ctx, decision, err, ratelimit.PreAuthRoute, call, log)
+	}
+}
+
+func limitCall(ctx context.Context, decision ratelimit.Decision, err error, route string, call func(ctx context.Context) error, log *slog.Logger) error {
+	if err != nil {
+		log.Error("Rate limiter is unavailable", "error", err.Error())
+		return call(ctx)
+	}
+	if !decision.Allowed {
+		prometheusinfo.RateLimited.WithLabelValues(route).Inc()
+		return status.Errorf(codes.ResourceExhausted, "rate limit exceeded, retry after %ss", ratelimit.Seconds(decision.RetryAfter))
+	}
+	return call(ctx)
+}
@@ -0,0 +1,69 @@
+package grpcserver
+
+import (
+	"RobotService/api/robotsv1"
+	"RobotService/internal/entities"
+	"RobotService/internal/events"
+	"encoding/json"
+	"errors"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Перевод между сгенерированными сообщениями и сущностями сервиса
+
+func robotToProto(robot entities.Robot) *robotsv1.Robot {
+	msg := &robotsv1.Robot{
+		Id:     int64(robot.ID),
+		Name:   robot.Name,
+		Type:   robot.Type,
+		XCord:  int64(robot.XCord),
+		YCord:  int64(robot.YCord),
+		ZCord:  int64(robot.ZCord),
+		Labels: robot.Labels,
+	}
+	if len(robot.Attributes) > 0 {
+		attributes, _ := json.Marshal(robot.Attributes)
+		msg.AttributesJson = string(attributes)
+	}
+	return msg
+}
+
+func eventToProto(event events.RobotEvent) *robotsv1.RobotEvent {
+	msg := &robotsv1.RobotEvent{
+		Sequence:   event.Sequence,
+		Kind:       event.Kind,
+		RoutingKey: event.RoutingKey,
+		RobotId:    int64(event.RobotID),
+		Message:    event.Message,
+		Time:       timestamppb.New(event.Time),
+		Zone:       event.Zone,
+	}
+	if event.Robot != nil {
+		msg.Robot = robotToProto(*event.Robot)
+	}
+	return msg
+}
+
+// Атрибуты и патчи ходят JSON объектом в строковом поле
+func decodeObject(data string) (map[string]any, error) {
+	if data == "" {
+		return nil, nil
+	}
+	var object map[string]any
+	if err := json.Unmarshal([]byte(data), &object); err != nil {
+		return nil, errors.New("attributes must be a JSON object")
+	}
+	return object, nil
+}
+
+func intIDs(ids []int64) []int {
+	if len(ids) == 0 {
+		return nil
+	}
+	converted := make([]int, len(ids))
+	for i, id := range ids {
+		converted[i] = int(id)
+	}
+	return converted
+}
@@ -0,0 +1,225 @@
+package grpcserver
+
+import (
+	"RobotService/api/robotsv1"
+	"RobotService/internal/dto"
+	"RobotService/internal/entities"
+	"RobotService/internal/events"
+	"RobotService/internal/prometheusinfo"
+	"RobotService/internal/rbac"
+	"RobotService/internal/services"
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+const (
+	defaultLimit = 100
+	maxLimit     = 1000
+	watchBuffer  = 64
+)
+
+func (s *Server) CreateRobot(ctx context.Context, req *robotsv1.CreateRobotRequest) (*robotsv1.CreateRobotResponse, error) {
+	if req.Name == "" || req.Type == "" {
+		return nil, status.Error(codes.InvalidArgument, "name and type are required")
+	}
+	attributes, err := decodeObject(req.AttributesJson)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err = s.authorize(ctx, robotsv1.RobotService_CreateRobot_FullMethodName, rbac.RobotsCreate, rbac.Target{NewType: req.Type}); err != nil {
+		return nil, err
+	}
+
+	id, err := s.srvc.CreateRobot(ctx, dto.CreateRobotDTO{
+		Name: req.Name, Type: req.Type, XCord: int(req.XCord), YCord: int(req.YCord), ZCord: int(req.ZCord),
+		Attributes: attributes, Labels: req.Labels,
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	prometheusinfo.CreatedRobot.Inc()
+	prometheusinfo.CountOfRobotType.WithLabelValues(req.Type).Inc()
+	return &robotsv1.CreateRobotResponse{Id: int64(id)}, nil
+}
+
+func (s *Server) GetRobot(ctx context.Context, req *robotsv1.GetRobotRequest) (*robotsv1.Robot, error) {
+	if err := s.authorize(ctx, robotsv1.RobotService_GetRobot_FullMethodName, rbac.RobotsRead, rbac.Target{RobotID: int(req.Id)}); err != nil {
+		return nil, err
+	}
+
+	robot, err := s.srvc.GetRobotInfo(ctx, int(req.Id))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	prometheusinfo.GetRobot.Inc()
+	return robotToProto(*robot), nil
+}
+
+func (s *Server) ListRobots(ctx context.Context, req *robotsv1.ListRobotsRequest) (*robotsv1.ListRobotsResponse, error) {
+	if req.Limit < 0 || req.Offset < 0 {
+		return nil, status.Error(codes.InvalidArgument, "limit and offset must not be negative")
+	}
+	limit := int(req.Limit)
+	if limit == 0 {
+		limit = defaultLimit
+	}
+	if err := s.authorize(ctx, robotsv1.RobotService_ListRobots_FullMethodName, rbac.RobotsRead, rbac.Target{}); err != nil {
+		return nil, err
+	}
+
+	filter, err := services.ParseRobotFilter(req.Type, req.LabelSelector, req.AttributeSelectors)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	robots, err := s.srvc.ListRobots(filter, min(limit, maxLimit), int(req.Offset))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	resp := &robotsv1.ListRobotsResponse{Robots: make([]*robotsv1.Robot, len(robots))}
+	for i, robot := range robots {
+		resp.Robots[i] = robotToProto(robot)
+	}
+	return resp, nil
+}
+
+func (s *Server) UpdateRobotCords(ctx context.Context, req *robotsv1.UpdateRobotCordsRequest) (*emptypb.Empty, error) {
+	if err := s.authorize(ctx, robotsv1.RobotService_UpdateRobotCords_FullMethodName, rbac.RobotsMove, rbac.Target{RobotID: int(req.Id)}); err != nil {
+		return nil, err
+	}
+
+	err := s.srvc.UpdateRobotCords(ctx, dto.UpdateRobotCordDTO{ID: int(req.Id), XCord: int(req.XCord), YCord: int(req.YCord), ZCord: int(req.ZCord)})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	prometheusinfo.UpdateRobotCords.Inc()
+	return &emptypb.Empty{}, nil
+}
+
+func (s *Server) UpdateRobotName(ctx context.Context, req *robotsv1.UpdateRobotNameRequest) (*emptypb.Empty, error) {
+	if err := s.authorize(ctx, robotsv1.RobotService_UpdateRobotName_FullMethodName, rbac.RobotsRename, rbac.Target{RobotID: int(req.Id)}); err != nil {
+		return nil, err
+	}
+
+	err := s.srvc.UpdateRobotName(ctx, dto.UpdateRobotNameDTO{ID: int(req.Id), Name: req.Name})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	prometheusinfo.UpdateRobotNames.Inc()
+	return &emptypb.Empty{}, nil
+}
+
+func (s *Server) UpdateRobotAttributes(ctx context.Context, req *robotsv1.UpdateRobotAttributesRequest) (*robotsv1.Robot, error) {
+	patch, err := decodeObject(req.PatchJson)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err = s.authorize(ctx, robotsv1.RobotService_UpdateRobotAttributes_FullMethodName, rbac.RobotsAttributes, rbac.Target{RobotID: int(req.Id)}); err != nil {
+		return nil, err
+	}
+
+	robot, err := s.srvc.PatchRobotAttributes(ctx, int(req.Id), patch)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return robotToProto(*robot), nil
+}
+
+func (s *Server) UpdateRobotLabels(ctx context.Context, req *robotsv1.UpdateRobotLabelsRequest) (*robotsv1.Robot, error) {
+	if err := s.authorize(ctx, robotsv1.RobotService_UpdateRobotLabels_FullMethodName, rbac.RobotsLabels, rbac.Target{RobotID: int(req.Id)}); err != nil {
+		return nil, err
+	}
+
+	var robot *entities.Robot
+	var err error
+	if req.Replace {
+		labels := req.Labels
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		for _, key := range req.Remove {
+			delete(labels, key)
+		}
+		robot, err = s.srvc.SetRobotLabels(ctx, int(req.Id), labels)
+	} else {
+		patch := make(map[string]*string, len(req.Labels)+len(req.Remove))
+		for key, value := range req.Labels {
+			patch[key] = &value
+		}
+		for _, key := range req.Remove {
+			patch[key] = nil
+		}
+		robot, err = s.srvc.PatchRobotLabels(ctx, int(req.Id), patch)
+	}
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return robotToProto(*robot), nil
+}
+
+func (s *Server) ChangeRobotType(ctx context.Context, req *robotsv1.ChangeRobotTypeRequest) (*emptypb.Empty, error) {
+	target := rbac.Target{RobotID: int(req.Id), NewType: req.Type}
+	if err := s.authorize(ctx, robotsv1.RobotService_ChangeRobotType_FullMethodName, rbac.RobotsRetype, target); err != nil {
+		return nil, err
+	}
+
+	err := s.srvc.ChangeRobotType(ctx, dto.ChangeTypeDTO{ID: int(req.Id), Type: req.Type})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	prometheusinfo.CountOfRobotType.WithLabelValues(req.Type).Inc()
+	prometheusinfo.UpdateRobotType.Inc()
+	return &emptypb.Empty{}, nil
+}
+
+func (s *Server) DeleteRobot(ctx context.Context, req *robotsv1.DeleteRobotRequest) (*emptypb.Empty, error) {
+	if err := s.authorize(ctx, robotsv1.RobotService_DeleteRobot_FullMethodName, rbac.RobotsDelete, rbac.Target{RobotID: int(req.Id)}); err != nil {
+		return nil, err
+	}
+
+	err := s.srvc.DeleteRobot(ctx, int(req.Id))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	prometheusinfo.DeletedRobot.Inc()
+	return &emptypb.Empty{}, nil
+}
+
+// Шлём клиенту события из хаба, пока он не отключится
+func (s *Server) WatchRobots(req *robotsv1.WatchRobotsRequest, stream grpc.ServerStreamingServer[robotsv1.RobotEvent]) error {
+	ctx := stream.Context()
+	if s.hub == nil {
+		return status.Error(codes.Unimplemented, "event stream is disabled")
+	}
+	labels, err := services.ParseLabelSelector(req.LabelSelector)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	filter := events.Filter{IDs: intIDs(req.Ids), Type: req.Type, Labels: labels}
+	if err = s.authorize(ctx, robotsv1.RobotService_WatchRobots_FullMethodName, rbac.RobotsRead, rbac.Target{}); err != nil {
+		return err
+	}
+
+	ch, cancel := s.hub.Subscribe(watchBuffer)
+	defer cancel()
+	for {
+		select {
+		case <-ctx.Done():
+			return toStatus(ctx.Err())
+		case event, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			if !filter.Match(event) {
+				continue
+			}
+			if err := stream.Send(eventToProto(event)); err != nil {
+				return err
+			}
+		}
+	}
+}
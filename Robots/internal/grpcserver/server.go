@@ -0,0 +1,67 @@
+package grpcserver
+
+import (
+	"RobotService/api/robotsv1"
+	"RobotService/internal/events"
+	"RobotService/internal/rbac"
+	"RobotService/internal/services"
+	"context"
+	"net"
+
+	"google.golang.org/grpc"
+)
+
+// gRPC сервер на grpc-go, код сообщений и сервиса сгенерирован из api/proto/robots.proto в api/robotsv1.
+// Работает с тем же RbtSrvic, что и HTTP хендлеры
+type Server struct {
+	robotsv1.UnimplementedRobotServiceServer
+
+	srvc   *services.RbtSrvic
+	hub    *events.Hub
+	policy *rbac.Engine
+	grpc   *grpc.Server
+}
+
+// policy == nil - проверки прав выключены. Интерсепторы ставятся и на унарные вызовы, и на стримы,
+// первый - самый внешний
+func NewServer(srvc *services.RbtSrvic, policy *rbac.Engine, interceptors ...Interceptor) *Server {
+	unary := make([]grpc.UnaryServerInterceptor, len(interceptors))
+	stream := make([]grpc.StreamServerInterceptor, len(interceptors))
+	for i, interceptor := range interceptors {
+		unary[i], stream[i] = interceptor.Unary(), interceptor.Stream()
+	}
+
+	s := &Server{srvc: srvc, hub: srvc.Events, policy: policy}
+	s.grpc = grpc.NewServer(grpc.ChainUnaryInterceptor(unary...), grpc.ChainStreamInterceptor(stream...))
+	robotsv1.RegisterRobotServiceServer(s.grpc, s)
+	return s
+}
+
+func (s *Server) ListenAndServe(addr string) error {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(lis)
+}
+
+func (s *Server) Serve(lis net.Listener) error {
+	return s.grpc.Serve(lis)
+}
+
+// Закрываем соединения, текущие вызовы и стримы обрываются
+func (s *Server) Stop() {
+	s.grpc.Stop()
+}
+
+// Те же права, что и у HTTP маршрутов. Имя метода нужно для аудит лога
+func (s *Server) authorize(ctx context.Context, method string, permission rbac.Permission, target rbac.Target) error {
+	if s.policy == nil {
+		return nil
+	}
+	err := s.policy.Check(ctx, permission, target, "grpc", method)
+	if err != nil {
+		return toStatus(err)
+	}
+	return nil
+}
@@ -0,0 +1,262 @@
+package grpcserver
+
+import (
+	"RobotService/api/robotsv1"
+	"RobotService/internal/auth"
+	"RobotService/internal/config"
+	"RobotService/internal/events"
+	"RobotService/internal/ratelimit"
+	"RobotService/internal/repositories"
+	"RobotService/internal/requestinfo"
+	"RobotService/internal/services"
+	"RobotService/internal/sorrage"
+	"RobotService/internal/spatial"
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// Сервер в памяти и настоящий клиент grpc-go к нему
+func newTestClient(t *testing.T, srvc *services.RbtSrvic, interceptors ...Interceptor) robotsv1.RobotServiceClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	server := NewServer(srvc, nil, interceptors...)
+	go func() { _ = server.Serve(lis) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///robots",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return robotsv1.NewRobotServiceClient(conn)
+}
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// Интерсептор, который запоминает, с чем его вызвали
+type callRecorder struct {
+	mu        sync.Mutex
+	calls     []CallInfo
+	requestID string
+}
+
+func (r *callRecorder) interceptor() Interceptor {
+	return func(ctx context.Context, info CallInfo, call func(ctx context.Context) error) error {
+		r.mu.Lock()
+		r.calls = append(r.calls, info)
+		r.requestID = requestinfo.From(ctx).ID
+		r.mu.Unlock()
+		return call(ctx)
+	}
+}
+
+func TestUnaryInterceptorsAndValidation(t *testing.T) {
+	recorder := &callRecorder{}
+	client := newTestClient(t, &services.RbtSrvic{}, RequestInfoInterceptor(), recorder.interceptor())
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-request-id", "req-1")
+	_, err := client.CreateRobot(ctx, &robotsv1.CreateRobotRequest{Type: "drone"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("err = %v, want InvalidArgument", err)
+	}
+	_, err = client.CreateRobot(ctx, &robotsv1.CreateRobotRequest{Name: "r1", Type: "drone", AttributesJson: "[1]"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("bad attributes: err = %v, want InvalidArgument", err)
+	}
+	_, err = client.ListRobots(ctx, &robotsv1.ListRobotsRequest{Limit: -1})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("negative limit: err = %v, want InvalidArgument", err)
+	}
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	if len(recorder.calls) != 3 {
+		t.Fatalf("interceptor saw %d calls, want 3", len(recorder.calls))
+	}
+	call := recorder.calls[0]
+	if call.Method != robotsv1.RobotService_CreateRobot_FullMethodName || call.Stream || call.Peer == "" {
+		t.Fatalf("call info = %+v", call)
+	}
+	if call.Header.Get(requestinfo.Header) != "req-1" || recorder.requestID != "req-1" {
+		t.Fatalf("request id: header %q, context %q", call.Header.Get(requestinfo.Header), recorder.requestID)
+	}
+}
+
+func TestAuthInterceptor(t *testing.T) {
+	client := newTestClient(t, &services.RbtSrvic{}, AuthInterceptor(&auth.Authenticator{}, true, testLogger()))
+
+	_, err := client.ListRobots(context.Background(), &robotsv1.ListRobotsRequest{})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("unary: err = %v, want Unauthenticated", err)
+	}
+	stream, err := client.WatchRobots(context.Background(), &robotsv1.WatchRobotsRequest{})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("stream: err = %v, want Unauthenticated", err)
+	}
+}
+
+func TestRateLimitInterceptor(t *testing.T) {
+	store := sorrage.NewClient(miniredis.RunT(t).Addr())
+	limiter, err := ratelimit.NewLimiter(config.RateLimit{Enabled: true, Default: config.Limit{Requests: 1, Per: time.Minute, Burst: 1}}, store)
+	if err != nil {
+		t.Fatalf("limiter: %v", err)
+	}
+	client := newTestClient(t, &services.RbtSrvic{}, RequestInfoInterceptor(), RateLimitInterceptor(limiter, testLogger()))
+
+	// Первый вызов проходит лимит и падает на проверке, второй уже не пускают
+	if _, err = client.CreateRobot(context.Background(), &robotsv1.CreateRobotRequest{}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("first call: err = %v, want InvalidArgument", err)
+	}
+	if _, err = client.CreateRobot(context.Background(), &robotsv1.CreateRobotRequest{}); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("second call: err = %v, want ResourceExhausted", err)
+	}
+}
+
+func TestIPRateLimitInterceptorBeforeAuth(t *testing.T) {
+	store := sorrage.NewClient(miniredis.RunT(t).Addr())
+	limiter, err := ratelimit.NewLimiter(config.RateLimit{
+		Enabled: true,
+		PerIP:   config.Limit{Requests: 1, Per: time.Minute, Burst: 1},
+		Default: config.Limit{Requests: 60, Per: time.Minute},
+	}, store)
+	if err != nil {
+		t.Fatalf("limiter: %v", err)
+	}
+	client := newTestClient(t, &services.RbtSrvic{}, RequestInfoInterceptor(), IPRateLimitInterceptor(limiter, testLogger()),
+		AuthInterceptor(&auth.Authenticator{}, true, testLogger()))
+
+	// Вызов без учётных данных тратит ведро адреса, следующий до аутентификации не доходит
+	if _, err = client.CreateRobot(context.Background(), &robotsv1.CreateRobotRequest{}); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("first call: err = %v, want Unauthenticated", err)
+	}
+	if _, err = client.CreateRobot(context.Background(), &robotsv1.CreateRobotRequest{}); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("second call: err = %v, want ResourceExhausted", err)
+	}
+}
+
+func TestWatchRobots(t *testing.T) {
+	hub := events.NewHub()
+	recorder := &callRecorder{}
+	client := newTestClient(t, &services.RbtSrvic{Events: hub}, recorder.interceptor())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, err := client.WatchRobots(ctx, &robotsv1.WatchRobotsRequest{Ids: []int64{7}})
+	if err != nil {
+		t.Fatalf("WatchRobots: %v", err)
+	}
+
+	// Подписка появляется на сервере не сразу, поэтому публикуем, пока клиент не получит событие.
+	// События чужого робота фильтр отбрасывает
+	go func() {
+		ticker := time.NewTicker(5 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				hub.Publish(events.RobotEvent{Kind: events.KindUpdated, RobotID: 8})
+				hub.Publish(events.RobotEvent{Kind: events.KindUpdated, RobotID: 7, Zone: "dock"})
+			}
+		}
+	}()
+
+	event, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv: %v", err)
+	}
+	if event.RobotId != 7 || event.Kind != events.KindUpdated || event.Zone != "dock" || event.Sequence == 0 || event.Time == nil {
+		t.Fatalf("event = %+v", event)
+	}
+
+	recorder.mu.Lock()
+	call := recorder.calls[0]
+	recorder.mu.Unlock()
+	if call.Method != robotsv1.RobotService_WatchRobots_FullMethodName || !call.Stream {
+		t.Fatalf("call info = %+v", call)
+	}
+}
+
+func TestWatchRobotsBadSelector(t *testing.T) {
+	client := newTestClient(t, &services.RbtSrvic{Events: events.NewHub()})
+
+	stream, err := client.WatchRobots(context.Background(), &robotsv1.WatchRobotsRequest{LabelSelector: "site in ("})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("err = %v, want InvalidArgument", err)
+	}
+}
+
+// Вызовы, которым нужна база. Без ROBOTS_TEST_DATABASE_URL пропускаются
+func TestRobotCallsWithDatabase(t *testing.T) {
+	dbURL := os.Getenv("ROBOTS_TEST_DATABASE_URL")
+	if dbURL == "" {
+		t.Skip("ROBOTS_TEST_DATABASE_URL is not set")
+	}
+	db, err := sorrage.CreatePostgresPool(dbURL)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(db.Close)
+	if err = sorrage.Migrate(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	srvc := &services.RbtSrvic{
+		RobotRepository: repositories.RobotRepositories{DataBase: db, Index: spatial.New()},
+		Redis:           sorrage.NewClient(miniredis.RunT(t).Addr()),
+		Events:          events.NewHub(),
+	}
+	client := newTestClient(t, srvc)
+	ctx := context.Background()
+
+	created, err := client.CreateRobot(ctx, &robotsv1.CreateRobotRequest{
+		Name: "grpc", Type: "drone", XCord: 3_000_001, YCord: 3_000_001,
+		AttributesJson: `{"model":"x4"}`, Labels: map[string]string{"site": "wh2"},
+	})
+	if err != nil {
+		t.Fatalf("CreateRobot: %v", err)
+	}
+	t.Cleanup(func() { _, _ = client.DeleteRobot(ctx, &robotsv1.DeleteRobotRequest{Id: created.Id}) })
+
+	robot, err := client.GetRobot(ctx, &robotsv1.GetRobotRequest{Id: created.Id})
+	if err != nil {
+		t.Fatalf("GetRobot: %v", err)
+	}
+	if robot.Name != "grpc" || robot.XCord != 3_000_001 || robot.AttributesJson != `{"model":"x4"}` || robot.Labels["site"] != "wh2" {
+		t.Fatalf("robot = %+v", robot)
+	}
+
+	if _, err = client.UpdateRobotName(ctx, &robotsv1.UpdateRobotNameRequest{Id: created.Id, Name: "renamed"}); err != nil {
+		t.Fatalf("UpdateRobotName: %v", err)
+	}
+	if _, err = client.DeleteRobot(ctx, &robotsv1.DeleteRobotRequest{Id: created.Id}); err != nil {
+		t.Fatalf("DeleteRobot: %v", err)
+	}
+	if _, err = client.GetRobot(ctx, &robotsv1.GetRobotRequest{Id: created.Id}); status.Code(err) != codes.NotFound {
+		t.Fatalf("GetRobot after delete: err = %v, want NotFound", err)
+	}
+}
@@ -0,0 +1,47 @@
+package grpcserver
+
+import (
+	"RobotService/internal/lockdown"
+	"RobotService/internal/rbac"
+	"RobotService/internal/repositories"
+	"RobotService/internal/services"
+	"context"
+	"errors"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Переводим ошибки сервиса в статусы gRPC. Ошибки, которые уже статус, отдаются как есть
+func toStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	var pending *services.PendingApprovalError
+	var collision *services.CollisionError
+	switch {
+	case errors.As(err, &pending):
+		// Через gRPC заявку не вернуть, отдаём её номер в сообщении
+		return status.Error(codes.FailedPrecondition, pending.Error())
+	case errors.As(err, &collision):
+		return status.Error(codes.Aborted, collision.Error())
+	case errors.Is(err, services.ErrUnknownRobotType), errors.Is(err, services.ErrAltitudeOutOfRange),
+		errors.Is(err, services.ErrInvalidAttributes), errors.Is(err, services.ErrInvalidLabels),
+		errors.Is(err, services.ErrOutsideWorld), errors.Is(err, services.ErrPositionBlocked):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, lockdown.ErrLockedDown):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, rbac.ErrDenied):
+		return status.Error(codes.PermissionDenied, "permission denied")
+	case errors.Is(err, repositories.ErrRobotNotFound):
+		return status.Error(codes.NotFound, "robot not found")
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+	return status.Error(codes.Internal, "internal error")
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"RobotService/internal/dto"
+	"RobotService/internal/entities"
+	"RobotService/internal/lockdown"
+	"RobotService/internal/middlewares"
+	"RobotService/internal/rbac"
+	"RobotService/internal/repositories"
+	"RobotService/internal/services"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+var approvalStatuses = []string{
+	entities.ApprovalPending, entities.ApprovalApproved, entities.ApprovalRejected,
+	entities.ApprovalExpired, entities.ApprovalFailed,
+}
+
+type ApprovalHandler struct {
+	Srvc   *services.ApprovalService
+	Policy *rbac.Engine
+}
+
+func (hndl *ApprovalHandler) SetRoute(router chi.Router) {
+	read := router.With(middlewares.Authorize(hndl.Policy, rbac.ApprovalsRead, nil))
+	read.Get("/approvals", hndl.ListApprovals)
+	read.Get("/approvals/{id}", hndl.GetApproval)
+	router.With(middlewares.Authorize(hndl.Policy, rbac.ApprovalsDecide, nil)).Post("/approvals/{id}", hndl.DecideApproval)
+}
+
+// @Summary List approval requests
+// @Description Pending and decided change requests, newest first
+// @Tags approvals
+// @Produce json
+// @Param status query string false "pending, approved, rejected, expired or failed"
+// @Param limit query int false "Page size (default 100, max 500)"
+// @Param offset query int false "Offset"
+// @Success 200 {array} entities.Approval
+// @Failure 400 {string} string "Invalid filter"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /approvals [get]
+func (hndl *ApprovalHandler) ListApprovals(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	if status != "" && !slices.Contains(approvalStatuses, status) {
+		http.Error(w, "неизвестный статус", http.StatusBadRequest)
+		return
+	}
+	limit, offset, err := parsePaging(r)
+	if err != nil {
+		http.Error(w, "неверные параметры страницы", http.StatusBadRequest)
+		return
+	}
+
+	approvals, err := hndl.Srvc.ListApprovals(status, limit, offset)
+	if err != nil {
+		writeApprovalError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, approvals)
+}
+
+// @Summary Get approval request
+// @Tags approvals
+// @Produce json
+// @Param id path int true "Approval ID"
+// @Success 200 {object} entities.Approval
+// @Failure 400 {string} string "Invalid ID"
+// @Failure 404 {string} string "Approval not found"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /approvals/{id} [get]
+func (hndl *ApprovalHandler) GetApproval(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "неверный айди", http.StatusBadRequest)
+		return
+	}
+
+	approval, err := hndl.Srvc.GetApproval(id)
+	if err != nil {
+		writeApprovalError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, approval)
+}
+
+// @Summary Approve or reject a change request
+// @Description Approval must come from someone other than the requester and applies the change right away.
+// @Description The requester may reject (cancel) their own request
+// @Tags approvals
+// @Accept json
+// @Produce json
+// @Param id path int true "Approval ID"
+// @Param decision body dto.ApprovalDecisionDTO true "approve or reject"
+// @Param Idempotency-Key header string false "Idempotency key for safe retries"
+// @Success 200 {object} entities.Approval
+// @Failure 400 {string} string "Invalid decision"
+// @Failure 403 {string} string "Self approval or missing permission"
+// @Failure 404 {string} string "Approval not found"
+// @Failure 409 {string} string "Already decided or expired"
+// @Failure 423 {string} string "Locked down"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /approvals/{id} [post]
+func (hndl *ApprovalHandler) DecideApproval(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "неверный айди", http.StatusBadRequest)
+		return
+	}
+	var decision dto.ApprovalDecisionDTO
+	if err = json.NewDecoder(r.Body).Decode(&decision); err != nil {
+		http.Error(w, "проблемы с жсоником", http.StatusBadRequest)
+		return
+	}
+
+	approval, err := hndl.Srvc.Decide(r.Context(), id, decision)
+	if err != nil {
+		writeApprovalError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, approval)
+}
+
+func writeApprovalError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidDecision):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, services.ErrSelfApproval), errors.Is(err, services.ErrApprovalForbidden):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, repositories.ErrApprovalNotFound):
+		http.Error(w, "заявка не найдена", http.StatusNotFound)
+	case errors.Is(err, repositories.ErrApprovalNotPending):
+		http.Error(w, "заявка уже решена или истекла", http.StatusConflict)
+	case errors.Is(err, lockdown.ErrLockedDown):
+		http.Error(w, err.Error(), http.StatusLocked)
+	default:
+		http.Error(w, "Error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"RobotService/internal/entities"
+	"RobotService/internal/middlewares"
+	"RobotService/internal/rbac"
+	"RobotService/internal/services"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type AuditHandler struct {
+	Srvc   services.AuditService
+	Policy *rbac.Engine
+}
+
+func (hndl *AuditHandler) SetRoute(router chi.Router) {
+	read := router.With(middlewares.Authorize(hndl.Policy, rbac.AuditRead, nil))
+	read.Get("/audit", hndl.ListAudit)
+	read.Get("/audit/export", hndl.ExportAudit)
+}
+
+// @Summary Audit log
+// @Description Robot mutations and access denials, newest first
+// @Tags audit
+// @Produce json
+// @Param robot_id query int false "Robot ID"
+// @Param actor query string false "Principal, e.g. api-key:ci or jwt:alice"
+// @Param from query string false "From time, RFC3339 (inclusive)"
+// @Param to query string false "To time, RFC3339 (exclusive)"
+// @Param limit query int false "Page size (default 100, max 1000)"
+// @Param offset query int false "Offset"
+// @Success 200 {array} entities.AuditRecord
+// @Failure 400 {string} string "Invalid filter"
+// @Failure 500 {string} string "Internal error"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /audit [get]
+func (hndl *AuditHandler) ListAudit(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseAuditFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	limit, offset, err := parsePaging(r)
+	if err != nil {
+		http.Error(w, "неверные параметры страницы", http.StatusBadRequest)
+		return
+	}
+
+	records, err := hndl.Srvc.ListAudit(filter, limit, offset)
+	if err != nil {
+		writeAuditError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, records)
+}
+
+// @Summary Export audit log
+// @Description All matching records as JSON Lines, oldest first
+// @Tags audit
+// @Produce application/x-ndjson
+// @Param robot_id query int false "Robot ID"
+// @Param actor query string false "Principal, e.g. api-key:ci or jwt:alice"
+// @Param from query string false "From time, RFC3339 (inclusive)"
+// @Param to query string false "To time, RFC3339 (exclusive)"
+// @Success 200 {string} string "JSON Lines"
+// @Failure 400 {string} string "Invalid filter"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /audit/export [get]
+func (hndl *AuditHandler) ExportAudit(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseAuditFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", `attachment; filename="audit.jsonl"`)
+	// Ошибка посреди выгрузки просто обрывает ответ, статус уже ушёл
+	if err = hndl.Srvc.ExportAudit(w, filter); err != nil {
+		writeAuditError(w, err)
+	}
+}
+
+func parseAuditFilter(r *http.Request) (entities.AuditFilter, error) {
+	query := r.URL.Query()
+	filter := entities.AuditFilter{Actor: query.Get("actor")}
+
+	if value := query.Get("robot_id"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			return filter, errors.New("неверный robot_id")
+		}
+		filter.RobotID = &id
+	}
+	for name, field := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		value := query.Get(name)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return filter, errors.New(name + ": нужно время в RFC3339")
+		}
+		*field = &parsed
+	}
+	return filter, nil
+}
+
+func writeAuditError(w http.ResponseWriter, err error) {
+	if errors.Is(err, services.ErrInvalidAuditFilter) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	http.Error(w, "Error", http.StatusInternalServerError)
+}
@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"RobotService/internal/dto"
+	"RobotService/internal/entities"
+	"RobotService/internal/middlewares"
+	"RobotService/internal/rbac"
+	"RobotService/internal/repositories"
+	"RobotService/internal/services"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+var commandStatuses = []string{
+	entities.CommandRunning, entities.CommandCompleted, entities.CommandCancelled, entities.CommandFailed,
+}
+
+type CommandHandler struct {
+	Srvc   *services.MotionService
+	Policy *rbac.Engine
+}
+
+func (hndl *CommandHandler) SetRoute(router chi.Router) {
+	can := func(permission rbac.Permission, target middlewares.TargetFunc) func(http.Handler) http.Handler {
+		return middlewares.Authorize(hndl.Policy, permission, target)
+	}
+	router.With(can(rbac.RobotsMove, middlewares.RobotFromURL)).Post("/robots/{id}/move", hndl.Move)
+	router.With(can(rbac.RobotsRead, nil)).Get("/commands", hndl.ListCommands)
+	router.With(can(rbac.RobotsRead, hndl.commandRobot)).Get("/commands/{id}", hndl.GetCommand)
+	router.With(can(rbac.RobotsMove, hndl.commandRobot)).Delete("/commands/{id}", hndl.CancelCommand)
+}
+
+// Цель проверки прав для /commands/{id} - робот команды
+func (hndl *CommandHandler) commandRobot(r *http.Request) (rbac.Target, error) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		return rbac.Target{}, err
+	}
+	command, err := hndl.Srvc.GetCommand(id)
+	if err != nil {
+		return rbac.Target{}, err
+	}
+	return rbac.Target{RobotID: command.RobotID}, nil
+}
+
+// @Summary Move robot along a planned path
+// @Description Accept a move command: plan a path to the target (or take the active path with useActivePath) and let the motion engine
+// @Description drive the robot along it at the max speed of its type, emitting robots.PositionChanged on every step.
+// @Description The path reserves space-time cells (see /reservations): the robot waits for cells reserved by others
+// @Description or the path is replanned around them.
+// @Description Progress is available at the Location URL. When the command ends (completed, failed or cancelled)
+// @Description robots.StatusChanged reports the robot idle, so the mission allocator can pick it up
+// @Tags commands
+// @Accept json
+// @Produce json
+// @Param id path int true "Robot ID"
+// @Param move body dto.MoveDTO true "Target"
+// @Param Idempotency-Key header string false "Idempotency key for safe retries"
+// @Success 202 {object} entities.MoveCommand
+// @Header 202 {string} Location "/commands/{id}"
+// @Failure 400 {string} string "Invalid JSON, target outside the world, blocked or out of the altitude range"
+// @Failure 404 {string} string "Robot not found or has no active path"
+// @Failure 409 {string} string "Robot already has a running command, the active path is stale or conflicts with reservations of other robots"
+// @Failure 422 {string} string "No path to the target"
+// @Failure 423 {string} string "Locked down"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /robots/{id}/move [post]
+func (hndl *CommandHandler) Move(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "неверный айди", http.StatusBadRequest)
+		return
+	}
+	var move dto.MoveDTO
+	if err = json.NewDecoder(r.Body).Decode(&move); err != nil {
+		http.Error(w, "проблемы с жсоником", http.StatusBadRequest)
+		return
+	}
+
+	command, err := hndl.Srvc.Move(r.Context(), id, move)
+	if err != nil {
+		writeCommandError(w, err)
+		return
+	}
+	w.Header().Set("Location", "/commands/"+strconv.Itoa(command.ID))
+	writeJSON(w, http.StatusAccepted, command)
+}
+
+// @Summary List move commands
+// @Description Newest first
+// @Tags commands
+// @Produce json
+// @Param robotId query int false "Only commands of this robot"
+// @Param status query string false "running, completed, cancelled or failed"
+// @Param limit query int false "Page size (default 100, max 1000)"
+// @Param offset query int false "Offset"
+// @Success 200 {array} entities.MoveCommand
+// @Failure 400 {string} string "Invalid filter"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /commands [get]
+func (hndl *CommandHandler) ListCommands(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	status := query.Get("status")
+	if status != "" && !slices.Contains(commandStatuses, status) {
+		http.Error(w, "неизвестный статус", http.StatusBadRequest)
+		return
+	}
+	robotID := 0
+	if value := query.Get("robotId"); value != "" {
+		var err error
+		if robotID, err = strconv.Atoi(value); err != nil || robotID <= 0 {
+			http.Error(w, "неверный айди робота", http.StatusBadRequest)
+			return
+		}
+	}
+	limit, offset, err := parsePaging(r)
+	if err != nil {
+		http.Error(w, "неверные параметры страницы", http.StatusBadRequest)
+		return
+	}
+
+	commands, err := hndl.Srvc.ListCommands(robotID, status, limit, offset)
+	if err != nil {
+		writeCommandError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, commands)
+}
+
+// @Summary Get move command
+// @Description Status and progress of the command: traveled distance, current position and why the robot waits, if it does
+// @Tags commands
+// @Produce json
+// @Param id path int true "Command ID"
+// @Success 200 {object} entities.MoveCommand
+// @Failure 400 {string} string "Invalid ID"
+// @Failure 404 {string} string "Command not found"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /commands/{id} [get]
+func (hndl *CommandHandler) GetCommand(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "неверный айди", http.StatusBadRequest)
+		return
+	}
+
+	command, err := hndl.Srvc.GetCommand(id)
+	if err != nil {
+		writeCommandError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, command)
+}
+
+// @Summary Cancel move command
+// @Description The robot stops where it is
+// @Tags commands
+// @Produce json
+// @Param id path int true "Command ID"
+// @Param Idempotency-Key header string false "Idempotency key for safe retries"
+// @Success 200 {object} entities.MoveCommand
+// @Failure 400 {string} string "Invalid ID"
+// @Failure 404 {string} string "Command not found"
+// @Failure 409 {string} string "Command already finished"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /commands/{id} [delete]
+func (hndl *CommandHandler) CancelCommand(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "неверный айди", http.StatusBadRequest)
+		return
+	}
+
+	command, err := hndl.Srvc.Cancel(r.Context(), id)
+	if err != nil {
+		writeCommandError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, command)
+}
+
+func writeCommandError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, repositories.ErrCommandNotFound):
+		http.Error(w, "команда не найдена", http.StatusNotFound)
+	case errors.Is(err, repositories.ErrRobotBusy), errors.Is(err, repositories.ErrCommandFinished),
+		errors.Is(err, services.ErrStalePath), errors.Is(err, services.ErrReservationConflict):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		writeNavigationError(w, err)
+	}
+}
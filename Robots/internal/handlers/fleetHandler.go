@@ -0,0 +1,431 @@
+package handlers
+
+import (
+	"RobotService/internal/dto"
+	"RobotService/internal/lockdown"
+	"RobotService/internal/middlewares"
+	"RobotService/internal/rbac"
+	"RobotService/internal/repositories"
+	"RobotService/internal/services"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type FleetHandler struct {
+	Srvc   *services.FleetService
+	Policy *rbac.Engine
+}
+
+// Групповые команды затрагивают роботов любых типов и групп, поэтому цели нет:
+// нужны права без ограничений по области
+func (hndl *FleetHandler) SetRoute(router chi.Router) {
+	can := func(permission rbac.Permission) func(http.Handler) http.Handler {
+		return middlewares.Authorize(hndl.Policy, permission, nil)
+	}
+	read := router.With(can(rbac.RobotsRead))
+	write := router.With(can(rbac.FleetsWrite))
+	write.Post("/fleets", hndl.CreateFleet)
+	read.Get("/fleets", hndl.ListFleets)
+	read.Get("/fleets/{id}", hndl.GetFleet)
+	write.Put("/fleets/{id}", hndl.UpdateFleet)
+	write.Delete("/fleets/{id}", hndl.DeleteFleet)
+	read.Get("/fleets/{id}/robots", hndl.FleetRobots)
+	write.Post("/fleets/{id}/members", hndl.AddMembers)
+	write.Delete("/fleets/{id}/members/{robotId}", hndl.RemoveMember)
+	router.With(can(rbac.RobotsMove)).Post("/fleets/{id}/move", hndl.MoveFleet)
+	router.With(can(rbac.RobotsRetype)).Post("/fleets/{id}/retype", hndl.RetypeFleet)
+	router.With(can(rbac.RobotsDelete)).Post("/fleets/{id}/decommission", hndl.DecommissionFleet)
+}
+
+// @Summary Create fleet
+// @Description Create a named group of robots, optionally nested into a parent fleet
+// @Tags fleets
+// @Accept json
+// @Produce json
+// @Param fleet body dto.FleetDTO true "Fleet"
+// @Param Idempotency-Key header string false "Idempotency key for safe retries"
+// @Success 201 {object} entities.Fleet
+// @Failure 400 {string} string "Invalid fleet or parent not found"
+// @Failure 409 {string} string "Fleet already exists"
+// @Failure 500 {string} string "Internal error"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /fleets [post]
+func (hndl *FleetHandler) CreateFleet(w http.ResponseWriter, r *http.Request) {
+	var fleetdto dto.FleetDTO
+	if err := json.NewDecoder(r.Body).Decode(&fleetdto); err != nil {
+		http.Error(w, "проблемы с жсоником", http.StatusBadRequest)
+		return
+	}
+
+	fleet, err := hndl.Srvc.CreateFleet(r.Context(), fleetdto)
+	if err != nil {
+		writeFleetError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, fleet)
+}
+
+// @Summary List fleets
+// @Tags fleets
+// @Produce json
+// @Success 200 {array} entities.Fleet
+// @Failure 500 {string} string "Internal error"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /fleets [get]
+func (hndl *FleetHandler) ListFleets(w http.ResponseWriter, r *http.Request) {
+	fleets, err := hndl.Srvc.ListFleets()
+	if err != nil {
+		writeFleetError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, fleets)
+}
+
+// @Summary Get fleet
+// @Tags fleets
+// @Produce json
+// @Param id path int true "Fleet ID"
+// @Success 200 {object} entities.Fleet
+// @Failure 400 {string} string "Invalid ID"
+// @Failure 404 {string} string "Fleet not found"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /fleets/{id} [get]
+func (hndl *FleetHandler) GetFleet(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "неверный айди", http.StatusBadRequest)
+		return
+	}
+
+	fleet, err := hndl.Srvc.GetFleet(id)
+	if err != nil {
+		writeFleetError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, fleet)
+}
+
+// @Summary Update fleet
+// @Description Replace name, description and parent. A fleet cannot be nested into itself or its descendants
+// @Tags fleets
+// @Accept json
+// @Produce json
+// @Param id path int true "Fleet ID"
+// @Param fleet body dto.FleetDTO true "Fleet"
+// @Param Idempotency-Key header string false "Idempotency key for safe retries"
+// @Success 200 {object} entities.Fleet
+// @Failure 400 {string} string "Invalid fleet or parent not found"
+// @Failure 404 {string} string "Fleet not found"
+// @Failure 409 {string} string "Name taken or nesting cycle"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /fleets/{id} [put]
+func (hndl *FleetHandler) UpdateFleet(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "неверный айди", http.StatusBadRequest)
+		return
+	}
+	var fleetdto dto.FleetDTO
+	if err = json.NewDecoder(r.Body).Decode(&fleetdto); err != nil {
+		http.Error(w, "проблемы с жсоником", http.StatusBadRequest)
+		return
+	}
+
+	fleet, err := hndl.Srvc.UpdateFleet(r.Context(), id, fleetdto)
+	if err != nil {
+		writeFleetError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, fleet)
+}
+
+// @Summary Delete fleet
+// @Description Robots stay, only the group is removed. Nested fleets must be deleted or moved first
+// @Tags fleets
+// @Param id path int true "Fleet ID"
+// @Param Idempotency-Key header string false "Idempotency key for safe retries"
+// @Success 204 {string} string "No Content"
+// @Failure 400 {string} string "Invalid ID"
+// @Failure 404 {string} string "Fleet not found"
+// @Failure 409 {string} string "Fleet has nested fleets"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /fleets/{id} [delete]
+func (hndl *FleetHandler) DeleteFleet(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "неверный айди", http.StatusBadRequest)
+		return
+	}
+
+	if err = hndl.Srvc.DeleteFleet(r.Context(), id); err != nil {
+		writeFleetError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary List fleet robots
+// @Description Direct members, or with recursive=true members of all nested fleets too
+// @Tags fleets
+// @Produce json
+// @Param id path int true "Fleet ID"
+// @Param recursive query bool false "Include nested fleets"
+// @Success 200 {array} entities.Robot
+// @Failure 400 {string} string "Invalid ID"
+// @Failure 404 {string} string "Fleet not found"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /fleets/{id}/robots [get]
+func (hndl *FleetHandler) FleetRobots(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "неверный айди", http.StatusBadRequest)
+		return
+	}
+	recursive := false
+	if value := r.URL.Query().Get("recursive"); value != "" {
+		if recursive, err = strconv.ParseBool(value); err != nil {
+			http.Error(w, "recursive должен быть true или false", http.StatusBadRequest)
+			return
+		}
+	}
+
+	robots, err := hndl.Srvc.FleetRobots(id, recursive)
+	if err != nil {
+		writeFleetError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, robots)
+}
+
+// @Summary Add robots to fleet
+// @Description Robots that are already members are skipped. A robot may belong to several fleets
+// @Tags fleets
+// @Accept json
+// @Produce json
+// @Param id path int true "Fleet ID"
+// @Param members body dto.FleetMembersDTO true "Robot IDs"
+// @Param Idempotency-Key header string false "Idempotency key for safe retries"
+// @Success 200 {object} entities.Fleet
+// @Failure 400 {string} string "Invalid ID list"
+// @Failure 404 {string} string "Fleet or robot not found"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /fleets/{id}/members [post]
+func (hndl *FleetHandler) AddMembers(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "неверный айди", http.StatusBadRequest)
+		return
+	}
+	var members dto.FleetMembersDTO
+	if err = json.NewDecoder(r.Body).Decode(&members); err != nil {
+		http.Error(w, "проблемы с жсоником", http.StatusBadRequest)
+		return
+	}
+
+	fleet, err := hndl.Srvc.AddMembers(r.Context(), id, members.RobotIDs)
+	if err != nil {
+		writeFleetError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, fleet)
+}
+
+// @Summary Remove robot from fleet
+// @Tags fleets
+// @Param id path int true "Fleet ID"
+// @Param robotId path int true "Robot ID"
+// @Param Idempotency-Key header string false "Idempotency key for safe retries"
+// @Success 204 {string} string "No Content"
+// @Failure 400 {string} string "Invalid ID"
+// @Failure 404 {string} string "Robot is not a member"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /fleets/{id}/members/{robotId} [delete]
+func (hndl *FleetHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "неверный айди", http.StatusBadRequest)
+		return
+	}
+	robotID, err := strconv.Atoi(chi.URLParam(r, "robotId"))
+	if err != nil {
+		http.Error(w, "неверный айди робота", http.StatusBadRequest)
+		return
+	}
+
+	if err = hndl.Srvc.RemoveMember(r.Context(), id, robotID); err != nil {
+		writeFleetError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary Move fleet
+// @Description Shift every robot of the fleet and its nested fleets by the offset in one transaction.
+// @Description If any robot leaves the altitude range of its type or would come too close to a robot outside the fleet, nothing moves
+// @Tags fleets
+// @Accept json
+// @Produce json
+// @Param id path int true "Fleet ID"
+// @Param offset body dto.FleetMoveDTO true "Offset"
+// @Param Idempotency-Key header string false "Idempotency key for safe retries"
+// @Success 200 {object} entities.FleetOperation
+// @Failure 400 {string} string "Invalid JSON, altitude out of range or position off the map"
+// @Failure 404 {string} string "Fleet not found"
+// @Failure 409 {object} services.CollisionError "Action requires per-robot approval, or a member would come too close to another robot"
+// @Failure 423 {string} string "Locked down"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /fleets/{id}/move [post]
+func (hndl *FleetHandler) MoveFleet(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "неверный айди", http.StatusBadRequest)
+		return
+	}
+	var offset dto.FleetMoveDTO
+	if err = json.NewDecoder(r.Body).Decode(&offset); err != nil {
+		http.Error(w, "проблемы с жсоником", http.StatusBadRequest)
+		return
+	}
+
+	operation, err := hndl.Srvc.MoveFleet(r.Context(), id, offset)
+	if err != nil {
+		writeFleetError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, operation)
+}
+
+// @Summary Retype fleet
+// @Description Change the type of every robot of the fleet and its nested fleets in one transaction
+// @Tags fleets
+// @Accept json
+// @Produce json
+// @Param id path int true "Fleet ID"
+// @Param retype body dto.FleetRetypeDTO true "New type"
+// @Param Idempotency-Key header string false "Idempotency key for safe retries"
+// @Success 200 {object} entities.FleetOperation
+// @Failure 400 {string} string "Invalid JSON, unknown type or robot does not fit the type"
+// @Failure 404 {string} string "Fleet not found"
+// @Failure 409 {string} string "Action requires per-robot approval"
+// @Failure 423 {string} string "Locked down"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /fleets/{id}/retype [post]
+func (hndl *FleetHandler) RetypeFleet(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "неверный айди", http.StatusBadRequest)
+		return
+	}
+	var retype dto.FleetRetypeDTO
+	if err = json.NewDecoder(r.Body).Decode(&retype); err != nil || retype.Type == "" {
+		http.Error(w, "нужен тип", http.StatusBadRequest)
+		return
+	}
+
+	operation, err := hndl.Srvc.RetypeFleet(r.Context(), id, retype.Type)
+	if err != nil {
+		writeFleetError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, operation)
+}
+
+// @Summary Decommission fleet
+// @Description Delete every robot of the fleet and its nested fleets in one transaction. The fleets stay empty
+// @Tags fleets
+// @Produce json
+// @Param id path int true "Fleet ID"
+// @Param Idempotency-Key header string false "Idempotency key for safe retries"
+// @Success 200 {object} entities.FleetOperation
+// @Failure 400 {string} string "Invalid ID"
+// @Failure 404 {string} string "Fleet not found"
+// @Failure 409 {string} string "Action requires per-robot approval"
+// @Failure 423 {string} string "Locked down"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /fleets/{id}/decommission [post]
+func (hndl *FleetHandler) DecommissionFleet(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "неверный айди", http.StatusBadRequest)
+		return
+	}
+
+	operation, err := hndl.Srvc.DecommissionFleet(r.Context(), id)
+	if err != nil {
+		writeFleetError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, operation)
+}
+
+func writeFleetError(w http.ResponseWriter, err error) {
+	var collision *services.CollisionError
+	switch {
+	case errors.As(err, &collision):
+		writeJSON(w, http.StatusConflict, collision)
+	case errors.Is(err, repositories.ErrFleetNotFound):
+		http.Error(w, "флот не найден", http.StatusNotFound)
+	case errors.Is(err, repositories.ErrRobotNotFound), errors.Is(err, repositories.ErrNotFleetMember):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, services.ErrInvalidFleet), errors.Is(err, repositories.ErrFleetParentNotFound),
+		errors.Is(err, services.ErrUnknownRobotType), errors.Is(err, services.ErrAltitudeOutOfRange),
+		errors.Is(err, services.ErrInvalidAttributes), errors.Is(err, services.ErrOutsideWorld),
+		errors.Is(err, services.ErrPositionBlocked):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, repositories.ErrFleetExists), errors.Is(err, repositories.ErrFleetCycle),
+		errors.Is(err, repositories.ErrFleetHasChildren), errors.Is(err, services.ErrFleetNeedsApproval):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, lockdown.ErrLockedDown):
+		http.Error(w, err.Error(), http.StatusLocked)
+	default:
+		http.Error(w, "Error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"RobotService/internal/dto"
+	"RobotService/internal/lockdown"
+	"RobotService/internal/middlewares"
+	"RobotService/internal/rbac"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type LockdownHandler struct {
+	Guard  *lockdown.Guard
+	Policy *rbac.Engine
+}
+
+func (hndl *LockdownHandler) SetRoute(router chi.Router) {
+	router.With(middlewares.Authorize(hndl.Policy, rbac.LockdownRead, nil)).Get("/lockdown", hndl.GetLockdown)
+	manage := router.With(middlewares.Authorize(hndl.Policy, rbac.LockdownManage, nil))
+	manage.Put("/lockdown", hndl.Lock)
+	manage.Delete("/lockdown", hndl.Unlock)
+}
+
+// @Summary Current lockdown mode
+// @Description normal, deletes_frozen (robot deletes rejected) or read_only (all robot mutations rejected)
+// @Tags lockdown
+// @Produce json
+// @Success 200 {object} entities.LockdownState
+// @Failure 500 {string} string "Internal error"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /lockdown [get]
+func (hndl *LockdownHandler) GetLockdown(w http.ResponseWriter, r *http.Request) {
+	state, err := hndl.Guard.State()
+	if err != nil {
+		http.Error(w, "Error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, state)
+}
+
+// @Summary Enable lockdown manually
+// @Description Replaces the current mode, including one enabled automatically
+// @Tags lockdown
+// @Accept json
+// @Produce json
+// @Param lockdown body dto.LockdownDTO true "Mode and reason"
+// @Success 200 {object} entities.LockdownState
+// @Failure 400 {string} string "Invalid mode"
+// @Failure 500 {string} string "Internal error"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /lockdown [put]
+func (hndl *LockdownHandler) Lock(w http.ResponseWriter, r *http.Request) {
+	var data dto.LockdownDTO
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		http.Error(w, "проблемы с жсоником", http.StatusBadRequest)
+		return
+	}
+
+	state, err := hndl.Guard.Lock(r.Context(), data.Mode, data.Reason)
+	if errors.Is(err, lockdown.ErrInvalidMode) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, state)
+}
+
+// @Summary Lift lockdown
+// @Description Back to normal mode. Rule counters start over from this moment
+// @Tags lockdown
+// @Produce json
+// @Success 200 {object} entities.LockdownState
+// @Failure 500 {string} string "Internal error"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /lockdown [delete]
+func (hndl *LockdownHandler) Unlock(w http.ResponseWriter, r *http.Request) {
+	state, err := hndl.Guard.Unlock(r.Context())
+	if err != nil {
+		http.Error(w, "Error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, state)
+}
@@ -0,0 +1,289 @@
+package handlers
+
+import (
+	"RobotService/internal/dto"
+	"RobotService/internal/entities"
+	"RobotService/internal/middlewares"
+	"RobotService/internal/rbac"
+	"RobotService/internal/repositories"
+	"RobotService/internal/services"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+var missionStatuses = []string{
+	entities.MissionQueued, entities.MissionActive, entities.MissionPaused,
+	entities.MissionCompleted, entities.MissionFailed, entities.MissionCancelled,
+}
+
+type MissionHandler struct {
+	Srvc   *services.MissionService
+	Policy *rbac.Engine
+}
+
+func (hndl *MissionHandler) SetRoute(router chi.Router) {
+	can := func(permission rbac.Permission, target middlewares.TargetFunc) func(http.Handler) http.Handler {
+		return middlewares.Authorize(hndl.Policy, permission, target)
+	}
+	write := router.With(can(rbac.MissionsWrite, nil))
+	write.Post("/missions", hndl.CreateMission)
+	router.With(can(rbac.RobotsRead, nil)).Get("/missions", hndl.ListMissions)
+	router.With(can(rbac.RobotsRead, nil)).Get("/missions/{id}", hndl.GetMission)
+	// Назначенный робот будет ездить по шагам миссии, так что нужно и право двигать его
+	write.With(can(rbac.RobotsMove, middlewares.RobotIDFromBody)).Post("/missions/{id}/assign", hndl.AssignMission)
+	write.Post("/missions/{id}/pause", hndl.PauseMission)
+	write.Post("/missions/{id}/resume", hndl.ResumeMission)
+	write.Post("/missions/{id}/cancel", hndl.CancelMission)
+}
+
+// @Summary Create mission
+// @Description Create a mission of ordered steps: move (xCord, yCord, zCord), wait (seconds), pickup (payload) and dropoff (payload).
+// @Description The mission is queued; with robotId it starts as soon as the robot finishes its previous mission.
+// @Description Assigning a robot here requires robots:move on it
+// @Tags missions
+// @Accept json
+// @Produce json
+// @Param mission body dto.CreateMissionDTO true "Mission"
+// @Param Idempotency-Key header string false "Idempotency key for safe retries"
+// @Success 201 {object} entities.Mission
+// @Failure 400 {string} string "Invalid JSON or mission"
+// @Failure 404 {string} string "Robot not found"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /missions [post]
+func (hndl *MissionHandler) CreateMission(w http.ResponseWriter, r *http.Request) {
+	var mission dto.CreateMissionDTO
+	if err := json.NewDecoder(r.Body).Decode(&mission); err != nil {
+		http.Error(w, "проблемы с жсоником", http.StatusBadRequest)
+		return
+	}
+	if mission.RobotID != 0 && hndl.Policy != nil {
+		err := hndl.Policy.Check(r.Context(), rbac.RobotsMove, rbac.Target{RobotID: mission.RobotID}, "http", r.Method+" "+r.URL.Path)
+		if errors.Is(err, rbac.ErrDenied) {
+			http.Error(w, "недостаточно прав", http.StatusForbidden)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	created, err := hndl.Srvc.CreateMission(r.Context(), mission)
+	if err != nil {
+		writeMissionError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, created)
+}
+
+// @Summary List missions
+// @Description Newest first
+// @Tags missions
+// @Produce json
+// @Param robotId query int false "Only missions of this robot"
+// @Param status query string false "queued, active, paused, completed, failed or cancelled"
+// @Param limit query int false "Page size (default 100, max 1000)"
+// @Param offset query int false "Offset"
+// @Success 200 {array} entities.Mission
+// @Failure 400 {string} string "Invalid filter"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /missions [get]
+func (hndl *MissionHandler) ListMissions(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	status := query.Get("status")
+	if status != "" && !slices.Contains(missionStatuses, status) {
+		http.Error(w, "неизвестный статус", http.StatusBadRequest)
+		return
+	}
+	robotID := 0
+	if value := query.Get("robotId"); value != "" {
+		var err error
+		if robotID, err = strconv.Atoi(value); err != nil || robotID <= 0 {
+			http.Error(w, "неверный айди робота", http.StatusBadRequest)
+			return
+		}
+	}
+	limit, offset, err := parsePaging(r)
+	if err != nil {
+		http.Error(w, "неверные параметры страницы", http.StatusBadRequest)
+		return
+	}
+
+	missions, err := hndl.Srvc.ListMissions(robotID, status, limit, offset)
+	if err != nil {
+		writeMissionError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, missions)
+}
+
+// @Summary Get mission
+// @Description State, current step and carried payload of the mission
+// @Tags missions
+// @Produce json
+// @Param id path int true "Mission ID"
+// @Success 200 {object} entities.Mission
+// @Failure 400 {string} string "Invalid ID"
+// @Failure 404 {string} string "Mission not found"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /missions/{id} [get]
+func (hndl *MissionHandler) GetMission(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "неверный айди", http.StatusBadRequest)
+		return
+	}
+
+	mission, err := hndl.Srvc.GetMission(id)
+	if err != nil {
+		writeMissionError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, mission)
+}
+
+// @Summary Assign mission to a robot
+// @Description Only a queued mission can be (re)assigned. Requires robots:move on the robot
+// @Tags missions
+// @Accept json
+// @Produce json
+// @Param id path int true "Mission ID"
+// @Param assign body dto.AssignMissionDTO true "Robot"
+// @Param Idempotency-Key header string false "Idempotency key for safe retries"
+// @Success 200 {object} entities.Mission
+// @Failure 400 {string} string "Invalid JSON or ID"
+// @Failure 404 {string} string "Mission or robot not found"
+// @Failure 409 {string} string "Mission is not queued"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /missions/{id}/assign [post]
+func (hndl *MissionHandler) AssignMission(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "неверный айди", http.StatusBadRequest)
+		return
+	}
+	var assign dto.AssignMissionDTO
+	if err = json.NewDecoder(r.Body).Decode(&assign); err != nil || assign.RobotID <= 0 {
+		http.Error(w, "проблемы с жсоником", http.StatusBadRequest)
+		return
+	}
+
+	mission, err := hndl.Srvc.Assign(r.Context(), id, assign.RobotID)
+	if err != nil {
+		writeMissionError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, mission)
+}
+
+// @Summary Pause mission
+// @Description The current move command is cancelled; after resume the current step starts over
+// @Tags missions
+// @Produce json
+// @Param id path int true "Mission ID"
+// @Param Idempotency-Key header string false "Idempotency key for safe retries"
+// @Success 200 {object} entities.Mission
+// @Failure 400 {string} string "Invalid ID"
+// @Failure 404 {string} string "Mission not found"
+// @Failure 409 {string} string "Mission is not active"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /missions/{id}/pause [post]
+func (hndl *MissionHandler) PauseMission(w http.ResponseWriter, r *http.Request) {
+	hndl.change(w, r, hndl.Srvc.Pause)
+}
+
+// @Summary Resume mission
+// @Tags missions
+// @Produce json
+// @Param id path int true "Mission ID"
+// @Param Idempotency-Key header string false "Idempotency key for safe retries"
+// @Success 200 {object} entities.Mission
+// @Failure 400 {string} string "Invalid ID"
+// @Failure 404 {string} string "Mission not found"
+// @Failure 409 {string} string "Mission is not paused"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /missions/{id}/resume [post]
+func (hndl *MissionHandler) ResumeMission(w http.ResponseWriter, r *http.Request) {
+	hndl.change(w, r, hndl.Srvc.Resume)
+}
+
+// @Summary Cancel mission
+// @Description The robot stops where it is
+// @Tags missions
+// @Produce json
+// @Param id path int true "Mission ID"
+// @Param Idempotency-Key header string false "Idempotency key for safe retries"
+// @Success 200 {object} entities.Mission
+// @Failure 400 {string} string "Invalid ID"
+// @Failure 404 {string} string "Mission not found"
+// @Failure 409 {string} string "Mission already finished"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /missions/{id}/cancel [post]
+func (hndl *MissionHandler) CancelMission(w http.ResponseWriter, r *http.Request) {
+	hndl.change(w, r, hndl.Srvc.Cancel)
+}
+
+// Общая часть pause, resume и cancel
+func (hndl *MissionHandler) change(w http.ResponseWriter, r *http.Request, apply func(ctx context.Context, id int) (*entities.Mission, error)) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "неверный айди", http.StatusBadRequest)
+		return
+	}
+
+	mission, err := apply(r.Context(), id)
+	if err != nil {
+		writeMissionError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, mission)
+}
+
+func writeMissionError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, repositories.ErrMissionNotFound):
+		http.Error(w, "миссия не найдена", http.StatusNotFound)
+	case errors.Is(err, repositories.ErrRobotNotFound):
+		http.Error(w, "робот не найден", http.StatusNotFound)
+	case errors.Is(err, services.ErrInvalidMission):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, services.ErrMissionState), errors.Is(err, repositories.ErrRobotOnMission):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, "Error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"RobotService/internal/dto"
+	"RobotService/internal/middlewares"
+	"RobotService/internal/pathfind"
+	"RobotService/internal/rbac"
+	"RobotService/internal/repositories"
+	"RobotService/internal/services"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type NavigationHandler struct {
+	Srvc   *services.NavigationService
+	Policy *rbac.Engine
+}
+
+func (hndl *NavigationHandler) SetRoute(router chi.Router) {
+	can := func(permission rbac.Permission) func(http.Handler) http.Handler {
+		return middlewares.Authorize(hndl.Policy, permission, middlewares.RobotFromURL)
+	}
+	router.With(can(rbac.RobotsMove)).Post("/robots/{id}/navigate", hndl.Navigate)
+	router.With(can(rbac.RobotsRead)).Get("/robots/{id}/path", hndl.GetPath)
+	router.With(can(rbac.RobotsMove)).Delete("/robots/{id}/path", hndl.DeletePath)
+}
+
+// @Summary Plan a path for the robot
+// @Description Obstacle- and geofence-avoiding path from the current position to the target over a voxel grid of the world map (Theta*).
+// @Description The robot does not move. With store=true the path replaces the active path of the robot
+// @Tags navigation
+// @Accept json
+// @Produce json
+// @Param id path int true "Robot ID"
+// @Param target body dto.NavigateDTO true "Target point"
+// @Param Idempotency-Key header string false "Idempotency key for safe retries"
+// @Success 200 {object} entities.NavigationPlan
+// @Failure 400 {string} string "Invalid JSON, target outside the world, blocked or out of the altitude range"
+// @Failure 404 {string} string "Robot not found"
+// @Failure 422 {string} string "No path to the target"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /robots/{id}/navigate [post]
+func (hndl *NavigationHandler) Navigate(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "неверный айди", http.StatusBadRequest)
+		return
+	}
+	var target dto.NavigateDTO
+	if err = json.NewDecoder(r.Body).Decode(&target); err != nil {
+		http.Error(w, "проблемы с жсоником", http.StatusBadRequest)
+		return
+	}
+
+	plan, err := hndl.Srvc.Navigate(r.Context(), id, target)
+	if err != nil {
+		writeNavigationError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, plan)
+}
+
+// @Summary Get active path
+// @Tags navigation
+// @Produce json
+// @Param id path int true "Robot ID"
+// @Success 200 {object} entities.NavigationPlan
+// @Failure 400 {string} string "Invalid ID"
+// @Failure 404 {string} string "Robot has no active path"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /robots/{id}/path [get]
+func (hndl *NavigationHandler) GetPath(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "неверный айди", http.StatusBadRequest)
+		return
+	}
+
+	plan, err := hndl.Srvc.GetPath(id)
+	if err != nil {
+		writeNavigationError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, plan)
+}
+
+// @Summary Clear active path
+// @Tags navigation
+// @Param id path int true "Robot ID"
+// @Param Idempotency-Key header string false "Idempotency key for safe retries"
+// @Success 204 {string} string "No Content"
+// @Failure 400 {string} string "Invalid ID"
+// @Failure 404 {string} string "Robot has no active path"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /robots/{id}/path [delete]
+func (hndl *NavigationHandler) DeletePath(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "неверный айди", http.StatusBadRequest)
+		return
+	}
+
+	if err = hndl.Srvc.DeletePath(r.Context(), id); err != nil {
+		writeNavigationError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeNavigationError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, repositories.ErrNoActivePath):
+		http.Error(w, "у робота нет активного маршрута", http.StatusNotFound)
+	case errors.Is(err, pathfind.ErrNoPath), errors.Is(err, pathfind.ErrSearchLimit):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	default:
+		writeServiceError(w, err)
+	}
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"RobotService/internal/middlewares"
+	"RobotService/internal/rbac"
+	"RobotService/internal/services"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type ReservationHandler struct {
+	Srvc   *services.MotionService
+	Policy *rbac.Engine
+}
+
+func (hndl *ReservationHandler) SetRoute(router chi.Router) {
+	router.With(middlewares.Authorize(hndl.Policy, rbac.RobotsRead, nil)).Get("/reservations", hndl.ListReservations)
+	router.With(middlewares.Authorize(hndl.Policy, rbac.RobotsRead, hndl.reservationRobot)).Get("/reservations/{commandId}", hndl.GetReservation)
+}
+
+// Цель проверки прав для /reservations/{commandId} - робот команды
+func (hndl *ReservationHandler) reservationRobot(r *http.Request) (rbac.Target, error) {
+	id, err := strconv.Atoi(chi.URLParam(r, "commandId"))
+	if err != nil {
+		return rbac.Target{}, err
+	}
+	command, err := hndl.Srvc.GetCommand(id)
+	if err != nil {
+		return rbac.Target{}, err
+	}
+	return rbac.Target{RobotID: command.RobotID}, nil
+}
+
+// @Summary List space-time reservations
+// @Description Reservations of running move commands: which engine ticks (slots) they hold and how many voxel cells.
+// @Description Slot times are approximate
+// @Tags reservations
+// @Produce json
+// @Param robotId query int false "Only reservations of this robot"
+// @Success 200 {array} entities.Reservation
+// @Failure 400 {string} string "Invalid robot ID"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /reservations [get]
+func (hndl *ReservationHandler) ListReservations(w http.ResponseWriter, r *http.Request) {
+	robotID := 0
+	if value := r.URL.Query().Get("robotId"); value != "" {
+		var err error
+		if robotID, err = strconv.Atoi(value); err != nil || robotID <= 0 {
+			http.Error(w, "неверный айди робота", http.StatusBadRequest)
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, hndl.Srvc.Reservations(robotID))
+}
+
+// @Summary Get reservation of a move command
+// @Description All reserved cells of the command, by slot. Cell coordinates are the lowest corner of the voxel
+// @Tags reservations
+// @Produce json
+// @Param commandId path int true "Command ID"
+// @Success 200 {object} entities.Reservation
+// @Failure 400 {string} string "Invalid ID"
+// @Failure 404 {string} string "Command not found or holds no reservation"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /reservations/{commandId} [get]
+func (hndl *ReservationHandler) GetReservation(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "commandId"))
+	if err != nil {
+		http.Error(w, "неверный айди", http.StatusBadRequest)
+		return
+	}
+
+	reservation, err := hndl.Srvc.Reservation(id)
+	if errors.Is(err, services.ErrNoReservation) {
+		http.Error(w, "у команды нет резервирования", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		writeCommandError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, reservation)
+}
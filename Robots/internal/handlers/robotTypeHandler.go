@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"RobotService/internal/dto"
+	"RobotService/internal/middlewares"
+	"RobotService/internal/rbac"
+	"RobotService/internal/repositories"
+	"RobotService/internal/services"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type RobotTypeHandler struct {
+	Srvc   *services.RobotTypeService
+	Policy *rbac.Engine
+}
+
+func (hndl *RobotTypeHandler) SetRoute(router chi.Router) {
+	read := router.With(middlewares.Authorize(hndl.Policy, rbac.RobotsRead, nil))
+	write := router.With(middlewares.Authorize(hndl.Policy, rbac.TypesWrite, nil))
+	write.Post("/robot-types", hndl.CreateRobotType)
+	read.Get("/robot-types", hndl.ListRobotTypes)
+	read.Get("/robot-types/{name}", hndl.GetRobotType)
+	write.Put("/robot-types/{name}", hndl.UpdateRobotType)
+	write.Delete("/robot-types/{name}", hndl.DeleteRobotType)
+}
+
+// @Summary Create robot type
+// @Description Register a type with its capabilities. Robots can only be created with registered types
+// @Tags robot-types
+// @Accept json
+// @Produce json
+// @Param robotType body dto.CreateRobotTypeDTO true "Robot type"
+// @Param Idempotency-Key header string false "Idempotency key for safe retries"
+// @Success 201 {object} entities.RobotType
+// @Failure 400 {string} string "Invalid robot type"
+// @Failure 409 {string} string "Robot type already exists"
+// @Failure 500 {string} string "Internal error"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /robot-types [post]
+func (hndl *RobotTypeHandler) CreateRobotType(w http.ResponseWriter, r *http.Request) {
+	var createdto dto.CreateRobotTypeDTO
+	if err := json.NewDecoder(r.Body).Decode(&createdto); err != nil {
+		http.Error(w, "проблемы с жсоником", http.StatusBadRequest)
+		return
+	}
+
+	robotType, err := hndl.Srvc.CreateRobotType(r.Context(), createdto)
+	if err != nil {
+		writeRobotTypeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, robotType)
+}
+
+// @Summary List robot types
+// @Tags robot-types
+// @Produce json
+// @Success 200 {array} entities.RobotType
+// @Failure 500 {string} string "Internal error"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /robot-types [get]
+func (hndl *RobotTypeHandler) ListRobotTypes(w http.ResponseWriter, r *http.Request) {
+	robotTypes, err := hndl.Srvc.ListRobotTypes()
+	if err != nil {
+		writeRobotTypeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, robotTypes)
+}
+
+// @Summary Get robot type
+// @Tags robot-types
+// @Produce json
+// @Param name path string true "Type name"
+// @Success 200 {object} entities.RobotType
+// @Failure 404 {string} string "Robot type not found"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /robot-types/{name} [get]
+func (hndl *RobotTypeHandler) GetRobotType(w http.ResponseWriter, r *http.Request) {
+	robotType, err := hndl.Srvc.GetRobotType(chi.URLParam(r, "name"))
+	if err != nil {
+		writeRobotTypeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, robotType)
+}
+
+// @Summary Update robot type
+// @Description Replace type capabilities. The name cannot change, robots reference it
+// @Tags robot-types
+// @Accept json
+// @Produce json
+// @Param name path string true "Type name"
+// @Param robotType body dto.UpdateRobotTypeDTO true "Robot type"
+// @Param Idempotency-Key header string false "Idempotency key for safe retries"
+// @Success 200 {object} entities.RobotType
+// @Failure 400 {string} string "Invalid robot type"
+// @Failure 404 {string} string "Robot type not found"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /robot-types/{name} [put]
+func (hndl *RobotTypeHandler) UpdateRobotType(w http.ResponseWriter, r *http.Request) {
+	var updatedto dto.UpdateRobotTypeDTO
+	if err := json.NewDecoder(r.Body).Decode(&updatedto); err != nil {
+		http.Error(w, "проблемы с жсоником", http.StatusBadRequest)
+		return
+	}
+
+	robotType, err := hndl.Srvc.UpdateRobotType(r.Context(), chi.URLParam(r, "name"), updatedto)
+	if err != nil {
+		writeRobotTypeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, robotType)
+}
+
+// @Summary Delete robot type
+// @Description Only types without robots can be deleted
+// @Tags robot-types
+// @Param name path string true "Type name"
+// @Param Idempotency-Key header string false "Idempotency key for safe retries"
+// @Success 204 {string} string "No Content"
+// @Failure 404 {string} string "Robot type not found"
+// @Failure 409 {string} string "Robot type is in use"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /robot-types/{name} [delete]
+func (hndl *RobotTypeHandler) DeleteRobotType(w http.ResponseWriter, r *http.Request) {
+	if err := hndl.Srvc.DeleteRobotType(r.Context(), chi.URLParam(r, "name")); err != nil {
+		writeRobotTypeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeRobotTypeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidRobotType):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, repositories.ErrRobotTypeNotFound):
+		http.Error(w, "тип не найден", http.StatusNotFound)
+	case errors.Is(err, repositories.ErrRobotTypeExists), errors.Is(err, repositories.ErrRobotTypeInUse):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, "Error", http.StatusInternalServerError)
+	}
+}
@@ -2,9 +2,18 @@ package handlers
 
 import (
 	"RobotService/internal/dto"
+	"RobotService/internal/entities"
+	"RobotService/internal/lockdown"
+	"RobotService/internal/middlewares"
 	"RobotService/internal/prometheusinfo"
+	"RobotService/internal/rbac"
+	"RobotService/internal/repositories"
+	"RobotService/internal/robotio"
 	"RobotService/internal/services"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
 	"net/http"
 	"strconv"
 
@@ -13,17 +22,37 @@ import (
 	"github.com/go-chi/chi/v5"
 )
 
+const (
+	defaultLimit = 100
+	maxLimit     = 1000
+)
+
 type RbtHndler struct {
-	Srvc services.RbtSrvic
+	Srvc   services.RbtSrvic
+	Policy *rbac.Engine
+	// Чужие сайты, которым можно открыть вебсокет, см. config.Streams
+	AllowedOrigins []string
 }
 
-func (hndler *RbtHndler) SetRoute(router *chi.Mux) {
-	router.Post("/robots/create", hndler.RobotCreate)
-	router.Get("/robots/{id}", hndler.GetRobotInfo)
-	router.Put("/robots/updatecord", hndler.UpdateRobotCord)
-	router.Put("/robots/updatename", hndler.UpdateRobotName)
-	router.Put("/robots/updatetype", hndler.ChangeRobotType)
-	router.Delete("/robots/delete/{id}", hndler.DeleteRobot)
+func (hndler *RbtHndler) SetRoute(router chi.Router) {
+	can := func(permission rbac.Permission, target middlewares.TargetFunc) func(http.Handler) http.Handler {
+		return middlewares.Authorize(hndler.Policy, permission, target)
+	}
+	router.With(can(rbac.RobotsCreate, middlewares.RobotFromBody)).Post("/robots/create", hndler.RobotCreate)
+	router.With(can(rbac.RobotsRead, nil)).Get("/robots", hndler.ListRobots)
+	router.With(can(rbac.RobotsRead, middlewares.RobotFromURL)).Get("/robots/{id}", hndler.GetRobotInfo)
+	router.With(can(rbac.RobotsMove, middlewares.RobotFromBody)).Put("/robots/updatecord", hndler.UpdateRobotCord)
+	router.With(can(rbac.RobotsRename, middlewares.RobotFromBody)).Put("/robots/updatename", hndler.UpdateRobotName)
+	router.With(can(rbac.RobotsRetype, middlewares.RobotFromBody)).Put("/robots/updatetype", hndler.ChangeRobotType)
+	router.With(can(rbac.RobotsDelete, middlewares.RobotFromURL)).Delete("/robots/delete/{id}", hndler.DeleteRobot)
+	router.With(can(rbac.RobotsAttributes, middlewares.RobotFromURL)).Patch("/robots/{id}", hndler.PatchRobot)
+	router.With(can(rbac.RobotsLabels, middlewares.RobotFromURL)).Put("/robots/{id}/labels", hndler.SetRobotLabels)
+	router.With(can(rbac.RobotsLabels, middlewares.RobotFromURL)).Patch("/robots/{id}/labels", hndler.PatchRobotLabels)
+	router.With(can(rbac.RobotsRead, nil)).Get("/robots/near", hndler.NearRobots)
+	router.With(can(rbac.RobotsRead, nil)).Get("/robots/export", hndler.ExportRobots)
+	router.With(can(rbac.RobotsImport, nil)).Post("/robots/import", hndler.ImportRobots)
+	router.With(can(rbac.RobotsRead, nil)).Get("/robots/stream", hndler.StreamRobots)
+	router.With(can(rbac.RobotsRead, nil)).Get("/robots/ws", hndler.WebSocketRobots)
 }
 
 // @Summary Create new robot
@@ -32,9 +61,17 @@ func (hndler *RbtHndler) SetRoute(router *chi.Mux) {
 // @Accept json
 // @Produce json
 // @Param robot body dto.CreateRobotDTO true "Robot info"
+// @Param Idempotency-Key header string false "Idempotency key for safe retries"
 // @Success 201 {integer} int "Robot ID"
-// @Failure 400 {string} string "Invalid JSON"
+// @Failure 400 {string} string "Invalid JSON, unknown type, altitude out of range or position off the map"
+// @Failure 409 {object} services.CollisionError "Too close to another robot"
 // @Failure 500 {string} string "Internal error"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 423 {string} string "Locked down"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
 // @Router /robots/create [post]
 func (hndlr *RbtHndler) RobotCreate(w http.ResponseWriter, r *http.Request) {
 
@@ -46,9 +83,10 @@ func (hndlr *RbtHndler) RobotCreate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	id, err := hndlr.Srvc.CreateRobot(createdto)
+	id, err := hndlr.Srvc.CreateRobot(r.Context(), createdto)
 	if err != nil {
-		http.Error(w, "err", 500)
+		writeServiceError(w, err)
+		return
 	}
 	prometheusinfo.CreatedRobot.Inc()
 	prometheusinfo.CountOfRobotType.WithLabelValues(createdto.Type).Inc()
@@ -61,6 +99,116 @@ func (hndlr *RbtHndler) RobotCreate(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// @Summary List robots
+// @Description Get a page of robots ordered by ID
+// @Tags robots
+// @Produce json
+// @Param limit query int false "Page size (default 100, max 1000)"
+// @Param offset query int false "Offset"
+// @Param type query string false "Filter by robot type"
+// @Param labelSelector query string false "Label selector: site=wh2,team!=qa,env in (prod,stage),gpu,!deprecated"
+// @Param attr query []string false "Attribute selectors: key, key=value, key!=value, key>n, key>=n, key<n, key<=n; dots go into nested objects" collectionFormat(multi)
+// @Success 200 {array} entities.Robot
+// @Failure 400 {string} string "Invalid paging or selector"
+// @Failure 500 {string} string "Internal server error"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /robots [get]
+func (hndl *RbtHndler) ListRobots(w http.ResponseWriter, r *http.Request) {
+	limit, offset, err := parsePaging(r)
+	if err != nil {
+		http.Error(w, "неверные параметры страницы", 400)
+		return
+	}
+
+	query := r.URL.Query()
+	filter, err := services.ParseRobotFilter(query.Get("type"), query.Get("labelSelector"), query["attr"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	robots, err := hndl.Srvc.ListRobots(filter, limit, offset)
+	if err != nil {
+		http.Error(w, "Error", 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(robots)
+}
+
+// @Summary Find robots near a point
+// @Description Robots within radius of the point and/or the k nearest ones, nearest first (ties by ID).
+// @Description At least one of radius and k is required, without k at most 1000 robots are returned
+// @Tags robots
+// @Produce json
+// @Param x query int true "X"
+// @Param y query int true "Y"
+// @Param z query int true "Z"
+// @Param radius query number false "Maximum distance"
+// @Param k query int false "Number of nearest robots (max 1000)"
+// @Success 200 {array} entities.NearbyRobot
+// @Failure 400 {string} string "Invalid point, radius or k"
+// @Failure 500 {string} string "Internal server error"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /robots/near [get]
+func (hndl *RbtHndler) NearRobots(w http.ResponseWriter, r *http.Request) {
+	center, radius, k, err := parseNearQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	robots, err := hndl.Srvc.NearRobots(center, radius, k)
+	if err != nil {
+		http.Error(w, "Error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, robots)
+}
+
+// Точка x, y, z обязательна, из radius и k нужен хотя бы один
+func parseNearQuery(r *http.Request) (entities.RobotCord, float64, int, error) {
+	query := r.URL.Query()
+	var center entities.RobotCord
+	for _, axis := range []struct {
+		name  string
+		value *int
+	}{{"x", &center.XCord}, {"y", &center.YCord}, {"z", &center.ZCord}} {
+		var err error
+		if *axis.value, err = strconv.Atoi(query.Get(axis.name)); err != nil {
+			return center, 0, 0, fmt.Errorf("%s должен быть целым числом", axis.name)
+		}
+	}
+
+	radius, k := math.Inf(1), maxLimit
+	if value := query.Get("radius"); value != "" {
+		var err error
+		if radius, err = strconv.ParseFloat(value, 64); err != nil || radius < 0 || math.IsNaN(radius) {
+			return center, 0, 0, errors.New("radius должен быть неотрицательным числом")
+		}
+	}
+	if value := query.Get("k"); value != "" {
+		var err error
+		if k, err = strconv.Atoi(value); err != nil || k <= 0 || k > maxLimit {
+			return center, 0, 0, fmt.Errorf("k должен быть от 1 до %d", maxLimit)
+		}
+	}
+	if query.Get("radius") == "" && query.Get("k") == "" {
+		return center, 0, 0, errors.New("нужен radius или k")
+	}
+	return center, radius, k, nil
+}
+
 // @Summary Get robot info
 // @Description Get detailed robot info by ID
 // @Tags robots
@@ -68,7 +216,13 @@ func (hndlr *RbtHndler) RobotCreate(w http.ResponseWriter, r *http.Request) {
 // @Param id path int true "Robot ID"
 // @Success 200 {object} entities.Robot
 // @Failure 400 {string} string "Invalid ID"
+// @Failure 404 {string} string "Robot not found"
 // @Failure 500 {string} string "Internal server error"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
 // @Router /robots/{id} [get]
 func (hndl *RbtHndler) GetRobotInfo(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.Atoi(chi.URLParam(r, "id"))
@@ -77,13 +231,14 @@ func (hndl *RbtHndler) GetRobotInfo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	robotinfo, err := hndl.Srvc.GetRobotInfo(id)
+	robotinfo, err := hndl.Srvc.GetRobotInfo(r.Context(), id)
 	if err != nil {
-		http.Error(w, "Error", 500)
+		writeServiceError(w, err)
 		return
 	}
 
 	prometheusinfo.GetRobot.Inc()
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(robotinfo)
 }
@@ -93,18 +248,32 @@ func (hndl *RbtHndler) GetRobotInfo(w http.ResponseWriter, r *http.Request) {
 // @Tags robots
 // @Accept json
 // @Param robot body dto.UpdateRobotCordDTO true "Updated coordinates"
+// @Param Idempotency-Key header string false "Idempotency key for safe retries"
 // @Success 204 {string} string "No Content"
-// @Failure 400 {string} string "Invalid JSON"
+// @Failure 400 {string} string "Invalid JSON, altitude out of range or position outside the world, in an obstacle or geofence"
+// @Failure 409 {object} services.CollisionError "Path or target too close to another robot"
+// @Failure 404 {string} string "Robot not found"
 // @Failure 500 {string} string "Failed to update robot cords"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 423 {string} string "Locked down"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
 // @Router /robots/updatecord [put]
 func (hdlr *RbtHndler) UpdateRobotCord(w http.ResponseWriter, r *http.Request) {
 	newRobotData := dto.UpdateRobotCordDTO{}
 	err := json.NewDecoder(r.Body).Decode(&newRobotData)
 	if err != nil {
 		http.Error(w, "проблемы с жсоником", 400)
+		return
 	}
 
-	_ = hdlr.Srvc.UpdateRobotCords(newRobotData)
+	err = hdlr.Srvc.UpdateRobotCords(r.Context(), newRobotData)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
 
 	prometheusinfo.UpdateRobotCords.Inc()
 	w.WriteHeader(http.StatusNoContent)
@@ -115,18 +284,31 @@ func (hdlr *RbtHndler) UpdateRobotCord(w http.ResponseWriter, r *http.Request) {
 // @Tags robots
 // @Accept json
 // @Param robot body dto.UpdateRobotNameDTO true "Updated name"
+// @Param Idempotency-Key header string false "Idempotency key for safe retries"
 // @Success 204 {string} string "No Content"
 // @Failure 400 {string} string "Invalid JSON"
+// @Failure 404 {string} string "Robot not found"
 // @Failure 500 {string} string "Failed to update robot name"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 423 {string} string "Locked down"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
 // @Router /robots/updatename [put]
 func (handler *RbtHndler) UpdateRobotName(w http.ResponseWriter, r *http.Request) {
 	newRobotData := dto.UpdateRobotNameDTO{}
 	err := json.NewDecoder(r.Body).Decode(&newRobotData)
 	if err != nil {
 		http.Error(w, "проблемы с жсоником", 400)
+		return
 	}
 
-	_ = handler.Srvc.UpdateRobotName(newRobotData)
+	err = handler.Srvc.UpdateRobotName(r.Context(), newRobotData)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
 	prometheusinfo.UpdateRobotNames.Inc()
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -136,18 +318,32 @@ func (handler *RbtHndler) UpdateRobotName(w http.ResponseWriter, r *http.Request
 // @Tags robots
 // @Accept json
 // @Param robot body dto.ChangeTypeDTO true "Updated type"
+// @Param Idempotency-Key header string false "Idempotency key for safe retries"
 // @Success 204 {string} string "No Content"
-// @Failure 400 {string} string "Invalid JSON"
+// @Success 202 {object} entities.Approval "Waiting for a second approval"
+// @Failure 400 {string} string "Invalid JSON, unknown type or altitude out of range"
+// @Failure 404 {string} string "Robot not found"
 // @Failure 500 {string} string "Failed to update robot type"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 423 {string} string "Locked down"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
 // @Router /robots/updatetype [put]
 func (hdler *RbtHndler) ChangeRobotType(w http.ResponseWriter, r *http.Request) {
 	newRobotData := dto.ChangeTypeDTO{}
 	err := json.NewDecoder(r.Body).Decode(&newRobotData)
 	if err != nil {
 		http.Error(w, "проблемы с жсоником", 400)
+		return
 	}
 
-	_ = hdler.Srvc.ChangeRobotType(newRobotData)
+	err = hdler.Srvc.ChangeRobotType(r.Context(), newRobotData)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
 
 	prometheusinfo.CountOfRobotType.WithLabelValues(newRobotData.Type).Inc()
 	prometheusinfo.UpdateRobotType.Inc()
@@ -158,9 +354,18 @@ func (hdler *RbtHndler) ChangeRobotType(w http.ResponseWriter, r *http.Request)
 // @Description Delete robot by ID
 // @Tags robots
 // @Param id path int true "Robot ID"
+// @Param Idempotency-Key header string false "Idempotency key for safe retries"
 // @Success 204 {string} string "No Content"
+// @Success 202 {object} entities.Approval "Waiting for a second approval"
 // @Failure 400 {string} string "Invalid ID"
+// @Failure 404 {string} string "Robot not found"
 // @Failure 500 {string} string "Failed to delete robot"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 423 {string} string "Locked down"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
 // @Router /robots/delete/{id} [delete]
 func (hnd *RbtHndler) DeleteRobot(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.Atoi(chi.URLParam(r, "id"))
@@ -169,10 +374,245 @@ func (hnd *RbtHndler) DeleteRobot(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = hnd.Srvc.DeleteRobot(id)
+	err = hnd.Srvc.DeleteRobot(r.Context(), id)
 	if err != nil {
+		writeServiceError(w, err)
 		return
 	}
 	prometheusinfo.DeletedRobot.Inc()
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// @Summary Patch robot attributes
+// @Description JSON Merge Patch of custom attributes: null removes a key, nested objects are merged.
+// @Description The result is validated against the attribute schema of the robot type
+// @Tags robots
+// @Accept json
+// @Produce json
+// @Param id path int true "Robot ID"
+// @Param patch body dto.PatchRobotDTO true "Attributes patch"
+// @Param Idempotency-Key header string false "Idempotency key for safe retries"
+// @Success 200 {object} entities.Robot
+// @Failure 400 {string} string "Invalid JSON or attributes do not match the schema"
+// @Failure 404 {string} string "Robot not found"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 423 {string} string "Locked down"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /robots/{id} [patch]
+func (hndl *RbtHndler) PatchRobot(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "неверный айди", http.StatusBadRequest)
+		return
+	}
+	var patch dto.PatchRobotDTO
+	if err = json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		http.Error(w, "проблемы с жсоником", http.StatusBadRequest)
+		return
+	}
+
+	robot, err := hndl.Srvc.PatchRobotAttributes(r.Context(), id, patch.Attributes)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, robot)
+}
+
+// @Summary Replace robot labels
+// @Description Replace all labels of the robot, an empty object removes them all
+// @Tags robots
+// @Accept json
+// @Produce json
+// @Param id path int true "Robot ID"
+// @Param labels body dto.SetRobotLabelsDTO true "New labels"
+// @Param Idempotency-Key header string false "Idempotency key for safe retries"
+// @Success 200 {object} entities.Robot
+// @Failure 400 {string} string "Invalid JSON or label"
+// @Failure 404 {string} string "Robot not found"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 423 {string} string "Locked down"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /robots/{id}/labels [put]
+func (hndl *RbtHndler) SetRobotLabels(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "неверный айди", http.StatusBadRequest)
+		return
+	}
+	var body dto.SetRobotLabelsDTO
+	if err = json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "проблемы с жсоником", http.StatusBadRequest)
+		return
+	}
+
+	robot, err := hndl.Srvc.SetRobotLabels(r.Context(), id, body.Labels)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, robot)
+}
+
+// @Summary Patch robot labels
+// @Description Set the given labels on top of the current ones, null removes a label
+// @Tags robots
+// @Accept json
+// @Produce json
+// @Param id path int true "Robot ID"
+// @Param labels body dto.PatchRobotLabelsDTO true "Labels to set or remove"
+// @Param Idempotency-Key header string false "Idempotency key for safe retries"
+// @Success 200 {object} entities.Robot
+// @Failure 400 {string} string "Invalid JSON or label"
+// @Failure 404 {string} string "Robot not found"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 423 {string} string "Locked down"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /robots/{id}/labels [patch]
+func (hndl *RbtHndler) PatchRobotLabels(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "неверный айди", http.StatusBadRequest)
+		return
+	}
+	var body dto.PatchRobotLabelsDTO
+	if err = json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "проблемы с жсоником", http.StatusBadRequest)
+		return
+	}
+
+	robot, err := hndl.Srvc.PatchRobotLabels(r.Context(), id, body.Labels)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, robot)
+}
+
+// @Summary Export robots
+// @Description Stream the robot registry as JSON Lines or CSV, optionally only robots matching a label selector
+// @Tags robots
+// @Produce json
+// @Produce text/csv
+// @Param format query string false "jsonl (default) or csv"
+// @Param labelSelector query string false "Label selector: site=wh2,team!=qa,env in (prod,stage),gpu,!deprecated"
+// @Success 200 {string} string "Robot registry"
+// @Failure 400 {string} string "Unknown format or invalid selector"
+// @Failure 500 {string} string "Failed to export robots"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /robots/export [get]
+func (hndl *RbtHndler) ExportRobots(w http.ResponseWriter, r *http.Request) {
+	format, err := robotio.ParseFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		http.Error(w, "неизвестный формат", 400)
+		return
+	}
+	filter, err := services.ParseRobotFilter("", r.URL.Query().Get("labelSelector"), nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", robotio.ContentType(format))
+	w.Header().Set("Content-Disposition", "attachment; filename=robots."+format)
+	err = hndl.Srvc.ExportRobots(w, format, filter)
+	if err != nil {
+		// Заголовки уже могли уйти клиенту, так что просто обрываем поток
+		http.Error(w, "Error", 500)
+	}
+}
+
+// @Summary Import robots
+// @Description Validate and upsert robots from JSON Lines or CSV in batches. Robots with id are overwritten, without id are created
+// @Tags robots
+// @Accept json
+// @Accept text/csv
+// @Produce json
+// @Param format query string false "jsonl (default) or csv"
+// @Param dryRun query bool false "Only validate, do not write"
+// @Param Idempotency-Key header string false "Idempotency key for safe retries"
+// @Success 200 {object} entities.ImportReport
+// @Failure 400 {object} entities.ImportReport
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 423 {string} string "Locked down"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /robots/import [post]
+func (hndl *RbtHndler) ImportRobots(w http.ResponseWriter, r *http.Request) {
+	format, err := robotio.ParseFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		http.Error(w, "неизвестный формат", 400)
+		return
+	}
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dryRun"))
+
+	report, err := hndl.Srvc.ImportRobots(r.Context(), r.Body, format, dryRun)
+	if errors.Is(err, lockdown.ErrLockedDown) {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+	json.NewEncoder(w).Encode(report)
+}
+
+// Разбираем limit и offset из query, по умолчанию отдаём первые 100
+func parsePaging(r *http.Request) (int, int, error) {
+	limit, offset := defaultLimit, 0
+	var err error
+	if value := r.URL.Query().Get("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil || limit <= 0 {
+			return 0, 0, errors.New("bad limit")
+		}
+	}
+	if value := r.URL.Query().Get("offset"); value != "" {
+		if offset, err = strconv.Atoi(value); err != nil || offset < 0 {
+			return 0, 0, errors.New("bad offset")
+		}
+	}
+	return min(limit, maxLimit), offset, nil
+}
+
+// Общая обработка ошибок сервиса: ненайденный робот - 404, изменение ждёт подтверждения - 202 с заявкой,
+// робот не проходит по реестру типов или схеме атрибутов - 400, защитный режим - 423, остальное - 500
+func writeServiceError(w http.ResponseWriter, err error) {
+	var pending *services.PendingApprovalError
+	var collision *services.CollisionError
+	switch {
+	case errors.As(err, &pending):
+		w.Header().Set("Location", "/approvals/"+strconv.Itoa(pending.Approval.ID))
+		writeJSON(w, http.StatusAccepted, pending.Approval)
+	case errors.As(err, &collision):
+		writeJSON(w, http.StatusConflict, collision)
+	case errors.Is(err, repositories.ErrRobotNotFound):
+		http.Error(w, "робот не найден", http.StatusNotFound)
+	case errors.Is(err, services.ErrUnknownRobotType), errors.Is(err, services.ErrAltitudeOutOfRange),
+		errors.Is(err, services.ErrInvalidAttributes), errors.Is(err, services.ErrInvalidLabels),
+		errors.Is(err, services.ErrOutsideWorld), errors.Is(err, services.ErrPositionBlocked):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, lockdown.ErrLockedDown):
+		http.Error(w, err.Error(), http.StatusLocked)
+	default:
+		http.Error(w, "Error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"RobotService/internal/services"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// Кривой селектор отсекается до сервиса, поэтому хендлеру сервис не нужен
+func TestInvalidSelectorIsBadRequest(t *testing.T) {
+	hndl := &RbtHndler{}
+	tests := []struct {
+		handler http.HandlerFunc
+		query   url.Values
+	}{
+		{hndl.ListRobots, url.Values{"labelSelector": {"env in (prod"}}},
+		{hndl.ListRobots, url.Values{"labelSelector": {"site=wh 2"}}},
+		{hndl.ListRobots, url.Values{"labelSelector": {"site=wh2,"}}},
+		{hndl.ListRobots, url.Values{"attr": {"battery.level>high"}}},
+		{hndl.ExportRobots, url.Values{"labelSelector": {"!"}}},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		tt.handler(w, httptest.NewRequest(http.MethodGet, "/robots?"+tt.query.Encode(), nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%v: status = %d, want 400", tt.query, w.Code)
+		}
+	}
+}
+
+func TestInvalidLabelsAreBadRequest(t *testing.T) {
+	w := httptest.NewRecorder()
+	writeServiceError(w, fmt.Errorf("%w: bad key %q", services.ErrInvalidLabels, "_site"))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", w.Code)
+	}
+}
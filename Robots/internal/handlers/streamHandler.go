@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"RobotService/internal/events"
+	"RobotService/internal/prometheusinfo"
+	"RobotService/internal/services"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+const (
+	streamBuffer      = 64
+	heartbeatInterval = 15 * time.Second
+)
+
+// @Summary Robot change stream (SSE)
+// @Description Server-Sent Events with robot create/update/delete events. Supports Last-Event-ID resumption.
+// @Description A gap event means events were dropped for a slow client, the stream then closes so the browser resumes from history.
+// @Description A reset event means the ID cannot be resumed (restart, another instance or history overflow) and state must be reloaded.
+// @Description The stream only sees changes made through this instance
+// @Tags robots
+// @Produce text/event-stream
+// @Param id query string false "Robot IDs, comma separated"
+// @Param type query string false "Robot type"
+// @Param region query string false "Box minX,minY,minZ,maxX,maxY,maxZ"
+// @Param labelSelector query string false "Label selector: site=wh2,team!=qa,env in (prod,stage),gpu,!deprecated"
+// @Param Last-Event-ID header string false "Resume after this event"
+// @Param access_token query string false "Token from POST /auth/stream-token, for clients that cannot send auth headers"
+// @Success 200 {string} string "Event stream"
+// @Failure 400 {string} string "Invalid filter"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /robots/stream [get]
+func (hndl *RbtHndler) StreamRobots(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok || hndl.Srvc.Events == nil {
+		http.Error(w, "стриминг не поддерживается", 500)
+		return
+	}
+
+	// Браузер сам присылает Last-Event-ID при переподключении
+	hub := hndl.Srvc.Events
+	backlog, ch, cancel := hub.SubscribeSince(r.Header.Get("Last-Event-ID"), streamBuffer)
+	defer cancel()
+	prometheusinfo.StreamClients.WithLabelValues("sse").Inc()
+	defer prometheusinfo.StreamClients.WithLabelValues("sse").Dec()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	for _, event := range backlog {
+		if filter.Match(event) {
+			writeSSE(w, hub.EventID(event), event)
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+			flusher.Flush()
+		case event, ok := <-ch:
+			if !ok {
+				return
+			}
+			if !filter.Match(event) {
+				continue
+			}
+			writeSSE(w, hub.EventID(event), event)
+			flusher.Flush()
+			// После потерь закрываем поток: браузер переподключится с Last-Event-ID и догонит пропущенное
+			// из истории, а если оно уже вытеснено, получит reset
+			if event.Kind == events.KindGap {
+				return
+			}
+		}
+	}
+}
+
+// У меток gap и reset нет id, чтобы браузер не сдвинул Last-Event-ID
+func writeSSE(w http.ResponseWriter, id string, event events.RobotEvent) {
+	data, _ := json.Marshal(event)
+	if id != "" {
+		fmt.Fprintf(w, "id: %s\n", id)
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Kind, data)
+}
+
+// @Summary Robot change stream (WebSocket)
+// @Description WebSocket with robot create/update/delete events as JSON messages.
+// @Description Browsers may connect only from the service's own origin or one listed in streams.allowedOrigins, other origins get 403.
+// @Description A gap message means events were dropped for a slow client and state should be reloaded
+// @Tags robots
+// @Param id query string false "Robot IDs, comma separated"
+// @Param type query string false "Robot type"
+// @Param region query string false "Box minX,minY,minZ,maxX,maxY,maxZ"
+// @Param labelSelector query string false "Label selector: site=wh2,team!=qa,env in (prod,stage),gpu,!deprecated"
+// @Param access_token query string false "Token from POST /auth/stream-token, for clients that cannot send auth headers"
+// @Success 101 {string} string "Switching Protocols"
+// @Failure 400 {string} string "Invalid filter"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /robots/ws [get]
+func (hndl *RbtHndler) WebSocketRobots(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+	if hndl.Srvc.Events == nil {
+		http.Error(w, "стриминг не поддерживается", 500)
+		return
+	}
+
+	server := websocket.Server{
+		// Браузер шлёт на вебсокет куки и токены любой странице, поэтому чужие сайты пускаем только из списка.
+		// Ошибка здесь - ответ 403
+		Handshake: func(_ *websocket.Config, r *http.Request) error {
+			if !originAllowed(r, hndl.AllowedOrigins) {
+				return errors.New("origin not allowed")
+			}
+			return nil
+		},
+		Handler: func(ws *websocket.Conn) {
+			hndl.serveWebSocket(ws, filter)
+		},
+	}
+	server.ServeHTTP(w, r)
+}
+
+func (hndl *RbtHndler) serveWebSocket(ws *websocket.Conn, filter events.Filter) {
+	defer ws.Close()
+	ch, cancel := hndl.Srvc.Events.Subscribe(streamBuffer)
+	defer cancel()
+	prometheusinfo.StreamClients.WithLabelValues("websocket").Inc()
+	defer prometheusinfo.StreamClients.WithLabelValues("websocket").Dec()
+
+	// Клиенту писать нечего, читаем только чтобы заметить закрытие соединения
+	ctx, stop := context.WithCancel(ws.Request().Context())
+	defer stop()
+	go func() {
+		defer stop()
+		var discard []byte
+		for websocket.Message.Receive(ws, &discard) == nil {
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-ch:
+			if !ok {
+				return
+			}
+			if !filter.Match(event) {
+				continue
+			}
+			if err := websocket.JSON.Send(ws, event); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Без Origin приходят не браузеры, им проверка не нужна. Свой домен пускаем всегда
+func originAllowed(r *http.Request, allowed []string) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	for _, candidate := range allowed {
+		if candidate == "*" || strings.EqualFold(strings.TrimRight(candidate, "/"), u.Scheme+"://"+u.Host) {
+			return true
+		}
+	}
+	return false
+}
+
+// Фильтр из query: id=1,2&id=3&type=drone&region=0,0,0,10,10,10&labelSelector=site=wh2
+func parseFilter(query url.Values) (events.Filter, error) {
+	var filter events.Filter
+	labels, err := services.ParseLabelSelector(query.Get("labelSelector"))
+	if err != nil {
+		return filter, err
+	}
+	filter.Labels = labels
+	for _, value := range query["id"] {
+		for _, part := range strings.Split(value, ",") {
+			id, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil {
+				return filter, errors.New("неверный id в фильтре")
+			}
+			filter.IDs = append(filter.IDs, id)
+		}
+	}
+	filter.Type = query.Get("type")
+
+	if value := query.Get("region"); value != "" {
+		parts := strings.Split(value, ",")
+		if len(parts) != 6 {
+			return filter, errors.New("region: нужно 6 чисел minX,minY,minZ,maxX,maxY,maxZ")
+		}
+		var bounds [6]int
+		for i, part := range parts {
+			n, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil {
+				return filter, errors.New("region: неверное число")
+			}
+			bounds[i] = n
+		}
+		filter.Region = &events.Region{
+			MinX: min(bounds[0], bounds[3]), MinY: min(bounds[1], bounds[4]), MinZ: min(bounds[2], bounds[5]),
+			MaxX: max(bounds[0], bounds[3]), MaxY: max(bounds[1], bounds[4]), MaxZ: max(bounds[2], bounds[5]),
+		}
+	}
+	return filter, nil
+}
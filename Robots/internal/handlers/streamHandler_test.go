@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOriginAllowed(t *testing.T) {
+	allowed := []string{"https://dash.example.com/", "http://localhost:3000"}
+	tests := []struct {
+		origin  string
+		allowed []string
+		want    bool
+	}{
+		{"", nil, true},
+		{"https://robots.example.com", nil, true},
+		{"https://dash.example.com", allowed, true},
+		{"HTTPS://DASH.example.com", allowed, true},
+		{"http://localhost:3000", allowed, true},
+		{"http://localhost:3001", allowed, false},
+		{"http://dash.example.com", allowed, false},
+		{"https://evil.example.com", allowed, false},
+		{"https://evil.example.com", []string{"*"}, true},
+		{"null", allowed, false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "http://robots.example.com/robots/ws", nil)
+		if tt.origin != "" {
+			r.Header.Set("Origin", tt.origin)
+		}
+		if got := originAllowed(r, tt.allowed); got != tt.want {
+			t.Errorf("originAllowed(%q, %v) = %v, want %v", tt.origin, tt.allowed, got, tt.want)
+		}
+	}
+}
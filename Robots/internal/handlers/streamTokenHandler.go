@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"RobotService/internal/auth"
+	"RobotService/internal/entities"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type StreamTokenHandler struct {
+	Tokens *auth.StreamTokens
+}
+
+func (hndl *StreamTokenHandler) SetRoute(router chi.Router) {
+	router.Post("/auth/stream-token", hndl.IssueStreamToken)
+}
+
+// @Summary Short-lived token for event streams
+// @Description Browser EventSource and WebSocket cannot send auth headers. The token is accepted by /robots/stream and /robots/ws
+// @Description as ?access_token= or via the robots_stream_token cookie set by this response, and carries the caller's identity and roles.
+// @Description Tokens are signed with a per-process key and stop working after a restart
+// @Tags auth
+// @Produce json
+// @Success 200 {object} entities.StreamToken
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 429 {string} string "Too many requests"
+// @Failure 500 {string} string "Internal error"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /auth/stream-token [post]
+func (hndl *StreamTokenHandler) IssueStreamToken(w http.ResponseWriter, r *http.Request) {
+	principal := auth.FromContext(r.Context())
+	if principal == nil {
+		// Анонимам токен не нужен: без обязательной аутентификации стримы открыты и так
+		http.Error(w, "требуется аутентификация", http.StatusUnauthorized)
+		return
+	}
+	token, expires, err := hndl.Tokens.Issue(principal)
+	if err != nil {
+		http.Error(w, "Error", http.StatusInternalServerError)
+		return
+	}
+
+	// Кука только для стримов и только с того же сайта, чужая страница с ней стрим не откроет
+	http.SetCookie(w, &http.Cookie{
+		Name:     auth.StreamTokenCookie,
+		Value:    token,
+		Path:     "/robots",
+		Expires:  expires,
+		MaxAge:   int(hndl.Tokens.TTL().Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+	writeJSON(w, http.StatusOK, entities.StreamToken{Token: token, ExpiresAt: expires})
+}
@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"RobotService/internal/dto"
+	"RobotService/internal/middlewares"
+	"RobotService/internal/rbac"
+	"RobotService/internal/repositories"
+	"RobotService/internal/services"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type WebhookHandler struct {
+	Srvc   services.WebhookService
+	Policy *rbac.Engine
+}
+
+func (hndl *WebhookHandler) SetRoute(router chi.Router) {
+	read := router.With(middlewares.Authorize(hndl.Policy, rbac.WebhooksRead, nil))
+	write := router.With(middlewares.Authorize(hndl.Policy, rbac.WebhooksWrite, nil))
+	write.Post("/webhooks", hndl.CreateWebhook)
+	read.Get("/webhooks", hndl.ListWebhooks)
+	read.Get("/webhooks/{id}", hndl.GetWebhook)
+	write.Put("/webhooks/{id}", hndl.UpdateWebhook)
+	write.Delete("/webhooks/{id}", hndl.DeleteWebhook)
+	read.Get("/webhooks/{id}/deliveries", hndl.ListDeliveries)
+}
+
+// @Summary Create webhook
+// @Description Subscribe a URL to robot events. Deliveries are signed with HMAC-SHA256 of the secret
+// @Description The host must resolve to public addresses only: loopback, private, link-local and unspecified addresses are rejected
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param webhook body dto.CreateWebhookDTO true "Webhook"
+// @Param Idempotency-Key header string false "Idempotency key for safe retries"
+// @Success 201 {object} entities.Webhook
+// @Failure 400 {string} string "Invalid webhook"
+// @Failure 500 {string} string "Internal error"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /webhooks [post]
+func (hndl *WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var createdto dto.CreateWebhookDTO
+	if err := json.NewDecoder(r.Body).Decode(&createdto); err != nil {
+		http.Error(w, "проблемы с жсоником", http.StatusBadRequest)
+		return
+	}
+
+	hook, err := hndl.Srvc.CreateWebhook(createdto)
+	if err != nil {
+		writeWebhookError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, hook)
+}
+
+// @Summary List webhooks
+// @Tags webhooks
+// @Produce json
+// @Success 200 {array} entities.Webhook
+// @Failure 500 {string} string "Internal error"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /webhooks [get]
+func (hndl *WebhookHandler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	hooks, err := hndl.Srvc.ListWebhooks()
+	if err != nil {
+		writeWebhookError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, hooks)
+}
+
+// @Summary Get webhook
+// @Tags webhooks
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Success 200 {object} entities.Webhook
+// @Failure 400 {string} string "Invalid ID"
+// @Failure 404 {string} string "Webhook not found"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /webhooks/{id} [get]
+func (hndl *WebhookHandler) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "неверный айди", http.StatusBadRequest)
+		return
+	}
+
+	hook, err := hndl.Srvc.GetWebhook(id)
+	if err != nil {
+		writeWebhookError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, hook)
+}
+
+// @Summary Update webhook
+// @Description Change url, secret or events. Set active=true to re-enable an auto-disabled webhook.
+// @Description A new url is checked like on creation: it must resolve to public addresses only
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Param webhook body dto.UpdateWebhookDTO true "Fields to change"
+// @Param Idempotency-Key header string false "Idempotency key for safe retries"
+// @Success 200 {object} entities.Webhook
+// @Failure 400 {string} string "Invalid webhook"
+// @Failure 404 {string} string "Webhook not found"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /webhooks/{id} [put]
+func (hndl *WebhookHandler) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "неверный айди", http.StatusBadRequest)
+		return
+	}
+	var updatedto dto.UpdateWebhookDTO
+	if err = json.NewDecoder(r.Body).Decode(&updatedto); err != nil {
+		http.Error(w, "проблемы с жсоником", http.StatusBadRequest)
+		return
+	}
+
+	hook, err := hndl.Srvc.UpdateWebhook(id, updatedto)
+	if err != nil {
+		writeWebhookError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, hook)
+}
+
+// @Summary Delete webhook
+// @Tags webhooks
+// @Param id path int true "Webhook ID"
+// @Param Idempotency-Key header string false "Idempotency key for safe retries"
+// @Success 204 {string} string "No Content"
+// @Failure 400 {string} string "Invalid ID"
+// @Failure 404 {string} string "Webhook not found"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /webhooks/{id} [delete]
+func (hndl *WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "неверный айди", http.StatusBadRequest)
+		return
+	}
+
+	if err = hndl.Srvc.DeleteWebhook(id); err != nil {
+		writeWebhookError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary Webhook delivery log
+// @Description Latest delivery attempts, newest first
+// @Tags webhooks
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Param limit query int false "Page size (default 100, max 500)"
+// @Success 200 {array} entities.WebhookDelivery
+// @Failure 400 {string} string "Invalid ID"
+// @Failure 404 {string} string "Webhook not found"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /webhooks/{id}/deliveries [get]
+func (hndl *WebhookHandler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "неверный айди", http.StatusBadRequest)
+		return
+	}
+	limit, _, err := parsePaging(r)
+	if err != nil {
+		http.Error(w, "неверные параметры страницы", http.StatusBadRequest)
+		return
+	}
+
+	deliveries, err := hndl.Srvc.ListDeliveries(id, limit)
+	if err != nil {
+		writeWebhookError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, deliveries)
+}
+
+func writeWebhookError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidWebhook):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, repositories.ErrWebhookNotFound):
+		http.Error(w, "вебхук не найден", http.StatusNotFound)
+	default:
+		http.Error(w, "Error", http.StatusInternalServerError)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
@@ -0,0 +1,262 @@
+package handlers
+
+import (
+	"RobotService/internal/dto"
+	"RobotService/internal/entities"
+	"RobotService/internal/middlewares"
+	"RobotService/internal/rbac"
+	"RobotService/internal/repositories"
+	"RobotService/internal/services"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type WorldHandler struct {
+	Srvc   *services.WorldService
+	Policy *rbac.Engine
+}
+
+func (hndl *WorldHandler) SetRoute(router chi.Router) {
+	read := router.With(middlewares.Authorize(hndl.Policy, rbac.RobotsRead, nil))
+	write := router.With(middlewares.Authorize(hndl.Policy, rbac.WorldWrite, nil))
+	read.Get("/world", hndl.GetWorld)
+	write.Put("/world/bounds", hndl.SetBounds)
+	write.Delete("/world/bounds", hndl.ClearBounds)
+	write.Post("/world/zones", hndl.CreateZone)
+	read.Get("/world/zones", hndl.ListZones)
+	read.Get("/world/zones/{id}", hndl.GetZone)
+	write.Put("/world/zones/{id}", hndl.UpdateZone)
+	write.Delete("/world/zones/{id}", hndl.DeleteZone)
+}
+
+// @Summary Get world map
+// @Description World bounds (absent when the world is unbounded) with all zones, obstacles and geofences
+// @Tags world
+// @Produce json
+// @Success 200 {object} entities.World
+// @Failure 500 {string} string "Internal error"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /world [get]
+func (hndl *WorldHandler) GetWorld(w http.ResponseWriter, r *http.Request) {
+	world, err := hndl.Srvc.GetWorld()
+	if err != nil {
+		writeWorldError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, world)
+}
+
+// @Summary Set world bounds
+// @Description Robots cannot be created or moved outside the bounds. Robots already outside stay where they are
+// @Tags world
+// @Accept json
+// @Produce json
+// @Param bounds body entities.WorldBounds true "Inclusive bounds"
+// @Param Idempotency-Key header string false "Idempotency key for safe retries"
+// @Success 200 {object} entities.WorldBounds
+// @Failure 400 {string} string "Invalid bounds"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /world/bounds [put]
+func (hndl *WorldHandler) SetBounds(w http.ResponseWriter, r *http.Request) {
+	var bounds entities.WorldBounds
+	if err := json.NewDecoder(r.Body).Decode(&bounds); err != nil {
+		http.Error(w, "проблемы с жсоником", http.StatusBadRequest)
+		return
+	}
+
+	updated, err := hndl.Srvc.SetBounds(r.Context(), bounds)
+	if err != nil {
+		writeWorldError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, updated)
+}
+
+// @Summary Remove world bounds
+// @Description The world becomes unbounded, zones still apply
+// @Tags world
+// @Param Idempotency-Key header string false "Idempotency key for safe retries"
+// @Success 204 {string} string "No Content"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /world/bounds [delete]
+func (hndl *WorldHandler) ClearBounds(w http.ResponseWriter, r *http.Request) {
+	if err := hndl.Srvc.ClearBounds(r.Context()); err != nil {
+		writeWorldError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary Create zone
+// @Description An axis-aligned box or a polygon extruded between minZ and maxZ.
+// @Description kind=zone emits robots.ZoneEntered/ZoneLeft when robots cross it, obstacle and geofence keep robots out
+// @Tags world
+// @Accept json
+// @Produce json
+// @Param zone body dto.ZoneDTO true "Zone"
+// @Param Idempotency-Key header string false "Idempotency key for safe retries"
+// @Success 201 {object} entities.Zone
+// @Failure 400 {string} string "Invalid zone"
+// @Failure 409 {string} string "Zone already exists"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /world/zones [post]
+func (hndl *WorldHandler) CreateZone(w http.ResponseWriter, r *http.Request) {
+	var zonedto dto.ZoneDTO
+	if err := json.NewDecoder(r.Body).Decode(&zonedto); err != nil {
+		http.Error(w, "проблемы с жсоником", http.StatusBadRequest)
+		return
+	}
+
+	zone, err := hndl.Srvc.CreateZone(r.Context(), zonedto)
+	if err != nil {
+		writeWorldError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, zone)
+}
+
+// @Summary List zones
+// @Tags world
+// @Produce json
+// @Success 200 {array} entities.Zone
+// @Failure 500 {string} string "Internal error"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /world/zones [get]
+func (hndl *WorldHandler) ListZones(w http.ResponseWriter, r *http.Request) {
+	zones, err := hndl.Srvc.ListZones()
+	if err != nil {
+		writeWorldError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, zones)
+}
+
+// @Summary Get zone
+// @Tags world
+// @Produce json
+// @Param id path int true "Zone ID"
+// @Success 200 {object} entities.Zone
+// @Failure 400 {string} string "Invalid ID"
+// @Failure 404 {string} string "Zone not found"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /world/zones/{id} [get]
+func (hndl *WorldHandler) GetZone(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "неверный айди", http.StatusBadRequest)
+		return
+	}
+
+	zone, err := hndl.Srvc.GetZone(id)
+	if err != nil {
+		writeWorldError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, zone)
+}
+
+// @Summary Update zone
+// @Description Replace the zone. Robots already inside a new obstacle or geofence stay where they are
+// @Tags world
+// @Accept json
+// @Produce json
+// @Param id path int true "Zone ID"
+// @Param zone body dto.ZoneDTO true "Zone"
+// @Param Idempotency-Key header string false "Idempotency key for safe retries"
+// @Success 200 {object} entities.Zone
+// @Failure 400 {string} string "Invalid zone"
+// @Failure 404 {string} string "Zone not found"
+// @Failure 409 {string} string "Zone name taken"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /world/zones/{id} [put]
+func (hndl *WorldHandler) UpdateZone(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "неверный айди", http.StatusBadRequest)
+		return
+	}
+	var zonedto dto.ZoneDTO
+	if err = json.NewDecoder(r.Body).Decode(&zonedto); err != nil {
+		http.Error(w, "проблемы с жсоником", http.StatusBadRequest)
+		return
+	}
+
+	zone, err := hndl.Srvc.UpdateZone(r.Context(), id, zonedto)
+	if err != nil {
+		writeWorldError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, zone)
+}
+
+// @Summary Delete zone
+// @Tags world
+// @Param id path int true "Zone ID"
+// @Param Idempotency-Key header string false "Idempotency key for safe retries"
+// @Success 204 {string} string "No Content"
+// @Failure 400 {string} string "Invalid ID"
+// @Failure 404 {string} string "Zone not found"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 429 {string} string "Too many requests"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /world/zones/{id} [delete]
+func (hndl *WorldHandler) DeleteZone(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "неверный айди", http.StatusBadRequest)
+		return
+	}
+
+	if err = hndl.Srvc.DeleteZone(r.Context(), id); err != nil {
+		writeWorldError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeWorldError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidZone), errors.Is(err, services.ErrInvalidWorldBounds):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, repositories.ErrZoneNotFound):
+		http.Error(w, "зона не найдена", http.StatusNotFound)
+	case errors.Is(err, repositories.ErrZoneExists):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, "Error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,280 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Подмножество JSON Schema (2020-12), которого хватает для атрибутов роботов:
+// type, enum, const, ограничения чисел, строк, массивов и объектов, allOf/anyOf/oneOf/not.
+// $ref и остальное не поддерживаем и честно отказываем при компиляции, чтобы схема
+// не пропускала молча то, что автор хотел запретить. pattern - синтаксис RE2 из regexp
+
+var ErrInvalidSchema = errors.New("invalid json schema")
+
+// Ключи, которые ничего не проверяют
+var annotations = map[string]bool{
+	"$schema": true, "$id": true, "$comment": true, "title": true, "description": true,
+	"default": true, "examples": true, "deprecated": true, "readOnly": true, "writeOnly": true, "format": true,
+}
+
+var knownTypes = map[string]bool{
+	"null": true, "boolean": true, "object": true, "array": true, "number": true, "integer": true, "string": true,
+}
+
+type Schema struct {
+	// true/false вместо объекта: пропускает всё или ничего
+	boolean *bool
+
+	types    []string
+	enum     []any
+	hasConst bool
+	constant any
+
+	minimum, maximum                   *float64
+	exclusiveMinimum, exclusiveMaximum *float64
+	multipleOf                         *float64
+
+	minLength, maxLength *int
+	pattern              *regexp.Regexp
+
+	items              *Schema
+	minItems, maxItems *int
+	uniqueItems        bool
+
+	properties          map[string]*Schema
+	patternProperties   []patternSchema
+	additional          *Schema
+	required            []string
+	minProperties       *int
+	maxProperties       *int
+	allOf, anyOf, oneOf []*Schema
+	not                 *Schema
+}
+
+type patternSchema struct {
+	pattern *regexp.Regexp
+	schema  *Schema
+}
+
+// Ошибка проверки документа, по одной строке на каждое нарушение
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return strings.Join(e.Problems, "; ")
+}
+
+func Compile(raw []byte) (*Schema, error) {
+	var doc any
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSchema, err)
+	}
+	return compile(doc, "#")
+}
+
+func compile(doc any, path string) (*Schema, error) {
+	if value, ok := doc.(bool); ok {
+		return &Schema{boolean: &value}, nil
+	}
+	object, ok := doc.(map[string]any)
+	if !ok {
+		return nil, schemaError(path, "schema must be an object or a boolean")
+	}
+
+	schema := &Schema{}
+	// Ключи по порядку, чтобы ошибки компиляции были стабильными
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := object[key]
+		at := path + "/" + key
+		var err error
+		switch key {
+		case "type":
+			schema.types, err = compileTypes(value, at)
+		case "enum":
+			values, ok := value.([]any)
+			if !ok {
+				return nil, schemaError(at, "must be an array")
+			}
+			schema.enum = values
+		case "const":
+			schema.hasConst, schema.constant = true, value
+		case "minimum":
+			schema.minimum, err = number(value, at)
+		case "maximum":
+			schema.maximum, err = number(value, at)
+		case "exclusiveMinimum":
+			schema.exclusiveMinimum, err = number(value, at)
+		case "exclusiveMaximum":
+			schema.exclusiveMaximum, err = number(value, at)
+		case "multipleOf":
+			if schema.multipleOf, err = number(value, at); err == nil && *schema.multipleOf <= 0 {
+				err = schemaError(at, "must be greater than 0")
+			}
+		case "minLength":
+			schema.minLength, err = count(value, at)
+		case "maxLength":
+			schema.maxLength, err = count(value, at)
+		case "pattern":
+			schema.pattern, err = compilePattern(value, at)
+		case "items":
+			schema.items, err = compile(value, at)
+		case "minItems":
+			schema.minItems, err = count(value, at)
+		case "maxItems":
+			schema.maxItems, err = count(value, at)
+		case "uniqueItems":
+			unique, ok := value.(bool)
+			if !ok {
+				return nil, schemaError(at, "must be a boolean")
+			}
+			schema.uniqueItems = unique
+		case "properties":
+			schema.properties, err = compileMap(value, at)
+		case "patternProperties":
+			var compiled map[string]*Schema
+			if compiled, err = compileMap(value, at); err == nil {
+				for pattern, sub := range compiled {
+					re, err := compilePattern(pattern, at+"/"+pattern)
+					if err != nil {
+						return nil, err
+					}
+					schema.patternProperties = append(schema.patternProperties, patternSchema{pattern: re, schema: sub})
+				}
+			}
+		case "additionalProperties":
+			schema.additional, err = compile(value, at)
+		case "required":
+			schema.required, err = stringList(value, at)
+		case "minProperties":
+			schema.minProperties, err = count(value, at)
+		case "maxProperties":
+			schema.maxProperties, err = count(value, at)
+		case "allOf":
+			schema.allOf, err = compileList(value, at)
+		case "anyOf":
+			schema.anyOf, err = compileList(value, at)
+		case "oneOf":
+			schema.oneOf, err = compileList(value, at)
+		case "not":
+			schema.not, err = compile(value, at)
+		default:
+			if !annotations[key] {
+				return nil, schemaError(at, "unsupported keyword")
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return schema, nil
+}
+
+func schemaError(path, msg string) error {
+	return fmt.Errorf("%w: %s: %s", ErrInvalidSchema, path, msg)
+}
+
+func compileTypes(value any, path string) ([]string, error) {
+	if name, ok := value.(string); ok {
+		value = []any{name}
+	}
+	names, err := stringList(value, path)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		if !knownTypes[name] {
+			return nil, schemaError(path, fmt.Sprintf("unknown type %q", name))
+		}
+	}
+	return names, nil
+}
+
+func compileMap(value any, path string) (map[string]*Schema, error) {
+	object, ok := value.(map[string]any)
+	if !ok {
+		return nil, schemaError(path, "must be an object")
+	}
+	compiled := make(map[string]*Schema, len(object))
+	for name, sub := range object {
+		schema, err := compile(sub, path+"/"+name)
+		if err != nil {
+			return nil, err
+		}
+		compiled[name] = schema
+	}
+	return compiled, nil
+}
+
+func compileList(value any, path string) ([]*Schema, error) {
+	list, ok := value.([]any)
+	if !ok || len(list) == 0 {
+		return nil, schemaError(path, "must be a non-empty array")
+	}
+	compiled := make([]*Schema, len(list))
+	for i, sub := range list {
+		schema, err := compile(sub, fmt.Sprintf("%s/%d", path, i))
+		if err != nil {
+			return nil, err
+		}
+		compiled[i] = schema
+	}
+	return compiled, nil
+}
+
+func compilePattern(value any, path string) (*regexp.Regexp, error) {
+	pattern, ok := value.(string)
+	if !ok {
+		return nil, schemaError(path, "must be a string")
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, schemaError(path, err.Error())
+	}
+	return re, nil
+}
+
+func number(value any, path string) (*float64, error) {
+	n, ok := value.(float64)
+	if !ok {
+		return nil, schemaError(path, "must be a number")
+	}
+	return &n, nil
+}
+
+func count(value any, path string) (*int, error) {
+	n, ok := value.(float64)
+	if !ok || n < 0 || n != math.Trunc(n) {
+		return nil, schemaError(path, "must be a non-negative integer")
+	}
+	c := int(n)
+	return &c, nil
+}
+
+func stringList(value any, path string) ([]string, error) {
+	list, ok := value.([]any)
+	if !ok {
+		return nil, schemaError(path, "must be an array of strings")
+	}
+	names := make([]string, len(list))
+	for i, item := range list {
+		name, ok := item.(string)
+		if !ok {
+			return nil, schemaError(path, "must be an array of strings")
+		}
+		names[i] = name
+	}
+	return names, nil
+}
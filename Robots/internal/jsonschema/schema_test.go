@@ -0,0 +1,188 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		doc    string
+		// Ожидаемые нарушения по порядку, пусто - документ проходит
+		problems []string
+	}{
+		{name: "true", schema: `true`, doc: `{"a":1}`},
+		{name: "false", schema: `false`, doc: `1`, problems: []string{"/: not allowed"}},
+		{name: "annotations only", schema: `{"title":"x","description":"y","format":"email","default":1,"$comment":"c"}`, doc: `"z"`},
+
+		// type
+		{name: "type", schema: `{"type":"string"}`, doc: `"a"`},
+		{name: "type mismatch", schema: `{"type":"string"}`, doc: `1`, problems: []string{"/: must be string, got integer"}},
+		{name: "type list", schema: `{"type":["string","null"]}`, doc: `null`},
+		{name: "integer is a number", schema: `{"type":"number"}`, doc: `3`},
+		{name: "number is not an integer", schema: `{"type":"integer"}`, doc: `3.5`, problems: []string{"/: must be integer, got number"}},
+		{name: "integer from float", schema: `{"type":"integer"}`, doc: `3.0`},
+		{name: "type stops other checks", schema: `{"type":"string","minLength":5}`, doc: `true`, problems: []string{"/: must be string, got boolean"}},
+
+		// enum, const
+		{name: "enum", schema: `{"enum":["a",1,null]}`, doc: `1`},
+		{name: "enum mismatch", schema: `{"enum":["a",1]}`, doc: `"b"`, problems: []string{`/: must be one of ["a",1]`}},
+		{name: "enum object", schema: `{"enum":[{"a":[1]}]}`, doc: `{"a":[1]}`},
+		{name: "const", schema: `{"const":{"a":1}}`, doc: `{"a":1}`},
+		{name: "const mismatch", schema: `{"const":"x"}`, doc: `"y"`, problems: []string{`/: must be "x"`}},
+
+		// числа
+		{name: "minimum", schema: `{"minimum":1}`, doc: `1`},
+		{name: "below minimum", schema: `{"minimum":1}`, doc: `0.5`, problems: []string{"/: must be >= 1"}},
+		{name: "above maximum", schema: `{"maximum":1}`, doc: `2`, problems: []string{"/: must be <= 1"}},
+		{name: "exclusiveMinimum", schema: `{"exclusiveMinimum":1}`, doc: `1`, problems: []string{"/: must be > 1"}},
+		{name: "exclusiveMaximum", schema: `{"exclusiveMaximum":1}`, doc: `1`, problems: []string{"/: must be < 1"}},
+		{name: "multipleOf", schema: `{"multipleOf":0.1}`, doc: `0.3`},
+		{name: "not multipleOf", schema: `{"multipleOf":2}`, doc: `3`, problems: []string{"/: must be a multiple of 2"}},
+		{name: "number keywords ignore strings", schema: `{"minimum":10}`, doc: `"a"`},
+
+		// строки
+		{name: "minLength counts runes", schema: `{"minLength":2}`, doc: `"жж"`},
+		{name: "too short", schema: `{"minLength":2}`, doc: `"ж"`, problems: []string{"/: must be at least 2 characters"}},
+		{name: "too long", schema: `{"maxLength":1}`, doc: `"ab"`, problems: []string{"/: must be at most 1 characters"}},
+		{name: "pattern", schema: `{"pattern":"^[a-z]+-\\d+$"}`, doc: `"wh-2"`},
+		{name: "pattern mismatch", schema: `{"pattern":"^x"}`, doc: `"y"`, problems: []string{"/: must match pattern ^x"}},
+		{name: "pattern is not anchored", schema: `{"pattern":"b"}`, doc: `"abc"`},
+
+		// массивы
+		{name: "items", schema: `{"items":{"type":"integer"}}`, doc: `[1,"a",2,true]`,
+			problems: []string{"/1: must be integer, got string", "/3: must be integer, got boolean"}},
+		{name: "minItems", schema: `{"minItems":2}`, doc: `[1]`, problems: []string{"/: must have at least 2 items"}},
+		{name: "maxItems", schema: `{"maxItems":1}`, doc: `[1,2]`, problems: []string{"/: must have at most 1 items"}},
+		{name: "uniqueItems", schema: `{"uniqueItems":true}`, doc: `[{"a":1},{"a":2}]`},
+		{name: "duplicate items", schema: `{"uniqueItems":true}`, doc: `[{"a":1},{"a":1}]`, problems: []string{"/: items must be unique"}},
+		{name: "uniqueItems false", schema: `{"uniqueItems":false}`, doc: `[1,1]`},
+
+		// объекты
+		{name: "properties", schema: `{"properties":{"a":{"type":"string"},"b":{"type":"integer"}}}`, doc: `{"a":1,"b":2,"c":3}`,
+			problems: []string{"/a: must be string, got integer"}},
+		{name: "required", schema: `{"required":["a","b"]}`, doc: `{"a":1}`, problems: []string{`/: missing required property "b"`}},
+		{name: "minProperties", schema: `{"minProperties":1}`, doc: `{}`, problems: []string{"/: must have at least 1 properties"}},
+		{name: "maxProperties", schema: `{"maxProperties":1}`, doc: `{"a":1,"b":2}`, problems: []string{"/: must have at most 1 properties"}},
+		{name: "patternProperties", schema: `{"patternProperties":{"^x-":{"type":"string"}}}`, doc: `{"x-a":"1","x-b":2,"y":3}`,
+			problems: []string{"/x-b: must be string, got integer"}},
+		{name: "additionalProperties false", schema: `{"properties":{"a":true},"patternProperties":{"^x-":true},"additionalProperties":false}`,
+			doc: `{"a":1,"x-b":2,"c":3}`, problems: []string{"/c: unknown property"}},
+		{name: "additionalProperties schema", schema: `{"properties":{"a":true},"additionalProperties":{"type":"integer"}}`,
+			doc: `{"a":"s","b":1,"c":"s"}`, problems: []string{"/c: must be integer, got string"}},
+		{name: "pointer escaping", schema: `{"additionalProperties":false}`, doc: `{"a/b~c":1}`, problems: []string{"/a~1b~0c: unknown property"}},
+		{name: "nested path", schema: `{"properties":{"arm":{"properties":{"joints":{"items":{"maximum":6}}}}}}`, doc: `{"arm":{"joints":[1,7]}}`,
+			problems: []string{"/arm/joints/1: must be <= 6"}},
+		{name: "all object problems", schema: `{"required":["a"],"minProperties":3,"additionalProperties":false}`, doc: `{"b":1}`,
+			problems: []string{`/: missing required property "a"`, "/: must have at least 3 properties", "/b: unknown property"}},
+
+		// allOf
+		{name: "allOf", schema: `{"allOf":[{"type":"integer"},{"minimum":1}]}`, doc: `2`},
+		{name: "allOf reports each", schema: `{"allOf":[{"minimum":5},{"multipleOf":2}]}`, doc: `3`,
+			problems: []string{"/: must be >= 5", "/: must be a multiple of 2"}},
+
+		// anyOf
+		{name: "anyOf first", schema: `{"anyOf":[{"type":"string"},{"type":"integer"}]}`, doc: `"a"`},
+		{name: "anyOf both", schema: `{"anyOf":[{"type":"number"},{"type":"integer"}]}`, doc: `1`},
+		{name: "anyOf none", schema: `{"anyOf":[{"type":"string"},{"type":"integer"}]}`, doc: `1.5`,
+			problems: []string{"/: must match at least one schema in anyOf"}},
+		{name: "anyOf nested problems hidden", schema: `{"properties":{"a":{"anyOf":[{"maxLength":1},{"pattern":"^z"}]}}}`, doc: `{"a":"yy"}`,
+			problems: []string{"/a: must match at least one schema in anyOf"}},
+
+		// oneOf
+		{name: "oneOf exactly one", schema: `{"oneOf":[{"type":"string"},{"type":"integer"}]}`, doc: `1`},
+		{name: "oneOf none", schema: `{"oneOf":[{"type":"string"},{"type":"integer"}]}`, doc: `null`,
+			problems: []string{"/: must match exactly one schema in oneOf, matched 0"}},
+		{name: "oneOf several", schema: `{"oneOf":[{"type":"number"},{"type":"integer"},{"minimum":0}]}`, doc: `1`,
+			problems: []string{"/: must match exactly one schema in oneOf, matched 3"}},
+		{name: "oneOf with false", schema: `{"oneOf":[false,true]}`, doc: `1`},
+
+		// not
+		{name: "not", schema: `{"not":{"type":"string"}}`, doc: `1`},
+		{name: "not matched", schema: `{"not":{"type":"string"}}`, doc: `"a"`, problems: []string{"/: must not match the schema in not"}},
+		{name: "not false", schema: `{"not":false}`, doc: `"a"`},
+		{name: "not true", schema: `{"not":true}`, doc: `"a"`, problems: []string{"/: must not match the schema in not"}},
+		{name: "not inside property", schema: `{"properties":{"mode":{"not":{"enum":["debug"]}}}}`, doc: `{"mode":"debug"}`,
+			problems: []string{"/mode: must not match the schema in not"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema, err := Compile([]byte(tt.schema))
+			if err != nil {
+				t.Fatalf("Compile: %v", err)
+			}
+			var doc any
+			if err = json.Unmarshal([]byte(tt.doc), &doc); err != nil {
+				t.Fatal(err)
+			}
+			err = schema.Validate(doc)
+			if len(tt.problems) == 0 {
+				if err != nil {
+					t.Fatalf("Validate: %v", err)
+				}
+				return
+			}
+			var validation *ValidationError
+			if !errors.As(err, &validation) {
+				t.Fatalf("err = %v, want *ValidationError", err)
+			}
+			if strings.Join(validation.Problems, "\n") != strings.Join(tt.problems, "\n") {
+				t.Fatalf("problems:\n%s\nwant:\n%s", strings.Join(validation.Problems, "\n"), strings.Join(tt.problems, "\n"))
+			}
+		})
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		want   string
+	}{
+		{name: "not json", schema: `{`, want: "unexpected end"},
+		{name: "not an object", schema: `[]`, want: "#: schema must be an object or a boolean"},
+		{name: "$ref", schema: `{"$ref":"#/$defs/x"}`, want: "#/$ref: unsupported keyword"},
+		{name: "$defs", schema: `{"$defs":{}}`, want: "#/$defs: unsupported keyword"},
+		{name: "if", schema: `{"if":true,"then":true}`, want: "#/if: unsupported keyword"},
+		{name: "prefixItems", schema: `{"prefixItems":[true]}`, want: "#/prefixItems: unsupported keyword"},
+		{name: "contains", schema: `{"contains":true}`, want: "#/contains: unsupported keyword"},
+		{name: "dependentRequired", schema: `{"dependentRequired":{}}`, want: "#/dependentRequired: unsupported keyword"},
+		{name: "typo", schema: `{"maxLenght":3}`, want: "#/maxLenght: unsupported keyword"},
+		{name: "nested unsupported", schema: `{"properties":{"a":{"items":{"$ref":"#"}}}}`, want: "#/properties/a/items/$ref: unsupported keyword"},
+		{name: "unsupported in oneOf", schema: `{"oneOf":[true,{"unevaluatedProperties":false}]}`, want: "#/oneOf/1/unevaluatedProperties: unsupported keyword"},
+		{name: "unsupported in not", schema: `{"not":{"propertyNames":{}}}`, want: "#/not/propertyNames: unsupported keyword"},
+		{name: "unknown type", schema: `{"type":"int"}`, want: `#/type: unknown type "int"`},
+		{name: "type not a string", schema: `{"type":5}`, want: "#/type: must be an array of strings"},
+		{name: "enum not an array", schema: `{"enum":"a"}`, want: "#/enum: must be an array"},
+		{name: "minimum not a number", schema: `{"minimum":"1"}`, want: "#/minimum: must be a number"},
+		{name: "multipleOf zero", schema: `{"multipleOf":0}`, want: "#/multipleOf: must be greater than 0"},
+		{name: "negative count", schema: `{"minLength":-1}`, want: "#/minLength: must be a non-negative integer"},
+		{name: "fractional count", schema: `{"maxItems":1.5}`, want: "#/maxItems: must be a non-negative integer"},
+		{name: "bad pattern", schema: `{"pattern":"("}`, want: "#/pattern: error parsing regexp"},
+		{name: "lookahead pattern", schema: `{"pattern":"(?=a)"}`, want: "#/pattern: error parsing regexp"},
+		{name: "bad patternProperties key", schema: `{"patternProperties":{"[":true}}`, want: "#/patternProperties/[: error parsing regexp"},
+		{name: "uniqueItems not a boolean", schema: `{"uniqueItems":1}`, want: "#/uniqueItems: must be a boolean"},
+		{name: "properties not an object", schema: `{"properties":[]}`, want: "#/properties: must be an object"},
+		{name: "required not strings", schema: `{"required":[1]}`, want: "#/required: must be an array of strings"},
+		{name: "empty allOf", schema: `{"allOf":[]}`, want: "#/allOf: must be a non-empty array"},
+		{name: "anyOf not an array", schema: `{"anyOf":{}}`, want: "#/anyOf: must be a non-empty array"},
+		{name: "bad oneOf item", schema: `{"oneOf":[1]}`, want: "#/oneOf/0: schema must be an object or a boolean"},
+		{name: "bad not", schema: `{"not":"x"}`, want: "#/not: schema must be an object or a boolean"},
+		{name: "first error by key order", schema: `{"zzz":1,"aaa":1}`, want: "#/aaa: unsupported keyword"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Compile([]byte(tt.schema))
+			if !errors.Is(err, ErrInvalidSchema) {
+				t.Fatalf("err = %v, want ErrInvalidSchema", err)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
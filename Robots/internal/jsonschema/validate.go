@@ -0,0 +1,244 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Проверяем документ, разобранный через encoding/json (map[string]any, []any, float64, ...)
+func (s *Schema) Validate(doc any) error {
+	var problems []string
+	s.validate(doc, "", &problems)
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// Проходит ли документ, без сбора сообщений (для anyOf/oneOf/not)
+func (s *Schema) matches(doc any) bool {
+	var problems []string
+	s.validate(doc, "", &problems)
+	return len(problems) == 0
+}
+
+func (s *Schema) validate(doc any, path string, problems *[]string) {
+	report := func(format string, args ...any) {
+		at := path
+		if at == "" {
+			at = "/"
+		}
+		*problems = append(*problems, at+": "+fmt.Sprintf(format, args...))
+	}
+
+	if s.boolean != nil {
+		if !*s.boolean {
+			report("not allowed")
+		}
+		return
+	}
+
+	if len(s.types) > 0 && !hasType(doc, s.types) {
+		report("must be %s, got %s", strings.Join(s.types, " or "), typeOf(doc))
+		return
+	}
+	if len(s.enum) > 0 && !contains(s.enum, doc) {
+		report("must be one of %s", encode(s.enum))
+	}
+	if s.hasConst && !reflect.DeepEqual(s.constant, doc) {
+		report("must be %s", encode(s.constant))
+	}
+
+	switch value := doc.(type) {
+	case float64:
+		s.validateNumber(value, report)
+	case string:
+		s.validateString(value, report)
+	case []any:
+		s.validateArray(value, path, problems, report)
+	case map[string]any:
+		s.validateObject(value, path, problems, report)
+	}
+
+	for _, sub := range s.allOf {
+		sub.validate(doc, path, problems)
+	}
+	if len(s.anyOf) > 0 {
+		matched := false
+		for _, sub := range s.anyOf {
+			if sub.matches(doc) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			report("must match at least one schema in anyOf")
+		}
+	}
+	if len(s.oneOf) > 0 {
+		matched := 0
+		for _, sub := range s.oneOf {
+			if sub.matches(doc) {
+				matched++
+			}
+		}
+		if matched != 1 {
+			report("must match exactly one schema in oneOf, matched %d", matched)
+		}
+	}
+	if s.not != nil && s.not.matches(doc) {
+		report("must not match the schema in not")
+	}
+}
+
+func (s *Schema) validateNumber(value float64, report func(string, ...any)) {
+	if s.minimum != nil && value < *s.minimum {
+		report("must be >= %v", *s.minimum)
+	}
+	if s.maximum != nil && value > *s.maximum {
+		report("must be <= %v", *s.maximum)
+	}
+	if s.exclusiveMinimum != nil && value <= *s.exclusiveMinimum {
+		report("must be > %v", *s.exclusiveMinimum)
+	}
+	if s.exclusiveMaximum != nil && value >= *s.exclusiveMaximum {
+		report("must be < %v", *s.exclusiveMaximum)
+	}
+	if s.multipleOf != nil {
+		quotient := value / *s.multipleOf
+		if math.Abs(quotient-math.Round(quotient)) > 1e-9 {
+			report("must be a multiple of %v", *s.multipleOf)
+		}
+	}
+}
+
+func (s *Schema) validateString(value string, report func(string, ...any)) {
+	length := utf8.RuneCountInString(value)
+	if s.minLength != nil && length < *s.minLength {
+		report("must be at least %d characters", *s.minLength)
+	}
+	if s.maxLength != nil && length > *s.maxLength {
+		report("must be at most %d characters", *s.maxLength)
+	}
+	if s.pattern != nil && !s.pattern.MatchString(value) {
+		report("must match pattern %s", s.pattern)
+	}
+}
+
+func (s *Schema) validateArray(value []any, path string, problems *[]string, report func(string, ...any)) {
+	if s.minItems != nil && len(value) < *s.minItems {
+		report("must have at least %d items", *s.minItems)
+	}
+	if s.maxItems != nil && len(value) > *s.maxItems {
+		report("must have at most %d items", *s.maxItems)
+	}
+	if s.uniqueItems {
+		for i := range value {
+			if contains(value[:i], value[i]) {
+				report("items must be unique")
+				break
+			}
+		}
+	}
+	if s.items != nil {
+		for i, item := range value {
+			s.items.validate(item, fmt.Sprintf("%s/%d", path, i), problems)
+		}
+	}
+}
+
+func (s *Schema) validateObject(value map[string]any, path string, problems *[]string, report func(string, ...any)) {
+	for _, name := range s.required {
+		if _, ok := value[name]; !ok {
+			report("missing required property %q", name)
+		}
+	}
+	if s.minProperties != nil && len(value) < *s.minProperties {
+		report("must have at least %d properties", *s.minProperties)
+	}
+	if s.maxProperties != nil && len(value) > *s.maxProperties {
+		report("must have at most %d properties", *s.maxProperties)
+	}
+
+	names := make([]string, 0, len(value))
+	for name := range value {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		at := path + "/" + escape(name)
+		matched := false
+		if sub, ok := s.properties[name]; ok {
+			matched = true
+			sub.validate(value[name], at, problems)
+		}
+		for _, pp := range s.patternProperties {
+			if pp.pattern.MatchString(name) {
+				matched = true
+				pp.schema.validate(value[name], at, problems)
+			}
+		}
+		if !matched && s.additional != nil {
+			if s.additional.boolean != nil && !*s.additional.boolean {
+				*problems = append(*problems, at+": unknown property")
+				continue
+			}
+			s.additional.validate(value[name], at, problems)
+		}
+	}
+}
+
+func hasType(doc any, types []string) bool {
+	actual := typeOf(doc)
+	for _, name := range types {
+		if name == actual || (name == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+func typeOf(doc any) string {
+	switch value := doc.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if value == math.Trunc(value) && !math.IsInf(value, 0) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", doc)
+}
+
+func contains(values []any, doc any) bool {
+	for _, value := range values {
+		if reflect.DeepEqual(value, doc) {
+			return true
+		}
+	}
+	return false
+}
+
+func encode(value any) string {
+	data, _ := json.Marshal(value)
+	return string(data)
+}
+
+// JSON Pointer: ~ и / в именах экранируются
+func escape(name string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(name)
+}
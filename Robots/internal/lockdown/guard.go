@@ -0,0 +1,268 @@
+package lockdown
+
+import (
+	"RobotService/internal/config"
+	"RobotService/internal/entities"
+	"RobotService/internal/prometheusinfo"
+	"RobotService/internal/ratelimit"
+	"RobotService/internal/rbac"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Как часто перечитываем режим из редиски. Режим общий, его может включить соседний инстанс
+const refreshEvery = time.Second
+
+var (
+	ErrLockedDown  = errors.New("robot mutations are locked down")
+	ErrInvalidMode = errors.New("invalid lockdown mode")
+)
+
+// Строгость режимов: более строгий не перекрывается более мягким
+var levels = map[string]int{
+	entities.LockdownNormal:        0,
+	entities.LockdownDeletesFrozen: 1,
+	entities.LockdownReadOnly:      2,
+}
+
+// Изменения роботов, которые считаем и которые блокирует read_only
+var mutations = []rbac.Permission{
+	rbac.RobotsCreate, rbac.RobotsMove, rbac.RobotsRename, rbac.RobotsRetype, rbac.RobotsDelete, rbac.RobotsImport,
+	rbac.RobotsAttributes, rbac.RobotsLabels,
+}
+
+// Где живёт режим и счётчики, в проде это редиска
+type Store interface {
+	CountInWindow(key, member string, window time.Duration) (int, error)
+	GetLockdown() (*entities.LockdownState, error)
+	EscalateLockdown(state entities.LockdownState) (bool, error)
+	SetLockdown(state entities.LockdownState) error
+	ClearLockdown() error
+}
+
+// Включение и снятие режима пишем в аудит
+type Auditor interface {
+	Record(ctx context.Context, action string, robotID int, before, after *entities.Robot, opErr error, details string)
+}
+
+type rule struct {
+	config.LockdownRule
+	actions []rbac.Permission
+}
+
+type Guard struct {
+	store Store
+	audit Auditor
+	log   *slog.Logger
+	rules []rule
+	// Уникальные отметки событий в окнах разных инстансов
+	instance string
+	seq      atomic.Uint64
+
+	mu      sync.Mutex
+	state   entities.LockdownState
+	fetched time.Time
+}
+
+// Правила проверяем на старте. enabled: false выключает только правила, ручной режим работает всегда
+func NewGuard(cfg config.Lockdown, store Store, audit Auditor, log *slog.Logger) (*Guard, error) {
+	guard := &Guard{store: store, audit: audit, log: log, state: normal()}
+	var id [6]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return nil, err
+	}
+	guard.instance = hex.EncodeToString(id[:])
+	setModeMetric(entities.LockdownNormal)
+
+	if !cfg.Enabled {
+		return guard, nil
+	}
+	names := map[string]bool{}
+	for i, r := range cfg.Rules {
+		switch {
+		case r.Name == "" || names[r.Name]:
+			return nil, fmt.Errorf("lockdown.rules[%d]: name must be unique and not empty", i)
+		case r.Threshold <= 0 || r.Window <= 0:
+			return nil, fmt.Errorf("lockdown.rules[%s]: threshold and window must be positive", r.Name)
+		case r.Mode != entities.LockdownDeletesFrozen && r.Mode != entities.LockdownReadOnly:
+			return nil, fmt.Errorf("lockdown.rules[%s]: mode must be %s or %s", r.Name, entities.LockdownDeletesFrozen, entities.LockdownReadOnly)
+		}
+		names[r.Name] = true
+
+		actions := mutations
+		if len(r.Actions) > 0 {
+			actions = nil
+			for _, action := range r.Actions {
+				if !slices.Contains(mutations, rbac.Permission(action)) {
+					return nil, fmt.Errorf("lockdown.rules[%s]: %q is not a robot mutation", r.Name, action)
+				}
+				actions = append(actions, rbac.Permission(action))
+			}
+		}
+		guard.rules = append(guard.rules, rule{LockdownRule: r, actions: actions})
+	}
+	return guard, nil
+}
+
+func normal() entities.LockdownState {
+	return entities.LockdownState{Mode: entities.LockdownNormal}
+}
+
+// Запрещено ли изменение в текущем режиме. Чтение не запрещается никогда
+func (g *Guard) Check(ctx context.Context, action rbac.Permission) error {
+	if g == nil {
+		return nil
+	}
+	state := g.current()
+	blocked := false
+	switch state.Mode {
+	case entities.LockdownReadOnly:
+		blocked = slices.Contains(mutations, action)
+	case entities.LockdownDeletesFrozen:
+		blocked = action == rbac.RobotsDelete
+	}
+	if !blocked {
+		return nil
+	}
+	prometheusinfo.LockdownBlocked.WithLabelValues(string(action)).Inc()
+	return fmt.Errorf("%w: %s since %s, %s", ErrLockedDown, state.Mode, state.Since.Format(time.RFC3339), state.Reason)
+}
+
+// Учитываем успешное изменение и включаем режим, если какое-то правило превышено
+func (g *Guard) Observe(ctx context.Context, action rbac.Permission) {
+	if g == nil {
+		return
+	}
+	client := ratelimit.Client(ctx)
+	for _, r := range g.rules {
+		if !slices.Contains(r.actions, action) {
+			continue
+		}
+		key := r.Name
+		if r.PerActor {
+			key += ":" + client
+		}
+		member := g.instance + "-" + strconv.FormatUint(g.seq.Add(1), 36)
+		count, err := g.store.CountInWindow(key, member, r.Window)
+		if err != nil {
+			g.log.Warn("Lockdown counter failed", "rule", r.Name, "error", err.Error())
+			continue
+		}
+		if count > r.Threshold {
+			g.trigger(ctx, r, count, client)
+		}
+	}
+}
+
+func (g *Guard) trigger(ctx context.Context, r rule, count int, client string) {
+	now := time.Now().UTC()
+	state := entities.LockdownState{
+		Mode:   r.Mode,
+		Level:  levels[r.Mode],
+		Reason: fmt.Sprintf("rule %s: %d mutations in %s", r.Name, count, r.Window),
+		Actor:  client,
+		Since:  &now,
+	}
+	changed, err := g.store.EscalateLockdown(state)
+	if err != nil {
+		g.log.Error("Unable to enable lockdown", "rule", r.Name, "error", err.Error())
+		return
+	}
+	if !changed {
+		return
+	}
+	g.remember(state)
+	prometheusinfo.LockdownTriggered.WithLabelValues(r.Name, r.Mode).Inc()
+	g.log.Warn("Lockdown enabled", "mode", r.Mode, "rule", r.Name, "count", count, "actor", client, "audit", true)
+	g.audit.Record(ctx, "lockdown:"+r.Mode, 0, nil, nil, nil, state.Reason)
+}
+
+// Текущий режим прямо из хранилища
+func (g *Guard) State() (entities.LockdownState, error) {
+	state, err := g.store.GetLockdown()
+	if err != nil {
+		return entities.LockdownState{}, err
+	}
+	if state == nil {
+		return g.remember(normal()), nil
+	}
+	return g.remember(*state), nil
+}
+
+// Ручное включение режима. Можно и ослабить режим, включённый автоматикой
+func (g *Guard) Lock(ctx context.Context, mode, reason string) (entities.LockdownState, error) {
+	if mode != entities.LockdownDeletesFrozen && mode != entities.LockdownReadOnly {
+		return entities.LockdownState{}, fmt.Errorf("%w: %q", ErrInvalidMode, mode)
+	}
+	now := time.Now().UTC()
+	state := entities.LockdownState{Mode: mode, Level: levels[mode], Reason: reason, Actor: ratelimit.Client(ctx), Since: &now}
+	if err := g.store.SetLockdown(state); err != nil {
+		return entities.LockdownState{}, err
+	}
+	g.remember(state)
+	g.log.Warn("Lockdown enabled manually", "mode", mode, "actor", state.Actor, "reason", reason, "audit", true)
+	g.audit.Record(ctx, "lockdown:"+mode, 0, nil, nil, nil, "manual: "+reason)
+	return state, nil
+}
+
+// Снятие режима. Счётчики правил начинаются заново с момента снятия
+func (g *Guard) Unlock(ctx context.Context) (entities.LockdownState, error) {
+	previous, err := g.State()
+	if err != nil {
+		return entities.LockdownState{}, err
+	}
+	if err = g.store.ClearLockdown(); err != nil {
+		return entities.LockdownState{}, err
+	}
+	state := g.remember(normal())
+	g.log.Warn("Lockdown lifted", "was", previous.Mode, "actor", ratelimit.Client(ctx), "audit", true)
+	g.audit.Record(ctx, "lockdown:"+entities.LockdownNormal, 0, nil, nil, nil, "lifted "+previous.Mode)
+	return state, nil
+}
+
+// Режим из памяти, раз в refreshEvery перечитываем. Если редиска недоступна, живём с последним известным
+func (g *Guard) current() entities.LockdownState {
+	g.mu.Lock()
+	state, fresh := g.state, time.Since(g.fetched) < refreshEvery
+	g.mu.Unlock()
+	if fresh {
+		return state
+	}
+
+	latest, err := g.State()
+	if err != nil {
+		g.log.Warn("Unable to read lockdown state", "error", err.Error())
+		g.mu.Lock()
+		g.fetched = time.Now()
+		g.mu.Unlock()
+		return state
+	}
+	return latest
+}
+
+func (g *Guard) remember(state entities.LockdownState) entities.LockdownState {
+	g.mu.Lock()
+	g.state, g.fetched = state, time.Now()
+	g.mu.Unlock()
+	setModeMetric(state.Mode)
+	return state
+}
+
+func setModeMetric(current string) {
+	for mode := range levels {
+		value := 0.0
+		if mode == current {
+			value = 1
+		}
+		prometheusinfo.LockdownMode.WithLabelValues(mode).Set(value)
+	}
+}
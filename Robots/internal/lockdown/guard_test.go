@@ -0,0 +1,265 @@
+package lockdown
+
+import (
+	"RobotService/internal/auth"
+	"RobotService/internal/config"
+	"RobotService/internal/entities"
+	"RobotService/internal/rbac"
+	"RobotService/internal/sorrage"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+type recordingAudit struct {
+	mu      sync.Mutex
+	actions []string
+}
+
+func (a *recordingAudit) Record(ctx context.Context, action string, robotID int, before, after *entities.Robot, opErr error, details string) {
+	a.mu.Lock()
+	a.actions = append(a.actions, action)
+	a.mu.Unlock()
+}
+
+type testGuard struct {
+	*Guard
+	server *miniredis.Miniredis
+	audit  *recordingAudit
+	now    time.Time
+}
+
+// Guard на miniredis: время окон двигает advance
+func newTestGuard(t *testing.T, rules ...config.LockdownRule) *testGuard {
+	t.Helper()
+	server := miniredis.RunT(t)
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	server.SetTime(now)
+	audit := &recordingAudit{}
+	guard, err := NewGuard(config.Lockdown{Enabled: true, Rules: rules}, sorrage.NewClient(server.Addr()), audit, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("NewGuard: %v", err)
+	}
+	return &testGuard{Guard: guard, server: server, audit: audit, now: now}
+}
+
+func (g *testGuard) advance(d time.Duration) {
+	g.now = g.now.Add(d)
+	g.server.SetTime(g.now)
+}
+
+func actor(subject string) context.Context {
+	return auth.WithPrincipal(context.Background(), &auth.Principal{Subject: subject, Method: auth.MethodJWT})
+}
+
+func TestNewGuardRules(t *testing.T) {
+	valid := config.LockdownRule{Name: "deletes", Threshold: 3, Window: time.Minute, Mode: entities.LockdownReadOnly}
+	tests := []struct {
+		name  string
+		rules []config.LockdownRule
+	}{
+		{"empty name", []config.LockdownRule{{Threshold: 3, Window: time.Minute, Mode: entities.LockdownReadOnly}}},
+		{"duplicate name", []config.LockdownRule{valid, valid}},
+		{"zero threshold", []config.LockdownRule{{Name: "a", Window: time.Minute, Mode: entities.LockdownReadOnly}}},
+		{"zero window", []config.LockdownRule{{Name: "a", Threshold: 3, Mode: entities.LockdownReadOnly}}},
+		{"normal mode", []config.LockdownRule{{Name: "a", Threshold: 3, Window: time.Minute, Mode: entities.LockdownNormal}}},
+		{"not a mutation", []config.LockdownRule{{Name: "a", Threshold: 3, Window: time.Minute, Mode: entities.LockdownReadOnly, Actions: []string{"robots:read"}}}},
+	}
+	discard := slog.New(slog.NewTextHandler(io.Discard, nil))
+	for _, tt := range tests {
+		if _, err := NewGuard(config.Lockdown{Enabled: true, Rules: tt.rules}, nil, nil, discard); err == nil {
+			t.Errorf("%s: no error", tt.name)
+		}
+	}
+	// Выключенные правила не проверяются и не работают
+	guard, err := NewGuard(config.Lockdown{Rules: tests[0].rules}, nil, nil, discard)
+	if err != nil || len(guard.rules) != 0 {
+		t.Fatalf("disabled rules: %+v, err = %v", guard, err)
+	}
+	guard, err = NewGuard(config.Lockdown{Enabled: true, Rules: []config.LockdownRule{valid}}, nil, nil, discard)
+	if err != nil || len(guard.rules[0].actions) != len(mutations) {
+		t.Fatalf("rule without actions watches %v, err = %v", guard.rules, err)
+	}
+}
+
+// Что разрешено в каждом режиме: чтение всегда, deletes_frozen запрещает только удаление
+func TestCheckAllowedActions(t *testing.T) {
+	g := newTestGuard(t)
+	ctx := actor("admin")
+	all := append([]rbac.Permission{rbac.RobotsRead, rbac.AuditRead, rbac.LockdownManage}, mutations...)
+	blocked := map[string]map[rbac.Permission]bool{
+		entities.LockdownNormal:        {},
+		entities.LockdownDeletesFrozen: {rbac.RobotsDelete: true},
+		entities.LockdownReadOnly:      {},
+	}
+	for _, action := range mutations {
+		blocked[entities.LockdownReadOnly][action] = true
+	}
+
+	for _, mode := range []string{entities.LockdownDeletesFrozen, entities.LockdownReadOnly, entities.LockdownNormal} {
+		if mode == entities.LockdownNormal {
+			if _, err := g.Unlock(ctx); err != nil {
+				t.Fatal(err)
+			}
+		} else if _, err := g.Lock(ctx, mode, "test"); err != nil {
+			t.Fatal(err)
+		}
+		for _, action := range all {
+			err := g.Check(ctx, action)
+			if blocked[mode][action] != errors.Is(err, ErrLockedDown) {
+				t.Errorf("%s: %s err = %v, blocked %v", mode, action, err, blocked[mode][action])
+			}
+		}
+	}
+
+	var none *Guard
+	if err := none.Check(ctx, rbac.RobotsDelete); err != nil {
+		t.Fatalf("nil guard: %v", err)
+	}
+}
+
+func TestObserveTripsRule(t *testing.T) {
+	g := newTestGuard(t, config.LockdownRule{Name: "mass-delete", Actions: []string{string(rbac.RobotsDelete)}, Threshold: 3, Window: time.Minute, Mode: entities.LockdownReadOnly})
+	ctx := actor("alice")
+
+	// Чужие действия правило не считает
+	for range 5 {
+		g.Observe(ctx, rbac.RobotsMove)
+	}
+	for range 3 {
+		g.Observe(ctx, rbac.RobotsDelete)
+	}
+	if err := g.Check(ctx, rbac.RobotsMove); err != nil {
+		t.Fatalf("locked at the threshold: %v", err)
+	}
+	// Окно скользит: через 61 секунду старые удаления уже не в счёт
+	g.advance(61 * time.Second)
+	for range 3 {
+		g.Observe(ctx, rbac.RobotsDelete)
+	}
+	if err := g.Check(ctx, rbac.RobotsMove); err != nil {
+		t.Fatalf("old events counted: %v", err)
+	}
+
+	g.Observe(ctx, rbac.RobotsDelete)
+	err := g.Check(ctx, rbac.RobotsMove)
+	if !errors.Is(err, ErrLockedDown) {
+		t.Fatalf("err = %v, want ErrLockedDown over the threshold", err)
+	}
+	state, err := g.State()
+	if err != nil || state.Mode != entities.LockdownReadOnly || state.Actor != auth.Actor(ctx) || state.Since == nil {
+		t.Fatalf("state = %+v, err = %v", state, err)
+	}
+	if len(g.audit.actions) != 1 || g.audit.actions[0] != "lockdown:"+entities.LockdownReadOnly {
+		t.Fatalf("audit = %v", g.audit.actions)
+	}
+	// Повторное превышение режим не включает заново
+	g.Observe(ctx, rbac.RobotsDelete)
+	if len(g.audit.actions) != 1 {
+		t.Fatalf("audit after another delete = %v", g.audit.actions)
+	}
+
+	// После снятия счёт начинается заново
+	if _, err = g.Unlock(actor("admin")); err != nil {
+		t.Fatal(err)
+	}
+	g.advance(time.Millisecond)
+	for range 3 {
+		g.Observe(ctx, rbac.RobotsDelete)
+	}
+	if err = g.Check(ctx, rbac.RobotsDelete); err != nil {
+		t.Fatalf("counters survived the unlock: %v", err)
+	}
+}
+
+func TestObservePerActor(t *testing.T) {
+	g := newTestGuard(t, config.LockdownRule{Name: "per-actor", PerActor: true, Threshold: 2, Window: time.Minute, Mode: entities.LockdownDeletesFrozen})
+	for range 2 {
+		g.Observe(actor("alice"), rbac.RobotsCreate)
+		g.Observe(actor("bob"), rbac.RobotsRename)
+	}
+	if err := g.Check(actor("alice"), rbac.RobotsDelete); err != nil {
+		t.Fatalf("two actors at the threshold: %v", err)
+	}
+	g.Observe(actor("bob"), rbac.RobotsMove)
+	if err := g.Check(actor("alice"), rbac.RobotsDelete); !errors.Is(err, ErrLockedDown) {
+		t.Fatalf("err = %v, want ErrLockedDown for everyone", err)
+	}
+	if state, _ := g.State(); state.Actor != auth.Actor(actor("bob")) {
+		t.Fatalf("tripped by %q", state.Actor)
+	}
+}
+
+func TestManualLock(t *testing.T) {
+	g := newTestGuard(t, config.LockdownRule{Name: "deletes", Threshold: 1, Window: time.Minute, Mode: entities.LockdownDeletesFrozen})
+	ctx := actor("admin")
+	if _, err := g.Lock(ctx, entities.LockdownNormal, "no"); !errors.Is(err, ErrInvalidMode) {
+		t.Fatalf("lock to normal: err = %v", err)
+	}
+	if _, err := g.Lock(ctx, "panic", "no"); !errors.Is(err, ErrInvalidMode) {
+		t.Fatalf("unknown mode: err = %v", err)
+	}
+
+	state, err := g.Lock(ctx, entities.LockdownReadOnly, "incident")
+	if err != nil || state.Mode != entities.LockdownReadOnly || state.Actor != auth.Actor(ctx) || state.Reason != "incident" {
+		t.Fatalf("Lock: %+v, err = %v", state, err)
+	}
+	// Правило мягче ручного режима его не ослабляет
+	g.Observe(ctx, rbac.RobotsCreate)
+	g.Observe(ctx, rbac.RobotsCreate)
+	if state, _ = g.State(); state.Mode != entities.LockdownReadOnly {
+		t.Fatalf("rule downgraded the lockdown to %s", state.Mode)
+	}
+	// Вручную ослабить можно
+	if state, err = g.Lock(ctx, entities.LockdownDeletesFrozen, "calmer"); err != nil || state.Mode != entities.LockdownDeletesFrozen {
+		t.Fatalf("downgrade: %+v, err = %v", state, err)
+	}
+	if err = g.Check(ctx, rbac.RobotsCreate); err != nil {
+		t.Fatalf("create in deletes_frozen: %v", err)
+	}
+
+	state, err = g.Unlock(ctx)
+	if err != nil || state.Mode != entities.LockdownNormal {
+		t.Fatalf("Unlock: %+v, err = %v", state, err)
+	}
+	if err = g.Check(ctx, rbac.RobotsDelete); err != nil {
+		t.Fatalf("delete after unlock: %v", err)
+	}
+	want := []string{"lockdown:read_only", "lockdown:deletes_frozen", "lockdown:normal"}
+	if len(g.audit.actions) != len(want) {
+		t.Fatalf("audit = %v, want %v", g.audit.actions, want)
+	}
+	for i := range want {
+		if g.audit.actions[i] != want[i] {
+			t.Fatalf("audit = %v, want %v", g.audit.actions, want)
+		}
+	}
+}
+
+// Режим, включённый другим экземпляром, виден после refreshEvery
+func TestCheckSeesSharedState(t *testing.T) {
+	g := newTestGuard(t)
+	other, err := NewGuard(config.Lockdown{}, sorrage.NewClient(g.server.Addr()), &recordingAudit{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := actor("admin")
+	if err = g.Check(ctx, rbac.RobotsDelete); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = other.Lock(ctx, entities.LockdownDeletesFrozen, "elsewhere"); err != nil {
+		t.Fatal(err)
+	}
+	g.mu.Lock()
+	g.fetched = time.Now().Add(-refreshEvery)
+	g.mu.Unlock()
+	if err = g.Check(ctx, rbac.RobotsDelete); !errors.Is(err, ErrLockedDown) {
+		t.Fatalf("err = %v, want ErrLockedDown from the shared state", err)
+	}
+}
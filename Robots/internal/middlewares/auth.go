@@ -0,0 +1,58 @@
+package middlewares
+
+import (
+	"RobotService/internal/auth"
+	"RobotService/internal/requestinfo"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// Стримы RbtHndler.StreamRobots и WebSocketRobots. Браузер открывает их без своих заголовков,
+// поэтому здесь принимается ещё и короткий токен стрима из query или куки
+var streamPaths = map[string]bool{
+	"/robots/stream": true,
+	"/robots/ws":     true,
+}
+
+// Проверяем учётные данные и кладём принципала в контекст запроса.
+// Если required=false, запросы без учётных данных пропускаем анонимно, но неверные всё равно отбиваем
+func Authenticate(authn *auth.Authenticator, required bool, log *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var principal *auth.Principal
+			var err error
+			if r.Method == http.MethodGet && streamPaths[r.URL.Path] {
+				principal, err = authn.AuthenticateStream(r)
+			} else {
+				principal, err = authn.Authenticate(r.Header)
+			}
+			switch {
+			case err == nil:
+				r = r.WithContext(auth.WithPrincipal(r.Context(), principal))
+			case errors.Is(err, auth.ErrNoCredentials) && !required:
+			case errors.Is(err, auth.ErrNoCredentials), errors.Is(err, auth.ErrInvalidCredentials):
+				log.Warn("Authentication failed", "method", r.Method, "path", r.URL.Path, "remote", r.RemoteAddr, "error", err.Error())
+				w.Header().Set("WWW-Authenticate", `Bearer realm="robots"`)
+				http.Error(w, "требуется аутентификация", http.StatusUnauthorized)
+				return
+			default:
+				log.Error("Authentication error", "error", err.Error())
+				http.Error(w, "Error", http.StatusInternalServerError)
+				return
+			}
+
+			// Обёртка chi сохраняет Flusher и Hijacker, без них не работают SSE и вебсокеты
+			start := time.Now()
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r)
+			log.Info("HTTP request",
+				"method", r.Method, "path", r.URL.Path, "status", ww.Status(),
+				"principal", principal.String(), "request_id", requestinfo.From(r.Context()).ID,
+				"duration", time.Since(start))
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package middlewares
+
+import (
+	"RobotService/internal/auth"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestStreamTokenOnlyOpensStreams(t *testing.T) {
+	tokens, err := auth.NewStreamTokens(time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, _, err := tokens.Issue(&auth.Principal{Subject: "alice", Method: auth.MethodJWT})
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := Authenticate(&auth.Authenticator{Streams: tokens}, true, log)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, auth.Actor(r.Context()))
+	}))
+
+	tests := []struct {
+		method, target string
+		status         int
+	}{
+		{http.MethodGet, "/robots/stream?access_token=" + token, http.StatusOK},
+		{http.MethodGet, "/robots/ws?access_token=" + token, http.StatusOK},
+		{http.MethodGet, "/robots?access_token=" + token, http.StatusUnauthorized},
+		{http.MethodPost, "/robots/stream?access_token=" + token, http.StatusUnauthorized},
+		{http.MethodGet, "/robots/stream?access_token=bad.token", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(tt.method, tt.target, nil))
+		if w.Code != tt.status {
+			t.Fatalf("%s %s: status = %d, want %d", tt.method, tt.target, w.Code, tt.status)
+		}
+		if tt.status == http.StatusOK && w.Body.String() != "jwt:alice" {
+			t.Fatalf("%s %s: actor = %q", tt.method, tt.target, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package middlewares
+
+import (
+	"RobotService/internal/auth"
+	"RobotService/internal/rbac"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Больше этого тело ради цели не читаем, робот в JSON столько не весит
+const maxTargetBody = 64 << 10
+
+// Достаёт из запроса робота, с которым работает хендлер
+type TargetFunc func(r *http.Request) (rbac.Target, error)
+
+// Робот из пути: /robots/{id}
+func RobotFromURL(r *http.Request) (rbac.Target, error) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		return rbac.Target{}, err
+	}
+	return rbac.Target{RobotID: id}, nil
+}
+
+// Робот из JSON тела: {"id": 1, "type": "drone"}
+func RobotFromBody(r *http.Request) (rbac.Target, error) {
+	var fields struct {
+		ID   int    `json:"id"`
+		Type string `json:"type"`
+	}
+	if err := peekBody(r, &fields); err != nil {
+		return rbac.Target{}, err
+	}
+	return rbac.Target{RobotID: fields.ID, NewType: fields.Type}, nil
+}
+
+// Робот из поля robotId JSON тела: {"robotId": 1}, например назначение миссии
+func RobotIDFromBody(r *http.Request) (rbac.Target, error) {
+	var fields struct {
+		RobotID int `json:"robotId"`
+	}
+	if err := peekBody(r, &fields); err != nil {
+		return rbac.Target{}, err
+	}
+	return rbac.Target{RobotID: fields.RobotID}, nil
+}
+
+// Читаем тело в fields и возвращаем его на место для хендлера
+func peekBody(r *http.Request, fields any) error {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxTargetBody+1))
+	if err != nil {
+		return err
+	}
+	r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), r.Body))
+	if len(body) > maxTargetBody || json.Unmarshal(body, fields) != nil {
+		return errors.New("cannot read robot from body")
+	}
+	return nil
+}
+
+// Проверка прав по политике, вешается на каждый маршрут через router.With.
+// Без политики (nil) пропускаем всё
+func Authorize(policy *rbac.Engine, permission rbac.Permission, target TargetFunc) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if policy == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal := auth.FromContext(r.Context())
+
+			var t rbac.Target
+			if target != nil && policy.NeedsTarget(principal) {
+				// Если цель не разобрать, роли с ограничениями её не покроют, а без ограничений она не нужна
+				t, _ = target(r)
+			}
+
+			err := policy.Check(r.Context(), permission, t, "http", r.Method+" "+r.URL.Path)
+			switch {
+			case err == nil:
+				next.ServeHTTP(w, r)
+			case errors.Is(err, rbac.ErrDenied):
+				http.Error(w, "недостаточно прав", http.StatusForbidden)
+			default:
+				http.Error(w, "Error", http.StatusInternalServerError)
+			}
+		})
+	}
+}
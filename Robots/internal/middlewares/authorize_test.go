@@ -0,0 +1,89 @@
+package middlewares
+
+import (
+	"RobotService/internal/auth"
+	"RobotService/internal/config"
+	"RobotService/internal/entities"
+	"RobotService/internal/rbac"
+	"RobotService/internal/repositories"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type fakeRobots map[int]*entities.Robot
+
+func (f fakeRobots) GetRobotInfo(id int) (*entities.Robot, error) {
+	if id < 0 {
+		return nil, errors.New("db is down")
+	}
+	robot, ok := f[id]
+	if !ok {
+		return nil, repositories.ErrRobotNotFound
+	}
+	return robot, nil
+}
+
+func TestAuthorize(t *testing.T) {
+	cfg := config.RBAC{Roles: map[string]config.Role{
+		"viewer":         {Permissions: []string{"robots:read"}},
+		"operator":       {Inherits: []string{"viewer"}, Permissions: []string{"robots:move"}},
+		"drone-operator": {Permissions: []string{"robots:move"}, Types: []string{"drone"}},
+	}}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	policy, err := rbac.NewEngine(cfg, fakeRobots{1: {ID: 1, Type: "drone"}, 2: {ID: 2, Type: "rover"}}, nil, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := Authorize(policy, rbac.RobotsMove, RobotFromBody)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Тело после проверки цели остаётся хендлеру
+		body, _ := io.ReadAll(r.Body)
+		_, _ = w.Write(body)
+	}))
+
+	tests := []struct {
+		name      string
+		principal *auth.Principal
+		body      string
+		status    int
+	}{
+		{name: "operator", principal: &auth.Principal{Subject: "o", Roles: []string{"operator"}}, body: `{"id":2}`, status: http.StatusOK},
+		{name: "viewer", principal: &auth.Principal{Subject: "v", Roles: []string{"viewer"}}, body: `{"id":2}`, status: http.StatusForbidden},
+		{name: "anonymous", body: `{"id":2}`, status: http.StatusForbidden},
+		{name: "scoped in scope", principal: &auth.Principal{Subject: "d", Roles: []string{"drone-operator"}}, body: `{"id":1}`, status: http.StatusOK},
+		{name: "scoped out of scope", principal: &auth.Principal{Subject: "d", Roles: []string{"drone-operator"}}, body: `{"id":2}`, status: http.StatusForbidden},
+		{name: "scoped bad body", principal: &auth.Principal{Subject: "d", Roles: []string{"drone-operator"}}, body: `{`, status: http.StatusForbidden},
+		{name: "lookup error", principal: &auth.Principal{Subject: "d", Roles: []string{"drone-operator"}}, body: `{"id":-1}`, status: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/robots/move", strings.NewReader(tt.body))
+			if tt.principal != nil {
+				r = r.WithContext(auth.WithPrincipal(r.Context(), tt.principal))
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+			if tt.status == http.StatusOK && w.Body.String() != tt.body {
+				t.Fatalf("handler got body %q, want %q", w.Body.String(), tt.body)
+			}
+		})
+	}
+}
+
+func TestAuthorizeWithoutPolicy(t *testing.T) {
+	handler := Authorize(nil, rbac.RobotsDelete, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/robots/delete/1", nil))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusNoContent)
+	}
+}
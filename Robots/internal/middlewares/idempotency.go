@@ -0,0 +1,160 @@
+package middlewares
+
+import (
+	"RobotService/internal/auth"
+	"RobotService/internal/requestinfo"
+	"RobotService/internal/sorrage"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+const (
+	IdempotencyHeader = "Idempotency-Key"
+	ReplayedHeader    = "Idempotent-Replayed"
+
+	idempotencyTTL     = 24 * time.Hour
+	idempotencyLockTTL = 30 * time.Second
+)
+
+// Перехватываем ответ хендлера, чтобы потом положить его в редиску
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+// Как и у http.ResponseWriter, считается только первый вызов
+func (rec *responseRecorder) WriteHeader(status int) {
+	if rec.status != 0 {
+		return
+	}
+	rec.status = status
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
+
+// Мидлварь для изменяющих запросов с заголовком Idempotency-Key.
+// Повтор с тем же телом получает сохранённый ответ, повтор с другим телом - 422
+func Idempotency(cache *sorrage.RdsCache) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyHeader)
+			if key == "" || !isMutating(r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			key = idempotencyScope(r) + ":" + key
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				http.Error(w, "не удалось прочитать тело запроса", http.StatusBadRequest)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			fingerprint := makeFingerprint(r, body)
+
+			record, err := cache.GetIdempotencyRecord(key)
+			if err == nil {
+				replay(w, record, fingerprint)
+				return
+			}
+			if !errors.Is(err, sorrage.ErrIdempotencyNotFound) {
+				http.Error(w, "Error", http.StatusInternalServerError)
+				return
+			}
+
+			locked, err := cache.LockIdempotencyKey(key, idempotencyLockTTL)
+			if err != nil {
+				http.Error(w, "Error", http.StatusInternalServerError)
+				return
+			}
+			if !locked {
+				w.Header().Set("Retry-After", "1")
+				http.Error(w, "запрос с этим ключом уже обрабатывается", http.StatusConflict)
+				return
+			}
+			defer func() { _ = cache.UnlockIdempotencyKey(key) }()
+			// Пока мы шли от чтения к блокировке, первый запрос мог успеть сохранить ответ и отпустить ключ
+			record, err = cache.GetIdempotencyRecord(key)
+			if err == nil {
+				replay(w, record, fingerprint)
+				return
+			}
+			if !errors.Is(err, sorrage.ErrIdempotencyNotFound) {
+				http.Error(w, "Error", http.StatusInternalServerError)
+				return
+			}
+
+			rec := &responseRecorder{ResponseWriter: w}
+			next.ServeHTTP(rec, r)
+
+			// Серверные ошибки не запоминаем, чтобы клиент мог повторить запрос
+			if rec.status == 0 || rec.status >= http.StatusInternalServerError {
+				return
+			}
+			err = cache.SetIdempotencyRecord(key, sorrage.IdempotencyRecord{
+				Fingerprint: fingerprint,
+				StatusCode:  rec.status,
+				ContentType: rec.Header().Get("Content-Type"),
+				Body:        rec.body.Bytes(),
+			}, idempotencyTTL)
+			if err != nil {
+				log.Printf("Не удалось сохранить ответ по ключу идемпотентности %s: %v", key, err)
+			}
+		})
+	}
+}
+
+// Ключи разных клиентов не должны пересекаться, иначе можно получить чужой ответ.
+// Анонимные клиенты различаются только адресом
+func idempotencyScope(r *http.Request) string {
+	if actor := auth.Actor(r.Context()); actor != "" {
+		return actor
+	}
+	return "anonymous@" + requestinfo.From(r.Context()).SourceIP
+}
+
+func replay(w http.ResponseWriter, record *sorrage.IdempotencyRecord, fingerprint string) {
+	if record.Fingerprint != fingerprint {
+		http.Error(w, "ключ идемпотентности уже использован с другим запросом", http.StatusUnprocessableEntity)
+		return
+	}
+	if record.ContentType != "" {
+		w.Header().Set("Content-Type", record.ContentType)
+	}
+	w.Header().Set(ReplayedHeader, "true")
+	w.WriteHeader(record.StatusCode)
+	_, _ = w.Write(record.Body)
+}
+
+// Отпечаток запроса: метод, путь и тело
+func makeFingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(r.Method))
+	hash.Write([]byte{0})
+	hash.Write([]byte(r.URL.Path))
+	hash.Write([]byte{0})
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func isMutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
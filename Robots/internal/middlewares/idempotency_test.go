@@ -0,0 +1,87 @@
+package middlewares
+
+import (
+	"RobotService/internal/sorrage"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// Хендлер, который считает вызовы и отвечает 201
+type countingHandler struct {
+	calls int
+}
+
+func (h *countingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.calls++
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	// Второй WriteHeader http.ResponseWriter игнорирует, запись тоже должна
+	w.WriteHeader(http.StatusInternalServerError)
+	_, _ = w.Write([]byte(`{"id":1}`))
+}
+
+func newIdempotencyServer(t *testing.T) (http.Handler, *countingHandler) {
+	t.Helper()
+	cache := sorrage.NewClient(miniredis.RunT(t).Addr())
+	handler := &countingHandler{}
+	return RequestInfo(Idempotency(cache)(handler)), handler
+}
+
+func idempotentRequest(server http.Handler, key, body, remoteAddr string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "/robots", strings.NewReader(body))
+	r.Header.Set(IdempotencyHeader, key)
+	r.RemoteAddr = remoteAddr
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, r)
+	return w
+}
+
+func TestIdempotencyReplaysFirstStatus(t *testing.T) {
+	server, handler := newIdempotencyServer(t)
+
+	first := idempotentRequest(server, "k1", `{"name":"a"}`, "10.0.0.1:1000")
+	if first.Code != http.StatusCreated {
+		t.Fatalf("first status = %d, want 201", first.Code)
+	}
+	second := idempotentRequest(server, "k1", `{"name":"a"}`, "10.0.0.1:1001")
+	if second.Code != http.StatusCreated || second.Header().Get(ReplayedHeader) != "true" {
+		t.Fatalf("replay status = %d, replayed = %q", second.Code, second.Header().Get(ReplayedHeader))
+	}
+	if second.Body.String() != `{"id":1}` {
+		t.Fatalf("replay body = %q", second.Body.String())
+	}
+	if handler.calls != 1 {
+		t.Fatalf("handler called %d times, want 1", handler.calls)
+	}
+}
+
+func TestIdempotencyRejectsDifferentBody(t *testing.T) {
+	server, handler := newIdempotencyServer(t)
+
+	idempotentRequest(server, "k1", `{"name":"a"}`, "10.0.0.1:1000")
+	w := idempotentRequest(server, "k1", `{"name":"b"}`, "10.0.0.1:1000")
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want 422", w.Code)
+	}
+	if handler.calls != 1 {
+		t.Fatalf("handler called %d times, want 1", handler.calls)
+	}
+}
+
+func TestIdempotencyScopesAnonymousKeysByAddress(t *testing.T) {
+	server, handler := newIdempotencyServer(t)
+
+	idempotentRequest(server, "shared", `{"name":"a"}`, "10.0.0.1:1000")
+	// Другой анонимный клиент с тем же ключом не получает чужой ответ
+	w := idempotentRequest(server, "shared", `{"name":"b"}`, "10.0.0.2:1000")
+	if w.Code != http.StatusCreated || w.Header().Get(ReplayedHeader) != "" {
+		t.Fatalf("status = %d, replayed = %q", w.Code, w.Header().Get(ReplayedHeader))
+	}
+	if handler.calls != 2 {
+		t.Fatalf("handler called %d times, want 2", handler.calls)
+	}
+}
@@ -0,0 +1,69 @@
+package middlewares
+
+import (
+	"RobotService/internal/prometheusinfo"
+	"RobotService/internal/ratelimit"
+	"RobotService/internal/requestinfo"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Лимит частоты на клиента. routes нужен, чтобы ещё до роутинга узнать шаблон маршрута
+// и найти его лимит. Если редиска недоступна, пропускаем запрос: лимит не должен класть API
+func RateLimit(limiter *ratelimit.Limiter, routes chi.Routes, log *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Несуществующие пути все вместе, иначе метрика разрастётся от сканеров
+			route := "*"
+			rctx := chi.NewRouteContext()
+			if routes.Match(rctx, r.Method, r.URL.Path) {
+				route = r.Method + " " + rctx.RoutePattern()
+			}
+
+			decision, err := limiter.Allow(ratelimit.Client(r.Context()), route)
+			if limited(w, decision, err, route, log) {
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Лимит на адрес до аутентификации: неверные ключи и токены тоже тратят ведро.
+// Вешается перед Authenticate, ведро принципала остаётся за ним
+func RateLimitIP(limiter *ratelimit.Limiter, log *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			decision, err := limiter.AllowIP(requestinfo.From(r.Context()).SourceIP)
+			if limited(w, decision, err, ratelimit.PreAuthRoute, log) {
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Пишет заголовки RateLimit-* и 429, если токена нет. true - запрос дальше не идёт
+func limited(w http.ResponseWriter, decision ratelimit.Decision, err error, route string, log *slog.Logger) bool {
+	if err != nil {
+		log.Error("Rate limiter is unavailable", "error", err.Error())
+		return false
+	}
+	if decision.Policy == "" {
+		return !decision.Allowed
+	}
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(decision.Limit))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+	w.Header().Set("RateLimit-Reset", ratelimit.Seconds(decision.Reset))
+	w.Header().Set("RateLimit-Policy", decision.Policy)
+	if !decision.Allowed {
+		prometheusinfo.RateLimited.WithLabelValues(route).Inc()
+		w.Header().Set("Retry-After", ratelimit.Seconds(decision.RetryAfter))
+		http.Error(w, "слишком много запросов", http.StatusTooManyRequests)
+		return true
+	}
+	return false
+}
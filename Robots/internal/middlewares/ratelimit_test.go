@@ -0,0 +1,153 @@
+package middlewares
+
+import (
+	"RobotService/internal/auth"
+	"RobotService/internal/config"
+	"RobotService/internal/ratelimit"
+	"RobotService/internal/sorrage"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-chi/chi/v5"
+)
+
+func newRateLimitRouter(t *testing.T, store ratelimit.Store) *chi.Mux {
+	t.Helper()
+	limiter, err := ratelimit.NewLimiter(config.RateLimit{
+		Default: config.Limit{Requests: 60, Per: time.Minute, Burst: 3},
+		Routes:  map[string]config.Limit{"POST /robots/create": {Requests: 60, Per: time.Minute, Burst: 1}},
+	}, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	router := chi.NewRouter()
+	router.Use(RequestInfo)
+	router.Use(RateLimit(limiter, router, log))
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	router.Get("/robots/{id}", ok)
+	router.Post("/robots/create", ok)
+	return router
+}
+
+func send(handler http.Handler, method, target string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, nil)
+	r.RemoteAddr = "10.0.0.1:1000"
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestRateLimitHeaders(t *testing.T) {
+	server := miniredis.RunT(t)
+	server.SetTime(time.Unix(1_700_000_000, 0))
+	router := newRateLimitRouter(t, sorrage.NewClient(server.Addr()))
+
+	for i, remaining := range []string{"2", "1", "0"} {
+		// Разные id - один шаблон маршрута и одно ведро
+		w := send(router, http.MethodGet, "/robots/"+remaining)
+		if w.Code != http.StatusOK {
+			t.Fatalf("request %d: status = %d", i, w.Code)
+		}
+		if got := w.Header().Get("RateLimit-Remaining"); got != remaining {
+			t.Fatalf("request %d: RateLimit-Remaining = %q, want %s", i, got, remaining)
+		}
+		if w.Header().Get("RateLimit-Limit") != "3" || w.Header().Get("RateLimit-Policy") != "60;w=60;burst=3" {
+			t.Fatalf("request %d: headers = %v", i, w.Header())
+		}
+		if w.Header().Get("Retry-After") != "" {
+			t.Fatalf("request %d: Retry-After on allowed request", i)
+		}
+	}
+
+	w := send(router, http.MethodGet, "/robots/1")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want 429", w.Code)
+	}
+	if w.Header().Get("Retry-After") != "1" || w.Header().Get("RateLimit-Remaining") != "0" || w.Header().Get("RateLimit-Reset") != "3" {
+		t.Fatalf("headers = %v", w.Header())
+	}
+
+	// Через секунду в ведре снова токен
+	server.SetTime(time.Unix(1_700_000_001, 0))
+	if w = send(router, http.MethodGet, "/robots/1"); w.Code != http.StatusOK {
+		t.Fatalf("after refill: status = %d", w.Code)
+	}
+}
+
+func TestRateLimitPerRoute(t *testing.T) {
+	server := miniredis.RunT(t)
+	server.SetTime(time.Unix(1_700_000_000, 0))
+	router := newRateLimitRouter(t, sorrage.NewClient(server.Addr()))
+
+	if w := send(router, http.MethodPost, "/robots/create"); w.Code != http.StatusOK || w.Header().Get("RateLimit-Limit") != "1" {
+		t.Fatalf("first create: status = %d, headers = %v", w.Code, w.Header())
+	}
+	if w := send(router, http.MethodPost, "/robots/create"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("second create: status = %d, want 429", w.Code)
+	}
+	// У остальных маршрутов своё ведро, в том числе у несуществующих
+	if w := send(router, http.MethodGet, "/robots/1"); w.Code != http.StatusOK || w.Header().Get("RateLimit-Limit") != "3" {
+		t.Fatalf("read: status = %d, headers = %v", w.Code, w.Header())
+	}
+	if w := send(router, http.MethodGet, "/missing"); w.Code != http.StatusNotFound {
+		t.Fatalf("missing: status = %d, want 404", w.Code)
+	}
+}
+
+func TestRateLimitFailsOpen(t *testing.T) {
+	server := miniredis.RunT(t)
+	router := newRateLimitRouter(t, sorrage.NewClient(server.Addr()))
+	server.Close()
+
+	for i := 0; i < 5; i++ {
+		w := send(router, http.MethodPost, "/robots/create")
+		if w.Code != http.StatusOK {
+			t.Fatalf("request %d: status = %d, want 200", i, w.Code)
+		}
+		if w.Header().Get("RateLimit-Limit") != "" {
+			t.Fatalf("request %d: headers without a decision: %v", i, w.Header())
+		}
+	}
+}
+
+func TestRateLimitIPBeforeAuth(t *testing.T) {
+	server := miniredis.RunT(t)
+	server.SetTime(time.Unix(1_700_000_000, 0))
+	limiter, err := ratelimit.NewLimiter(config.RateLimit{
+		PerIP:   config.Limit{Requests: 60, Per: time.Minute, Burst: 2},
+		Default: config.Limit{Requests: 60, Per: time.Minute},
+	}, sorrage.NewClient(server.Addr()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens, err := auth.NewStreamTokens(time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := RequestInfo(RateLimitIP(limiter, log)(Authenticate(&auth.Authenticator{Streams: tokens}, true, log)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }))))
+
+	// Перебор неверных токенов упирается в ведро адреса, до аутентификации
+	for i, status := range []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests} {
+		w := send(handler, http.MethodGet, "/robots/stream?access_token=bad.token")
+		if w.Code != status {
+			t.Fatalf("request %d: status = %d, want %d", i, w.Code, status)
+		}
+	}
+
+	// Другой адрес - другое ведро
+	r := httptest.NewRequest(http.MethodGet, "/robots/stream?access_token=bad.token", nil)
+	r.RemoteAddr = "10.0.0.2:1000"
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("other address: status = %d, want 401", w.Code)
+	}
+}
@@ -0,0 +1,15 @@
+package middlewares
+
+import (
+	"RobotService/internal/requestinfo"
+	"net/http"
+)
+
+// Request id и адрес клиента в контекст, request id заодно возвращаем клиенту
+func RequestInfo(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info := requestinfo.New(r.Header.Get(requestinfo.Header), r.RemoteAddr)
+		w.Header().Set(requestinfo.Header, info.ID)
+		next.ServeHTTP(w, r.WithContext(requestinfo.With(r.Context(), info)))
+	})
+}
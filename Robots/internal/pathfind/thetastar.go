@@ -0,0 +1,193 @@
+package pathfind
+
+import (
+	"RobotService/internal/entities"
+	"container/heap"
+	"errors"
+	"math"
+)
+
+var (
+	ErrNoPath = errors.New("no path to the target")
+	// Поиск упёрся в ограничение по числу узлов. Путь может и быть, но слишком длинный или запутанный
+	ErrSearchLimit = errors.New("path search limit exceeded")
+)
+
+// Сетка вокселей: узлы - точки from + Step*(i, j, k), лежащие в коробке Min..Max (включительно), где from - старт поиска.
+// Free говорит, может ли робот находиться в точке. Между узлами путь идёт по прямой,
+// и прямая проверяется по всем целым точкам с шагом не больше единицы, так что
+// тонкие препятствия между узлами не проскочить
+type Grid struct {
+	Step     int
+	Min, Max entities.RobotCord
+	Free     func(entities.RobotCord) bool
+	// Сколько узлов можно раскрыть, 0 - без ограничения
+	MaxExpanded int
+}
+
+type key [3]int
+
+// Цель - отдельный узел вне сетки, её ключ ни с чем не совпадёт
+var goalKey = key{math.MinInt, math.MinInt, math.MinInt}
+
+// Путь из from в to алгоритмом Theta*: это A* по сетке, но узел может взять родителем
+// родителя соседа, если тот виден напрямую. Получаются ломаные под любым углом, а не по 26 направлениям.
+// Первая точка пути - from, последняя - to
+func (g *Grid) Find(from, to entities.RobotCord) ([]entities.RobotCord, error) {
+	if from == to {
+		return []entities.RobotCord{from}, nil
+	}
+	step := max(g.Step, 1)
+	cord := func(k key) entities.RobotCord {
+		if k == goalKey {
+			return to
+		}
+		return entities.RobotCord{XCord: from.XCord + k[0]*step, YCord: from.YCord + k[1]*step, ZCord: from.ZCord + k[2]*step}
+	}
+	inBox := func(c entities.RobotCord) bool {
+		return c.XCord >= g.Min.XCord && c.XCord <= g.Max.XCord &&
+			c.YCord >= g.Min.YCord && c.YCord <= g.Max.YCord &&
+			c.ZCord >= g.Min.ZCord && c.ZCord <= g.Max.ZCord
+	}
+	// Цель становится соседом любого узла в пределах одной ячейки от неё
+	reach := float64(step) * math.Sqrt(3)
+
+	start := key{}
+	cost := map[key]float64{start: 0}
+	parent := map[key]key{start: start}
+	closed := map[key]bool{}
+	open := &openList{{key: start, f: distance(from, to)}}
+
+	relax := func(s, next key) {
+		c := cord(next)
+		// Theta*: сначала пробуем напрямую от родителя s, потом через сам s
+		if p := parent[s]; p != s && g.lineOfSight(cord(p), c) {
+			g.update(open, cost, parent, next, p, cost[p]+distance(cord(p), c), distance(c, to))
+			return
+		}
+		if g.lineOfSight(cord(s), c) {
+			g.update(open, cost, parent, next, s, cost[s]+distance(cord(s), c), distance(c, to))
+		}
+	}
+
+	expanded := 0
+	for open.Len() > 0 {
+		s := heap.Pop(open).(openItem).key
+		if closed[s] {
+			continue
+		}
+		if s == goalKey {
+			return reconstruct(parent, s, cord), nil
+		}
+		closed[s] = true
+		expanded++
+		if g.MaxExpanded > 0 && expanded > g.MaxExpanded {
+			return nil, ErrSearchLimit
+		}
+
+		if distance(cord(s), to) <= reach {
+			relax(s, goalKey)
+		}
+		for dx := -1; dx <= 1; dx++ {
+			for dy := -1; dy <= 1; dy++ {
+				for dz := -1; dz <= 1; dz++ {
+					if dx == 0 && dy == 0 && dz == 0 {
+						continue
+					}
+					next := key{s[0] + dx, s[1] + dy, s[2] + dz}
+					if closed[next] {
+						continue
+					}
+					if c := cord(next); !inBox(c) || !g.Free(c) {
+						continue
+					}
+					relax(s, next)
+				}
+			}
+		}
+	}
+	return nil, ErrNoPath
+}
+
+func (g *Grid) update(open *openList, cost map[key]float64, parent map[key]key, next, via key, candidate, h float64) {
+	if old, ok := cost[next]; ok && old <= candidate {
+		return
+	}
+	cost[next] = candidate
+	parent[next] = via
+	// Старую запись в очереди не ищем: она всплывёт позже и будет пропущена как закрытая
+	heap.Push(open, openItem{key: next, f: candidate + h, g: candidate})
+}
+
+// Видно ли b из a: все целые точки отрезка с шагом не больше единицы свободны
+func (g *Grid) lineOfSight(a, b entities.RobotCord) bool {
+	n := int(math.Ceil(distance(a, b)))
+	for i := 1; i <= n; i++ {
+		t := float64(i) / float64(n)
+		c := entities.RobotCord{
+			XCord: a.XCord + int(math.Round(t*float64(b.XCord-a.XCord))),
+			YCord: a.YCord + int(math.Round(t*float64(b.YCord-a.YCord))),
+			ZCord: a.ZCord + int(math.Round(t*float64(b.ZCord-a.ZCord))),
+		}
+		if !g.Free(c) {
+			return false
+		}
+	}
+	return true
+}
+
+func reconstruct(parent map[key]key, last key, cord func(key) entities.RobotCord) []entities.RobotCord {
+	var path []entities.RobotCord
+	for k := last; ; k = parent[k] {
+		path = append(path, cord(k))
+		if parent[k] == k {
+			break
+		}
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+// Длина ломаной
+func Length(path []entities.RobotCord) float64 {
+	total := 0.0
+	for i := 1; i < len(path); i++ {
+		total += distance(path[i-1], path[i])
+	}
+	return total
+}
+
+func distance(a, b entities.RobotCord) float64 {
+	dx, dy, dz := float64(a.XCord-b.XCord), float64(a.YCord-b.YCord), float64(a.ZCord-b.ZCord)
+	return math.Sqrt(dx*dx + dy*dy + dz*dz)
+}
+
+type openItem struct {
+	key  key
+	f, g float64
+}
+
+// Очередь по f, при равенстве - сначала более далёкие от старта, они ближе к цели
+type openList []openItem
+
+func (q openList) Len() int { return len(q) }
+
+func (q openList) Less(i, j int) bool {
+	if q[i].f != q[j].f {
+		return q[i].f < q[j].f
+	}
+	return q[i].g > q[j].g
+}
+
+func (q openList) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *openList) Push(x any) { *q = append(*q, x.(openItem)) }
+
+func (q *openList) Pop() any {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
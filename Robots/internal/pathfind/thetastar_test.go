@@ -0,0 +1,186 @@
+package pathfind
+
+import (
+	"RobotService/internal/entities"
+	"errors"
+	"math"
+	"testing"
+)
+
+func cord(x, y int) entities.RobotCord {
+	return entities.RobotCord{XCord: x, YCord: y}
+}
+
+// Плоская сетка z = 0 в коробке x, y от -size до size, blocked - занятые точки
+func plane(size int, blocked func(x, y int) bool) *Grid {
+	return &Grid{
+		Step: 1,
+		Min:  cord(-size, -size),
+		Max:  cord(size, size),
+		Free: func(c entities.RobotCord) bool {
+			return c.ZCord == 0 && (blocked == nil || !blocked(c.XCord, c.YCord))
+		},
+	}
+}
+
+// Стена x = 5 от y = -3 до 3
+func wall(x, y int) bool {
+	return x == 5 && y >= -3 && y <= 3
+}
+
+// Путь начинается в from, кончается в to, и каждый отрезок свободен
+func checkPath(t *testing.T, g *Grid, path []entities.RobotCord, from, to entities.RobotCord) {
+	t.Helper()
+	if len(path) == 0 || path[0] != from || path[len(path)-1] != to {
+		t.Fatalf("path %v does not go from %v to %v", path, from, to)
+	}
+	for i := 1; i < len(path); i++ {
+		if !g.lineOfSight(path[i-1], path[i]) {
+			t.Fatalf("segment %v -> %v of %v is blocked", path[i-1], path[i], path)
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	tests := []struct {
+		name      string
+		grid      *Grid
+		from, to  entities.RobotCord
+		waypoints int
+		length    float64
+	}{
+		{name: "straight line", grid: plane(20, nil), from: cord(0, 0), to: cord(10, 0), waypoints: 2, length: 10},
+		{name: "any angle", grid: plane(20, nil), from: cord(0, 0), to: cord(7, 3), waypoints: 2, length: math.Sqrt(58)},
+		{name: "start equals goal", grid: plane(20, nil), from: cord(3, 3), to: cord(3, 3), waypoints: 1, length: 0},
+		// Обход стены через её край: два отрезка по sqrt(25+16)
+		{name: "around obstacle", grid: plane(20, wall), from: cord(0, 0), to: cord(10, 0), waypoints: 3, length: 2 * math.Sqrt(41)},
+		{name: "coarse step", grid: &Grid{Step: 5, Min: cord(-20, -20), Max: cord(40, 20), Free: plane(40, wall).Free}, from: cord(0, 0), to: cord(23, 2)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, err := tt.grid.Find(tt.from, tt.to)
+			if err != nil {
+				t.Fatalf("Find: %v", err)
+			}
+			checkPath(t, tt.grid, path, tt.from, tt.to)
+			if tt.waypoints == 0 {
+				return
+			}
+			if len(path) != tt.waypoints {
+				t.Fatalf("path %v has %d waypoints, want %d", path, len(path), tt.waypoints)
+			}
+			if math.Abs(Length(path)-tt.length) > 1e-9 {
+				t.Fatalf("length %v, want %v", Length(path), tt.length)
+			}
+		})
+	}
+}
+
+func TestFindFails(t *testing.T) {
+	// Цель в кольце радиуса 2: по 26 соседям кольцо не пройти, а до цели дотягиваются только из соседних узлов
+	ring := func(x, y int) bool {
+		return max(abs(x-10), abs(y)) == 2
+	}
+	tests := []struct {
+		name string
+		grid *Grid
+		to   entities.RobotCord
+		err  error
+	}{
+		{name: "enclosed target", grid: plane(15, ring), to: cord(10, 0), err: ErrNoPath},
+		{name: "blocked target", grid: plane(15, func(x, y int) bool { return x == 10 && y == 0 }), to: cord(10, 0), err: ErrNoPath},
+		{name: "target outside the box", grid: plane(5, nil), to: cord(10, 0), err: ErrNoPath},
+		{name: "search limit", grid: &Grid{Step: 1, Min: cord(-15, -15), Max: cord(15, 15), Free: plane(15, ring).Free, MaxExpanded: 20}, to: cord(10, 0), err: ErrSearchLimit},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, err := tt.grid.Find(cord(0, 0), tt.to)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("path %v, err = %v, want %v", path, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestFindBeatsGridAStar(t *testing.T) {
+	g := plane(20, wall)
+	from, to := cord(0, 0), cord(13, 6)
+	path, err := g.Find(from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkPath(t, g, path, from, to)
+
+	grid := gridAStar(g, from, to)
+	if grid == nil {
+		t.Fatal("grid A* found no path")
+	}
+	// Даже если склеить шаги по одной прямой, у пути по 8 направлениям больше изломов, и он длиннее
+	if len(path) >= len(corners(grid)) {
+		t.Fatalf("theta* %v has %d waypoints, grid A* %v has %d", path, len(path), corners(grid), len(corners(grid)))
+	}
+	if Length(path) >= Length(grid) {
+		t.Fatalf("theta* length %v, grid A* %v", Length(path), Length(grid))
+	}
+}
+
+// Обычный A* по 8 соседям в плоскости z = 0, каждый шаг - в соседнюю точку
+func gridAStar(g *Grid, from, to entities.RobotCord) []entities.RobotCord {
+	cost := map[entities.RobotCord]float64{from: 0}
+	parent := map[entities.RobotCord]entities.RobotCord{}
+	closed := map[entities.RobotCord]bool{}
+	for {
+		// Сетка маленькая, обходимся без очереди
+		var best entities.RobotCord
+		found := false
+		for c, cc := range cost {
+			if !closed[c] && (!found || cc+distance(c, to) < cost[best]+distance(best, to)) {
+				best, found = c, true
+			}
+		}
+		if !found {
+			return nil
+		}
+		if best == to {
+			break
+		}
+		closed[best] = true
+		for dx := -1; dx <= 1; dx++ {
+			for dy := -1; dy <= 1; dy++ {
+				next := cord(best.XCord+dx, best.YCord+dy)
+				if next == best || next.XCord < g.Min.XCord || next.XCord > g.Max.XCord ||
+					next.YCord < g.Min.YCord || next.YCord > g.Max.YCord || !g.Free(next) {
+					continue
+				}
+				if old, ok := cost[next]; !ok || cost[best]+distance(best, next) < old {
+					cost[next] = cost[best] + distance(best, next)
+					parent[next] = best
+				}
+			}
+		}
+	}
+	path := []entities.RobotCord{to}
+	for c := to; c != from; c = parent[c] {
+		path = append([]entities.RobotCord{parent[c]}, path...)
+	}
+	return path
+}
+
+// Концы пути и точки, где он меняет направление
+func corners(path []entities.RobotCord) []entities.RobotCord {
+	out := []entities.RobotCord{path[0]}
+	for i := 1; i < len(path)-1; i++ {
+		a, b, c := path[i-1], path[i], path[i+1]
+		if b.XCord-a.XCord != c.XCord-b.XCord || b.YCord-a.YCord != c.YCord-b.YCord {
+			out = append(out, b)
+		}
+	}
+	return append(out, path[len(path)-1])
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -1,6 +1,11 @@
 package prometheusinfo
 
 import (
+	"RobotService/internal/entities"
+	"fmt"
+	"regexp"
+	"strings"
+
 	"github.com/prometheus/client_golang/prometheus"
 )
 
@@ -56,6 +61,71 @@ var (
 		[]string{"method", "handler", "status"},
 	)
 
+	GRPCRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "grpc_requests_total",
+			Help: "Количество gRPC вызовов",
+		},
+		[]string{"method", "code"},
+	)
+
+	GRPCRequestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "grpc_request_duration_seconds",
+			Help:    "Duration of gRPC calls",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"method", "code"},
+	)
+
+	StreamClients = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "robot_stream_clients",
+			Help: "Количество подключённых клиентов стрима изменений",
+		},
+		[]string{"transport"},
+	)
+
+	AccessDenied = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "robot_access_denied_total",
+			Help: "Количество запросов, отклонённых политикой доступа",
+		},
+		[]string{"permission", "transport"},
+	)
+
+	RateLimited = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "robot_rate_limited_total",
+			Help: "Количество запросов, отклонённых лимитом частоты",
+		},
+		[]string{"route"},
+	)
+
+	LockdownMode = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "robot_lockdown_mode",
+			Help: "Текущий защитный режим: 1 у включённого режима, 0 у остальных",
+		},
+		[]string{"mode"},
+	)
+
+	LockdownTriggered = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "robot_lockdown_triggered_total",
+			Help: "Сколько раз правило включило защитный режим",
+		},
+		[]string{"rule", "mode"},
+	)
+
+	LockdownBlocked = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "robot_lockdown_blocked_total",
+			Help: "Количество изменений, отклонённых защитным режимом",
+		},
+		[]string{"action"},
+	)
+
 	CountOfRobotType = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "robot_types_total",
@@ -65,6 +135,66 @@ var (
 	)
 )
 
+// Операции над роботами в разрезе типа и меток из metrics.labelKeys. Собирается в RegisterRobotLabels,
+// потому что набор измерений известен только после чтения конфига
+var (
+	RobotOperations *prometheus.CounterVec
+	robotLabelKeys  []string
+)
+
+var notLabelChar = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// Ключ метки робота в имя измерения: site -> label_site, example.com/team -> label_example_com_team
+func labelDimension(key string) string {
+	return "label_" + notLabelChar.ReplaceAllString(key, "_")
+}
+
+// Список ключей - белый: значения меток попадают в измерения, так что каждый ключ множит число рядов
+func RegisterRobotLabels(keys []string) error {
+	dimensions := []string{"operation", "robot_type"}
+	seen := map[string]string{}
+	for _, key := range keys {
+		if strings.TrimSpace(key) == "" {
+			return fmt.Errorf("metrics.labelKeys: empty key")
+		}
+		dimension := labelDimension(key)
+		if other, ok := seen[dimension]; ok {
+			return fmt.Errorf("metrics.labelKeys: %q clashes with %q", key, other)
+		}
+		seen[dimension] = key
+		dimensions = append(dimensions, dimension)
+	}
+	robotLabelKeys = keys
+	RobotOperations = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "robot_operations_total",
+			Help: "Количество изменений роботов по типу и разрешённым меткам",
+		},
+		dimensions,
+	)
+	return prometheus.Register(RobotOperations)
+}
+
+// robot может быть nil, если состояние после изменения не удалось прочитать
+func ObserveRobotOperation(operation string, robot *entities.Robot) {
+	if RobotOperations == nil {
+		return
+	}
+	values := make([]string, 0, len(robotLabelKeys)+2)
+	values = append(values, operation, "")
+	if robot != nil {
+		values[1] = robot.Type
+	}
+	for _, key := range robotLabelKeys {
+		value := ""
+		if robot != nil {
+			value = robot.Labels[key]
+		}
+		values = append(values, value)
+	}
+	RobotOperations.WithLabelValues(values...).Inc()
+}
+
 func Register() {
 	prometheus.MustRegister(CreatedRobot)
 	prometheus.MustRegister(GetRobot)
@@ -75,4 +205,12 @@ func Register() {
 	prometheus.MustRegister(CountOfRobotType)
 
 	prometheus.MustRegister(RequestDuration)
+	prometheus.MustRegister(GRPCRequests)
+	prometheus.MustRegister(GRPCRequestDuration)
+	prometheus.MustRegister(StreamClients)
+	prometheus.MustRegister(AccessDenied)
+	prometheus.MustRegister(RateLimited)
+	prometheus.MustRegister(LockdownMode)
+	prometheus.MustRegister(LockdownTriggered)
+	prometheus.MustRegister(LockdownBlocked)
 }
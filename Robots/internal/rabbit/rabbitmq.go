@@ -40,13 +40,33 @@ func NewPublisher(amqpURL string) (*Publisher, error) {
 
 // Закрываем соединение с реббитом при выходе из программы
 func (p *Publisher) Close() {
+	if p == nil {
+		return
+	}
 	_ = p.channel.Close()
 	_ = p.conn.Close()
 }
 
+// Кто сделал изменение, уходит в заголовке actor
+func actorHeaders(actor string) amqp.Table {
+	if actor == "" {
+		return nil
+	}
+	return amqp.Table{"actor": actor}
+}
+
 // Отправка сообщений в реббит со структурой робота
-func (p *Publisher) Publish(robot *entities.Robot, routingKey string) error {
-	body, _ := json.Marshal(robot)
+func (p *Publisher) Publish(robot *entities.Robot, routingKey, actor string) error {
+	return p.PublishJSON(robot, routingKey, actor)
+}
+
+// Отправка любой структуры джесоном (миссии и прочее, что не робот).
+// Без паблишера (nil, например в CLI без реббита) события просто не уходят
+func (p *Publisher) PublishJSON(value any, routingKey, actor string) error {
+	if p == nil {
+		return nil
+	}
+	body, _ := json.Marshal(value)
 
 	log.Printf("Отправка в рэббит по routing key: %s", routingKey)
 	return p.channel.Publish(
@@ -56,13 +76,17 @@ func (p *Publisher) Publish(robot *entities.Robot, routingKey string) error {
 		false,
 		amqp.Publishing{
 			ContentType: "application/json",
+			Headers:     actorHeaders(actor),
 			Body:        body,
 		},
 	)
 }
 
 // Отправка сообщений в реббит с текстом
-func (p *Publisher) PublishWithText(message string, routingKey string) error {
+func (p *Publisher) PublishWithText(message string, routingKey, actor string) error {
+	if p == nil {
+		return nil
+	}
 	log.Printf("Отправка сообщения в рэббит по routing key: %s", routingKey)
 	return p.channel.Publish(
 		exchangeName,
@@ -71,6 +95,7 @@ func (p *Publisher) PublishWithText(message string, routingKey string) error {
 		false,
 		amqp.Publishing{
 			ContentType: "text/plain",
+			Headers:     actorHeaders(actor),
 			Body:        []byte(message),
 		},
 	)
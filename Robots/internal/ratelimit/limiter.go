@@ -0,0 +1,129 @@
+package ratelimit
+
+import (
+	"RobotService/internal/auth"
+	"RobotService/internal/config"
+	"RobotService/internal/requestinfo"
+	"RobotService/internal/sorrage"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const (
+	// Ведро для всех маршрутов без своего лимита
+	defaultRoute = "*"
+	// Ведро адреса до аутентификации, в метриках и ключе редиски
+	PreAuthRoute = "preauth"
+)
+
+// Откуда берём токены, в проде это редиска
+type Store interface {
+	TakeToken(key string, rate float64, burst int) (sorrage.TokenBucketResult, error)
+}
+
+type rule struct {
+	rate  float64
+	burst int
+	limit config.Limit
+}
+
+// Результат для заголовков RateLimit-*
+type Decision struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration
+	Reset      time.Duration
+	// "10;w=60" для RateLimit-Policy
+	Policy string
+}
+
+type Limiter struct {
+	store Store
+	// nil - ведра адреса нет
+	perIP  *rule
+	def    rule
+	routes map[string]rule
+}
+
+func NewLimiter(cfg config.RateLimit, store Store) (*Limiter, error) {
+	def, err := newRule(cfg.Default)
+	if err != nil {
+		return nil, fmt.Errorf("rateLimit.default: %w", err)
+	}
+	limiter := &Limiter{store: store, def: def, routes: map[string]rule{}}
+	if cfg.PerIP.Requests != 0 {
+		perIP, err := newRule(cfg.PerIP)
+		if err != nil {
+			return nil, fmt.Errorf("rateLimit.perIP: %w", err)
+		}
+		limiter.perIP = &perIP
+	}
+	for route, limit := range cfg.Routes {
+		r, err := newRule(limit)
+		if err != nil {
+			return nil, fmt.Errorf("rateLimit.routes[%s]: %w", route, err)
+		}
+		limiter.routes[route] = r
+	}
+	return limiter, nil
+}
+
+func newRule(limit config.Limit) (rule, error) {
+	if limit.Requests <= 0 || limit.Per <= 0 {
+		return rule{}, errors.New("requests and per must be positive")
+	}
+	if limit.Burst <= 0 {
+		limit.Burst = limit.Requests
+	}
+	return rule{rate: float64(limit.Requests) / limit.Per.Seconds(), burst: limit.Burst, limit: limit}, nil
+}
+
+// Берём токен клиента для маршрута. Маршрут без своего правила идёт в общее ведро клиента
+func (l *Limiter) Allow(client, route string) (Decision, error) {
+	r, ok := l.routes[route]
+	if !ok {
+		r, route = l.def, defaultRoute
+	}
+
+	return l.take(client+":"+route, r)
+}
+
+// Токен из ведра адреса, до аутентификации. Без ведра адреса пропускаем всех
+func (l *Limiter) AllowIP(ip string) (Decision, error) {
+	if l.perIP == nil {
+		return Decision{Allowed: true}, nil
+	}
+	return l.take("ip:"+ip+":"+PreAuthRoute, *l.perIP)
+}
+
+func (l *Limiter) take(key string, r rule) (Decision, error) {
+	result, err := l.store.TakeToken(key, r.rate, r.burst)
+	if err != nil {
+		return Decision{}, err
+	}
+	return Decision{
+		Allowed:    result.Allowed,
+		Limit:      r.burst,
+		Remaining:  result.Remaining,
+		RetryAfter: result.RetryAfter,
+		Reset:      result.Reset,
+		Policy:     strconv.Itoa(r.limit.Requests) + ";w=" + Seconds(r.limit.Per) + ";burst=" + strconv.Itoa(r.burst),
+	}, nil
+}
+
+// Секунды для заголовков, округляем вверх, чтобы клиент не пришёл раньше времени
+func Seconds(d time.Duration) string {
+	return strconv.Itoa(int((d + time.Second - 1) / time.Second))
+}
+
+// Клиент для лимита: принципал (API ключ, пользователь JWT), а для анонимов - IP
+func Client(ctx context.Context) string {
+	if actor := auth.Actor(ctx); actor != "" {
+		return actor
+	}
+	return "ip:" + requestinfo.From(ctx).SourceIP
+}
@@ -0,0 +1,118 @@
+package ratelimit
+
+import (
+	"RobotService/internal/auth"
+	"RobotService/internal/config"
+	"RobotService/internal/requestinfo"
+	"RobotService/internal/sorrage"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+type take struct {
+	key   string
+	rate  float64
+	burst int
+}
+
+// Ведро, которое всегда даёт токен и запоминает, о чём его спросили
+type recordingStore struct{ takes []take }
+
+func (s *recordingStore) TakeToken(key string, rate float64, burst int) (sorrage.TokenBucketResult, error) {
+	s.takes = append(s.takes, take{key, rate, burst})
+	return sorrage.TokenBucketResult{Allowed: true, Remaining: burst - 1}, nil
+}
+
+func TestAllowRoutes(t *testing.T) {
+	store := &recordingStore{}
+	limiter, err := NewLimiter(config.RateLimit{
+		PerIP:   config.Limit{Requests: 120, Per: time.Minute, Burst: 20},
+		Default: config.Limit{Requests: 600, Per: time.Minute, Burst: 100},
+		Routes:  map[string]config.Limit{"POST /robots/create": {Requests: 5, Per: time.Minute}},
+	}, store)
+	if err != nil {
+		t.Fatalf("NewLimiter: %v", err)
+	}
+
+	tests := []struct {
+		route  string
+		want   take
+		policy string
+	}{
+		{route: "POST /robots/create", want: take{"jwt:alice:POST /robots/create", 5.0 / 60, 5}, policy: "5;w=60;burst=5"},
+		{route: "GET /robots", want: take{"jwt:alice:*", 10, 100}, policy: "600;w=60;burst=100"},
+		{route: "/robots.RobotService/ListRobots", want: take{"jwt:alice:*", 10, 100}, policy: "600;w=60;burst=100"},
+	}
+	for _, tt := range tests {
+		store.takes = nil
+		decision, err := limiter.Allow("jwt:alice", tt.route)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.route, err)
+		}
+		if len(store.takes) != 1 || store.takes[0] != tt.want {
+			t.Fatalf("%s: takes = %+v, want %+v", tt.route, store.takes, tt.want)
+		}
+		if decision.Policy != tt.policy || decision.Limit != tt.want.burst {
+			t.Fatalf("%s: decision = %+v", tt.route, decision)
+		}
+	}
+
+	store.takes = nil
+	if _, err = limiter.AllowIP("10.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	if want := (take{"ip:10.0.0.1:preauth", 2, 20}); len(store.takes) != 1 || store.takes[0] != want {
+		t.Fatalf("AllowIP takes = %+v, want %+v", store.takes, want)
+	}
+}
+
+func TestAllowIPDisabled(t *testing.T) {
+	store := &recordingStore{}
+	limiter, err := NewLimiter(config.RateLimit{Default: config.Limit{Requests: 1, Per: time.Second}}, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decision, err := limiter.AllowIP("10.0.0.1")
+	if err != nil || !decision.Allowed || len(store.takes) != 0 {
+		t.Fatalf("decision = %+v, err = %v, takes = %v", decision, err, store.takes)
+	}
+}
+
+func TestNewLimiterErrors(t *testing.T) {
+	good := config.Limit{Requests: 1, Per: time.Second}
+	tests := []struct {
+		name string
+		cfg  config.RateLimit
+		want string
+	}{
+		{name: "default", cfg: config.RateLimit{}, want: "rateLimit.default"},
+		{name: "route", cfg: config.RateLimit{Default: good, Routes: map[string]config.Limit{"GET /x": {Requests: 1}}}, want: "rateLimit.routes[GET /x]"},
+		{name: "perIP", cfg: config.RateLimit{Default: good, PerIP: config.Limit{Requests: 1}}, want: "rateLimit.perIP"},
+	}
+	for _, tt := range tests {
+		if _, err := NewLimiter(tt.cfg, &recordingStore{}); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Fatalf("%s: err = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestClient(t *testing.T) {
+	ctx := requestinfo.With(context.Background(), requestinfo.Info{SourceIP: "10.0.0.1"})
+	if got := Client(ctx); got != "ip:10.0.0.1" {
+		t.Fatalf("anonymous client = %q", got)
+	}
+	ctx = auth.WithPrincipal(ctx, &auth.Principal{Subject: "ci", Method: auth.MethodAPIKey})
+	if got := Client(ctx); got != "api-key:ci" {
+		t.Fatalf("client = %q", got)
+	}
+}
+
+func TestSeconds(t *testing.T) {
+	for d, want := range map[time.Duration]string{0: "0", time.Millisecond: "1", time.Second: "1", 1001 * time.Millisecond: "2"} {
+		if got := Seconds(d); got != want {
+			t.Fatalf("Seconds(%v) = %s, want %s", d, got, want)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package rbac
+
+import (
+	"RobotService/internal/auth"
+	"RobotService/internal/config"
+	"RobotService/internal/entities"
+	"RobotService/internal/prometheusinfo"
+	"RobotService/internal/repositories"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+)
+
+type Permission string
+
+const (
+	RobotsRead   Permission = "robots:read"
+	RobotsCreate Permission = "robots:create"
+	RobotsMove   Permission = "robots:move"
+	RobotsRename Permission = "robots:rename"
+	RobotsRetype Permission = "robots:retype"
+	RobotsDelete Permission = "robots:delete"
+	RobotsImport Permission = "robots:import"
+	// Менять пользовательские атрибуты робота
+	RobotsAttributes Permission = "robots:attributes"
+	// Ставить и снимать метки робота
+	RobotsLabels  Permission = "robots:labels"
+	WebhooksRead  Permission = "webhooks:read"
+	WebhooksWrite Permission = "webhooks:write"
+	AuditRead     Permission = "audit:read"
+	// Заводить, менять и удалять типы в реестре. Читать реестр можно с robots:read
+	TypesWrite Permission = "types:write"
+	// Заводить флоты и менять их состав. Групповые команды требуют ещё и права на само действие
+	FleetsWrite Permission = "fleets:write"
+	// Менять карту мира: границы, зоны, препятствия и геозоны. Читать её можно с robots:read
+	WorldWrite Permission = "world:write"
+	// Смотреть заявки на подтверждение и решать по ним. Для одобрения нужно ещё и право на само действие
+	ApprovalsRead   Permission = "approvals:read"
+	ApprovalsDecide Permission = "approvals:decide"
+	// Смотреть защитный режим и включать/снимать его вручную
+	LockdownRead   Permission = "lockdown:read"
+	LockdownManage Permission = "lockdown:manage"
+	// Заводить миссии, ставить на паузу, продолжать и отменять. Назначение на робота требует ещё robots:move на него
+	MissionsWrite Permission = "missions:write"
+
+	// Все права сразу
+	anyPermission Permission = "*"
+)
+
+var knownPermissions = []Permission{
+	RobotsRead, RobotsCreate, RobotsMove, RobotsRename, RobotsRetype, RobotsDelete, RobotsImport, RobotsAttributes,
+	RobotsLabels, WebhooksRead, WebhooksWrite, TypesWrite, FleetsWrite, WorldWrite, AuditRead, ApprovalsRead, ApprovalsDecide,
+	LockdownRead, LockdownManage, MissionsWrite, anyPermission,
+}
+
+var ErrDenied = errors.New("access denied")
+
+// Куда пишем отказы, кроме лога
+type Auditor interface {
+	RecordDenied(ctx context.Context, action string, robotID int, reason string)
+}
+
+// Откуда движок узнаёт тип робота для ролей с ограничением по типу
+type RobotLookup interface {
+	GetRobotInfo(id int) (*entities.Robot, error)
+}
+
+// С чем работает запрос. RobotID = 0 - без конкретного робота (список, создание),
+// NewType - тип, который робот получит после операции
+type Target struct {
+	RobotID int
+	NewType string
+}
+
+// Права роли после разворачивания наследования
+type grant struct {
+	permissions []Permission
+	types       []string
+	robotIDs    []int
+	scoped      bool
+}
+
+type Engine struct {
+	log       *slog.Logger
+	audit     Auditor
+	robots    RobotLookup
+	roles     map[string]grant
+	anonymous []string
+}
+
+// Собираем роли из конфига. Ошибки в политике (неизвестное право, роль, группа, цикл наследования)
+// ловим на старте, а не на первом запросе
+func NewEngine(cfg config.RBAC, robots RobotLookup, audit Auditor, log *slog.Logger) (*Engine, error) {
+	engine := &Engine{log: log, audit: audit, robots: robots, roles: map[string]grant{}, anonymous: cfg.Anonymous}
+
+	names := make([]string, 0, len(cfg.Roles))
+	for name := range cfg.Roles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		role := cfg.Roles[name]
+		permissions, err := collectPermissions(cfg.Roles, name, nil)
+		if err != nil {
+			return nil, err
+		}
+		g := grant{permissions: permissions, types: role.Types, scoped: len(role.Types) > 0 || len(role.Groups) > 0}
+		for _, group := range role.Groups {
+			ids, ok := cfg.Groups[group]
+			if !ok {
+				return nil, fmt.Errorf("rbac: role %s: unknown group %q", name, group)
+			}
+			g.robotIDs = append(g.robotIDs, ids...)
+		}
+		engine.roles[name] = g
+	}
+	for _, name := range cfg.Anonymous {
+		if _, ok := engine.roles[name]; !ok {
+			return nil, fmt.Errorf("rbac: anonymous: unknown role %q", name)
+		}
+	}
+	return engine, nil
+}
+
+func collectPermissions(roles map[string]config.Role, name string, path []string) ([]Permission, error) {
+	if slices.Contains(path, name) {
+		return nil, fmt.Errorf("rbac: inheritance cycle %v -> %s", path, name)
+	}
+	role, ok := roles[name]
+	if !ok {
+		return nil, fmt.Errorf("rbac: role %s inherits unknown role %q", path[len(path)-1], name)
+	}
+
+	var permissions []Permission
+	for _, value := range role.Permissions {
+		permission := Permission(value)
+		if !slices.Contains(knownPermissions, permission) {
+			return nil, fmt.Errorf("rbac: role %s: unknown permission %q", name, value)
+		}
+		permissions = append(permissions, permission)
+	}
+	for _, parent := range role.Inherits {
+		inherited, err := collectPermissions(roles, parent, append(path, name))
+		if err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, inherited...)
+	}
+	return permissions, nil
+}
+
+// Роли принципала. Неизвестные роли (например, из чужого JWT) просто ничего не дают
+func (e *Engine) rolesOf(principal *auth.Principal) []string {
+	if principal == nil {
+		return e.anonymous
+	}
+	return principal.Roles
+}
+
+// Нужно ли вытаскивать цель из запроса. Если у принципала нет ролей с ограничениями,
+// решение от цели не зависит и тело запроса можно не трогать
+func (e *Engine) NeedsTarget(principal *auth.Principal) bool {
+	for _, name := range e.rolesOf(principal) {
+		if e.roles[name].scoped {
+			return true
+		}
+	}
+	return false
+}
+
+// Authorize для транспортов: принципал берём из контекста, отказ считаем в метрике и пишем в аудит.
+// where - что именно пытались сделать (метод и путь, метод gRPC)
+func (e *Engine) Check(ctx context.Context, permission Permission, target Target, transport, where string) error {
+	principal := auth.FromContext(ctx)
+	err := e.Authorize(principal, permission, target)
+	if errors.Is(err, ErrDenied) {
+		prometheusinfo.AccessDenied.WithLabelValues(string(permission), transport).Inc()
+		e.log.Warn("Access denied", "audit", true, "principal", describe(principal),
+			"permission", string(permission), "transport", transport, "call", where, "robot", target.RobotID)
+		if e.audit != nil {
+			e.audit.RecordDenied(ctx, string(permission), target.RobotID, transport+" "+where)
+		}
+	}
+	return err
+}
+
+// nil - можно, иначе ошибка с ErrDenied и причиной
+func (e *Engine) Authorize(principal *auth.Principal, permission Permission, target Target) error {
+	var robot *entities.Robot
+	var lookupErr error
+	looked := false
+
+	for _, name := range e.rolesOf(principal) {
+		g, ok := e.roles[name]
+		if !ok || !g.allows(permission) {
+			continue
+		}
+		if !g.scoped {
+			return nil
+		}
+		// Роль с ограничениями действует только на конкретного робота или создание робота нужного типа
+		if target.RobotID == 0 && target.NewType == "" {
+			continue
+		}
+		if target.RobotID != 0 && !looked {
+			robot, lookupErr = e.robots.GetRobotInfo(target.RobotID)
+			looked = true
+		}
+		if g.covers(target, robot, lookupErr) {
+			return nil
+		}
+	}
+	if lookupErr != nil && !errors.Is(lookupErr, repositories.ErrRobotNotFound) {
+		return lookupErr
+	}
+	return fmt.Errorf("%w: %s lacks %s", ErrDenied, describe(principal), permission)
+}
+
+func (g grant) allows(permission Permission) bool {
+	return slices.Contains(g.permissions, permission) || slices.Contains(g.permissions, anyPermission)
+}
+
+func (g grant) covers(target Target, robot *entities.Robot, lookupErr error) bool {
+	if target.RobotID != 0 && (lookupErr != nil || robot == nil) {
+		return false
+	}
+	if len(g.types) > 0 {
+		if robot != nil && !slices.Contains(g.types, robot.Type) {
+			return false
+		}
+		if target.NewType != "" && !slices.Contains(g.types, target.NewType) {
+			return false
+		}
+	}
+	if len(g.robotIDs) > 0 && !slices.Contains(g.robotIDs, target.RobotID) {
+		return false
+	}
+	return true
+}
+
+func describe(principal *auth.Principal) string {
+	if principal == nil {
+		return "anonymous"
+	}
+	return principal.String()
+}
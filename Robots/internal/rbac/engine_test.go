@@ -0,0 +1,237 @@
+package rbac
+
+import (
+	"RobotService/internal/auth"
+	"RobotService/internal/config"
+	"RobotService/internal/entities"
+	"RobotService/internal/repositories"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+type fakeRobots map[int]*entities.Robot
+
+func (f fakeRobots) GetRobotInfo(id int) (*entities.Robot, error) {
+	if id < 0 {
+		return nil, errors.New("db is down")
+	}
+	robot, ok := f[id]
+	if !ok {
+		return nil, repositories.ErrRobotNotFound
+	}
+	return robot, nil
+}
+
+type deniedRecord struct {
+	action  string
+	robotID int
+}
+
+type fakeAuditor struct{ denied []deniedRecord }
+
+func (a *fakeAuditor) RecordDenied(_ context.Context, action string, robotID int, _ string) {
+	a.denied = append(a.denied, deniedRecord{action: action, robotID: robotID})
+}
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// Встроенные роли - те, что получает сервис без файла конфига
+func builtinRBAC(t *testing.T) config.RBAC {
+	t.Helper()
+	t.Setenv("ROBOTSRV_CONFIG", filepath.Join(t.TempDir(), "missing.yaml"))
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg.RBAC
+}
+
+func user(roles ...string) *auth.Principal {
+	return &auth.Principal{Subject: "u", Method: auth.MethodJWT, Roles: roles}
+}
+
+func TestBuiltinRoles(t *testing.T) {
+	engine, err := NewEngine(builtinRBAC(t), fakeRobots{}, nil, testLogger())
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+
+	viewer := []Permission{RobotsRead, WebhooksRead}
+	operator := append(slices.Clone(viewer), RobotsMove, RobotsRename, RobotsAttributes, RobotsLabels,
+		FleetsWrite, MissionsWrite, ApprovalsRead, LockdownRead)
+	admin := append(slices.Clone(operator), RobotsCreate, RobotsRetype, RobotsDelete, RobotsImport,
+		WebhooksWrite, TypesWrite, WorldWrite, AuditRead, ApprovalsDecide, LockdownManage)
+
+	roles := []struct {
+		name    string
+		allowed []Permission
+	}{
+		{"viewer", viewer},
+		{"operator", operator},
+		{"admin", admin},
+		{"unknown", nil},
+	}
+	for _, role := range roles {
+		for _, permission := range knownPermissions {
+			if permission == anyPermission {
+				continue
+			}
+			// Встроенные роли без ограничений, от цели решение не зависит
+			for _, target := range []Target{{}, {RobotID: 1}} {
+				err := engine.Authorize(user(role.name), permission, target)
+				want := slices.Contains(role.allowed, permission)
+				if want && err != nil {
+					t.Errorf("%s %s %+v: %v", role.name, permission, target, err)
+				}
+				if !want && !errors.Is(err, ErrDenied) {
+					t.Errorf("%s %s %+v: err = %v, want ErrDenied", role.name, permission, target, err)
+				}
+			}
+		}
+		if engine.NeedsTarget(user(role.name)) {
+			t.Errorf("%s needs a target", role.name)
+		}
+	}
+
+	// Без ролей и анонимно ничего нельзя
+	if err = engine.Authorize(nil, RobotsRead, Target{}); !errors.Is(err, ErrDenied) || !strings.Contains(err.Error(), "anonymous") {
+		t.Errorf("anonymous: err = %v", err)
+	}
+	if err = engine.Authorize(user(), RobotsRead, Target{}); !errors.Is(err, ErrDenied) {
+		t.Errorf("no roles: err = %v", err)
+	}
+	// Несколько ролей складываются
+	if err = engine.Authorize(user("unknown", "operator"), RobotsMove, Target{}); err != nil {
+		t.Errorf("unknown+operator: %v", err)
+	}
+}
+
+func TestAnonymousRoles(t *testing.T) {
+	cfg := builtinRBAC(t)
+	cfg.Anonymous = []string{"viewer"}
+	engine, err := NewEngine(cfg, fakeRobots{}, nil, testLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = engine.Authorize(nil, RobotsRead, Target{}); err != nil {
+		t.Fatalf("anonymous read: %v", err)
+	}
+	if err = engine.Authorize(nil, RobotsMove, Target{RobotID: 1}); !errors.Is(err, ErrDenied) {
+		t.Fatalf("anonymous move: err = %v", err)
+	}
+	// У пользователя свои роли, анонимные к ним не добавляются
+	if err = engine.Authorize(user(), RobotsRead, Target{}); !errors.Is(err, ErrDenied) {
+		t.Fatalf("user without roles: err = %v", err)
+	}
+}
+
+func TestScopedRoles(t *testing.T) {
+	cfg := builtinRBAC(t)
+	cfg.Groups = map[string][]int{"dock": {1, 3}}
+	cfg.Roles["drone-operator"] = config.Role{Inherits: []string{"viewer"}, Permissions: []string{"robots:move", "robots:create"}, Types: []string{"drone"}}
+	cfg.Roles["dock-operator"] = config.Role{Permissions: []string{"robots:move"}, Groups: []string{"dock"}}
+	robots := fakeRobots{
+		1: {ID: 1, Type: "drone"},
+		2: {ID: 2, Type: "drone"},
+		3: {ID: 3, Type: "rover"},
+		4: {ID: 4, Type: "rover"},
+	}
+	engine, err := NewEngine(cfg, robots, nil, testLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		role       string
+		permission Permission
+		target     Target
+		allowed    bool
+		wantErr    string
+	}{
+		{name: "drone", role: "drone-operator", permission: RobotsMove, target: Target{RobotID: 2}, allowed: true},
+		{name: "rover", role: "drone-operator", permission: RobotsMove, target: Target{RobotID: 3}},
+		{name: "no target", role: "drone-operator", permission: RobotsMove},
+		{name: "inherited read is scoped too", role: "drone-operator", permission: RobotsRead, target: Target{RobotID: 4}},
+		{name: "create drone", role: "drone-operator", permission: RobotsCreate, target: Target{NewType: "drone"}, allowed: true},
+		{name: "create rover", role: "drone-operator", permission: RobotsCreate, target: Target{NewType: "rover"}},
+		{name: "retype drone to rover", role: "drone-operator", permission: RobotsMove, target: Target{RobotID: 1, NewType: "rover"}},
+		{name: "missing robot", role: "drone-operator", permission: RobotsMove, target: Target{RobotID: 99}},
+		{name: "lookup error", role: "drone-operator", permission: RobotsMove, target: Target{RobotID: -1}, wantErr: "db is down"},
+		{name: "group member", role: "dock-operator", permission: RobotsMove, target: Target{RobotID: 3}, allowed: true},
+		{name: "not in group", role: "dock-operator", permission: RobotsMove, target: Target{RobotID: 2}},
+		{name: "group lacks permission", role: "dock-operator", permission: RobotsDelete, target: Target{RobotID: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := engine.Authorize(user(tt.role), tt.permission, tt.target)
+			switch {
+			case tt.allowed && err != nil:
+				t.Fatalf("err = %v, want nil", err)
+			case tt.wantErr != "":
+				if err == nil || errors.Is(err, ErrDenied) || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+			case !tt.allowed && !errors.Is(err, ErrDenied):
+				t.Fatalf("err = %v, want ErrDenied", err)
+			}
+		})
+	}
+
+	// Роль без ограничений покрывает то, что не покрыла ограниченная
+	if err = engine.Authorize(user("drone-operator", "operator"), RobotsMove, Target{RobotID: 3}); err != nil {
+		t.Fatalf("drone-operator+operator: %v", err)
+	}
+	if !engine.NeedsTarget(user("viewer", "dock-operator")) || engine.NeedsTarget(user("admin")) {
+		t.Fatal("NeedsTarget")
+	}
+}
+
+func TestNewEngineErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.RBAC
+		wantErr string
+	}{
+		{name: "unknown permission", cfg: config.RBAC{Roles: map[string]config.Role{"r": {Permissions: []string{"robots:fly"}}}}, wantErr: "unknown permission"},
+		{name: "unknown group", cfg: config.RBAC{Roles: map[string]config.Role{"r": {Groups: []string{"dock"}}}}, wantErr: "unknown group"},
+		{name: "unknown parent", cfg: config.RBAC{Roles: map[string]config.Role{"r": {Inherits: []string{"root"}}}}, wantErr: "inherits unknown role"},
+		{name: "cycle", cfg: config.RBAC{Roles: map[string]config.Role{"a": {Inherits: []string{"b"}}, "b": {Inherits: []string{"a"}}}}, wantErr: "cycle"},
+		{name: "self inheritance", cfg: config.RBAC{Roles: map[string]config.Role{"a": {Inherits: []string{"a"}}}}, wantErr: "cycle"},
+		{name: "unknown anonymous role", cfg: config.RBAC{Anonymous: []string{"guest"}}, wantErr: "anonymous"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewEngine(tt.cfg, fakeRobots{}, nil, testLogger()); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckRecordsDenied(t *testing.T) {
+	auditor := &fakeAuditor{}
+	engine, err := NewEngine(builtinRBAC(t), fakeRobots{}, auditor, testLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := auth.WithPrincipal(context.Background(), user("viewer"))
+
+	if err = engine.Check(ctx, RobotsRead, Target{RobotID: 5}, "http", "GET /robots/5"); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if err = engine.Check(ctx, RobotsDelete, Target{RobotID: 5}, "http", "DELETE /robots/delete/5"); !errors.Is(err, ErrDenied) {
+		t.Fatalf("delete: err = %v, want ErrDenied", err)
+	}
+	if len(auditor.denied) != 1 || auditor.denied[0] != (deniedRecord{action: string(RobotsDelete), robotID: 5}) {
+		t.Fatalf("denied = %+v", auditor.denied)
+	}
+}
@@ -0,0 +1,75 @@
+package repositories
+
+import (
+	"RobotService/internal/entities"
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+type APIKeyRepository struct {
+	DataBase *pgxpool.Pool
+}
+
+const apiKeyColumns = "id, name, prefix, roles, created_at, last_used_at, revoked_at"
+
+func scanAPIKey(row pgx.Row) (*entities.APIKey, error) {
+	key := &entities.APIKey{}
+	err := row.Scan(&key.ID, &key.Name, &key.Prefix, &key.Roles, &key.CreatedAt, &key.LastUsedAt, &key.RevokedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func (repo *APIKeyRepository) CreateAPIKey(key entities.APIKey, hash string) (*entities.APIKey, error) {
+	if key.Roles == nil {
+		key.Roles = []string{}
+	}
+	query := "INSERT INTO api_keys (name, prefix, key_hash, roles) VALUES($1, $2, $3, $4) RETURNING " + apiKeyColumns
+	return scanAPIKey(repo.DataBase.QueryRow(context.Background(), query, key.Name, key.Prefix, hash, key.Roles))
+}
+
+func (repo *APIKeyRepository) ListAPIKeys() ([]entities.APIKey, error) {
+	rows, err := repo.DataBase.Query(context.Background(), "SELECT "+apiKeyColumns+" FROM api_keys ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []entities.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *key)
+	}
+	return keys, rows.Err()
+}
+
+func (repo *APIKeyRepository) RevokeAPIKey(id int) error {
+	query := "UPDATE api_keys SET revoked_at = now() WHERE id = $1 AND revoked_at IS NULL"
+	tag, err := repo.DataBase.Exec(context.Background(), query, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// Ищем действующий ключ по хэшу и заодно отмечаем, когда им пользовались
+func (repo *APIKeyRepository) AuthenticateAPIKey(hash string) (*entities.APIKey, error) {
+	query := `UPDATE api_keys SET last_used_at = now()
+		WHERE key_hash = $1 AND revoked_at IS NULL RETURNING ` + apiKeyColumns
+	return scanAPIKey(repo.DataBase.QueryRow(context.Background(), query, hash))
+}
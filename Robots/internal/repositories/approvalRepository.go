@@ -0,0 +1,103 @@
+package repositories
+
+import (
+	"RobotService/internal/entities"
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrApprovalNotFound = errors.New("approval not found")
+	// Заявка уже решена или истекла
+	ErrApprovalNotPending = errors.New("approval is not pending")
+)
+
+type ApprovalRepository struct {
+	DataBase *pgxpool.Pool
+}
+
+const approvalColumns = "id, action, robot_id, new_type, status, requested_by, decided_by, comment, error, created_at, expires_at, decided_at"
+
+func scanApproval(row pgx.Row) (*entities.Approval, error) {
+	approval := &entities.Approval{}
+	err := row.Scan(&approval.ID, &approval.Action, &approval.RobotID, &approval.NewType, &approval.Status,
+		&approval.RequestedBy, &approval.DecidedBy, &approval.Comment, &approval.Error,
+		&approval.CreatedAt, &approval.ExpiresAt, &approval.DecidedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrApprovalNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return approval, nil
+}
+
+func (repo *ApprovalRepository) CreateApproval(approval entities.Approval) (*entities.Approval, error) {
+	query := `INSERT INTO approvals (action, robot_id, new_type, requested_by, expires_at)
+		VALUES($1, $2, $3, $4, $5) RETURNING ` + approvalColumns
+	return scanApproval(repo.DataBase.QueryRow(context.Background(), query,
+		approval.Action, approval.RobotID, approval.NewType, approval.RequestedBy, approval.ExpiresAt))
+}
+
+func (repo *ApprovalRepository) GetApproval(id int) (*entities.Approval, error) {
+	return scanApproval(repo.DataBase.QueryRow(context.Background(), "SELECT "+approvalColumns+" FROM approvals WHERE id = $1", id))
+}
+
+// Пустой status - все заявки. Свежие сверху
+func (repo *ApprovalRepository) ListApprovals(status string, limit, offset int) ([]entities.Approval, error) {
+	query := "SELECT " + approvalColumns + " FROM approvals WHERE ($1 = '' OR status = $1) ORDER BY id DESC LIMIT $2 OFFSET $3"
+	rows, err := repo.DataBase.Query(context.Background(), query, status, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	approvals := []entities.Approval{}
+	for rows.Next() {
+		approval, err := scanApproval(rows)
+		if err != nil {
+			return nil, err
+		}
+		approvals = append(approvals, *approval)
+	}
+	return approvals, rows.Err()
+}
+
+// Просроченные заявки помечаем истёкшими при каждом обращении, отдельный фоновый процесс не нужен
+func (repo *ApprovalRepository) ExpireApprovals() error {
+	query := "UPDATE approvals SET status = 'expired', decided_at = expires_at WHERE status = 'pending' AND expires_at <= now()"
+	_, err := repo.DataBase.Exec(context.Background(), query)
+	return err
+}
+
+// Решение по заявке. Проверка статуса и срока в одном UPDATE, так что два одновременных решения не пройдут
+func (repo *ApprovalRepository) DecideApproval(id int, status, decidedBy, comment string) (*entities.Approval, error) {
+	query := `UPDATE approvals SET status = $2, decided_by = $3, comment = $4, decided_at = now()
+		WHERE id = $1 AND status = 'pending' AND expires_at > now() RETURNING ` + approvalColumns
+	approval, err := scanApproval(repo.DataBase.QueryRow(context.Background(), query, id, status, decidedBy, comment))
+	if errors.Is(err, ErrApprovalNotFound) {
+		if _, getErr := repo.GetApproval(id); getErr != nil {
+			return nil, getErr
+		}
+		return nil, ErrApprovalNotPending
+	}
+	return approval, err
+}
+
+// Одобренное изменение не применилось
+func (repo *ApprovalRepository) FailApproval(id int, reason string) error {
+	query := "UPDATE approvals SET status = 'failed', error = $2 WHERE id = $1 AND status = 'approved'"
+	_, err := repo.DataBase.Exec(context.Background(), query, id, reason)
+	return err
+}
+
+// Открытая заявка на то же действие с тем же роботом, чтобы не плодить дубли
+func (repo *ApprovalRepository) FindPendingApproval(action string, robotID int, newType string) (*entities.Approval, error) {
+	query := `SELECT ` + approvalColumns + ` FROM approvals
+		WHERE status = 'pending' AND action = $1 AND robot_id = $2 AND new_type = $3 AND expires_at > now()
+		ORDER BY id DESC LIMIT 1`
+	return scanApproval(repo.DataBase.QueryRow(context.Background(), query, action, robotID, newType))
+}
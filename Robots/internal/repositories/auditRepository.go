@@ -0,0 +1,127 @@
+package repositories
+
+import (
+	"RobotService/internal/entities"
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Журнал аудита. Только добавление и чтение, изменять записи не даёт триггер в базе
+type AuditRepository struct {
+	DataBase *pgxpool.Pool
+}
+
+const auditColumns = "id, created_at, actor, source_ip, request_id, action, robot_id, before, after, result, error, details"
+
+func (repo *AuditRepository) AppendAudit(record entities.AuditRecord) error {
+	before, err := robotJSON(record.Before)
+	if err != nil {
+		return err
+	}
+	after, err := robotJSON(record.After)
+	if err != nil {
+		return err
+	}
+	query := `INSERT INTO audit_log (actor, source_ip, request_id, action, robot_id, before, after, result, error, details)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+	_, err = repo.DataBase.Exec(context.Background(), query, record.Actor, record.SourceIP, record.RequestID,
+		record.Action, record.RobotID, before, after, record.Result, record.Error, record.Details)
+	return err
+}
+
+// Страница записей по фильтру, свежие сверху
+func (repo *AuditRepository) ListAudit(filter entities.AuditFilter, limit, offset int) ([]entities.AuditRecord, error) {
+	where, args := auditWhere(filter)
+	args = append(args, limit, offset)
+	query := "SELECT " + auditColumns + " FROM audit_log" + where +
+		" ORDER BY id DESC LIMIT $" + strconv.Itoa(len(args)-1) + " OFFSET $" + strconv.Itoa(len(args))
+
+	records := []entities.AuditRecord{}
+	err := repo.queryAudit(query, args, func(record entities.AuditRecord) error {
+		records = append(records, record)
+		return nil
+	})
+	return records, err
+}
+
+// Все записи по фильтру в порядке появления, для выгрузки
+func (repo *AuditRepository) ForEachAudit(filter entities.AuditFilter, fn func(entities.AuditRecord) error) error {
+	where, args := auditWhere(filter)
+	return repo.queryAudit("SELECT "+auditColumns+" FROM audit_log"+where+" ORDER BY id", args, fn)
+}
+
+func (repo *AuditRepository) queryAudit(query string, args []any, fn func(entities.AuditRecord) error) error {
+	rows, err := repo.DataBase.Query(context.Background(), query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var record entities.AuditRecord
+		var before, after []byte
+		err = rows.Scan(&record.ID, &record.Time, &record.Actor, &record.SourceIP, &record.RequestID, &record.Action,
+			&record.RobotID, &before, &after, &record.Result, &record.Error, &record.Details)
+		if err != nil {
+			return err
+		}
+		if record.Before, err = parseRobotJSON(before); err != nil {
+			return err
+		}
+		if record.After, err = parseRobotJSON(after); err != nil {
+			return err
+		}
+		if err = fn(record); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func auditWhere(filter entities.AuditFilter) (string, []any) {
+	var conditions []string
+	var args []any
+	add := func(condition string, value any) {
+		args = append(args, value)
+		conditions = append(conditions, condition+" $"+strconv.Itoa(len(args)))
+	}
+	if filter.RobotID != nil {
+		add("robot_id =", *filter.RobotID)
+	}
+	if filter.Actor != "" {
+		add("actor =", filter.Actor)
+	}
+	if filter.From != nil {
+		add("created_at >=", *filter.From)
+	}
+	if filter.To != nil {
+		add("created_at <", *filter.To)
+	}
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// nil робот пишем как NULL
+func robotJSON(robot *entities.Robot) ([]byte, error) {
+	if robot == nil {
+		return nil, nil
+	}
+	return json.Marshal(robot)
+}
+
+func parseRobotJSON(data []byte) (*entities.Robot, error) {
+	if data == nil {
+		return nil, nil
+	}
+	robot := &entities.Robot{}
+	if err := json.Unmarshal(data, robot); err != nil {
+		return nil, err
+	}
+	return robot, nil
+}
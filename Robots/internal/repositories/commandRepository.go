@@ -0,0 +1,146 @@
+package repositories
+
+import (
+	"RobotService/internal/entities"
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrCommandNotFound = errors.New("command not found")
+	// У робота уже есть выполняемая команда
+	ErrRobotBusy = errors.New("robot already has a running command")
+	// Команда уже завершена, отменена или провалилась
+	ErrCommandFinished = errors.New("command is already finished")
+)
+
+type CommandRepository struct {
+	DataBase *pgxpool.Pool
+}
+
+const commandColumns = "id, robot_id, status, target, waypoints, length, traveled, position, blocked, error, requested_by, created_at, updated_at, finished_at"
+
+func scanCommand(row pgx.Row) (*entities.MoveCommand, error) {
+	command := &entities.MoveCommand{}
+	var target, waypoints, position []byte
+	err := row.Scan(&command.ID, &command.RobotID, &command.Status, &target, &waypoints, &command.Length, &command.Traveled,
+		&position, &command.Blocked, &command.Error, &command.RequestedBy, &command.CreatedAt, &command.UpdatedAt, &command.FinishedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrCommandNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, field := range []struct {
+		data []byte
+		dest any
+	}{{target, &command.Target}, {waypoints, &command.Waypoints}, {position, &command.Position}} {
+		if err = json.Unmarshal(field.data, field.dest); err != nil {
+			return nil, err
+		}
+	}
+	if command.Length > 0 {
+		command.Progress = command.Traveled / command.Length
+	} else if command.Status == entities.CommandCompleted {
+		command.Progress = 1
+	}
+	return command, nil
+}
+
+func (repo *CommandRepository) CreateCommand(command entities.MoveCommand) (*entities.MoveCommand, error) {
+	target, _ := json.Marshal(command.Target)
+	waypoints, _ := json.Marshal(command.Waypoints)
+	position, _ := json.Marshal(command.Position)
+	query := `INSERT INTO move_commands (robot_id, status, target, waypoints, length, position, requested_by)
+		VALUES($1, $2, $3, $4, $5, $6, $7) RETURNING ` + commandColumns
+	created, err := scanCommand(repo.DataBase.QueryRow(context.Background(), query,
+		command.RobotID, command.Status, target, waypoints, command.Length, position, command.RequestedBy))
+	switch pgErrorCode(err) {
+	case pgUniqueViolation:
+		return nil, ErrRobotBusy
+	case pgForeignKeyViolation:
+		return nil, ErrRobotNotFound
+	}
+	return created, err
+}
+
+func (repo *CommandRepository) GetCommand(id int) (*entities.MoveCommand, error) {
+	return scanCommand(repo.DataBase.QueryRow(context.Background(), "SELECT "+commandColumns+" FROM move_commands WHERE id = $1", id))
+}
+
+// robotID = 0 и пустой status - без фильтра. Свежие сверху
+func (repo *CommandRepository) ListCommands(robotID int, status string, limit, offset int) ([]entities.MoveCommand, error) {
+	query := "SELECT " + commandColumns + ` FROM move_commands WHERE ($1 = 0 OR robot_id = $1) AND ($2 = '' OR status = $2)
+		ORDER BY id DESC LIMIT $3 OFFSET $4`
+	rows, err := repo.DataBase.Query(context.Background(), query, robotID, status, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	commands := []entities.MoveCommand{}
+	for rows.Next() {
+		command, err := scanCommand(rows)
+		if err != nil {
+			return nil, err
+		}
+		commands = append(commands, *command)
+	}
+	return commands, rows.Err()
+}
+
+// Выполняемые команды по порядку создания, для движка при старте
+func (repo *CommandRepository) RunningCommands() ([]entities.MoveCommand, error) {
+	rows, err := repo.DataBase.Query(context.Background(), "SELECT "+commandColumns+" FROM move_commands WHERE status = 'running' ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	commands := []entities.MoveCommand{}
+	for rows.Next() {
+		command, err := scanCommand(rows)
+		if err != nil {
+			return nil, err
+		}
+		commands = append(commands, *command)
+	}
+	return commands, rows.Err()
+}
+
+// Сохраняем ход выполнения и маршрут (его могли построить заново). Завершённую команду не трогаем,
+// так что отмена не перетрётся шагом движка
+func (repo *CommandRepository) UpdateCommand(command entities.MoveCommand) (*entities.MoveCommand, error) {
+	position, _ := json.Marshal(command.Position)
+	waypoints, _ := json.Marshal(command.Waypoints)
+	query := `UPDATE move_commands SET status = $2, traveled = $3, position = $4, blocked = $5, error = $6,
+		waypoints = $7, length = $8, updated_at = now(), finished_at = CASE WHEN $2 = 'running' THEN NULL ELSE now() END
+		WHERE id = $1 AND status = 'running' RETURNING ` + commandColumns
+	updated, err := scanCommand(repo.DataBase.QueryRow(context.Background(), query,
+		command.ID, command.Status, command.Traveled, position, command.Blocked, command.Error, waypoints, command.Length))
+	if errors.Is(err, ErrCommandNotFound) {
+		if _, getErr := repo.GetCommand(command.ID); getErr != nil {
+			return nil, getErr
+		}
+		return nil, ErrCommandFinished
+	}
+	return updated, err
+}
+
+// Отмена меняет только статус: ход команды, сохранённый движком, остаётся как есть
+func (repo *CommandRepository) CancelCommand(id int) (*entities.MoveCommand, error) {
+	query := `UPDATE move_commands SET status = 'cancelled', blocked = '', updated_at = now(), finished_at = now()
+		WHERE id = $1 AND status = 'running' RETURNING ` + commandColumns
+	cancelled, err := scanCommand(repo.DataBase.QueryRow(context.Background(), query, id))
+	if errors.Is(err, ErrCommandNotFound) {
+		if _, getErr := repo.GetCommand(id); getErr != nil {
+			return nil, getErr
+		}
+		return nil, ErrCommandFinished
+	}
+	return cancelled, err
+}
@@ -0,0 +1,256 @@
+package repositories
+
+import (
+	"RobotService/internal/entities"
+	"RobotService/internal/spatial"
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrFleetNotFound       = errors.New("fleet not found")
+	ErrFleetExists         = errors.New("fleet already exists")
+	ErrFleetParentNotFound = errors.New("parent fleet not found")
+	// Флот нельзя вложить в самого себя или в своего потомка
+	ErrFleetCycle = errors.New("fleet cannot be nested into itself")
+	// Сначала нужно удалить или вынести вложенные флоты
+	ErrFleetHasChildren = errors.New("fleet has nested fleets")
+	ErrNotFleetMember   = errors.New("robot is not a member of the fleet")
+)
+
+type FleetRepository struct {
+	DataBase *pgxpool.Pool
+	// Тот же индекс позиций, что у репозитория роботов: групповые команды тоже двигают и удаляют роботов
+	Index *spatial.Index
+}
+
+// Прямые участники собираются подзапросом, чтобы колонки годились и для RETURNING
+const fleetColumns = "id, name, description, parent_id, created_at, updated_at, " +
+	"COALESCE((SELECT array_agg(robot_id ORDER BY robot_id) FROM fleet_members WHERE fleet_id = fleets.id), '{}')"
+
+// Флот $1 и все вложенные в него. UNION, а не UNION ALL, чтобы не зациклиться, если цикл всё же есть
+const fleetTree = `WITH RECURSIVE tree AS (
+	SELECT id FROM fleets WHERE id = $1
+	UNION
+	SELECT fleets.id FROM fleets JOIN tree ON fleets.parent_id = tree.id
+) `
+
+func scanFleet(row pgx.Row) (*entities.Fleet, error) {
+	fleet := &entities.Fleet{}
+	err := row.Scan(&fleet.ID, &fleet.Name, &fleet.Description, &fleet.ParentID, &fleet.CreatedAt, &fleet.UpdatedAt, &fleet.RobotIDs)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrFleetNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return fleet, nil
+}
+
+func (repo *FleetRepository) CreateFleet(fleet entities.Fleet) (*entities.Fleet, error) {
+	query := "INSERT INTO fleets (name, description, parent_id) VALUES($1, $2, $3) RETURNING " + fleetColumns
+	created, err := scanFleet(repo.DataBase.QueryRow(context.Background(), query, fleet.Name, fleet.Description, fleet.ParentID))
+	switch pgErrorCode(err) {
+	case pgUniqueViolation:
+		return nil, ErrFleetExists
+	case pgForeignKeyViolation:
+		return nil, ErrFleetParentNotFound
+	}
+	return created, err
+}
+
+func (repo *FleetRepository) GetFleet(id int) (*entities.Fleet, error) {
+	query := "SELECT " + fleetColumns + " FROM fleets WHERE id = $1"
+	return scanFleet(repo.DataBase.QueryRow(context.Background(), query, id))
+}
+
+func (repo *FleetRepository) ListFleets() ([]entities.Fleet, error) {
+	rows, err := repo.DataBase.Query(context.Background(), "SELECT "+fleetColumns+" FROM fleets ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	fleets := []entities.Fleet{}
+	for rows.Next() {
+		fleet, err := scanFleet(rows)
+		if err != nil {
+			return nil, err
+		}
+		fleets = append(fleets, *fleet)
+	}
+	return fleets, rows.Err()
+}
+
+// Меняем имя, описание и родителя. Таблицу флотов блокируем на запись,
+// чтобы два параллельных переноса не собрали цикл в обход проверки.
+// Begin у пула берёт отдельное соединение на всю транзакцию, чужие запросы в неё не попадут
+func (repo *FleetRepository) UpdateFleet(fleet entities.Fleet) (*entities.Fleet, error) {
+	ctx := context.Background()
+	tx, err := repo.DataBase.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err = tx.Exec(ctx, "LOCK TABLE fleets IN SHARE ROW EXCLUSIVE MODE"); err != nil {
+		return nil, err
+	}
+	if fleet.ParentID != nil {
+		var cycle bool
+		err = tx.QueryRow(ctx, fleetTree+"SELECT EXISTS (SELECT 1 FROM tree WHERE id = $2)", fleet.ID, *fleet.ParentID).Scan(&cycle)
+		if err != nil {
+			return nil, err
+		}
+		if cycle {
+			return nil, ErrFleetCycle
+		}
+	}
+
+	query := "UPDATE fleets SET name = $2, description = $3, parent_id = $4, updated_at = now() WHERE id = $1 RETURNING " + fleetColumns
+	updated, err := scanFleet(tx.QueryRow(ctx, query, fleet.ID, fleet.Name, fleet.Description, fleet.ParentID))
+	switch pgErrorCode(err) {
+	case pgUniqueViolation:
+		return nil, ErrFleetExists
+	case pgForeignKeyViolation:
+		return nil, ErrFleetParentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return updated, tx.Commit(ctx)
+}
+
+func (repo *FleetRepository) DeleteFleet(id int) error {
+	tag, err := repo.DataBase.Exec(context.Background(), "DELETE FROM fleets WHERE id = $1", id)
+	if pgErrorCode(err) == pgForeignKeyViolation {
+		return ErrFleetHasChildren
+	}
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrFleetNotFound
+	}
+	return nil
+}
+
+// Уже состоящих роботов пропускаем. Несуществующий робот роняет всю пачку
+func (repo *FleetRepository) AddMembers(fleetID int, robotIDs []int) error {
+	query := "INSERT INTO fleet_members (fleet_id, robot_id) SELECT $1, unnest($2::int[]) ON CONFLICT DO NOTHING"
+	_, err := repo.DataBase.Exec(context.Background(), query, fleetID, robotIDs)
+	if pgErrorCode(err) == pgForeignKeyViolation {
+		return ErrRobotNotFound
+	}
+	return err
+}
+
+func (repo *FleetRepository) RemoveMember(fleetID, robotID int) error {
+	query := "DELETE FROM fleet_members WHERE fleet_id = $1 AND robot_id = $2"
+	tag, err := repo.DataBase.Exec(context.Background(), query, fleetID, robotID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFleetMember
+	}
+	return nil
+}
+
+// Роботы флота. recursive - вместе с роботами вложенных флотов
+func (repo *FleetRepository) FleetRobots(fleetID int, recursive bool) ([]entities.Robot, error) {
+	query := "SELECT " + robotColumns + " FROM robots WHERE id IN (SELECT robot_id FROM fleet_members WHERE fleet_id = $1) ORDER BY id"
+	if recursive {
+		query = fleetTree + "SELECT " + robotColumns + " FROM robots WHERE id IN " +
+			"(SELECT robot_id FROM fleet_members WHERE fleet_id IN (SELECT id FROM tree)) ORDER BY id"
+	}
+	rows, err := repo.DataBase.Query(context.Background(), query, fleetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	robots := []entities.Robot{}
+	for rows.Next() {
+		robot, err := scanRobot(rows)
+		if err != nil {
+			return nil, err
+		}
+		robots = append(robots, *robot)
+	}
+	return robots, rows.Err()
+}
+
+// Групповая команда одной транзакцией: блокируем роботов флота со всеми вложенными,
+// change считает новое состояние каждого (nil - удалить). Любая ошибка change откатывает всё.
+// Возвращаем состояния до и после, after[i] == nil у удалённых.
+// Транзакция держит своё соединение из пула, запросы change идут через другие
+func (repo *FleetRepository) ApplyToMembers(fleetID int, change func(entities.Robot) (*entities.Robot, error)) ([]entities.Robot, []*entities.Robot, error) {
+	ctx := context.Background()
+	tx, err := repo.DataBase.Begin(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var exists bool
+	if err = tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM fleets WHERE id = $1)", fleetID).Scan(&exists); err != nil {
+		return nil, nil, err
+	}
+	if !exists {
+		return nil, nil, ErrFleetNotFound
+	}
+
+	query := fleetTree + "SELECT " + robotColumns + " FROM robots WHERE id IN " +
+		"(SELECT robot_id FROM fleet_members WHERE fleet_id IN (SELECT id FROM tree)) ORDER BY id FOR UPDATE"
+	rows, err := tx.Query(ctx, query, fleetID)
+	if err != nil {
+		return nil, nil, err
+	}
+	var before []entities.Robot
+	for rows.Next() {
+		robot, err := scanRobot(rows)
+		if err != nil {
+			rows.Close()
+			return nil, nil, err
+		}
+		before = append(before, *robot)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	after := make([]*entities.Robot, len(before))
+	batch := &pgx.Batch{}
+	for i, robot := range before {
+		if after[i], err = change(robot); err != nil {
+			return nil, nil, err
+		}
+		if after[i] == nil {
+			batch.Queue("DELETE FROM robots WHERE id = $1", robot.ID)
+			continue
+		}
+		batch.Queue("UPDATE robots SET name = $2, type = $3, xcord = $4, ycord = $5, zcord = $6 WHERE id = $1",
+			robot.ID, after[i].Name, after[i].Type, after[i].XCord, after[i].YCord, after[i].ZCord)
+	}
+	if batch.Len() > 0 {
+		if err = tx.SendBatch(ctx, batch).Close(); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return nil, nil, err
+	}
+	for i := range before {
+		if after[i] == nil {
+			repo.Index.Remove(before[i].ID)
+		} else {
+			repo.Index.Upsert(before[i].ID, after[i].Cord())
+		}
+	}
+	return before, after, nil
+}
@@ -0,0 +1,132 @@
+package repositories
+
+import (
+	"RobotService/internal/entities"
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrMissionNotFound = errors.New("mission not found")
+	// У робота уже есть активная или приостановленная миссия
+	ErrRobotOnMission = errors.New("robot already has an active mission")
+)
+
+type MissionRepository struct {
+	DataBase *pgxpool.Pool
+}
+
+const missionColumns = "id, name, robot_id, status, steps, current_step, payload, error, created_by, created_at, updated_at, started_at, finished_at"
+
+func scanMission(row pgx.Row) (*entities.Mission, error) {
+	mission := &entities.Mission{}
+	var steps []byte
+	err := row.Scan(&mission.ID, &mission.Name, &mission.RobotID, &mission.Status, &steps, &mission.CurrentStep, &mission.Payload,
+		&mission.Error, &mission.CreatedBy, &mission.CreatedAt, &mission.UpdatedAt, &mission.StartedAt, &mission.FinishedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrMissionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(steps, &mission.Steps); err != nil {
+		return nil, err
+	}
+	if len(mission.Steps) > 0 {
+		mission.Progress = float64(mission.CurrentStep) / float64(len(mission.Steps))
+	}
+	return mission, nil
+}
+
+func (repo *MissionRepository) CreateMission(mission entities.Mission) (*entities.Mission, error) {
+	steps, _ := json.Marshal(mission.Steps)
+	query := `INSERT INTO missions (name, robot_id, status, steps, created_by) VALUES($1, $2, $3, $4, $5) RETURNING ` + missionColumns
+	created, err := scanMission(repo.DataBase.QueryRow(context.Background(), query,
+		mission.Name, mission.RobotID, mission.Status, steps, mission.CreatedBy))
+	if pgErrorCode(err) == pgForeignKeyViolation {
+		return nil, ErrRobotNotFound
+	}
+	return created, err
+}
+
+func (repo *MissionRepository) GetMission(id int) (*entities.Mission, error) {
+	return scanMission(repo.DataBase.QueryRow(context.Background(), "SELECT "+missionColumns+" FROM missions WHERE id = $1", id))
+}
+
+// robotID = 0 и пустой status - без фильтра. Свежие сверху
+func (repo *MissionRepository) ListMissions(robotID int, status string, limit, offset int) ([]entities.Mission, error) {
+	query := "SELECT " + missionColumns + ` FROM missions WHERE ($1 = 0 OR robot_id = $1) AND ($2 = '' OR status = $2)
+		ORDER BY id DESC LIMIT $3 OFFSET $4`
+	return repo.queryMissions(query, robotID, status, limit, offset)
+}
+
+// Что исполнитель должен вести: активные и назначенные миссии в очереди, старые первыми
+func (repo *MissionRepository) PendingMissions() ([]entities.Mission, error) {
+	query := "SELECT " + missionColumns + ` FROM missions
+		WHERE status = 'active' OR (status = 'queued' AND robot_id IS NOT NULL) ORDER BY id`
+	return repo.queryMissions(query)
+}
+
+// Миссии в очереди без робота, старые первыми - работа для распределителя
+func (repo *MissionRepository) UnassignedMissions(limit int) ([]entities.Mission, error) {
+	query := "SELECT " + missionColumns + ` FROM missions WHERE status = 'queued' AND robot_id IS NULL ORDER BY id LIMIT $1`
+	return repo.queryMissions(query, limit)
+}
+
+// Роботы, у которых есть назначенная, активная или приостановленная миссия
+func (repo *MissionRepository) BusyRobots() (map[int]bool, error) {
+	rows, err := repo.DataBase.Query(context.Background(),
+		`SELECT DISTINCT robot_id FROM missions WHERE robot_id IS NOT NULL AND status IN ('queued', 'active', 'paused')`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	busy := map[int]bool{}
+	for rows.Next() {
+		var robotID int
+		if err = rows.Scan(&robotID); err != nil {
+			return nil, err
+		}
+		busy[robotID] = true
+	}
+	return busy, rows.Err()
+}
+
+func (repo *MissionRepository) queryMissions(query string, args ...any) ([]entities.Mission, error) {
+	rows, err := repo.DataBase.Query(context.Background(), query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	missions := []entities.Mission{}
+	for rows.Next() {
+		mission, err := scanMission(rows)
+		if err != nil {
+			return nil, err
+		}
+		missions = append(missions, *mission)
+	}
+	return missions, rows.Err()
+}
+
+// Сохраняем состояние миссии целиком: переходы идут через сервис под его блокировкой
+func (repo *MissionRepository) UpdateMission(mission entities.Mission) (*entities.Mission, error) {
+	steps, _ := json.Marshal(mission.Steps)
+	query := `UPDATE missions SET robot_id = $2, status = $3, steps = $4, current_step = $5, payload = $6, error = $7,
+		started_at = $8, finished_at = $9, updated_at = now() WHERE id = $1 RETURNING ` + missionColumns
+	updated, err := scanMission(repo.DataBase.QueryRow(context.Background(), query, mission.ID, mission.RobotID, mission.Status,
+		steps, mission.CurrentStep, mission.Payload, mission.Error, mission.StartedAt, mission.FinishedAt))
+	switch pgErrorCode(err) {
+	case pgUniqueViolation:
+		return nil, ErrRobotOnMission
+	case pgForeignKeyViolation:
+		return nil, ErrRobotNotFound
+	}
+	return updated, err
+}
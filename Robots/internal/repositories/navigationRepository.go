@@ -0,0 +1,60 @@
+package repositories
+
+import (
+	"RobotService/internal/entities"
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrNoActivePath = errors.New("robot has no active path")
+
+// Активные маршруты роботов, по одному на робота
+type NavigationRepository struct {
+	DataBase *pgxpool.Pool
+}
+
+// Новый маршрут заменяет прежний
+func (repo *NavigationRepository) SavePath(plan entities.NavigationPlan) error {
+	data, err := json.Marshal(plan)
+	if err != nil {
+		return err
+	}
+	query := `INSERT INTO robot_paths (robot_id, plan) VALUES($1, $2)
+		ON CONFLICT (robot_id) DO UPDATE SET plan = $2, created_at = now()`
+	_, err = repo.DataBase.Exec(context.Background(), query, plan.RobotID, data)
+	if pgErrorCode(err) == pgForeignKeyViolation {
+		return ErrRobotNotFound
+	}
+	return err
+}
+
+func (repo *NavigationRepository) GetPath(robotID int) (*entities.NavigationPlan, error) {
+	var data []byte
+	err := repo.DataBase.QueryRow(context.Background(), "SELECT plan FROM robot_paths WHERE robot_id = $1", robotID).Scan(&data)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNoActivePath
+	}
+	if err != nil {
+		return nil, err
+	}
+	plan := &entities.NavigationPlan{}
+	if err = json.Unmarshal(data, plan); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+func (repo *NavigationRepository) DeletePath(robotID int) error {
+	tag, err := repo.DataBase.Exec(context.Background(), "DELETE FROM robot_paths WHERE robot_id = $1", robotID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNoActivePath
+	}
+	return nil
+}
@@ -2,66 +2,398 @@ package repositories
 
 import (
 	"RobotService/internal/entities"
+	"RobotService/internal/spatial"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+var ErrRobotNotFound = errors.New("robot not found")
+
 type RobotRepositories struct {
-	DataBase *pgx.Conn
+	DataBase *pgxpool.Pool
+	// Индекс позиций в памяти, его обновляют все записи через репозиторий.
+	// Изменения из других экземпляров сервиса он не видит до перезапуска
+	Index *spatial.Index
+}
+
+const robotColumns = "id, name, type, xcord, ycord, zcord, attributes, labels"
+
+func scanRobot(row pgx.Row) (*entities.Robot, error) {
+	robot := &entities.Robot{}
+	var attributes, labels []byte
+	err := row.Scan(&robot.ID, &robot.Name, &robot.Type, &robot.XCord, &robot.YCord, &robot.ZCord, &attributes, &labels)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrRobotNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(attributes, &robot.Attributes); err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(labels, &robot.Labels); err != nil {
+		return nil, err
+	}
+	return robot, nil
+}
+
+// Атрибуты в базе всегда объект, пустой - {}
+func attributesJSON(attributes map[string]any) ([]byte, error) {
+	if attributes == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(attributes)
+}
+
+func labelsJSON(labels map[string]string) ([]byte, error) {
+	if labels == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(labels)
 }
 
 func (repo *RobotRepositories) CreateRobot(robot entities.Robot) (entities.Robot, error) {
-	query := "INSERT INTO robots (name, type, xcord, ycord, zcord) VALUES($1, $2, $3, $4, $5) returning id"
-	err := repo.DataBase.QueryRow(context.Background(), query, robot.Name, robot.Type, robot.XCord, robot.YCord, robot.ZCord).Scan(&robot.ID)
+	attributes, err := attributesJSON(robot.Attributes)
+	if err != nil {
+		return robot, err
+	}
+	labels, err := labelsJSON(robot.Labels)
 	if err != nil {
 		return robot, err
 	}
+	query := "INSERT INTO robots (name, type, xcord, ycord, zcord, attributes, labels) VALUES($1, $2, $3, $4, $5, $6, $7) returning id"
+	err = repo.DataBase.QueryRow(context.Background(), query, robot.Name, robot.Type, robot.XCord, robot.YCord, robot.ZCord, attributes, labels).Scan(&robot.ID)
+	if err != nil {
+		return robot, err
+	}
+	repo.Index.Upsert(robot.ID, robot.Cord())
 	return robot, nil
 }
 
 func (repo *RobotRepositories) GetRobotInfo(id int) (*entities.Robot, error) {
-	robot := &entities.Robot{ID: id}
-	query := "SELECT name, type, xcord, ycord, zcord FROM robots WHERE id = $1"
-	err := repo.DataBase.QueryRow(context.Background(), query, id).Scan(&robot.Name, &robot.Type, &robot.XCord, &robot.YCord, &robot.ZCord)
+	query := "SELECT " + robotColumns + " FROM robots WHERE id = $1"
+	return scanRobot(repo.DataBase.QueryRow(context.Background(), query, id))
+}
+
+// Постраничный список роботов по фильтру
+func (repo *RobotRepositories) ListRobots(filter entities.RobotFilter, limit, offset int) ([]entities.Robot, error) {
+	where, args, err := robotFilterSQL(filter)
 	if err != nil {
 		return nil, err
 	}
-	return robot, nil
+	args = append(args, limit, offset)
+	query := "SELECT " + robotColumns + " FROM robots WHERE " + where +
+		" ORDER BY id LIMIT $" + strconv.Itoa(len(args)-1) + " OFFSET $" + strconv.Itoa(len(args))
+	rows, err := repo.DataBase.Query(context.Background(), query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	robots := []entities.Robot{}
+	for rows.Next() {
+		robot, err := scanRobot(rows)
+		if err != nil {
+			return nil, err
+		}
+		robots = append(robots, *robot)
+	}
+	return robots, rows.Err()
+}
+
+// Условие WHERE и его параметры. Равенство атрибутов и меток идёт через @>, чтобы работал GIN индекс
+func robotFilterSQL(filter entities.RobotFilter) (string, []any, error) {
+	conds := []string{"TRUE"}
+	var args []any
+	arg := func(value any) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	if filter.Type != "" {
+		conds = append(conds, "type = "+arg(filter.Type))
+	}
+	for _, selector := range filter.Attributes {
+		path := arg(selector.Path)
+		switch selector.Op {
+		case entities.SelectorExists:
+			conds = append(conds, "attributes #> "+path+"::text[] IS NOT NULL")
+		case entities.SelectorEqual, entities.SelectorNotEqual:
+			// {"a": {"b": value}} для пути a.b
+			var doc any = selector.Value
+			for i := len(selector.Path) - 1; i >= 0; i-- {
+				doc = map[string]any{selector.Path[i]: doc}
+			}
+			data, err := json.Marshal(doc)
+			if err != nil {
+				return "", nil, err
+			}
+			cond := "attributes @> " + arg(data) + "::jsonb"
+			if selector.Op == entities.SelectorNotEqual {
+				cond = "NOT " + cond
+			}
+			conds = append(conds, cond)
+		case entities.SelectorGreater, entities.SelectorGreaterE, entities.SelectorLess, entities.SelectorLessE:
+			// Сравниваем только числа, CASE не даёт постгресу привести к numeric строку
+			conds = append(conds, fmt.Sprintf("CASE WHEN jsonb_typeof(attributes #> %[1]s::text[]) = 'number' "+
+				"THEN (attributes #>> %[1]s::text[])::numeric %[2]s %[3]s::numeric ELSE FALSE END", path, selector.Op, arg(selector.Value)))
+		default:
+			return "", nil, fmt.Errorf("unknown selector operation %q", selector.Op)
+		}
+	}
+	for _, selector := range filter.Labels {
+		cond, err := labelSelectorSQL(selector, arg)
+		if err != nil {
+			return "", nil, err
+		}
+		conds = append(conds, cond)
+	}
+	return strings.Join(conds, " AND "), args, nil
+}
+
+// in раскрываем в OR из @>, с ANY индекс бы не работал
+func labelSelectorSQL(selector entities.LabelSelector, arg func(any) string) (string, error) {
+	switch selector.Op {
+	case entities.LabelExists:
+		return "labels ? " + arg(selector.Key), nil
+	case entities.LabelNotExists:
+		return "NOT labels ? " + arg(selector.Key), nil
+	}
+
+	matches := make([]string, 0, len(selector.Values))
+	for _, value := range selector.Values {
+		data, err := json.Marshal(map[string]string{selector.Key: value})
+		if err != nil {
+			return "", err
+		}
+		matches = append(matches, "labels @> "+arg(data)+"::jsonb")
+	}
+	cond := "(" + strings.Join(matches, " OR ") + ")"
+	switch selector.Op {
+	case entities.LabelEquals, entities.LabelIn:
+		return cond, nil
+	case entities.LabelNotEquals, entities.LabelNotIn:
+		return "NOT " + cond, nil
+	}
+	return "", fmt.Errorf("unknown label selector operation %q", selector.Op)
 }
 
 func (repo *RobotRepositories) UpdateRobotCords(id int, newCords entities.RobotCord) error {
 	query := "UPDATE robots SET xcord = $1, ycord = $2, zcord = $3 WHERE id = $4"
-	_, err := repo.DataBase.Exec(context.Background(), query, newCords.XCord, newCords.YCord, newCords.ZCord, id)
+	tag, err := repo.DataBase.Exec(context.Background(), query, newCords.XCord, newCords.YCord, newCords.ZCord, id)
 	if err != nil {
 		return err
 	}
+	if tag.RowsAffected() == 0 {
+		return ErrRobotNotFound
+	}
+	repo.Index.Upsert(id, newCords)
 	return nil
 }
 
 func (repo *RobotRepositories) UpdateRobotName(id int, newName string) error {
 	query := "UPDATE robots SET name = $1 WHERE id = $2"
-	_, err := repo.DataBase.Exec(context.Background(), query, newName, id)
+	tag, err := repo.DataBase.Exec(context.Background(), query, newName, id)
 	if err != nil {
 		return err
 	}
+	if tag.RowsAffected() == 0 {
+		return ErrRobotNotFound
+	}
 	return nil
 }
 
 func (repo *RobotRepositories) ChangeRobotType(id int, newType string) error {
 	query := "UPDATE robots SET type = $1 WHERE id = $2"
-	_, err := repo.DataBase.Exec(context.Background(), query, newType, id)
+	tag, err := repo.DataBase.Exec(context.Background(), query, newType, id)
 	if err != nil {
 		return err
 	}
+	if tag.RowsAffected() == 0 {
+		return ErrRobotNotFound
+	}
 	return nil
 }
 
 func (repo *RobotRepositories) DeleteRobot(id int) error {
 	query := "DELETE FROM robots WHERE id = $1"
-	_, err := repo.DataBase.Exec(context.Background(), query, id)
+	tag, err := repo.DataBase.Exec(context.Background(), query, id)
 	if err != nil {
 		return err
 	}
+	if tag.RowsAffected() == 0 {
+		return ErrRobotNotFound
+	}
+	repo.Index.Remove(id)
 	return nil
 }
+
+// Проходимся по роботам под фильтр по порядку, не загружая всю таблицу в память
+func (repo *RobotRepositories) ForEachRobot(filter entities.RobotFilter, fn func(robot entities.Robot) error) error {
+	where, args, err := robotFilterSQL(filter)
+	if err != nil {
+		return err
+	}
+	query := "SELECT " + robotColumns + " FROM robots WHERE " + where + " ORDER BY id"
+	rows, err := repo.DataBase.Query(context.Background(), query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		robot, err := scanRobot(rows)
+		if err != nil {
+			return err
+		}
+		if err = fn(*robot); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (repo *RobotRepositories) UpdateRobotAttributes(id int, attributes map[string]any) error {
+	data, err := attributesJSON(attributes)
+	if err != nil {
+		return err
+	}
+	tag, err := repo.DataBase.Exec(context.Background(), "UPDATE robots SET attributes = $1 WHERE id = $2", data, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrRobotNotFound
+	}
+	return nil
+}
+
+func (repo *RobotRepositories) UpdateRobotLabels(id int, labels map[string]string) error {
+	data, err := labelsJSON(labels)
+	if err != nil {
+		return err
+	}
+	tag, err := repo.DataBase.Exec(context.Background(), "UPDATE robots SET labels = $1 WHERE id = $2", data, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrRobotNotFound
+	}
+	return nil
+}
+
+// Вставляем или обновляем пачку роботов одной транзакцией на отдельном соединении из пула.
+// Роботы без ID создаются заново, с ID - перезаписываются
+func (repo *RobotRepositories) UpsertRobots(robots []entities.Robot) error {
+	ctx := context.Background()
+	tx, err := repo.DataBase.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	batch := &pgx.Batch{}
+	for _, robot := range robots {
+		attributes, err := attributesJSON(robot.Attributes)
+		if err != nil {
+			return err
+		}
+		labels, err := labelsJSON(robot.Labels)
+		if err != nil {
+			return err
+		}
+		if robot.ID == 0 {
+			batch.Queue("INSERT INTO robots (name, type, xcord, ycord, zcord, attributes, labels) VALUES($1, $2, $3, $4, $5, $6, $7) RETURNING id",
+				robot.Name, robot.Type, robot.XCord, robot.YCord, robot.ZCord, attributes, labels)
+			continue
+		}
+		batch.Queue(`INSERT INTO robots (id, name, type, xcord, ycord, zcord, attributes, labels) VALUES($1, $2, $3, $4, $5, $6, $7, $8)
+			ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name, type = EXCLUDED.type,
+			xcord = EXCLUDED.xcord, ycord = EXCLUDED.ycord, zcord = EXCLUDED.zcord,
+			attributes = EXCLUDED.attributes, labels = EXCLUDED.labels RETURNING id`,
+			robot.ID, robot.Name, robot.Type, robot.XCord, robot.YCord, robot.ZCord, attributes, labels)
+	}
+	// Явно вставленные ID не двигают сиквенс, подтягиваем его сами
+	batch.Queue("SELECT setval(pg_get_serial_sequence('robots', 'id'), GREATEST((SELECT MAX(id) FROM robots), 1))")
+
+	// ID новых роботов нужны индексу позиций
+	results := tx.SendBatch(ctx, batch)
+	ids := make([]int, len(robots))
+	for i := range robots {
+		if err = results.QueryRow().Scan(&ids[i]); err != nil {
+			results.Close()
+			return err
+		}
+	}
+	if err = results.Close(); err != nil {
+		return err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return err
+	}
+	for i := range robots {
+		repo.Index.Upsert(ids[i], robots[i].Cord())
+	}
+	return nil
+}
+
+// Перестраиваем индекс позиций по базе
+func (repo *RobotRepositories) RebuildIndex() error {
+	rows, err := repo.DataBase.Query(context.Background(), "SELECT id, xcord, ycord, zcord FROM robots")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	positions := map[int]entities.RobotCord{}
+	for rows.Next() {
+		var id int
+		var cord entities.RobotCord
+		if err = rows.Scan(&id, &cord.XCord, &cord.YCord, &cord.ZCord); err != nil {
+			return err
+		}
+		positions[id] = cord
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	repo.Index.Reset(positions)
+	return nil
+}
+
+// Роботы по списку ID в том же порядке. Пропавших между запросами просто пропускаем
+func (repo *RobotRepositories) GetRobots(ids []int) ([]entities.Robot, error) {
+	query := "SELECT " + robotColumns + " FROM robots WHERE id = ANY($1)"
+	rows, err := repo.DataBase.Query(context.Background(), query, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byID := make(map[int]entities.Robot, len(ids))
+	for rows.Next() {
+		robot, err := scanRobot(rows)
+		if err != nil {
+			return nil, err
+		}
+		byID[robot.ID] = *robot
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	robots := make([]entities.Robot, 0, len(ids))
+	for _, id := range ids {
+		if robot, ok := byID[id]; ok {
+			robots = append(robots, robot)
+		}
+	}
+	return robots, nil
+}
@@ -0,0 +1,110 @@
+package repositories
+
+import (
+	"RobotService/internal/entities"
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrRobotTypeNotFound = errors.New("robot type not found")
+	ErrRobotTypeExists   = errors.New("robot type already exists")
+	// На тип ещё ссылаются роботы
+	ErrRobotTypeInUse = errors.New("robot type is in use")
+)
+
+// Коды ошибок постгреса
+const (
+	pgUniqueViolation     = "23505"
+	pgForeignKeyViolation = "23503"
+)
+
+type RobotTypeRepository struct {
+	DataBase *pgxpool.Pool
+}
+
+const robotTypeColumns = "name, description, max_speed, min_z, max_z, payload_capacity, safety_radius, attribute_schema, created_at, updated_at"
+
+func scanRobotType(row pgx.Row) (*entities.RobotType, error) {
+	robotType := &entities.RobotType{}
+	var schema []byte
+	err := row.Scan(&robotType.Name, &robotType.Description, &robotType.MaxSpeed, &robotType.MinZ, &robotType.MaxZ,
+		&robotType.PayloadCapacity, &robotType.SafetyRadius, &schema, &robotType.CreatedAt, &robotType.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrRobotTypeNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	robotType.AttributeSchema = schema
+	return robotType, nil
+}
+
+func pgErrorCode(err error) string {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code
+	}
+	return ""
+}
+
+func (repo *RobotTypeRepository) CreateRobotType(robotType entities.RobotType) (*entities.RobotType, error) {
+	query := `INSERT INTO robot_types (name, description, max_speed, min_z, max_z, payload_capacity, safety_radius, attribute_schema)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8) RETURNING ` + robotTypeColumns
+	created, err := scanRobotType(repo.DataBase.QueryRow(context.Background(), query, robotType.Name, robotType.Description,
+		robotType.MaxSpeed, robotType.MinZ, robotType.MaxZ, robotType.PayloadCapacity, robotType.SafetyRadius, []byte(robotType.AttributeSchema)))
+	if pgErrorCode(err) == pgUniqueViolation {
+		return nil, ErrRobotTypeExists
+	}
+	return created, err
+}
+
+func (repo *RobotTypeRepository) GetRobotType(name string) (*entities.RobotType, error) {
+	query := "SELECT " + robotTypeColumns + " FROM robot_types WHERE name = $1"
+	return scanRobotType(repo.DataBase.QueryRow(context.Background(), query, name))
+}
+
+func (repo *RobotTypeRepository) ListRobotTypes() ([]entities.RobotType, error) {
+	query := "SELECT " + robotTypeColumns + " FROM robot_types ORDER BY name"
+	rows, err := repo.DataBase.Query(context.Background(), query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	robotTypes := []entities.RobotType{}
+	for rows.Next() {
+		robotType, err := scanRobotType(rows)
+		if err != nil {
+			return nil, err
+		}
+		robotTypes = append(robotTypes, *robotType)
+	}
+	return robotTypes, rows.Err()
+}
+
+func (repo *RobotTypeRepository) UpdateRobotType(robotType entities.RobotType) (*entities.RobotType, error) {
+	query := `UPDATE robot_types SET description = $2, max_speed = $3, min_z = $4, max_z = $5,
+		payload_capacity = $6, safety_radius = $7, attribute_schema = $8, updated_at = now()
+		WHERE name = $1 RETURNING ` + robotTypeColumns
+	return scanRobotType(repo.DataBase.QueryRow(context.Background(), query, robotType.Name, robotType.Description,
+		robotType.MaxSpeed, robotType.MinZ, robotType.MaxZ, robotType.PayloadCapacity, robotType.SafetyRadius, []byte(robotType.AttributeSchema)))
+}
+
+func (repo *RobotTypeRepository) DeleteRobotType(name string) error {
+	tag, err := repo.DataBase.Exec(context.Background(), "DELETE FROM robot_types WHERE name = $1", name)
+	if pgErrorCode(err) == pgForeignKeyViolation {
+		return ErrRobotTypeInUse
+	}
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrRobotTypeNotFound
+	}
+	return nil
+}
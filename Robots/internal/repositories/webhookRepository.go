@@ -0,0 +1,104 @@
+package repositories
+
+import (
+	"RobotService/internal/entities"
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrWebhookNotFound = errors.New("webhook not found")
+
+type WebhookRepository struct {
+	DataBase *pgxpool.Pool
+}
+
+const webhookColumns = "id, url, secret, events, active, failure_count, disabled_reason, created_at, updated_at"
+
+func scanWebhook(row pgx.Row) (*entities.Webhook, error) {
+	hook := &entities.Webhook{}
+	err := row.Scan(&hook.ID, &hook.URL, &hook.Secret, &hook.Events, &hook.Active,
+		&hook.FailureCount, &hook.DisabledReason, &hook.CreatedAt, &hook.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrWebhookNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return hook, nil
+}
+
+func (repo *WebhookRepository) CreateWebhook(hook entities.Webhook) (*entities.Webhook, error) {
+	query := "INSERT INTO webhooks (url, secret, events) VALUES($1, $2, $3) RETURNING " + webhookColumns
+	return scanWebhook(repo.DataBase.QueryRow(context.Background(), query, hook.URL, hook.Secret, hook.Events))
+}
+
+func (repo *WebhookRepository) GetWebhook(id int) (*entities.Webhook, error) {
+	query := "SELECT " + webhookColumns + " FROM webhooks WHERE id = $1"
+	return scanWebhook(repo.DataBase.QueryRow(context.Background(), query, id))
+}
+
+func (repo *WebhookRepository) ListWebhooks() ([]entities.Webhook, error) {
+	query := "SELECT " + webhookColumns + " FROM webhooks ORDER BY id"
+	rows, err := repo.DataBase.Query(context.Background(), query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hooks := []entities.Webhook{}
+	for rows.Next() {
+		hook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, *hook)
+	}
+	return hooks, rows.Err()
+}
+
+// Перезаписываем настройки вебхука целиком. При включении сбрасываем счётчик ошибок
+func (repo *WebhookRepository) UpdateWebhook(hook entities.Webhook) (*entities.Webhook, error) {
+	query := `UPDATE webhooks SET url = $1, secret = $2, events = $3, active = $4,
+		failure_count = CASE WHEN $4 AND NOT active THEN 0 ELSE failure_count END,
+		disabled_reason = CASE WHEN $4 THEN '' ELSE disabled_reason END,
+		updated_at = now()
+		WHERE id = $5 RETURNING ` + webhookColumns
+	return scanWebhook(repo.DataBase.QueryRow(context.Background(), query, hook.URL, hook.Secret, hook.Events, hook.Active, hook.ID))
+}
+
+func (repo *WebhookRepository) DeleteWebhook(id int) error {
+	tag, err := repo.DataBase.Exec(context.Background(), "DELETE FROM webhooks WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+// Последние попытки доставки, свежие сверху
+func (repo *WebhookRepository) ListDeliveries(webhookID, limit int) ([]entities.WebhookDelivery, error) {
+	query := `SELECT id, webhook_id, delivery_id, event, attempt, status_code, success, error, duration_ms, created_at
+		FROM webhook_deliveries WHERE webhook_id = $1 ORDER BY created_at DESC, id DESC LIMIT $2`
+	rows, err := repo.DataBase.Query(context.Background(), query, webhookID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []entities.WebhookDelivery{}
+	for rows.Next() {
+		var d entities.WebhookDelivery
+		err = rows.Scan(&d.ID, &d.WebhookID, &d.DeliveryID, &d.Event, &d.Attempt, &d.StatusCode,
+			&d.Success, &d.Error, &d.DurationMs, &d.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
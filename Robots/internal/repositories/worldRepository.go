@@ -0,0 +1,133 @@
+package repositories
+
+import (
+	"RobotService/internal/entities"
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrZoneNotFound = errors.New("zone not found")
+	ErrZoneExists   = errors.New("zone already exists")
+)
+
+type WorldRepository struct {
+	DataBase *pgxpool.Pool
+}
+
+const zoneColumns = "id, name, kind, min_x, min_y, max_x, max_y, polygon, min_z, max_z, created_at, updated_at"
+
+func scanZone(row pgx.Row) (*entities.Zone, error) {
+	zone := &entities.Zone{}
+	var polygon []byte
+	err := row.Scan(&zone.ID, &zone.Name, &zone.Kind, &zone.MinX, &zone.MinY, &zone.MaxX, &zone.MaxY,
+		&polygon, &zone.MinZ, &zone.MaxZ, &zone.CreatedAt, &zone.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrZoneNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(polygon) > 0 {
+		if err = json.Unmarshal(polygon, &zone.Polygon); err != nil {
+			return nil, err
+		}
+	}
+	return zone, nil
+}
+
+// Прямоугольные зоны храним с polygon = NULL
+func polygonJSON(polygon []entities.Point) []byte {
+	if len(polygon) == 0 {
+		return nil
+	}
+	data, _ := json.Marshal(polygon)
+	return data
+}
+
+// Границы мира, nil - мир не ограничен
+func (repo *WorldRepository) GetBounds() (*entities.WorldBounds, error) {
+	bounds := &entities.WorldBounds{}
+	err := repo.DataBase.QueryRow(context.Background(), "SELECT min_x, min_y, min_z, max_x, max_y, max_z FROM world_bounds").
+		Scan(&bounds.MinX, &bounds.MinY, &bounds.MinZ, &bounds.MaxX, &bounds.MaxY, &bounds.MaxZ)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return bounds, nil
+}
+
+func (repo *WorldRepository) SetBounds(bounds entities.WorldBounds) error {
+	query := `INSERT INTO world_bounds (id, min_x, min_y, min_z, max_x, max_y, max_z) VALUES(1, $1, $2, $3, $4, $5, $6)
+		ON CONFLICT (id) DO UPDATE SET min_x = $1, min_y = $2, min_z = $3, max_x = $4, max_y = $5, max_z = $6, updated_at = now()`
+	_, err := repo.DataBase.Exec(context.Background(), query,
+		bounds.MinX, bounds.MinY, bounds.MinZ, bounds.MaxX, bounds.MaxY, bounds.MaxZ)
+	return err
+}
+
+func (repo *WorldRepository) ClearBounds() error {
+	_, err := repo.DataBase.Exec(context.Background(), "DELETE FROM world_bounds")
+	return err
+}
+
+func (repo *WorldRepository) CreateZone(zone entities.Zone) (*entities.Zone, error) {
+	query := `INSERT INTO zones (name, kind, min_x, min_y, max_x, max_y, polygon, min_z, max_z)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING ` + zoneColumns
+	created, err := scanZone(repo.DataBase.QueryRow(context.Background(), query, zone.Name, zone.Kind,
+		zone.MinX, zone.MinY, zone.MaxX, zone.MaxY, polygonJSON(zone.Polygon), zone.MinZ, zone.MaxZ))
+	if pgErrorCode(err) == pgUniqueViolation {
+		return nil, ErrZoneExists
+	}
+	return created, err
+}
+
+func (repo *WorldRepository) GetZone(id int) (*entities.Zone, error) {
+	query := "SELECT " + zoneColumns + " FROM zones WHERE id = $1"
+	return scanZone(repo.DataBase.QueryRow(context.Background(), query, id))
+}
+
+func (repo *WorldRepository) ListZones() ([]entities.Zone, error) {
+	rows, err := repo.DataBase.Query(context.Background(), "SELECT "+zoneColumns+" FROM zones ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	zones := []entities.Zone{}
+	for rows.Next() {
+		zone, err := scanZone(rows)
+		if err != nil {
+			return nil, err
+		}
+		zones = append(zones, *zone)
+	}
+	return zones, rows.Err()
+}
+
+func (repo *WorldRepository) UpdateZone(zone entities.Zone) (*entities.Zone, error) {
+	query := `UPDATE zones SET name = $2, kind = $3, min_x = $4, min_y = $5, max_x = $6, max_y = $7,
+		polygon = $8, min_z = $9, max_z = $10, updated_at = now() WHERE id = $1 RETURNING ` + zoneColumns
+	updated, err := scanZone(repo.DataBase.QueryRow(context.Background(), query, zone.ID, zone.Name, zone.Kind,
+		zone.MinX, zone.MinY, zone.MaxX, zone.MaxY, polygonJSON(zone.Polygon), zone.MinZ, zone.MaxZ))
+	if pgErrorCode(err) == pgUniqueViolation {
+		return nil, ErrZoneExists
+	}
+	return updated, err
+}
+
+func (repo *WorldRepository) DeleteZone(id int) error {
+	tag, err := repo.DataBase.Exec(context.Background(), "DELETE FROM zones WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrZoneNotFound
+	}
+	return nil
+}
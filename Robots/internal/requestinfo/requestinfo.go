@@ -0,0 +1,46 @@
+package requestinfo
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net"
+)
+
+const (
+	Header = "X-Request-Id"
+
+	// Чужой request id длиннее этого не берём, генерируем свой
+	maxIDLength = 128
+)
+
+// Откуда пришёл запрос, нужно для аудита
+type Info struct {
+	ID       string
+	SourceIP string
+}
+
+type infoKey struct{}
+
+func With(ctx context.Context, info Info) context.Context {
+	return context.WithValue(ctx, infoKey{}, info)
+}
+
+func From(ctx context.Context) Info {
+	info, _ := ctx.Value(infoKey{}).(Info)
+	return info
+}
+
+// Берём request id клиента или прокси, если он вменяемый, иначе придумываем свой
+func New(requestID, remoteAddr string) Info {
+	if requestID == "" || len(requestID) > maxIDLength {
+		buf := make([]byte, 16)
+		_, _ = rand.Read(buf)
+		requestID = hex.EncodeToString(buf)
+	}
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	return Info{ID: requestID, SourceIP: host}
+}
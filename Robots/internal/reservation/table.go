@@ -0,0 +1,175 @@
+package reservation
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+var ErrConflict = errors.New("cell is reserved")
+
+// Воксель - куб мира со стороной в размер вокселя, координаты в вокселях
+type Voxel struct {
+	X, Y, Z int
+}
+
+// Воксель в конкретном временном слоте (номер тика движка)
+type Cell struct {
+	Voxel
+	Slot int64
+}
+
+// Ячейка занята другим владельцем
+type ConflictError struct {
+	Cell  Cell
+	Owner int
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("voxel (%d, %d, %d) at slot %d is reserved by %d", e.Cell.X, e.Cell.Y, e.Cell.Z, e.Cell.Slot, e.Owner)
+}
+
+func (e *ConflictError) Unwrap() error {
+	return ErrConflict
+}
+
+// Таблица резервирования (воксель, слот) -> владелец. Владелец - любой ненулевой номер, у нас команда перемещения
+// (или -robotID, пока команда пишется в базу).
+// Не потокобезопасна, блокировка на вызывающем
+type Table struct {
+	cells map[Cell]int
+	owned map[int][]Cell
+	// Сколько ячеек каждого владельца в вокселе по всем слотам, чтобы отвечать "занят ли воксель когда-нибудь"
+	voxels map[Voxel]map[int]int
+}
+
+func New() *Table {
+	return &Table{cells: map[Cell]int{}, owned: map[int][]Cell{}, voxels: map[Voxel]map[int]int{}}
+}
+
+// Кто держит ячейку, 0 - никто
+func (t *Table) Owner(cell Cell) int {
+	return t.cells[cell]
+}
+
+// Свободна ли ячейка для owner: никем не занята или занята им самим
+func (t *Table) Free(cell Cell, owner int) bool {
+	holder := t.cells[cell]
+	return holder == 0 || holder == owner
+}
+
+// Занят ли воксель кем-то кроме owner хотя бы в одном слоте
+func (t *Table) Contested(voxel Voxel, owner int) bool {
+	for holder := range t.voxels[voxel] {
+		if holder != owner {
+			return true
+		}
+	}
+	return false
+}
+
+// Воксели, которые кто-то кроме owner занимает хотя бы в одном слоте.
+// Копия: по ней можно искать обход, отпустив блокировку таблицы
+func (t *Table) ContestedVoxels(owner int) map[Voxel]bool {
+	contested := map[Voxel]bool{}
+	for voxel := range t.voxels {
+		if t.Contested(voxel, owner) {
+			contested[voxel] = true
+		}
+	}
+	return contested
+}
+
+// Занимаем все ячейки или ни одной
+func (t *Table) Claim(owner int, cells []Cell) error {
+	for _, cell := range cells {
+		if !t.Free(cell, owner) {
+			return &ConflictError{Cell: cell, Owner: t.cells[cell]}
+		}
+	}
+	for _, cell := range cells {
+		if t.cells[cell] == owner {
+			continue
+		}
+		t.cells[cell] = owner
+		t.owned[owner] = append(t.owned[owner], cell)
+		if t.voxels[cell.Voxel] == nil {
+			t.voxels[cell.Voxel] = map[int]int{}
+		}
+		t.voxels[cell.Voxel][owner]++
+	}
+	return nil
+}
+
+// Все ячейки from переходят к to, например от временного владельца к созданной команде
+func (t *Table) Transfer(from, to int) {
+	for _, cell := range t.owned[from] {
+		t.cells[cell] = to
+		counts := t.voxels[cell.Voxel]
+		if counts[from]--; counts[from] <= 0 {
+			delete(counts, from)
+		}
+		counts[to]++
+	}
+	if cells, ok := t.owned[from]; ok {
+		t.owned[to] = append(t.owned[to], cells...)
+		delete(t.owned, from)
+	}
+}
+
+func (t *Table) Release(owner int) {
+	for _, cell := range t.owned[owner] {
+		t.drop(cell, owner)
+	}
+	delete(t.owned, owner)
+}
+
+// Забываем прошедшие слоты
+func (t *Table) Prune(before int64) {
+	for owner, cells := range t.owned {
+		kept := cells[:0]
+		for _, cell := range cells {
+			if cell.Slot < before {
+				t.drop(cell, owner)
+			} else {
+				kept = append(kept, cell)
+			}
+		}
+		if len(kept) == 0 {
+			delete(t.owned, owner)
+		} else {
+			t.owned[owner] = kept
+		}
+	}
+}
+
+func (t *Table) drop(cell Cell, owner int) {
+	delete(t.cells, cell)
+	counts := t.voxels[cell.Voxel]
+	if counts[owner]--; counts[owner] <= 0 {
+		delete(counts, owner)
+	}
+	if len(counts) == 0 {
+		delete(t.voxels, cell.Voxel)
+	}
+}
+
+// Ячейки владельца по слотам, затем по координатам
+func (t *Table) Cells(owner int) []Cell {
+	cells := slices.Clone(t.owned[owner])
+	slices.SortFunc(cells, func(a, b Cell) int {
+		return cmp.Or(cmp.Compare(a.Slot, b.Slot), cmp.Compare(a.X, b.X), cmp.Compare(a.Y, b.Y), cmp.Compare(a.Z, b.Z))
+	})
+	return cells
+}
+
+// Владельцы по возрастанию
+func (t *Table) Owners() []int {
+	owners := make([]int, 0, len(t.owned))
+	for owner := range t.owned {
+		owners = append(owners, owner)
+	}
+	slices.Sort(owners)
+	return owners
+}
@@ -0,0 +1,127 @@
+package reservation
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func cell(x, y, z int, slot int64) Cell {
+	return Cell{Voxel: Voxel{X: x, Y: y, Z: z}, Slot: slot}
+}
+
+func TestClaim(t *testing.T) {
+	table := New()
+	if err := table.Claim(1, []Cell{cell(0, 0, 0, 1), cell(1, 0, 0, 2)}); err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+	// Свои ячейки можно занимать повторно
+	if err := table.Claim(1, []Cell{cell(1, 0, 0, 2), cell(2, 0, 0, 3)}); err != nil {
+		t.Fatalf("reclaim: %v", err)
+	}
+	if got := table.Cells(1); len(got) != 3 {
+		t.Fatalf("cells = %v, want 3 without duplicates", got)
+	}
+
+	// Чужая ячейка в середине: не занимаем ничего
+	err := table.Claim(2, []Cell{cell(5, 0, 0, 1), cell(1, 0, 0, 2), cell(6, 0, 0, 3)})
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) || !errors.Is(err, ErrConflict) {
+		t.Fatalf("err = %v, want *ConflictError", err)
+	}
+	if conflict.Cell != cell(1, 0, 0, 2) || conflict.Owner != 1 {
+		t.Fatalf("conflict = %+v", conflict)
+	}
+	if table.Owner(cell(5, 0, 0, 1)) != 0 || len(table.Cells(2)) != 0 {
+		t.Fatal("failed claim took some cells")
+	}
+
+	// Тот же воксель в другом слоте свободен
+	if !table.Free(cell(1, 0, 0, 3), 2) || table.Free(cell(1, 0, 0, 2), 2) || !table.Free(cell(1, 0, 0, 2), 1) {
+		t.Fatal("Free")
+	}
+	if !table.Contested(Voxel{X: 1}, 2) || table.Contested(Voxel{X: 1}, 1) || table.Contested(Voxel{X: 9}, 2) {
+		t.Fatal("Contested")
+	}
+	contested := table.ContestedVoxels(2)
+	if len(contested) != 3 || !contested[Voxel{X: 2}] {
+		t.Fatalf("contested = %v", contested)
+	}
+}
+
+func TestTransfer(t *testing.T) {
+	table := New()
+	// Пока команда пишется в базу, ячейки держит -robotID
+	if err := table.Claim(-7, []Cell{cell(0, 0, 0, 1), cell(1, 0, 0, 2)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := table.Claim(3, []Cell{cell(1, 0, 0, 5)}); err != nil {
+		t.Fatal(err)
+	}
+	table.Transfer(-7, 42)
+
+	if table.Owner(cell(0, 0, 0, 1)) != 42 || table.Owner(cell(1, 0, 0, 2)) != 42 {
+		t.Fatalf("owners after transfer: %d, %d", table.Owner(cell(0, 0, 0, 1)), table.Owner(cell(1, 0, 0, 2)))
+	}
+	if len(table.Cells(-7)) != 0 || len(table.Cells(42)) != 2 {
+		t.Fatalf("cells: -7 %v, 42 %v", table.Cells(-7), table.Cells(42))
+	}
+	if !slices.Equal(table.Owners(), []int{3, 42}) {
+		t.Fatalf("owners = %v", table.Owners())
+	}
+	// Счётчики вокселей тоже перешли: для нового владельца его воксели не чужие
+	if table.Contested(Voxel{X: 0}, 42) || !table.Contested(Voxel{X: 0}, -7) || !table.Contested(Voxel{X: 1}, 42) {
+		t.Fatal("voxel counts were not transferred")
+	}
+
+	table.Release(42)
+	if table.Owner(cell(0, 0, 0, 1)) != 0 || table.Contested(Voxel{X: 0}, 3) || len(table.Cells(42)) != 0 {
+		t.Fatal("release left cells behind")
+	}
+	if table.Owner(cell(1, 0, 0, 5)) != 3 || !table.Contested(Voxel{X: 1}, 42) {
+		t.Fatal("release dropped someone else's cells")
+	}
+
+	// Перенос от того, у кого ничего нет, ничего не создаёт
+	table.Transfer(-8, 43)
+	if !slices.Equal(table.Owners(), []int{3}) {
+		t.Fatalf("owners = %v", table.Owners())
+	}
+}
+
+func TestPrune(t *testing.T) {
+	table := New()
+	if err := table.Claim(1, []Cell{cell(0, 0, 0, 1), cell(1, 0, 0, 2), cell(2, 0, 0, 3)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := table.Claim(2, []Cell{cell(5, 0, 0, 1), cell(5, 0, 0, 2)}); err != nil {
+		t.Fatal(err)
+	}
+	table.Prune(3)
+
+	if got := table.Cells(1); len(got) != 1 || got[0] != cell(2, 0, 0, 3) {
+		t.Fatalf("cells of 1 = %v", got)
+	}
+	// У владельца не осталось ячеек - его нет и в списке
+	if !slices.Equal(table.Owners(), []int{1}) {
+		t.Fatalf("owners = %v", table.Owners())
+	}
+	if table.Owner(cell(0, 0, 0, 1)) != 0 || len(table.ContestedVoxels(0)) != 1 {
+		t.Fatalf("contested = %v", table.ContestedVoxels(0))
+	}
+	// Прошедшую ячейку можно занять снова
+	if err := table.Claim(3, []Cell{cell(5, 0, 0, 2)}); err != nil {
+		t.Fatalf("claim after prune: %v", err)
+	}
+}
+
+func TestCellsOrder(t *testing.T) {
+	table := New()
+	if err := table.Claim(1, []Cell{cell(2, 0, 0, 2), cell(1, 1, 0, 1), cell(1, 0, 5, 1), cell(0, 0, 0, 2)}); err != nil {
+		t.Fatal(err)
+	}
+	want := []Cell{cell(1, 0, 5, 1), cell(1, 1, 0, 1), cell(0, 0, 0, 2), cell(2, 0, 0, 2)}
+	if got := table.Cells(1); !slices.Equal(got, want) {
+		t.Fatalf("cells = %v, want %v", got, want)
+	}
+}
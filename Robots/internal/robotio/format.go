@@ -0,0 +1,245 @@
+package robotio
+
+import (
+	"RobotService/internal/entities"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	FormatJSONL = "jsonl"
+	FormatCSV   = "csv"
+)
+
+var (
+	ErrUnknownFormat = errors.New("unknown format, expected jsonl or csv")
+	// Поток дальше читать нельзя: битый заголовок, слишком длинная строка и т.п.
+	ErrBrokenInput = errors.New("broken input")
+)
+
+// attributes и labels в CSV - JSON объекты в одной ячейке
+var csvHeader = []string{"id", "name", "type", "xCord", "yCord", "zCord", "attributes", "labels"}
+
+// Проверяем формат, пустая строка означает JSON Lines
+func ParseFormat(format string) (string, error) {
+	switch strings.ToLower(format) {
+	case "", FormatJSONL, "ndjson":
+		return FormatJSONL, nil
+	case FormatCSV:
+		return FormatCSV, nil
+	}
+	return "", ErrUnknownFormat
+}
+
+func ContentType(format string) string {
+	if format == FormatCSV {
+		return "text/csv"
+	}
+	return "application/x-ndjson"
+}
+
+// Писатель реестра: по одному роботу на строку
+type Encoder struct {
+	format string
+	json   *json.Encoder
+	csv    *csv.Writer
+	header bool
+}
+
+func NewEncoder(w io.Writer, format string) *Encoder {
+	if format == FormatCSV {
+		return &Encoder{format: format, csv: csv.NewWriter(w)}
+	}
+	return &Encoder{format: format, json: json.NewEncoder(w)}
+}
+
+func (enc *Encoder) Encode(robot entities.Robot) error {
+	if enc.format != FormatCSV {
+		return enc.json.Encode(robot)
+	}
+	if !enc.header {
+		enc.header = true
+		if err := enc.csv.Write(csvHeader); err != nil {
+			return err
+		}
+	}
+	attributes, err := jsonCell(robot.Attributes, len(robot.Attributes))
+	if err != nil {
+		return err
+	}
+	labels, err := jsonCell(robot.Labels, len(robot.Labels))
+	if err != nil {
+		return err
+	}
+	return enc.csv.Write([]string{
+		strconv.Itoa(robot.ID),
+		robot.Name,
+		robot.Type,
+		strconv.Itoa(robot.XCord),
+		strconv.Itoa(robot.YCord),
+		strconv.Itoa(robot.ZCord),
+		attributes,
+		labels,
+	})
+}
+
+// Пустой объект в CSV - пустая ячейка
+func jsonCell(value any, size int) (string, error) {
+	if size == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(value)
+	return string(data), err
+}
+
+// Дописываем буфер CSV, для JSON Lines ничего не делает
+func (enc *Encoder) Flush() error {
+	if enc.csv == nil {
+		return nil
+	}
+	if !enc.header {
+		enc.header = true
+		_ = enc.csv.Write(csvHeader)
+	}
+	enc.csv.Flush()
+	return enc.csv.Error()
+}
+
+// Читатель реестра. Помнит номер строки, чтобы было что написать в отчёте об ошибках
+type Decoder struct {
+	format  string
+	lines   *bufio.Scanner
+	csv     *csv.Reader
+	columns map[string]int
+	line    int
+}
+
+func NewDecoder(r io.Reader, format string) *Decoder {
+	if format == FormatCSV {
+		reader := csv.NewReader(r)
+		reader.FieldsPerRecord = -1
+		reader.TrimLeadingSpace = true
+		return &Decoder{format: format, csv: reader}
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	return &Decoder{format: format, lines: scanner}
+}
+
+// Номер строки последней прочитанной записи
+func (dec *Decoder) Line() int {
+	return dec.line
+}
+
+// Возвращает io.EOF, когда записи закончились. Ошибка разбора одной записи не мешает читать дальше
+func (dec *Decoder) Next() (entities.Robot, error) {
+	if dec.format == FormatCSV {
+		return dec.nextCSV()
+	}
+	return dec.nextJSON()
+}
+
+func (dec *Decoder) nextJSON() (entities.Robot, error) {
+	var robot entities.Robot
+	for dec.lines.Scan() {
+		dec.line++
+		text := strings.TrimSpace(dec.lines.Text())
+		if text == "" {
+			continue
+		}
+		err := json.Unmarshal([]byte(text), &robot)
+		return robot, err
+	}
+	if err := dec.lines.Err(); err != nil {
+		return robot, fmt.Errorf("%w: %v", ErrBrokenInput, err)
+	}
+	return robot, io.EOF
+}
+
+func (dec *Decoder) nextCSV() (entities.Robot, error) {
+	var robot entities.Robot
+	if dec.columns == nil {
+		header, err := dec.csv.Read()
+		if errors.Is(err, io.EOF) {
+			return robot, err
+		}
+		if err != nil {
+			return robot, fmt.Errorf("%w: %v", ErrBrokenInput, err)
+		}
+		dec.line++
+		dec.columns = make(map[string]int, len(header))
+		for i, name := range header {
+			dec.columns[strings.ToLower(strings.TrimSpace(name))] = i
+		}
+		for _, name := range []string{"name", "type"} {
+			if _, ok := dec.columns[name]; !ok {
+				return robot, fmt.Errorf("%w: csv header has no %q column", ErrBrokenInput, name)
+			}
+		}
+	}
+
+	record, err := dec.csv.Read()
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		dec.line = parseErr.Line
+		return robot, err
+	}
+	if err != nil && !errors.Is(err, io.EOF) {
+		return robot, fmt.Errorf("%w: %v", ErrBrokenInput, err)
+	}
+	if err != nil {
+		return robot, err
+	}
+	dec.line, _ = dec.csv.FieldPos(0)
+
+	field := func(name string) string {
+		i, ok := dec.columns[strings.ToLower(name)]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+	number := func(name string) (int, error) {
+		value := field(name)
+		if value == "" {
+			return 0, nil
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return 0, fmt.Errorf("column %s: %q is not a number", name, value)
+		}
+		return n, nil
+	}
+
+	robot.Name = field("name")
+	robot.Type = field("type")
+	if robot.ID, err = number("id"); err != nil {
+		return robot, err
+	}
+	if robot.XCord, err = number("xCord"); err != nil {
+		return robot, err
+	}
+	if robot.YCord, err = number("yCord"); err != nil {
+		return robot, err
+	}
+	if robot.ZCord, err = number("zCord"); err != nil {
+		return robot, err
+	}
+	if value := field("attributes"); value != "" {
+		if err = json.Unmarshal([]byte(value), &robot.Attributes); err != nil {
+			return robot, fmt.Errorf("column attributes: must be a JSON object")
+		}
+	}
+	if value := field("labels"); value != "" {
+		if err = json.Unmarshal([]byte(value), &robot.Labels); err != nil {
+			return robot, fmt.Errorf("column labels: must be a JSON object with string values")
+		}
+	}
+	return robot, nil
+}
@@ -0,0 +1,136 @@
+package robotio
+
+import (
+	"RobotService/internal/entities"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Все записи потока: робот или ошибка и номер строки
+type decoded struct {
+	robot entities.Robot
+	err   error
+	line  int
+}
+
+func decodeAll(t *testing.T, input, format string) []decoded {
+	t.Helper()
+	dec := NewDecoder(strings.NewReader(input), format)
+	var out []decoded
+	for {
+		robot, err := dec.Next()
+		if errors.Is(err, io.EOF) {
+			return out
+		}
+		out = append(out, decoded{robot, err, dec.Line()})
+		if errors.Is(err, ErrBrokenInput) {
+			return out
+		}
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for input, want := range map[string]string{"": FormatJSONL, "JSONL": FormatJSONL, "ndjson": FormatJSONL, "Csv": FormatCSV} {
+		if got, err := ParseFormat(input); err != nil || got != want {
+			t.Errorf("ParseFormat(%q) = %q, %v", input, got, err)
+		}
+	}
+	if _, err := ParseFormat("xml"); !errors.Is(err, ErrUnknownFormat) {
+		t.Fatalf("xml: err = %v", err)
+	}
+}
+
+func TestDecodeJSONL(t *testing.T) {
+	input := `{"id":1,"name":"r1","type":"rover","xCord":-5,"labels":{"site":"a"}}
+
+  {"name":"r2","type":"drone","zCord":3}
+{"name":
+{"name":"r4","type":"rover","attributes":{"battery":80}}
+`
+	got := decodeAll(t, input, FormatJSONL)
+	if len(got) != 4 {
+		t.Fatalf("%d records: %+v", len(got), got)
+	}
+	if got[0].err != nil || got[0].line != 1 || got[0].robot.XCord != -5 || got[0].robot.Labels["site"] != "a" {
+		t.Fatalf("first = %+v", got[0])
+	}
+	// Пустая строка пропускается, но номер строки учитывает
+	if got[1].err != nil || got[1].line != 3 || got[1].robot.Name != "r2" || got[1].robot.ZCord != 3 {
+		t.Fatalf("second = %+v", got[1])
+	}
+	if got[2].err == nil || errors.Is(got[2].err, ErrBrokenInput) || got[2].line != 4 {
+		t.Fatalf("broken line = %+v", got[2])
+	}
+	if got[3].err != nil || got[3].line != 5 || got[3].robot.Attributes["battery"] != 80.0 {
+		t.Fatalf("after the broken line = %+v", got[3])
+	}
+}
+
+func TestDecodeCSV(t *testing.T) {
+	// Порядок колонок любой, регистр в заголовке не важен, лишних колонок может не быть
+	input := "Type, NAME ,xCord,yCord,labels\n" +
+		"rover,r1,-5,7,\"{\"\"site\"\":\"\"a\"\"}\"\n" +
+		"drone,r2,ten,0,\n" +
+		"rover,r3,1,2,[1]\n" +
+		"rover,r4\n"
+	got := decodeAll(t, input, FormatCSV)
+	if len(got) != 4 {
+		t.Fatalf("%d records: %+v", len(got), got)
+	}
+	want := entities.Robot{Name: "r1", Type: "rover", XCord: -5, YCord: 7, Labels: map[string]string{"site": "a"}}
+	if got[0].err != nil || got[0].line != 2 || !reflect.DeepEqual(got[0].robot, want) {
+		t.Fatalf("first = %+v", got[0])
+	}
+	if got[1].err == nil || !strings.Contains(got[1].err.Error(), `column xCord: "ten" is not a number`) || got[1].line != 3 {
+		t.Fatalf("bad number = %+v", got[1])
+	}
+	if got[2].err == nil || !strings.Contains(got[2].err.Error(), "column labels") || got[2].line != 4 {
+		t.Fatalf("bad labels = %+v", got[2])
+	}
+	if got[3].err != nil || got[3].line != 5 || got[3].robot.Name != "r4" || got[3].robot.XCord != 0 {
+		t.Fatalf("short record = %+v", got[3])
+	}
+}
+
+func TestDecodeCSVBrokenHeader(t *testing.T) {
+	got := decodeAll(t, "id,title,type\n1,r1,rover\n", FormatCSV)
+	if len(got) != 1 || !errors.Is(got[0].err, ErrBrokenInput) || !strings.Contains(got[0].err.Error(), `"name"`) {
+		t.Fatalf("got %+v", got)
+	}
+	if got := decodeAll(t, "", FormatCSV); len(got) != 0 {
+		t.Fatalf("empty input: %+v", got)
+	}
+	// Незакрытая кавычка - ошибка разбора с номером строки, а не обрыв потока
+	got = decodeAll(t, "name,type\nr1,rover\n\"r2,rover\n", FormatCSV)
+	if len(got) != 2 || got[1].err == nil || errors.Is(got[1].err, ErrBrokenInput) || got[1].line != 3 {
+		t.Fatalf("bare quote: %+v", got)
+	}
+}
+
+func TestEncodeCSV(t *testing.T) {
+	var out strings.Builder
+	enc := NewEncoder(&out, FormatCSV)
+	if err := enc.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	// Пустой реестр - один заголовок
+	if out.String() != strings.Join(csvHeader, ",")+"\n" {
+		t.Fatalf("empty export = %q", out.String())
+	}
+
+	out.Reset()
+	enc = NewEncoder(&out, FormatCSV)
+	if err := enc.Encode(entities.Robot{ID: 4, Name: "a, b", Type: "rover", XCord: -1, Labels: map[string]string{"site": "x"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Join(csvHeader, ",") + "\n" + `4,"a, b",rover,-1,0,0,,"{""site"":""x""}"` + "\n"
+	if out.String() != want {
+		t.Fatalf("export = %q, want %q", out.String(), want)
+	}
+}
@@ -0,0 +1,105 @@
+package robotio
+
+import (
+	"RobotService/internal/entities"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const batchSize = 500
+
+// То, что нужно от репозитория для импорта и экспорта
+type Store interface {
+	ForEachRobot(filter entities.RobotFilter, fn func(robot entities.Robot) error) error
+	UpsertRobots(robots []entities.Robot) error
+}
+
+// Выгружаем роботов под фильтр в writer в нужном формате, пустой фильтр - весь реестр
+func Export(store Store, w io.Writer, format string, filter entities.RobotFilter) error {
+	enc := NewEncoder(w, format)
+	err := store.ForEachRobot(filter, enc.Encode)
+	if err != nil {
+		return err
+	}
+	return enc.Flush()
+}
+
+// Проверка одной записи перед импортом
+func Validate(robot entities.Robot) error {
+	switch {
+	case robot.ID < 0:
+		return fmt.Errorf("id must not be negative")
+	case robot.Name == "":
+		return fmt.Errorf("name is required")
+	case robot.Type == "":
+		return fmt.Errorf("type is required")
+	}
+	return nil
+}
+
+// Читаем записи, валидируем и пачками записываем в базу.
+// В режиме dryRun только проверяем, в базу ничего не пишем.
+// check - дополнительная проверка записи (например, по реестру типов), может быть nil.
+// onBatch вызывается после каждой успешно записанной пачки (например, чтобы почистить кэш)
+func Import(store Store, r io.Reader, format string, dryRun bool, check func(entities.Robot) error, onBatch func([]entities.Robot)) (entities.ImportReport, error) {
+	report := entities.ImportReport{DryRun: dryRun, Errors: []entities.ImportError{}}
+	dec := NewDecoder(r, format)
+
+	batch := make([]entities.Robot, 0, batchSize)
+	lines := make([]int, 0, batchSize)
+
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if dryRun {
+			report.Imported += len(batch)
+		} else if err := store.UpsertRobots(batch); err != nil {
+			// Пачка пишется транзакцией, так что падает она целиком
+			for i, robot := range batch {
+				report.Errors = append(report.Errors, entities.ImportError{Line: lines[i], ID: robot.ID, Error: err.Error()})
+			}
+			report.Failed += len(batch)
+		} else {
+			report.Imported += len(batch)
+			if onBatch != nil {
+				onBatch(batch)
+			}
+		}
+		batch = batch[:0]
+		lines = lines[:0]
+	}
+
+	for {
+		robot, err := dec.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		report.Total++
+		if err == nil {
+			err = Validate(robot)
+		}
+		if err == nil && check != nil {
+			err = check(robot)
+		}
+		if err != nil {
+			report.Failed++
+			report.Errors = append(report.Errors, entities.ImportError{Line: dec.Line(), ID: robot.ID, Error: err.Error()})
+			// Битый заголовок CSV или оборванный поток дальше читать бессмысленно
+			if errors.Is(err, ErrBrokenInput) {
+				flush()
+				return report, err
+			}
+			continue
+		}
+
+		batch = append(batch, robot)
+		lines = append(lines, dec.Line())
+		if len(batch) == batchSize {
+			flush()
+		}
+	}
+	flush()
+	return report, nil
+}
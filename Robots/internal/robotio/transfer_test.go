@@ -0,0 +1,149 @@
+package robotio
+
+import (
+	"RobotService/internal/entities"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Реестр в памяти: роботы по порядку ID, fail - ошибка записи пачки
+type memStore struct {
+	robots  []entities.Robot
+	batches int
+	fail    error
+}
+
+func (s *memStore) ForEachRobot(filter entities.RobotFilter, fn func(robot entities.Robot) error) error {
+	for _, robot := range s.robots {
+		if err := fn(robot); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *memStore) UpsertRobots(robots []entities.Robot) error {
+	if s.fail != nil {
+		return s.fail
+	}
+	s.batches++
+	for _, robot := range robots {
+		replaced := false
+		for i := range s.robots {
+			if robot.ID != 0 && s.robots[i].ID == robot.ID {
+				s.robots[i], replaced = robot, true
+			}
+		}
+		if !replaced {
+			s.robots = append(s.robots, robot)
+		}
+	}
+	return nil
+}
+
+func TestValidate(t *testing.T) {
+	for _, tt := range []struct {
+		robot entities.Robot
+		err   string
+	}{
+		{entities.Robot{Name: "r", Type: "rover"}, ""},
+		{entities.Robot{ID: -1, Name: "r", Type: "rover"}, "id must not be negative"},
+		{entities.Robot{Type: "rover"}, "name is required"},
+		{entities.Robot{Name: "r"}, "type is required"},
+	} {
+		err := Validate(tt.robot)
+		if (err == nil) != (tt.err == "") || (err != nil && err.Error() != tt.err) {
+			t.Errorf("Validate(%+v) = %v, want %q", tt.robot, err, tt.err)
+		}
+	}
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	robots := []entities.Robot{
+		{ID: 1, Name: "rover, one", Type: "rover", XCord: -5, YCord: 10, Labels: map[string]string{"site": "warehouse-2"}},
+		{ID: 2, Name: `quoted "two"`, Type: "drone", ZCord: 40, Attributes: map[string]any{"battery": 80.5, "tags": []any{"a"}}},
+		{ID: 3, Name: "bare", Type: "rover"},
+	}
+	for _, format := range []string{FormatJSONL, FormatCSV} {
+		var out strings.Builder
+		if err := Export(&memStore{robots: robots}, &out, format, entities.RobotFilter{}); err != nil {
+			t.Fatalf("%s: Export: %v", format, err)
+		}
+		target := &memStore{}
+		report, err := Import(target, strings.NewReader(out.String()), format, false, nil, nil)
+		if err != nil || report.Total != 3 || report.Imported != 3 || report.Failed != 0 {
+			t.Fatalf("%s: report = %+v, err = %v", format, report, err)
+		}
+		if !reflect.DeepEqual(target.robots, robots) {
+			t.Fatalf("%s: imported %+v, want %+v", format, target.robots, robots)
+		}
+	}
+}
+
+func TestImportReportsFailedLines(t *testing.T) {
+	input := "name,type,xCord\n" +
+		"r1,rover,1\n" +
+		"r2,rover,x\n" +
+		",rover,3\n" +
+		"r4,forklift,4\n" +
+		"r5,rover,5\n"
+	check := func(robot entities.Robot) error {
+		if robot.Type != "rover" {
+			return errors.New("unknown robot type")
+		}
+		return nil
+	}
+	var written []entities.Robot
+	store := &memStore{}
+	report, err := Import(store, strings.NewReader(input), FormatCSV, false, check, func(batch []entities.Robot) {
+		written = append(written, batch...)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Total != 5 || report.Imported != 2 || report.Failed != 3 || len(store.robots) != 2 || len(written) != 2 {
+		t.Fatalf("report = %+v, stored %+v", report, store.robots)
+	}
+	want := []entities.ImportError{
+		{Line: 3, Error: `column xCord: "x" is not a number`},
+		{Line: 4, Error: "name is required"},
+		{Line: 5, Error: "unknown robot type"},
+	}
+	if !reflect.DeepEqual(report.Errors, want) {
+		t.Fatalf("errors = %+v, want %+v", report.Errors, want)
+	}
+
+	// Пробный прогон ничего не пишет
+	dry := &memStore{}
+	report, err = Import(dry, strings.NewReader(input), FormatCSV, true, check, nil)
+	if err != nil || !report.DryRun || report.Imported != 2 || dry.batches != 0 || len(dry.robots) != 0 {
+		t.Fatalf("dry run: %+v, err = %v", report, err)
+	}
+}
+
+func TestImportFailedBatch(t *testing.T) {
+	store := &memStore{fail: errors.New("deadlock detected")}
+	input := `{"id":7,"name":"r1","type":"rover"}` + "\n" + `{"name":"r2","type":"rover"}` + "\n"
+	report, err := Import(store, strings.NewReader(input), FormatJSONL, false, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Пачка пишется транзакцией: падает целиком, каждая строка в отчёте
+	want := []entities.ImportError{{Line: 1, ID: 7, Error: "deadlock detected"}, {Line: 2, Error: "deadlock detected"}}
+	if report.Imported != 0 || report.Failed != 2 || !reflect.DeepEqual(report.Errors, want) {
+		t.Fatalf("report = %+v", report)
+	}
+}
+
+func TestImportStopsOnBrokenHeader(t *testing.T) {
+	store := &memStore{}
+	report, err := Import(store, strings.NewReader("id;name;type\n1;r1;rover\n"), FormatCSV, false, nil, nil)
+	if !errors.Is(err, ErrBrokenInput) {
+		t.Fatalf("err = %v, want ErrBrokenInput", err)
+	}
+	if report.Total != 1 || report.Failed != 1 || len(report.Errors) != 1 || report.Errors[0].Line != 1 || len(store.robots) != 0 {
+		t.Fatalf("report = %+v", report)
+	}
+}
@@ -0,0 +1,267 @@
+package services
+
+import (
+	"RobotService/internal/allocation"
+	"RobotService/internal/auth"
+	"RobotService/internal/entities"
+	"RobotService/internal/events"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	defaultAllocationInterval = 10 * time.Second
+	// Больше за проход не раскладываем: венгерский алгоритм кубический
+	maxAllocationMissions = 256
+	maxAllocationRobots   = 1024
+	// Заряд роботов без battery.level считаем полным
+	fullBattery = 100.0
+)
+
+// От имени распределителя назначения видны в аудите и событиях
+var allocatorPrincipal = &auth.Principal{Subject: "allocator", Method: "system"}
+
+// Распределитель раздаёт миссии без робота свободным роботам (без миссий в очереди и без выполняемых команд).
+// Цена пары - расстояние от робота до первой точки move плюс BatteryWeight за каждый процент недозаряда
+// (атрибут battery.level). Не подходят роботы с зарядом ниже MinBattery и роботы, чей тип не может
+// выполнить шаги: высота точек вне диапазона типа, pickup у типа без грузоподъёмности.
+// Раз в Interval вся очередь раскладывается венгерским алгоритмом, новые миссии и освободившиеся
+// роботы (robots.StatusChanged после миссии или отдельной команды перемещения) - жадно, сразу по событию
+type AllocatorService struct {
+	Missions      *MissionService
+	Robots        *RbtSrvic
+	Interval      time.Duration
+	MinBattery    float64
+	BatteryWeight float64
+	Log           *slog.Logger
+
+	mu sync.Mutex
+}
+
+// Свободный робот с тем, что нужно для цены
+type allocationCandidate struct {
+	robot     entities.Robot
+	robotType *entities.RobotType
+	battery   float64
+}
+
+// Цикл распределителя, живёт до отмены ctx
+func (srv *AllocatorService) Run(ctx context.Context) {
+	interval := srv.Interval
+	if interval <= 0 {
+		interval = defaultAllocationInterval
+	}
+	// Если события потерялись из-за переполнения, сразу делаем полный проход
+	updates, cancel := srv.Robots.Events.Subscribe(64)
+	defer cancel()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	srv.batch()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			srv.batch()
+		case event, ok := <-updates:
+			if !ok {
+				return
+			}
+			switch {
+			case event.Kind == events.KindGap:
+				srv.batch()
+			case event.Kind == events.KindStatusChanged:
+				srv.robotFreed(event.RobotID)
+			case event.Kind == events.KindMissionProgress && event.RobotID == 0 && event.MissionID != 0:
+				srv.missionArrived(event.MissionID)
+			}
+		}
+	}
+}
+
+// Вся очередь на всех свободных роботов, венгерским алгоритмом
+func (srv *AllocatorService) batch() {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	missions, err := srv.Missions.Repository.UnassignedMissions(maxAllocationMissions)
+	if err != nil {
+		srv.Log.Error("Allocator cannot read missions", "error", err.Error())
+		return
+	}
+	if len(missions) == 0 {
+		return
+	}
+	robots, err := srv.idleRobots(0)
+	if err != nil {
+		srv.Log.Error("Allocator cannot read robots", "error", err.Error())
+		return
+	}
+	srv.allocate(missions, robots, "batch", allocation.Hungarian)
+}
+
+// Новая миссия: жадно берём лучшего из свободных роботов
+func (srv *AllocatorService) missionArrived(missionID int) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	mission, err := srv.Missions.GetMission(missionID)
+	if err != nil || mission.Status != entities.MissionQueued || mission.RobotID != nil {
+		return
+	}
+	robots, err := srv.idleRobots(0)
+	if err != nil {
+		srv.Log.Error("Allocator cannot read robots", "error", err.Error())
+		return
+	}
+	srv.allocate([]entities.Mission{*mission}, robots, "stream", allocation.Greedy)
+}
+
+// Робот сменил статус: если он свободен, жадно отдаём ему лучшую миссию из очереди
+func (srv *AllocatorService) robotFreed(robotID int) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	robots, err := srv.idleRobots(robotID)
+	if err != nil || len(robots) == 0 {
+		return
+	}
+	missions, err := srv.Missions.Repository.UnassignedMissions(maxAllocationMissions)
+	if err != nil {
+		srv.Log.Error("Allocator cannot read missions", "error", err.Error())
+		return
+	}
+	srv.allocate(missions, robots, "stream", allocation.Greedy)
+}
+
+// Считаем цены, решаем и назначаем. Каждое решение пишем в лог с ценой и её составляющими
+func (srv *AllocatorService) allocate(missions []entities.Mission, robots []allocationCandidate, mode string, solve func([][]float64) []int) {
+	if len(missions) == 0 {
+		return
+	}
+	costs := make([][]float64, len(missions))
+	reasons := make([]string, len(missions))
+	for i, mission := range missions {
+		costs[i] = make([]float64, len(robots))
+		for j, candidate := range robots {
+			cost, reason := srv.cost(mission, candidate)
+			costs[i][j] = cost
+			if reason != "" && reasons[i] == "" {
+				reasons[i] = fmt.Sprintf("robot %d: %s", candidate.robot.ID, reason)
+			}
+		}
+	}
+
+	ctx := auth.WithPrincipal(context.Background(), allocatorPrincipal)
+	assigned := 0
+	for i, col := range solve(costs) {
+		mission := missions[i]
+		if col == allocation.Unassigned {
+			srv.Log.Debug("Allocator left mission queued", "mode", mode, "mission", mission.ID,
+				"candidates", len(robots), "firstRejection", reasons[i])
+			continue
+		}
+		candidate := robots[col]
+		if _, err := srv.Missions.Assign(ctx, mission.ID, candidate.robot.ID); err != nil {
+			srv.Log.Warn("Allocator cannot assign mission", "mode", mode, "mission", mission.ID, "robot", candidate.robot.ID, "error", err.Error())
+			continue
+		}
+		assigned++
+		srv.Log.Info("Allocator assigned mission", "mode", mode, "mission", mission.ID, "robot", candidate.robot.ID,
+			"cost", costs[i][col], "distance", srv.approach(mission, candidate.robot), "battery", candidate.battery)
+	}
+	srv.Log.Debug("Allocator pass", "mode", mode, "missions", len(missions), "robots", len(robots), "assigned", assigned)
+}
+
+// Цена пары, +Inf и причина - робот не подходит
+func (srv *AllocatorService) cost(mission entities.Mission, candidate allocationCandidate) (float64, string) {
+	if candidate.battery < srv.MinBattery {
+		return math.Inf(1), fmt.Sprintf("battery %.0f%% is below %.0f%%", candidate.battery, srv.MinBattery)
+	}
+	if robotType := candidate.robotType; robotType != nil {
+		for _, step := range mission.Steps {
+			if step.Kind == entities.StepMove && !robotType.AllowsAltitude(step.Target.ZCord) {
+				return math.Inf(1), fmt.Sprintf("altitude %d is outside %s of type %s", step.Target.ZCord, altitudeRange(robotType), robotType.Name)
+			}
+			if step.Kind == entities.StepPickup && robotType.PayloadCapacity <= 0 {
+				return math.Inf(1), fmt.Sprintf("type %s cannot carry payload", robotType.Name)
+			}
+		}
+	}
+	return srv.approach(mission, candidate.robot) + srv.BatteryWeight*(fullBattery-candidate.battery), ""
+}
+
+// Расстояние от робота до первой точки миссии, 0 - в миссии нет перемещений
+func (srv *AllocatorService) approach(mission entities.Mission, robot entities.Robot) float64 {
+	for _, step := range mission.Steps {
+		if step.Kind == entities.StepMove {
+			return distance(robot.Cord(), *step.Target)
+		}
+	}
+	return 0
+}
+
+// Свободные роботы: все или только robotID (0 - все)
+func (srv *AllocatorService) idleRobots(robotID int) ([]allocationCandidate, error) {
+	busy, err := srv.Missions.Repository.BusyRobots()
+	if err != nil {
+		return nil, err
+	}
+	if srv.Missions.Motion != nil {
+		for id := range srv.Missions.Motion.movingRobots() {
+			busy[id] = true
+		}
+	}
+	types := map[string]*entities.RobotType{}
+	if srv.Robots.Types != nil {
+		robotTypes, err := srv.Robots.Types.ListRobotTypes()
+		if err != nil {
+			return nil, err
+		}
+		for i := range robotTypes {
+			types[robotTypes[i].Name] = &robotTypes[i]
+		}
+	}
+
+	candidates := []allocationCandidate{}
+	add := func(robot entities.Robot) {
+		if !busy[robot.ID] {
+			candidates = append(candidates, allocationCandidate{robot: robot, robotType: types[robot.Type], battery: batteryLevel(robot)})
+		}
+	}
+	if robotID != 0 {
+		robot, err := srv.Robots.RobotRepository.GetRobotInfo(robotID)
+		if err != nil {
+			return nil, err
+		}
+		add(*robot)
+		return candidates, nil
+	}
+	errEnough := errors.New("enough robots")
+	err = srv.Robots.RobotRepository.ForEachRobot(entities.RobotFilter{}, func(robot entities.Robot) error {
+		add(robot)
+		if len(candidates) >= maxAllocationRobots {
+			return errEnough
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errEnough) {
+		return nil, err
+	}
+	return candidates, nil
+}
+
+// Заряд из атрибута battery.level в процентах, без него - полный
+func batteryLevel(robot entities.Robot) float64 {
+	battery, _ := robot.Attributes["battery"].(map[string]any)
+	switch level := battery["level"].(type) {
+	case float64:
+		return level
+	case int:
+		return float64(level)
+	}
+	return fullBattery
+}
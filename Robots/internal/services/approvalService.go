@@ -0,0 +1,199 @@
+package services
+
+import (
+	"RobotService/internal/auth"
+	"RobotService/internal/dto"
+	"RobotService/internal/entities"
+	"RobotService/internal/rbac"
+	"RobotService/internal/repositories"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+const (
+	DecisionApprove = "approve"
+	DecisionReject  = "reject"
+
+	maxApprovalsPage = 500
+)
+
+var (
+	ErrInvalidDecision = errors.New("invalid decision")
+	// Одобрять собственную заявку нельзя, в этом весь смысл
+	ErrSelfApproval = errors.New("requester cannot approve own request")
+	// У одобряющего нет права на само действие
+	ErrApprovalForbidden = errors.New("approver lacks permission for the action")
+)
+
+// Изменение не применено, а ждёт подтверждения. Хендлеры отвечают на это 202 с заявкой
+type PendingApprovalError struct {
+	Approval *entities.Approval
+}
+
+func (e *PendingApprovalError) Error() string {
+	return fmt.Sprintf("%s of robot %d is waiting for approval %d", e.Approval.Action, e.Approval.RobotID, e.Approval.ID)
+}
+
+type approvedKey struct{}
+
+// Контекст, в котором изменение выполняется по одобренной заявке и второй раз заявку не создаёт
+func withApproval(ctx context.Context, id int) context.Context {
+	return context.WithValue(ctx, approvedKey{}, id)
+}
+
+func approvalFrom(ctx context.Context) (int, bool) {
+	id, ok := ctx.Value(approvedKey{}).(int)
+	return id, ok
+}
+
+// В аудит изменения пишем, по какой заявке оно сделано
+func approvalNote(ctx context.Context) string {
+	if id, ok := approvalFrom(ctx); ok {
+		return fmt.Sprintf("approval %d", id)
+	}
+	return ""
+}
+
+// Действия, которые умеем применять по заявке
+var approvableActions = []rbac.Permission{rbac.RobotsDelete, rbac.RobotsRetype}
+
+func ValidateApprovalActions(actions []string) error {
+	for _, action := range actions {
+		if !slices.Contains(approvableActions, rbac.Permission(action)) {
+			return fmt.Errorf("approvals: action %q cannot require approval, supported: %v", action, approvableActions)
+		}
+	}
+	return nil
+}
+
+type ApprovalService struct {
+	Repository repositories.ApprovalRepository
+	// Сервис роботов, через который применяются одобренные изменения
+	Robots *RbtSrvic
+	// Чтобы проверить, что у одобряющего есть право на само действие. nil - не проверяем
+	Policy  *rbac.Engine
+	Audit   *AuditService
+	Actions []string
+	TTL     time.Duration
+}
+
+// Нужно ли подтверждение для действия в этом контексте
+func (srv *ApprovalService) required(ctx context.Context, action rbac.Permission) bool {
+	if srv == nil || !slices.Contains(srv.Actions, string(action)) {
+		return false
+	}
+	_, approved := approvalFrom(ctx)
+	return !approved
+}
+
+// Создаём заявку (или отдаём уже открытую на то же самое) и возвращаем PendingApprovalError
+func (srv *ApprovalService) request(ctx context.Context, action rbac.Permission, robotID int, newType string) error {
+	// Заявка на несуществующего робота никому не нужна
+	if _, err := srv.Robots.RobotRepository.GetRobotInfo(robotID); err != nil {
+		return err
+	}
+
+	approval, err := srv.Repository.FindPendingApproval(string(action), robotID, newType)
+	if errors.Is(err, repositories.ErrApprovalNotFound) {
+		approval, err = srv.Repository.CreateApproval(entities.Approval{
+			Action:      string(action),
+			RobotID:     robotID,
+			NewType:     newType,
+			RequestedBy: auth.Actor(ctx),
+			ExpiresAt:   time.Now().Add(srv.TTL),
+		})
+		if err == nil {
+			srv.Audit.Record(ctx, string(action), robotID, nil, nil, nil, fmt.Sprintf("approval %d requested", approval.ID))
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return &PendingApprovalError{Approval: approval}
+}
+
+func (srv *ApprovalService) GetApproval(id int) (*entities.Approval, error) {
+	if err := srv.Repository.ExpireApprovals(); err != nil {
+		return nil, err
+	}
+	return srv.Repository.GetApproval(id)
+}
+
+func (srv *ApprovalService) ListApprovals(status string, limit, offset int) ([]entities.Approval, error) {
+	if err := srv.Repository.ExpireApprovals(); err != nil {
+		return nil, err
+	}
+	return srv.Repository.ListApprovals(status, min(limit, maxApprovalsPage), offset)
+}
+
+// Решение по заявке. При одобрении сразу применяем изменение от имени одобрившего,
+// событие уходит только после этого
+func (srv *ApprovalService) Decide(ctx context.Context, id int, data dto.ApprovalDecisionDTO) (*entities.Approval, error) {
+	if data.Decision != DecisionApprove && data.Decision != DecisionReject {
+		return nil, fmt.Errorf("%w: decision must be approve or reject", ErrInvalidDecision)
+	}
+	if err := srv.Repository.ExpireApprovals(); err != nil {
+		return nil, err
+	}
+	approval, err := srv.Repository.GetApproval(id)
+	if err != nil {
+		return nil, err
+	}
+	if approval.Status != entities.ApprovalPending {
+		return nil, repositories.ErrApprovalNotPending
+	}
+
+	actor := auth.Actor(ctx)
+	status := entities.ApprovalRejected
+	// Отклонить может и сам автор, это отмена заявки
+	if data.Decision == DecisionApprove {
+		if actor == "" || actor == approval.RequestedBy {
+			return nil, ErrSelfApproval
+		}
+		target := rbac.Target{RobotID: approval.RobotID, NewType: approval.NewType}
+		if srv.Policy != nil {
+			if err = srv.Policy.Authorize(auth.FromContext(ctx), rbac.Permission(approval.Action), target); err != nil {
+				if errors.Is(err, rbac.ErrDenied) {
+					return nil, fmt.Errorf("%w: %v", ErrApprovalForbidden, err)
+				}
+				return nil, err
+			}
+		}
+		// В защитном режиме одобренная заявка всё равно не применилась бы
+		if err = srv.Robots.Lockdown.Check(ctx, rbac.Permission(approval.Action)); err != nil {
+			return nil, err
+		}
+		status = entities.ApprovalApproved
+	}
+
+	approval, err = srv.Repository.DecideApproval(id, status, actor, data.Comment)
+	if err != nil {
+		return nil, err
+	}
+	srv.Audit.Record(ctx, "approvals:"+data.Decision, approval.RobotID, nil, nil, nil,
+		fmt.Sprintf("approval %d for %s requested by %s", approval.ID, approval.Action, approval.RequestedBy))
+	if status != entities.ApprovalApproved {
+		return approval, nil
+	}
+
+	if err = srv.apply(withApproval(ctx, approval.ID), approval); err != nil {
+		if failErr := srv.Repository.FailApproval(approval.ID, err.Error()); failErr != nil {
+			return nil, failErr
+		}
+		approval.Status, approval.Error = entities.ApprovalFailed, err.Error()
+	}
+	return approval, nil
+}
+
+func (srv *ApprovalService) apply(ctx context.Context, approval *entities.Approval) error {
+	switch rbac.Permission(approval.Action) {
+	case rbac.RobotsDelete:
+		return srv.Robots.DeleteRobot(ctx, approval.RobotID)
+	case rbac.RobotsRetype:
+		return srv.Robots.ChangeRobotType(ctx, dto.ChangeTypeDTO{ID: approval.RobotID, Type: approval.NewType})
+	}
+	return fmt.Errorf("action %s cannot be approved", approval.Action)
+}
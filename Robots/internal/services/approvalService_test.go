@@ -0,0 +1,218 @@
+package services
+
+import (
+	"RobotService/internal/config"
+	"RobotService/internal/dto"
+	"RobotService/internal/entities"
+	"RobotService/internal/events"
+	"RobotService/internal/rbac"
+	"RobotService/internal/repositories"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestApprovalRequired(t *testing.T) {
+	var none *ApprovalService
+	if none.required(context.Background(), rbac.RobotsDelete) {
+		t.Fatal("nil service requires approval")
+	}
+	srv := &ApprovalService{Actions: []string{string(rbac.RobotsDelete)}}
+	if !srv.required(context.Background(), rbac.RobotsDelete) {
+		t.Fatal("delete does not require approval")
+	}
+	if srv.required(context.Background(), rbac.RobotsRetype) {
+		t.Fatal("retype requires approval")
+	}
+	// Изменение по одобренной заявке вторую заявку не создаёт
+	if srv.required(withApproval(context.Background(), 12), rbac.RobotsDelete) {
+		t.Fatal("approved change requires approval")
+	}
+	if note := approvalNote(withApproval(context.Background(), 12)); note != "approval 12" {
+		t.Fatalf("note = %q", note)
+	}
+}
+
+func TestValidateApprovalActions(t *testing.T) {
+	if err := ValidateApprovalActions([]string{"robots:delete", "robots:retype"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateApprovalActions([]string{"robots:move"}); err == nil {
+		t.Fatal("robots:move accepted")
+	}
+}
+
+func TestDecideRejectsUnknownDecision(t *testing.T) {
+	// До базы дело не доходит
+	_, err := (&ApprovalService{}).Decide(context.Background(), 1, dto.ApprovalDecisionDTO{Decision: "maybe"})
+	if !errors.Is(err, ErrInvalidDecision) {
+		t.Fatalf("err = %v, want ErrInvalidDecision", err)
+	}
+}
+
+func newTestApprovals(t *testing.T, f *testFixture, ttl time.Duration) *ApprovalService {
+	t.Helper()
+	policy, err := rbac.NewEngine(config.RBAC{Roles: map[string]config.Role{
+		"operator": {Permissions: []string{"robots:read", "robots:move"}},
+		"admin":    {Inherits: []string{"operator"}, Permissions: []string{"robots:delete", "robots:retype"}},
+	}}, nil, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	approvals := &ApprovalService{
+		Repository: repositories.ApprovalRepository{DataBase: f.db},
+		Robots:     f.robots,
+		Policy:     policy,
+		Audit:      f.robots.Audit,
+		Actions:    []string{string(rbac.RobotsDelete), string(rbac.RobotsRetype)},
+		TTL:        ttl,
+	}
+	f.robots.Approvals = approvals
+	return approvals
+}
+
+func pendingApproval(t *testing.T, err error) *entities.Approval {
+	t.Helper()
+	var pending *PendingApprovalError
+	if !errors.As(err, &pending) {
+		t.Fatalf("err = %v, want *PendingApprovalError", err)
+	}
+	if pending.Approval.Status != entities.ApprovalPending {
+		t.Fatalf("approval = %+v", pending.Approval)
+	}
+	return pending.Approval
+}
+
+func TestApprovalFlow(t *testing.T) {
+	f := newTestFixture(t)
+	approvals := newTestApprovals(t, f, time.Hour)
+	robot := f.createRobot(t, entities.Robot{Name: "approval-delete"})
+	ch, unsubscribe := f.robots.Events.Subscribe(16)
+	defer unsubscribe()
+
+	alice := actorContext("alice", "admin")
+	approval := pendingApproval(t, f.robots.DeleteRobot(alice, robot.ID))
+	if approval.RequestedBy != "jwt:alice" || approval.Action != string(rbac.RobotsDelete) || approval.RobotID != robot.ID {
+		t.Fatalf("approval = %+v", approval)
+	}
+	// Повторный запрос отдаёт ту же открытую заявку
+	if again := pendingApproval(t, f.robots.DeleteRobot(alice, robot.ID)); again.ID != approval.ID {
+		t.Fatalf("second request created approval %d, want %d", again.ID, approval.ID)
+	}
+	if _, err := f.robots.RobotRepository.GetRobotInfo(robot.ID); err != nil {
+		t.Fatalf("robot deleted before approval: %v", err)
+	}
+
+	approve := dto.ApprovalDecisionDTO{Decision: DecisionApprove}
+	if _, err := approvals.Decide(alice, approval.ID, approve); !errors.Is(err, ErrSelfApproval) {
+		t.Fatalf("self approval: err = %v, want ErrSelfApproval", err)
+	}
+	if _, err := approvals.Decide(actorContext("bob", "operator"), approval.ID, approve); !errors.Is(err, ErrApprovalForbidden) {
+		t.Fatalf("operator approval: err = %v, want ErrApprovalForbidden", err)
+	}
+	// Пока заявку не одобрили, событий об удалении нет
+	select {
+	case event := <-ch:
+		t.Fatalf("event before approval: %+v", event)
+	default:
+	}
+
+	decided, err := approvals.Decide(actorContext("carol", "admin"), approval.ID, approve)
+	if err != nil {
+		t.Fatalf("approve: %v", err)
+	}
+	if decided.Status != entities.ApprovalApproved || decided.DecidedBy != "jwt:carol" {
+		t.Fatalf("decided = %+v", decided)
+	}
+	if _, err = f.robots.RobotRepository.GetRobotInfo(robot.ID); !errors.Is(err, repositories.ErrRobotNotFound) {
+		t.Fatalf("robot after approval: err = %v, want ErrRobotNotFound", err)
+	}
+	select {
+	case event := <-ch:
+		if event.Kind != events.KindDeleted || event.RobotID != robot.ID || event.Actor != "jwt:carol" {
+			t.Fatalf("event = %+v", event)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no event after approval")
+	}
+
+	// Решение уже принято
+	if _, err = approvals.Decide(actorContext("dave", "admin"), approval.ID, approve); !errors.Is(err, repositories.ErrApprovalNotPending) {
+		t.Fatalf("second decision: err = %v, want ErrApprovalNotPending", err)
+	}
+}
+
+func TestApprovalRejectedByRequester(t *testing.T) {
+	f := newTestFixture(t)
+	approvals := newTestApprovals(t, f, time.Hour)
+	robot := f.createRobot(t, entities.Robot{Name: "approval-reject"})
+
+	alice := actorContext("alice", "admin")
+	approval := pendingApproval(t, f.robots.DeleteRobot(alice, robot.ID))
+	// Отклонить собственную заявку можно, это отмена
+	decided, err := approvals.Decide(alice, approval.ID, dto.ApprovalDecisionDTO{Decision: DecisionReject, Comment: "передумала"})
+	if err != nil {
+		t.Fatalf("reject: %v", err)
+	}
+	if decided.Status != entities.ApprovalRejected || decided.Comment != "передумала" {
+		t.Fatalf("decided = %+v", decided)
+	}
+	if _, err = f.robots.RobotRepository.GetRobotInfo(robot.ID); err != nil {
+		t.Fatalf("rejected delete removed the robot: %v", err)
+	}
+}
+
+func TestApprovalExpires(t *testing.T) {
+	f := newTestFixture(t)
+	// Заявка истекает сразу при создании
+	approvals := newTestApprovals(t, f, -time.Minute)
+	robot := f.createRobot(t, entities.Robot{Name: "approval-expire"})
+
+	approval := pendingApproval(t, f.robots.DeleteRobot(actorContext("alice", "admin"), robot.ID))
+	got, err := approvals.GetApproval(approval.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != entities.ApprovalExpired || got.DecidedAt == nil || !got.DecidedAt.Equal(got.ExpiresAt) {
+		t.Fatalf("approval = %+v, want expired", got)
+	}
+	_, err = approvals.Decide(actorContext("carol", "admin"), approval.ID, dto.ApprovalDecisionDTO{Decision: DecisionApprove})
+	if !errors.Is(err, repositories.ErrApprovalNotPending) {
+		t.Fatalf("approve expired: err = %v, want ErrApprovalNotPending", err)
+	}
+}
+
+func TestApprovalApplyFails(t *testing.T) {
+	f := newTestFixture(t)
+	approvals := newTestApprovals(t, f, time.Hour)
+	robot := f.createRobot(t, entities.Robot{Name: "approval-fail"})
+	target := f.robotType + "-next"
+	f.createType(t, dto.CreateRobotTypeDTO{Name: target})
+
+	approval := pendingApproval(t, f.robots.ChangeRobotType(actorContext("alice", "admin"), dto.ChangeTypeDTO{ID: robot.ID, Type: target}))
+	if approval.NewType != target {
+		t.Fatalf("approval = %+v", approval)
+	}
+	// Пока заявка ждала, тип убрали из реестра
+	if err := f.robots.Types.DeleteRobotType(context.Background(), target); err != nil {
+		t.Fatal(err)
+	}
+
+	decided, err := approvals.Decide(actorContext("carol", "admin"), approval.ID, dto.ApprovalDecisionDTO{Decision: DecisionApprove})
+	if err != nil {
+		t.Fatalf("approve: %v", err)
+	}
+	if decided.Status != entities.ApprovalFailed || decided.Error == "" {
+		t.Fatalf("decided = %+v, want failed", decided)
+	}
+	stored, err := approvals.GetApproval(approval.ID)
+	if err != nil || stored.Status != entities.ApprovalFailed || stored.Error != decided.Error {
+		t.Fatalf("stored = %+v, err = %v", stored, err)
+	}
+	if current, _ := f.robots.RobotRepository.GetRobotInfo(robot.ID); current == nil || current.Type != f.robotType {
+		t.Fatalf("robot = %+v, want type %s", current, f.robotType)
+	}
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"RobotService/internal/auth"
+	"RobotService/internal/entities"
+	"RobotService/internal/repositories"
+	"RobotService/internal/requestinfo"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+)
+
+const (
+	AuditSuccess = "success"
+	AuditError   = "error"
+	AuditDenied  = "denied"
+
+	maxAuditPage = 1000
+)
+
+var ErrInvalidAuditFilter = errors.New("invalid audit filter")
+
+// Журнал аудита. Запись не должна ломать саму операцию: если база не приняла запись, только логируем
+type AuditService struct {
+	Repository repositories.AuditRepository
+}
+
+// Итог изменения робота. robotID = 0 - операция не про одного робота (импорт)
+func (srv *AuditService) Record(ctx context.Context, action string, robotID int, before, after *entities.Robot, opErr error, details string) {
+	result, errText := AuditSuccess, ""
+	if opErr != nil {
+		result, errText = AuditError, opErr.Error()
+	}
+	srv.append(ctx, entities.AuditRecord{
+		Action: action, Before: before, After: after, Result: result, Error: errText, Details: details,
+	}, robotID)
+}
+
+// Отказ политики доступа
+func (srv *AuditService) RecordDenied(ctx context.Context, action string, robotID int, reason string) {
+	srv.append(ctx, entities.AuditRecord{Action: action, Result: AuditDenied, Error: reason}, robotID)
+}
+
+func (srv *AuditService) append(ctx context.Context, record entities.AuditRecord, robotID int) {
+	if srv == nil {
+		return
+	}
+	info := requestinfo.From(ctx)
+	record.Actor = auth.Actor(ctx)
+	record.SourceIP = info.SourceIP
+	record.RequestID = info.ID
+	if robotID != 0 {
+		record.RobotID = &robotID
+	}
+	if err := srv.Repository.AppendAudit(record); err != nil {
+		log.Printf("Не удалось записать аудит %s (request %s): %v", record.Action, record.RequestID, err)
+	}
+}
+
+func (srv *AuditService) ListAudit(filter entities.AuditFilter, limit, offset int) ([]entities.AuditRecord, error) {
+	if err := validateAuditFilter(filter); err != nil {
+		return nil, err
+	}
+	return srv.Repository.ListAudit(filter, min(limit, maxAuditPage), offset)
+}
+
+// Выгрузка в JSON Lines: одна запись - одна строка
+func (srv *AuditService) ExportAudit(w io.Writer, filter entities.AuditFilter) error {
+	if err := validateAuditFilter(filter); err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	return srv.Repository.ForEachAudit(filter, func(record entities.AuditRecord) error {
+		return enc.Encode(record)
+	})
+}
+
+func validateAuditFilter(filter entities.AuditFilter) error {
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		return fmt.Errorf("%w: from must be before to", ErrInvalidAuditFilter)
+	}
+	return nil
+}
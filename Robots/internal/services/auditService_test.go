@@ -0,0 +1,124 @@
+package services
+
+import (
+	"RobotService/internal/auth"
+	"RobotService/internal/config"
+	"RobotService/internal/entities"
+	"RobotService/internal/rbac"
+	"RobotService/internal/requestinfo"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestAuditFilterValidation(t *testing.T) {
+	srv := &AuditService{}
+	from := time.Now()
+	for _, to := range []time.Time{from, from.Add(-time.Second)} {
+		filter := entities.AuditFilter{From: &from, To: &to}
+		if _, err := srv.ListAudit(filter, 10, 0); !errors.Is(err, ErrInvalidAuditFilter) {
+			t.Errorf("ListAudit(to %v): err = %v", to, err)
+		}
+		if err := srv.ExportAudit(io.Discard, filter); !errors.Is(err, ErrInvalidAuditFilter) {
+			t.Errorf("ExportAudit(to %v): err = %v", to, err)
+		}
+	}
+	// Без журнала запись просто пропускается
+	var none *AuditService
+	none.Record(context.Background(), "robots:move", 1, nil, nil, nil, "")
+	none.RecordDenied(context.Background(), "robots:move", 1, "http")
+}
+
+func TestAuditRecords(t *testing.T) {
+	f := newTestFixture(t)
+	audit := f.robots.Audit
+	subject := "audit-" + strconv.Itoa(f.base)
+	ctx := requestinfo.With(actorContext(subject, "operator"), requestinfo.Info{ID: "req-1", SourceIP: "203.0.113.7"})
+	robotID := f.base
+
+	before := &entities.Robot{ID: robotID, Name: "before", XCord: 1}
+	after := &entities.Robot{ID: robotID, Name: "after", XCord: 2}
+	audit.Record(ctx, string(rbac.RobotsMove), robotID, before, after, nil, "moved")
+	audit.Record(ctx, string(rbac.RobotsDelete), robotID, before, nil, errors.New("robot is busy"), "")
+	audit.Record(ctx, string(rbac.RobotsImport), 0, nil, nil, nil, "imported 3")
+
+	// Отказ политики пишет сам движок rbac
+	policy, err := rbac.NewEngine(config.RBAC{Roles: map[string]config.Role{"operator": {Permissions: []string{"robots:read"}}}},
+		nil, audit, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = policy.Check(ctx, rbac.RobotsDelete, rbac.Target{RobotID: robotID}, "http", "DELETE /robots/1"); !errors.Is(err, rbac.ErrDenied) {
+		t.Fatalf("Check: err = %v, want ErrDenied", err)
+	}
+
+	records, err := audit.ListAudit(entities.AuditFilter{Actor: auth.Actor(ctx)}, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 4 {
+		t.Fatalf("%d records: %+v", len(records), records)
+	}
+	// Новые первыми
+	denied, imported, failed, moved := records[0], records[1], records[2], records[3]
+	for _, record := range records {
+		if record.Actor != "jwt:"+subject || record.SourceIP != "203.0.113.7" || record.RequestID != "req-1" || record.Time.IsZero() {
+			t.Fatalf("record %+v lost the request context", record)
+		}
+	}
+	if moved.Action != string(rbac.RobotsMove) || moved.Result != AuditSuccess || moved.Error != "" || moved.Details != "moved" ||
+		moved.RobotID == nil || *moved.RobotID != robotID || moved.Before == nil || moved.Before.Name != "before" || moved.After == nil || moved.After.XCord != 2 {
+		t.Fatalf("success record = %+v", moved)
+	}
+	if failed.Result != AuditError || failed.Error != "robot is busy" || failed.After != nil {
+		t.Fatalf("error record = %+v", failed)
+	}
+	if imported.RobotID != nil || imported.Details != "imported 3" {
+		t.Fatalf("record without robot = %+v", imported)
+	}
+	if denied.Action != string(rbac.RobotsDelete) || denied.Result != AuditDenied || denied.Error != "http DELETE /robots/1" ||
+		denied.RobotID == nil || *denied.RobotID != robotID || denied.Before != nil {
+		t.Fatalf("denied record = %+v", denied)
+	}
+
+	byRobot, err := audit.ListAudit(entities.AuditFilter{Actor: auth.Actor(ctx), RobotID: &robotID}, 10, 0)
+	if err != nil || len(byRobot) != 3 {
+		t.Fatalf("by robot: %d records, err = %v", len(byRobot), err)
+	}
+	page, err := audit.ListAudit(entities.AuditFilter{Actor: auth.Actor(ctx)}, 2, 1)
+	if err != nil || len(page) != 2 || page[0].ID != imported.ID {
+		t.Fatalf("page = %+v, err = %v", page, err)
+	}
+
+	// Выгрузка - по записи на строку, старые первыми
+	var out bytes.Buffer
+	if err = audit.ExportAudit(&out, entities.AuditFilter{Actor: auth.Actor(ctx)}); err != nil {
+		t.Fatal(err)
+	}
+	var exported []entities.AuditRecord
+	scanner := bufio.NewScanner(&out)
+	for scanner.Scan() {
+		var record entities.AuditRecord
+		if err = json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("line %q: %v", scanner.Text(), err)
+		}
+		exported = append(exported, record)
+	}
+	if len(exported) != 4 || exported[0].ID != moved.ID || exported[3].Result != AuditDenied {
+		t.Fatalf("exported = %+v", exported)
+	}
+
+	// Журнал только дописывается
+	for _, query := range []string{"UPDATE audit_log SET result = 'success' WHERE id = $1", "DELETE FROM audit_log WHERE id = $1"} {
+		if _, err = f.db.Exec(context.Background(), query, failed.ID); err == nil {
+			t.Fatalf("%q changed the audit log", query)
+		}
+	}
+}
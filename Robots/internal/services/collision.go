@@ -0,0 +1,154 @@
+package services
+
+import (
+	"RobotService/internal/entities"
+	"RobotService/internal/repositories"
+	"fmt"
+	"math"
+	"sync"
+)
+
+// Перемещение остановлено: робот подошёл бы к другому ближе безопасного расстояния
+type CollisionError struct {
+	RobotID int `json:"robotId"`
+	// Робот, который мешает, в его текущем состоянии
+	Blocking entities.Robot `json:"blocking"`
+	// Насколько близко роботы сошлись бы на пути и сколько нужно
+	Distance       float64 `json:"distance"`
+	SafetyDistance float64 `json:"safetyDistance"`
+	// Конфликт в самой точке назначения, а не только по дороге к ней
+	AtTarget bool `json:"atTarget"`
+}
+
+func (e *CollisionError) Error() string {
+	where := "on the way"
+	if e.AtTarget {
+		where = "at the target"
+	}
+	return fmt.Sprintf("robot %d would come within %.2f of robot %d %s, safety distance is %.2f",
+		e.RobotID, e.Distance, e.Blocking.ID, where, e.SafetyDistance)
+}
+
+// Проверка столкновений по индексу позиций. Роботы не сближаются больше, чем на сумму радиусов
+// безопасности своих типов, и никогда не встают в одну точку. Проверяется отрезок от старой позиции
+// до новой, остальные роботы считаются стоящими на месте.
+// Проверка и запись идут под одной блокировкой, так что два перемещения в этом процессе друг друга не пропустят
+type CollisionChecker struct {
+	Repository repositories.RobotRepositories
+	Types      *RobotTypeService
+
+	mu sync.Mutex
+}
+
+func (c *CollisionChecker) lock() {
+	if c != nil {
+		c.mu.Lock()
+	}
+}
+
+func (c *CollisionChecker) unlock() {
+	if c != nil {
+		c.mu.Unlock()
+	}
+}
+
+// Радиусы безопасности по типам и самый большой из них. Без реестра все радиусы нулевые
+func (c *CollisionChecker) safetyRadii() (map[string]float64, float64, error) {
+	radii := map[string]float64{}
+	if c.Types == nil {
+		return radii, 0, nil
+	}
+	robotTypes, err := c.Types.ListRobotTypes()
+	if err != nil {
+		return nil, 0, err
+	}
+	largest := 0.0
+	for _, robotType := range robotTypes {
+		radii[robotType.Name] = robotType.SafetyRadius
+		largest = max(largest, robotType.SafetyRadius)
+	}
+	return radii, largest, nil
+}
+
+// Проверяем путь робота из from в to (для нового робота from == to).
+// exclude - роботы, которые двигаются вместе с ним и друг другу не мешают
+func (c *CollisionChecker) check(robot entities.Robot, from, to entities.RobotCord, exclude map[int]bool) error {
+	if c == nil {
+		return nil
+	}
+	radii, largest, err := c.safetyRadii()
+	if err != nil {
+		return err
+	}
+	own := radii[robot.Type]
+
+	// Кандидаты - всё в шаре вокруг середины отрезка, куда заведомо попадает любой, кто может помешать
+	dx, dy, dz := float64(to.XCord-from.XCord), float64(to.YCord-from.YCord), float64(to.ZCord-from.ZCord)
+	length := math.Sqrt(dx*dx + dy*dy + dz*dz)
+	center := entities.RobotCord{
+		XCord: from.XCord + (to.XCord-from.XCord)/2,
+		YCord: from.YCord + (to.YCord-from.YCord)/2,
+		ZCord: from.ZCord + (to.ZCord-from.ZCord)/2,
+	}
+	neighbors := c.Repository.Index.Nearest(center, length/2+2+own+largest, math.MaxInt)
+	ids := make([]int, 0, len(neighbors))
+	for _, neighbor := range neighbors {
+		if neighbor.ID != robot.ID && !exclude[neighbor.ID] {
+			ids = append(ids, neighbor.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	others, err := c.Repository.GetRobots(ids)
+	if err != nil {
+		return err
+	}
+	if blocking := firstCollision(robot, from, to, others, radii); blocking != nil {
+		return blocking
+	}
+	return nil
+}
+
+// Первый по ходу робот из others, к которому robot на пути from-to подойдёт ближе суммы радиусов, или nil
+func firstCollision(robot entities.Robot, from, to entities.RobotCord, others []entities.Robot, radii map[string]float64) *CollisionError {
+	own := radii[robot.Type]
+	var blocking *CollisionError
+	firstHit := math.Inf(1)
+	for _, other := range others {
+		safety := own + radii[other.Type]
+		t, closest := closestApproach(from, to, other.Cord())
+		start := distance(from, other.Cord())
+		end := distance(to, other.Cord())
+		tooClose := func(d float64) bool { return d < safety || d == 0 }
+		if !tooClose(closest) {
+			continue
+		}
+		// Уже стоящим слишком близко разрешаем расходиться, но не сближаться дальше
+		if robot.ID != 0 && tooClose(start) && closest >= start {
+			continue
+		}
+		if t < firstHit {
+			firstHit = t
+			blocking = &CollisionError{RobotID: robot.ID, Blocking: other, Distance: closest, SafetyDistance: safety, AtTarget: tooClose(end)}
+		}
+	}
+	return blocking
+}
+
+// Точка отрезка from-to, ближайшая к point: доля пути t в [0, 1] и расстояние
+func closestApproach(from, to, point entities.RobotCord) (float64, float64) {
+	dx, dy, dz := float64(to.XCord-from.XCord), float64(to.YCord-from.YCord), float64(to.ZCord-from.ZCord)
+	px, py, pz := float64(point.XCord-from.XCord), float64(point.YCord-from.YCord), float64(point.ZCord-from.ZCord)
+	t := 0.0
+	if length2 := dx*dx + dy*dy + dz*dz; length2 > 0 {
+		t = min(max((px*dx+py*dy+pz*dz)/length2, 0), 1)
+	}
+	ex, ey, ez := px-t*dx, py-t*dy, pz-t*dz
+	return t, math.Sqrt(ex*ex + ey*ey + ez*ez)
+}
+
+func distance(a, b entities.RobotCord) float64 {
+	dx, dy, dz := float64(a.XCord-b.XCord), float64(a.YCord-b.YCord), float64(a.ZCord-b.ZCord)
+	return math.Sqrt(dx*dx + dy*dy + dz*dz)
+}
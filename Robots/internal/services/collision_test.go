@@ -0,0 +1,139 @@
+package services
+
+import (
+	"RobotService/internal/dto"
+	"RobotService/internal/entities"
+	"RobotService/internal/repositories"
+	"RobotService/internal/spatial"
+	"errors"
+	"math"
+	"testing"
+)
+
+func at(x, y, z int) entities.RobotCord {
+	return entities.RobotCord{XCord: x, YCord: y, ZCord: z}
+}
+
+func TestFirstCollision(t *testing.T) {
+	radii := map[string]float64{"rover": 2, "drone": 1}
+	mover := entities.Robot{ID: 1, Type: "rover"}
+	from, to := at(0, 0, 0), at(10, 0, 0)
+
+	tests := []struct {
+		name     string
+		robot    entities.Robot
+		from, to entities.RobotCord
+		others   []entities.Robot
+		blocking int
+		distance float64
+		atTarget bool
+	}{
+		{name: "blocker on the path", others: []entities.Robot{{ID: 2, Type: "rover", XCord: 5}}, blocking: 2, distance: 0},
+		{name: "blocker beside the path", others: []entities.Robot{{ID: 2, Type: "rover", XCord: 5, YCord: 5}}},
+		// Ровно на безопасном расстоянии можно, ближе - нет
+		{name: "exactly at the safety distance", others: []entities.Robot{{ID: 2, Type: "rover", XCord: 5, YCord: 4}}},
+		{name: "just inside the safety distance", others: []entities.Robot{{ID: 2, Type: "rover", XCord: 5, YCord: 3}}, blocking: 2, distance: 3},
+		{name: "radii of both types", others: []entities.Robot{{ID: 2, Type: "drone", XCord: 5, YCord: 3}}},
+		{name: "vertical clearance", others: []entities.Robot{{ID: 2, Type: "drone", XCord: 5, ZCord: 3}}},
+		{name: "at the target", others: []entities.Robot{{ID: 2, Type: "rover", XCord: 12}}, blocking: 2, distance: 2, atTarget: true},
+		{name: "beyond the target", others: []entities.Robot{{ID: 2, Type: "rover", XCord: 15}}},
+		// Кто уже стоит слишком близко, может отходить, но не подходить ближе
+		{name: "moving away from a close robot", others: []entities.Robot{{ID: 2, Type: "rover", XCord: -3}}},
+		{name: "moving past a close robot", others: []entities.Robot{{ID: 2, Type: "rover", XCord: 3}}, blocking: 2, distance: 0},
+		{name: "first blocker on the way", others: []entities.Robot{{ID: 2, Type: "rover", XCord: 8}, {ID: 3, Type: "rover", XCord: 4, YCord: 1}}, blocking: 3, distance: 1},
+		{name: "unknown types meet only in one point", others: []entities.Robot{{ID: 2, Type: "cart", XCord: 5, YCord: 1}}, robot: entities.Robot{ID: 1, Type: "cart"}},
+		{name: "unknown types in one point", others: []entities.Robot{{ID: 2, Type: "cart", XCord: 10}}, robot: entities.Robot{ID: 1, Type: "cart"}, blocking: 2, distance: 0, atTarget: true},
+		// Новому роботу отойти некуда, соседство внутри радиуса сразу конфликт
+		{name: "new robot next to another", robot: entities.Robot{Type: "rover"}, from: at(0, 0, 0), to: at(0, 0, 0),
+			others: []entities.Robot{{ID: 2, Type: "rover", YCord: 3}}, blocking: 2, distance: 3, atTarget: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			robot := tt.robot
+			if robot.Type == "" {
+				robot = mover
+			}
+			start, end := tt.from, tt.to
+			if start == (entities.RobotCord{}) && end == (entities.RobotCord{}) && robot.ID != 0 {
+				start, end = from, to
+			}
+			got := firstCollision(robot, start, end, tt.others, radii)
+			if tt.blocking == 0 {
+				if got != nil {
+					t.Fatalf("collision %v, want none", got)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatalf("no collision, want robot %d", tt.blocking)
+			}
+			if got.Blocking.ID != tt.blocking || math.Abs(got.Distance-tt.distance) > 1e-9 || got.AtTarget != tt.atTarget || got.RobotID != robot.ID {
+				t.Fatalf("collision %+v, want robot %d at %v, at target %v", got, tt.blocking, tt.distance, tt.atTarget)
+			}
+		})
+	}
+}
+
+func TestClosestApproach(t *testing.T) {
+	tests := []struct {
+		point    entities.RobotCord
+		t, dist  float64
+		from, to entities.RobotCord
+	}{
+		{point: at(5, 3, 0), t: 0.5, dist: 3, from: at(0, 0, 0), to: at(10, 0, 0)},
+		{point: at(-4, 3, 0), t: 0, dist: 5, from: at(0, 0, 0), to: at(10, 0, 0)},
+		{point: at(13, 0, 4), t: 1, dist: 5, from: at(0, 0, 0), to: at(10, 0, 0)},
+		{point: at(3, 4, 0), t: 0, dist: 5, from: at(0, 0, 0), to: at(0, 0, 0)},
+	}
+	for _, tt := range tests {
+		gotT, gotDist := closestApproach(tt.from, tt.to, tt.point)
+		if math.Abs(gotT-tt.t) > 1e-9 || math.Abs(gotDist-tt.dist) > 1e-9 {
+			t.Errorf("closestApproach(%v, %v, %v) = %v, %v, want %v, %v", tt.from, tt.to, tt.point, gotT, gotDist, tt.t, tt.dist)
+		}
+	}
+}
+
+// Роботы, которые едут вместе, и сам робот отсеиваются по индексу, до базы дело не доходит
+func TestCheckIgnoresTogether(t *testing.T) {
+	index := spatial.New()
+	index.Upsert(1, at(0, 0, 0))
+	index.Upsert(2, at(5, 0, 0))
+	index.Upsert(3, at(10, 0, 0))
+	checker := &CollisionChecker{Repository: repositories.RobotRepositories{Index: index}}
+
+	robot := entities.Robot{ID: 1}
+	if err := checker.check(robot, at(0, 0, 0), at(10, 0, 0), map[int]bool{2: true, 3: true}); err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	var none *CollisionChecker
+	if err := none.check(robot, at(0, 0, 0), at(10, 0, 0), nil); err != nil {
+		t.Fatalf("nil checker: %v", err)
+	}
+}
+
+func TestCheckWithRegistry(t *testing.T) {
+	f := newTestFixture(t)
+	wide := f.robotType + "-wide"
+	f.createType(t, dto.CreateRobotTypeDTO{Name: wide, SafetyRadius: 2})
+	mover := f.createRobot(t, entities.Robot{Name: "collision-mover", Type: wide})
+	beside := f.createRobot(t, entities.Robot{Name: "collision-beside", XCord: 5, YCord: 2})
+	ahead := f.createRobot(t, entities.Robot{Name: "collision-ahead", XCord: 15, YCord: 1})
+	checker := f.robots.Collisions
+
+	// Рядом с путём ровно на радиусе: можно
+	if err := checker.check(*mover, mover.Cord(), f.at(10, 0, 0), nil); err != nil {
+		t.Fatalf("move past the boundary: %v", err)
+	}
+	var collision *CollisionError
+	err := checker.check(*mover, mover.Cord(), f.at(20, 0, 0), nil)
+	if !errors.As(err, &collision) || collision.Blocking.ID != ahead.ID || collision.SafetyDistance != 2 || collision.AtTarget {
+		t.Fatalf("move through: err = %v, want collision with %d", err, ahead.ID)
+	}
+	if err = checker.check(*mover, mover.Cord(), f.at(20, 0, 0), map[int]bool{ahead.ID: true}); err != nil {
+		t.Fatalf("move together with the blocker: %v", err)
+	}
+	err = checker.check(*mover, mover.Cord(), f.at(5, 1, 0), nil)
+	if !errors.As(err, &collision) || collision.Blocking.ID != beside.ID || !collision.AtTarget {
+		t.Fatalf("move next to a robot: err = %v, want collision with %d at the target", err, beside.ID)
+	}
+}
@@ -0,0 +1,233 @@
+package services
+
+import (
+	"RobotService/internal/auth"
+	"RobotService/internal/dto"
+	"RobotService/internal/entities"
+	"RobotService/internal/events"
+	"RobotService/internal/prometheusinfo"
+	"RobotService/internal/rbac"
+	"RobotService/internal/repositories"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	maxFleetName = 128
+	// Сколько роботов можно добавить одним запросом
+	maxFleetMembersBatch = 1000
+)
+
+var (
+	ErrInvalidFleet = errors.New("invalid fleet")
+	// Действие требует подтверждения по каждому роботу, групповой командой его не обойти
+	ErrFleetNeedsApproval = errors.New("action requires approval, apply it to robots one by one")
+)
+
+type FleetService struct {
+	Repository repositories.FleetRepository
+	// Через сервис роботов идут проверки, кэш, события и аудит групповых команд
+	Robots *RbtSrvic
+	Audit  *AuditService
+}
+
+func (srv *FleetService) CreateFleet(ctx context.Context, data dto.FleetDTO) (*entities.Fleet, error) {
+	fleet, err := fleetFromDTO(0, data)
+	if err != nil {
+		return nil, err
+	}
+	created, err := srv.Repository.CreateFleet(fleet)
+	if err != nil {
+		return nil, err
+	}
+	srv.Audit.Record(ctx, string(rbac.FleetsWrite), 0, nil, nil, nil, fmt.Sprintf("created fleet %d %s", created.ID, created.Name))
+	return created, nil
+}
+
+func (srv *FleetService) GetFleet(id int) (*entities.Fleet, error) {
+	return srv.Repository.GetFleet(id)
+}
+
+func (srv *FleetService) ListFleets() ([]entities.Fleet, error) {
+	return srv.Repository.ListFleets()
+}
+
+func (srv *FleetService) UpdateFleet(ctx context.Context, id int, data dto.FleetDTO) (*entities.Fleet, error) {
+	fleet, err := fleetFromDTO(id, data)
+	if err != nil {
+		return nil, err
+	}
+	updated, err := srv.Repository.UpdateFleet(fleet)
+	if err != nil {
+		return nil, err
+	}
+	srv.Audit.Record(ctx, string(rbac.FleetsWrite), 0, nil, nil, nil, fmt.Sprintf("updated fleet %d", id))
+	return updated, nil
+}
+
+func (srv *FleetService) DeleteFleet(ctx context.Context, id int) error {
+	if err := srv.Repository.DeleteFleet(id); err != nil {
+		return err
+	}
+	srv.Audit.Record(ctx, string(rbac.FleetsWrite), 0, nil, nil, nil, fmt.Sprintf("deleted fleet %d", id))
+	return nil
+}
+
+func fleetFromDTO(id int, data dto.FleetDTO) (entities.Fleet, error) {
+	fleet := entities.Fleet{ID: id, Name: strings.TrimSpace(data.Name), Description: data.Description, ParentID: data.ParentID}
+	switch {
+	case fleet.Name == "" || len(fleet.Name) > maxFleetName:
+		return fleet, fmt.Errorf("%w: name must be 1-%d characters", ErrInvalidFleet, maxFleetName)
+	case fleet.ParentID != nil && *fleet.ParentID == id:
+		return fleet, repositories.ErrFleetCycle
+	}
+	return fleet, nil
+}
+
+func (srv *FleetService) AddMembers(ctx context.Context, id int, robotIDs []int) (*entities.Fleet, error) {
+	if len(robotIDs) == 0 || len(robotIDs) > maxFleetMembersBatch {
+		return nil, fmt.Errorf("%w: robotIds must contain 1-%d ids", ErrInvalidFleet, maxFleetMembersBatch)
+	}
+	// Флот проверяем отдельно, иначе внешний ключ не скажет, кого именно не нашлось
+	if _, err := srv.Repository.GetFleet(id); err != nil {
+		return nil, err
+	}
+	if err := srv.Repository.AddMembers(id, robotIDs); err != nil {
+		return nil, err
+	}
+	srv.Audit.Record(ctx, string(rbac.FleetsWrite), 0, nil, nil, nil, fmt.Sprintf("fleet %d: added robots %v", id, robotIDs))
+	return srv.Repository.GetFleet(id)
+}
+
+func (srv *FleetService) RemoveMember(ctx context.Context, id, robotID int) error {
+	if err := srv.Repository.RemoveMember(id, robotID); err != nil {
+		return err
+	}
+	srv.Audit.Record(ctx, string(rbac.FleetsWrite), robotID, nil, nil, nil, fmt.Sprintf("fleet %d: removed robot", id))
+	return nil
+}
+
+// Роботы флота, recursive - вместе с вложенными флотами
+func (srv *FleetService) FleetRobots(id int, recursive bool) ([]entities.Robot, error) {
+	if _, err := srv.Repository.GetFleet(id); err != nil {
+		return nil, err
+	}
+	return srv.Repository.FleetRobots(id, recursive)
+}
+
+// Сдвигаем всех роботов флота. Если хоть один вылетает за допустимую высоту своего типа
+// или за пределы карты, не двигаем никого
+func (srv *FleetService) MoveFleet(ctx context.Context, id int, offset dto.FleetMoveDTO) (*entities.FleetOperation, error) {
+	positionCheck, err := srv.Robots.World.positionCheck()
+	if err != nil {
+		return nil, err
+	}
+	// Роботы флота двигаются вместе и друг другу не мешают, проверяем их только против остальных
+	members, err := srv.Repository.FleetRobots(id, true)
+	if err != nil {
+		return nil, err
+	}
+	together := make(map[int]bool, len(members))
+	for _, member := range members {
+		together[member.ID] = true
+	}
+
+	collisions := srv.Robots.Collisions
+	collisions.lock()
+	defer collisions.unlock()
+	var blocked entities.Robot
+	msg := fmt.Sprintf("Флот %d сдвинут на X:%d, Y:%d, Z:%d", id, offset.DX, offset.DY, offset.DZ)
+	operation, err := srv.apply(ctx, id, rbac.RobotsMove, keyfleetmove, msg, func(robot entities.Robot) (*entities.Robot, error) {
+		from := robot.Cord()
+		robot.XCord += offset.DX
+		robot.YCord += offset.DY
+		robot.ZCord += offset.DZ
+		if err := srv.Robots.Types.validateAltitude(robot.Type, robot.ZCord); err != nil {
+			return nil, fmt.Errorf("robot %d: %w", robot.ID, err)
+		}
+		if err := positionCheck(robot.Cord()); err != nil {
+			return nil, fmt.Errorf("robot %d: %w", robot.ID, err)
+		}
+		if err := collisions.check(robot, from, robot.Cord(), together); err != nil {
+			blocked = robot
+			blocked.XCord, blocked.YCord, blocked.ZCord = from.XCord, from.YCord, from.ZCord
+			return nil, err
+		}
+		return &robot, nil
+	})
+	if err != nil {
+		return nil, srv.Robots.collisionPrevented(ctx, blocked, err)
+	}
+	return operation, nil
+}
+
+func (srv *FleetService) RetypeFleet(ctx context.Context, id int, newType string) (*entities.FleetOperation, error) {
+	msg := fmt.Sprintf("Тип роботов флота %d изменён на %s", id, newType)
+	return srv.apply(ctx, id, rbac.RobotsRetype, keyfleetretype, msg, func(robot entities.Robot) (*entities.Robot, error) {
+		robot.Type = newType
+		if err := srv.Robots.Types.validateRobot(robot); err != nil {
+			return nil, fmt.Errorf("robot %d: %w", robot.ID, err)
+		}
+		return &robot, nil
+	})
+}
+
+// Списываем (удаляем) всех роботов флота, сам флот остаётся пустым
+func (srv *FleetService) DecommissionFleet(ctx context.Context, id int) (*entities.FleetOperation, error) {
+	msg := fmt.Sprintf("Роботы флота %d списаны", id)
+	return srv.apply(ctx, id, rbac.RobotsDelete, keyfleetdecommission, msg, func(entities.Robot) (*entities.Robot, error) {
+		return nil, nil
+	})
+}
+
+// Общая часть групповых команд: проверки, одна транзакция, затем по событию на робота и одно общее
+func (srv *FleetService) apply(ctx context.Context, id int, action rbac.Permission, routingKey, msg string,
+	change func(entities.Robot) (*entities.Robot, error)) (*entities.FleetOperation, error) {
+	robots := srv.Robots
+	if err := robots.Lockdown.Check(ctx, action); err != nil {
+		return nil, err
+	}
+	if robots.Approvals.required(ctx, action) {
+		return nil, fmt.Errorf("%w: %s", ErrFleetNeedsApproval, action)
+	}
+
+	details := "fleet " + strconv.Itoa(id)
+	before, after, err := srv.Repository.ApplyToMembers(id, change)
+	if err != nil {
+		srv.Audit.Record(ctx, string(action), 0, nil, nil, err, details)
+		return nil, err
+	}
+
+	actor := auth.Actor(ctx)
+	operation := &entities.FleetOperation{FleetID: id, Action: string(action), Affected: len(before), RobotIDs: make([]int, len(before))}
+	for i := range before {
+		robotID := before[i].ID
+		operation.RobotIDs[i] = robotID
+		_ = robots.Redis.DeleteRobotData(strconv.Itoa(robotID))
+		robots.Audit.Record(ctx, string(action), robotID, &before[i], after[i], nil, details)
+		robots.Lockdown.Observe(ctx, action)
+
+		if after[i] == nil {
+			text := fmt.Sprintf("Робота с ID: %d был списан вместе с флотом %d", robotID, id)
+			robots.publishToRabbitWithText(text, keydel, actor)
+			robots.Events.Publish(events.RobotEvent{Kind: events.KindDeleted, RoutingKey: keydel, RobotID: robotID, Robot: &before[i], Message: text, Actor: actor})
+			prometheusinfo.ObserveRobotOperation(string(action), &before[i])
+			continue
+		}
+		key, text := keyupdatecords, fmt.Sprintf("Координаты робота с ID: %d были изменены на  X:%d, Y:%d, Z:%d", robotID, after[i].XCord, after[i].YCord, after[i].ZCord)
+		if action == rbac.RobotsRetype {
+			key, text = keyupdatetype, fmt.Sprintf("Тип робота с ID: %d был изменен на %s", robotID, after[i].Type)
+		}
+		robots.publishToRabbitWithText(text, key, actor)
+		robots.Events.Publish(events.RobotEvent{Kind: events.KindUpdated, RoutingKey: key, RobotID: robotID, Robot: after[i], Message: text, Actor: actor})
+		robots.publishZoneTransitions(before[i].Cord(), after[i], actor)
+		prometheusinfo.ObserveRobotOperation(string(action), after[i])
+	}
+
+	robots.publishToRabbitWithText(fmt.Sprintf("%s, роботов: %d", msg, len(before)), routingKey, actor)
+	srv.Audit.Record(ctx, string(action), 0, nil, nil, nil, fmt.Sprintf("%s: %d robots", details, len(before)))
+	return operation, nil
+}
@@ -0,0 +1,201 @@
+package services
+
+import (
+	"RobotService/internal/auth"
+	"RobotService/internal/dto"
+	"RobotService/internal/entities"
+	"RobotService/internal/events"
+	"RobotService/internal/repositories"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestFleetFromDTO(t *testing.T) {
+	parent := 7
+	fleet, err := fleetFromDTO(3, dto.FleetDTO{Name: "  alpha ", Description: "d", ParentID: &parent})
+	if err != nil || fleet.Name != "alpha" || fleet.ID != 3 || *fleet.ParentID != 7 {
+		t.Fatalf("fleet = %+v, err = %v", fleet, err)
+	}
+	for _, tt := range []struct {
+		name string
+		data dto.FleetDTO
+		err  error
+	}{
+		{"empty name", dto.FleetDTO{Name: "   "}, ErrInvalidFleet},
+		{"long name", dto.FleetDTO{Name: strings.Repeat("a", maxFleetName+1)}, ErrInvalidFleet},
+		{"own parent", dto.FleetDTO{Name: "a", ParentID: ptr(3)}, repositories.ErrFleetCycle},
+	} {
+		if _, err = fleetFromDTO(3, tt.data); !errors.Is(err, tt.err) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.err)
+		}
+	}
+	var none *FleetService
+	if _, err = none.AddMembers(context.Background(), 1, nil); !errors.Is(err, ErrInvalidFleet) {
+		t.Fatalf("no robots: err = %v", err)
+	}
+}
+
+func newTestFleets(t *testing.T, f *testFixture) *FleetService {
+	t.Helper()
+	return &FleetService{
+		Repository: repositories.FleetRepository{DataBase: f.db, Index: f.robots.RobotRepository.Index},
+		Robots:     f.robots,
+		Audit:      f.robots.Audit,
+	}
+}
+
+// Флот теста, удаляется после теста
+func createTestFleet(t *testing.T, f *testFixture, fleets *FleetService, name string, parentID *int, robots ...*entities.Robot) *entities.Fleet {
+	t.Helper()
+	fleet, err := fleets.CreateFleet(actorContext("alice", "admin"), dto.FleetDTO{Name: f.robotType + "-" + name, ParentID: parentID})
+	if err != nil {
+		t.Fatalf("create fleet %s: %v", name, err)
+	}
+	t.Cleanup(func() { _ = fleets.Repository.DeleteFleet(fleet.ID) })
+	if len(robots) > 0 {
+		ids := make([]int, len(robots))
+		for i, robot := range robots {
+			ids[i] = robot.ID
+		}
+		if fleet, err = fleets.AddMembers(actorContext("alice", "admin"), fleet.ID, ids); err != nil {
+			t.Fatalf("add members to %s: %v", name, err)
+		}
+	}
+	return fleet
+}
+
+func TestNestedFleetCycle(t *testing.T) {
+	f := newTestFixture(t)
+	fleets := newTestFleets(t, f)
+	ctx := actorContext("alice", "admin")
+	top := createTestFleet(t, f, fleets, "top", nil)
+	middle := createTestFleet(t, f, fleets, "middle", &top.ID)
+	bottom := createTestFleet(t, f, fleets, "bottom", &middle.ID)
+
+	// Любой предок, включая непрямого, не может стать потомком
+	for _, parent := range []int{middle.ID, bottom.ID, top.ID} {
+		_, err := fleets.UpdateFleet(ctx, top.ID, dto.FleetDTO{Name: top.Name, ParentID: &parent})
+		if !errors.Is(err, repositories.ErrFleetCycle) {
+			t.Errorf("top under %d: err = %v, want ErrFleetCycle", parent, err)
+		}
+	}
+	if fleet, err := fleets.GetFleet(top.ID); err != nil || fleet.ParentID != nil {
+		t.Fatalf("top after rejected moves = %+v, err = %v", fleet, err)
+	}
+	missing := -1
+	if _, err := fleets.UpdateFleet(ctx, bottom.ID, dto.FleetDTO{Name: bottom.Name, ParentID: &missing}); !errors.Is(err, repositories.ErrFleetParentNotFound) {
+		t.Fatalf("unknown parent: err = %v", err)
+	}
+	// Перенос в соседнюю ветку разрешён
+	if _, err := fleets.UpdateFleet(ctx, bottom.ID, dto.FleetDTO{Name: bottom.Name, ParentID: &top.ID}); err != nil {
+		t.Fatalf("bottom under top: %v", err)
+	}
+	if err := fleets.DeleteFleet(ctx, top.ID); !errors.Is(err, repositories.ErrFleetHasChildren) {
+		t.Fatalf("delete parent: err = %v", err)
+	}
+}
+
+func TestMoveFleet(t *testing.T) {
+	f := newTestFixture(t)
+	fleets := newTestFleets(t, f)
+	// Свой субъект, чтобы в аудите не было чужих групповых команд
+	ctx := actorContext(f.robotType, "admin")
+	first := f.createRobot(t, entities.Robot{Name: "first"})
+	second := f.createRobot(t, entities.Robot{Name: "second", XCord: 10})
+	nested := f.createRobot(t, entities.Robot{Name: "nested", XCord: 20})
+	top := createTestFleet(t, f, fleets, "squad", nil, first, second)
+	createTestFleet(t, f, fleets, "sub", &top.ID, nested)
+
+	// Участники двигаются вместе: first встаёт туда, откуда уходит second
+	ch, unsubscribe := f.robots.Events.Subscribe(64)
+	defer unsubscribe()
+	operation, err := fleets.MoveFleet(ctx, top.ID, dto.FleetMoveDTO{DX: 10})
+	if err != nil {
+		t.Fatalf("MoveFleet: %v", err)
+	}
+	if operation.Affected != 3 || len(operation.RobotIDs) != 3 {
+		t.Fatalf("operation = %+v", operation)
+	}
+	for _, robot := range []*entities.Robot{first, second, nested} {
+		moved, err := f.robots.RobotRepository.GetRobotInfo(robot.ID)
+		if err != nil || moved.XCord != robot.XCord+10 {
+			t.Fatalf("robot %d at %+v, err = %v", robot.ID, moved, err)
+		}
+	}
+	// По событию на робота, общее событие одно - запись аудита без робота
+	updated := map[int]int{}
+	for len(ch) > 0 {
+		event := <-ch
+		if event.Kind != events.KindUpdated {
+			t.Fatalf("unexpected event %+v", event)
+		}
+		updated[event.RobotID]++
+	}
+	if len(updated) != 3 || updated[first.ID] != 1 || updated[second.ID] != 1 || updated[nested.ID] != 1 {
+		t.Fatalf("updated events = %v", updated)
+	}
+	records, err := fleets.Audit.ListAudit(entities.AuditFilter{Actor: auth.Actor(ctx)}, 100, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	summaries := 0
+	for _, record := range records {
+		if record.RobotID == nil && strings.HasSuffix(record.Details, ": 3 robots") && strings.Contains(record.Details, "fleet ") {
+			summaries++
+		}
+	}
+	if summaries != 1 {
+		t.Fatalf("%d summary records in %+v", summaries, records)
+	}
+}
+
+// Один заблокированный участник - не двигается никто
+func TestMoveFleetIsAllOrNothing(t *testing.T) {
+	f := newTestFixture(t)
+	fleets := newTestFleets(t, f)
+	ctx := actorContext("alice", "admin")
+	// Тип создаём до роботов, чтобы при очистке он удалялся после них
+	low := f.robotType + "-low"
+	f.createType(t, dto.CreateRobotTypeDTO{Name: low, MaxZ: ptr(1)})
+	free := f.createRobot(t, entities.Robot{Name: "free"})
+	stuck := f.createRobot(t, entities.Robot{Name: "stuck", XCord: 10})
+	outsider := f.createRobot(t, entities.Robot{Name: "outsider", XCord: 15})
+	fleet := createTestFleet(t, f, fleets, "squad", nil, free, stuck)
+
+	ch, unsubscribe := f.robots.Events.Subscribe(64)
+	defer unsubscribe()
+	_, err := fleets.MoveFleet(ctx, fleet.ID, dto.FleetMoveDTO{DX: 5})
+	var collision *CollisionError
+	if !errors.As(err, &collision) || collision.Blocking.ID != outsider.ID {
+		t.Fatalf("err = %v, want a collision with the outsider", err)
+	}
+	for _, robot := range []*entities.Robot{free, stuck} {
+		current, err := f.robots.RobotRepository.GetRobotInfo(robot.ID)
+		if err != nil || current.Cord() != robot.Cord() {
+			t.Fatalf("robot %d moved to %+v, err = %v", robot.ID, current, err)
+		}
+	}
+	// Только предупреждение о столкновении, с исходной позицией заблокированного
+	event := <-ch
+	if event.Kind != events.KindCollisionPrevented || event.RobotID != stuck.ID || event.Robot.Cord() != stuck.Cord() || len(ch) != 0 {
+		t.Fatalf("events: %+v and %d more", event, len(ch))
+	}
+
+	// Вылет за высоту типа тоже отменяет весь сдвиг
+	if _, err = fleets.MoveFleet(ctx, fleet.ID, dto.FleetMoveDTO{DY: 100, DZ: 1}); err != nil {
+		t.Fatalf("move without limits: %v", err)
+	}
+	if _, err = fleets.RetypeFleet(ctx, fleet.ID, low); err != nil {
+		t.Fatalf("RetypeFleet: %v", err)
+	}
+	if _, err = fleets.MoveFleet(ctx, fleet.ID, dto.FleetMoveDTO{DZ: 1}); !errors.Is(err, ErrAltitudeOutOfRange) {
+		t.Fatalf("above the type: err = %v", err)
+	}
+	for _, robot := range []*entities.Robot{free, stuck} {
+		if current, _ := f.robots.RobotRepository.GetRobotInfo(robot.ID); current.ZCord != robot.ZCord+1 || current.Type != low {
+			t.Fatalf("robot %d = %+v", robot.ID, current)
+		}
+	}
+}
@@ -0,0 +1,436 @@
+package services
+
+import (
+	"RobotService/internal/auth"
+	"RobotService/internal/dto"
+	"RobotService/internal/entities"
+	"RobotService/internal/events"
+	"RobotService/internal/lockdown"
+	"RobotService/internal/rbac"
+	"RobotService/internal/repositories"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMissionTick = time.Second
+	maxMissionName     = 128
+	maxMissionSteps    = 100
+	maxMissionPayload  = 128
+	// Дольше суток на одном шаге ждать не даём
+	maxWaitSeconds = 24 * 60 * 60
+)
+
+var (
+	ErrInvalidMission = errors.New("invalid mission")
+	// Переход не из того состояния: паузу ставим только активной, продолжаем только приостановленную и т.п.
+	ErrMissionState = errors.New("mission cannot do this in its current state")
+)
+
+// Миссии и их исполнитель. Назначенная миссия ждёт в очереди, пока у робота не закончится предыдущая,
+// потом раз в Tick исполнитель ведёт её по шагам: move отдаёт движку перемещений и ждёт команду,
+// wait ждёт, pickup и dropoff меняют груз сразу. Пауза и отмена останавливают текущую команду,
+// после паузы шаг начинается заново. Исполнитель один на процесс, как и движок
+type MissionService struct {
+	Repository repositories.MissionRepository
+	Robots     *RbtSrvic
+	Motion     *MotionService
+	Audit      *AuditService
+	Tick       time.Duration
+
+	mu sync.Mutex
+}
+
+func (srv *MissionService) CreateMission(ctx context.Context, data dto.CreateMissionDTO) (*entities.Mission, error) {
+	mission, err := missionFromDTO(data)
+	if err != nil {
+		return nil, err
+	}
+	mission.CreatedBy = auth.Actor(ctx)
+	created, err := srv.Repository.CreateMission(*mission)
+	if err != nil {
+		return nil, err
+	}
+	robotID := 0
+	if created.RobotID != nil {
+		robotID = *created.RobotID
+	}
+	message := fmt.Sprintf("created mission %d, %d steps", created.ID, len(created.Steps))
+	srv.Audit.Record(ctx, string(rbac.MissionsWrite), robotID, nil, nil, nil, message)
+	srv.publish(created, message, created.CreatedBy)
+	return created, nil
+}
+
+func (srv *MissionService) GetMission(id int) (*entities.Mission, error) {
+	return srv.Repository.GetMission(id)
+}
+
+func (srv *MissionService) ListMissions(robotID int, status string, limit, offset int) ([]entities.Mission, error) {
+	return srv.Repository.ListMissions(robotID, status, limit, offset)
+}
+
+// Назначить или переназначить робота, пока миссия в очереди
+func (srv *MissionService) Assign(ctx context.Context, id, robotID int) (*entities.Mission, error) {
+	return srv.transition(ctx, id, func(mission *entities.Mission) error {
+		if mission.Status != entities.MissionQueued {
+			return fmt.Errorf("%w: robot can be assigned only to a queued mission", ErrMissionState)
+		}
+		if _, err := srv.Robots.RobotRepository.GetRobotInfo(robotID); err != nil {
+			return err
+		}
+		mission.RobotID = &robotID
+		return nil
+	}, fmt.Sprintf("assigned to robot %d", robotID))
+}
+
+func (srv *MissionService) Pause(ctx context.Context, id int) (*entities.Mission, error) {
+	return srv.transition(ctx, id, func(mission *entities.Mission) error {
+		if mission.Status != entities.MissionActive {
+			return fmt.Errorf("%w: only an active mission can be paused", ErrMissionState)
+		}
+		srv.stopStep(ctx, mission)
+		mission.Status = entities.MissionPaused
+		return nil
+	}, "paused")
+}
+
+func (srv *MissionService) Resume(ctx context.Context, id int) (*entities.Mission, error) {
+	return srv.transition(ctx, id, func(mission *entities.Mission) error {
+		if mission.Status != entities.MissionPaused {
+			return fmt.Errorf("%w: only a paused mission can be resumed", ErrMissionState)
+		}
+		mission.Status = entities.MissionActive
+		return nil
+	}, "resumed")
+}
+
+func (srv *MissionService) Cancel(ctx context.Context, id int) (*entities.Mission, error) {
+	return srv.transition(ctx, id, func(mission *entities.Mission) error {
+		if mission.Finished() {
+			return fmt.Errorf("%w: mission is already %s", ErrMissionState, mission.Status)
+		}
+		srv.stopStep(ctx, mission)
+		now := time.Now().UTC()
+		mission.Status, mission.FinishedAt = entities.MissionCancelled, &now
+		return nil
+	}, "cancelled")
+}
+
+// Переход по запросу: меняем миссию под блокировкой исполнителя, сохраняем и сообщаем о ходе
+func (srv *MissionService) transition(ctx context.Context, id int, change func(*entities.Mission) error, what string) (*entities.Mission, error) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	mission, err := srv.Repository.GetMission(id)
+	if err != nil {
+		return nil, err
+	}
+	started := mission.Status == entities.MissionActive || mission.Status == entities.MissionPaused
+	if err = change(mission); err != nil {
+		return nil, err
+	}
+	updated, err := srv.Repository.UpdateMission(*mission)
+	if err != nil {
+		return nil, err
+	}
+	message := fmt.Sprintf("mission %d %s", id, what)
+	srv.Audit.Record(ctx, string(rbac.MissionsWrite), missionRobot(updated), nil, nil, nil, message)
+	srv.publish(updated, message, auth.Actor(ctx))
+	if started && updated.Finished() {
+		srv.publishStatus(updated, entities.RobotIdle, auth.Actor(ctx))
+	}
+	return updated, nil
+}
+
+// Останавливаем текущий шаг: команда перемещения отменяется, шаг потом начнётся с начала
+func (srv *MissionService) stopStep(ctx context.Context, mission *entities.Mission) {
+	if mission.CurrentStep >= len(mission.Steps) {
+		return
+	}
+	step := &mission.Steps[mission.CurrentStep]
+	if step.CommandID != 0 {
+		_, err := srv.Motion.Cancel(ctx, step.CommandID)
+		if err != nil && !errors.Is(err, repositories.ErrCommandFinished) && !errors.Is(err, repositories.ErrCommandNotFound) {
+			log.Println("Миссии: не удалось отменить команду", step.CommandID, err)
+		}
+	}
+	step.CommandID, step.StartedAt = 0, nil
+}
+
+// Цикл исполнителя, живёт до отмены ctx
+func (srv *MissionService) Run(ctx context.Context) {
+	tick := srv.Tick
+	if tick <= 0 {
+		tick = defaultMissionTick
+	}
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			srv.advance()
+		}
+	}
+}
+
+// Один тик: миссии по порядку создания, из очереди запускаем, только если робот свободен
+func (srv *MissionService) advance() {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	pending, err := srv.Repository.PendingMissions()
+	if err != nil {
+		log.Println("Миссии: не удалось прочитать очередь", err)
+		return
+	}
+	busy := map[int]bool{}
+	for i := range pending {
+		if pending[i].Status == entities.MissionActive && pending[i].RobotID != nil {
+			busy[*pending[i].RobotID] = true
+		}
+	}
+	for i := range pending {
+		mission := &pending[i]
+		if mission.Status == entities.MissionQueued {
+			if busy[*mission.RobotID] {
+				continue
+			}
+			if !srv.start(mission) {
+				continue
+			}
+			busy[*mission.RobotID] = true
+		}
+		srv.step(mission)
+	}
+}
+
+// Запускаем миссию из очереди. false - робот ещё занят приостановленной миссией
+func (srv *MissionService) start(mission *entities.Mission) bool {
+	now := time.Now().UTC()
+	mission.Status, mission.StartedAt = entities.MissionActive, &now
+	started, err := srv.Repository.UpdateMission(*mission)
+	if errors.Is(err, repositories.ErrRobotOnMission) {
+		return false
+	}
+	if err != nil {
+		log.Println("Миссии: не удалось запустить миссию", mission.ID, err)
+		return false
+	}
+	*mission = *started
+	srv.publish(mission, fmt.Sprintf("mission %d started on robot %d", mission.ID, *mission.RobotID), mission.CreatedBy)
+	srv.publishStatus(mission, entities.RobotOnMission, mission.CreatedBy)
+	return true
+}
+
+// Продвигаем миссию, сколько получится за тик: мгновенные шаги идут подряд, move и wait ждут следующих тиков
+func (srv *MissionService) step(mission *entities.Mission) {
+	ctx := context.Background()
+	if mission.RobotID == nil {
+		srv.fail(mission, "robot was deleted")
+		return
+	}
+	for mission.CurrentStep < len(mission.Steps) {
+		step := &mission.Steps[mission.CurrentStep]
+		idle := step.StartedAt == nil
+		done, err := srv.run(ctx, mission, step)
+		if err != nil {
+			srv.fail(mission, fmt.Sprintf("step %d (%s): %v", mission.CurrentStep+1, step.Kind, err))
+			return
+		}
+		if !done {
+			// Пишем в базу, только когда шаг начался, а не на каждом тике ожидания
+			if idle && step.StartedAt != nil {
+				srv.save(mission)
+			}
+			return
+		}
+		now := time.Now().UTC()
+		if step.StartedAt == nil {
+			step.StartedAt = &now
+		}
+		step.FinishedAt = &now
+		mission.CurrentStep++
+		if mission.CurrentStep < len(mission.Steps) {
+			srv.save(mission)
+			srv.publish(mission, fmt.Sprintf("mission %d: step %d of %d done", mission.ID, mission.CurrentStep, len(mission.Steps)), mission.CreatedBy)
+		}
+	}
+
+	now := time.Now().UTC()
+	mission.Status, mission.FinishedAt = entities.MissionCompleted, &now
+	srv.save(mission)
+	message := fmt.Sprintf("mission %d completed by robot %d", mission.ID, *mission.RobotID)
+	srv.Audit.Record(ctx, string(rbac.MissionsWrite), *mission.RobotID, nil, nil, nil, message)
+	srv.publish(mission, message, mission.CreatedBy)
+	srv.publishStatus(mission, entities.RobotIdle, mission.CreatedBy)
+}
+
+// Выполняем шаг. true - шаг закончен, ошибка проваливает миссию
+func (srv *MissionService) run(ctx context.Context, mission *entities.Mission, step *entities.MissionStep) (bool, error) {
+	switch step.Kind {
+	case entities.StepMove:
+		return srv.runMove(ctx, mission, step)
+	case entities.StepWait:
+		if step.StartedAt == nil {
+			now := time.Now().UTC()
+			step.StartedAt = &now
+		}
+		return time.Since(*step.StartedAt).Seconds() >= step.Seconds, nil
+	case entities.StepPickup:
+		if mission.Payload != "" {
+			return false, fmt.Errorf("robot already carries %q", mission.Payload)
+		}
+		mission.Payload = step.Payload
+		return true, nil
+	case entities.StepDropoff:
+		if mission.Payload == "" || (step.Payload != "" && step.Payload != mission.Payload) {
+			return false, fmt.Errorf("robot does not carry %q", step.Payload)
+		}
+		mission.Payload = ""
+		return true, nil
+	}
+	return false, fmt.Errorf("unknown step kind %q", step.Kind)
+}
+
+// move: отдаём движку команду и ждём, чем она закончится. Пока робот занят чужой командой,
+// зажат в защитном режиме или ячейки заняты, пробуем снова на следующем тике
+func (srv *MissionService) runMove(ctx context.Context, mission *entities.Mission, step *entities.MissionStep) (bool, error) {
+	if step.CommandID == 0 {
+		command, err := srv.Motion.move(ctx, *mission.RobotID, dto.MoveDTO{
+			XCord: step.Target.XCord, YCord: step.Target.YCord, ZCord: step.Target.ZCord,
+		}, mission.CreatedBy)
+		switch {
+		case errors.Is(err, repositories.ErrRobotBusy), errors.Is(err, lockdown.ErrLockedDown),
+			errors.Is(err, ErrReservationConflict), errors.Is(err, ErrStalePath):
+			return false, nil
+		case err != nil:
+			return false, err
+		}
+		now := time.Now().UTC()
+		step.CommandID, step.StartedAt = command.ID, &now
+		return false, nil
+	}
+
+	command, err := srv.Motion.GetCommand(step.CommandID)
+	if err != nil {
+		return false, err
+	}
+	switch command.Status {
+	case entities.CommandCompleted:
+		return true, nil
+	case entities.CommandFailed:
+		return false, fmt.Errorf("move command %d failed: %s", command.ID, command.Error)
+	case entities.CommandCancelled:
+		return false, fmt.Errorf("move command %d was cancelled", command.ID)
+	}
+	return false, nil
+}
+
+func (srv *MissionService) fail(mission *entities.Mission, reason string) {
+	srv.stopStep(context.Background(), mission)
+	now := time.Now().UTC()
+	mission.Status, mission.Error, mission.FinishedAt = entities.MissionFailed, reason, &now
+	srv.save(mission)
+	srv.Audit.Record(context.Background(), string(rbac.MissionsWrite), missionRobot(mission), nil, nil, errors.New(reason),
+		fmt.Sprintf("mission %d failed", mission.ID))
+	srv.publish(mission, fmt.Sprintf("mission %d failed: %s", mission.ID, reason), mission.CreatedBy)
+	srv.publishStatus(mission, entities.RobotIdle, mission.CreatedBy)
+}
+
+func (srv *MissionService) save(mission *entities.Mission) {
+	saved, err := srv.Repository.UpdateMission(*mission)
+	if err != nil {
+		log.Println("Миссии: не удалось сохранить миссию", mission.ID, err)
+		return
+	}
+	*mission = *saved
+}
+
+// Событие хода миссии: в реббит миссия джесоном, в хаб - описание. Ключ по состоянию
+func (srv *MissionService) publish(mission *entities.Mission, message, actor string) {
+	key, kind := keymissionprogress, events.KindMissionProgress
+	switch mission.Status {
+	case entities.MissionCompleted:
+		key, kind = keymissioncompleted, events.KindMissionCompleted
+	case entities.MissionFailed:
+		key, kind = keymissionfailed, events.KindMissionFailed
+	}
+	srv.Robots.publishToRabbitWithJSON(mission, key, actor)
+	srv.Robots.Events.Publish(events.RobotEvent{Kind: kind, RoutingKey: key, RobotID: missionRobot(mission), MissionID: mission.ID,
+		Message: message, Actor: actor})
+}
+
+// Робот миссии взял её или освободился. Миссия без робота (его удалили) никого не освобождает
+func (srv *MissionService) publishStatus(mission *entities.Mission, status, actor string) {
+	if mission.RobotID == nil {
+		return
+	}
+	robotStatus := entities.RobotStatus{RobotID: *mission.RobotID, Status: status, MissionID: mission.ID}
+	srv.Robots.publishToRabbitWithJSON(robotStatus, keystatus, actor)
+	srv.Robots.Events.Publish(events.RobotEvent{Kind: events.KindStatusChanged, RoutingKey: keystatus, RobotID: robotStatus.RobotID,
+		MissionID: mission.ID, Message: fmt.Sprintf("robot %d is %s", robotStatus.RobotID, status), Actor: actor})
+}
+
+func missionRobot(mission *entities.Mission) int {
+	if mission.RobotID == nil {
+		return 0
+	}
+	return *mission.RobotID
+}
+
+// Проверяем шаги заранее, в том числе груз: взять можно, только если руки свободны, отдать - только то, что везём
+func missionFromDTO(data dto.CreateMissionDTO) (*entities.Mission, error) {
+	name := strings.TrimSpace(data.Name)
+	if len(name) > maxMissionName {
+		return nil, fmt.Errorf("%w: name is longer than %d", ErrInvalidMission, maxMissionName)
+	}
+	if len(data.Steps) == 0 || len(data.Steps) > maxMissionSteps {
+		return nil, fmt.Errorf("%w: mission needs 1 to %d steps", ErrInvalidMission, maxMissionSteps)
+	}
+	mission := &entities.Mission{Name: name, Status: entities.MissionQueued, Steps: make([]entities.MissionStep, len(data.Steps))}
+	if data.RobotID != 0 {
+		robotID := data.RobotID
+		mission.RobotID = &robotID
+	}
+	carried := ""
+	for i, stepData := range data.Steps {
+		step := entities.MissionStep{Kind: stepData.Kind}
+		invalid := func(reason string) error {
+			return fmt.Errorf("%w: step %d (%s): %s", ErrInvalidMission, i+1, stepData.Kind, reason)
+		}
+		if len(stepData.Payload) > maxMissionPayload {
+			return nil, invalid(fmt.Sprintf("payload is longer than %d", maxMissionPayload))
+		}
+		switch stepData.Kind {
+		case entities.StepMove:
+			step.Target = &entities.RobotCord{XCord: stepData.XCord, YCord: stepData.YCord, ZCord: stepData.ZCord}
+		case entities.StepWait:
+			if stepData.Seconds <= 0 || stepData.Seconds > maxWaitSeconds {
+				return nil, invalid(fmt.Sprintf("seconds must be in (0, %d]", maxWaitSeconds))
+			}
+			step.Seconds = stepData.Seconds
+		case entities.StepPickup:
+			if stepData.Payload == "" {
+				return nil, invalid("payload is required")
+			}
+			if carried != "" {
+				return nil, invalid(fmt.Sprintf("robot already carries %q", carried))
+			}
+			step.Payload, carried = stepData.Payload, stepData.Payload
+		case entities.StepDropoff:
+			if carried == "" || (stepData.Payload != "" && stepData.Payload != carried) {
+				return nil, invalid("robot does not carry this payload")
+			}
+			step.Payload, carried = carried, ""
+		default:
+			return nil, fmt.Errorf("%w: step %d: unknown kind %q", ErrInvalidMission, i+1, stepData.Kind)
+		}
+		mission.Steps[i] = step
+	}
+	return mission, nil
+}
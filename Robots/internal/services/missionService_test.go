@@ -0,0 +1,268 @@
+package services
+
+import (
+	"RobotService/internal/dto"
+	"RobotService/internal/entities"
+	"RobotService/internal/repositories"
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMissionFromDTO(t *testing.T) {
+	mission, err := missionFromDTO(dto.CreateMissionDTO{
+		Name:    " delivery ",
+		RobotID: 7,
+		Steps: []dto.MissionStepDTO{
+			{Kind: entities.StepMove, XCord: 1, YCord: 2, ZCord: 3},
+			{Kind: entities.StepPickup, Payload: "box"},
+			{Kind: entities.StepWait, Seconds: 1.5},
+			{Kind: entities.StepDropoff},
+			{Kind: entities.StepPickup, Payload: "crate"},
+			{Kind: entities.StepDropoff, Payload: "crate"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []entities.MissionStep{
+		{Kind: entities.StepMove, Target: &entities.RobotCord{XCord: 1, YCord: 2, ZCord: 3}},
+		{Kind: entities.StepPickup, Payload: "box"},
+		{Kind: entities.StepWait, Seconds: 1.5},
+		// Пустой dropoff отдаёт то, что везём
+		{Kind: entities.StepDropoff, Payload: "box"},
+		{Kind: entities.StepPickup, Payload: "crate"},
+		{Kind: entities.StepDropoff, Payload: "crate"},
+	}
+	if mission.Name != "delivery" || mission.Status != entities.MissionQueued || mission.RobotID == nil || *mission.RobotID != 7 {
+		t.Fatalf("mission = %+v", mission)
+	}
+	if !reflect.DeepEqual(mission.Steps, want) {
+		t.Fatalf("steps = %+v, want %+v", mission.Steps, want)
+	}
+
+	unassigned, err := missionFromDTO(dto.CreateMissionDTO{Steps: []dto.MissionStepDTO{{Kind: entities.StepWait, Seconds: 1}}})
+	if err != nil || unassigned.RobotID != nil {
+		t.Fatalf("unassigned mission = %+v, err = %v", unassigned, err)
+	}
+
+	tooMany := make([]dto.MissionStepDTO, maxMissionSteps+1)
+	for i := range tooMany {
+		tooMany[i] = dto.MissionStepDTO{Kind: entities.StepWait, Seconds: 1}
+	}
+	pickup := dto.MissionStepDTO{Kind: entities.StepPickup, Payload: "box"}
+	tests := []struct {
+		name  string
+		steps []dto.MissionStepDTO
+		title string
+	}{
+		{name: "no steps"},
+		{name: "too many steps", steps: tooMany},
+		{name: "long name", steps: []dto.MissionStepDTO{{Kind: entities.StepWait, Seconds: 1}}, title: strings.Repeat("n", maxMissionName+1)},
+		{name: "unknown kind", steps: []dto.MissionStepDTO{{Kind: "fly"}}},
+		{name: "zero wait", steps: []dto.MissionStepDTO{{Kind: entities.StepWait}}},
+		{name: "negative wait", steps: []dto.MissionStepDTO{{Kind: entities.StepWait, Seconds: -1}}},
+		{name: "wait over a day", steps: []dto.MissionStepDTO{{Kind: entities.StepWait, Seconds: maxWaitSeconds + 1}}},
+		{name: "pickup without payload", steps: []dto.MissionStepDTO{{Kind: entities.StepPickup}}},
+		{name: "long payload", steps: []dto.MissionStepDTO{{Kind: entities.StepPickup, Payload: strings.Repeat("p", maxMissionPayload+1)}}},
+		{name: "second pickup", steps: []dto.MissionStepDTO{pickup, {Kind: entities.StepPickup, Payload: "crate"}}},
+		{name: "dropoff with empty hands", steps: []dto.MissionStepDTO{{Kind: entities.StepDropoff}}},
+		{name: "dropoff of another payload", steps: []dto.MissionStepDTO{pickup, {Kind: entities.StepDropoff, Payload: "crate"}}},
+		{name: "dropoff twice", steps: []dto.MissionStepDTO{pickup, {Kind: entities.StepDropoff}, {Kind: entities.StepDropoff}}},
+	}
+	for _, tt := range tests {
+		if got, err := missionFromDTO(dto.CreateMissionDTO{Name: tt.title, Steps: tt.steps}); !errors.Is(err, ErrInvalidMission) {
+			t.Errorf("%s: mission %+v, err = %v, want ErrInvalidMission", tt.name, got, err)
+		}
+	}
+}
+
+// Мгновенные шаги и ожидание исполняются без движка и базы
+func TestRunInstantSteps(t *testing.T) {
+	srv := &MissionService{}
+	mission := &entities.Mission{}
+	ctx := context.Background()
+
+	if done, err := srv.run(ctx, mission, &entities.MissionStep{Kind: entities.StepPickup, Payload: "box"}); !done || err != nil || mission.Payload != "box" {
+		t.Fatalf("pickup: done %v, err %v, payload %q", done, err, mission.Payload)
+	}
+	if _, err := srv.run(ctx, mission, &entities.MissionStep{Kind: entities.StepPickup, Payload: "crate"}); err == nil {
+		t.Fatal("second pickup succeeded")
+	}
+	if _, err := srv.run(ctx, mission, &entities.MissionStep{Kind: entities.StepDropoff, Payload: "crate"}); err == nil {
+		t.Fatal("dropoff of another payload succeeded")
+	}
+	if done, err := srv.run(ctx, mission, &entities.MissionStep{Kind: entities.StepDropoff}); !done || err != nil || mission.Payload != "" {
+		t.Fatalf("dropoff: done %v, err %v, payload %q", done, err, mission.Payload)
+	}
+
+	wait := &entities.MissionStep{Kind: entities.StepWait, Seconds: 60}
+	if done, err := srv.run(ctx, mission, wait); done || err != nil || wait.StartedAt == nil {
+		t.Fatalf("wait start: done %v, err %v, step %+v", done, err, wait)
+	}
+	started := time.Now().Add(-time.Minute)
+	wait.StartedAt = &started
+	if done, _ := srv.run(ctx, mission, wait); !done {
+		t.Fatal("wait did not end after its seconds")
+	}
+	if _, err := srv.run(ctx, mission, &entities.MissionStep{Kind: "fly"}); err == nil {
+		t.Fatal("unknown step kind succeeded")
+	}
+}
+
+func newTestMissions(f *testFixture) *MissionService {
+	return &MissionService{
+		Repository: repositories.MissionRepository{DataBase: f.db},
+		Robots:     f.robots,
+		Motion:     newTestMotion(f, 3),
+		Audit:      f.robots.Audit,
+	}
+}
+
+// Миссия не должна пережить тест активной: у робота может быть только одна такая
+func createTestMission(t *testing.T, missions *MissionService, data dto.CreateMissionDTO) *entities.Mission {
+	t.Helper()
+	mission, err := missions.CreateMission(actorContext("alice", "operator"), data)
+	if err != nil {
+		t.Fatalf("CreateMission: %v", err)
+	}
+	t.Cleanup(func() { _, _ = missions.Cancel(context.Background(), mission.ID) })
+	return mission
+}
+
+func missionStatus(t *testing.T, missions *MissionService, id int) *entities.Mission {
+	t.Helper()
+	mission, err := missions.GetMission(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mission
+}
+
+func TestMissionLifecycle(t *testing.T) {
+	f := newTestFixture(t)
+	missions := newTestMissions(f)
+	robot := f.createRobot(t, entities.Robot{Name: "mission-runner"})
+	ctx := actorContext("alice", "operator")
+
+	mission := createTestMission(t, missions, dto.CreateMissionDTO{Name: "lifecycle", RobotID: robot.ID, Steps: []dto.MissionStepDTO{
+		{Kind: entities.StepPickup, Payload: "box"},
+		{Kind: entities.StepWait, Seconds: 0.05},
+		{Kind: entities.StepDropoff},
+	}})
+	if mission.Status != entities.MissionQueued {
+		t.Fatalf("new mission is %s", mission.Status)
+	}
+	// Продолжить можно только приостановленную, поставить на паузу - только активную
+	if _, err := missions.Resume(ctx, mission.ID); !errors.Is(err, ErrMissionState) {
+		t.Fatalf("resume a queued mission: err = %v", err)
+	}
+	if _, err := missions.Pause(ctx, mission.ID); !errors.Is(err, ErrMissionState) {
+		t.Fatalf("pause a queued mission: err = %v", err)
+	}
+
+	// queued -> active: груз взят, ожидание началось
+	missions.advance()
+	active := missionStatus(t, missions, mission.ID)
+	if active.Status != entities.MissionActive || active.CurrentStep != 1 || active.Payload != "box" || active.StartedAt == nil {
+		t.Fatalf("after the first tick: %+v", active)
+	}
+	if _, err := missions.Assign(ctx, mission.ID, robot.ID); !errors.Is(err, ErrMissionState) {
+		t.Fatalf("reassign an active mission: err = %v", err)
+	}
+
+	paused, err := missions.Pause(ctx, mission.ID)
+	if err != nil || paused.Status != entities.MissionPaused {
+		t.Fatalf("Pause: %+v, err = %v", paused, err)
+	}
+	// На паузе исполнитель миссию не трогает
+	time.Sleep(60 * time.Millisecond)
+	missions.advance()
+	if still := missionStatus(t, missions, mission.ID); still.Status != entities.MissionPaused || still.CurrentStep != 1 {
+		t.Fatalf("paused mission moved on: %+v", still)
+	}
+
+	resumed, err := missions.Resume(ctx, mission.ID)
+	if err != nil || resumed.Status != entities.MissionActive {
+		t.Fatalf("Resume: %+v, err = %v", resumed, err)
+	}
+	// После паузы шаг начинается заново
+	missions.advance()
+	if still := missionStatus(t, missions, mission.ID); still.Status != entities.MissionActive || still.CurrentStep != 1 {
+		t.Fatalf("wait after resume: %+v", still)
+	}
+	time.Sleep(60 * time.Millisecond)
+	missions.advance()
+	done := missionStatus(t, missions, mission.ID)
+	if done.Status != entities.MissionCompleted || done.CurrentStep != 3 || done.Payload != "" || done.FinishedAt == nil {
+		t.Fatalf("finished mission: %+v", done)
+	}
+
+	// Из конечного состояния никуда
+	for name, change := range map[string]func(context.Context, int) (*entities.Mission, error){
+		"resume": missions.Resume, "pause": missions.Pause, "cancel": missions.Cancel,
+	} {
+		if _, err = change(ctx, mission.ID); !errors.Is(err, ErrMissionState) {
+			t.Errorf("%s a completed mission: err = %v, want ErrMissionState", name, err)
+		}
+	}
+}
+
+func TestMissionCancelled(t *testing.T) {
+	f := newTestFixture(t)
+	missions := newTestMissions(f)
+	robot := f.createRobot(t, entities.Robot{Name: "mission-cancelled"})
+	ctx := actorContext("alice", "operator")
+	steps := []dto.MissionStepDTO{{Kind: entities.StepWait, Seconds: 60}}
+
+	// Из очереди
+	queued := createTestMission(t, missions, dto.CreateMissionDTO{Steps: steps})
+	cancelled, err := missions.Cancel(ctx, queued.ID)
+	if err != nil || cancelled.Status != entities.MissionCancelled || cancelled.FinishedAt == nil {
+		t.Fatalf("cancel a queued mission: %+v, err = %v", cancelled, err)
+	}
+	if _, err = missions.Assign(ctx, queued.ID, robot.ID); !errors.Is(err, ErrMissionState) {
+		t.Fatalf("assign a cancelled mission: err = %v", err)
+	}
+
+	// С паузы: робот освобождается для следующей миссии
+	mission := createTestMission(t, missions, dto.CreateMissionDTO{RobotID: robot.ID, Steps: steps})
+	missions.advance()
+	if _, err = missions.Pause(ctx, mission.ID); err != nil {
+		t.Fatalf("Pause: %v", err)
+	}
+	if cancelled, err = missions.Cancel(ctx, mission.ID); err != nil || cancelled.Status != entities.MissionCancelled {
+		t.Fatalf("cancel a paused mission: %+v, err = %v", cancelled, err)
+	}
+	if _, err = missions.Resume(ctx, mission.ID); !errors.Is(err, ErrMissionState) {
+		t.Fatalf("resume a cancelled mission: err = %v", err)
+	}
+	next := createTestMission(t, missions, dto.CreateMissionDTO{RobotID: robot.ID, Steps: steps})
+	missions.advance()
+	if started := missionStatus(t, missions, next.ID); started.Status != entities.MissionActive {
+		t.Fatalf("next mission is %s", started.Status)
+	}
+}
+
+func TestMissionFailsWithoutRobot(t *testing.T) {
+	f := newTestFixture(t)
+	missions := newTestMissions(f)
+	robot := f.createRobot(t, entities.Robot{Name: "mission-failed"})
+
+	mission := createTestMission(t, missions, dto.CreateMissionDTO{RobotID: robot.ID, Steps: []dto.MissionStepDTO{{Kind: entities.StepWait, Seconds: 60}}})
+	missions.advance()
+	if err := f.robots.RobotRepository.DeleteRobot(robot.ID); err != nil {
+		t.Fatal(err)
+	}
+	missions.advance()
+	failed := missionStatus(t, missions, mission.ID)
+	if failed.Status != entities.MissionFailed || failed.Error != "robot was deleted" || failed.FinishedAt == nil {
+		t.Fatalf("mission without robot: %+v", failed)
+	}
+	if _, err := missions.Resume(actorContext("alice", "operator"), mission.ID); !errors.Is(err, ErrMissionState) {
+		t.Fatalf("resume a failed mission: err = %v", err)
+	}
+}
@@ -0,0 +1,495 @@
+package services
+
+import (
+	"RobotService/internal/auth"
+	"RobotService/internal/dto"
+	"RobotService/internal/entities"
+	"RobotService/internal/events"
+	"RobotService/internal/rbac"
+	"RobotService/internal/repositories"
+	"RobotService/internal/reservation"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"slices"
+	"sync"
+	"time"
+)
+
+const defaultMotionTick = 500 * time.Millisecond
+
+// Активный маршрут начинается не там, где робот сейчас стоит
+var ErrStalePath = errors.New("active path does not start at the robot position")
+
+// Команды перемещения и движок, который их выполняет. Маршрут команды резервирует ячейки
+// пространство-время (см. reservations.go), и раз в Tick робот встаёт туда, где ему положено быть
+// по расписанию, через те же проверки карты и столкновений, что и ручное перемещение.
+// mu защищает только состояние в памяти (команды, таблицу, номер тика): в базу и редиску ходим без неё,
+// чтобы медленная база не держала запросы к движку и распределитель миссий.
+// Движок один на процесс: выполняемые команды он поднимает из базы при старте,
+// несколько экземпляров сервиса вели бы одних и тех же роботов
+type MotionService struct {
+	Repository repositories.CommandRepository
+	Robots     *RbtSrvic
+	Navigation *NavigationService
+	Audit      *AuditService
+	Tick       time.Duration
+	// Сторона вокселя таблицы резервирования и сколько тиков за маршрут робот может ждать
+	VoxelSize int
+	MaxWait   int
+
+	mu     sync.Mutex
+	active map[int]*motion
+	// Роботы, чья команда сейчас создаётся: второй раз их не отправить
+	starting map[int]bool
+	table    *reservation.Table
+	// Номер текущего тика и время нулевого
+	slot  int64
+	epoch time.Time
+}
+
+// Выполняемая команда и её расписание: timeline[i] - сколько пути пройдено к тику start+i.
+// timeline = nil - расписания нет (после старта, ожидания или помехи), строим заново на ближайшем тике.
+// command подменяется целиком под mu, timeline и start трогает только цикл движка
+type motion struct {
+	command  *entities.MoveCommand
+	timeline []float64
+	start    int64
+	// Команда из POST /robots/{id}/move, а не шаг миссии: по её окончании робот свободен.
+	// После рестарта это не знаем, такие команды считаем шагами миссий, робота подберёт плановый проход распределителя
+	standalone bool
+}
+
+func (srv *MotionService) ready() {
+	if srv.active == nil {
+		srv.active = map[int]*motion{}
+	}
+	if srv.starting == nil {
+		srv.starting = map[int]bool{}
+	}
+	if srv.table == nil {
+		srv.table = reservation.New()
+	}
+	if srv.epoch.IsZero() {
+		srv.epoch = time.Now()
+	}
+}
+
+// Принимаем команду: строим маршрут (или берём активный), резервируем его и отдаём движку
+func (srv *MotionService) Move(ctx context.Context, robotID int, data dto.MoveDTO) (*entities.MoveCommand, error) {
+	return srv.start(ctx, robotID, data, auth.Actor(ctx), true)
+}
+
+// Команда для шага миссии. actor - от чьего имени команда, для миссий это их автор
+func (srv *MotionService) move(ctx context.Context, robotID int, data dto.MoveDTO, actor string) (*entities.MoveCommand, error) {
+	return srv.start(ctx, robotID, data, actor, false)
+}
+
+func (srv *MotionService) start(ctx context.Context, robotID int, data dto.MoveDTO, actor string, standalone bool) (*entities.MoveCommand, error) {
+	if err := srv.Robots.Lockdown.Check(ctx, rbac.RobotsMove); err != nil {
+		return nil, err
+	}
+	var plan *entities.NavigationPlan
+	var err error
+	if data.UseActivePath {
+		plan, err = srv.activePath(robotID)
+	} else {
+		plan, err = srv.Navigation.Plan(robotID, entities.RobotCord{XCord: data.XCord, YCord: data.YCord, ZCord: data.ZCord})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	srv.mu.Lock()
+	srv.ready()
+	if srv.busy(robotID) {
+		srv.mu.Unlock()
+		return nil, repositories.ErrRobotBusy
+	}
+	srv.starting[robotID] = true
+	srv.mu.Unlock()
+	defer func() {
+		srv.mu.Lock()
+		delete(srv.starting, robotID)
+		srv.mu.Unlock()
+	}()
+
+	robot, err := srv.Robots.RobotRepository.GetRobotInfo(robotID)
+	if err != nil {
+		return nil, err
+	}
+	// Пока строили маршрут, робота могли передвинуть
+	if robot.Cord() != plan.From {
+		return nil, fmt.Errorf("%w: robot moved while the path was planned", ErrStalePath)
+	}
+	draft := &entities.MoveCommand{
+		RobotID:     robotID,
+		Status:      entities.CommandRunning,
+		Target:      plan.To,
+		Waypoints:   plan.Waypoints,
+		Length:      plan.Length,
+		Position:    plan.From,
+		RequestedBy: actor,
+	}
+	// Пока команды нет в базе, её ячейки держит временный владелец -robotID
+	owner := -robotID
+	sched, base, replanned, err := srv.reserve(owner, *robot, draft, 0)
+	if err != nil {
+		return nil, err
+	}
+	command, err := srv.Repository.CreateCommand(*draft)
+	srv.mu.Lock()
+	if err != nil {
+		srv.table.Release(owner)
+	} else {
+		srv.table.Transfer(owner, command.ID)
+		running := *command
+		srv.active[command.ID] = &motion{command: &running, timeline: sched.timeline, start: base, standalone: standalone}
+	}
+	srv.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	details := fmt.Sprintf("move command %d to (%d, %d, %d)", command.ID, plan.To.XCord, plan.To.YCord, plan.To.ZCord)
+	if replanned {
+		details += ", replanned around reserved cells"
+	}
+	srv.Audit.Record(ctx, string(rbac.RobotsMove), robotID, nil, nil, nil, details)
+	srv.Robots.Lockdown.Observe(ctx, rbac.RobotsMove)
+	return command, nil
+}
+
+func (srv *MotionService) activePath(robotID int) (*entities.NavigationPlan, error) {
+	robot, err := srv.Robots.RobotRepository.GetRobotInfo(robotID)
+	if err != nil {
+		return nil, err
+	}
+	plan, err := srv.Navigation.GetPath(robotID)
+	if err != nil {
+		return nil, err
+	}
+	if plan.From != robot.Cord() {
+		return nil, fmt.Errorf("%w: path starts at (%d, %d, %d)", ErrStalePath, plan.From.XCord, plan.From.YCord, plan.From.ZCord)
+	}
+	return plan, nil
+}
+
+// Под mu: у робота есть выполняемая команда или она как раз создаётся
+func (srv *MotionService) busy(robotID int) bool {
+	if srv.starting[robotID] {
+		return true
+	}
+	for _, m := range srv.active {
+		if m.command.RobotID == robotID {
+			return true
+		}
+	}
+	return false
+}
+
+// Под mu: роботы, чьё место в таблице резервирования, а не среди стоящих.
+// Команды, которые ещё пишутся в базу, держат ячейки на временном владельце -robotID
+func (srv *MotionService) moving() map[int]bool {
+	moving := map[int]bool{}
+	for _, m := range srv.active {
+		moving[m.command.RobotID] = true
+	}
+	for _, owner := range srv.table.Owners() {
+		if owner < 0 {
+			moving[-owner] = true
+		}
+	}
+	return moving
+}
+
+// Роботы с выполняемыми или создаваемыми командами
+func (srv *MotionService) movingRobots() map[int]bool {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	srv.ready()
+	moving := srv.moving()
+	for robotID := range srv.starting {
+		moving[robotID] = true
+	}
+	return moving
+}
+
+func (srv *MotionService) GetCommand(id int) (*entities.MoveCommand, error) {
+	return srv.Repository.GetCommand(id)
+}
+
+func (srv *MotionService) ListCommands(robotID int, status string, limit, offset int) ([]entities.MoveCommand, error) {
+	return srv.Repository.ListCommands(robotID, status, limit, offset)
+}
+
+// Робот останавливается там, где его застала отмена, резервирование освобождается.
+// Если движок как раз делает шаг этой команды, его сохранение увидит отмену и шаг отпустит ячейки сам
+func (srv *MotionService) Cancel(ctx context.Context, id int) (*entities.MoveCommand, error) {
+	cancelled, err := srv.Repository.CancelCommand(id)
+	if err != nil {
+		return nil, err
+	}
+	srv.mu.Lock()
+	srv.ready()
+	m := srv.active[id]
+	delete(srv.active, id)
+	srv.table.Release(id)
+	srv.mu.Unlock()
+	srv.Audit.Record(ctx, string(rbac.RobotsMove), cancelled.RobotID, nil, nil, nil, fmt.Sprintf("move command %d cancelled", id))
+	if m != nil && m.standalone {
+		srv.publishIdle(cancelled, auth.Actor(ctx))
+	}
+	return cancelled, nil
+}
+
+// Цикл движка, живёт до отмены ctx
+func (srv *MotionService) Run(ctx context.Context) error {
+	running, err := srv.Repository.RunningCommands()
+	if err != nil {
+		return err
+	}
+	srv.mu.Lock()
+	srv.ready()
+	for i := range running {
+		srv.active[running[i].ID] = &motion{command: &running[i]}
+	}
+	srv.mu.Unlock()
+
+	ticker := time.NewTicker(srv.tick())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			srv.advance()
+		}
+	}
+}
+
+func (srv *MotionService) tick() time.Duration {
+	if srv.Tick <= 0 {
+		return defaultMotionTick
+	}
+	return srv.Tick
+}
+
+// Один тик: все выполняемые команды, старые первыми. Под mu только сдвиг тика и список команд,
+// сами шаги читают и пишут базу без блокировки и берут её лишь на операции с таблицей
+func (srv *MotionService) advance() {
+	srv.mu.Lock()
+	srv.ready()
+	srv.slot++
+	slot := srv.slot
+	// Прошлый тик ещё нужен: от него строится расписание взамен сорванного
+	srv.table.Prune(slot - 1)
+	ids := make([]int, 0, len(srv.active))
+	for id := range srv.active {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	motions := make([]*motion, len(ids))
+	for i, id := range ids {
+		motions[i] = srv.active[id]
+	}
+	srv.mu.Unlock()
+
+	for _, m := range motions {
+		command := *m.command
+		srv.step(m, &command, slot)
+		srv.mu.Lock()
+		m.command = &command
+		// Отменённую через Cancel команду он уже убрал и сам сообщил о свободном роботе
+		ended := command.Finished() && srv.active[command.ID] == m
+		// Команду могли отменить, пока шёл шаг: её ячейки, занятые шагом, тоже отпускаем
+		if command.Finished() || srv.active[command.ID] != m {
+			delete(srv.active, command.ID)
+			srv.table.Release(command.ID)
+		}
+		srv.mu.Unlock()
+		if ended && m.standalone {
+			srv.publishIdle(&command, command.RequestedBy)
+		}
+	}
+}
+
+// Отдельная команда закончилась, робот свободен: то же событие, что и в конце миссии, его ждёт распределитель.
+// Шаги миссий так не сообщаем, робот остаётся на миссии до её конца
+func (srv *MotionService) publishIdle(command *entities.MoveCommand, actor string) {
+	robotStatus := entities.RobotStatus{RobotID: command.RobotID, Status: entities.RobotIdle}
+	srv.Robots.publishToRabbitWithJSON(robotStatus, keystatus, actor)
+	srv.Robots.Events.Publish(events.RobotEvent{Kind: events.KindStatusChanged, RoutingKey: keystatus, RobotID: command.RobotID,
+		Message: fmt.Sprintf("robot %d is %s after move command %d %s", command.RobotID, entities.RobotIdle, command.ID, command.Status), Actor: actor})
+}
+
+// Шаг одной команды в тике slot. command - копия, advance вернёт её в motion
+func (srv *MotionService) step(m *motion, command *entities.MoveCommand, slot int64) {
+	// Движок работает сам по себе, без запроса. Автор команды виден в её событиях PositionChanged
+	ctx := context.Background()
+	robot, err := srv.Robots.RobotRepository.GetRobotInfo(command.RobotID)
+	if errors.Is(err, repositories.ErrRobotNotFound) {
+		srv.finish(ctx, command, entities.CommandFailed, "robot was deleted")
+		return
+	}
+	if err != nil {
+		log.Println("Движок: не удалось прочитать робота", command.RobotID, err)
+		return
+	}
+	if robot.Cord() != command.Position {
+		srv.finish(ctx, command, entities.CommandFailed, "robot was moved outside the command")
+		return
+	}
+	if err = srv.Robots.Lockdown.Check(ctx, rbac.RobotsMove); err != nil {
+		srv.unschedule(m, command.ID)
+		srv.block(command, err.Error())
+		return
+	}
+	if m.timeline == nil {
+		if err = srv.reschedule(ctx, m, command, *robot); err != nil {
+			srv.block(command, err.Error())
+			return
+		}
+	}
+
+	i := min(slot-m.start, int64(len(m.timeline)-1))
+	traveled := m.timeline[i]
+	next := pointAt(command.Waypoints, traveled)
+	if next != robot.Cord() {
+		// Карта могла поменяться после планирования
+		if err = srv.Robots.checkCords(robot, next); err != nil {
+			srv.finish(ctx, command, entities.CommandFailed, err.Error())
+			return
+		}
+		err = srv.Robots.writeCords(robot, robot.ID, next)
+		var collision *CollisionError
+		switch {
+		case errors.As(err, &collision):
+			// Помеха вне резервирования (робот без команды, ручное перемещение). Ждём и строим расписание заново.
+			// Событие шлём один раз, а не на каждом тике
+			if command.Blocked == "" {
+				srv.Robots.collisionPrevented(ctx, *robot, err)
+			}
+			srv.unschedule(m, command.ID)
+			srv.block(command, err.Error())
+			return
+		case errors.Is(err, repositories.ErrRobotNotFound):
+			srv.finish(ctx, command, entities.CommandFailed, "robot was deleted")
+			return
+		case err != nil:
+			log.Println("Движок: не удалось сдвинуть робота", command.RobotID, err)
+			srv.unschedule(m, command.ID)
+			return
+		}
+		from := robot.Cord()
+		robot.XCord, robot.YCord, robot.ZCord = next.XCord, next.YCord, next.ZCord
+		srv.Robots.publishPositionChanged(from, robot, command.RequestedBy)
+	}
+
+	command.Blocked = ""
+	if traveled == command.Traveled && traveled < command.Length && slot > m.start {
+		command.Blocked = "waiting for a reserved cell"
+	}
+	command.Traveled, command.Position = traveled, next
+	if traveled >= command.Length {
+		srv.finish(ctx, command, entities.CommandCompleted, "")
+		return
+	}
+	// Длинное расписание обрезано, продолжение строим, когда доедем до его конца
+	if i == int64(len(m.timeline)-1) {
+		srv.unschedule(m, command.ID)
+	}
+	srv.save(command)
+}
+
+// Робот сошёл с расписания: отпускаем его ячейки, на следующем тике построим новое
+func (srv *MotionService) unschedule(m *motion, commandID int) {
+	srv.mu.Lock()
+	srv.table.Release(commandID)
+	srv.mu.Unlock()
+	m.timeline = nil
+}
+
+// Расписание от прошлого тика, чтобы робот поехал уже на текущем
+func (srv *MotionService) reschedule(ctx context.Context, m *motion, command *entities.MoveCommand, robot entities.Robot) error {
+	srv.mu.Lock()
+	srv.table.Release(command.ID)
+	srv.mu.Unlock()
+	sched, base, replanned, err := srv.reserve(command.ID, robot, command, 1)
+	if err != nil {
+		return err
+	}
+	m.timeline, m.start = sched.timeline, base
+	if replanned {
+		srv.Audit.Record(ctx, string(rbac.RobotsMove), robot.ID, nil, nil, nil,
+			fmt.Sprintf("move command %d replanned around reserved cells", command.ID))
+	}
+	return nil
+}
+
+// Тип робота из реестра, nil - реестра нет или тип не найден
+func (srv *MotionService) robotType(typeName string) *entities.RobotType {
+	if srv.Robots.Types == nil {
+		return nil
+	}
+	robotType, err := srv.Robots.Types.lookup(typeName)
+	if err != nil {
+		return nil
+	}
+	return robotType
+}
+
+func (srv *MotionService) block(command *entities.MoveCommand, reason string) {
+	command.Blocked = reason
+	srv.save(command)
+}
+
+func (srv *MotionService) finish(ctx context.Context, command *entities.MoveCommand, status, reason string) {
+	command.Status, command.Error, command.Blocked = status, reason, ""
+	srv.save(command)
+	var opErr error
+	if reason != "" {
+		opErr = errors.New(reason)
+	}
+	srv.Audit.Record(ctx, string(rbac.RobotsMove), command.RobotID, nil, nil, opErr, fmt.Sprintf("move command %d %s", command.ID, status))
+}
+
+// Сохраняем ход. Если команду уже завершили в обход движка, просто её отпускаем
+func (srv *MotionService) save(command *entities.MoveCommand) {
+	saved, err := srv.Repository.UpdateCommand(*command)
+	switch {
+	case errors.Is(err, repositories.ErrCommandFinished), errors.Is(err, repositories.ErrCommandNotFound):
+		command.Status = entities.CommandCancelled
+	case err != nil:
+		log.Println("Движок: не удалось сохранить команду", command.ID, err)
+	default:
+		*command = *saved
+	}
+}
+
+// Целая точка на ломаной, пройдя по ней traveled от начала
+func pointAt(waypoints []entities.RobotCord, traveled float64) entities.RobotCord {
+	if len(waypoints) == 0 {
+		return entities.RobotCord{}
+	}
+	for i := 1; i < len(waypoints); i++ {
+		from, to := waypoints[i-1], waypoints[i]
+		length := distance(from, to)
+		if traveled > length {
+			traveled -= length
+			continue
+		}
+		t := 0.0
+		if length > 0 {
+			t = traveled / length
+		}
+		return entities.RobotCord{
+			XCord: from.XCord + int(math.Round(t*float64(to.XCord-from.XCord))),
+			YCord: from.YCord + int(math.Round(t*float64(to.YCord-from.YCord))),
+			ZCord: from.ZCord + int(math.Round(t*float64(to.ZCord-from.ZCord))),
+		}
+	}
+	return waypoints[len(waypoints)-1]
+}
@@ -0,0 +1,211 @@
+package services
+
+import (
+	"RobotService/internal/dto"
+	"RobotService/internal/entities"
+	"RobotService/internal/events"
+	"RobotService/internal/repositories"
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestPointAt(t *testing.T) {
+	corner := []entities.RobotCord{at(0, 0, 0), at(4, 0, 0), at(4, 3, 0)}
+	tests := []struct {
+		name      string
+		waypoints []entities.RobotCord
+		traveled  float64
+		want      entities.RobotCord
+	}{
+		{"start", corner, 0, at(0, 0, 0)},
+		{"first segment", corner, 2.4, at(2, 0, 0)},
+		{"rounds half up", corner, 2.5, at(3, 0, 0)},
+		// Ровно на стыке - конец первого отрезка, а не начало второго
+		{"segment boundary", corner, 4, at(4, 0, 0)},
+		{"second segment", corner, 5, at(4, 1, 0)},
+		{"end", corner, 7, at(4, 3, 0)},
+		{"past the end", corner, 100, at(4, 3, 0)},
+		{"diagonal", []entities.RobotCord{at(0, 0, 0), at(3, 4, 0)}, 2.5, at(2, 2, 0)},
+		{"vertical down", []entities.RobotCord{at(1, 1, 10), at(1, 1, 0)}, 3, at(1, 1, 7)},
+		{"zero-length segment", []entities.RobotCord{at(0, 0, 0), at(0, 0, 0), at(2, 0, 0)}, 1, at(1, 0, 0)},
+		{"single point", []entities.RobotCord{at(3, 3, 3)}, 5, at(3, 3, 3)},
+		{"no waypoints", nil, 5, at(0, 0, 0)},
+	}
+	for _, tt := range tests {
+		if got := pointAt(tt.waypoints, tt.traveled); got != tt.want {
+			t.Errorf("%s: pointAt(%v) = %v, want %v", tt.name, tt.traveled, got, tt.want)
+		}
+	}
+}
+
+// Робот, который проезжает 1 за тик
+func newMovingRobot(t *testing.T, f *testFixture, name string) *entities.Robot {
+	t.Helper()
+	slow := f.robotType + "-slow"
+	f.createType(t, dto.CreateRobotTypeDTO{Name: slow, MaxSpeed: 1})
+	return f.createRobot(t, entities.Robot{Name: name, Type: slow})
+}
+
+// Тикаем, пока команда не закончится
+func runToEnd(t *testing.T, motion *MotionService, commandID int) *entities.MoveCommand {
+	t.Helper()
+	for range 20 {
+		motion.advance()
+		command, err := motion.GetCommand(commandID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if command.Finished() {
+			return command
+		}
+	}
+	t.Fatalf("command %d did not finish", commandID)
+	return nil
+}
+
+// Сколько событий status_changed о роботе уже пришло
+func statusEvents(ch <-chan events.RobotEvent, robotID int) int {
+	count := 0
+	for {
+		select {
+		case event := <-ch:
+			if event.Kind == events.KindStatusChanged && event.RobotID == robotID {
+				count++
+			}
+		default:
+			return count
+		}
+	}
+}
+
+func TestMoveRunsToCompletion(t *testing.T) {
+	f := newTestFixture(t)
+	motion := newTestMotion(f, 3)
+	robot := newMovingRobot(t, f, "motion-mover")
+	ch, unsubscribe := f.robots.Events.Subscribe(64)
+	defer unsubscribe()
+
+	ctx := actorContext("alice", "operator")
+	target := f.at(3, 0, 0)
+	command, err := motion.Move(ctx, robot.ID, dto.MoveDTO{XCord: target.XCord, YCord: target.YCord})
+	if err != nil {
+		t.Fatalf("Move: %v", err)
+	}
+	if command.Status != entities.CommandRunning || command.RequestedBy != "alice" {
+		t.Fatalf("command = %+v", command)
+	}
+
+	// Первый тик - один шаг, команда ещё едет
+	motion.advance()
+	running, err := motion.GetCommand(command.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if running.Status != entities.CommandRunning || running.Position != f.at(1, 0, 0) || running.Traveled != 1 {
+		t.Fatalf("after one tick: %+v", running)
+	}
+	if statusEvents(ch, robot.ID) != 0 {
+		t.Fatal("status_changed before the command ended")
+	}
+
+	done := runToEnd(t, motion, command.ID)
+	if done.Status != entities.CommandCompleted || done.Position != target || done.Traveled != done.Length {
+		t.Fatalf("finished command = %+v", done)
+	}
+	stored, err := f.robots.RobotRepository.GetRobotInfo(robot.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Cord() != target {
+		t.Fatalf("robot at %v, want %v", stored.Cord(), target)
+	}
+	// Отдельная команда: о свободном роботе сообщаем один раз, ячеек за ней не остаётся
+	if n := statusEvents(ch, robot.ID); n != 1 {
+		t.Fatalf("%d status_changed events, want 1", n)
+	}
+	motion.mu.Lock()
+	defer motion.mu.Unlock()
+	if motion.active[command.ID] != nil || len(motion.table.Cells(command.ID)) != 0 {
+		t.Fatal("finished command is still scheduled")
+	}
+}
+
+func TestMoveCancelled(t *testing.T) {
+	f := newTestFixture(t)
+	motion := newTestMotion(f, 3)
+	robot := newMovingRobot(t, f, "motion-cancelled")
+	ch, unsubscribe := f.robots.Events.Subscribe(64)
+	defer unsubscribe()
+
+	ctx := actorContext("alice", "operator")
+	target := f.at(10, 0, 0)
+	command, err := motion.Move(ctx, robot.ID, dto.MoveDTO{XCord: target.XCord, YCord: target.YCord})
+	if err != nil {
+		t.Fatalf("Move: %v", err)
+	}
+	motion.advance()
+	motion.advance()
+
+	cancelled, err := motion.Cancel(ctx, command.ID)
+	if err != nil {
+		t.Fatalf("Cancel: %v", err)
+	}
+	if cancelled.Status != entities.CommandCancelled || cancelled.Position != f.at(2, 0, 0) {
+		t.Fatalf("cancelled command = %+v", cancelled)
+	}
+	if n := statusEvents(ch, robot.ID); n != 1 {
+		t.Fatalf("%d status_changed events, want 1", n)
+	}
+
+	// Робот остался там, где его застала отмена, следующие тики его не двигают
+	motion.advance()
+	stored, err := f.robots.RobotRepository.GetRobotInfo(robot.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Cord() != f.at(2, 0, 0) {
+		t.Fatalf("robot at %v after cancel", stored.Cord())
+	}
+	motion.mu.Lock()
+	cells := motion.table.Cells(command.ID)
+	motion.mu.Unlock()
+	if len(cells) != 0 {
+		t.Fatalf("cancelled command holds %v", cells)
+	}
+	if _, err = motion.Cancel(ctx, command.ID); !errors.Is(err, repositories.ErrCommandFinished) {
+		t.Fatalf("second cancel: err = %v, want ErrCommandFinished", err)
+	}
+}
+
+// Шаг миссии: робот остаётся на миссии, о свободном роботе сообщает она, а не движок
+func TestMissionStepDoesNotPublishStatus(t *testing.T) {
+	f := newTestFixture(t)
+	motion := newTestMotion(f, 3)
+	robot := newMovingRobot(t, f, "motion-mission")
+	ch, unsubscribe := f.robots.Events.Subscribe(64)
+	defer unsubscribe()
+
+	target := f.at(2, 0, 0)
+	command, err := motion.move(context.Background(), robot.ID, dto.MoveDTO{XCord: target.XCord, YCord: target.YCord}, "bob")
+	if err != nil {
+		t.Fatalf("move: %v", err)
+	}
+	if done := runToEnd(t, motion, command.ID); done.Status != entities.CommandCompleted || done.RequestedBy != "bob" {
+		t.Fatalf("finished command = %+v", done)
+	}
+	if n := statusEvents(ch, robot.ID); n != 0 {
+		t.Fatalf("%d status_changed events for a mission step", n)
+	}
+
+	second, err := motion.move(context.Background(), robot.ID, dto.MoveDTO{XCord: f.base, YCord: f.base}, "bob")
+	if err != nil {
+		t.Fatalf("move back: %v", err)
+	}
+	if _, err = motion.Cancel(actorContext("bob", "operator"), second.ID); err != nil {
+		t.Fatalf("Cancel: %v", err)
+	}
+	if n := statusEvents(ch, robot.ID); n != 0 {
+		t.Fatalf("%d status_changed events for a cancelled mission step", n)
+	}
+}
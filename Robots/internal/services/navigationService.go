@@ -0,0 +1,166 @@
+package services
+
+import (
+	"RobotService/internal/dto"
+	"RobotService/internal/entities"
+	"RobotService/internal/pathfind"
+	"RobotService/internal/rbac"
+	"RobotService/internal/repositories"
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	// Сетка не больше стольких ячеек по самой длинной стороне, в большом мире ячейка крупнее единицы.
+	// Проход уже ячейки на такой сетке можно не найти
+	maxGridSide = 128
+	// Сколько ячеек раскрывает один поиск, дальше отвечаем, что путь слишком сложный
+	maxPathExpanded = 200000
+	// Запас вокруг старта, цели и препятствий, когда у мира нет границ
+	minGridPadding = 16
+)
+
+// Маршруты в обход препятствий и геозон по карте мира
+type NavigationService struct {
+	Repository repositories.NavigationRepository
+	Robots     *RbtSrvic
+	Audit      *AuditService
+}
+
+// Маршрут робота от текущей позиции до цели. Со store маршрут становится активным маршрутом робота
+func (srv *NavigationService) Navigate(ctx context.Context, robotID int, target dto.NavigateDTO) (*entities.NavigationPlan, error) {
+	plan, err := srv.Plan(robotID, entities.RobotCord{XCord: target.XCord, YCord: target.YCord, ZCord: target.ZCord})
+	if err != nil {
+		return nil, err
+	}
+	if !target.Store {
+		return plan, nil
+	}
+	if err = srv.Repository.SavePath(*plan); err != nil {
+		return nil, err
+	}
+	srv.Audit.Record(ctx, string(rbac.RobotsMove), robotID, nil, nil, nil,
+		fmt.Sprintf("active path to (%d, %d, %d), %d waypoints", target.XCord, target.YCord, target.ZCord, len(plan.Waypoints)))
+	return plan, nil
+}
+
+// Только расчёт, ничего не сохраняем
+func (srv *NavigationService) Plan(robotID int, to entities.RobotCord) (*entities.NavigationPlan, error) {
+	return srv.plan(robotID, to, nil)
+}
+
+// avoid - дополнительно занятые точки, например зарезервированные другими роботами
+func (srv *NavigationService) plan(robotID int, to entities.RobotCord, avoid func(entities.RobotCord) bool) (*entities.NavigationPlan, error) {
+	robot, err := srv.Robots.RobotRepository.GetRobotInfo(robotID)
+	if err != nil {
+		return nil, err
+	}
+	var robotType *entities.RobotType
+	if srv.Robots.Types != nil {
+		if robotType, err = srv.Robots.Types.lookup(robot.Type); err != nil {
+			return nil, err
+		}
+	}
+	positionCheck, err := srv.Robots.World.positionCheck()
+	if err != nil {
+		return nil, err
+	}
+	world, err := srv.Robots.World.mapSnapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	// Цель проверяем отдельно, чтобы ответить, чем именно она плоха, а не просто "пути нет"
+	if err = positionCheck(to); err != nil {
+		return nil, err
+	}
+	if robotType != nil {
+		if err = checkAltitude(robotType, to.ZCord); err != nil {
+			return nil, err
+		}
+	}
+
+	from := robot.Cord()
+	grid := navigationGrid(world, from, to)
+	// Старт свободен всегда: робот уже там, даже если зону поставили поверх него
+	grid.Free = func(cord entities.RobotCord) bool {
+		if cord == from {
+			return true
+		}
+		if robotType != nil && !robotType.AllowsAltitude(cord.ZCord) {
+			return false
+		}
+		if avoid != nil && avoid(cord) {
+			return false
+		}
+		return positionCheck(cord) == nil
+	}
+	waypoints, err := grid.Find(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &entities.NavigationPlan{
+		RobotID:   robotID,
+		From:      from,
+		To:        to,
+		Waypoints: waypoints,
+		Length:    pathfind.Length(waypoints),
+		CreatedAt: time.Now().UTC(),
+	}
+	if robotType != nil && robotType.MaxSpeed > 0 {
+		seconds := plan.Length / robotType.MaxSpeed
+		plan.EstimatedSeconds = &seconds
+	}
+	return plan, nil
+}
+
+func (srv *NavigationService) GetPath(robotID int) (*entities.NavigationPlan, error) {
+	return srv.Repository.GetPath(robotID)
+}
+
+func (srv *NavigationService) DeletePath(ctx context.Context, robotID int) error {
+	if err := srv.Repository.DeletePath(robotID); err != nil {
+		return err
+	}
+	srv.Audit.Record(ctx, string(rbac.RobotsMove), robotID, nil, nil, nil, "active path cleared")
+	return nil
+}
+
+// Сетка вокселей по карте: границы мира, а без них - коробка вокруг старта, цели и
+// запрещённых зон с запасом. Старт всегда внутри, даже если робот стоит за границами мира
+func navigationGrid(world *entities.World, from, to entities.RobotCord) *pathfind.Grid {
+	lo := entities.RobotCord{XCord: min(from.XCord, to.XCord), YCord: min(from.YCord, to.YCord), ZCord: min(from.ZCord, to.ZCord)}
+	hi := entities.RobotCord{XCord: max(from.XCord, to.XCord), YCord: max(from.YCord, to.YCord), ZCord: max(from.ZCord, to.ZCord)}
+	if world.Bounds != nil {
+		lo = entities.RobotCord{XCord: min(lo.XCord, world.Bounds.MinX), YCord: min(lo.YCord, world.Bounds.MinY), ZCord: min(lo.ZCord, world.Bounds.MinZ)}
+		hi = entities.RobotCord{XCord: max(hi.XCord, world.Bounds.MaxX), YCord: max(hi.YCord, world.Bounds.MaxY), ZCord: max(hi.ZCord, world.Bounds.MaxZ)}
+	} else {
+		for _, zone := range world.Zones {
+			if !zone.Blocks() {
+				continue
+			}
+			lo.XCord, lo.YCord = min(lo.XCord, zone.MinX), min(lo.YCord, zone.MinY)
+			hi.XCord, hi.YCord = max(hi.XCord, zone.MaxX), max(hi.YCord, zone.MaxY)
+			if zone.MinZ != nil {
+				lo.ZCord = min(lo.ZCord, *zone.MinZ)
+			}
+			if zone.MaxZ != nil {
+				hi.ZCord = max(hi.ZCord, *zone.MaxZ)
+			}
+		}
+		span := max(hi.XCord-lo.XCord, hi.YCord-lo.YCord, hi.ZCord-lo.ZCord)
+		pad := max(minGridPadding, span/4)
+		lo = entities.RobotCord{XCord: lo.XCord - pad, YCord: lo.YCord - pad, ZCord: lo.ZCord - pad}
+		hi = entities.RobotCord{XCord: hi.XCord + pad, YCord: hi.YCord + pad, ZCord: hi.ZCord + pad}
+	}
+	span := max(hi.XCord-lo.XCord, hi.YCord-lo.YCord, hi.ZCord-lo.ZCord)
+	return &pathfind.Grid{
+		Step:        max(1, int(math.Ceil(float64(span)/maxGridSide))),
+		Min:         lo,
+		Max:         hi,
+		MaxExpanded: maxPathExpanded,
+	}
+}
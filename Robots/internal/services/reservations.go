@@ -0,0 +1,336 @@
+package services
+
+import (
+	"RobotService/internal/entities"
+	"RobotService/internal/pathfind"
+	"RobotService/internal/reservation"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Расписание не длиннее стольких тиков, дальше оно строится заново, когда это кончится
+const maxScheduleSlots = 7200
+
+var (
+	// Маршрут не удалось разложить по свободным ячейкам ни ожиданием, ни обходом
+	ErrReservationConflict = errors.New("path conflicts with reserved cells")
+	// У команды нет резервирования: она завершена или ещё ждёт расписания
+	ErrNoReservation = errors.New("command has no reservation")
+)
+
+// Расписание команды: timeline[i] - пройденный путь к тику base+i, cells - занятые им ячейки
+type schedule struct {
+	timeline []float64
+	cells    []reservation.Cell
+}
+
+// Что мешает роботу при расписании: стоящие без команд роботы и чужие резервирования.
+// Робот занимает воксели своего радиуса безопасности, стоящий робот - своего.
+// nearby и start читаются из базы без блокировки, parked из них собирает park уже под mu
+type scheduleScope struct {
+	owner  int
+	radius float64
+	speed  float64
+	nearby map[int][]reservation.Voxel
+	start  []reservation.Voxel
+	parked map[reservation.Voxel]bool
+}
+
+func (srv *MotionService) voxelSize() int {
+	if srv.VoxelSize <= 0 {
+		return 1
+	}
+	return srv.VoxelSize
+}
+
+func (srv *MotionService) maxWait() int {
+	if srv.MaxWait < 0 {
+		return 0
+	}
+	return srv.MaxWait
+}
+
+// Расписание по текущему маршруту команды с резервированием его ячеек за owner, от тика srv.slot-lag.
+// Если оно упирается в чужие ячейки - по маршруту в обход них, тогда второе значение true и маршрут уже записан в command.
+// Базу (типы, соседи, карта для обхода) читаем без mu, под ней только считаем расписание и занимаем ячейки
+func (srv *MotionService) reserve(owner int, robot entities.Robot, command *entities.MoveCommand, lag int64) (*schedule, int64, bool, error) {
+	scope, err := srv.scope(owner, robot, command.Waypoints)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	srv.mu.Lock()
+	sched, base, err := srv.claim(scope, command.Waypoints, command.Length, command.Traveled, lag)
+	if !errors.Is(err, ErrReservationConflict) {
+		srv.mu.Unlock()
+		return sched, base, false, err
+	}
+	// Обходим и стоящих роботов, и воксели, которые кто-то занимает хотя бы в одном слоте.
+	// Ищем по снимку, чтобы не держать блокировку на чтении карты и поиске
+	blocked := srv.table.ContestedVoxels(owner)
+	for voxel := range scope.parked {
+		blocked[voxel] = true
+	}
+	srv.mu.Unlock()
+
+	avoid := func(cord entities.RobotCord) bool {
+		for _, voxel := range srv.footprint(cord, scope.radius) {
+			if blocked[voxel] {
+				return true
+			}
+		}
+		return false
+	}
+	plan, planErr := srv.Navigation.plan(robot.ID, command.Target, avoid)
+	if errors.Is(planErr, pathfind.ErrNoPath) || errors.Is(planErr, pathfind.ErrSearchLimit) {
+		return nil, 0, false, fmt.Errorf("%w: no detour: %v", ErrReservationConflict, planErr)
+	}
+	if planErr != nil {
+		return nil, 0, false, planErr
+	}
+	if scope, err = srv.scope(owner, robot, plan.Waypoints); err != nil {
+		return nil, 0, false, err
+	}
+	// Пока искали обход, таблица могла измениться, тогда это обычный конфликт
+	srv.mu.Lock()
+	sched, base, err = srv.claim(scope, plan.Waypoints, plan.Length, 0, lag)
+	srv.mu.Unlock()
+	if err != nil {
+		return nil, 0, false, err
+	}
+	command.Waypoints, command.Length, command.Traveled = plan.Waypoints, plan.Length, 0
+	return sched, base, true, nil
+}
+
+// Под mu: расписание от тика srv.slot-lag и захват его ячеек. Ячейки проверены под той же блокировкой,
+// так что занять их раньше нас никто не успеет
+func (srv *MotionService) claim(scope *scheduleScope, waypoints []entities.RobotCord, length, traveled float64, lag int64) (*schedule, int64, error) {
+	srv.ready()
+	srv.park(scope)
+	base := srv.slot - lag
+	sched, err := srv.schedule(scope, waypoints, length, traveled, base)
+	if err != nil {
+		return nil, base, err
+	}
+	if err = srv.table.Claim(scope.owner, sched.cells); err != nil {
+		return nil, base, err
+	}
+	return sched, base, nil
+}
+
+// Радиус, скорость и воксели роботов вокруг маршрута. Читает базу, вызывается без mu
+func (srv *MotionService) scope(owner int, robot entities.Robot, waypoints []entities.RobotCord) (*scheduleScope, error) {
+	scope := &scheduleScope{owner: owner, nearby: map[int][]reservation.Voxel{}}
+	if robotType := srv.robotType(robot.Type); robotType != nil {
+		scope.speed = robotType.MaxSpeed
+	}
+	radii, largest := map[string]float64{}, 0.0
+	if srv.Robots.Collisions != nil {
+		var err error
+		if radii, largest, err = srv.Robots.Collisions.safetyRadii(); err != nil {
+			return nil, err
+		}
+	}
+	scope.radius = radii[robot.Type]
+	if len(waypoints) == 0 {
+		return scope, nil
+	}
+	scope.start = srv.footprint(waypoints[0], scope.radius)
+
+	// Кандидаты - шар вокруг рамки маршрута
+	low, high := waypoints[0], waypoints[0]
+	for _, point := range waypoints[1:] {
+		low = entities.RobotCord{XCord: min(low.XCord, point.XCord), YCord: min(low.YCord, point.YCord), ZCord: min(low.ZCord, point.ZCord)}
+		high = entities.RobotCord{XCord: max(high.XCord, point.XCord), YCord: max(high.YCord, point.YCord), ZCord: max(high.ZCord, point.ZCord)}
+	}
+	center := entities.RobotCord{XCord: low.XCord + (high.XCord-low.XCord)/2, YCord: low.YCord + (high.YCord-low.YCord)/2, ZCord: low.ZCord + (high.ZCord-low.ZCord)/2}
+	reach := distance(low, high)/2 + scope.radius + largest + 2*float64(srv.voxelSize())
+	ids := []int{}
+	for _, neighbor := range srv.Robots.RobotRepository.Index.Nearest(center, reach, math.MaxInt) {
+		if neighbor.ID != robot.ID {
+			ids = append(ids, neighbor.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return scope, nil
+	}
+	others, err := srv.Robots.RobotRepository.GetRobots(ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, other := range others {
+		scope.nearby[other.ID] = srv.footprint(other.Cord(), radii[other.Type])
+	}
+	return scope, nil
+}
+
+// Под mu: стоящие - соседи без выполняемых команд, едущих учитывает таблица
+func (srv *MotionService) park(scope *scheduleScope) {
+	moving := srv.moving()
+	scope.parked = map[reservation.Voxel]bool{}
+	for id, voxels := range scope.nearby {
+		if moving[id] {
+			continue
+		}
+		for _, voxel := range voxels {
+			scope.parked[voxel] = true
+		}
+	}
+	// Робот, уже стоящий слишком близко к другому, должен иметь возможность отъехать
+	for _, voxel := range scope.start {
+		delete(scope.parked, voxel)
+	}
+}
+
+// Кооперативное расписание: на каждом тике робот либо проезжает speed*tick по маршруту, если все ячейки
+// на этом отрезке свободны, либо ждёт на месте. Ждать больше maxWait тиков или там, где нас переедут, нельзя
+func (srv *MotionService) schedule(scope *scheduleScope, waypoints []entities.RobotCord, length, traveled float64, base int64) (*schedule, error) {
+	sched := &schedule{timeline: []float64{traveled}}
+	free := func(cells []reservation.Cell) bool {
+		for _, cell := range cells {
+			if scope.parked[cell.Voxel] || !srv.table.Free(cell, scope.owner) {
+				return false
+			}
+		}
+		return true
+	}
+	// Там, где робот стоит сейчас, он уже есть, занимаем что получится
+	for _, cell := range srv.cells(srv.footprint(pointAt(waypoints, traveled), scope.radius), base) {
+		if srv.table.Free(cell, scope.owner) {
+			sched.cells = append(sched.cells, cell)
+		}
+	}
+
+	stride := math.Inf(1)
+	if scope.speed > 0 {
+		stride = scope.speed * srv.tick().Seconds()
+	}
+	waited := 0
+	for slot := base + 1; traveled < length && slot-base <= maxScheduleSlots; slot++ {
+		next := min(traveled+stride, length)
+		cells := srv.cells(srv.sweep(waypoints, traveled, next, scope.radius), slot)
+		if !free(cells) {
+			waited++
+			cells = srv.cells(srv.footprint(pointAt(waypoints, traveled), scope.radius), slot)
+			if waited > srv.maxWait() {
+				return nil, fmt.Errorf("%w: waited %d ticks at %.2f of the path", ErrReservationConflict, srv.maxWait(), traveled)
+			}
+			if !free(cells) {
+				return nil, fmt.Errorf("%w: the waiting spot at %.2f of the path is reserved", ErrReservationConflict, traveled)
+			}
+			next = traveled
+		}
+		sched.cells = append(sched.cells, cells...)
+		traveled = next
+		sched.timeline = append(sched.timeline, traveled)
+	}
+	return sched, nil
+}
+
+func (srv *MotionService) cells(voxels []reservation.Voxel, slot int64) []reservation.Cell {
+	cells := make([]reservation.Cell, len(voxels))
+	for i, voxel := range voxels {
+		cells[i] = reservation.Cell{Voxel: voxel, Slot: slot}
+	}
+	return cells
+}
+
+// Воксели куба со стороной 2*radius вокруг точки
+func (srv *MotionService) footprint(cord entities.RobotCord, radius float64) []reservation.Voxel {
+	size := srv.voxelSize()
+	reach := int(math.Ceil(radius))
+	voxel := func(value, offset int) int { return floorDiv(value+offset, size) }
+	voxels := []reservation.Voxel{}
+	for x := voxel(cord.XCord, -reach); x <= voxel(cord.XCord, reach); x++ {
+		for y := voxel(cord.YCord, -reach); y <= voxel(cord.YCord, reach); y++ {
+			for z := voxel(cord.ZCord, -reach); z <= voxel(cord.ZCord, reach); z++ {
+				voxels = append(voxels, reservation.Voxel{X: x, Y: y, Z: z})
+			}
+		}
+	}
+	return voxels
+}
+
+// Воксели, которые робот заденет, проехав маршрут от from до to (пройденный путь)
+func (srv *MotionService) sweep(waypoints []entities.RobotCord, from, to, radius float64) []reservation.Voxel {
+	seen := map[reservation.Voxel]bool{}
+	voxels := []reservation.Voxel{}
+	step := float64(srv.voxelSize()) / 2
+	for at := from; ; at += step {
+		at = min(at, to)
+		for _, voxel := range srv.footprint(pointAt(waypoints, at), radius) {
+			if !seen[voxel] {
+				seen[voxel] = true
+				voxels = append(voxels, voxel)
+			}
+		}
+		if at >= to {
+			return voxels
+		}
+	}
+}
+
+func floorDiv(a, b int) int {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
+
+// Резервирования выполняемых команд без ячеек, robotID = 0 - всех роботов
+func (srv *MotionService) Reservations(robotID int) []entities.Reservation {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	srv.ready()
+	reservations := []entities.Reservation{}
+	for _, owner := range srv.table.Owners() {
+		m := srv.active[owner]
+		if m == nil || (robotID != 0 && m.command.RobotID != robotID) {
+			continue
+		}
+		reservations = append(reservations, srv.reservation(m, false))
+	}
+	return reservations
+}
+
+// Резервирование команды со всеми ячейками
+func (srv *MotionService) Reservation(commandID int) (*entities.Reservation, error) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	srv.ready()
+	m := srv.active[commandID]
+	if m == nil || len(srv.table.Cells(commandID)) == 0 {
+		return nil, ErrNoReservation
+	}
+	view := srv.reservation(m, true)
+	return &view, nil
+}
+
+func (srv *MotionService) reservation(m *motion, withCells bool) entities.Reservation {
+	cells := srv.table.Cells(m.command.ID)
+	size := srv.voxelSize()
+	result := entities.Reservation{
+		CommandID: m.command.ID,
+		RobotID:   m.command.RobotID,
+		VoxelSize: size,
+		CellCount: len(cells),
+	}
+	if len(cells) > 0 {
+		result.FromSlot, result.ToSlot = cells[0].Slot, cells[len(cells)-1].Slot
+		result.StartsAt, result.EndsAt = srv.slotTime(result.FromSlot), srv.slotTime(result.ToSlot)
+	}
+	if withCells {
+		result.Cells = make([]entities.ReservedCell, len(cells))
+		for i, cell := range cells {
+			result.Cells[i] = entities.ReservedCell{X: cell.X * size, Y: cell.Y * size, Z: cell.Z * size, Slot: cell.Slot, At: srv.slotTime(cell.Slot)}
+		}
+	}
+	return result
+}
+
+// Примерное время слота: тикер не сбивается, так что слот n наступает через n тиков после старта
+func (srv *MotionService) slotTime(slot int64) time.Time {
+	return srv.epoch.Add(time.Duration(slot) * srv.tick()).UTC()
+}
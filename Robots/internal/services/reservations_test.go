@@ -0,0 +1,215 @@
+package services
+
+import (
+	"RobotService/internal/dto"
+	"RobotService/internal/entities"
+	"RobotService/internal/repositories"
+	"RobotService/internal/reservation"
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+// Движок без базы: расписание и таблица живут в памяти. Скорость 1 за тик, воксель 1
+func newScheduler(maxWait int) *MotionService {
+	srv := &MotionService{Tick: time.Second, VoxelSize: 1, MaxWait: maxWait}
+	srv.ready()
+	return srv
+}
+
+var straight = []entities.RobotCord{at(0, 0, 0), at(5, 0, 0)}
+
+func voxelCell(x int, slot int64) reservation.Cell {
+	return reservation.Cell{Voxel: reservation.Voxel{X: x}, Slot: slot}
+}
+
+func TestScheduleFreePath(t *testing.T) {
+	srv := newScheduler(0)
+	sched, base, err := srv.claim(&scheduleScope{owner: 7, speed: 1}, straight, 5, 0, 0)
+	if err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+	if base != 0 || !slices.Equal(sched.timeline, []float64{0, 1, 2, 3, 4, 5}) {
+		t.Fatalf("base %d, timeline %v", base, sched.timeline)
+	}
+	// Ячейки в таблице за владельцем: текущая точка и каждый отрезок пути в своём слоте
+	for _, cell := range []reservation.Cell{voxelCell(0, 0), voxelCell(0, 1), voxelCell(1, 1), voxelCell(3, 4), voxelCell(5, 5)} {
+		if owner := srv.table.Owner(cell); owner != 7 {
+			t.Fatalf("cell %+v owned by %d, want 7", cell, owner)
+		}
+	}
+	if srv.table.Owner(voxelCell(5, 1)) != 0 {
+		t.Fatal("the end of the path is reserved too early")
+	}
+}
+
+func TestScheduleStartsFromLag(t *testing.T) {
+	srv := newScheduler(0)
+	srv.slot = 10
+	// Продолжение с середины пути, от прошлого тика
+	sched, base, err := srv.claim(&scheduleScope{owner: 7, speed: 2}, straight, 5, 2, 1)
+	if err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+	if base != 9 || !slices.Equal(sched.timeline, []float64{2, 4, 5}) {
+		t.Fatalf("base %d, timeline %v", base, sched.timeline)
+	}
+	if srv.table.Owner(voxelCell(2, 9)) != 7 || srv.table.Owner(voxelCell(5, 11)) != 7 {
+		t.Fatalf("cells = %v", srv.table.Cells(7))
+	}
+}
+
+func TestScheduleWaitsForReservedCell(t *testing.T) {
+	srv := newScheduler(3)
+	// Чужая команда проезжает воксель 2 в слотах 2 и 3
+	if err := srv.table.Claim(9, []reservation.Cell{voxelCell(2, 2), voxelCell(2, 3)}); err != nil {
+		t.Fatal(err)
+	}
+	sched, _, err := srv.claim(&scheduleScope{owner: 7, speed: 1}, straight, 5, 0, 0)
+	if err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+	// Два тика ждём на 1, потом едем дальше
+	if want := []float64{0, 1, 1, 1, 2, 3, 4, 5}; !slices.Equal(sched.timeline, want) {
+		t.Fatalf("timeline %v, want %v", sched.timeline, want)
+	}
+	if srv.table.Owner(voxelCell(1, 2)) != 7 || srv.table.Owner(voxelCell(1, 3)) != 7 || srv.table.Owner(voxelCell(2, 4)) != 7 {
+		t.Fatalf("cells = %v", srv.table.Cells(7))
+	}
+}
+
+func TestScheduleConflicts(t *testing.T) {
+	tests := []struct {
+		name    string
+		maxWait int
+		taken   []reservation.Cell
+		nearby  map[int][]reservation.Voxel
+	}{
+		{name: "waits too long", maxWait: 1, taken: []reservation.Cell{voxelCell(2, 2), voxelCell(2, 3)}},
+		{name: "waiting spot is reserved", maxWait: 3, taken: []reservation.Cell{voxelCell(2, 2), voxelCell(1, 2)}},
+		// Стоящий робот никуда не уедет, ждать его бесполезно
+		{name: "parked robot on the path", maxWait: 3, nearby: map[int][]reservation.Voxel{42: {{X: 3}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newScheduler(tt.maxWait)
+			if err := srv.table.Claim(9, tt.taken); err != nil {
+				t.Fatal(err)
+			}
+			_, _, err := srv.claim(&scheduleScope{owner: 7, speed: 1, nearby: tt.nearby}, straight, 5, 0, 0)
+			if !errors.Is(err, ErrReservationConflict) {
+				t.Fatalf("err = %v, want ErrReservationConflict", err)
+			}
+			// Всё или ничего: при конфликте ни одной ячейки не занято
+			if cells := srv.table.Cells(7); len(cells) != 0 {
+				t.Fatalf("cells after conflict: %v", cells)
+			}
+		})
+	}
+}
+
+func TestScheduleIgnoresMovingAndStartNeighbors(t *testing.T) {
+	srv := newScheduler(0)
+	// Робот 42 едет по своей команде, его место в таблице, а не среди стоящих.
+	// Робот 43 стоит там же, где мы: отъехать от него можно
+	srv.active[100] = &motion{command: &entities.MoveCommand{ID: 100, RobotID: 42}}
+	scope := &scheduleScope{owner: 7, speed: 1, nearby: map[int][]reservation.Voxel{42: {{X: 3}}, 43: {{X: 0}}}}
+	scope.start = srv.footprint(straight[0], 0)
+	if _, _, err := srv.claim(scope, straight, 5, 0, 0); err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+}
+
+func TestAdvancePrunesPastSlots(t *testing.T) {
+	srv := newScheduler(0)
+	srv.slot = 4
+	if err := srv.table.Claim(9, []reservation.Cell{voxelCell(0, 2), voxelCell(0, 3), voxelCell(0, 4), voxelCell(0, 6)}); err != nil {
+		t.Fatal(err)
+	}
+	// Без команд тик только сдвигает слот и забывает прошлое, кроме предыдущего тика
+	srv.advance()
+	if srv.slot != 5 {
+		t.Fatalf("slot = %d, want 5", srv.slot)
+	}
+	want := []reservation.Cell{voxelCell(0, 4), voxelCell(0, 6)}
+	if got := srv.table.Cells(9); !slices.Equal(got, want) {
+		t.Fatalf("cells = %v, want %v", got, want)
+	}
+}
+
+func TestFootprintAndSweep(t *testing.T) {
+	srv := &MotionService{VoxelSize: 2}
+	// Куб радиуса 1 вокруг (-1, 0, 0) в вокселях по 2: x от -2 до 0, y и z от -1 до 1
+	voxels := srv.footprint(at(-1, 0, 0), 1)
+	if len(voxels) != 2*2*2 || !slices.Contains(voxels, reservation.Voxel{X: -1, Y: -1, Z: -1}) || !slices.Contains(voxels, reservation.Voxel{X: 0}) {
+		t.Fatalf("footprint = %v", voxels)
+	}
+	swept := srv.sweep([]entities.RobotCord{at(0, 0, 0), at(7, 0, 0)}, 0, 7, 0)
+	if want := []reservation.Voxel{{X: 0}, {X: 1}, {X: 2}, {X: 3}}; !slices.Equal(swept, want) {
+		t.Fatalf("sweep = %v, want %v", swept, want)
+	}
+}
+
+func newTestMotion(f *testFixture, maxWait int) *MotionService {
+	return &MotionService{
+		Repository: repositories.CommandRepository{DataBase: f.db},
+		Robots:     f.robots,
+		Navigation: &NavigationService{Repository: repositories.NavigationRepository{DataBase: f.db}, Robots: f.robots, Audit: f.robots.Audit},
+		Audit:      f.robots.Audit,
+		Tick:       time.Second,
+		VoxelSize:  1,
+		MaxWait:    maxWait,
+	}
+}
+
+func TestMoveReplansAroundReservedCells(t *testing.T) {
+	f := newTestFixture(t)
+	motion := newTestMotion(f, 0)
+	robot := f.createRobot(t, entities.Robot{Name: "reservation-mover"})
+
+	// Чужая команда надолго заняла точку на прямой до цели, а ждать нельзя
+	blocked := reservation.Voxel{X: f.base + 5, Y: f.base}
+	taken := []reservation.Cell{}
+	for slot := int64(0); slot < 20; slot++ {
+		taken = append(taken, reservation.Cell{Voxel: blocked, Slot: slot})
+	}
+	motion.mu.Lock()
+	motion.ready()
+	err := motion.table.Claim(1_000_000_000, taken)
+	motion.mu.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	command, err := motion.Move(context.Background(), robot.ID, dto.MoveDTO{XCord: f.base + 10, YCord: f.base})
+	if err != nil {
+		t.Fatalf("Move: %v", err)
+	}
+	t.Cleanup(func() { _, _ = motion.Cancel(context.Background(), command.ID) })
+
+	if len(command.Waypoints) < 3 || command.Target != f.at(10, 0, 0) {
+		t.Fatalf("command = %+v, want a detour to the target", command)
+	}
+	for i := 1; i < len(command.Waypoints); i++ {
+		for _, voxel := range motion.sweep(command.Waypoints[i-1:i+1], 0, distance(command.Waypoints[i-1], command.Waypoints[i]), 0) {
+			if voxel == blocked {
+				t.Fatalf("detour %v goes through the reserved voxel", command.Waypoints)
+			}
+		}
+	}
+
+	// Ячейки временного владельца -robotID перешли к созданной команде
+	motion.mu.Lock()
+	defer motion.mu.Unlock()
+	if cells := motion.table.Cells(-robot.ID); len(cells) != 0 {
+		t.Fatalf("temporary owner still holds %v", cells)
+	}
+	if cells := motion.table.Cells(command.ID); len(cells) == 0 || motion.table.Owner(cells[0]) != command.ID {
+		t.Fatalf("command %d holds %v", command.ID, cells)
+	}
+	if motion.active[command.ID] == nil || !motion.active[command.ID].standalone {
+		t.Fatal("command is not running")
+	}
+}
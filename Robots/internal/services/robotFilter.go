@@ -0,0 +1,85 @@
+package services
+
+import (
+	"RobotService/internal/entities"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const maxSelectors = 20
+
+var ErrInvalidSelector = errors.New("invalid selector")
+
+// Операции в порядке поиска: двухсимвольные раньше, чтобы ">=" не разобрался как ">"
+var selectorOps = []string{
+	entities.SelectorNotEqual, entities.SelectorGreaterE, entities.SelectorLessE,
+	entities.SelectorEqual, entities.SelectorGreater, entities.SelectorLess,
+}
+
+var attributeKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Собираем фильтр списка из параметров запроса: тип, селектор меток (см. ParseLabelSelector)
+// и селекторы атрибутов вида battery.level>=20, model=x4, active=true, payload (атрибут просто есть)
+func ParseRobotFilter(robotType, labelSelector string, attributes []string) (entities.RobotFilter, error) {
+	filter := entities.RobotFilter{Type: robotType}
+	labels, err := ParseLabelSelector(labelSelector)
+	if err != nil {
+		return filter, err
+	}
+	filter.Labels = labels
+	if len(attributes) > maxSelectors {
+		return filter, fmt.Errorf("%w: at most %d attribute selectors", ErrInvalidSelector, maxSelectors)
+	}
+	for _, expr := range attributes {
+		selector, err := parseAttributeSelector(expr)
+		if err != nil {
+			return filter, err
+		}
+		filter.Attributes = append(filter.Attributes, selector)
+	}
+	return filter, nil
+}
+
+func parseAttributeSelector(expr string) (entities.AttributeSelector, error) {
+	selector := entities.AttributeSelector{Op: entities.SelectorExists}
+	key, value := expr, ""
+	for _, op := range selectorOps {
+		if i := strings.Index(expr, op); i >= 0 && (selector.Op == entities.SelectorExists || i < len(key)) {
+			selector.Op, key, value = op, expr[:i], expr[i+len(op):]
+		}
+	}
+
+	key = strings.TrimSpace(key)
+	selector.Path = strings.Split(key, ".")
+	for _, part := range selector.Path {
+		if !attributeKey.MatchString(part) {
+			return selector, fmt.Errorf("%w: %q: bad attribute name", ErrInvalidSelector, expr)
+		}
+	}
+	if selector.Op == entities.SelectorExists {
+		return selector, nil
+	}
+
+	// Значение - JSON литерал (число, true, null, "строка"), всё остальное считаем строкой
+	value = strings.TrimSpace(value)
+	if err := json.Unmarshal([]byte(value), &selector.Value); err != nil {
+		selector.Value = value
+	}
+	switch selector.Op {
+	case entities.SelectorEqual, entities.SelectorNotEqual:
+		if _, isObject := selector.Value.(map[string]any); isObject {
+			return selector, fmt.Errorf("%w: %q: objects cannot be compared", ErrInvalidSelector, expr)
+		}
+		if _, isArray := selector.Value.([]any); isArray {
+			return selector, fmt.Errorf("%w: %q: arrays cannot be compared", ErrInvalidSelector, expr)
+		}
+	default:
+		if _, ok := selector.Value.(float64); !ok {
+			return selector, fmt.Errorf("%w: %q: %s needs a number", ErrInvalidSelector, expr, selector.Op)
+		}
+	}
+	return selector, nil
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"RobotService/internal/entities"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const maxLabels = 64
+
+var ErrInvalidLabels = errors.New("invalid labels")
+
+// Правила ключей и значений как у Kubernetes: необязательный DNS префикс через /,
+// имя и значение до 63 символов из букв, цифр, -, _ и ., по краям буква или цифра
+var (
+	labelName   = regexp.MustCompile(`^[A-Za-z0-9]([-A-Za-z0-9_.]{0,61}[A-Za-z0-9])?$`)
+	labelPrefix = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+	setSelector = regexp.MustCompile(`^(\S+)\s+(in|notin)\s*\((.*)\)$`)
+)
+
+func validLabelKey(key string) bool {
+	prefix, name, found := strings.Cut(key, "/")
+	if !found {
+		return labelName.MatchString(key)
+	}
+	return len(prefix) <= 253 && labelPrefix.MatchString(prefix) && labelName.MatchString(name)
+}
+
+func validLabelValue(value string) bool {
+	return value == "" || labelName.MatchString(value)
+}
+
+func validateLabels(labels map[string]string) error {
+	if len(labels) > maxLabels {
+		return fmt.Errorf("%w: at most %d labels", ErrInvalidLabels, maxLabels)
+	}
+	for key, value := range labels {
+		if !validLabelKey(key) {
+			return fmt.Errorf("%w: bad key %q", ErrInvalidLabels, key)
+		}
+		if !validLabelValue(value) {
+			return fmt.Errorf("%w: bad value %q for %s", ErrInvalidLabels, value, key)
+		}
+	}
+	return nil
+}
+
+// Разбор селектора меток: site=wh2,team!=qa,env in (prod,stage),env notin (dev),gpu,!deprecated.
+// Пустая строка - без условий
+func ParseLabelSelector(expr string) ([]entities.LabelSelector, error) {
+	var selectors []entities.LabelSelector
+	for _, term := range splitSelector(expr) {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			if strings.TrimSpace(expr) == "" {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("%w: %q: empty requirement", ErrInvalidSelector, expr)
+		}
+		selector, err := parseLabelRequirement(term)
+		if err != nil {
+			return nil, err
+		}
+		selectors = append(selectors, selector)
+	}
+	if len(selectors) > maxSelectors {
+		return nil, fmt.Errorf("%w: at most %d label requirements", ErrInvalidSelector, maxSelectors)
+	}
+	return selectors, nil
+}
+
+// Делим по запятым вне скобок, запятые внутри in (...) разделяют значения
+func splitSelector(expr string) []string {
+	var terms []string
+	depth, start := 0, 0
+	for i, r := range expr {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				terms = append(terms, expr[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(terms, expr[start:])
+}
+
+func parseLabelRequirement(term string) (entities.LabelSelector, error) {
+	selector := entities.LabelSelector{}
+	switch {
+	case strings.HasPrefix(term, "!"):
+		selector.Key, selector.Op = strings.TrimSpace(term[1:]), entities.LabelNotExists
+	case setSelector.MatchString(term):
+		match := setSelector.FindStringSubmatch(term)
+		selector.Key, selector.Op = match[1], match[2]
+		for _, value := range strings.Split(match[3], ",") {
+			selector.Values = append(selector.Values, strings.TrimSpace(value))
+		}
+	case strings.Contains(term, "!="):
+		key, value, _ := strings.Cut(term, "!=")
+		selector.Key, selector.Op, selector.Values = strings.TrimSpace(key), entities.LabelNotEquals, []string{strings.TrimSpace(value)}
+	case strings.Contains(term, "="):
+		key, value, _ := strings.Cut(term, "=")
+		value = strings.TrimPrefix(value, "=")
+		selector.Key, selector.Op, selector.Values = strings.TrimSpace(key), entities.LabelEquals, []string{strings.TrimSpace(value)}
+	default:
+		selector.Key, selector.Op = term, entities.LabelExists
+	}
+
+	if !validLabelKey(selector.Key) {
+		return selector, fmt.Errorf("%w: %q: bad label key", ErrInvalidSelector, term)
+	}
+	for _, value := range selector.Values {
+		if !validLabelValue(value) {
+			return selector, fmt.Errorf("%w: %q: bad label value %q", ErrInvalidSelector, term, value)
+		}
+	}
+	return selector, nil
+}
@@ -0,0 +1,185 @@
+package services
+
+import (
+	"RobotService/internal/entities"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestParseLabelSelector(t *testing.T) {
+	tests := []struct {
+		expr string
+		want []entities.LabelSelector
+	}{
+		{expr: "", want: nil},
+		{expr: "  ", want: nil},
+		{expr: "site=wh2", want: []entities.LabelSelector{{Key: "site", Op: entities.LabelEquals, Values: []string{"wh2"}}}},
+		{expr: "site==wh2", want: []entities.LabelSelector{{Key: "site", Op: entities.LabelEquals, Values: []string{"wh2"}}}},
+		{expr: " site = wh2 ", want: []entities.LabelSelector{{Key: "site", Op: entities.LabelEquals, Values: []string{"wh2"}}}},
+		{expr: "team!=qa", want: []entities.LabelSelector{{Key: "team", Op: entities.LabelNotEquals, Values: []string{"qa"}}}},
+		{expr: "env in (prod, stage)", want: []entities.LabelSelector{{Key: "env", Op: entities.LabelIn, Values: []string{"prod", "stage"}}}},
+		{expr: "env notin (dev)", want: []entities.LabelSelector{{Key: "env", Op: entities.LabelNotIn, Values: []string{"dev"}}}},
+		{expr: "gpu", want: []entities.LabelSelector{{Key: "gpu", Op: entities.LabelExists}}},
+		{expr: "!deprecated", want: []entities.LabelSelector{{Key: "deprecated", Op: entities.LabelNotExists}}},
+		{expr: "example.com/tier=", want: []entities.LabelSelector{{Key: "example.com/tier", Op: entities.LabelEquals, Values: []string{""}}}},
+		{
+			expr: "site=wh2,env in (prod,stage),gpu,!deprecated",
+			want: []entities.LabelSelector{
+				{Key: "site", Op: entities.LabelEquals, Values: []string{"wh2"}},
+				{Key: "env", Op: entities.LabelIn, Values: []string{"prod", "stage"}},
+				{Key: "gpu", Op: entities.LabelExists},
+				{Key: "deprecated", Op: entities.LabelNotExists},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := ParseLabelSelector(tt.expr)
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLabelSelectorErrors(t *testing.T) {
+	tests := []string{
+		"site=wh2,",
+		",site=wh2",
+		"site=wh2,,gpu",
+		"=wh2",
+		"site=wh 2",
+		"site=-wh2",
+		"env in (prod,",
+		"env in (prod,st@ge)",
+		"!",
+		"bad key",
+		"Example.com/tier=gold",
+		"/tier=gold",
+		"site=" + strings.Repeat("a", 64),
+		strings.TrimSuffix(strings.Repeat("gpu,", maxSelectors+1), ","),
+	}
+	for _, expr := range tests {
+		t.Run(expr, func(t *testing.T) {
+			if got, err := ParseLabelSelector(expr); !errors.Is(err, ErrInvalidSelector) {
+				t.Fatalf("got %+v, err = %v, want ErrInvalidSelector", got, err)
+			}
+		})
+	}
+}
+
+func TestLabelSelectorMatches(t *testing.T) {
+	labels := map[string]string{"site": "wh2", "env": "prod", "gpu": ""}
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"site=wh2", true},
+		{"site==wh1", false},
+		{"site!=wh1", true},
+		{"site!=wh2", false},
+		// != и notin пропускают роботов без метки
+		{"team!=qa", true},
+		{"team notin (qa)", true},
+		{"env in (prod,stage)", true},
+		{"env in (dev)", false},
+		{"env notin (prod)", false},
+		{"team in (qa)", false},
+		{"gpu", true},
+		{"team", false},
+		{"!team", true},
+		{"!gpu", false},
+		{"site=wh2,env in (prod),!team", true},
+		{"site=wh2,env=stage", false},
+		{"", true},
+	}
+	for _, tt := range tests {
+		selectors, err := ParseLabelSelector(tt.expr)
+		if err != nil {
+			t.Fatalf("%q: %v", tt.expr, err)
+		}
+		if got := entities.MatchLabels(selectors, labels); got != tt.want {
+			t.Errorf("%q matches = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestValidateLabels(t *testing.T) {
+	many := map[string]string{}
+	for i := 0; i <= maxLabels; i++ {
+		many["l"+strconv.Itoa(i)] = "v"
+	}
+	tests := []struct {
+		name   string
+		labels map[string]string
+		valid  bool
+	}{
+		{name: "nil", valid: true},
+		{name: "plain", labels: map[string]string{"site": "wh2", "team": "ops-1", "tier": "a.b_c"}, valid: true},
+		{name: "prefixed key", labels: map[string]string{"robots.example.com/zone": "north"}, valid: true},
+		{name: "empty value", labels: map[string]string{"gpu": ""}, valid: true},
+		{name: "63 characters", labels: map[string]string{strings.Repeat("k", 63): strings.Repeat("v", 63)}, valid: true},
+		{name: "empty key", labels: map[string]string{"": "v"}},
+		{name: "long key", labels: map[string]string{strings.Repeat("k", 64): "v"}},
+		{name: "long value", labels: map[string]string{"site": strings.Repeat("v", 64)}},
+		{name: "space in value", labels: map[string]string{"site": "wh 2"}},
+		{name: "value edge", labels: map[string]string{"site": "wh2-"}},
+		{name: "key edge", labels: map[string]string{"_site": "wh2"}},
+		{name: "upper case prefix", labels: map[string]string{"Robots.example.com/zone": "north"}},
+		{name: "empty name", labels: map[string]string{"robots.example.com/": "north"}},
+		{name: "too many", labels: many},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateLabels(tt.labels)
+			if tt.valid && err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			if !tt.valid && !errors.Is(err, ErrInvalidLabels) {
+				t.Fatalf("err = %v, want ErrInvalidLabels", err)
+			}
+		})
+	}
+}
+
+func TestParseRobotFilter(t *testing.T) {
+	filter, err := ParseRobotFilter("drone", "site=wh2", []string{"battery.level>=20", "model=x4", "active=true", "payload"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := entities.RobotFilter{
+		Type:   "drone",
+		Labels: []entities.LabelSelector{{Key: "site", Op: entities.LabelEquals, Values: []string{"wh2"}}},
+		Attributes: []entities.AttributeSelector{
+			{Path: []string{"battery", "level"}, Op: entities.SelectorGreaterE, Value: 20.0},
+			{Path: []string{"model"}, Op: entities.SelectorEqual, Value: "x4"},
+			{Path: []string{"active"}, Op: entities.SelectorEqual, Value: true},
+			{Path: []string{"payload"}, Op: entities.SelectorExists},
+		},
+	}
+	if !reflect.DeepEqual(filter, want) {
+		t.Fatalf("filter = %+v, want %+v", filter, want)
+	}
+
+	for _, tt := range []struct{ labels, attr string }{
+		{labels: "env in (prod"},
+		{attr: "battery.level>high"},
+		{attr: "bad key=1"},
+		{attr: `model={"a":1}`},
+		{attr: "model!=[1]"},
+	} {
+		var attrs []string
+		if tt.attr != "" {
+			attrs = []string{tt.attr}
+		}
+		if _, err = ParseRobotFilter("", tt.labels, attrs); !errors.Is(err, ErrInvalidSelector) {
+			t.Errorf("%+v: err = %v, want ErrInvalidSelector", tt, err)
+		}
+	}
+}
@@ -1,12 +1,21 @@
 package services
 
 import (
+	"RobotService/internal/auth"
 	"RobotService/internal/dto"
 	"RobotService/internal/entities"
+	"RobotService/internal/events"
+	"RobotService/internal/lockdown"
+	"RobotService/internal/prometheusinfo"
 	"RobotService/internal/rabbit"
+	"RobotService/internal/rbac"
 	"RobotService/internal/repositories"
+	"RobotService/internal/robotio"
 	"RobotService/internal/sorrage"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 
 	"log"
 	"strconv"
@@ -14,41 +23,104 @@ import (
 )
 
 const (
-	keyadd         = "robots.Add"
-	keyget         = "robots.Get"
-	keyupdatecords = "robots.UpdateCord"
-	keyupdatename  = "robots.UpdateName"
-	keyupdatetype  = "robots.UpdateType"
-	keydel         = "robots.Del"
+	keyadd          = "robots.Add"
+	keyget          = "robots.Get"
+	keyupdatecords  = "robots.UpdateCord"
+	keyupdatename   = "robots.UpdateName"
+	keyupdatetype   = "robots.UpdateType"
+	keydel          = "robots.Del"
+	keyimport       = "robots.Import"
+	keyupdateattrs  = "robots.UpdateAttributes"
+	keyupdatelabels = "robots.UpdateLabels"
+	// Общие события групповых команд над флотом
+	keyfleetmove         = "robots.FleetMove"
+	keyfleetretype       = "robots.FleetRetype"
+	keyfleetdecommission = "robots.FleetDecommission"
+	// Робот пересёк границу зоны карты
+	keyzoneentered = "robots.ZoneEntered"
+	keyzoneleft    = "robots.ZoneLeft"
+	keycollision   = "robots.CollisionPrevented"
+	keyposition    = "robots.PositionChanged"
+	// Ход миссий, тело - миссия джесоном
+	keymissionprogress  = "robots.MissionProgress"
+	keymissioncompleted = "robots.MissionCompleted"
+	keymissionfailed    = "robots.MissionFailed"
+	// Робот взял миссию или освободился, тело - entities.RobotStatus
+	keystatus = "robots.StatusChanged"
 )
 
+// Все routing key, которые сервис отправляет в эксчендж robots
+var EventTypes = []string{keyadd, keyget, keyupdatecords, keyupdatename, keyupdatetype, keydel, keyimport, keyupdateattrs, keyupdatelabels,
+	keyfleetmove, keyfleetretype, keyfleetdecommission, keyzoneentered, keyzoneleft,
+	keycollision, keyposition, keymissionprogress, keymissioncompleted, keymissionfailed, keystatus}
+
 type RbtSrvic struct {
 	RobotRepository repositories.RobotRepositories
 	Redis           *sorrage.RdsCache
 	Rabbit          *rabbit.Publisher
+	Events          *events.Hub
+	Audit           *AuditService
+	Approvals       *ApprovalService
+	// Защитный режим: проверяется перед изменением, успешные изменения идут в его счётчики
+	Lockdown *lockdown.Guard
+	// Реестр типов, по нему проверяем создание, перемещение и смену типа
+	Types *RobotTypeService
+	// Карта мира: роботов не пускаем за границы, в препятствия и геозоны
+	World *WorldService
+	// Проверка столкновений при создании и перемещении
+	Collisions *CollisionChecker
 }
 
-func (srvc *RbtSrvic) CreateRobot(dto dto.CreateRobotDTO) (int, error) {
+func (srvc *RbtSrvic) CreateRobot(ctx context.Context, dto dto.CreateRobotDTO) (int, error) {
 	robot := entities.Robot{
-		Name:  dto.Name,
-		Type:  dto.Type,
-		XCord: dto.XCord,
-		YCord: dto.YCord,
-		ZCord: dto.ZCord,
+		Name:       dto.Name,
+		Type:       dto.Type,
+		XCord:      dto.XCord,
+		YCord:      dto.YCord,
+		ZCord:      dto.ZCord,
+		Attributes: dto.Attributes,
+		Labels:     dto.Labels,
+	}
+	if err := srvc.Lockdown.Check(ctx, rbac.RobotsCreate); err != nil {
+		return 0, err
+	}
+	if err := validateLabels(robot.Labels); err != nil {
+		return 0, err
+	}
+	if err := srvc.Types.validateRobot(robot); err != nil {
+		return 0, err
+	}
+	if err := srvc.World.checkPosition(robot.Cord()); err != nil {
+		return 0, err
+	}
+	srvc.Collisions.lock()
+	if err := srvc.Collisions.check(robot, robot.Cord(), robot.Cord(), nil); err != nil {
+		srvc.Collisions.unlock()
+		return 0, srvc.collisionPrevented(ctx, robot, err)
 	}
 	createdRobot, err := srvc.RobotRepository.CreateRobot(robot)
+	srvc.Collisions.unlock()
+	if err != nil {
+		srvc.Audit.Record(ctx, string(rbac.RobotsCreate), 0, nil, &robot, err, "")
+		return 0, err
+	}
+	srvc.Audit.Record(ctx, string(rbac.RobotsCreate), createdRobot.ID, nil, &createdRobot, nil, "")
+	srvc.Lockdown.Observe(ctx, rbac.RobotsCreate)
+	prometheusinfo.ObserveRobotOperation(string(rbac.RobotsCreate), &createdRobot)
 	// После создания робота закидываем его данные в редиску
 	_ = srvc.Redis.SetRobotData(strconv.Itoa(createdRobot.ID), createdRobot, 5*time.Minute)
-	srvc.publishToRabbitWithStruct(&createdRobot, keyadd)
-	return createdRobot.ID, err
+	actor := auth.Actor(ctx)
+	srvc.publishToRabbitWithStruct(&createdRobot, keyadd, actor)
+	srvc.Events.Publish(events.RobotEvent{Kind: events.KindCreated, RoutingKey: keyadd, RobotID: createdRobot.ID, Robot: &createdRobot, Actor: actor})
+	return createdRobot.ID, nil
 }
 
-func (serv *RbtSrvic) GetRobotInfo(id int) (*entities.Robot, error) {
+func (serv *RbtSrvic) GetRobotInfo(ctx context.Context, id int) (*entities.Robot, error) {
 	idStr := strconv.Itoa(id)
 	// Пытаемся получить данные из кэша, если они есть - получаем ошибку и идём дальше по коду, если данные есть то ретёрним их
 	robotdata, err := serv.Redis.GetRobotData(idStr)
 	if err == nil {
-		serv.publishToRabbitWithStruct(robotdata, keyget)
+		serv.publishToRabbitWithStruct(robotdata, keyget, auth.Actor(ctx))
 		return robotdata, nil
 	}
 	// Если данных в кэше нет, то обращаемся к репозиторию и получаем данные из БД
@@ -58,69 +130,423 @@ func (serv *RbtSrvic) GetRobotInfo(id int) (*entities.Robot, error) {
 	}
 	// Добавляем полученные данные в кэш на пять минут
 	err = serv.Redis.SetRobotData(idStr, *robotdata, 5*time.Minute)
-	serv.publishToRabbitWithStruct(robotdata, keyget)
+	serv.publishToRabbitWithStruct(robotdata, keyget, auth.Actor(ctx))
 	return robotdata, err
 }
 
-func (srv *RbtSrvic) UpdateRobotCords(updateData dto.UpdateRobotCordDTO) error {
+func (srv *RbtSrvic) UpdateRobotCords(ctx context.Context, updateData dto.UpdateRobotCordDTO) error {
 	newCord := entities.RobotCord{XCord: updateData.XCord, YCord: updateData.YCord, ZCord: updateData.ZCord}
 	robotID := updateData.ID
-	err := srv.RobotRepository.UpdateRobotCords(robotID, newCord)
+	if err := srv.Lockdown.Check(ctx, rbac.RobotsMove); err != nil {
+		return err
+	}
+	before, err := srv.robotBefore(robotID)
 	if err != nil {
 		return err
 	}
+	if err = srv.checkCords(before, newCord); err != nil {
+		return err
+	}
+	if err = srv.writeCords(before, robotID, newCord); err != nil {
+		var collision *CollisionError
+		if errors.As(err, &collision) {
+			return srv.collisionPrevented(ctx, *before, err)
+		}
+		srv.Audit.Record(ctx, string(rbac.RobotsMove), robotID, before, nil, err, "")
+		return err
+	}
 	// Удаление кэша после обновления
 	_ = srv.Redis.DeleteRobotData(strconv.Itoa(robotID))
 	msgToRabbit := fmt.Sprintf("Координаты робота с ID: %d были изменены на  X:%d, Y:%d, Z:%d", robotID, newCord.XCord, newCord.YCord, newCord.ZCord)
-	srv.publishToRabbitWithText(msgToRabbit, keyupdatecords)
+	actor := auth.Actor(ctx)
+	srv.publishToRabbitWithText(msgToRabbit, keyupdatecords, actor)
+	after := srv.publishUpdated(robotID, msgToRabbit, keyupdatecords, actor)
+	if before != nil && after != nil {
+		srv.publishZoneTransitions(before.Cord(), after, actor)
+	}
+	srv.Audit.Record(ctx, string(rbac.RobotsMove), robotID, before, after, nil, "")
+	srv.Lockdown.Observe(ctx, rbac.RobotsMove)
+	prometheusinfo.ObserveRobotOperation(string(rbac.RobotsMove), after)
 	return nil
 }
 
-func (sv *RbtSrvic) UpdateRobotName(updateData dto.UpdateRobotNameDTO) error {
+// Состояние робота до изменения для аудита и событий. Робота нет - nil без ошибки,
+// об этом скажет сама запись. Другие ошибки базы возвращаем, а не делаем вид, что робота нет
+func (srv *RbtSrvic) robotBefore(id int) (*entities.Robot, error) {
+	robot, err := srv.RobotRepository.GetRobotInfo(id)
+	if errors.Is(err, repositories.ErrRobotNotFound) {
+		return nil, nil
+	}
+	return robot, err
+}
+
+// Может ли робот встать в точку: высота по типу и карта. before = nil - робота нет, запись это и скажет
+func (srv *RbtSrvic) checkCords(before *entities.Robot, newCord entities.RobotCord) error {
+	if before != nil {
+		if err := srv.Types.validateAltitude(before.Type, newCord.ZCord); err != nil {
+			return err
+		}
+	}
+	return srv.World.checkPosition(newCord)
+}
+
+// Проверка столкновений и запись новой позиции под одной блокировкой. Столкновение - *CollisionError
+func (srv *RbtSrvic) writeCords(before *entities.Robot, robotID int, newCord entities.RobotCord) error {
+	srv.Collisions.lock()
+	defer srv.Collisions.unlock()
+	if before != nil {
+		if err := srv.Collisions.check(*before, before.Cord(), newCord, nil); err != nil {
+			return err
+		}
+	}
+	return srv.RobotRepository.UpdateRobotCords(robotID, newCord)
+}
+
+func (sv *RbtSrvic) UpdateRobotName(ctx context.Context, updateData dto.UpdateRobotNameDTO) error {
 	newName := updateData.Name
 	robotID := updateData.ID
-	err := sv.RobotRepository.UpdateRobotName(robotID, newName)
+	if err := sv.Lockdown.Check(ctx, rbac.RobotsRename); err != nil {
+		return err
+	}
+	before, err := sv.robotBefore(robotID)
 	if err != nil {
 		return err
 	}
+	err = sv.RobotRepository.UpdateRobotName(robotID, newName)
+	if err != nil {
+		sv.Audit.Record(ctx, string(rbac.RobotsRename), robotID, before, nil, err, "")
+		return err
+	}
 	// Удаление кэша после обновления
 	_ = sv.Redis.DeleteRobotData(strconv.Itoa(robotID))
 	msgToRabbit := fmt.Sprintf("Имя робота с ID: %d было изменены на %s", robotID, newName)
-	sv.publishToRabbitWithText(msgToRabbit, keyupdatename)
+	actor := auth.Actor(ctx)
+	sv.publishToRabbitWithText(msgToRabbit, keyupdatename, actor)
+	after := sv.publishUpdated(robotID, msgToRabbit, keyupdatename, actor)
+	sv.Audit.Record(ctx, string(rbac.RobotsRename), robotID, before, after, nil, "")
+	sv.Lockdown.Observe(ctx, rbac.RobotsRename)
+	prometheusinfo.ObserveRobotOperation(string(rbac.RobotsRename), after)
 	return nil
 }
 
-func (ssrv *RbtSrvic) ChangeRobotType(updateData dto.ChangeTypeDTO) error {
+func (ssrv *RbtSrvic) ChangeRobotType(ctx context.Context, updateData dto.ChangeTypeDTO) error {
 	newType := updateData.Type
 	robotID := updateData.ID
-	err := ssrv.RobotRepository.ChangeRobotType(robotID, newType)
+	// Заявку в защитном режиме тоже не создаём
+	if err := ssrv.Lockdown.Check(ctx, rbac.RobotsRetype); err != nil {
+		return err
+	}
+	// Тип проверяем до заявки, чтобы не одобрять заведомо невозможное
+	before, err := ssrv.robotBefore(robotID)
 	if err != nil {
 		return err
 	}
+	if before != nil {
+		retyped := *before
+		retyped.Type = newType
+		if err := ssrv.Types.validateRobot(retyped); err != nil {
+			return err
+		}
+	}
+	if ssrv.Approvals.required(ctx, rbac.RobotsRetype) {
+		return ssrv.Approvals.request(ctx, rbac.RobotsRetype, robotID, newType)
+	}
+	err = ssrv.RobotRepository.ChangeRobotType(robotID, newType)
+	if err != nil {
+		ssrv.Audit.Record(ctx, string(rbac.RobotsRetype), robotID, before, nil, err, approvalNote(ctx))
+		return err
+	}
 	// Удаление кэша после обновления
 	_ = ssrv.Redis.DeleteRobotData(strconv.Itoa(robotID))
 	msgToRabbit := fmt.Sprintf("Тип робота с ID: %d был изменен на %s", robotID, newType)
-	ssrv.publishToRabbitWithText(msgToRabbit, keyupdatetype)
+	actor := auth.Actor(ctx)
+	ssrv.publishToRabbitWithText(msgToRabbit, keyupdatetype, actor)
+	after := ssrv.publishUpdated(robotID, msgToRabbit, keyupdatetype, actor)
+	ssrv.Audit.Record(ctx, string(rbac.RobotsRetype), robotID, before, after, nil, approvalNote(ctx))
+	ssrv.Lockdown.Observe(ctx, rbac.RobotsRetype)
+	prometheusinfo.ObserveRobotOperation(string(rbac.RobotsRetype), after)
 	return nil
 }
 
-func (srv *RbtSrvic) DeleteRobot(id int) error {
+func (srv *RbtSrvic) DeleteRobot(ctx context.Context, id int) error {
+	if err := srv.Lockdown.Check(ctx, rbac.RobotsDelete); err != nil {
+		return err
+	}
+	if srv.Approvals.required(ctx, rbac.RobotsDelete) {
+		return srv.Approvals.request(ctx, rbac.RobotsDelete, id, "")
+	}
+	// Запоминаем робота до удаления, чтобы подписчики знали, кого именно не стало
+	lastState, err := srv.robotBefore(id)
+	if err != nil {
+		return err
+	}
+	err = srv.RobotRepository.DeleteRobot(id)
+	srv.Audit.Record(ctx, string(rbac.RobotsDelete), id, lastState, nil, err, approvalNote(ctx))
+	if err != nil {
+		return err
+	}
+	srv.Lockdown.Observe(ctx, rbac.RobotsDelete)
+	prometheusinfo.ObserveRobotOperation(string(rbac.RobotsDelete), lastState)
+	_ = srv.Redis.DeleteRobotData(strconv.Itoa(id))
 	msgToRabbit := fmt.Sprintf("Робота с ID: %d был уничтожен. Помянем...", id)
-	srv.publishToRabbitWithText(msgToRabbit, keydel)
+	actor := auth.Actor(ctx)
+	srv.publishToRabbitWithText(msgToRabbit, keydel, actor)
+	srv.Events.Publish(events.RobotEvent{Kind: events.KindDeleted, RoutingKey: keydel, RobotID: id, Robot: lastState, Message: msgToRabbit, Actor: actor})
+	return nil
+}
+
+// Меняем атрибуты робота JSON Merge Patch'ем и проверяем результат по схеме типа
+func (srv *RbtSrvic) PatchRobotAttributes(ctx context.Context, id int, patch map[string]any) (*entities.Robot, error) {
+	if err := srv.Lockdown.Check(ctx, rbac.RobotsAttributes); err != nil {
+		return nil, err
+	}
+	before, err := srv.RobotRepository.GetRobotInfo(id)
+	if err != nil {
+		return nil, err
+	}
+	patched := *before
+	patched.Attributes = mergePatch(before.Attributes, patch)
+	if err = srv.Types.validateRobot(patched); err != nil {
+		return nil, err
+	}
+	if err = srv.RobotRepository.UpdateRobotAttributes(id, patched.Attributes); err != nil {
+		srv.Audit.Record(ctx, string(rbac.RobotsAttributes), id, before, nil, err, "")
+		return nil, err
+	}
 	_ = srv.Redis.DeleteRobotData(strconv.Itoa(id))
-	return srv.RobotRepository.DeleteRobot(id)
+	msgToRabbit := fmt.Sprintf("Атрибуты робота с ID: %d были изменены", id)
+	actor := auth.Actor(ctx)
+	srv.publishToRabbitWithText(msgToRabbit, keyupdateattrs, actor)
+	after := srv.publishUpdated(id, msgToRabbit, keyupdateattrs, actor)
+	srv.Audit.Record(ctx, string(rbac.RobotsAttributes), id, before, after, nil, "")
+	srv.Lockdown.Observe(ctx, rbac.RobotsAttributes)
+	if after == nil {
+		after = &patched
+	}
+	prometheusinfo.ObserveRobotOperation(string(rbac.RobotsAttributes), after)
+	return after, nil
+}
+
+// Заменяем все метки робота
+func (srv *RbtSrvic) SetRobotLabels(ctx context.Context, id int, labels map[string]string) (*entities.Robot, error) {
+	return srv.updateLabels(ctx, id, func(map[string]string) map[string]string {
+		return labels
+	})
+}
+
+// Точечно меняем метки: nil значение удаляет метку, остальные ставятся поверх текущих
+func (srv *RbtSrvic) PatchRobotLabels(ctx context.Context, id int, patch map[string]*string) (*entities.Robot, error) {
+	return srv.updateLabels(ctx, id, func(current map[string]string) map[string]string {
+		labels := make(map[string]string, len(current)+len(patch))
+		for key, value := range current {
+			labels[key] = value
+		}
+		for key, value := range patch {
+			if value == nil {
+				delete(labels, key)
+			} else {
+				labels[key] = *value
+			}
+		}
+		return labels
+	})
+}
+
+func (srv *RbtSrvic) updateLabels(ctx context.Context, id int, change func(map[string]string) map[string]string) (*entities.Robot, error) {
+	if err := srv.Lockdown.Check(ctx, rbac.RobotsLabels); err != nil {
+		return nil, err
+	}
+	before, err := srv.RobotRepository.GetRobotInfo(id)
+	if err != nil {
+		return nil, err
+	}
+	updated := *before
+	updated.Labels = change(before.Labels)
+	if err = validateLabels(updated.Labels); err != nil {
+		return nil, err
+	}
+	if err = srv.RobotRepository.UpdateRobotLabels(id, updated.Labels); err != nil {
+		srv.Audit.Record(ctx, string(rbac.RobotsLabels), id, before, nil, err, "")
+		return nil, err
+	}
+	_ = srv.Redis.DeleteRobotData(strconv.Itoa(id))
+	msgToRabbit := fmt.Sprintf("Метки робота с ID: %d были изменены", id)
+	actor := auth.Actor(ctx)
+	srv.publishToRabbitWithText(msgToRabbit, keyupdatelabels, actor)
+	after := srv.publishUpdated(id, msgToRabbit, keyupdatelabels, actor)
+	srv.Audit.Record(ctx, string(rbac.RobotsLabels), id, before, after, nil, "")
+	srv.Lockdown.Observe(ctx, rbac.RobotsLabels)
+	if after == nil {
+		after = &updated
+	}
+	prometheusinfo.ObserveRobotOperation(string(rbac.RobotsLabels), after)
+	return after, nil
+}
+
+// Список роботов идёт мимо кэша, в редиске лежат только отдельные роботы
+func (srv *RbtSrvic) ListRobots(filter entities.RobotFilter, limit, offset int) ([]entities.Robot, error) {
+	return srv.RobotRepository.ListRobots(filter, limit, offset)
+}
+
+// Роботы рядом с точкой по индексу позиций: до k ближайших не дальше radius, ближние первыми
+func (srv *RbtSrvic) NearRobots(center entities.RobotCord, radius float64, k int) ([]entities.NearbyRobot, error) {
+	neighbors := srv.RobotRepository.Index.Nearest(center, radius, k)
+	ids := make([]int, len(neighbors))
+	for i, neighbor := range neighbors {
+		ids[i] = neighbor.ID
+	}
+	robots, err := srv.RobotRepository.GetRobots(ids)
+	if err != nil {
+		return nil, err
+	}
+	// GetRobots сохраняет порядок и пропускает удалённые за это время
+	nearby := make([]entities.NearbyRobot, 0, len(robots))
+	for _, neighbor := range neighbors {
+		if len(nearby) < len(robots) && robots[len(nearby)].ID == neighbor.ID {
+			nearby = append(nearby, entities.NearbyRobot{Robot: robots[len(nearby)], Distance: neighbor.Distance})
+		}
+	}
+	return nearby, nil
+}
+
+func (srv *RbtSrvic) ExportRobots(w io.Writer, format string, filter entities.RobotFilter) error {
+	return robotio.Export(&srv.RobotRepository, w, format, filter)
+}
+
+func (srv *RbtSrvic) ImportRobots(ctx context.Context, r io.Reader, format string, dryRun bool) (entities.ImportReport, error) {
+	if !dryRun {
+		if err := srv.Lockdown.Check(ctx, rbac.RobotsImport); err != nil {
+			return entities.ImportReport{}, err
+		}
+	}
+	typeCheck, err := srv.Types.ImportCheck()
+	if err != nil {
+		return entities.ImportReport{}, err
+	}
+	positionCheck, err := srv.World.positionCheck()
+	if err != nil {
+		return entities.ImportReport{}, err
+	}
+	check := func(robot entities.Robot) error {
+		if err := typeCheck(robot); err != nil {
+			return err
+		}
+		return positionCheck(robot.Cord())
+	}
+	report, err := robotio.Import(&srv.RobotRepository, r, format, dryRun, check, func(robots []entities.Robot) {
+		// Перезаписанные роботы могли остаться в кэше со старыми данными
+		for _, robot := range robots {
+			if robot.ID != 0 {
+				_ = srv.Redis.DeleteRobotData(strconv.Itoa(robot.ID))
+			}
+		}
+	})
+	if !dryRun {
+		details := fmt.Sprintf("total: %d, imported: %d, failed: %d", report.Total, report.Imported, report.Failed)
+		srv.Audit.Record(ctx, string(rbac.RobotsImport), 0, nil, nil, err, details)
+	}
+	if !dryRun && report.Imported > 0 {
+		srv.Lockdown.Observe(ctx, rbac.RobotsImport)
+		msgToRabbit := fmt.Sprintf("Импортировано роботов: %d, с ошибками: %d", report.Imported, report.Failed)
+		srv.publishToRabbitWithText(msgToRabbit, keyimport, auth.Actor(ctx))
+	}
+	return report, err
+}
+
+// Отправка подписчикам внутри сервиса события об изменении робота вместе с его новым состоянием.
+// Новое состояние возвращаем, оно же идёт в аудит
+func (srv *RbtSrvic) publishUpdated(robotID int, msg, routingKey, actor string) *entities.Robot {
+	robot, err := srv.RobotRepository.GetRobotInfo(robotID)
+	if err != nil {
+		robot = nil
+	}
+	srv.Events.Publish(events.RobotEvent{Kind: events.KindUpdated, RoutingKey: routingKey, RobotID: robotID, Robot: robot, Message: msg, Actor: actor})
+	return robot
+}
+
+// События входа и выхода из зон карты для робота, который переместился из from
+func (srv *RbtSrvic) publishZoneTransitions(from entities.RobotCord, robot *entities.Robot, actor string) {
+	entered, left, err := srv.World.transitions(from, robot.Cord())
+	if err != nil {
+		log.Println("Не удалось прочитать карту для событий зон:", err)
+		return
+	}
+	publish := func(zone entities.Zone, kind, routingKey, msg string) {
+		srv.publishToRabbitWithText(msg, routingKey, actor)
+		srv.Events.Publish(events.RobotEvent{Kind: kind, RoutingKey: routingKey, RobotID: robot.ID, Robot: robot, Message: msg, Actor: actor, Zone: zone.Name})
+	}
+	for _, zone := range left {
+		publish(zone, events.KindZoneLeft, keyzoneleft, fmt.Sprintf("Робот с ID: %d покинул зону %s", robot.ID, zone.Name))
+	}
+	for _, zone := range entered {
+		publish(zone, events.KindZoneEntered, keyzoneentered, fmt.Sprintf("Робот с ID: %d вошёл в зону %s", robot.ID, zone.Name))
+	}
+}
+
+// Шаг движка перемещений: робот уже записан в новой точке from -> robot
+func (srv *RbtSrvic) publishPositionChanged(from entities.RobotCord, robot *entities.Robot, actor string) {
+	_ = srv.Redis.DeleteRobotData(strconv.Itoa(robot.ID))
+	srv.publishToRabbitWithStruct(robot, keyposition, actor)
+	srv.Events.Publish(events.RobotEvent{Kind: events.KindPositionChanged, RoutingKey: keyposition, RobotID: robot.ID, Robot: robot, Actor: actor})
+	srv.publishZoneTransitions(from, robot, actor)
+}
+
+// Если ошибка - столкновение, сообщаем о предотвращённом столкновении. Ошибку отдаём дальше как есть
+func (srv *RbtSrvic) collisionPrevented(ctx context.Context, robot entities.Robot, err error) error {
+	var collision *CollisionError
+	if !errors.As(err, &collision) {
+		return err
+	}
+	msg := fmt.Sprintf("Робот с ID: %d не перемещён: слишком близко к роботу с ID: %d (%.2f при безопасном %.2f)",
+		robot.ID, collision.Blocking.ID, collision.Distance, collision.SafetyDistance)
+	if robot.ID == 0 {
+		msg = fmt.Sprintf("Робот %s не создан: слишком близко к роботу с ID: %d (%.2f при безопасном %.2f)",
+			robot.Name, collision.Blocking.ID, collision.Distance, collision.SafetyDistance)
+	}
+	actor := auth.Actor(ctx)
+	srv.publishToRabbitWithText(msg, keycollision, actor)
+	srv.Events.Publish(events.RobotEvent{Kind: events.KindCollisionPrevented, RoutingKey: keycollision, RobotID: robot.ID, Robot: &robot, Message: msg, Actor: actor})
+	return err
 }
 
 // Отправка в реббит сообщения со струтурой робота
-func (srv *RbtSrvic) publishToRabbitWithStruct(robot *entities.Robot, routingKey string) {
-	if err := srv.Rabbit.Publish(robot, routingKey); err != nil {
+func (srv *RbtSrvic) publishToRabbitWithStruct(robot *entities.Robot, routingKey, actor string) {
+	if err := srv.Rabbit.Publish(robot, routingKey, actor); err != nil {
+		log.Println("Не получилось отправить, сорян")
+	}
+}
+
+// Отправка в реббит любой структуры джесоном
+func (srv *RbtSrvic) publishToRabbitWithJSON(value any, routingKey, actor string) {
+	if err := srv.Rabbit.PublishJSON(value, routingKey, actor); err != nil {
 		log.Println("Не получилось отправить, сорян")
 	}
 }
 
 // Отправка в реббит сообщения с текстом
-func (srv *RbtSrvic) publishToRabbitWithText(msg string, routingKey string) {
-	if err := srv.Rabbit.PublishWithText(msg, routingKey); err != nil {
+func (srv *RbtSrvic) publishToRabbitWithText(msg, routingKey, actor string) {
+	if err := srv.Rabbit.PublishWithText(msg, routingKey, actor); err != nil {
 		log.Println("Не получилось отправить, сорян")
 	}
 }
+
+// JSON Merge Patch (RFC 7396): null удаляет ключ, объекты сливаются рекурсивно, остальное заменяется
+func mergePatch(target, patch map[string]any) map[string]any {
+	result := make(map[string]any, len(target)+len(patch))
+	for key, value := range target {
+		result[key] = value
+	}
+	for key, value := range patch {
+		if value == nil {
+			delete(result, key)
+			continue
+		}
+		if sub, ok := value.(map[string]any); ok {
+			existing, _ := result[key].(map[string]any)
+			result[key] = mergePatch(existing, sub)
+			continue
+		}
+		result[key] = value
+	}
+	return result
+}
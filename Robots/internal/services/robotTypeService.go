@@ -0,0 +1,247 @@
+package services
+
+import (
+	"RobotService/internal/dto"
+	"RobotService/internal/entities"
+	"RobotService/internal/jsonschema"
+	"RobotService/internal/rbac"
+	"RobotService/internal/repositories"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+)
+
+var (
+	ErrInvalidRobotType = errors.New("invalid robot type")
+	// Ошибки проверки робота по реестру типов
+	ErrUnknownRobotType   = errors.New("unknown robot type")
+	ErrAltitudeOutOfRange = errors.New("altitude is out of range for robot type")
+	ErrInvalidAttributes  = errors.New("attributes do not match robot type schema")
+)
+
+// Имя типа идёт в метрики и роли, так что держим его коротким и без сюрпризов
+var robotTypeName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+type RobotTypeService struct {
+	Repository repositories.RobotTypeRepository
+	Audit      *AuditService
+
+	// Скомпилированные схемы атрибутов по имени типа, сбрасываются по updated_at
+	mu      sync.Mutex
+	schemas map[string]compiledSchema
+}
+
+type compiledSchema struct {
+	updatedAt time.Time
+	schema    *jsonschema.Schema
+}
+
+func (srv *RobotTypeService) CreateRobotType(ctx context.Context, data dto.CreateRobotTypeDTO) (*entities.RobotType, error) {
+	if !robotTypeName.MatchString(data.Name) {
+		return nil, fmt.Errorf("%w: name must match %s", ErrInvalidRobotType, robotTypeName)
+	}
+	robotType := entities.RobotType{
+		Name:            data.Name,
+		Description:     data.Description,
+		MaxSpeed:        data.MaxSpeed,
+		MinZ:            data.MinZ,
+		MaxZ:            data.MaxZ,
+		PayloadCapacity: data.PayloadCapacity,
+		SafetyRadius:    data.SafetyRadius,
+		AttributeSchema: data.AttributeSchema,
+	}
+	if err := validateRobotType(&robotType); err != nil {
+		return nil, err
+	}
+	created, err := srv.Repository.CreateRobotType(robotType)
+	if err != nil {
+		return nil, err
+	}
+	srv.Audit.Record(ctx, string(rbac.TypesWrite), 0, nil, nil, nil, "created type "+created.Name)
+	return created, nil
+}
+
+func (srv *RobotTypeService) GetRobotType(name string) (*entities.RobotType, error) {
+	return srv.Repository.GetRobotType(name)
+}
+
+func (srv *RobotTypeService) ListRobotTypes() ([]entities.RobotType, error) {
+	return srv.Repository.ListRobotTypes()
+}
+
+func (srv *RobotTypeService) UpdateRobotType(ctx context.Context, name string, data dto.UpdateRobotTypeDTO) (*entities.RobotType, error) {
+	robotType := entities.RobotType{
+		Name:            name,
+		Description:     data.Description,
+		MaxSpeed:        data.MaxSpeed,
+		MinZ:            data.MinZ,
+		MaxZ:            data.MaxZ,
+		PayloadCapacity: data.PayloadCapacity,
+		SafetyRadius:    data.SafetyRadius,
+		AttributeSchema: data.AttributeSchema,
+	}
+	if err := validateRobotType(&robotType); err != nil {
+		return nil, err
+	}
+	updated, err := srv.Repository.UpdateRobotType(robotType)
+	if err != nil {
+		return nil, err
+	}
+	srv.Audit.Record(ctx, string(rbac.TypesWrite), 0, nil, nil, nil, "updated type "+name)
+	return updated, nil
+}
+
+func (srv *RobotTypeService) DeleteRobotType(ctx context.Context, name string) error {
+	if err := srv.Repository.DeleteRobotType(name); err != nil {
+		return err
+	}
+	srv.Audit.Record(ctx, string(rbac.TypesWrite), 0, nil, nil, nil, "deleted type "+name)
+	return nil
+}
+
+// Проверяем робота по реестру: тип существует, высота в его диапазоне, атрибуты подходят под схему.
+// Без реестра (nil) проверку пропускаем, тип всё равно держит внешний ключ
+func (srv *RobotTypeService) validateRobot(robot entities.Robot) error {
+	if srv == nil {
+		return nil
+	}
+	robotType, err := srv.lookup(robot.Type)
+	if err != nil {
+		return err
+	}
+	if err = checkAltitude(robotType, robot.ZCord); err != nil {
+		return err
+	}
+	schema, err := srv.schemaFor(robotType)
+	if err != nil {
+		return err
+	}
+	return checkAttributes(schema, robot.Attributes)
+}
+
+// При перемещении проверяем только высоту: схему могли ужесточить позже, это не повод не пускать робота
+func (srv *RobotTypeService) validateAltitude(typeName string, z int) error {
+	if srv == nil {
+		return nil
+	}
+	robotType, err := srv.lookup(typeName)
+	if err != nil {
+		return err
+	}
+	return checkAltitude(robotType, z)
+}
+
+func (srv *RobotTypeService) lookup(typeName string) (*entities.RobotType, error) {
+	robotType, err := srv.Repository.GetRobotType(typeName)
+	if errors.Is(err, repositories.ErrRobotTypeNotFound) {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownRobotType, typeName)
+	}
+	return robotType, err
+}
+
+func (srv *RobotTypeService) schemaFor(robotType *entities.RobotType) (*jsonschema.Schema, error) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if cached, ok := srv.schemas[robotType.Name]; ok && cached.updatedAt.Equal(robotType.UpdatedAt) {
+		return cached.schema, nil
+	}
+	schema, err := jsonschema.Compile(robotType.AttributeSchema)
+	if err != nil {
+		return nil, err
+	}
+	if srv.schemas == nil {
+		srv.schemas = map[string]compiledSchema{}
+	}
+	srv.schemas[robotType.Name] = compiledSchema{updatedAt: robotType.UpdatedAt, schema: schema}
+	return schema, nil
+}
+
+func checkAttributes(schema *jsonschema.Schema, attributes map[string]any) error {
+	if attributes == nil {
+		attributes = map[string]any{}
+	}
+	if err := schema.Validate(attributes); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidAttributes, err)
+	}
+	return nil
+}
+
+// Проверка для импорта: реестр читаем один раз, а не на каждую строку. Метки проверяем и без реестра
+func (srv *RobotTypeService) ImportCheck() (func(robot entities.Robot) error, error) {
+	if srv == nil {
+		return func(robot entities.Robot) error { return validateLabels(robot.Labels) }, nil
+	}
+	robotTypes, err := srv.Repository.ListRobotTypes()
+	if err != nil {
+		return nil, err
+	}
+	registry := make(map[string]*entities.RobotType, len(robotTypes))
+	schemas := make(map[string]*jsonschema.Schema, len(robotTypes))
+	for i := range robotTypes {
+		registry[robotTypes[i].Name] = &robotTypes[i]
+		if schemas[robotTypes[i].Name], err = srv.schemaFor(&robotTypes[i]); err != nil {
+			return nil, err
+		}
+	}
+	return func(robot entities.Robot) error {
+		if err := validateLabels(robot.Labels); err != nil {
+			return err
+		}
+		robotType, ok := registry[robot.Type]
+		if !ok {
+			return fmt.Errorf("%w: %q", ErrUnknownRobotType, robot.Type)
+		}
+		if err := checkAltitude(robotType, robot.ZCord); err != nil {
+			return err
+		}
+		return checkAttributes(schemas[robot.Type], robot.Attributes)
+	}, nil
+}
+
+func checkAltitude(robotType *entities.RobotType, z int) error {
+	if robotType.AllowsAltitude(z) {
+		return nil
+	}
+	return fmt.Errorf("%w: z=%d, %s allows %s", ErrAltitudeOutOfRange, z, robotType.Name, altitudeRange(robotType))
+}
+
+func altitudeRange(robotType *entities.RobotType) string {
+	bound := func(z *int, open string) string {
+		if z == nil {
+			return open
+		}
+		return fmt.Sprint(*z)
+	}
+	return "[" + bound(robotType.MinZ, "-inf") + ", " + bound(robotType.MaxZ, "+inf") + "]"
+}
+
+func validateRobotType(robotType *entities.RobotType) error {
+	switch {
+	case robotType.MaxSpeed < 0:
+		return fmt.Errorf("%w: maxSpeed must not be negative", ErrInvalidRobotType)
+	case robotType.PayloadCapacity < 0:
+		return fmt.Errorf("%w: payloadCapacity must not be negative", ErrInvalidRobotType)
+	case robotType.SafetyRadius < 0:
+		return fmt.Errorf("%w: safetyRadius must not be negative", ErrInvalidRobotType)
+	case robotType.MinZ != nil && robotType.MaxZ != nil && *robotType.MinZ > *robotType.MaxZ:
+		return fmt.Errorf("%w: minZ must not be greater than maxZ", ErrInvalidRobotType)
+	}
+	schema := bytes.TrimSpace(robotType.AttributeSchema)
+	if len(schema) == 0 || bytes.Equal(schema, []byte("null")) {
+		robotType.AttributeSchema = json.RawMessage("{}")
+		return nil
+	}
+	var object map[string]any
+	if err := json.Unmarshal(schema, &object); err != nil {
+		return fmt.Errorf("%w: attributeSchema must be a JSON object", ErrInvalidRobotType)
+	}
+	if _, err := jsonschema.Compile(schema); err != nil {
+		return fmt.Errorf("%w: attributeSchema: %v", ErrInvalidRobotType, err)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"RobotService/internal/dto"
+	"RobotService/internal/entities"
+	"RobotService/internal/repositories"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateRobotType(t *testing.T) {
+	tests := []struct {
+		name      string
+		robotType entities.RobotType
+		valid     bool
+	}{
+		{name: "empty", valid: true},
+		{name: "full", robotType: entities.RobotType{MaxSpeed: 2, PayloadCapacity: 5, SafetyRadius: 1, MinZ: ptr(0), MaxZ: ptr(10),
+			AttributeSchema: json.RawMessage(`{"type":"object","required":["battery"]}`)}, valid: true},
+		{name: "null schema", robotType: entities.RobotType{AttributeSchema: json.RawMessage(" null ")}, valid: true},
+		{name: "flat altitude", robotType: entities.RobotType{MinZ: ptr(3), MaxZ: ptr(3)}, valid: true},
+		{name: "negative speed", robotType: entities.RobotType{MaxSpeed: -1}},
+		{name: "negative payload", robotType: entities.RobotType{PayloadCapacity: -1}},
+		{name: "negative radius", robotType: entities.RobotType{SafetyRadius: -0.5}},
+		{name: "inverted altitude", robotType: entities.RobotType{MinZ: ptr(5), MaxZ: ptr(1)}},
+		{name: "schema is not an object", robotType: entities.RobotType{AttributeSchema: json.RawMessage(`[1]`)}},
+		{name: "broken schema", robotType: entities.RobotType{AttributeSchema: json.RawMessage(`{"type":"robot"}`)}},
+	}
+	for _, tt := range tests {
+		robotType := tt.robotType
+		err := validateRobotType(&robotType)
+		if tt.valid != (err == nil) || (err != nil && !errors.Is(err, ErrInvalidRobotType)) {
+			t.Errorf("%s: err = %v, valid %v", tt.name, err, tt.valid)
+		}
+		if tt.valid && len(robotType.AttributeSchema) == 0 {
+			t.Errorf("%s: schema was not defaulted", tt.name)
+		}
+	}
+}
+
+func TestCheckAltitude(t *testing.T) {
+	drone := &entities.RobotType{Name: "drone", MinZ: ptr(1), MaxZ: ptr(100)}
+	if err := checkAltitude(drone, 1); err != nil {
+		t.Fatal(err)
+	}
+	err := checkAltitude(drone, 0)
+	if !errors.Is(err, ErrAltitudeOutOfRange) || !strings.Contains(err.Error(), "[1, 100]") {
+		t.Fatalf("err = %v", err)
+	}
+	rover := &entities.RobotType{Name: "rover", MaxZ: ptr(0)}
+	if err = checkAltitude(rover, -50); err != nil {
+		t.Fatal(err)
+	}
+	if err = checkAltitude(rover, 1); !errors.Is(err, ErrAltitudeOutOfRange) || !strings.Contains(err.Error(), "[-inf, 0]") {
+		t.Fatalf("err = %v", err)
+	}
+	// Без реестра проверяются только метки
+	var none *RobotTypeService
+	check, err := none.ImportCheck()
+	if err != nil || check(entities.Robot{Type: "anything", ZCord: -1}) != nil || !errors.Is(check(entities.Robot{Labels: map[string]string{"": "v"}}), ErrInvalidLabels) {
+		t.Fatalf("nil registry: err = %v", err)
+	}
+}
+
+func TestRobotTypeRegistry(t *testing.T) {
+	f := newTestFixture(t)
+	types := f.robots.Types
+	ctx := actorContext("alice", "admin")
+	drone := f.robotType + "-drone"
+	created, err := types.CreateRobotType(ctx, dto.CreateRobotTypeDTO{Name: drone, MinZ: ptr(1), MaxZ: ptr(50),
+		AttributeSchema: json.RawMessage(`{"type":"object","properties":{"battery":{"type":"number","maximum":100}},"required":["battery"]}`)})
+	if err != nil {
+		t.Fatalf("CreateRobotType: %v", err)
+	}
+	t.Cleanup(func() { _ = types.Repository.DeleteRobotType(drone) })
+	if created.Name != drone || *created.MaxZ != 50 {
+		t.Fatalf("created = %+v", created)
+	}
+	if _, err = types.CreateRobotType(ctx, dto.CreateRobotTypeDTO{Name: drone}); !errors.Is(err, repositories.ErrRobotTypeExists) {
+		t.Fatalf("duplicate: err = %v", err)
+	}
+	if _, err = types.CreateRobotType(ctx, dto.CreateRobotTypeDTO{Name: "Drone!"}); !errors.Is(err, ErrInvalidRobotType) {
+		t.Fatalf("bad name: err = %v", err)
+	}
+
+	// Робот проверяется по реестру: тип, высота, атрибуты
+	base := dto.CreateRobotDTO{Name: "registry-drone", Type: drone, XCord: f.base, YCord: f.base, ZCord: 10, Attributes: map[string]any{"battery": 80}}
+	for _, tt := range []struct {
+		name   string
+		change func(*dto.CreateRobotDTO)
+		err    error
+	}{
+		{"unknown type", func(d *dto.CreateRobotDTO) { d.Type = f.robotType + "-missing" }, ErrUnknownRobotType},
+		{"below the type", func(d *dto.CreateRobotDTO) { d.ZCord = 0 }, ErrAltitudeOutOfRange},
+		{"missing attribute", func(d *dto.CreateRobotDTO) { d.Attributes = nil }, ErrInvalidAttributes},
+		{"attribute out of range", func(d *dto.CreateRobotDTO) { d.Attributes = map[string]any{"battery": 120} }, ErrInvalidAttributes},
+	} {
+		data := base
+		tt.change(&data)
+		if _, err = f.robots.CreateRobot(ctx, data); !errors.Is(err, tt.err) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.err)
+		}
+	}
+	id, err := f.robots.CreateRobot(ctx, base)
+	if err != nil {
+		t.Fatalf("CreateRobot: %v", err)
+	}
+	t.Cleanup(func() { _ = f.robots.RobotRepository.DeleteRobot(id) })
+
+	if err = f.robots.ChangeRobotType(ctx, dto.ChangeTypeDTO{ID: id, Type: f.robotType + "-missing"}); !errors.Is(err, ErrUnknownRobotType) {
+		t.Fatalf("retype to unknown: err = %v", err)
+	}
+	// Внешний ключ держит тип и без проверки реестра
+	if _, err = f.robots.RobotRepository.CreateRobot(entities.Robot{Name: "fk", Type: f.robotType + "-missing"}); err == nil {
+		t.Fatal("robot with an unknown type was inserted")
+	}
+	if err = f.robots.RobotRepository.ChangeRobotType(id, f.robotType+"-missing"); err == nil {
+		t.Fatal("robot was retyped to an unknown type")
+	}
+
+	// Тип, на который ссылаются роботы, не удалить
+	if err = types.DeleteRobotType(ctx, drone); !errors.Is(err, repositories.ErrRobotTypeInUse) {
+		t.Fatalf("delete in use: err = %v", err)
+	}
+	if _, err = types.GetRobotType(drone); err != nil {
+		t.Fatalf("type in use is gone: %v", err)
+	}
+	if err = f.robots.RobotRepository.DeleteRobot(id); err != nil {
+		t.Fatal(err)
+	}
+	if err = types.DeleteRobotType(ctx, drone); err != nil {
+		t.Fatalf("delete unused: %v", err)
+	}
+	if err = types.DeleteRobotType(ctx, drone); !errors.Is(err, repositories.ErrRobotTypeNotFound) {
+		t.Fatalf("delete twice: err = %v", err)
+	}
+}
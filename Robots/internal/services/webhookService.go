@@ -0,0 +1,135 @@
+package services
+
+import (
+	"RobotService/internal/dto"
+	"RobotService/internal/entities"
+	"RobotService/internal/repositories"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"slices"
+	"time"
+)
+
+const (
+	allEvents         = "*"
+	minSecretLength   = 16
+	maxDeliveriesPage = 500
+	// Сколько ждём DNS при проверке адреса вебхука
+	webhookResolveTimeout = 5 * time.Second
+)
+
+var ErrInvalidWebhook = errors.New("invalid webhook")
+
+type WebhookService struct {
+	Repository repositories.WebhookRepository
+}
+
+func (srv *WebhookService) CreateWebhook(data dto.CreateWebhookDTO) (*entities.Webhook, error) {
+	hook := entities.Webhook{URL: data.URL, Secret: data.Secret, Events: data.Events}
+	if err := validateWebhook(hook); err != nil {
+		return nil, err
+	}
+	if err := checkWebhookTarget(hook.URL); err != nil {
+		return nil, err
+	}
+	return srv.Repository.CreateWebhook(hook)
+}
+
+func (srv *WebhookService) GetWebhook(id int) (*entities.Webhook, error) {
+	return srv.Repository.GetWebhook(id)
+}
+
+func (srv *WebhookService) ListWebhooks() ([]entities.Webhook, error) {
+	return srv.Repository.ListWebhooks()
+}
+
+func (srv *WebhookService) UpdateWebhook(id int, data dto.UpdateWebhookDTO) (*entities.Webhook, error) {
+	hook, err := srv.Repository.GetWebhook(id)
+	if err != nil {
+		return nil, err
+	}
+	if data.URL != "" {
+		hook.URL = data.URL
+	}
+	if data.Secret != "" {
+		hook.Secret = data.Secret
+	}
+	if data.Events != nil {
+		hook.Events = data.Events
+	}
+	if data.Active != nil {
+		hook.Active = *data.Active
+	}
+	if err = validateWebhook(*hook); err != nil {
+		return nil, err
+	}
+	if data.URL != "" {
+		if err = checkWebhookTarget(hook.URL); err != nil {
+			return nil, err
+		}
+	}
+	return srv.Repository.UpdateWebhook(*hook)
+}
+
+func (srv *WebhookService) DeleteWebhook(id int) error {
+	return srv.Repository.DeleteWebhook(id)
+}
+
+func (srv *WebhookService) ListDeliveries(id, limit int) ([]entities.WebhookDelivery, error) {
+	if _, err := srv.Repository.GetWebhook(id); err != nil {
+		return nil, err
+	}
+	return srv.Repository.ListDeliveries(id, min(limit, maxDeliveriesPage))
+}
+
+func validateWebhook(hook entities.Webhook) error {
+	target, err := url.Parse(hook.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return fmt.Errorf("%w: url must be an absolute http(s) url", ErrInvalidWebhook)
+	}
+	if len(hook.Secret) < minSecretLength {
+		return fmt.Errorf("%w: secret must be at least %d characters", ErrInvalidWebhook, minSecretLength)
+	}
+	if len(hook.Events) == 0 {
+		return fmt.Errorf("%w: events must not be empty, use \"*\" for all events", ErrInvalidWebhook)
+	}
+	for _, event := range hook.Events {
+		if event != allEvents && !slices.Contains(EventTypes, event) {
+			return fmt.Errorf("%w: unknown event %q", ErrInvalidWebhook, event)
+		}
+	}
+	return nil
+}
+
+// Вебхук не должен смотреть во внутреннюю сеть: иначе через него можно дёргать соседние сервисы
+// и метаданные облака от имени сервиса. Проверяем все адреса имени. DNS может ответить иначе
+// при доставке, поэтому сервис уведомлений проверяет адрес ещё раз перед соединением
+func checkWebhookTarget(rawURL string) error {
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("%w: url must be an absolute http(s) url", ErrInvalidWebhook)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), webhookResolveTimeout)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", target.Hostname())
+	if err != nil || len(addrs) == 0 {
+		return fmt.Errorf("%w: cannot resolve host %q", ErrInvalidWebhook, target.Hostname())
+	}
+	for _, addr := range addrs {
+		if !publicAddress(addr) {
+			return fmt.Errorf("%w: host %q resolves to non-public address %s", ErrInvalidWebhook, target.Hostname(), addr)
+		}
+	}
+	return nil
+}
+
+// Loopback, link-local (в том числе 169.254.169.254), частные сети, 0.0.0.0/:: и multicast не пускаем
+func publicAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsValid() && !addr.IsLoopback() && !addr.IsLinkLocalUnicast() && !addr.IsLinkLocalMulticast() &&
+		!addr.IsInterfaceLocalMulticast() && !addr.IsMulticast() && !addr.IsPrivate() && !addr.IsUnspecified()
+}
@@ -0,0 +1,44 @@
+package services
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckWebhookTarget(t *testing.T) {
+	tests := []struct {
+		url     string
+		allowed bool
+	}{
+		{url: "https://93.184.215.14/hook", allowed: true},
+		{url: "http://8.8.8.8:8080/hook", allowed: true},
+		{url: "https://[2606:4700::1111]/hook", allowed: true},
+
+		{url: "http://localhost:8083/robots"},
+		{url: "http://127.0.0.1/hook"},
+		{url: "http://127.1.2.3/hook"},
+		{url: "http://[::1]/hook"},
+		{url: "http://10.0.0.5/hook"},
+		{url: "http://172.16.3.4/hook"},
+		{url: "http://192.168.1.10/hook"},
+		{url: "http://[fd00::1]/hook"},
+		{url: "http://169.254.169.254/latest/meta-data"},
+		{url: "http://[fe80::1]/hook"},
+		{url: "http://0.0.0.0/hook"},
+		{url: "http://[::]/hook"},
+		{url: "http://[::ffff:127.0.0.1]/hook"},
+		{url: "http://[::ffff:10.0.0.1]/hook"},
+		{url: "http://224.0.0.1/hook"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			err := checkWebhookTarget(tt.url)
+			if tt.allowed && err != nil {
+				t.Fatalf("err = %v, want nil", err)
+			}
+			if !tt.allowed && !errors.Is(err, ErrInvalidWebhook) {
+				t.Fatalf("err = %v, want ErrInvalidWebhook", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,259 @@
+package services
+
+import (
+	"RobotService/internal/dto"
+	"RobotService/internal/entities"
+	"RobotService/internal/rbac"
+	"RobotService/internal/repositories"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	maxZoneName     = 128
+	maxPolygonSides = 256
+	// Предел координат зоны: разности с точкой внутри описанного прямоугольника меньше 2^31,
+	// и произведения в проверке многоугольника помещаются в int64
+	maxZoneCoordinate = 1 << 30
+	// Сколько живёт снимок карты для проверок. Свои изменения сбрасывают его сразу,
+	// чужие (из другого экземпляра сервиса) видны не позже чем через это время
+	worldCacheTTL = 5 * time.Second
+)
+
+var (
+	ErrInvalidZone        = errors.New("invalid zone")
+	ErrInvalidWorldBounds = errors.New("invalid world bounds")
+	// Ошибки проверки позиции робота по карте
+	ErrOutsideWorld    = errors.New("position is outside the world")
+	ErrPositionBlocked = errors.New("position is blocked")
+)
+
+type WorldService struct {
+	Repository repositories.WorldRepository
+	Audit      *AuditService
+
+	mu       sync.Mutex
+	cached   *entities.World
+	loadedAt time.Time
+}
+
+// Карта целиком, из базы, а не из снимка
+func (srv *WorldService) GetWorld() (*entities.World, error) {
+	bounds, err := srv.Repository.GetBounds()
+	if err != nil {
+		return nil, err
+	}
+	zones, err := srv.Repository.ListZones()
+	if err != nil {
+		return nil, err
+	}
+	return &entities.World{Bounds: bounds, Zones: zones}, nil
+}
+
+// Уже стоящих за новыми границами роботов не трогаем, ограничение действует на следующие перемещения
+func (srv *WorldService) SetBounds(ctx context.Context, bounds entities.WorldBounds) (*entities.WorldBounds, error) {
+	if bounds.MinX > bounds.MaxX || bounds.MinY > bounds.MaxY || bounds.MinZ > bounds.MaxZ {
+		return nil, fmt.Errorf("%w: min must not be greater than max", ErrInvalidWorldBounds)
+	}
+	if err := srv.Repository.SetBounds(bounds); err != nil {
+		return nil, err
+	}
+	srv.invalidate()
+	srv.Audit.Record(ctx, string(rbac.WorldWrite), 0, nil, nil, nil, fmt.Sprintf("world bounds set to %+v", bounds))
+	return &bounds, nil
+}
+
+func (srv *WorldService) ClearBounds(ctx context.Context) error {
+	if err := srv.Repository.ClearBounds(); err != nil {
+		return err
+	}
+	srv.invalidate()
+	srv.Audit.Record(ctx, string(rbac.WorldWrite), 0, nil, nil, nil, "world bounds cleared")
+	return nil
+}
+
+func (srv *WorldService) CreateZone(ctx context.Context, data dto.ZoneDTO) (*entities.Zone, error) {
+	zone, err := zoneFromDTO(0, data)
+	if err != nil {
+		return nil, err
+	}
+	created, err := srv.Repository.CreateZone(zone)
+	if err != nil {
+		return nil, err
+	}
+	srv.invalidate()
+	srv.Audit.Record(ctx, string(rbac.WorldWrite), 0, nil, nil, nil, fmt.Sprintf("created %s %d %s", created.Kind, created.ID, created.Name))
+	return created, nil
+}
+
+func (srv *WorldService) GetZone(id int) (*entities.Zone, error) {
+	return srv.Repository.GetZone(id)
+}
+
+func (srv *WorldService) ListZones() ([]entities.Zone, error) {
+	return srv.Repository.ListZones()
+}
+
+func (srv *WorldService) UpdateZone(ctx context.Context, id int, data dto.ZoneDTO) (*entities.Zone, error) {
+	zone, err := zoneFromDTO(id, data)
+	if err != nil {
+		return nil, err
+	}
+	updated, err := srv.Repository.UpdateZone(zone)
+	if err != nil {
+		return nil, err
+	}
+	srv.invalidate()
+	srv.Audit.Record(ctx, string(rbac.WorldWrite), 0, nil, nil, nil, fmt.Sprintf("updated zone %d", id))
+	return updated, nil
+}
+
+func (srv *WorldService) DeleteZone(ctx context.Context, id int) error {
+	if err := srv.Repository.DeleteZone(id); err != nil {
+		return err
+	}
+	srv.invalidate()
+	srv.Audit.Record(ctx, string(rbac.WorldWrite), 0, nil, nil, nil, fmt.Sprintf("deleted zone %d", id))
+	return nil
+}
+
+func zoneFromDTO(id int, data dto.ZoneDTO) (entities.Zone, error) {
+	zone := entities.Zone{ID: id, Name: strings.TrimSpace(data.Name), Kind: data.Kind, MinZ: data.MinZ, MaxZ: data.MaxZ}
+	switch {
+	case zone.Name == "" || len(zone.Name) > maxZoneName:
+		return zone, fmt.Errorf("%w: name must be 1-%d characters", ErrInvalidZone, maxZoneName)
+	case zone.Kind != entities.ZoneArea && zone.Kind != entities.ZoneObstacle && zone.Kind != entities.ZoneGeofence:
+		return zone, fmt.Errorf("%w: kind must be zone, obstacle or geofence", ErrInvalidZone)
+	case (data.Box == nil) == (len(data.Polygon) == 0):
+		return zone, fmt.Errorf("%w: exactly one of box and polygon is required", ErrInvalidZone)
+	case zone.MinZ != nil && zone.MaxZ != nil && *zone.MinZ > *zone.MaxZ:
+		return zone, fmt.Errorf("%w: minZ must not be greater than maxZ", ErrInvalidZone)
+	}
+
+	if data.Box != nil {
+		if !zoneCoordinates(data.Box.MinX, data.Box.MinY, data.Box.MaxX, data.Box.MaxY) {
+			return zone, fmt.Errorf("%w: coordinates must be within ±%d", ErrInvalidZone, maxZoneCoordinate)
+		}
+		if data.Box.MinX > data.Box.MaxX || data.Box.MinY > data.Box.MaxY {
+			return zone, fmt.Errorf("%w: box min must not be greater than max", ErrInvalidZone)
+		}
+		zone.MinX, zone.MinY, zone.MaxX, zone.MaxY = data.Box.MinX, data.Box.MinY, data.Box.MaxX, data.Box.MaxY
+		return zone, nil
+	}
+	if len(data.Polygon) < 3 || len(data.Polygon) > maxPolygonSides {
+		return zone, fmt.Errorf("%w: polygon must have 3-%d points", ErrInvalidZone, maxPolygonSides)
+	}
+	for _, point := range data.Polygon {
+		if !zoneCoordinates(point.X, point.Y) {
+			return zone, fmt.Errorf("%w: coordinates must be within ±%d", ErrInvalidZone, maxZoneCoordinate)
+		}
+	}
+	// Описанный прямоугольник отсекает большинство точек без обхода многоугольника
+	zone.Polygon = data.Polygon
+	zone.MinX, zone.MinY = data.Polygon[0].X, data.Polygon[0].Y
+	zone.MaxX, zone.MaxY = zone.MinX, zone.MinY
+	for _, point := range data.Polygon[1:] {
+		zone.MinX, zone.MaxX = min(zone.MinX, point.X), max(zone.MaxX, point.X)
+		zone.MinY, zone.MaxY = min(zone.MinY, point.Y), max(zone.MaxY, point.Y)
+	}
+	return zone, nil
+}
+
+func zoneCoordinates(values ...int) bool {
+	for _, v := range values {
+		if v < -maxZoneCoordinate || v > maxZoneCoordinate {
+			return false
+		}
+	}
+	return true
+}
+
+func (srv *WorldService) invalidate() {
+	srv.mu.Lock()
+	srv.cached = nil
+	srv.mu.Unlock()
+}
+
+// Снимок карты для проверок. Его не меняют, только заменяют целиком
+func (srv *WorldService) snapshot() (*entities.World, error) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if srv.cached != nil && time.Since(srv.loadedAt) < worldCacheTTL {
+		return srv.cached, nil
+	}
+	world, err := srv.GetWorld()
+	if err != nil {
+		return nil, err
+	}
+	srv.cached, srv.loadedAt = world, time.Now()
+	return world, nil
+}
+
+// Может ли робот занять точку: внутри мира и не в препятствии или геозоне.
+// Без карты (nil) ничего не проверяем
+func (srv *WorldService) checkPosition(cord entities.RobotCord) error {
+	check, err := srv.positionCheck()
+	if err != nil {
+		return err
+	}
+	return check(cord)
+}
+
+// Проверка по одному снимку, для импорта и групповых команд, где точек много
+func (srv *WorldService) positionCheck() (func(entities.RobotCord) error, error) {
+	if srv == nil {
+		return func(entities.RobotCord) error { return nil }, nil
+	}
+	world, err := srv.snapshot()
+	if err != nil {
+		return nil, err
+	}
+	return func(cord entities.RobotCord) error {
+		if world.Bounds != nil && !world.Bounds.Contains(cord) {
+			return fmt.Errorf("%w: (%d, %d, %d)", ErrOutsideWorld, cord.XCord, cord.YCord, cord.ZCord)
+		}
+		for i := range world.Zones {
+			if world.Zones[i].Blocks() && world.Zones[i].Contains(cord) {
+				return fmt.Errorf("%w: (%d, %d, %d) is inside %s %q", ErrPositionBlocked,
+					cord.XCord, cord.YCord, cord.ZCord, world.Zones[i].Kind, world.Zones[i].Name)
+			}
+		}
+		return nil
+	}, nil
+}
+
+// Снимок для планирования маршрутов. Без карты (nil) мир пустой и без границ
+func (srv *WorldService) mapSnapshot() (*entities.World, error) {
+	if srv == nil {
+		return &entities.World{}, nil
+	}
+	return srv.snapshot()
+}
+
+// Обычные зоны, границу которых робот пересёк, переместившись из from в to
+func (srv *WorldService) transitions(from, to entities.RobotCord) (entered, left []entities.Zone, err error) {
+	if srv == nil || from == to {
+		return nil, nil, nil
+	}
+	world, err := srv.snapshot()
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, zone := range world.Zones {
+		if zone.Kind != entities.ZoneArea {
+			continue
+		}
+		wasIn, isIn := zone.Contains(from), zone.Contains(to)
+		switch {
+		case isIn && !wasIn:
+			entered = append(entered, zone)
+		case wasIn && !isIn:
+			left = append(left, zone)
+		}
+	}
+	return entered, left, nil
+}
@@ -0,0 +1,213 @@
+package services
+
+import (
+	"RobotService/internal/dto"
+	"RobotService/internal/entities"
+	"RobotService/internal/events"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Карта без базы: снимок уже загружен и ещё не устарел
+func cachedWorld(world *entities.World) *WorldService {
+	return &WorldService{cached: world, loadedAt: time.Now().Add(time.Hour)}
+}
+
+func ptr(v int) *int {
+	return &v
+}
+
+func TestZoneFromDTO(t *testing.T) {
+	zone, err := zoneFromDTO(3, dto.ZoneDTO{Name: " dock ", Kind: entities.ZoneArea, Box: &dto.ZoneBoxDTO{MinX: -1, MinY: 2, MaxX: 4, MaxY: 5}, MinZ: ptr(0)})
+	if err != nil {
+		t.Fatalf("box: %v", err)
+	}
+	want := entities.Zone{ID: 3, Name: "dock", Kind: entities.ZoneArea, MinX: -1, MinY: 2, MaxX: 4, MaxY: 5, MinZ: ptr(0)}
+	if !reflect.DeepEqual(zone, want) {
+		t.Fatalf("box zone = %+v, want %+v", zone, want)
+	}
+
+	// У многоугольника прямоугольник считается по его вершинам
+	polygon := []entities.Point{{X: 2, Y: -3}, {X: 8, Y: 1}, {X: -4, Y: 6}}
+	zone, err = zoneFromDTO(0, dto.ZoneDTO{Name: "fence", Kind: entities.ZoneGeofence, Polygon: polygon})
+	if err != nil {
+		t.Fatalf("polygon: %v", err)
+	}
+	if zone.MinX != -4 || zone.MinY != -3 || zone.MaxX != 8 || zone.MaxY != 6 || len(zone.Polygon) != 3 {
+		t.Fatalf("polygon zone = %+v", zone)
+	}
+
+	box := &dto.ZoneBoxDTO{MaxX: 1, MaxY: 1}
+	tests := []struct {
+		name string
+		data dto.ZoneDTO
+	}{
+		{"empty name", dto.ZoneDTO{Name: " ", Kind: entities.ZoneArea, Box: box}},
+		{"unknown kind", dto.ZoneDTO{Name: "z", Kind: "lake", Box: box}},
+		{"no shape", dto.ZoneDTO{Name: "z", Kind: entities.ZoneArea}},
+		{"box and polygon", dto.ZoneDTO{Name: "z", Kind: entities.ZoneArea, Box: box, Polygon: polygon}},
+		{"inverted heights", dto.ZoneDTO{Name: "z", Kind: entities.ZoneArea, Box: box, MinZ: ptr(5), MaxZ: ptr(1)}},
+		{"inverted box", dto.ZoneDTO{Name: "z", Kind: entities.ZoneArea, Box: &dto.ZoneBoxDTO{MinX: 2, MaxX: 1, MaxY: 1}}},
+		{"two points", dto.ZoneDTO{Name: "z", Kind: entities.ZoneArea, Polygon: polygon[:2]}},
+		{"box too far", dto.ZoneDTO{Name: "z", Kind: entities.ZoneArea, Box: &dto.ZoneBoxDTO{MinX: -maxZoneCoordinate - 1, MaxX: 1, MaxY: 1}}},
+		{"polygon too far", dto.ZoneDTO{Name: "z", Kind: entities.ZoneArea, Polygon: []entities.Point{{}, {X: maxZoneCoordinate + 1}, {Y: 1}}}},
+	}
+	for _, tt := range tests {
+		if _, err := zoneFromDTO(0, tt.data); !errors.Is(err, ErrInvalidZone) {
+			t.Errorf("%s: err = %v, want ErrInvalidZone", tt.name, err)
+		}
+	}
+}
+
+func TestPositionCheck(t *testing.T) {
+	world := cachedWorld(&entities.World{
+		Bounds: &entities.WorldBounds{MinX: -100, MinY: -100, MinZ: 0, MaxX: 100, MaxY: 100, MaxZ: 50},
+		Zones: []entities.Zone{
+			{Name: "dock", Kind: entities.ZoneArea, MinX: 0, MinY: 0, MaxX: 10, MaxY: 10},
+			{Name: "pillar", Kind: entities.ZoneObstacle, MinX: 20, MinY: 20, MaxX: 22, MaxY: 22},
+			// Геозона только на высоте 10 и выше: под ней проезжать можно
+			{Name: "airspace", Kind: entities.ZoneGeofence, MinX: -50, MinY: -50, MaxX: -10, MaxY: -10, MinZ: ptr(10),
+				Polygon: []entities.Point{{X: -50, Y: -50}, {X: -10, Y: -50}, {X: -50, Y: -10}}},
+		},
+	})
+	tests := []struct {
+		cord entities.RobotCord
+		err  error
+	}{
+		{at(5, 5, 0), nil},
+		{at(100, -100, 50), nil},
+		{at(101, 0, 0), ErrOutsideWorld},
+		{at(0, 0, -1), ErrOutsideWorld},
+		{at(21, 22, 0), ErrPositionBlocked},
+		{at(-40, -40, 10), ErrPositionBlocked},
+		{at(-40, -40, 9), nil},
+		// Вне треугольника, но внутри его прямоугольника
+		{at(-15, -15, 10), nil},
+	}
+	for _, tt := range tests {
+		if err := world.checkPosition(tt.cord); !errors.Is(err, tt.err) {
+			t.Errorf("checkPosition(%v) = %v, want %v", tt.cord, err, tt.err)
+		}
+	}
+
+	// Без карты проверять нечего
+	var none *WorldService
+	if err := none.checkPosition(at(1e6, 0, 0)); err != nil {
+		t.Fatalf("nil world: %v", err)
+	}
+}
+
+func TestTransitions(t *testing.T) {
+	world := cachedWorld(&entities.World{Zones: []entities.Zone{
+		{Name: "dock", Kind: entities.ZoneArea, MinX: 0, MinY: 0, MaxX: 10, MaxY: 10},
+		{Name: "bay", Kind: entities.ZoneArea, MinX: 5, MinY: 0, MaxX: 20, MaxY: 10},
+		// Препятствия и геозоны событий входа не дают
+		{Name: "pillar", Kind: entities.ZoneObstacle, MinX: 0, MinY: 0, MaxX: 30, MaxY: 30},
+	}})
+	names := func(zones []entities.Zone) []string {
+		var out []string
+		for _, zone := range zones {
+			out = append(out, zone.Name)
+		}
+		return out
+	}
+	tests := []struct {
+		from, to      entities.RobotCord
+		entered, left []string
+	}{
+		{from: at(-5, 5, 0), to: at(2, 5, 0), entered: []string{"dock"}},
+		{from: at(2, 5, 0), to: at(7, 5, 0), entered: []string{"bay"}},
+		{from: at(7, 5, 0), to: at(15, 5, 0), left: []string{"dock"}},
+		{from: at(15, 5, 0), to: at(2, 5, 0), entered: []string{"dock"}, left: []string{"bay"}},
+		{from: at(7, 5, 0), to: at(25, 5, 0), left: []string{"dock", "bay"}},
+		{from: at(2, 5, 0), to: at(3, 6, 0)},
+		{from: at(2, 5, 0), to: at(2, 5, 0)},
+	}
+	for _, tt := range tests {
+		entered, left, err := world.transitions(tt.from, tt.to)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(names(entered), tt.entered) || !reflect.DeepEqual(names(left), tt.left) {
+			t.Errorf("%v -> %v: entered %v, left %v, want %v, %v", tt.from, tt.to, names(entered), names(left), tt.entered, tt.left)
+		}
+	}
+}
+
+func TestPublishZoneTransitions(t *testing.T) {
+	srv := &RbtSrvic{
+		Events: events.NewHub(),
+		World: cachedWorld(&entities.World{Zones: []entities.Zone{
+			{Name: "dock", Kind: entities.ZoneArea, MinX: 0, MinY: 0, MaxX: 10, MaxY: 10},
+			{Name: "bay", Kind: entities.ZoneArea, MinX: 20, MinY: 0, MaxX: 30, MaxY: 10},
+		}}),
+	}
+	ch, unsubscribe := srv.Events.Subscribe(8)
+	defer unsubscribe()
+
+	robot := &entities.Robot{ID: 5, XCord: 25, YCord: 5}
+	srv.publishZoneTransitions(at(5, 5, 0), robot, "alice")
+	// Сначала выход, потом вход
+	for _, want := range []struct{ kind, zone string }{{events.KindZoneLeft, "dock"}, {events.KindZoneEntered, "bay"}} {
+		select {
+		case event := <-ch:
+			if event.Kind != want.kind || event.Zone != want.zone || event.RobotID != 5 || event.Robot != robot || event.Actor != "alice" {
+				t.Fatalf("event = %+v, want %s %s", event, want.kind, want.zone)
+			}
+		default:
+			t.Fatalf("no %s event", want.kind)
+		}
+	}
+
+	// Внутри одной зоны событий нет
+	srv.publishZoneTransitions(at(22, 2, 0), robot, "alice")
+	select {
+	case event := <-ch:
+		t.Fatalf("unexpected event %+v", event)
+	default:
+	}
+}
+
+func TestMoveIntoGeofenceIsRejected(t *testing.T) {
+	f := newTestFixture(t)
+	robot := f.createRobot(t, entities.Robot{Name: "geofence-mover"})
+	fence := entities.Zone{Name: "fence", Kind: entities.ZoneGeofence, MinX: f.base + 10, MinY: f.base - 5, MaxX: f.base + 20, MaxY: f.base + 5}
+	dock := entities.Zone{Name: "dock", Kind: entities.ZoneArea, MinX: f.base + 3, MinY: f.base - 5, MaxX: f.base + 6, MaxY: f.base + 5}
+	f.robots.World = cachedWorld(&entities.World{Zones: []entities.Zone{fence, dock}})
+	ch, unsubscribe := f.robots.Events.Subscribe(16)
+	defer unsubscribe()
+
+	ctx := actorContext("alice", "operator")
+	target := f.at(15, 0, 0)
+	err := f.robots.UpdateRobotCords(ctx, dto.UpdateRobotCordDTO{ID: robot.ID, XCord: target.XCord, YCord: target.YCord})
+	if !errors.Is(err, ErrPositionBlocked) {
+		t.Fatalf("move into the geofence: err = %v, want ErrPositionBlocked", err)
+	}
+	stored, err := f.robots.RobotRepository.GetRobotInfo(robot.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Cord() != robot.Cord() {
+		t.Fatalf("robot moved to %v", stored.Cord())
+	}
+
+	target = f.at(4, 0, 0)
+	if err = f.robots.UpdateRobotCords(ctx, dto.UpdateRobotCordDTO{ID: robot.ID, XCord: target.XCord, YCord: target.YCord}); err != nil {
+		t.Fatalf("move into the dock: %v", err)
+	}
+	for {
+		select {
+		case event := <-ch:
+			if event.Kind == events.KindZoneEntered {
+				if event.Zone != "dock" || event.RobotID != robot.ID {
+					t.Fatalf("event = %+v", event)
+				}
+				return
+			}
+		default:
+			t.Fatal("no zone_entered event")
+		}
+	}
+}
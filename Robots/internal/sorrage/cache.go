@@ -23,6 +23,11 @@ func NewClient(addr string) *RdsCache {
 	return &RdsCache{client: rdb}
 }
 
+// Проверка, что редиска отвечает
+func (rds *RdsCache) Ping() error {
+	return rds.client.Ping(ctx).Err()
+}
+
 func (rds *RdsCache) SetRobotData(key string, robotdata entities.Robot, ttl time.Duration) error {
 	pref := "robots:"
 	data, err := json.Marshal(robotdata)
@@ -4,7 +4,7 @@ import (
 	"context"
 	"fmt"
 
-	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type ConnectionInfo struct {
@@ -16,13 +16,20 @@ type ConnectionInfo struct {
 	SSLMode  string
 }
 
-func CreatePostgresConnection(cfg string) (*pgx.Conn, error) {
-	conn, err := pgx.Connect(context.Background(), cfg)
+// Пул, а не одно соединение: pgx.Conn не потокобезопасен, а базу одновременно дёргают хендлеры,
+// gRPC и фоновые циклы. Размер пула настраивается параметром pool_max_conns в урле
+func CreatePostgresPool(cfg string) (*pgxpool.Pool, error) {
+	pool, err := pgxpool.New(context.Background(), cfg)
 	if err != nil {
 		return nil, err
 	}
+	// pgxpool.New соединяется лениво, недоступную базу хотим увидеть сразу
+	if err = pool.Ping(context.Background()); err != nil {
+		pool.Close()
+		return nil, err
+	}
 
-	return conn, nil
+	return pool, nil
 }
 
 func MakeURL(info ConnectionInfo) string {
@@ -0,0 +1,56 @@
+package sorrage
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const idempotencyPref = "idempotency:"
+
+// Ошибка, если по ключу идемпотентности ещё ничего не сохранено
+var ErrIdempotencyNotFound = errors.New("idempotency key not found")
+
+// Сохранённый ответ на запрос с ключом идемпотентности
+type IdempotencyRecord struct {
+	Fingerprint string `json:"fingerprint"`
+	StatusCode  int    `json:"statusCode"`
+	ContentType string `json:"contentType"`
+	Body        []byte `json:"body"`
+}
+
+func (rds *RdsCache) GetIdempotencyRecord(key string) (*IdempotencyRecord, error) {
+	data, err := rds.client.Get(ctx, idempotencyPref+key).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrIdempotencyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var record IdempotencyRecord
+	err = json.Unmarshal([]byte(data), &record)
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func (rds *RdsCache) SetIdempotencyRecord(key string, record IdempotencyRecord, ttl time.Duration) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return rds.client.Set(ctx, idempotencyPref+key, data, ttl).Err()
+}
+
+// Захватываем ключ на время обработки запроса, чтобы два одинаковых ретрая не выполнились параллельно
+func (rds *RdsCache) LockIdempotencyKey(key string, ttl time.Duration) (bool, error) {
+	return rds.client.SetNX(ctx, idempotencyPref+key+":lock", 1, ttl).Result()
+}
+
+func (rds *RdsCache) UnlockIdempotencyKey(key string) error {
+	return rds.client.Del(ctx, idempotencyPref+key+":lock").Err()
+}
@@ -0,0 +1,97 @@
+package sorrage
+
+import (
+	"RobotService/internal/entities"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	lockdownKey = "lockdown:state"
+	// Когда режим последний раз сняли. События до этого момента в окна не попадают,
+	// иначе сразу после снятия режим включился бы снова на старых счётчиках
+	lockdownUnlockedKey = "lockdown:unlocked"
+	lockdownCountPref   = "lockdown:rate:"
+)
+
+// Скользящее окно: в sorted set лежат отметки событий за последние window миллисекунд.
+// KEYS: счётчик, время снятия режима. ARGV: окно в миллисекундах, уникальный member для события
+var slidingWindowScript = redis.NewScript(`
+local window = tonumber(ARGV[1])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local floor = math.max(now - window, tonumber(redis.call('GET', KEYS[2])) or 0)
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', floor)
+redis.call('ZADD', KEYS[1], now, ARGV[2])
+redis.call('PEXPIRE', KEYS[1], window)
+return redis.call('ZCARD', KEYS[1])
+`)
+
+// Снимаем режим и запоминаем момент снятия по часам редиски
+var unlockScript = redis.NewScript(`
+local t = redis.call('TIME')
+redis.call('SET', KEYS[2], tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000))
+return redis.call('DEL', KEYS[1])
+`)
+
+// Ставим режим, только если он строже текущего. ARGV: состояние в json, его уровень
+var escalateScript = redis.NewScript(`
+local current = redis.call('GET', KEYS[1])
+if current then
+	local state = cjson.decode(current)
+	if (tonumber(state['level']) or 0) >= tonumber(ARGV[2]) then
+		return 0
+	end
+end
+redis.call('SET', KEYS[1], ARGV[1])
+return 1
+`)
+
+// Отмечаем событие и возвращаем, сколько их было в окне вместе с ним
+func (rds *RdsCache) CountInWindow(key, member string, window time.Duration) (int, error) {
+	count, err := slidingWindowScript.Run(ctx, rds.client, []string{lockdownCountPref + key, lockdownUnlockedKey}, window.Milliseconds(), member).Int()
+	return count, err
+}
+
+// nil, если режим не включён
+func (rds *RdsCache) GetLockdown() (*entities.LockdownState, error) {
+	data, err := rds.client.Get(ctx, lockdownKey).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var state entities.LockdownState
+	if err = json.Unmarshal([]byte(data), &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// Повышаем режим. false - уже стоит такой же или строже
+func (rds *RdsCache) EscalateLockdown(state entities.LockdownState) (bool, error) {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return false, err
+	}
+	changed, err := escalateScript.Run(ctx, rds.client, []string{lockdownKey}, data, state.Level).Int()
+	return changed == 1, err
+}
+
+// Ручная установка режима админом, без сравнения уровней
+func (rds *RdsCache) SetLockdown(state entities.LockdownState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return rds.client.Set(ctx, lockdownKey, data, 0).Err()
+}
+
+func (rds *RdsCache) ClearLockdown() error {
+	return unlockScript.Run(ctx, rds.client, []string{lockdownKey, lockdownUnlockedKey}).Err()
+}
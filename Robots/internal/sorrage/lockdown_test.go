@@ -0,0 +1,111 @@
+package sorrage
+
+import (
+	"RobotService/internal/entities"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestCountInWindow(t *testing.T) {
+	server := miniredis.RunT(t)
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	server.SetTime(now)
+	cache := NewClient(server.Addr())
+
+	seq := 0
+	count := func(key string) int {
+		t.Helper()
+		seq++
+		n, err := cache.CountInWindow(key, "m"+strconv.Itoa(seq), time.Minute)
+		if err != nil {
+			t.Fatalf("CountInWindow: %v", err)
+		}
+		return n
+	}
+	advance := func(d time.Duration) {
+		now = now.Add(d)
+		server.SetTime(now)
+	}
+
+	for want := 1; want <= 3; want++ {
+		if n := count("deletes"); n != want {
+			t.Fatalf("count = %d, want %d", n, want)
+		}
+	}
+	// Окно скользит: через 40 секунд добавляем два, ещё через 30 три первых уже вне окна
+	advance(40 * time.Second)
+	count("deletes")
+	if n := count("deletes"); n != 5 {
+		t.Fatalf("count after 40s = %d, want 5", n)
+	}
+	advance(30 * time.Second)
+	if n := count("deletes"); n != 3 {
+		t.Fatalf("count after 70s = %d, want 3", n)
+	}
+	if n := count("moves"); n != 1 {
+		t.Fatalf("other key = %d, want 1", n)
+	}
+	if ttl := server.TTL(lockdownCountPref + "deletes"); ttl <= 0 || ttl > time.Minute {
+		t.Fatalf("ttl = %v", ttl)
+	}
+
+	// После снятия режима старые события не считаются
+	if err := cache.ClearLockdown(); err != nil {
+		t.Fatal(err)
+	}
+	if n := count("deletes"); n != 1 {
+		t.Fatalf("count after unlock = %d, want 1", n)
+	}
+}
+
+func TestLockdownState(t *testing.T) {
+	server := miniredis.RunT(t)
+	cache := NewClient(server.Addr())
+
+	if state, err := cache.GetLockdown(); err != nil || state != nil {
+		t.Fatalf("initial state = %+v, err = %v", state, err)
+	}
+	frozen := entities.LockdownState{Mode: entities.LockdownDeletesFrozen, Level: 1, Reason: "rule a"}
+	readOnly := entities.LockdownState{Mode: entities.LockdownReadOnly, Level: 2, Reason: "rule b"}
+
+	// Повышение только к более строгому
+	for _, step := range []struct {
+		state   entities.LockdownState
+		changed bool
+		mode    string
+	}{
+		{frozen, true, entities.LockdownDeletesFrozen},
+		{frozen, false, entities.LockdownDeletesFrozen},
+		{readOnly, true, entities.LockdownReadOnly},
+		{frozen, false, entities.LockdownReadOnly},
+	} {
+		changed, err := cache.EscalateLockdown(step.state)
+		if err != nil {
+			t.Fatal(err)
+		}
+		state, err := cache.GetLockdown()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if changed != step.changed || state.Mode != step.mode {
+			t.Fatalf("escalate to %s: changed %v, mode %s, want %v, %s", step.state.Mode, changed, state.Mode, step.changed, step.mode)
+		}
+	}
+
+	// Вручную можно и ослабить
+	if err := cache.SetLockdown(frozen); err != nil {
+		t.Fatal(err)
+	}
+	if state, _ := cache.GetLockdown(); state == nil || state.Mode != entities.LockdownDeletesFrozen || state.Reason != "rule a" {
+		t.Fatalf("manual state = %+v", state)
+	}
+	if err := cache.ClearLockdown(); err != nil {
+		t.Fatal(err)
+	}
+	if state, err := cache.GetLockdown(); err != nil || state != nil {
+		t.Fatalf("state after clear = %+v, err = %v", state, err)
+	}
+}
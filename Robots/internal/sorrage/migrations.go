@@ -0,0 +1,220 @@
+package sorrage
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Схема базы. Каждый шаг идемпотентный, так что прогоняем всё при каждом старте
+var migrations = []string{
+	`CREATE TABLE IF NOT EXISTS robots (
+		id SERIAL PRIMARY KEY,
+		name TEXT NOT NULL,
+		type TEXT NOT NULL,
+		xcord INT NOT NULL DEFAULT 0,
+		ycord INT NOT NULL DEFAULT 0,
+		zcord INT NOT NULL DEFAULT 0
+	)`,
+	`CREATE TABLE IF NOT EXISTS webhooks (
+		id SERIAL PRIMARY KEY,
+		url TEXT NOT NULL,
+		secret TEXT NOT NULL,
+		events TEXT[] NOT NULL,
+		active BOOLEAN NOT NULL DEFAULT TRUE,
+		failure_count INT NOT NULL DEFAULT 0,
+		disabled_reason TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+		updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`,
+	`CREATE TABLE IF NOT EXISTS webhook_deliveries (
+		id BIGSERIAL PRIMARY KEY,
+		webhook_id INT NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+		delivery_id TEXT NOT NULL,
+		event TEXT NOT NULL,
+		attempt INT NOT NULL,
+		status_code INT NOT NULL DEFAULT 0,
+		success BOOLEAN NOT NULL,
+		error TEXT NOT NULL DEFAULT '',
+		duration_ms INT NOT NULL DEFAULT 0,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`,
+	`CREATE INDEX IF NOT EXISTS webhook_deliveries_webhook_idx ON webhook_deliveries (webhook_id, created_at DESC)`,
+	`CREATE TABLE IF NOT EXISTS api_keys (
+		id SERIAL PRIMARY KEY,
+		name TEXT NOT NULL,
+		prefix TEXT NOT NULL,
+		key_hash TEXT NOT NULL UNIQUE,
+		roles TEXT[] NOT NULL DEFAULT '{}',
+		created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+		last_used_at TIMESTAMPTZ,
+		revoked_at TIMESTAMPTZ
+	)`,
+	`CREATE TABLE IF NOT EXISTS audit_log (
+		id BIGSERIAL PRIMARY KEY,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+		actor TEXT NOT NULL DEFAULT '',
+		source_ip TEXT NOT NULL DEFAULT '',
+		request_id TEXT NOT NULL DEFAULT '',
+		action TEXT NOT NULL,
+		robot_id INT,
+		before JSONB,
+		after JSONB,
+		result TEXT NOT NULL,
+		error TEXT NOT NULL DEFAULT '',
+		details TEXT NOT NULL DEFAULT ''
+	)`,
+	`CREATE TABLE IF NOT EXISTS approvals (
+		id SERIAL PRIMARY KEY,
+		action TEXT NOT NULL,
+		robot_id INT NOT NULL,
+		new_type TEXT NOT NULL DEFAULT '',
+		status TEXT NOT NULL DEFAULT 'pending',
+		requested_by TEXT NOT NULL,
+		decided_by TEXT NOT NULL DEFAULT '',
+		comment TEXT NOT NULL DEFAULT '',
+		error TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+		expires_at TIMESTAMPTZ NOT NULL,
+		decided_at TIMESTAMPTZ
+	)`,
+	`CREATE INDEX IF NOT EXISTS approvals_status_idx ON approvals (status, id)`,
+	`CREATE INDEX IF NOT EXISTS audit_log_robot_idx ON audit_log (robot_id, id)`,
+	`CREATE INDEX IF NOT EXISTS audit_log_actor_idx ON audit_log (actor, id)`,
+	`CREATE INDEX IF NOT EXISTS audit_log_created_idx ON audit_log (created_at)`,
+	// Журнал только дописывается: UPDATE, DELETE и TRUNCATE запрещены на уровне базы
+	`CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+	BEGIN
+		RAISE EXCEPTION 'audit_log is append-only';
+	END
+	$$ LANGUAGE plpgsql`,
+	`DROP TRIGGER IF EXISTS audit_log_no_update ON audit_log`,
+	`CREATE TRIGGER audit_log_no_update BEFORE UPDATE OR DELETE ON audit_log
+		FOR EACH ROW EXECUTE FUNCTION audit_log_append_only()`,
+	`DROP TRIGGER IF EXISTS audit_log_no_truncate ON audit_log`,
+	`CREATE TRIGGER audit_log_no_truncate BEFORE TRUNCATE ON audit_log
+		FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only()`,
+	`CREATE TABLE IF NOT EXISTS robot_types (
+		name TEXT PRIMARY KEY,
+		description TEXT NOT NULL DEFAULT '',
+		max_speed DOUBLE PRECISION NOT NULL DEFAULT 0,
+		min_z INT,
+		max_z INT,
+		payload_capacity DOUBLE PRECISION NOT NULL DEFAULT 0,
+		attribute_schema JSONB NOT NULL DEFAULT '{}',
+		created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+		updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`,
+	// Типы, которые уже есть у роботов, заводим в реестр, иначе внешний ключ не встанет
+	`INSERT INTO robot_types (name, description)
+		SELECT DISTINCT type, 'created from existing robots' FROM robots
+		ON CONFLICT (name) DO NOTHING`,
+	`DO $$
+	BEGIN
+		IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'robots_type_fkey') THEN
+			ALTER TABLE robots ADD CONSTRAINT robots_type_fkey FOREIGN KEY (type) REFERENCES robot_types (name);
+		END IF;
+	END
+	$$`,
+	`CREATE INDEX IF NOT EXISTS robots_type_idx ON robots (type)`,
+	`ALTER TABLE robots ADD COLUMN IF NOT EXISTS attributes JSONB NOT NULL DEFAULT '{}'`,
+	`CREATE INDEX IF NOT EXISTS robots_attributes_idx ON robots USING GIN (attributes jsonb_path_ops)`,
+	`ALTER TABLE robots ADD COLUMN IF NOT EXISTS labels JSONB NOT NULL DEFAULT '{}'`,
+	// Обычный jsonb_ops, а не jsonb_path_ops: селекторам меток нужен ещё и оператор ?
+	`CREATE INDEX IF NOT EXISTS robots_labels_idx ON robots USING GIN (labels)`,
+	`CREATE TABLE IF NOT EXISTS fleets (
+		id SERIAL PRIMARY KEY,
+		name TEXT NOT NULL UNIQUE,
+		description TEXT NOT NULL DEFAULT '',
+		parent_id INT REFERENCES fleets (id),
+		created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+		updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`,
+	`CREATE INDEX IF NOT EXISTS fleets_parent_idx ON fleets (parent_id)`,
+	// Робот может состоять в нескольких флотах. Удалённый робот или флот пропадает из состава сам
+	`CREATE TABLE IF NOT EXISTS fleet_members (
+		fleet_id INT NOT NULL REFERENCES fleets (id) ON DELETE CASCADE,
+		robot_id INT NOT NULL REFERENCES robots (id) ON DELETE CASCADE,
+		PRIMARY KEY (fleet_id, robot_id)
+	)`,
+	`CREATE INDEX IF NOT EXISTS fleet_members_robot_idx ON fleet_members (robot_id)`,
+	// Границы мира - одна строка, её отсутствие значит, что мир не ограничен
+	`CREATE TABLE IF NOT EXISTS world_bounds (
+		id INT PRIMARY KEY DEFAULT 1 CHECK (id = 1),
+		min_x INT NOT NULL,
+		min_y INT NOT NULL,
+		min_z INT NOT NULL,
+		max_x INT NOT NULL,
+		max_y INT NOT NULL,
+		max_z INT NOT NULL,
+		updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`,
+	`CREATE TABLE IF NOT EXISTS zones (
+		id SERIAL PRIMARY KEY,
+		name TEXT NOT NULL UNIQUE,
+		kind TEXT NOT NULL,
+		min_x INT NOT NULL,
+		min_y INT NOT NULL,
+		max_x INT NOT NULL,
+		max_y INT NOT NULL,
+		polygon JSONB,
+		min_z INT,
+		max_z INT,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+		updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`,
+	`ALTER TABLE robot_types ADD COLUMN IF NOT EXISTS safety_radius DOUBLE PRECISION NOT NULL DEFAULT 0`,
+	// Маршрут хранится целиком: его читают и заменяют только вместе
+	`CREATE TABLE IF NOT EXISTS robot_paths (
+		robot_id INT PRIMARY KEY REFERENCES robots (id) ON DELETE CASCADE,
+		plan JSONB NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`,
+	`CREATE TABLE IF NOT EXISTS move_commands (
+		id SERIAL PRIMARY KEY,
+		robot_id INT NOT NULL REFERENCES robots (id) ON DELETE CASCADE,
+		status TEXT NOT NULL,
+		target JSONB NOT NULL,
+		waypoints JSONB NOT NULL,
+		length DOUBLE PRECISION NOT NULL,
+		traveled DOUBLE PRECISION NOT NULL DEFAULT 0,
+		position JSONB NOT NULL,
+		blocked TEXT NOT NULL DEFAULT '',
+		error TEXT NOT NULL DEFAULT '',
+		requested_by TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+		updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+		finished_at TIMESTAMPTZ
+	)`,
+	// У робота не больше одной выполняемой команды
+	`CREATE UNIQUE INDEX IF NOT EXISTS move_commands_running_idx ON move_commands (robot_id) WHERE status = 'running'`,
+	`CREATE INDEX IF NOT EXISTS move_commands_robot_idx ON move_commands (robot_id, id)`,
+	// Удалённый робот оставляет миссии без исполнителя, исполнитель их проваливает
+	`CREATE TABLE IF NOT EXISTS missions (
+		id SERIAL PRIMARY KEY,
+		name TEXT NOT NULL DEFAULT '',
+		robot_id INT REFERENCES robots (id) ON DELETE SET NULL,
+		status TEXT NOT NULL,
+		steps JSONB NOT NULL,
+		current_step INT NOT NULL DEFAULT 0,
+		payload TEXT NOT NULL DEFAULT '',
+		error TEXT NOT NULL DEFAULT '',
+		created_by TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+		updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+		started_at TIMESTAMPTZ,
+		finished_at TIMESTAMPTZ
+	)`,
+	// У робота не больше одной начатой миссии
+	`CREATE UNIQUE INDEX IF NOT EXISTS missions_robot_active_idx ON missions (robot_id) WHERE status IN ('active', 'paused')`,
+	`CREATE INDEX IF NOT EXISTS missions_status_idx ON missions (status, id)`,
+}
+
+func Migrate(db *pgxpool.Pool) error {
+	for _, query := range migrations {
+		if _, err := db.Exec(context.Background(), query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
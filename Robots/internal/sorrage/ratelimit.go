@@ -0,0 +1,61 @@
+package sorrage
+
+import (
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const rateLimitPref = "ratelimit:"
+
+// Ведро токенов целиком в редиске, чтобы лимит был общим для всех инстансов.
+// Время берём из редиски, а не с машин, иначе разъехавшиеся часы ломают пополнение.
+// ARGV: скорость пополнения в токенах за миллисекунду, размер ведра
+var tokenBucketScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1]) or burst
+local ts = tonumber(state[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate)
+
+local allowed = 0
+local retry = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	retry = math.ceil((1 - tokens) / rate)
+end
+local full = math.ceil((burst - tokens) / rate)
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], full + 1000)
+return {allowed, math.floor(tokens), retry, full}
+`)
+
+// Результат попытки взять токен. RetryAfter - когда появится следующий токен,
+// Reset - когда ведро наполнится целиком
+type TokenBucketResult struct {
+	Allowed    bool
+	Remaining  int
+	RetryAfter time.Duration
+	Reset      time.Duration
+}
+
+// Берём один токен из ведра key. rate - токенов в секунду, burst - ёмкость ведра
+func (rds *RdsCache) TakeToken(key string, rate float64, burst int) (TokenBucketResult, error) {
+	values, err := tokenBucketScript.Run(ctx, rds.client, []string{rateLimitPref + key}, rate/1000, burst).Int64Slice()
+	if err != nil {
+		return TokenBucketResult{}, err
+	}
+	return TokenBucketResult{
+		Allowed:    values[0] == 1,
+		Remaining:  int(values[1]),
+		RetryAfter: time.Duration(values[2]) * time.Millisecond,
+		Reset:      time.Duration(values[3]) * time.Millisecond,
+	}, nil
+}
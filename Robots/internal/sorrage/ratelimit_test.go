@@ -0,0 +1,78 @@
+package sorrage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestTakeToken(t *testing.T) {
+	server := miniredis.RunT(t)
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	server.SetTime(now)
+	cache := NewClient(server.Addr())
+
+	take := func(key string) TokenBucketResult {
+		t.Helper()
+		result, err := cache.TakeToken(key, 1, 3)
+		if err != nil {
+			t.Fatalf("TakeToken: %v", err)
+		}
+		return result
+	}
+	advance := func(d time.Duration) {
+		now = now.Add(d)
+		server.SetTime(now)
+	}
+
+	// Полное ведро отдаёт burst токенов подряд
+	for want := 2; want >= 0; want-- {
+		if result := take("client"); !result.Allowed || result.Remaining != want {
+			t.Fatalf("result = %+v, want allowed with %d remaining", result, want)
+		}
+	}
+	result := take("client")
+	if result.Allowed || result.RetryAfter != time.Second || result.Reset != 3*time.Second {
+		t.Fatalf("empty bucket: %+v", result)
+	}
+	if ttl := server.TTL(rateLimitPref + "client"); ttl <= 0 || ttl > 5*time.Second {
+		t.Fatalf("ttl = %v", ttl)
+	}
+
+	// Пополнение идёт по времени редиски
+	advance(500 * time.Millisecond)
+	if result = take("client"); result.Allowed || result.RetryAfter != 500*time.Millisecond {
+		t.Fatalf("after 0.5s: %+v", result)
+	}
+	advance(500 * time.Millisecond)
+	if result = take("client"); !result.Allowed || result.Remaining != 0 {
+		t.Fatalf("after 1s: %+v", result)
+	}
+
+	// После долгого простоя в ведре не больше burst
+	advance(time.Hour)
+	allowed := 0
+	for i := 0; i < 10; i++ {
+		if take("client").Allowed {
+			allowed++
+		}
+	}
+	if allowed != 3 {
+		t.Fatalf("allowed %d after an hour, want burst 3", allowed)
+	}
+
+	// У другого клиента своё ведро
+	if result = take("other"); !result.Allowed || result.Remaining != 2 {
+		t.Fatalf("other client: %+v", result)
+	}
+}
+
+func TestTakeTokenRedisDown(t *testing.T) {
+	server := miniredis.RunT(t)
+	cache := NewClient(server.Addr())
+	server.Close()
+	if _, err := cache.TakeToken("client", 1, 1); err == nil {
+		t.Fatal("no error with redis down")
+	}
+}
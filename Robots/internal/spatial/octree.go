@@ -0,0 +1,313 @@
+package spatial
+
+import (
+	"RobotService/internal/entities"
+	"container/heap"
+	"math"
+	"sync"
+)
+
+// Сколько точек держит лист, прежде чем разделиться на восемь частей
+const leafCapacity = 16
+
+// Найденный робот и расстояние до точки запроса
+type Neighbor struct {
+	ID       int
+	Distance float64
+}
+
+// Индекс позиций роботов в памяти - октодерево по целочисленным координатам.
+// Корень растёт в сторону новых точек, пустеющие узлы схлопываются обратно в листья.
+// Методы безопасны для nil: без индекса изменения ничего не делают, поиск ничего не находит
+type Index struct {
+	mu        sync.RWMutex
+	root      *node
+	positions map[int]point
+}
+
+type point struct {
+	id      int
+	x, y, z int64
+}
+
+// Куб [min, min+size) по каждой оси, size - степень двойки.
+// У листа children == nil и точки в points, у внутреннего узла наоборот
+type node struct {
+	minX, minY, minZ int64
+	size             int64
+	count            int
+	points           []point
+	children         *[8]*node
+}
+
+func New() *Index {
+	return &Index{positions: map[int]point{}}
+}
+
+func newPoint(id int, cord entities.RobotCord) point {
+	return point{id: id, x: int64(cord.XCord), y: int64(cord.YCord), z: int64(cord.ZCord)}
+}
+
+// Перестраиваем индекс с нуля, например при старте сервиса
+func (idx *Index) Reset(positions map[int]entities.RobotCord) {
+	if idx == nil {
+		return
+	}
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.root = nil
+	idx.positions = make(map[int]point, len(positions))
+	for id, cord := range positions {
+		idx.insert(newPoint(id, cord))
+	}
+}
+
+// Добавляем робота или переносим уже известного
+func (idx *Index) Upsert(id int, cord entities.RobotCord) {
+	if idx == nil {
+		return
+	}
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if old, ok := idx.positions[id]; ok {
+		idx.root.remove(old)
+	}
+	idx.insert(newPoint(id, cord))
+}
+
+func (idx *Index) Remove(id int) {
+	if idx == nil {
+		return
+	}
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if old, ok := idx.positions[id]; ok {
+		idx.root.remove(old)
+		delete(idx.positions, id)
+	}
+}
+
+func (idx *Index) Len() int {
+	if idx == nil {
+		return 0
+	}
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.positions)
+}
+
+func (idx *Index) insert(p point) {
+	idx.positions[p.id] = p
+	if idx.root == nil {
+		idx.root = &node{minX: p.x, minY: p.y, minZ: p.z, size: 1}
+	}
+	// Удваиваем корень в сторону точки, пока она не поместится
+	for !idx.root.contains(p) {
+		old := idx.root
+		grown := &node{minX: old.minX, minY: old.minY, minZ: old.minZ, size: old.size * 2, count: old.count, children: &[8]*node{}}
+		if p.x < old.minX {
+			grown.minX -= old.size
+		}
+		if p.y < old.minY {
+			grown.minY -= old.size
+		}
+		if p.z < old.minZ {
+			grown.minZ -= old.size
+		}
+		if old.count > 0 {
+			grown.children[grown.octant(old.minX, old.minY, old.minZ)] = old
+		}
+		idx.root = grown
+	}
+	idx.root.insert(p)
+}
+
+func (n *node) contains(p point) bool {
+	return p.x >= n.minX && p.x < n.minX+n.size &&
+		p.y >= n.minY && p.y < n.minY+n.size &&
+		p.z >= n.minZ && p.z < n.minZ+n.size
+}
+
+func (n *node) octant(x, y, z int64) int {
+	half := n.size / 2
+	i := 0
+	if x >= n.minX+half {
+		i |= 1
+	}
+	if y >= n.minY+half {
+		i |= 2
+	}
+	if z >= n.minZ+half {
+		i |= 4
+	}
+	return i
+}
+
+func (n *node) child(i int) *node {
+	if n.children[i] == nil {
+		half := n.size / 2
+		c := &node{minX: n.minX, minY: n.minY, minZ: n.minZ, size: half}
+		if i&1 != 0 {
+			c.minX += half
+		}
+		if i&2 != 0 {
+			c.minY += half
+		}
+		if i&4 != 0 {
+			c.minZ += half
+		}
+		n.children[i] = c
+	}
+	return n.children[i]
+}
+
+func (n *node) insert(p point) {
+	n.count++
+	if n.children != nil {
+		n.child(n.octant(p.x, p.y, p.z)).insert(p)
+		return
+	}
+	n.points = append(n.points, p)
+	// Куб 1x1x1 не делится: роботы в одной точке так и лежат в одном листе
+	if len(n.points) <= leafCapacity || n.size == 1 {
+		return
+	}
+	points := n.points
+	n.points, n.children, n.count = nil, &[8]*node{}, 0
+	for _, q := range points {
+		n.insert(q)
+	}
+}
+
+func (n *node) remove(p point) bool {
+	if n == nil || !n.contains(p) {
+		return false
+	}
+	if n.children == nil {
+		for i, q := range n.points {
+			if q.id == p.id {
+				n.points = append(n.points[:i], n.points[i+1:]...)
+				n.count--
+				return true
+			}
+		}
+		return false
+	}
+	i := n.octant(p.x, p.y, p.z)
+	if !n.children[i].remove(p) {
+		return false
+	}
+	n.count--
+	if n.children[i].count == 0 {
+		n.children[i] = nil
+	}
+	if n.count <= leafCapacity {
+		n.points = n.collect(make([]point, 0, n.count))
+		n.children = nil
+	}
+	return true
+}
+
+func (n *node) collect(points []point) []point {
+	if n.children == nil {
+		return append(points, n.points...)
+	}
+	for _, c := range n.children {
+		if c != nil {
+			points = c.collect(points)
+		}
+	}
+	return points
+}
+
+// Квадрат расстояния от точки до ближайшей точки куба
+func (n *node) distance2(x, y, z float64) float64 {
+	axis := func(c float64, lo int64) float64 {
+		if d := float64(lo) - c; d > 0 {
+			return d * d
+		}
+		if d := c - float64(lo+n.size-1); d > 0 {
+			return d * d
+		}
+		return 0
+	}
+	return axis(x, n.minX) + axis(y, n.minY) + axis(z, n.minZ)
+}
+
+// До k ближайших к center роботов не дальше radius, по возрастанию расстояния
+// (при равенстве - по ID). radius = math.Inf(1) - без ограничения.
+// Обход best-first: из очереди по расстоянию достаём то узлы, то точки, первые k точек и есть ответ
+func (idx *Index) Nearest(center entities.RobotCord, radius float64, k int) []Neighbor {
+	if idx == nil || k <= 0 || radius < 0 {
+		return nil
+	}
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	if idx.root == nil || idx.root.count == 0 {
+		return nil
+	}
+
+	x, y, z := float64(center.XCord), float64(center.YCord), float64(center.ZCord)
+	limit := radius * radius
+	queue := &searchQueue{{distance2: idx.root.distance2(x, y, z), node: idx.root}}
+	var found []Neighbor
+	for queue.Len() > 0 {
+		item := heap.Pop(queue).(searchItem)
+		if item.distance2 > limit {
+			break
+		}
+		if item.node == nil {
+			found = append(found, Neighbor{ID: item.point.id, Distance: math.Sqrt(item.distance2)})
+			if len(found) == k {
+				break
+			}
+			continue
+		}
+		if item.node.children == nil {
+			for _, p := range item.node.points {
+				dx, dy, dz := float64(p.x)-x, float64(p.y)-y, float64(p.z)-z
+				heap.Push(queue, searchItem{distance2: dx*dx + dy*dy + dz*dz, point: p})
+			}
+			continue
+		}
+		for _, c := range item.node.children {
+			if c != nil {
+				heap.Push(queue, searchItem{distance2: c.distance2(x, y, z), node: c})
+			}
+		}
+	}
+	return found
+}
+
+type searchItem struct {
+	distance2 float64
+	node      *node
+	point     point
+}
+
+// Очередь с приоритетом по расстоянию. При равенстве сначала узлы, чтобы все точки
+// на этом расстоянии попали в очередь до выдачи, затем точки по ID
+type searchQueue []searchItem
+
+func (q searchQueue) Len() int { return len(q) }
+
+func (q searchQueue) Less(i, j int) bool {
+	if q[i].distance2 != q[j].distance2 {
+		return q[i].distance2 < q[j].distance2
+	}
+	if (q[i].node == nil) != (q[j].node == nil) {
+		return q[i].node != nil
+	}
+	return q[i].node == nil && q[i].point.id < q[j].point.id
+}
+
+func (q searchQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *searchQueue) Push(x any) { *q = append(*q, x.(searchItem)) }
+
+func (q *searchQueue) Pop() any {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
@@ -0,0 +1,347 @@
+package spatial
+
+import (
+	"RobotService/internal/entities"
+	"context"
+	"math"
+	"math/rand/v2"
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Эталон: перебираем все точки, как это сделала бы выборка без индекса
+func linearNearest(positions map[int]entities.RobotCord, center entities.RobotCord, radius float64, k int) []Neighbor {
+	var found []Neighbor
+	for id, cord := range positions {
+		dx := float64(cord.XCord - center.XCord)
+		dy := float64(cord.YCord - center.YCord)
+		dz := float64(cord.ZCord - center.ZCord)
+		if d := math.Sqrt(dx*dx + dy*dy + dz*dz); d <= radius {
+			found = append(found, Neighbor{ID: id, Distance: d})
+		}
+	}
+	slices.SortFunc(found, func(a, b Neighbor) int {
+		if a.Distance != b.Distance {
+			if a.Distance < b.Distance {
+				return -1
+			}
+			return 1
+		}
+		return a.ID - b.ID
+	})
+	return found[:min(k, len(found))]
+}
+
+// Счётчики узлов сходятся с точками, точки лежат в своих кубах, листья не переполнены без нужды
+func checkNode(t *testing.T, n *node) int {
+	t.Helper()
+	if n.children == nil {
+		for _, p := range n.points {
+			if !n.contains(p) {
+				t.Fatalf("point %+v outside its leaf %+v", p, n)
+			}
+		}
+		if len(n.points) > leafCapacity && n.size > 1 {
+			t.Fatalf("leaf of size %d holds %d points", n.size, len(n.points))
+		}
+		if n.count != len(n.points) {
+			t.Fatalf("leaf count = %d, points = %d", n.count, len(n.points))
+		}
+		return n.count
+	}
+	total := 0
+	for _, c := range n.children {
+		if c != nil {
+			if c.size*2 != n.size {
+				t.Fatalf("child size %d under %d", c.size, n.size)
+			}
+			total += checkNode(t, c)
+		}
+	}
+	if n.count != total {
+		t.Fatalf("node count = %d, children hold %d", n.count, total)
+	}
+	return total
+}
+
+func checkIndex(t *testing.T, idx *Index, positions map[int]entities.RobotCord) {
+	t.Helper()
+	if idx.Len() != len(positions) {
+		t.Fatalf("Len = %d, want %d", idx.Len(), len(positions))
+	}
+	if idx.root != nil {
+		if got := checkNode(t, idx.root); got != len(positions) {
+			t.Fatalf("tree holds %d points, want %d", got, len(positions))
+		}
+	}
+}
+
+func sameNeighbors(got, want []Neighbor) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i].ID != want[i].ID || math.Abs(got[i].Distance-want[i].Distance) > 1e-9 {
+			return false
+		}
+	}
+	return true
+}
+
+func randomCord(r *rand.Rand, span int) entities.RobotCord {
+	return entities.RobotCord{XCord: r.IntN(2*span) - span, YCord: r.IntN(2*span) - span, ZCord: r.IntN(span)}
+}
+
+func TestNearestSamePoint(t *testing.T) {
+	idx := New()
+	at := entities.RobotCord{XCord: 5, YCord: -3, ZCord: 2}
+	// Больше, чем влезает в лист: куб 1x1x1 всё равно не делится
+	for id := 100; id > 0; id-- {
+		idx.Upsert(id, at)
+	}
+	idx.Upsert(1000, entities.RobotCord{XCord: 6, YCord: -3, ZCord: 2})
+
+	got := idx.Nearest(at, 0, math.MaxInt)
+	if len(got) != 100 {
+		t.Fatalf("found %d robots at the point, want 100", len(got))
+	}
+	for i, n := range got {
+		if n.ID != i+1 || n.Distance != 0 {
+			t.Fatalf("neighbor %d = %+v, want ID %d at 0", i, n, i+1)
+		}
+	}
+	if got = idx.Nearest(at, 1, 3); len(got) != 3 || got[0].ID != 1 || got[2].ID != 3 {
+		t.Fatalf("k=3: %+v", got)
+	}
+
+	// Уводим половину в другую точку и убираем часть оставшихся
+	for id := 1; id <= 50; id++ {
+		idx.Upsert(id, entities.RobotCord{XCord: 40, YCord: 40, ZCord: 40})
+	}
+	for id := 51; id <= 60; id++ {
+		idx.Remove(id)
+	}
+	if got = idx.Nearest(at, 0, math.MaxInt); len(got) != 40 || got[0].ID != 61 {
+		t.Fatalf("after moves: %d robots, first %+v", len(got), got)
+	}
+	if got = idx.Nearest(entities.RobotCord{XCord: 40, YCord: 40, ZCord: 40}, 0, math.MaxInt); len(got) != 50 {
+		t.Fatalf("moved robots: %d, want 50", len(got))
+	}
+	if idx.Len() != 91 {
+		t.Fatalf("Len = %d, want 91", idx.Len())
+	}
+}
+
+func TestNearestRadius(t *testing.T) {
+	idx := New()
+	idx.Upsert(1, entities.RobotCord{XCord: 3, YCord: 4})
+	idx.Upsert(2, entities.RobotCord{XCord: 0, YCord: 0, ZCord: 6})
+	idx.Upsert(3, entities.RobotCord{XCord: -5})
+
+	tests := []struct {
+		name   string
+		radius float64
+		k      int
+		want   []int
+	}{
+		// Граница радиуса входит
+		{"boundary", 5, 10, []int{1, 3}},
+		{"below boundary", 4.99, 10, nil},
+		{"k limits", math.Inf(1), 2, []int{1, 3}},
+		{"unbounded", math.Inf(1), 10, []int{1, 3, 2}},
+		{"zero k", math.Inf(1), 0, nil},
+		{"negative radius", -1, 10, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ids []int
+			for _, n := range idx.Nearest(entities.RobotCord{}, tt.radius, tt.k) {
+				ids = append(ids, n.ID)
+			}
+			if !slices.Equal(ids, tt.want) {
+				t.Fatalf("ids = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
+
+func TestNilIndex(t *testing.T) {
+	var idx *Index
+	idx.Upsert(1, entities.RobotCord{})
+	idx.Remove(1)
+	idx.Reset(map[int]entities.RobotCord{1: {}})
+	if idx.Len() != 0 || idx.Nearest(entities.RobotCord{}, math.Inf(1), 1) != nil {
+		t.Fatal("nil index should stay empty")
+	}
+}
+
+// Случайные вставки, переносы и удаления сверяем с перебором. Координаты в маленьком кубе,
+// чтобы было много роботов в одной точке и на одном расстоянии
+func TestNearestMatchesLinearScan(t *testing.T) {
+	for _, span := range []int{3, 50, 100_000} {
+		r := rand.New(rand.NewPCG(uint64(span), 1))
+		idx := New()
+		positions := map[int]entities.RobotCord{}
+
+		for step := 0; step < 3000; step++ {
+			id := r.IntN(400)
+			switch op := r.IntN(10); {
+			case op < 6:
+				cord := randomCord(r, span)
+				idx.Upsert(id, cord)
+				positions[id] = cord
+			case op < 8:
+				idx.Remove(id)
+				delete(positions, id)
+			default:
+				center := randomCord(r, span)
+				radius := []float64{0, 1, float64(span) / 2, math.Inf(1)}[r.IntN(4)]
+				k := []int{1, 5, math.MaxInt}[r.IntN(3)]
+				got := idx.Nearest(center, radius, k)
+				if want := linearNearest(positions, center, radius, k); !sameNeighbors(got, want) {
+					t.Fatalf("span %d step %d: Nearest(%+v, %v, %d) = %+v, want %+v", span, step, center, radius, k, got, want)
+				}
+			}
+		}
+		checkIndex(t, idx, positions)
+
+		// Reset собирает то же дерево с нуля
+		idx.Reset(positions)
+		checkIndex(t, idx, positions)
+		center := randomCord(r, span)
+		if got, want := idx.Nearest(center, math.Inf(1), 20), linearNearest(positions, center, math.Inf(1), 20); !sameNeighbors(got, want) {
+			t.Fatalf("span %d after Reset: %+v, want %+v", span, got, want)
+		}
+	}
+}
+
+// Для бенчмарков: робот на каждую сотню квадратных метров склада 1 км x 1 км в несколько ярусов
+const benchRobots = 10_000
+
+func benchPositions() map[int]entities.RobotCord {
+	r := rand.New(rand.NewPCG(7, 7))
+	positions := make(map[int]entities.RobotCord, benchRobots)
+	for id := 1; id <= benchRobots; id++ {
+		positions[id] = entities.RobotCord{XCord: r.IntN(1000), YCord: r.IntN(1000), ZCord: r.IntN(10)}
+	}
+	return positions
+}
+
+func benchCenters() []entities.RobotCord {
+	r := rand.New(rand.NewPCG(8, 8))
+	centers := make([]entities.RobotCord, 256)
+	for i := range centers {
+		centers[i] = entities.RobotCord{XCord: r.IntN(1000), YCord: r.IntN(1000), ZCord: r.IntN(10)}
+	}
+	return centers
+}
+
+func benchIndex() *Index {
+	idx := New()
+	idx.Reset(benchPositions())
+	return idx
+}
+
+func BenchmarkNearest(b *testing.B) {
+	idx, centers := benchIndex(), benchCenters()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		idx.Nearest(centers[i%len(centers)], math.Inf(1), 10)
+	}
+}
+
+func BenchmarkRadius(b *testing.B) {
+	idx, centers := benchIndex(), benchCenters()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		idx.Nearest(centers[i%len(centers)], 25, math.MaxInt)
+	}
+}
+
+// Перебор в памяти - нижняя граница того, что стоит полный просмотр таблицы
+func BenchmarkNearestLinearScan(b *testing.B) {
+	positions, centers := benchPositions(), benchCenters()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		linearNearest(positions, centers[i%len(centers)], math.Inf(1), 10)
+	}
+}
+
+func BenchmarkRadiusLinearScan(b *testing.B) {
+	positions, centers := benchPositions(), benchCenters()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		linearNearest(positions, centers[i%len(centers)], 25, math.MaxInt)
+	}
+}
+
+// Тот же поиск запросом к постгресу по временной таблице без пространственного индекса.
+// Нужна база в ROBOTS_TEST_DATABASE_URL, иначе бенчмарк пропускается
+const nearestSQL = `SELECT id, sqrt(power(xcord - $1, 2) + power(ycord - $2, 2) + power(zcord - $3, 2)) AS distance
+	FROM bench_robots
+	WHERE power(xcord - $1, 2) + power(ycord - $2, 2) + power(zcord - $3, 2) <= $4
+	ORDER BY distance, id LIMIT $5`
+
+func benchDatabase(b *testing.B) *pgx.Conn {
+	b.Helper()
+	dbURL := os.Getenv("ROBOTS_TEST_DATABASE_URL")
+	if dbURL == "" {
+		b.Skip("ROBOTS_TEST_DATABASE_URL is not set")
+	}
+	ctx := context.Background()
+	conn, err := pgx.Connect(ctx, dbURL)
+	if err != nil {
+		b.Fatalf("connect: %v", err)
+	}
+	b.Cleanup(func() { _ = conn.Close(ctx) })
+
+	// Временная таблица живёт, пока живёт соединение, поэтому берём одно соединение, а не пул
+	if _, err = conn.Exec(ctx, "CREATE TEMP TABLE bench_robots (id int PRIMARY KEY, xcord int, ycord int, zcord int)"); err != nil {
+		b.Fatalf("create table: %v", err)
+	}
+	rows := make([][]any, 0, benchRobots)
+	for id, cord := range benchPositions() {
+		rows = append(rows, []any{id, cord.XCord, cord.YCord, cord.ZCord})
+	}
+	if _, err = conn.CopyFrom(ctx, pgx.Identifier{"bench_robots"}, []string{"id", "xcord", "ycord", "zcord"}, pgx.CopyFromRows(rows)); err != nil {
+		b.Fatalf("copy: %v", err)
+	}
+	if _, err = conn.Exec(ctx, "ANALYZE bench_robots"); err != nil {
+		b.Fatalf("analyze: %v", err)
+	}
+	return conn
+}
+
+func benchSQL(b *testing.B, radius2 float64, k int64) {
+	conn, centers := benchDatabase(b), benchCenters()
+	ctx := context.Background()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c := centers[i%len(centers)]
+		rows, err := conn.Query(ctx, nearestSQL, c.XCord, c.YCord, c.ZCord, radius2, k)
+		if err != nil {
+			b.Fatal(err)
+		}
+		for rows.Next() {
+			var n Neighbor
+			if err = rows.Scan(&n.ID, &n.Distance); err != nil {
+				b.Fatal(err)
+			}
+		}
+		rows.Close()
+		if err = rows.Err(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkNearestSQLScan(b *testing.B) {
+	benchSQL(b, math.MaxFloat64, 10)
+}
+
+func BenchmarkRadiusSQLScan(b *testing.B) {
+	benchSQL(b, 25*25, math.MaxInt64)
+}
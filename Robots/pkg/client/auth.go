@@ -0,0 +1,30 @@
+package client
+
+import "net/http"
+
+// Подписывает запрос перед отправкой. Вызывается на каждую попытку, так что токен можно обновлять
+type Authenticator interface {
+	Authenticate(req *http.Request) error
+}
+
+type AuthFunc func(req *http.Request) error
+
+func (f AuthFunc) Authenticate(req *http.Request) error {
+	return f(req)
+}
+
+// Статический API ключ в заголовке X-API-Key
+func APIKey(key string) Authenticator {
+	return AuthFunc(func(req *http.Request) error {
+		req.Header.Set("X-API-Key", key)
+		return nil
+	})
+}
+
+// JWT или другой bearer токен
+func BearerToken(token string) Authenticator {
+	return AuthFunc(func(req *http.Request) error {
+		req.Header.Set("Authorization", "Bearer "+token)
+		return nil
+	})
+}
@@ -0,0 +1,249 @@
+// Package client - Go клиент для HTTP API RobotService.
+// Методы повторяют RbtSrvic, а запросы и ответы используют те же DTO и сущности, что и сервер.
+package client
+
+import (
+	"bytes"
+	"context"
+	crand "crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"RobotService/internal/dto"
+	"RobotService/internal/entities"
+)
+
+// Алиасы, чтобы типы сервера можно было назвать и за пределами модуля
+type (
+	Robot               = entities.Robot
+	RobotCord           = entities.RobotCord
+	NearbyRobot         = entities.NearbyRobot
+	ImportReport        = entities.ImportReport
+	NavigationPlan      = entities.NavigationPlan
+	MoveCommand         = entities.MoveCommand
+	Reservation         = entities.Reservation
+	Mission             = entities.Mission
+	Approval            = entities.Approval
+	CreateRobotDTO      = dto.CreateRobotDTO
+	UpdateRobotCordDTO  = dto.UpdateRobotCordDTO
+	UpdateRobotNameDTO  = dto.UpdateRobotNameDTO
+	ChangeTypeDTO       = dto.ChangeTypeDTO
+	PatchRobotDTO       = dto.PatchRobotDTO
+	SetRobotLabelsDTO   = dto.SetRobotLabelsDTO
+	PatchRobotLabelsDTO = dto.PatchRobotLabelsDTO
+	NavigateDTO         = dto.NavigateDTO
+	MoveDTO             = dto.MoveDTO
+	CreateMissionDTO    = dto.CreateMissionDTO
+	MissionStepDTO      = dto.MissionStepDTO
+	AssignMissionDTO    = dto.AssignMissionDTO
+)
+
+const idempotencyHeader = "Idempotency-Key"
+
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	auth       Authenticator
+	retry      RetryPolicy
+}
+
+// Настройки повторов: экспоненциальная задержка от BaseDelay до MaxDelay со случайным разбросом
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 4,
+	BaseDelay:   200 * time.Millisecond,
+	MaxDelay:    5 * time.Second,
+}
+
+type Option func(*Client)
+
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) { c.httpClient = httpClient }
+}
+
+func WithAuth(auth Authenticator) Option {
+	return func(c *Client) { c.auth = auth }
+}
+
+// MaxAttempts = 1 отключает повторы
+func WithRetry(policy RetryPolicy) Option {
+	return func(c *Client) { c.retry = policy }
+}
+
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		retry:      DefaultRetryPolicy,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.retry.MaxAttempts < 1 {
+		c.retry.MaxAttempts = 1
+	}
+	return c
+}
+
+// Один запрос к API. Тело целиком держим в памяти, чтобы его можно было отправить повторно
+type request struct {
+	method      string
+	path        string
+	body        []byte
+	contentType string
+}
+
+func jsonRequest(method, path string, payload any) (request, error) {
+	req := request{method: method, path: path}
+	if payload == nil {
+		return req, nil
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return req, err
+	}
+	req.body = body
+	req.contentType = "application/json"
+	return req, nil
+}
+
+// Отправляем запрос с повторами. Изменяющим запросам проставляем Idempotency-Key,
+// поэтому их тоже можно безопасно повторять. Ответ 2xx возвращается вызывающему, остальное - в *APIError
+func (c *Client) do(ctx context.Context, req request) (*http.Response, error) {
+	idempotencyKey := ""
+	if req.method != http.MethodGet {
+		idempotencyKey = idempotencyKeyFrom(ctx)
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < c.retry.MaxAttempts; attempt++ {
+		if attempt > 0 {
+			if err := sleep(ctx, c.backoff(attempt, lastErr)); err != nil {
+				return nil, err
+			}
+		}
+
+		httpReq, err := http.NewRequestWithContext(ctx, req.method, c.baseURL+req.path, bytes.NewReader(req.body))
+		if err != nil {
+			return nil, err
+		}
+		if req.contentType != "" {
+			httpReq.Header.Set("Content-Type", req.contentType)
+		}
+		if idempotencyKey != "" {
+			httpReq.Header.Set(idempotencyHeader, idempotencyKey)
+		}
+		if c.auth != nil {
+			if err = c.auth.Authenticate(httpReq); err != nil {
+				return nil, err
+			}
+		}
+
+		resp, err := c.httpClient.Do(httpReq)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			lastErr = err
+			continue
+		}
+		if resp.StatusCode < 300 {
+			return resp, nil
+		}
+
+		apiErr := newAPIError(resp)
+		lastErr = apiErr
+		if !apiErr.retryable() {
+			return nil, lastErr
+		}
+	}
+	return nil, lastErr
+}
+
+// Делаем запрос и раскладываем JSON ответа в out (если out не nil)
+func (c *Client) doJSON(ctx context.Context, req request, out any) error {
+	resp, err := c.do(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (c *Client) backoff(attempt int, lastErr error) time.Duration {
+	var apiErr *APIError
+	if errors.As(lastErr, &apiErr) && apiErr.RetryAfter > 0 {
+		return min(apiErr.RetryAfter, c.retry.MaxDelay)
+	}
+	delay := c.retry.BaseDelay << (attempt - 1)
+	if delay <= 0 || delay > c.retry.MaxDelay {
+		delay = c.retry.MaxDelay
+	}
+	// Разброс до половины задержки, чтобы клиенты не ломились одновременно
+	if half := int64(delay / 2); half > 0 {
+		delay = delay/2 + time.Duration(rand.Int64N(half+1))
+	}
+	return delay
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+type idempotencyKeyCtx struct{}
+
+// Свой Idempotency-Key для изменяющего запроса, например чтобы повторить его после перезапуска процесса.
+// Без него каждый вызов получает новый случайный ключ
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyCtx{}, key)
+}
+
+func idempotencyKeyFrom(ctx context.Context) string {
+	if key, _ := ctx.Value(idempotencyKeyCtx{}).(string); key != "" {
+		return key
+	}
+	return newIdempotencyKey()
+}
+
+func newIdempotencyKey() string {
+	buf := make([]byte, 16)
+	_, _ = crand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return time.Until(at)
+	}
+	return 0
+}
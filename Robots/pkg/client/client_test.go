@@ -0,0 +1,280 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Сервер, который отвечает по очереди заданными кодами и запоминает Idempotency-Key каждого запроса
+type scriptedServer struct {
+	mu       sync.Mutex
+	statuses []int
+	header   http.Header
+	keys     []string
+}
+
+func (s *scriptedServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	attempt := len(s.keys)
+	s.keys = append(s.keys, r.Header.Get(idempotencyHeader))
+	status := s.statuses[min(attempt, len(s.statuses)-1)]
+	s.mu.Unlock()
+
+	for name, values := range s.header {
+		w.Header()[name] = values
+	}
+	if status < 300 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte("7"))
+		return
+	}
+	http.Error(w, http.StatusText(status), status)
+}
+
+func newScriptedClient(t *testing.T, server *scriptedServer) *Client {
+	t.Helper()
+	ts := httptest.NewServer(server)
+	t.Cleanup(ts.Close)
+	return New(ts.URL, WithRetry(RetryPolicy{MaxAttempts: 4, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}))
+}
+
+func TestRetryReusesIdempotencyKey(t *testing.T) {
+	server := &scriptedServer{statuses: []int{http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusCreated}}
+	c := newScriptedClient(t, server)
+
+	id, err := c.CreateRobot(context.Background(), CreateRobotDTO{Name: "r1"})
+	if err != nil {
+		t.Fatalf("CreateRobot: %v", err)
+	}
+	if id != 7 {
+		t.Fatalf("id = %d, want 7", id)
+	}
+	if len(server.keys) != 3 {
+		t.Fatalf("attempts = %d, want 3", len(server.keys))
+	}
+	for _, key := range server.keys {
+		if key == "" || key != server.keys[0] {
+			t.Fatalf("keys = %q, want one non-empty key on every attempt", server.keys)
+		}
+	}
+}
+
+func TestIdempotencyKeyFromContext(t *testing.T) {
+	server := &scriptedServer{statuses: []int{http.StatusCreated}}
+	c := newScriptedClient(t, server)
+
+	ctx := WithIdempotencyKey(context.Background(), "fixed")
+	if _, err := c.CreateRobot(ctx, CreateRobotDTO{Name: "r1"}); err != nil {
+		t.Fatalf("CreateRobot: %v", err)
+	}
+	if _, err := c.CreateRobot(context.Background(), CreateRobotDTO{Name: "r1"}); err != nil {
+		t.Fatalf("CreateRobot: %v", err)
+	}
+	if server.keys[0] != "fixed" || server.keys[1] == "fixed" || server.keys[1] == "" {
+		t.Fatalf("keys = %q", server.keys)
+	}
+}
+
+func TestGetHasNoIdempotencyKey(t *testing.T) {
+	server := &scriptedServer{statuses: []int{http.StatusOK}}
+	c := newScriptedClient(t, server)
+
+	if _, err := c.ListRobots(context.Background(), ListOptions{}); err == nil {
+		// Тело "7" не раскладывается в список, но запрос до сервера дошёл
+		t.Fatal("want decode error")
+	}
+	if len(server.keys) != 1 || server.keys[0] != "" {
+		t.Fatalf("keys = %q, want one request without a key", server.keys)
+	}
+}
+
+func TestTypedErrors(t *testing.T) {
+	tests := []struct {
+		status   int
+		sentinel error
+	}{
+		{http.StatusNotFound, ErrNotFound},
+		{http.StatusConflict, ErrConflict},
+		{http.StatusUnprocessableEntity, ErrUnprocessable},
+		{http.StatusUnauthorized, ErrUnauthorized},
+		{http.StatusForbidden, ErrForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			server := &scriptedServer{statuses: []int{tt.status}}
+			c := newScriptedClient(t, server)
+
+			_, err := c.CreateRobot(context.Background(), CreateRobotDTO{Name: "r1"})
+			if !errors.Is(err, tt.sentinel) {
+				t.Fatalf("err = %v, want %v", err, tt.sentinel)
+			}
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) || apiErr.StatusCode != tt.status {
+				t.Fatalf("err = %#v, want *APIError with %d", err, tt.status)
+			}
+			// Ошибки клиента не повторяются
+			if len(server.keys) != 1 {
+				t.Fatalf("attempts = %d, want 1", len(server.keys))
+			}
+		})
+	}
+}
+
+// Перегрузка и ошибки шлюза повторяются с тем же ключом
+func TestRetryableStatuses(t *testing.T) {
+	for _, status := range []int{http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout} {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			server := &scriptedServer{statuses: []int{status, status, http.StatusCreated}}
+			c := newScriptedClient(t, server)
+
+			if _, err := c.CreateRobot(context.Background(), CreateRobotDTO{Name: "r1"}); err != nil {
+				t.Fatalf("CreateRobot: %v", err)
+			}
+			if len(server.keys) != 3 || server.keys[0] != server.keys[2] {
+				t.Fatalf("keys = %q, want three attempts with one key", server.keys)
+			}
+		})
+	}
+}
+
+func TestConflictWithoutRetryAfterIsNotRetried(t *testing.T) {
+	server := &scriptedServer{statuses: []int{http.StatusConflict, http.StatusCreated}}
+	c := newScriptedClient(t, server)
+
+	_, err := c.CreateRobot(context.Background(), CreateRobotDTO{Name: "r1"})
+	var apiErr *APIError
+	if !errors.Is(err, ErrConflict) || !errors.As(err, &apiErr) || apiErr.RetryAfter != 0 {
+		t.Fatalf("err = %v, want ErrConflict without Retry-After", err)
+	}
+	if len(server.keys) != 1 {
+		t.Fatalf("attempts = %d, want 1", len(server.keys))
+	}
+}
+
+func TestRateLimited(t *testing.T) {
+	tests := []struct {
+		retryAfter string
+		min, max   time.Duration
+	}{
+		{"30", 30 * time.Second, 30 * time.Second},
+		{time.Now().Add(time.Minute).UTC().Format(http.TimeFormat), 58 * time.Second, time.Minute},
+		{"soon", 0, 0},
+	}
+	for _, tt := range tests {
+		server := &scriptedServer{statuses: []int{http.StatusTooManyRequests}, header: http.Header{"Retry-After": {tt.retryAfter}}}
+		c := newScriptedClient(t, server)
+
+		_, err := c.CreateRobot(context.Background(), CreateRobotDTO{Name: "r1"})
+		var apiErr *APIError
+		if !errors.Is(err, ErrRateLimited) || !errors.As(err, &apiErr) {
+			t.Fatalf("Retry-After %q: err = %v, want ErrRateLimited", tt.retryAfter, err)
+		}
+		if apiErr.RetryAfter < tt.min || apiErr.RetryAfter > tt.max {
+			t.Fatalf("Retry-After %q: parsed %v, want [%v, %v]", tt.retryAfter, apiErr.RetryAfter, tt.min, tt.max)
+		}
+		// Ждём не дольше MaxDelay, но все попытки тратим
+		if len(server.keys) != 4 {
+			t.Fatalf("Retry-After %q: attempts = %d, want 4", tt.retryAfter, len(server.keys))
+		}
+	}
+}
+
+func TestConflictWithRetryAfterIsRetried(t *testing.T) {
+	server := &scriptedServer{statuses: []int{http.StatusConflict, http.StatusCreated}, header: http.Header{"Retry-After": {"1"}}}
+	c := newScriptedClient(t, server)
+
+	if _, err := c.CreateRobot(context.Background(), CreateRobotDTO{Name: "r1"}); err != nil {
+		t.Fatalf("CreateRobot: %v", err)
+	}
+	if len(server.keys) != 2 || server.keys[0] != server.keys[1] {
+		t.Fatalf("keys = %q, want two attempts with one key", server.keys)
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	server := &scriptedServer{statuses: []int{http.StatusServiceUnavailable}}
+	c := newScriptedClient(t, server)
+
+	_, err := c.CreateRobot(context.Background(), CreateRobotDTO{Name: "r1"})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("err = %v, want 503", err)
+	}
+	if len(server.keys) != 4 {
+		t.Fatalf("attempts = %d, want 4", len(server.keys))
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	c := New("http://example", WithRetry(RetryPolicy{MaxAttempts: 10, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}))
+
+	tests := []struct {
+		attempt  int
+		err      error
+		min, max time.Duration
+	}{
+		{1, nil, 50 * time.Millisecond, 100 * time.Millisecond},
+		{2, nil, 100 * time.Millisecond, 200 * time.Millisecond},
+		{3, nil, 200 * time.Millisecond, 400 * time.Millisecond},
+		{5, nil, 500 * time.Millisecond, time.Second},
+		// Сдвиг переполняется, задержка всё равно не больше MaxDelay
+		{70, nil, 500 * time.Millisecond, time.Second},
+		{1, &APIError{StatusCode: http.StatusTooManyRequests, RetryAfter: 300 * time.Millisecond}, 300 * time.Millisecond, 300 * time.Millisecond},
+		{1, &APIError{StatusCode: http.StatusTooManyRequests, RetryAfter: time.Minute}, time.Second, time.Second},
+	}
+	for _, tt := range tests {
+		for range 20 {
+			if got := c.backoff(tt.attempt, tt.err); got < tt.min || got > tt.max {
+				t.Fatalf("backoff(%d, %v) = %v, want [%v, %v]", tt.attempt, tt.err, got, tt.min, tt.max)
+			}
+		}
+	}
+}
+
+func TestPendingApproval(t *testing.T) {
+	expires := time.Date(2026, 10, 20, 12, 0, 0, 0, time.UTC)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Робот 1 меняется сразу, остальное ждёт подтверждения
+		if r.URL.Path == "/robots/delete/1" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Location", "/approvals/12")
+		w.WriteHeader(http.StatusAccepted)
+		_ = json.NewEncoder(w).Encode(Approval{ID: 12, Action: "robots:delete", RobotID: 2, Status: "pending", ExpiresAt: expires})
+	}))
+	t.Cleanup(ts.Close)
+	c := New(ts.URL)
+
+	if err := c.DeleteRobot(context.Background(), 1); err != nil {
+		t.Fatalf("DeleteRobot(1): %v", err)
+	}
+
+	calls := map[string]func() error{
+		"delete": func() error { return c.DeleteRobot(context.Background(), 2) },
+		"retype": func() error { return c.ChangeRobotType(context.Background(), ChangeTypeDTO{ID: 2, Type: "drone"}) },
+	}
+	for name, call := range calls {
+		err := call()
+		var pending *PendingApprovalError
+		if !errors.As(err, &pending) {
+			t.Fatalf("%s: err = %v, want *PendingApprovalError", name, err)
+		}
+		if pending.Approval.ID != 12 || pending.Approval.Status != "pending" || !pending.Approval.ExpiresAt.Equal(expires) {
+			t.Fatalf("%s: approval = %+v", name, pending.Approval)
+		}
+		// Это не ошибка API
+		var apiErr *APIError
+		if errors.As(err, &apiErr) {
+			t.Fatalf("%s: err is also *APIError", name)
+		}
+	}
+}
@@ -0,0 +1,311 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+func (c *Client) CreateRobot(ctx context.Context, robot CreateRobotDTO) (int, error) {
+	req, err := jsonRequest(http.MethodPost, "/robots/create", robot)
+	if err != nil {
+		return 0, err
+	}
+	var id int
+	err = c.doJSON(ctx, req, &id)
+	return id, err
+}
+
+func (c *Client) GetRobotInfo(ctx context.Context, id int) (*Robot, error) {
+	var robot Robot
+	err := c.doJSON(ctx, request{method: http.MethodGet, path: fmt.Sprintf("/robots/%d", id)}, &robot)
+	if err != nil {
+		return nil, err
+	}
+	return &robot, nil
+}
+
+// Параметры списка, нулевые значения не отправляются
+type ListOptions struct {
+	Limit  int
+	Offset int
+	Type   string
+	// Селекторы атрибутов: battery.level>=20, model=x4
+	Attributes []string
+	// Селектор меток: site=wh2,env in (prod,stage)
+	LabelSelector string
+}
+
+func (opts ListOptions) query() url.Values {
+	query := url.Values{}
+	if opts.Limit > 0 {
+		query.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.Offset > 0 {
+		query.Set("offset", strconv.Itoa(opts.Offset))
+	}
+	if opts.Type != "" {
+		query.Set("type", opts.Type)
+	}
+	for _, selector := range opts.Attributes {
+		query.Add("attr", selector)
+	}
+	if opts.LabelSelector != "" {
+		query.Set("labelSelector", opts.LabelSelector)
+	}
+	return query
+}
+
+func (c *Client) ListRobots(ctx context.Context, opts ListOptions) ([]Robot, error) {
+	path := "/robots"
+	if query := opts.query().Encode(); query != "" {
+		path += "?" + query
+	}
+	var robots []Robot
+	err := c.doJSON(ctx, request{method: http.MethodGet, path: path}, &robots)
+	return robots, err
+}
+
+// Роботы рядом с точкой, ближние первыми. radius <= 0 и k <= 0 не отправляются, но хотя бы одно нужно
+func (c *Client) NearRobots(ctx context.Context, center RobotCord, radius float64, k int) ([]NearbyRobot, error) {
+	query := url.Values{}
+	query.Set("x", strconv.Itoa(center.XCord))
+	query.Set("y", strconv.Itoa(center.YCord))
+	query.Set("z", strconv.Itoa(center.ZCord))
+	if radius > 0 {
+		query.Set("radius", strconv.FormatFloat(radius, 'f', -1, 64))
+	}
+	if k > 0 {
+		query.Set("k", strconv.Itoa(k))
+	}
+	var robots []NearbyRobot
+	err := c.doJSON(ctx, request{method: http.MethodGet, path: "/robots/near?" + query.Encode()}, &robots)
+	return robots, err
+}
+
+// Маршрут до цели в обход препятствий. Робот не двигается, со Store маршрут сохраняется как активный
+func (c *Client) Navigate(ctx context.Context, id int, target NavigateDTO) (*NavigationPlan, error) {
+	req, err := jsonRequest(http.MethodPost, fmt.Sprintf("/robots/%d/navigate", id), target)
+	if err != nil {
+		return nil, err
+	}
+	var plan NavigationPlan
+	if err = c.doJSON(ctx, req, &plan); err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
+
+// Команда перемещения по маршруту. Сервер принимает её сразу, ход выполнения - в GetCommand
+func (c *Client) Move(ctx context.Context, id int, move MoveDTO) (*MoveCommand, error) {
+	req, err := jsonRequest(http.MethodPost, fmt.Sprintf("/robots/%d/move", id), move)
+	if err != nil {
+		return nil, err
+	}
+	var command MoveCommand
+	if err = c.doJSON(ctx, req, &command); err != nil {
+		return nil, err
+	}
+	return &command, nil
+}
+
+func (c *Client) GetCommand(ctx context.Context, id int) (*MoveCommand, error) {
+	var command MoveCommand
+	err := c.doJSON(ctx, request{method: http.MethodGet, path: fmt.Sprintf("/commands/%d", id)}, &command)
+	if err != nil {
+		return nil, err
+	}
+	return &command, nil
+}
+
+func (c *Client) CancelCommand(ctx context.Context, id int) (*MoveCommand, error) {
+	var command MoveCommand
+	err := c.doJSON(ctx, request{method: http.MethodDelete, path: fmt.Sprintf("/commands/%d", id)}, &command)
+	if err != nil {
+		return nil, err
+	}
+	return &command, nil
+}
+
+func (c *Client) GetReservation(ctx context.Context, commandID int) (*Reservation, error) {
+	var reservation Reservation
+	err := c.doJSON(ctx, request{method: http.MethodGet, path: fmt.Sprintf("/reservations/%d", commandID)}, &reservation)
+	if err != nil {
+		return nil, err
+	}
+	return &reservation, nil
+}
+
+func (c *Client) CreateMission(ctx context.Context, mission CreateMissionDTO) (*Mission, error) {
+	req, err := jsonRequest(http.MethodPost, "/missions", mission)
+	if err != nil {
+		return nil, err
+	}
+	var created Mission
+	if err = c.doJSON(ctx, req, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+func (c *Client) GetMission(ctx context.Context, id int) (*Mission, error) {
+	var mission Mission
+	err := c.doJSON(ctx, request{method: http.MethodGet, path: fmt.Sprintf("/missions/%d", id)}, &mission)
+	if err != nil {
+		return nil, err
+	}
+	return &mission, nil
+}
+
+func (c *Client) AssignMission(ctx context.Context, id, robotID int) (*Mission, error) {
+	req, err := jsonRequest(http.MethodPost, fmt.Sprintf("/missions/%d/assign", id), AssignMissionDTO{RobotID: robotID})
+	if err != nil {
+		return nil, err
+	}
+	var mission Mission
+	if err = c.doJSON(ctx, req, &mission); err != nil {
+		return nil, err
+	}
+	return &mission, nil
+}
+
+// action - pause, resume или cancel
+func (c *Client) ChangeMission(ctx context.Context, id int, action string) (*Mission, error) {
+	var mission Mission
+	err := c.doJSON(ctx, request{method: http.MethodPost, path: fmt.Sprintf("/missions/%d/%s", id, action)}, &mission)
+	if err != nil {
+		return nil, err
+	}
+	return &mission, nil
+}
+
+func (c *Client) UpdateRobotCords(ctx context.Context, update UpdateRobotCordDTO) error {
+	req, err := jsonRequest(http.MethodPut, "/robots/updatecord", update)
+	if err != nil {
+		return err
+	}
+	return c.doJSON(ctx, req, nil)
+}
+
+func (c *Client) UpdateRobotName(ctx context.Context, update UpdateRobotNameDTO) error {
+	req, err := jsonRequest(http.MethodPut, "/robots/updatename", update)
+	if err != nil {
+		return err
+	}
+	return c.doJSON(ctx, req, nil)
+}
+
+// Если смена типа требует подтверждения, возвращает *PendingApprovalError
+func (c *Client) ChangeRobotType(ctx context.Context, update ChangeTypeDTO) error {
+	req, err := jsonRequest(http.MethodPut, "/robots/updatetype", update)
+	if err != nil {
+		return err
+	}
+	return c.doApproval(ctx, req)
+}
+
+// JSON Merge Patch атрибутов: nil значение удаляет ключ. Возвращает робота после изменения
+func (c *Client) PatchRobotAttributes(ctx context.Context, id int, patch map[string]any) (*Robot, error) {
+	req, err := jsonRequest(http.MethodPatch, fmt.Sprintf("/robots/%d", id), PatchRobotDTO{Attributes: patch})
+	if err != nil {
+		return nil, err
+	}
+	var robot Robot
+	if err = c.doJSON(ctx, req, &robot); err != nil {
+		return nil, err
+	}
+	return &robot, nil
+}
+
+// Заменяет все метки робота
+func (c *Client) SetRobotLabels(ctx context.Context, id int, labels map[string]string) (*Robot, error) {
+	return c.updateLabels(ctx, http.MethodPut, id, SetRobotLabelsDTO{Labels: labels})
+}
+
+// Ставит метки поверх текущих, nil значение снимает метку
+func (c *Client) PatchRobotLabels(ctx context.Context, id int, patch map[string]*string) (*Robot, error) {
+	return c.updateLabels(ctx, http.MethodPatch, id, PatchRobotLabelsDTO{Labels: patch})
+}
+
+func (c *Client) updateLabels(ctx context.Context, method string, id int, body any) (*Robot, error) {
+	req, err := jsonRequest(method, fmt.Sprintf("/robots/%d/labels", id), body)
+	if err != nil {
+		return nil, err
+	}
+	var robot Robot
+	if err = c.doJSON(ctx, req, &robot); err != nil {
+		return nil, err
+	}
+	return &robot, nil
+}
+
+// Если удаление требует подтверждения, возвращает *PendingApprovalError
+func (c *Client) DeleteRobot(ctx context.Context, id int) error {
+	return c.doApproval(ctx, request{method: http.MethodDelete, path: fmt.Sprintf("/robots/delete/%d", id)})
+}
+
+// Изменение, которое сервер может отложить до подтверждения: на 202 в теле заявка
+func (c *Client) doApproval(ctx context.Context, req request) error {
+	resp, err := c.do(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	var approval Approval
+	if err = json.NewDecoder(resp.Body).Decode(&approval); err != nil {
+		return err
+	}
+	return &PendingApprovalError{Approval: &approval}
+}
+
+// Выгрузка реестра в w, format - jsonl или csv. labelSelector ограничивает выгрузку, пустой - весь реестр
+func (c *Client) ExportRobots(ctx context.Context, w io.Writer, format, labelSelector string) error {
+	query := url.Values{}
+	query.Set("format", format)
+	if labelSelector != "" {
+		query.Set("labelSelector", labelSelector)
+	}
+	resp, err := c.do(ctx, request{method: http.MethodGet, path: "/robots/export?" + query.Encode()})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// Импорт реестра из r. При битом файле сервер отвечает 400, текст отчёта будет в *APIError
+func (c *Client) ImportRobots(ctx context.Context, r io.Reader, format string, dryRun bool) (*ImportReport, error) {
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	query := url.Values{}
+	query.Set("format", format)
+	query.Set("dryRun", strconv.FormatBool(dryRun))
+	contentType := "application/x-ndjson"
+	if format == "csv" {
+		contentType = "text/csv"
+	}
+
+	var report ImportReport
+	err = c.doJSON(ctx, request{
+		method:      http.MethodPost,
+		path:        "/robots/import?" + query.Encode(),
+		body:        body,
+		contentType: contentType,
+	}, &report)
+	if err != nil {
+		return nil, err
+	}
+	return &report, nil
+}